@@ -0,0 +1,30 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Command gogitdiff-wasm exposes the gitdiff parser to JavaScript when
+// compiled to WebAssembly:
+//
+//	GOOS=js GOARCH=wasm go build -o gitdiff.wasm ./cmd/gogitdiff-wasm
+//
+// After the module starts, it defines a global gitdiffParse function that
+// takes the text of a patch and returns the JSON document produced by
+// gitdiff.ParseToJSON.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+func main() {
+	js.Global().Set("gitdiffParse", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) == 0 {
+			return gitdiff.ParseToJSON("")
+		}
+		return gitdiff.ParseToJSON(args[0].String())
+	}))
+
+	// keep the module running so the function stays available
+	select {}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+func runApply(e *env, args []string) error {
+	fs := newFlagSet(e, "apply")
+	dir := fs.String("d", ".", "apply the patch to files in `dir`")
+	check := fs.Bool("check", false, "check that the patch applies without modifying files")
+	fuzz := fs.Int64("fuzz", 0, "search up to `n` lines from the recorded position for each fragment (-1 for no limit)")
+	force := fs.Bool("force", false, "apply fragments at their recorded positions even if the context does not match")
+	ignoreSpace := fs.Bool("ignore-space-change", false, "ignore changes in the amount of whitespace when matching context")
+	irreversible := fs.Bool("irreversible-delete", false, "delete files even if the patch omits their content")
+	modeError := fs.Bool("mode-error", false, "fail instead of warning if file modes cannot be changed")
+	unsafePaths := fs.Bool("unsafe-paths", false, "allow file names that are unsafe to write, such as names containing \"..\"")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files, _, err := readPatch(e, fs)
+	if err != nil {
+		return err
+	}
+
+	opts := gitdiff.ApplyTreeOptions{
+		ApplyOptions: gitdiff.ApplyOptions{MaxOffset: *fuzz, AllowContentOmitted: *irreversible},
+		Check:        *check,
+		Unsafe:       *unsafePaths,
+		ModeError:    *modeError,
+		Warnf: func(format string, args ...interface{}) {
+			fmt.Fprintf(e.stderr, "warning: "+format+"\n", args...)
+		},
+	}
+	switch {
+	case *force:
+		opts.ApplyOptions.Matcher = gitdiff.ForceMatcher
+	case *ignoreSpace:
+		opts.ApplyOptions.Matcher = gitdiff.WhitespaceMatcher
+	}
+	return gitdiff.ApplyTree(*dir, files, opts)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	files := map[string]string{
+		"docs/readme.txt": "line 1\nline 2\nline 3\n",
+		"old.txt":         "renamed content\n",
+	}
+
+	setup := func(t *testing.T) string {
+		dir, err := ioutil.TempDir("", "gogitdiff-apply")
+		if err != nil {
+			t.Fatalf("unexpected error creating directory: %v", err)
+		}
+		for name, content := range files {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("unexpected error creating directory: %v", err)
+			}
+			if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("unexpected error writing file: %v", err)
+			}
+		}
+		return dir
+	}
+
+	read := func(t *testing.T, dir, name string) (string, bool) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			return "", false
+		}
+		if err != nil {
+			t.Fatalf("unexpected error reading file: %v", err)
+		}
+		return string(data), true
+	}
+
+	t.Run("apply", func(t *testing.T) {
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		if _, errOut, code := runTest(t, "", "apply", "-d", dir, "testdata/changes.patch"); code != 0 {
+			t.Fatalf("unexpected exit code %d\nstderr: %s", code, errOut)
+		}
+
+		expected := map[string]string{
+			"docs/readme.txt": "line 1\nline two \nline 3\n",
+			"src/main.go":     "package main\n<<<<<<< HEAD\n",
+			"new.txt":         "renamed content\n",
+		}
+		for name, content := range expected {
+			if actual, ok := read(t, dir, name); !ok || actual != content {
+				t.Errorf("incorrect content for %s: expected %q, actual %q", name, content, actual)
+			}
+		}
+		if _, ok := read(t, dir, "old.txt"); ok {
+			t.Errorf("renamed file old.txt still exists")
+		}
+	})
+
+	t.Run("check", func(t *testing.T) {
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		if _, errOut, code := runTest(t, "", "apply", "-check", "-d", dir, "testdata/changes.patch"); code != 0 {
+			t.Fatalf("unexpected exit code %d\nstderr: %s", code, errOut)
+		}
+		for name, content := range files {
+			if actual, _ := read(t, dir, name); actual != content {
+				t.Errorf("file %s was modified: %q", name, actual)
+			}
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		if err := ioutil.WriteFile(filepath.Join(dir, "docs", "readme.txt"), []byte("line 1\nline 2 changed\nline 3\n"), 0644); err != nil {
+			t.Fatalf("unexpected error writing file: %v", err)
+		}
+		if _, _, code := runTest(t, "", "apply", "-d", dir, "testdata/changes.patch"); code != 1 {
+			t.Fatalf("incorrect exit code: expected 1, actual %d", code)
+		}
+		if _, ok := read(t, dir, "src/main.go"); ok {
+			t.Errorf("file was created even though the patch failed")
+		}
+	})
+
+	t.Run("force", func(t *testing.T) {
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		if err := ioutil.WriteFile(filepath.Join(dir, "docs", "readme.txt"), []byte("line one\nline 2 changed\nline 3\n"), 0644); err != nil {
+			t.Fatalf("unexpected error writing file: %v", err)
+		}
+		if _, errOut, code := runTest(t, "", "apply", "-force", "-d", dir, "testdata/changes.patch"); code != 0 {
+			t.Fatalf("unexpected exit code %d\nstderr: %s", code, errOut)
+		}
+		if actual, _ := read(t, dir, "docs/readme.txt"); actual != "line one\nline two \nline 3\n" {
+			t.Errorf("incorrect content for docs/readme.txt: %q", actual)
+		}
+	})
+
+	t.Run("irreversibleDelete", func(t *testing.T) {
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		patch := "diff --git a/old.txt b/old.txt\ndeleted file mode 100644\nindex 1234567..0000000\n"
+		if _, _, code := runTest(t, patch, "apply", "-d", dir); code != 1 {
+			t.Fatalf("incorrect exit code: expected 1, actual %d", code)
+		}
+		if _, ok := read(t, dir, "old.txt"); !ok {
+			t.Fatalf("file was deleted without -irreversible-delete")
+		}
+
+		if _, errOut, code := runTest(t, patch, "apply", "-irreversible-delete", "-d", dir); code != 0 {
+			t.Fatalf("unexpected exit code %d\nstderr: %s", code, errOut)
+		}
+		if _, ok := read(t, dir, "old.txt"); ok {
+			t.Errorf("file old.txt still exists")
+		}
+	})
+
+	modePatch := "diff --git a/old.txt b/old.txt\nold mode 100644\nnew mode 100755\n"
+
+	mode := func(t *testing.T, dir, name string) os.FileMode {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("unexpected error reading file info: %v", err)
+		}
+		return info.Mode().Perm()
+	}
+
+	t.Run("modeOnly", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file modes are not supported on this system")
+		}
+
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		if _, errOut, code := runTest(t, modePatch, "apply", "-d", dir); code != 0 {
+			t.Fatalf("unexpected exit code %d\nstderr: %s", code, errOut)
+		}
+		if m := mode(t, dir, "old.txt"); m != 0755 {
+			t.Errorf("incorrect mode: expected %o, actual %o", 0755, m)
+		}
+		if actual, _ := read(t, dir, "old.txt"); actual != files["old.txt"] {
+			t.Errorf("file content was modified: %q", actual)
+		}
+	})
+
+	t.Run("unsafePath", func(t *testing.T) {
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		patch := "diff --git a/../escape.txt b/../escape.txt\nnew file mode 100644\n--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+escaped\n"
+		_, errOut, code := runTest(t, patch, "apply", "-d", filepath.Join(dir, "docs"))
+		if code != 1 {
+			t.Fatalf("incorrect exit code: expected 1, actual %d", code)
+		}
+		if !strings.Contains(errOut, "unsafe path") {
+			t.Errorf("incorrect error: %s", errOut)
+		}
+
+		if _, errOut, code := runTest(t, patch, "apply", "-unsafe-paths", "-d", filepath.Join(dir, "docs")); code != 0 {
+			t.Fatalf("unexpected exit code %d\nstderr: %s", code, errOut)
+		}
+		if actual, _ := read(t, dir, "escape.txt"); actual != "escaped\n" {
+			t.Errorf("incorrect content for escape.txt: %q", actual)
+		}
+	})
+}
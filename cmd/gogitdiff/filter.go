@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+func runFilter(e *env, args []string) error {
+	var include, exclude patterns
+
+	fs := newFlagSet(e, "filter")
+	fs.Var(&include, "include", "keep files matching `pattern` (may be repeated)")
+	fs.Var(&exclude, "exclude", "remove files matching `pattern` (may be repeated)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files, preamble, err := readPatch(e, fs)
+	if err != nil {
+		return err
+	}
+
+	var kept []*gitdiff.File
+	for _, f := range files {
+		if len(include) > 0 && !include.MatchFile(f) {
+			continue
+		}
+		if exclude.MatchFile(f) {
+			continue
+		}
+		kept = append(kept, f)
+	}
+
+	if len(kept) > 0 {
+		if _, err := io.WriteString(e.stdout, preamble); err != nil {
+			return err
+		}
+	}
+	return gitdiff.FormatFiles(e.stdout, kept)
+}
+
+// patterns is a list of path patterns set by a repeated flag. A pattern
+// matches a path if it matches the full path using path.Match or if it names
+// one of the directories containing the path.
+type patterns []string
+
+var _ flag.Value = (*patterns)(nil)
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(s string) error {
+	if _, err := path.Match(s, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %v", s, err)
+	}
+	*p = append(*p, s)
+	return nil
+}
+
+// MatchFile returns true if any pattern matches the old or new name of f.
+func (p patterns) MatchFile(f *gitdiff.File) bool {
+	for _, name := range []string{f.OldName, f.NewName} {
+		if name != "" && p.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p patterns) match(name string) bool {
+	for _, pattern := range p {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if dir := strings.TrimSuffix(pattern, "/"); strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
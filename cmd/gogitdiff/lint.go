@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+func runLint(e *env, args []string) error {
+	fs := newFlagSet(e, "lint")
+	sarif := fs.Bool("sarif", false, "print the problems as a SARIF log for code scanning tools")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files, _, err := readPatch(e, fs)
+	if err != nil {
+		return err
+	}
+
+	problems := lint(files)
+	if *sarif {
+		findings := make([]gitdiff.Finding, len(problems))
+		for i, p := range problems {
+			findings[i] = gitdiff.Finding{
+				File:     p.Name,
+				Line:     p.Line,
+				Column:   p.Column,
+				Rule:     p.Rule,
+				Redacted: p.Snippet,
+				Message:  p.Msg,
+			}
+		}
+		if err := gitdiff.WriteSARIF(e.stdout, "gogitdiff", lintRules, findings); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range problems {
+			fmt.Fprintln(e.stdout, p)
+		}
+	}
+	if len(problems) > 0 {
+		return &exitError{1}
+	}
+	return nil
+}
+
+// Rules reported by lint.
+const (
+	ruleInvalidFragment    = "invalid-fragment"
+	ruleTrailingWhitespace = "trailing-whitespace"
+	ruleConflictMarker     = "conflict-marker"
+)
+
+var lintRules = []gitdiff.SARIFRule{
+	{ID: ruleInvalidFragment, Description: "Fragment is not consistent with its header"},
+	{ID: ruleTrailingWhitespace, Description: "Added line ends with whitespace"},
+	{ID: ruleConflictMarker, Description: "Added line is a leftover merge conflict marker"},
+	{ID: gitdiff.RuleBidiControl, Description: "Added line contains a bidirectional control character"},
+	{ID: gitdiff.RuleInvisible, Description: "Added line contains an invisible character"},
+	{ID: gitdiff.RuleMixedScript, Description: "Added word mixes letters from different scripts"},
+}
+
+// unicodeMessages are the messages for the rules of gitdiff.UnicodeScanner.
+var unicodeMessages = map[string]string{
+	gitdiff.RuleBidiControl: "bidirectional control character",
+	gitdiff.RuleInvisible:   "invisible character",
+	gitdiff.RuleMixedScript: "word mixes letters from different scripts",
+}
+
+// problem is an issue found in a patch by lint.
+type problem struct {
+	Name   string
+	Line   int64
+	Column int
+	Rule   string
+	Msg    string
+
+	// Snippet is the line with the problem, with suspicious characters
+	// escaped, if the line is included in reports.
+	Snippet string
+}
+
+func (p problem) String() string {
+	if p.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", p.Name, p.Line, p.Column, p.Msg)
+	}
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", p.Name, p.Line, p.Msg)
+	}
+	return fmt.Sprintf("%s: %s", p.Name, p.Msg)
+}
+
+var conflictMarkers = []string{"<<<<<<<", "|||||||", "=======", ">>>>>>>"}
+
+// lint reports invalid fragments and added lines with trailing whitespace,
+// conflict markers, or suspicious Unicode characters. Line numbers refer to
+// the new version of each file.
+func lint(files []*gitdiff.File) []problem {
+	var problems []problem
+	for _, f := range files {
+		name := statName(f)
+		for i, frag := range f.TextFragments {
+			if err := frag.Validate(); err != nil {
+				problems = append(problems, problem{Name: name, Rule: ruleInvalidFragment, Msg: fmt.Sprintf("fragment %d: %v", i+1, err)})
+				continue
+			}
+
+			lineno := frag.NewPosition
+			for _, line := range frag.Lines {
+				if line.Op == gitdiff.OpAdd {
+					problems = append(problems, lintLine(name, lineno, line.Line)...)
+				}
+				if line.New() {
+					lineno++
+				}
+			}
+		}
+	}
+	return problems
+}
+
+func lintLine(name string, lineno int64, line string) []problem {
+	var problems []problem
+
+	content := strings.TrimRight(line, "\r\n")
+	if trimmed := strings.TrimRight(content, " \t"); trimmed != content {
+		problems = append(problems, problem{Name: name, Line: lineno, Rule: ruleTrailingWhitespace, Msg: "trailing whitespace"})
+	}
+	if isConflictMarker(content) {
+		problems = append(problems, problem{Name: name, Line: lineno, Rule: ruleConflictMarker, Msg: "leftover conflict marker"})
+	}
+	for _, f := range gitdiff.UnicodeScanner.ScanLine(name, lineno, line) {
+		problems = append(problems, problem{
+			Name:    name,
+			Line:    lineno,
+			Column:  f.Column,
+			Rule:    f.Rule,
+			Msg:     unicodeMessages[f.Rule],
+			Snippet: f.Redacted,
+		})
+	}
+	return problems
+}
+
+// isConflictMarker returns true if line is a marker left by a merge conflict:
+// a sequence of seven marker characters either alone or followed by a space.
+func isConflictMarker(line string) bool {
+	for _, marker := range conflictMarkers {
+		if line == marker || strings.HasPrefix(line, marker+" ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+// Command gogitdiff parses, inspects, and applies patches generated by Git
+// using the gitdiff package.
+//
+// Usage:
+//
+//	gogitdiff parse [-json] [patch]
+//	gogitdiff apply [-d dir] [-check] [-force] [-fuzz n] [-ignore-space-change] [-irreversible-delete] [-mode-error] [-unsafe-paths] [patch]
+//	gogitdiff stat [-numstat] [patch]
+//	gogitdiff filter [-include pattern]... [-exclude pattern]... [patch]
+//	gogitdiff lint [-sarif] [patch]
+//
+// If the patch is omitted or is "-", it is read from standard input.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(env *env, args []string) error
+}
+
+var commands = []command{
+	{"parse", "print the files in a patch", runParse},
+	{"apply", "apply a patch to files in a directory", runApply},
+	{"stat", "print the number of changed lines in each file", runStat},
+	{"filter", "print the files in a patch that match patterns", runFilter},
+	{"lint", "report problems with the changes in a patch", runLint},
+}
+
+// env holds the standard streams so commands can be tested.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// exitError is returned by commands that need a specific exit status.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func main() {
+	os.Exit(run(&env{os.Stdin, os.Stdout, os.Stderr}, os.Args[1:]))
+}
+
+func run(e *env, args []string) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return 2
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(e, args[1:])
+		switch err := err.(type) {
+		case nil:
+			return 0
+		case *exitError:
+			return err.code
+		}
+		if err == flag.ErrHelp {
+			return 2
+		}
+		fmt.Fprintf(e.stderr, "gogitdiff %s: %v\n", cmd.name, err)
+		return 1
+	}
+
+	fmt.Fprintf(e.stderr, "gogitdiff: unknown command %q\n", args[0])
+	usage(e.stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: gogitdiff <command> [options] [patch]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("gogitdiff "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// readPatch parses the patch named by the remaining arguments of fs.
+func readPatch(e *env, fs *flag.FlagSet) ([]*gitdiff.File, string, error) {
+	if fs.NArg() > 1 {
+		return nil, "", fmt.Errorf("too many arguments")
+	}
+
+	r := e.stdin
+	if name := fs.Arg(0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		r = f
+	}
+	return gitdiff.ParseAll(r)
+}
+
+func runParse(e *env, args []string) error {
+	fs := newFlagSet(e, "parse")
+	asJSON := fs.Bool("json", false, "print the parsed files as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files, _, err := readPatch(e, fs)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if files == nil {
+			files = []*gitdiff.File{}
+		}
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(files)
+	}
+	_, err = io.WriteString(e.stdout, gitdiff.FormatNameStatus(files))
+	return err
+}
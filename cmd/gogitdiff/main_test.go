@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func runTest(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := run(&env{strings.NewReader(stdin), &stdout, &stderr}, args)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestCommands(t *testing.T) {
+	tests := map[string]struct {
+		Args   []string
+		Stdin  string
+		Output string
+		Code   int
+	}{
+		"parse": {
+			Args:   []string{"parse", "testdata/changes.patch"},
+			Output: "M\tdocs/readme.txt\nA\tsrc/main.go\nR100\told.txt\tnew.txt\n",
+		},
+		"parseStdin": {
+			Args:   []string{"parse", "-"},
+			Stdin:  "diff --git a/a.txt b/a.txt\ndeleted file mode 100644\n",
+			Output: "D\ta.txt\n",
+		},
+		"stat": {
+			Args: []string{"stat", "testdata/changes.patch"},
+			Output: " docs/readme.txt    | 2 +-\n" +
+				" src/main.go        | 2 ++\n" +
+				" old.txt => new.txt | 0\n" +
+				" 3 files changed, 3 insertions(+), 1 deletion(-)\n",
+		},
+		"numstat": {
+			Args:   []string{"stat", "-numstat", "testdata/changes.patch"},
+			Output: "1\t1\tdocs/readme.txt\n2\t0\tsrc/main.go\n0\t0\told.txt => new.txt\n",
+		},
+		"filterInclude": {
+			Args: []string{"filter", "-include", "docs", "testdata/changes.patch"},
+			Output: "diff --git a/docs/readme.txt b/docs/readme.txt\n" +
+				"index 1111111..2222222 100644\n" +
+				"--- a/docs/readme.txt\n" +
+				"+++ b/docs/readme.txt\n" +
+				"@@ -1,3 +1,3 @@\n" +
+				" line 1\n" +
+				"-line 2\n" +
+				"+line two \n" +
+				" line 3\n",
+		},
+		"filterExclude": {
+			Args: []string{"filter", "-exclude", "*.txt", "-exclude", "docs/", "testdata/changes.patch"},
+			Output: "diff --git a/src/main.go b/src/main.go\n" +
+				"new file mode 100644\n" +
+				"index 0000000..3333333\n" +
+				"--- /dev/null\n" +
+				"+++ b/src/main.go\n" +
+				"@@ -0,0 +1,2 @@\n" +
+				"+package main\n" +
+				"+<<<<<<< HEAD\n",
+		},
+		"lint": {
+			Args:   []string{"lint", "testdata/changes.patch"},
+			Output: "docs/readme.txt:2: trailing whitespace\nsrc/main.go:2: leftover conflict marker\n",
+			Code:   1,
+		},
+		"lintClean": {
+			Args:  []string{"lint"},
+			Stdin: "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+		"lintUnicode": {
+			Args:   []string{"lint"},
+			Stdin:  "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+b := \"\u202e\"\n",
+			Output: "a.go:1:7: bidirectional control character\n",
+			Code:   1,
+		},
+		"invalidPatch": {
+			Args:  []string{"parse"},
+			Stdin: "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n-a\n+b\n",
+			Code:  1,
+		},
+		"unknownCommand": {
+			Args: []string{"frobnicate"},
+			Code: 2,
+		},
+		"noCommand": {
+			Code: 2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, errOut, code := runTest(t, test.Stdin, test.Args...)
+			if code != test.Code {
+				t.Fatalf("incorrect exit code: expected %d, actual %d\nstderr: %s", test.Code, code, errOut)
+			}
+			if out != test.Output {
+				t.Errorf("incorrect output\nexpected: %q\n  actual: %q", test.Output, out)
+			}
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	out, errOut, code := runTest(t, "", "parse", "-json", "testdata/changes.patch")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d\nstderr: %s", code, errOut)
+	}
+
+	var files []struct {
+		OldName  string
+		NewName  string
+		IsRename bool
+	}
+	if err := json.Unmarshal([]byte(out), &files); err != nil {
+		t.Fatalf("unexpected error decoding output: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("incorrect number of files: expected 3, actual %d", len(files))
+	}
+	if f := files[2]; f.OldName != "old.txt" || f.NewName != "new.txt" || !f.IsRename {
+		t.Errorf("incorrect rename: %+v", f)
+	}
+}
+
+func TestLintSARIF(t *testing.T) {
+	out, errOut, code := runTest(t, "", "lint", "-sarif", "testdata/changes.patch")
+	if code != 1 {
+		t.Fatalf("incorrect exit code: expected 1, actual %d\nstderr: %s", code, errOut)
+	}
+
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(out), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("incorrect number of runs: expected 1, actual %d", len(log.Runs))
+	}
+
+	var rules []string
+	for _, r := range log.Runs[0].Results {
+		rules = append(rules, r.RuleID+": "+r.Message.Text)
+	}
+	expected := []string{"trailing-whitespace: trailing whitespace", "conflict-marker: leftover conflict marker"}
+	if strings.Join(rules, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("incorrect results\nexpected: %q\n  actual: %q", expected, rules)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+func runStat(e *env, args []string) error {
+	fs := newFlagSet(e, "stat")
+	numstat := fs.Bool("numstat", false, "print machine-readable counts like git diff --numstat")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files, _, err := readPatch(e, fs)
+	if err != nil {
+		return err
+	}
+
+	if *numstat {
+		for _, f := range files {
+			added, deleted := countLines(f)
+			if f.IsBinary {
+				fmt.Fprintf(e.stdout, "-\t-\t%s\n", statName(f))
+			} else {
+				fmt.Fprintf(e.stdout, "%d\t%d\t%s\n", added, deleted, statName(f))
+			}
+		}
+		return nil
+	}
+	return writeStat(e.stdout, files)
+}
+
+// statName returns the name of a file as shown by git diff --stat.
+func statName(f *gitdiff.File) string {
+	switch {
+	case f.IsDelete:
+		return f.OldName
+	case f.IsRename || f.IsCopy:
+		return f.OldName + " => " + f.NewName
+	}
+	return f.NewName
+}
+
+func countLines(f *gitdiff.File) (added, deleted int64) {
+	for _, frag := range f.TextFragments {
+		added += frag.LinesAdded
+		deleted += frag.LinesDeleted
+	}
+	return
+}
+
+func plural(n int64, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+func writeStat(w io.Writer, files []*gitdiff.File) error {
+	const maxBar = 50
+
+	var width int
+	var maxChanged, added, deleted int64
+	for _, f := range files {
+		if n := len(statName(f)); n > width {
+			width = n
+		}
+		a, d := countLines(f)
+		if a+d > maxChanged {
+			maxChanged = a + d
+		}
+		added += a
+		deleted += d
+	}
+
+	var b strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&b, " %-*s | ", width, statName(f))
+		if f.IsBinary {
+			b.WriteString("Bin\n")
+			continue
+		}
+
+		a, d := countLines(f)
+		total := a + d
+		if maxChanged > maxBar {
+			a, d = scaleBar(a, maxChanged, maxBar), scaleBar(d, maxChanged, maxBar)
+		}
+		fmt.Fprintf(&b, "%d", total)
+		if total > 0 {
+			fmt.Fprintf(&b, " %s%s", strings.Repeat("+", int(a)), strings.Repeat("-", int(d)))
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, " %s changed, %s(+), %s(-)\n",
+		plural(int64(len(files)), "file", "files"),
+		plural(added, "insertion", "insertions"),
+		plural(deleted, "deletion", "deletions"),
+	)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// scaleBar scales n so that max fits in width, keeping non-zero counts
+// visible.
+func scaleBar(n, max, width int64) int64 {
+	if n == 0 {
+		return 0
+	}
+	if scaled := n * width / max; scaled > 0 {
+		return scaled
+	}
+	return 1
+}
@@ -0,0 +1,130 @@
+package gitdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AmOptions configures AmApply.
+type AmOptions struct {
+	// State is the state to resume from, as returned by an earlier call to
+	// AmApply. Use the zero AmState to start at the first patch.
+	State AmState
+
+	// Commit, if set, is called with the header of each patch after its
+	// changes are written to the tree, to record them like git am records a
+	// commit.
+	Commit func(h *PatchHeader) error
+
+	// ApplyOptions configures how the changes of each patch are applied,
+	// including the limits on the size of the results and on the number of
+	// files, which apply to each patch separately.
+	ApplyOptions ApplyOptions
+}
+
+// AmState records the progress of AmApply, like the .git/rebase-apply
+// directory of git am, so that a series stopped by a patch that does not
+// apply can be resumed after the problem is fixed. It can be serialized as
+// text with MarshalText to save it between runs.
+type AmState struct {
+	// Patch is the index in the series of the next patch to apply.
+	Patch int
+
+	// Total is the number of patches in the series.
+	Total int
+
+	// Applied is true if the changes of the patch at Patch are already in
+	// the tree, but were not committed because opts.Commit failed. Resuming
+	// from the state only commits the patch.
+	Applied bool
+}
+
+// AmApply applies the patches in series to tree in order, like git am. The
+// series is usually read with ParseSeriesPatch, which keeps the header of
+// each patch for opts.Commit. Each patch is applied with BuildTreeWithOptions,
+// which checks every change, including the content of deleted files, before
+// modifying the tree, so a patch that does not apply leaves the tree as it
+// was after the previous patch. Cover letters are skipped.
+//
+// If a patch does not apply or opts.Commit returns an error, AmApply stops
+// and returns the state at that patch along with the error. If the commit
+// failed, the changes of the patch are already in the tree and the state is
+// marked as Applied. After fixing the problem, callers can retry the patch by
+// calling AmApply again with the state, or, after applying and committing
+// the changes of the patch themselves, continue with the state returned by
+// Skip. If all patches apply, the returned state is at the end of the series
+// and the error is nil.
+func AmApply(series []*SeriesPatch, tree ApplyTarget, opts AmOptions) (AmState, error) {
+	state := opts.State
+	if state == (AmState{}) {
+		state.Total = len(series)
+	}
+	if state.Total != len(series) || state.Patch < 0 || state.Patch > len(series) || (state.Applied && state.Patch == len(series)) {
+		return state, fmt.Errorf("gitdiff: invalid am state %s for a series of %d patches", state, len(series))
+	}
+
+	for ; state.Patch < len(series); state.Patch++ {
+		p := series[state.Patch]
+		if p.Prefix.Number == 0 && len(p.Files) == 0 {
+			continue
+		}
+
+		if !state.Applied {
+			if err := BuildTreeWithOptions(p.Files, tree, opts.ApplyOptions); err != nil {
+				return state, fmt.Errorf("gitdiff: applying patch %d (%s): %w", state.Patch+1, p.Subject(), err)
+			}
+			state.Applied = true
+		}
+		if opts.Commit != nil {
+			if err := opts.Commit(p.Header); err != nil {
+				return state, fmt.Errorf("gitdiff: committing patch %d (%s): %w", state.Patch+1, p.Subject(), err)
+			}
+		}
+		state.Applied = false
+	}
+	return state, nil
+}
+
+// Done returns true if the state is at the end of the series.
+func (s AmState) Done() bool {
+	return s.Patch >= s.Total
+}
+
+// Skip returns the state after the patch at s. Use it to continue with the
+// next patch after resolving and committing a patch that failed to apply.
+func (s AmState) Skip() AmState {
+	if s.Patch < s.Total {
+		s.Patch++
+	}
+	s.Applied = false
+	return s
+}
+
+func (s AmState) String() string {
+	if s.Applied {
+		return fmt.Sprintf("%d/%d applied", s.Patch, s.Total)
+	}
+	return fmt.Sprintf("%d/%d", s.Patch, s.Total)
+}
+
+// MarshalText encodes the state as a short token.
+func (s AmState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state encoded by MarshalText.
+func (s *AmState) UnmarshalText(text []byte) error {
+	var state AmState
+	var extra string
+	str := string(text)
+	if trimmed := strings.TrimSuffix(str, " applied"); trimmed != str {
+		state.Applied = true
+		str = trimmed
+	}
+	n, _ := fmt.Sscanf(str, "%d/%d%s", &state.Patch, &state.Total, &extra)
+	if n != 2 || state.Patch < 0 || state.Patch > state.Total || (state.Applied && state.Patch == state.Total) {
+		return fmt.Errorf("gitdiff: invalid am state: %q", text)
+	}
+	*s = state
+	return nil
+}
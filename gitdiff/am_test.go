@@ -0,0 +1,238 @@
+package gitdiff
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAmApply(t *testing.T) {
+	newPatch := func(n int, old, new string) *SeriesPatch {
+		patch := fmt.Sprintf(`From 61f5cd90bed4d204ee3feb3aa41ee91d4734855b Mon Sep 17 00:00:00 2001
+From: Morton Haypenny <mhaypenny@example.com>
+Date: Sat, 11 Apr 2020 15:21:23 -0700
+Subject: [PATCH %d/3] Change %s to %s
+
+---
+diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1 +1 @@
+-%s
++%s
+`, n, old, new, old, new)
+
+		p, err := ParseSeriesPatch(strings.NewReader(patch))
+		if err != nil {
+			t.Fatalf("unexpected error parsing patch: %v", err)
+		}
+		return p
+	}
+
+	series := []*SeriesPatch{
+		{Prefix: SeriesPrefix{Tag: "PATCH", Version: 1, Total: 3}, Header: &PatchHeader{Title: "Cover"}},
+		newPatch(1, "a", "b"),
+		newPatch(2, "b", "c"),
+		newPatch(3, "c", "d"),
+	}
+
+	var commits []string
+	commit := func(h *PatchHeader) error {
+		commits = append(commits, h.Title)
+		return nil
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		commits = nil
+		tree := renameTree{&memTree{blobs: map[string]string{"file.txt": "a\n"}, modes: map[string]os.FileMode{}}}
+
+		state, err := AmApply(series, tree, AmOptions{Commit: commit})
+		if err != nil {
+			t.Fatalf("unexpected error applying series: %v", err)
+		}
+		if !state.Done() || state != (AmState{Patch: 4, Total: 4}) {
+			t.Errorf("incorrect state: %s", state)
+		}
+		if tree.blobs["file.txt"] != "d\n" {
+			t.Errorf("incorrect content: %q", tree.blobs["file.txt"])
+		}
+		expected := []string{"Change a to b", "Change b to c", "Change c to d"}
+		if !reflect.DeepEqual(expected, commits) {
+			t.Errorf("incorrect commits\nexpected: %q\n  actual: %q", expected, commits)
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		commits = nil
+		tree := renameTree{&memTree{blobs: map[string]string{"file.txt": "a\n"}, modes: map[string]os.FileMode{}}}
+
+		// the second patch fails until the file is fixed
+		series := append([]*SeriesPatch(nil), series...)
+		series[2] = newPatch(2, "x", "c")
+
+		state, err := AmApply(series, tree, AmOptions{Commit: commit})
+		if err == nil {
+			t.Fatal("expected error applying series, but got nil")
+		}
+		if state != (AmState{Patch: 2, Total: 4}) {
+			t.Fatalf("incorrect state: %s", state)
+		}
+		if tree.blobs["file.txt"] != "b\n" {
+			t.Errorf("incorrect content after failure: %q", tree.blobs["file.txt"])
+		}
+
+		text, err := state.MarshalText()
+		if err != nil {
+			t.Fatalf("unexpected error marshaling state: %v", err)
+		}
+		var saved AmState
+		if err := saved.UnmarshalText(text); err != nil {
+			t.Fatalf("unexpected error unmarshaling state: %v", err)
+		}
+
+		tree.blobs["file.txt"] = "x\n"
+		state, err = AmApply(series, tree, AmOptions{State: saved, Commit: commit})
+		if err != nil {
+			t.Fatalf("unexpected error resuming series: %v", err)
+		}
+		if !state.Done() || tree.blobs["file.txt"] != "d\n" {
+			t.Errorf("incorrect result: state %s, content %q", state, tree.blobs["file.txt"])
+		}
+		expected := []string{"Change a to b", "Change x to c", "Change c to d"}
+		if !reflect.DeepEqual(expected, commits) {
+			t.Errorf("incorrect commits\nexpected: %q\n  actual: %q", expected, commits)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		tree := renameTree{&memTree{blobs: map[string]string{"file.txt": "c\n"}, modes: map[string]os.FileMode{}}}
+
+		state, err := AmApply(series, tree, AmOptions{State: AmState{Patch: 1, Total: 4}})
+		if err == nil {
+			t.Fatal("expected error applying series, but got nil")
+		}
+
+		state, err = AmApply(series, tree, AmOptions{State: state.Skip().Skip()})
+		if err != nil {
+			t.Fatalf("unexpected error after skipping patches: %v", err)
+		}
+		if !state.Done() || tree.blobs["file.txt"] != "d\n" {
+			t.Errorf("incorrect result: state %s, content %q", state, tree.blobs["file.txt"])
+		}
+	})
+
+	t.Run("staleDelete", func(t *testing.T) {
+		del, err := ParseSeriesPatch(strings.NewReader(`From 61f5cd90bed4d204ee3feb3aa41ee91d4734855b Mon Sep 17 00:00:00 2001
+From: Morton Haypenny <mhaypenny@example.com>
+Date: Sat, 11 Apr 2020 15:21:23 -0700
+Subject: [PATCH 2/2] Remove file
+
+---
+diff --git a/file.txt b/file.txt
+deleted file mode 100644
+--- a/file.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-x
+`))
+		if err != nil {
+			t.Fatalf("unexpected error parsing patch: %v", err)
+		}
+
+		tree := renameTree{&memTree{blobs: map[string]string{"file.txt": "a\n"}, modes: map[string]os.FileMode{}}}
+		state, err := AmApply([]*SeriesPatch{newPatch(1, "a", "b"), del}, tree, AmOptions{})
+		if !errors.Is(err, &Conflict{}) {
+			t.Fatalf("expected conflict deleting file, but got: %v", err)
+		}
+		if state.Patch != 1 || tree.blobs["file.txt"] != "b\n" {
+			t.Errorf("incorrect result: state %s, content %q", state, tree.blobs["file.txt"])
+		}
+	})
+
+	t.Run("commitError", func(t *testing.T) {
+		tree := renameTree{&memTree{blobs: map[string]string{"file.txt": "a\n"}, modes: map[string]os.FileMode{}}}
+		errCommit := errors.New("commit failed")
+
+		state, err := AmApply(series, tree, AmOptions{Commit: func(h *PatchHeader) error { return errCommit }})
+		if !errors.Is(err, errCommit) {
+			t.Fatalf("incorrect error: %v", err)
+		}
+		if state != (AmState{Patch: 1, Total: 4, Applied: true}) || tree.blobs["file.txt"] != "b\n" {
+			t.Fatalf("incorrect result: state %s, content %q", state, tree.blobs["file.txt"])
+		}
+
+		commits = nil
+		state, err = AmApply(series, tree, AmOptions{State: state, Commit: commit})
+		if err != nil {
+			t.Fatalf("unexpected error resuming series: %v", err)
+		}
+		if !state.Done() || tree.blobs["file.txt"] != "d\n" {
+			t.Errorf("incorrect result: state %s, content %q", state, tree.blobs["file.txt"])
+		}
+		expected := []string{"Change a to b", "Change b to c", "Change c to d"}
+		if !reflect.DeepEqual(expected, commits) {
+			t.Errorf("incorrect commits\nexpected: %q\n  actual: %q", expected, commits)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		tree := renameTree{&memTree{blobs: map[string]string{"file.txt": "a\n"}, modes: map[string]os.FileMode{}}}
+
+		state, err := AmApply(series, tree, AmOptions{ApplyOptions: ApplyOptions{MaxTotalBytes: 1}})
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("expected limit error, but got: %v", err)
+		}
+		if state.Patch != 1 || tree.blobs["file.txt"] != "a\n" {
+			t.Errorf("incorrect result: state %s, content %q", state, tree.blobs["file.txt"])
+		}
+	})
+
+	t.Run("invalidState", func(t *testing.T) {
+		tree := renameTree{&memTree{blobs: map[string]string{}, modes: map[string]os.FileMode{}}}
+		if _, err := AmApply(series, tree, AmOptions{State: AmState{Patch: 1, Total: 2}}); err == nil {
+			t.Fatal("expected error with invalid state, but got nil")
+		}
+	})
+}
+
+func TestAmStateText(t *testing.T) {
+	tests := map[string]struct {
+		Input string
+		State AmState
+		Err   bool
+	}{
+		"start":      {Input: "0/3", State: AmState{Patch: 0, Total: 3}},
+		"end":        {Input: "3/3", State: AmState{Patch: 3, Total: 3}},
+		"applied":    {Input: "1/3 applied", State: AmState{Patch: 1, Total: 3, Applied: true}},
+		"extra":      {Input: "1/3x", Err: true},
+		"endApplied": {Input: "3/3 applied", Err: true},
+		"past":       {Input: "4/3", Err: true},
+		"negative":   {Input: "-1/3", Err: true},
+		"missing":    {Input: "1", Err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var state AmState
+			err := state.UnmarshalText([]byte(test.Input))
+			if test.Err {
+				if err == nil {
+					t.Fatalf("expected error unmarshaling %q, but got nil", test.Input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if state != test.State {
+				t.Errorf("incorrect state: expected %s, actual %s", test.State, state)
+			}
+			if s := state.String(); s != test.Input {
+				t.Errorf("incorrect string: %q", s)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package gitdiff
+
+// Annotation is a note that a tool attaches to a file, a fragment, or a line
+// of a fragment, such as a review comment or the result of an analysis.
+// Annotations are kept by the functions in this package that copy, filter,
+// split, or merge files, but they are not part of the patch: they are not
+// formatted, encoded, or compared by Equal.
+type Annotation struct {
+	// Source identifies the tool or person that added the annotation.
+	Source string
+
+	// Body is the text of the annotation.
+	Body string
+
+	// FragmentLine is the one-indexed line in the Lines of the fragment
+	// that the annotation applies to, or zero if it applies to the whole
+	// fragment. It is not used for annotations of files.
+	FragmentLine int
+
+	// Data holds any additional information from the tool.
+	Data interface{}
+}
+
+// AnnotateLine adds an annotation to a line of the fragment. The line is
+// one-indexed, like the FragmentLine of the annotation, which is set to it.
+func (f *TextFragment) AnnotateLine(line int, a Annotation) {
+	a.FragmentLine = line
+	f.Annotations = append(f.Annotations, a)
+}
+
+// LineAnnotations returns the annotations of a one-indexed line of the
+// fragment, in the order they were added.
+func (f *TextFragment) LineAnnotations(line int) []Annotation {
+	var annotations []Annotation
+	for _, a := range f.Annotations {
+		if a.FragmentLine == line {
+			annotations = append(annotations, a)
+		}
+	}
+	return annotations
+}
+
+// joinAnnotations returns the annotations of a followed by those of b in a
+// new slice.
+func joinAnnotations(a, b []Annotation) []Annotation {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+	joined := make([]Annotation, 0, len(a)+len(b))
+	joined = append(joined, a...)
+	return append(joined, b...)
+}
@@ -0,0 +1,116 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineAnnotations(t *testing.T) {
+	frag := &TextFragment{
+		Lines: []Line{
+			{OpContext, "a\n"},
+			{OpDelete, "b\n"},
+			{OpAdd, "c\n"},
+		},
+	}
+	frag.Annotations = append(frag.Annotations, Annotation{Source: "lint", Body: "whole fragment"})
+	frag.AnnotateLine(3, Annotation{Source: "review", Body: "first", FragmentLine: 1})
+	frag.AnnotateLine(3, Annotation{Source: "review", Body: "second"})
+
+	expected := []Annotation{
+		{Source: "review", Body: "first", FragmentLine: 3},
+		{Source: "review", Body: "second", FragmentLine: 3},
+	}
+	if actual := frag.LineAnnotations(3); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("incorrect line annotations\nexpected: %+v\n  actual: %+v", expected, actual)
+	}
+	if actual := frag.LineAnnotations(2); actual != nil {
+		t.Errorf("unexpected annotations for line without annotations: %+v", actual)
+	}
+}
+
+func TestAnnotationsSurviveTransforms(t *testing.T) {
+	note := func(body string) []Annotation {
+		return []Annotation{{Source: "test", Body: body}}
+	}
+
+	t.Run("mergeDuplicatePaths", func(t *testing.T) {
+		const patch = `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1 +1 @@
+-a
++A
+diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -5 +5 @@
+-e
++E
+`
+		files, _, err := ParseAll(strings.NewReader(patch))
+		if err != nil {
+			t.Fatalf("unexpected error parsing patch: %v", err)
+		}
+		files[0].Annotations = note("first")
+		files[1].Annotations = note("second")
+		files[1].TextFragments[0].AnnotateLine(2, note("line")[0])
+
+		merged, err := MergeDuplicatePaths(files, DuplicatePathOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error merging files: %v", err)
+		}
+		if expected := append(note("first"), note("second")...); !reflect.DeepEqual(expected, merged[0].Annotations) {
+			t.Errorf("incorrect file annotations: %+v", merged[0].Annotations)
+		}
+		if len(merged[0].TextFragments[1].LineAnnotations(2)) != 1 {
+			t.Errorf("line annotation was lost")
+		}
+	})
+
+	t.Run("detectRenames", func(t *testing.T) {
+		const patch = `diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-a
+-b
+diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++a
++b
+`
+		files, _, err := ParseAll(strings.NewReader(patch))
+		if err != nil {
+			t.Fatalf("unexpected error parsing patch: %v", err)
+		}
+		files[0].Annotations = note("deleted")
+		files[1].Annotations = note("created")
+
+		renamed := DetectRenames(files, 50)
+		if len(renamed) != 1 || !renamed[0].IsRename {
+			t.Fatalf("files were not paired: %+v", renamed)
+		}
+		if expected := append(note("deleted"), note("created")...); !reflect.DeepEqual(expected, renamed[0].Annotations) {
+			t.Errorf("incorrect file annotations: %+v", renamed[0].Annotations)
+		}
+	})
+
+	t.Run("normalize", func(t *testing.T) {
+		f := &File{NewName: "file.txt", Annotations: note("file")}
+		f.TextFragments = []*TextFragment{{Annotations: note("fragment")}}
+
+		n := Normalize([]*File{f}, NormalizeOptions{Positions: true})[0]
+		if !reflect.DeepEqual(note("file"), n.Annotations) || !reflect.DeepEqual(note("fragment"), n.TextFragments[0].Annotations) {
+			t.Errorf("annotations were lost")
+		}
+		if !Equal([]*File{f}, []*File{{NewName: "file.txt", TextFragments: []*TextFragment{{}}}}) {
+			t.Errorf("annotations affect equality")
+		}
+	})
+}
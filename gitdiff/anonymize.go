@@ -0,0 +1,103 @@
+package gitdiff
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+)
+
+// AnonymizeOptions configures AnonymizePaths.
+type AnonymizeOptions struct {
+	// Key is the secret key used to hash names. Names are only comparable
+	// between patches anonymized with the same key.
+	Key []byte
+
+	// KeepExtensions keeps the extension of the base name of each file, so
+	// that files can still be grouped by type.
+	KeepExtensions bool
+}
+
+// AnonymizePaths returns copies of files with each element of their names
+// replaced by an HMAC-SHA256 of the path up to and including the element.
+// Files in the same directory still share a directory and the same path
+// always has the same name, so the structure of the patch and statistics
+// like those of Measure are kept without revealing the layout of the
+// repository. This allows collecting telemetry about the shape of patches.
+//
+// The names in raw entries are also replaced and extended headers, which
+// may contain names, are removed along with the extensions parsed from them.
+// Files that share a PatchHeader share a copy of it without the BodyAppendix,
+// which usually contains a diffstat with the original names. The Title, Body,
+// and identities in the header are not changed, so callers must remove them
+// if they may reveal the repository. The content of the files is not changed
+// and the copies share their fragments with the original files.
+func AnonymizePaths(files []*File, opts AnonymizeOptions) []*File {
+	a := &anonymizer{
+		opts:    opts,
+		names:   make(map[string]string),
+		headers: make(map[*PatchHeader]*PatchHeader),
+	}
+
+	anonymized := make([]*File, len(files))
+	for i, f := range files {
+		n := *f
+		n.OldName = a.name(f.OldName)
+		n.NewName = a.name(f.NewName)
+		n.ExtendedHeaders = nil
+		n.Extensions = nil
+		if f.Raw != nil {
+			raw := *f.Raw
+			raw.OldName = a.name(raw.OldName)
+			raw.NewName = a.name(raw.NewName)
+			n.Raw = &raw
+		}
+		n.PatchHeader = a.header(f.PatchHeader)
+		anonymized[i] = &n
+	}
+	return anonymized
+}
+
+type anonymizer struct {
+	opts    AnonymizeOptions
+	names   map[string]string
+	headers map[*PatchHeader]*PatchHeader
+}
+
+func (a *anonymizer) header(h *PatchHeader) *PatchHeader {
+	if h == nil {
+		return nil
+	}
+	if anon, ok := a.headers[h]; ok {
+		return anon
+	}
+
+	anon := *h
+	anon.BodyAppendix = ""
+	a.headers[h] = &anon
+	return &anon
+}
+
+func (a *anonymizer) name(name string) string {
+	if name == "" {
+		return ""
+	}
+	if anon, ok := a.names[name]; ok {
+		return anon
+	}
+
+	elems := strings.Split(name, "/")
+	anon := make([]string, len(elems))
+	for i, elem := range elems {
+		mac := hmac.New(sha256.New, a.opts.Key)
+		mac.Write([]byte(strings.Join(elems[:i+1], "/")))
+		anon[i] = hex.EncodeToString(mac.Sum(nil)[:8])
+		if i == len(elems)-1 && a.opts.KeepExtensions {
+			anon[i] += path.Ext(elem)
+		}
+	}
+
+	a.names[name] = strings.Join(anon, "/")
+	return a.names[name]
+}
@@ -0,0 +1,109 @@
+package gitdiff
+
+import (
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizePaths(t *testing.T) {
+	patch := `:100644 100644 1111111 2222222 M	src/main.go
+:100644 100644 3333333 3333333 R100	src/util.go	lib/util.go
+
+diff --git a/src/main.go b/src/main.go
+index 1111111..2222222 100644
+--- a/src/main.go
++++ b/src/main.go
+@@ -1 +1 @@
+-package main
++package app
+diff --git a/src/util.go b/lib/util.go
+similarity index 100%
+rename from src/util.go
+rename to lib/util.go
+`
+
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	anon := AnonymizePaths(files, AnonymizeOptions{Key: []byte("secret"), KeepExtensions: true})
+	if len(anon) != len(files) {
+		t.Fatalf("incorrect number of files: %d", len(anon))
+	}
+
+	main, util := anon[0], anon[1]
+	for _, name := range []string{main.OldName, main.NewName, util.OldName, util.NewName} {
+		if strings.Contains(name, "src") || strings.Contains(name, "lib") || strings.Contains(name, "main") || strings.Contains(name, "util") {
+			t.Errorf("name was not anonymized: %s", name)
+		}
+		if path.Ext(name) != ".go" {
+			t.Errorf("extension was not kept: %s", name)
+		}
+	}
+	if path.Dir(main.NewName) != path.Dir(util.OldName) {
+		t.Errorf("files in the same directory have different directories: %s, %s", main.NewName, util.OldName)
+	}
+	if path.Dir(util.OldName) == path.Dir(util.NewName) {
+		t.Errorf("files in different directories have the same directory: %s, %s", util.OldName, util.NewName)
+	}
+	if main.Raw == nil || main.Raw.NewName != main.NewName || util.Raw == nil || util.Raw.NewName != util.NewName {
+		t.Errorf("raw entries were not anonymized: %+v, %+v", main.Raw, util.Raw)
+	}
+	m, am := Measure(files), Measure(anon)
+	if m.Fragments != am.Fragments || m.Churn() != am.Churn() || len(m.Directories) != len(am.Directories) {
+		t.Errorf("anonymized files have different metrics\nexpected: %+v\n  actual: %+v", m, am)
+	}
+	if files[0].OldName != "src/main.go" || files[0].Raw.OldName != "src/main.go" {
+		t.Errorf("original files were modified: %+v", files[0])
+	}
+
+	again := AnonymizePaths(files, AnonymizeOptions{Key: []byte("secret"), KeepExtensions: true})
+	if again[0].NewName != main.NewName {
+		t.Errorf("same key produced different names: %s, %s", again[0].NewName, main.NewName)
+	}
+	other := AnonymizePaths(files, AnonymizeOptions{Key: []byte("other")})
+	if other[0].NewName == main.NewName || path.Ext(other[0].NewName) != "" {
+		t.Errorf("incorrect name with a different key: %s", other[0].NewName)
+	}
+}
+
+func TestAnonymizePathsExtensions(t *testing.T) {
+	f := &File{
+		OldName:         "src/main.go",
+		NewName:         "src/main.go",
+		ExtendedHeaders: []string{"x-origin src/main.go"},
+		Extensions:      map[string]interface{}{"x-origin": "src/main.go"},
+	}
+
+	anon := AnonymizePaths([]*File{f}, AnonymizeOptions{Key: []byte("secret")})
+	if anon[0].ExtendedHeaders != nil || anon[0].Extensions != nil {
+		t.Errorf("extended headers were not removed: %q, %v", anon[0].ExtendedHeaders, anon[0].Extensions)
+	}
+	if f.Extensions["x-origin"] != "src/main.go" {
+		t.Errorf("original extensions were modified: %v", f.Extensions)
+	}
+}
+
+func TestAnonymizePathsHeader(t *testing.T) {
+	h := &PatchHeader{
+		Title:        "Fix the parser",
+		BodyAppendix: " src/main.go | 2 +-\n 1 file changed, 1 insertion(+), 1 deletion(-)\n",
+	}
+	files := []*File{
+		{OldName: "src/main.go", NewName: "src/main.go", PatchHeader: h},
+		{OldName: "src/util.go", NewName: "src/util.go", PatchHeader: h},
+	}
+
+	anon := AnonymizePaths(files, AnonymizeOptions{Key: []byte("secret")})
+	if anon[0].PatchHeader == h || anon[0].PatchHeader != anon[1].PatchHeader {
+		t.Fatalf("files do not share a copy of the header: %p, %p", anon[0].PatchHeader, anon[1].PatchHeader)
+	}
+	if anon[0].PatchHeader.BodyAppendix != "" || anon[0].PatchHeader.Title != h.Title {
+		t.Errorf("incorrect header: %+v", anon[0].PatchHeader)
+	}
+	if h.BodyAppendix == "" {
+		t.Errorf("original header was modified: %+v", h)
+	}
+}
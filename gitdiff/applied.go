@@ -0,0 +1,99 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// AlreadyApplied returns true if src, the current content of the file,
+// already matches the result of applying f, like the "patch already applied"
+// check of git apply. Automation can use it to skip patches that were
+// applied before instead of reporting a conflict.
+//
+// For text files, the patch is already applied if it does not apply to src
+// but the reverse of the patch does. For binary files, the hash of src must
+// match the new object ID of the file or, if the patch does not include
+// object IDs, src must match the literal content of the patch. Files without
+// content changes, such as pure renames, are never reported as applied. A
+// deleted file is applied if src is empty.
+func (f *File) AlreadyApplied(src io.ReaderAt) (bool, error) {
+	if f.ParentCount > 0 {
+		return false, fmt.Errorf("gitdiff: %s: cannot check if a combined diff is applied", fileName(f))
+	}
+
+	if f.IsDelete {
+		return isLen(src, 0)
+	}
+
+	if f.IsBinary {
+		return binaryApplied(f, src)
+	}
+
+	if len(f.TextFragments) == 0 {
+		return false, nil
+	}
+
+	applies := func(f *File) (bool, error) {
+		err := Apply(io.Discard, src, f)
+		if errors.Is(err, &Conflict{}) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	if ok, err := applies(f); ok || err != nil {
+		return false, err
+	}
+
+	rf := *f
+	rf.TextFragments = make([]*TextFragment, len(f.TextFragments))
+	for i, frag := range f.TextFragments {
+		rf.TextFragments[i] = reverseTextFragment(frag)
+	}
+	return applies(&rf)
+}
+
+func binaryApplied(f *File, src io.ReaderAt) (bool, error) {
+	data, err := io.ReadAll(io.NewSectionReader(src, 0, math.MaxInt64))
+	if err != nil {
+		return false, err
+	}
+
+	if f.NewOIDPrefix != "" && strings.Trim(f.NewOIDPrefix, "0") != "" {
+		return strings.HasPrefix(blobOID(data), f.NewOIDPrefix), nil
+	}
+	if frag := f.BinaryFragment; frag != nil && frag.Method == BinaryPatchLiteral {
+		return bytes.Equal(data, frag.Data), nil
+	}
+	return false, fmt.Errorf("gitdiff: %s: cannot check if a binary delta is applied without object IDs", fileName(f))
+}
+
+// reverseTextFragment returns a fragment that undoes the changes of f.
+func reverseTextFragment(f *TextFragment) *TextFragment {
+	r := &TextFragment{
+		Comment:         f.Comment,
+		OldPosition:     f.NewPosition,
+		OldLines:        f.NewLines,
+		NewPosition:     f.OldPosition,
+		NewLines:        f.OldLines,
+		LinesAdded:      f.LinesDeleted,
+		LinesDeleted:    f.LinesAdded,
+		LeadingContext:  f.LeadingContext,
+		TrailingContext: f.TrailingContext,
+		Lines:           make([]Line, len(f.Lines)),
+	}
+	for i, line := range f.Lines {
+		switch line.Op {
+		case OpAdd:
+			line.Op = OpDelete
+		case OpDelete:
+			line.Op = OpAdd
+		}
+		r.Lines[i] = line
+	}
+	return r
+}
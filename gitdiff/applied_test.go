@@ -0,0 +1,164 @@
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAlreadyApplied(t *testing.T) {
+	const textPatch = `diff --git a/file.txt b/file.txt
+index 1c23fcc..40a1f4a 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ line 1
+-line 2
++line two
+ line 3
+`
+	const newPatch = `diff --git a/file.txt b/file.txt
+new file mode 100644
+index 0000000..40a1f4a
+--- /dev/null
++++ b/file.txt
+@@ -0,0 +1,2 @@
++line 1
++line 2
+`
+	const deletePatch = `diff --git a/file.txt b/file.txt
+deleted file mode 100644
+index 40a1f4a..0000000
+--- a/file.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-line 1
+-line 2
+`
+
+	tests := map[string]struct {
+		Patch  string
+		File   *File
+		Src    string
+		Output bool
+		Err    interface{}
+	}{
+		"textApplied": {
+			Patch:  textPatch,
+			Src:    "line 1\nline two\nline 3\n",
+			Output: true,
+		},
+		"textNotApplied": {
+			Patch:  textPatch,
+			Src:    "line 1\nline 2\nline 3\n",
+			Output: false,
+		},
+		"textConflict": {
+			Patch:  textPatch,
+			Src:    "line 1\nline 2.5\nline 3\n",
+			Output: false,
+		},
+		"textShort": {
+			Patch:  textPatch,
+			Src:    "line 1\n",
+			Output: false,
+		},
+		"newApplied": {
+			Patch:  newPatch,
+			Src:    "line 1\nline 2\n",
+			Output: true,
+		},
+		"newNotApplied": {
+			Patch:  newPatch,
+			Src:    "",
+			Output: false,
+		},
+		"newExtraContent": {
+			Patch:  newPatch,
+			Src:    "line 1\nline 2\nline 3\n",
+			Output: false,
+		},
+		"deleteApplied": {
+			Patch:  deletePatch,
+			Src:    "",
+			Output: true,
+		},
+		"deleteNotApplied": {
+			Patch:  deletePatch,
+			Src:    "line 1\nline 2\n",
+			Output: false,
+		},
+		"modeOnly": {
+			Patch: `diff --git a/file.txt b/file.txt
+old mode 100644
+new mode 100755
+`,
+			Src:    "line 1\n",
+			Output: false,
+		},
+		"binaryOID": {
+			File: &File{
+				NewName:      "file.bin",
+				IsBinary:     true,
+				NewOIDPrefix: blobOID([]byte("\x00\x01\x02"))[:7],
+			},
+			Src:    "\x00\x01\x02",
+			Output: true,
+		},
+		"binaryOIDMismatch": {
+			File: &File{
+				NewName:      "file.bin",
+				IsBinary:     true,
+				NewOIDPrefix: blobOID([]byte("\x00\x01\x02"))[:7],
+			},
+			Src:    "\x00\x01",
+			Output: false,
+		},
+		"binaryLiteral": {
+			File: &File{
+				NewName:        "file.bin",
+				IsBinary:       true,
+				BinaryFragment: &BinaryFragment{Method: BinaryPatchLiteral, Size: 3, Data: []byte("\x00\x01\x02")},
+			},
+			Src:    "\x00\x01\x02",
+			Output: true,
+		},
+		"binaryDeltaWithoutOID": {
+			File: &File{
+				NewName:        "file.bin",
+				IsBinary:       true,
+				BinaryFragment: &BinaryFragment{Method: BinaryPatchDelta, Size: 3, Data: []byte{0x03, 0x03, 0x03, 0, 1, 2}},
+			},
+			Src: "\x00\x01\x02",
+			Err: "without object IDs",
+		},
+		"combined": {
+			File: &File{NewName: "file.txt", ParentCount: 2},
+			Err:  "combined diff",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := test.File
+			if f == nil {
+				files, _, err := ParseAll(strings.NewReader(test.Patch))
+				if err != nil {
+					t.Fatalf("unexpected error parsing patch: %v", err)
+				}
+				f = files[0]
+			}
+
+			applied, err := f.AlreadyApplied(strings.NewReader(test.Src))
+			if test.Err != nil {
+				assertError(t, test.Err, err, "checking patch")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error checking patch: %v", err)
+			}
+			if applied != test.Output {
+				t.Errorf("incorrect result: expected %t, actual %t", test.Output, applied)
+			}
+		})
+	}
+}
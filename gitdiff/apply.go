@@ -1,6 +1,7 @@
 package gitdiff
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -17,7 +18,27 @@ import (
 //	       // handle conflict
 //     }
 //
+// Conflicts returned by ApplyThreeWay also describe the conflicting changes.
 type Conflict struct {
+	// Path is the name of the file with the conflict.
+	Path string
+
+	// Ours, Theirs, and Base are the conflicting lines from the current
+	// content, from the result of the patch, and from the content the patch
+	// was created from.
+	Ours   []string
+	Theirs []string
+	Base   []string
+
+	// NewRange is the location of the conflict, including the conflict
+	// markers, in the merged content.
+	NewRange LineRange
+
+	// Resolved is true if the ConflictResolver of the ApplyOptions resolved
+	// the conflict. NewRange then covers the resolved lines, which replace
+	// the conflict markers.
+	Resolved bool
+
 	msg string
 }
 
@@ -43,6 +64,9 @@ type ApplyError struct {
 	Fragment int
 	// FragmentLine is the one-indexed line number in the fragment
 	FragmentLine int
+	// Excerpt shows the fragment lines around FragmentLine if the Applier
+	// has an ErrorContext. It is included in the error message.
+	Excerpt string
 
 	err error
 }
@@ -53,6 +77,9 @@ func (e *ApplyError) Unwrap() error {
 }
 
 func (e *ApplyError) Error() string {
+	if e.Excerpt != "" {
+		return fmt.Sprintf("%v\n%s", e.err, e.Excerpt)
+	}
 	return fmt.Sprintf("%v", e.err)
 }
 
@@ -91,6 +118,10 @@ var (
 	errApplyInProgress = errors.New("gitdiff: incompatible apply in progress")
 )
 
+// ErrLimitExceeded matches errors returned when applying a patch would
+// exceed one of the limits in ApplyOptions. Use errors.Is to test for it.
+var ErrLimitExceeded = errors.New("gitdiff: apply limit exceeded")
+
 const (
 	applyInitial = iota
 	applyText
@@ -104,6 +135,31 @@ func Apply(dst io.Writer, src io.ReaderAt, f *File) error {
 	return NewApplier(src).ApplyFile(dst, f)
 }
 
+// ApplyParent applies the changes between one parent of a combined diff and
+// the result, writing the result to dst. The src must contain the content of
+// the file in that parent. Parents are numbered from zero in the order they
+// appear in the fragment headers. A file that is not from a combined diff has
+// a single parent, so ApplyParent with parent 0 is equivalent to Apply.
+func (f *File) ApplyParent(dst io.Writer, src io.ReaderAt, parent int) error {
+	if f.ParentCount == 0 {
+		if parent != 0 {
+			return applyError(fmt.Errorf("invalid parent %d: file has 1 parent", parent))
+		}
+		return Apply(dst, src, f)
+	}
+	if parent < 0 || parent >= f.ParentCount {
+		return applyError(fmt.Errorf("invalid parent %d: file has %d parents", parent, f.ParentCount))
+	}
+
+	pf := *f
+	pf.TextFragments = nil
+	pf.ParentCount, pf.CombinedFragments = 0, nil
+	for _, frags := range f.CombinedFragments {
+		pf.TextFragments = append(pf.TextFragments, frags[parent])
+	}
+	return Apply(dst, src, &pf)
+}
+
 // Applier applies changes described in fragments to source data. If changes
 // are described in multiple fragments, those fragments must be applied in
 // order, usually by calling ApplyFile.
@@ -125,12 +181,118 @@ type Applier struct {
 	lineSrc   LineReaderAt
 	nextLine  int64
 	applyType int
+
+	// atEOF is true after a fragment applies at the end of the source with
+	// AnchorTail, when nextLine is not known
+	atEOF bool
+
+	opts   ApplyOptions
+	offset int64
+	index  *LineIndex
+}
+
+// ApplyOptions configures how an Applier finds the source lines that match a
+// text fragment. The zero value is "strict" mode.
+type ApplyOptions struct {
+	// Matcher decides if a fragment matches the source lines at a position.
+	// If nil, ExactMatcher is used.
+	Matcher Matcher
+
+	// MaxOffset is the maximum number of lines between the position recorded
+	// in a fragment and the position where the Applier may apply it. If
+	// zero, fragments only apply at their recorded positions. If negative,
+	// the Applier searches all positions after the previous fragment.
+	//
+	// Once a fragment applies at an offset, the Applier starts searching for
+	// later fragments at the same offset.
+	MaxOffset int64
+
+	// UnidiffZero allows a fragment without context at position 0 to insert
+	// lines at the start of a non-empty source, like the --unidiff-zero
+	// option of git apply. Patches generated with no context, such as by git
+	// diff -U0, use these fragments for lines added at the start of a file;
+	// otherwise, they only create new files. With UnidiffZero, ApplyFile
+	// checks that the source of a new file is empty instead.
+	UnidiffZero bool
+
+	// IndexSource enables a pre-indexing pass over the source the first time
+	// a fragment does not match at its recorded position. With the index,
+	// the Applier only tests positions where the fragment could match
+	// exactly instead of scanning every position, which is much faster for
+	// large sources and badly outdated positions. The index is only used
+	// when MaxOffset is non-zero and with the default exact matching.
+	IndexSource bool
+
+	// AnchorTail applies fragments that must end at the end of the source,
+	// which have leading context but no trailing context, by reading the
+	// source backward from its end. The lines before the fragment are then
+	// copied without being split into lines, which is much faster when a
+	// fragment changes the end of a large file. It requires a source that
+	// implements TailLineReaderAt or has a known size, such as a
+	// *bytes.Reader or an *os.File for a regular file. If the end of the
+	// source does not match, the fragment is applied as usual. Because the
+	// line where the fragment starts is not known, no fragments can follow
+	// it and ApplyPartial ignores this option.
+	AnchorTail bool
+
+	// AllowContentOmitted allows ApplyFile to apply deletions of files with
+	// ContentOmitted set. Because the patch does not include the old content,
+	// the source is not read or verified and the result is always empty. If
+	// false, applying these files fails.
+	AllowContentOmitted bool
+
+	// Filters transform the content of files applied with ApplyFile. Before
+	// applying, the Clean method of each filter is called in order on the
+	// source, so fragments match the cleaned content. After applying, the
+	// Smudge method of each filter is called in reverse order on the result.
+	Filters []Filter
+
+	// ErrorContext is the number of fragment lines before and after the line
+	// of an error that are included in the error as an excerpt. If zero,
+	// errors do not include an excerpt.
+	ErrorContext int
+
+	// ConflictStyle selects the conflict markers written by ApplyThreeWay.
+	ConflictStyle ConflictStyle
+
+	// ConflictResolver, if set, resolves the conflicts found by
+	// ApplyThreeWay instead of writing conflict markers. Conflicts that it
+	// does not resolve are written with markers as usual.
+	ConflictResolver ConflictResolver
+
+	// MaxFileBytes is the maximum size of the result of applying a file with
+	// ApplyFile. MaxTotalBytes is the maximum total size of the results of
+	// all files and MaxFiles is the maximum number of files changed by a
+	// patch applied with ApplyTree, BuildTreeWithOptions, or AmApply, which
+	// applies the limits to each patch. If a limit is exceeded, applying fails
+	// with an error matching ErrLimitExceeded. Limits of zero or less are
+	// not enforced. Set limits when applying untrusted patches, which can
+	// otherwise produce enormous output from small patches, such as with
+	// repeated copies or compressed binary literals.
+	MaxFileBytes  int64
+	MaxTotalBytes int64
+	MaxFiles      int
+
+	// Attributes, if non-nil, provides the Git attributes of the files
+	// applied with ApplyFile and adds filters for the attributes of text
+	// files after any other filters. For files with the text or eol
+	// attributes, CRLF line endings are converted to LF before applying,
+	// matching the content Git stores in the repository, and if the eol
+	// attribute is "crlf", LF line endings in the result are converted to
+	// CRLF. For files with the ident attribute, IdentFilter is used.
+	Attributes *Attributes
 }
 
 // NewApplier creates an Applier that reads data from src. If src is a
 // LineReaderAt, it is used directly to apply text fragments.
 func NewApplier(src io.ReaderAt) *Applier {
-	a := new(Applier)
+	return NewApplierWithOptions(src, ApplyOptions{})
+}
+
+// NewApplierWithOptions creates an Applier that reads data from src and uses
+// the given options to apply text fragments.
+func NewApplierWithOptions(src io.ReaderAt, opts ApplyOptions) *Applier {
+	a := &Applier{opts: opts}
 	a.Reset(src)
 	return a
 }
@@ -139,6 +301,7 @@ func NewApplier(src io.ReaderAt) *Applier {
 // existing source is reused.
 func (a *Applier) Reset(src io.ReaderAt) {
 	if src != nil {
+		a.index = nil
 		a.src = src
 		if lineSrc, ok := src.(LineReaderAt); ok {
 			a.lineSrc = lineSrc
@@ -147,6 +310,8 @@ func (a *Applier) Reset(src io.ReaderAt) {
 		}
 	}
 	a.nextLine = 0
+	a.offset = 0
+	a.atEOF = false
 	a.applyType = applyInitial
 }
 
@@ -165,6 +330,34 @@ func (a *Applier) ApplyFile(dst io.Writer, f *File) error {
 		return applyError(errors.New("text file contains binary fragment"))
 	}
 
+	if a.opts.MaxFileBytes > 0 {
+		dst = &limitWriter{w: dst, max: a.opts.MaxFileBytes}
+	}
+
+	if f.IsNew && a.opts.UnidiffZero {
+		ok, err := isLen(a.src, 0)
+		if err != nil {
+			return applyError(err)
+		}
+		if !ok {
+			return applyError(&Conflict{msg: "cannot create new file from non-empty src"})
+		}
+	}
+
+	if f.ContentOmitted {
+		if !f.IsDelete {
+			return applyError(errors.New("cannot apply file: content omitted from patch"))
+		}
+		if !a.opts.AllowContentOmitted {
+			return applyError(errors.New("cannot verify deleted file: content omitted from patch"))
+		}
+		return nil
+	}
+
+	if name, filters := a.filters(f); len(filters) > 0 {
+		return a.applyFiltered(dst, f, name, filters)
+	}
+
 	switch {
 	case f.BinaryFragment != nil:
 		return a.ApplyBinaryFragment(dst, f.BinaryFragment)
@@ -191,6 +384,74 @@ func (a *Applier) ApplyFile(dst io.Writer, f *File) error {
 	return applyError(a.Flush(dst))
 }
 
+// limitWriter writes to w until max bytes are written, then returns an error
+// matching ErrLimitExceeded instead of writing more.
+type limitWriter struct {
+	w       io.Writer
+	max     int64
+	written int64
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if lw.written+int64(len(p)) > lw.max {
+		return 0, fmt.Errorf("%w: result is larger than %d bytes", ErrLimitExceeded, lw.max)
+	}
+	n, err := lw.w.Write(p)
+	lw.written += int64(n)
+	return n, err
+}
+
+// filters returns the name of f and the filters that apply to it, including
+// those selected by its attributes.
+func (a *Applier) filters(f *File) (string, []Filter) {
+	name := f.NewName
+	if f.IsDelete {
+		name = f.OldName
+	}
+
+	filters := a.opts.Filters
+	if a.opts.Attributes != nil && !f.IsBinary {
+		attrFilters := attributeFilters(a.opts.Attributes.Lookup(name))
+		filters = append(filters[:len(filters):len(filters)], attrFilters...)
+	}
+	return name, filters
+}
+
+// applyFiltered cleans the source, applies f to the result, and then smudges
+// the result of the apply.
+func (a *Applier) applyFiltered(dst io.Writer, f *File, name string, filters []Filter) error {
+	var src bytes.Buffer
+	if _, err := copyFrom(&src, a.src, 0); err != nil {
+		return applyError(err)
+	}
+
+	data := src.Bytes()
+	for _, filter := range filters {
+		var err error
+		if data, err = filter.Clean(name, data); err != nil {
+			return applyError(fmt.Errorf("clean: %v", err))
+		}
+	}
+
+	opts := a.opts
+	opts.Filters, opts.Attributes = nil, nil
+
+	var out bytes.Buffer
+	if err := NewApplierWithOptions(bytes.NewReader(data), opts).ApplyFile(&out, f); err != nil {
+		return err
+	}
+
+	result := out.Bytes()
+	for i := len(filters) - 1; i >= 0; i-- {
+		var err error
+		if result, err = filters[i].Smudge(name, result); err != nil {
+			return applyError(fmt.Errorf("smudge: %v", err))
+		}
+	}
+	_, err := dst.Write(result)
+	return applyError(err)
+}
+
 // ApplyTextFragment applies the changes in the fragment f and writes unwritten
 // data before the start of the fragment and the result to dst. If multiple
 // text fragments apply to the same source, ApplyTextFragment must be called in
@@ -207,28 +468,49 @@ func (a *Applier) ApplyTextFragment(dst io.Writer, f *TextFragment) error {
 		return applyError(err)
 	}
 
-	// lines are 0-indexed, positions are 1-indexed (but new files have position = 0)
-	fragStart := f.OldPosition - 1
-	if fragStart < 0 {
-		fragStart = 0
+	if a.atEOF {
+		return applyError(&Conflict{msg: "fragment overlaps with an applied fragment"})
 	}
-	fragEnd := fragStart + f.OldLines
 
-	start := a.nextLine
-	if fragStart < start {
-		return applyError(&Conflict{"fragment overlaps with an applied fragment"})
-	}
+	// lines are 0-indexed, positions are 1-indexed (but new files have
+	// position = 0 and fragments without old lines, as generated without
+	// context, use the position of the line before them)
+	fragStart := fragmentOldStart(f)
 
-	if f.OldPosition == 0 {
+	if f.OldPosition == 0 && !a.opts.UnidiffZero {
 		ok, err := isLen(a.src, 0)
 		if err != nil {
 			return applyError(err)
 		}
 		if !ok {
-			return applyError(&Conflict{"cannot create new file from non-empty src"})
+			return applyError(&Conflict{msg: "cannot create new file from non-empty src"})
+		}
+	}
+
+	if a.opts.AnchorTail && isTailFragment(f) && !a.opts.UnidiffZero {
+		ok, err := a.applyTailFragment(dst, f)
+		if err != nil || ok {
+			return applyError(err)
 		}
 	}
 
+	// if the fragment matches somewhere, skip line-by-line comparisons;
+	// otherwise, apply at the recorded position to report the conflict
+	pos, matched, err := a.locateTextFragment(f, fragStart)
+	if err != nil {
+		return applyError(err)
+	}
+	if matched {
+		a.offset = pos - fragStart
+		fragStart = pos
+	}
+	fragEnd := fragStart + f.OldLines
+
+	start := a.nextLine
+	if fragStart < start {
+		return applyError(&Conflict{msg: "fragment overlaps with an applied fragment"})
+	}
+
 	preimage := make([][]byte, fragEnd-start)
 	n, err := a.lineSrc.ReadLinesAt(preimage, start)
 	if err != nil {
@@ -247,9 +529,9 @@ func (a *Applier) ApplyTextFragment(dst io.Writer, f *TextFragment) error {
 	// apply the changes in the fragment
 	used := int64(0)
 	for i, line := range f.Lines {
-		if err := applyTextLine(dst, line, preimage, used); err != nil {
+		if err := applyTextLine(dst, line, preimage, used, matched); err != nil {
 			a.nextLine = fragStart + used
-			return applyError(err, lineNum(a.nextLine), fragLineNum(i))
+			return a.excerpt(applyError(err, lineNum(a.nextLine), fragLineNum(i)), f)
 		}
 		if line.Old() {
 			used++
@@ -265,30 +547,219 @@ func (a *Applier) ApplyTextFragment(dst io.Writer, f *TextFragment) error {
 			return applyError(err, lineNum(a.nextLine))
 		}
 		if n > 0 {
-			return applyError(&Conflict{"src still has content after full delete"}, lineNum(a.nextLine))
+			return applyError(&Conflict{msg: "src still has content after full delete"}, lineNum(a.nextLine))
 		}
 	}
 
 	return nil
 }
 
-func applyTextLine(dst io.Writer, line Line, preimage [][]byte, i int64) (err error) {
-	if line.Old() && string(preimage[i]) != line.Line {
-		return &Conflict{"fragment line does not match src line"}
+// isTailFragment returns true if f must end at the end of the source, like
+// git apply does for fragments with leading but no trailing context.
+func isTailFragment(f *TextFragment) bool {
+	return f.LeadingContext > 0 && f.TrailingContext == 0 && f.OldLines > 0
+}
+
+// applyTailFragment applies f at the end of the source if the source supports
+// reading lines from the end and its last lines match f. It returns false
+// without writing anything otherwise.
+func (a *Applier) applyTailFragment(dst io.Writer, f *TextFragment) (bool, error) {
+	tail, ok := a.lineSrc.(TailLineReaderAt)
+	if !ok {
+		return false, nil
+	}
+	if ts, ok := tail.(interface{ tailSupported() bool }); ok && !ts.tailSupported() {
+		return false, nil
+	}
+
+	preimage := make([][]byte, f.OldLines)
+	n, offset, err := tail.ReadTailLines(preimage)
+	if err != nil {
+		return false, err
+	}
+	if n < len(preimage) {
+		return false, nil
+	}
+
+	used := 0
+	for _, line := range f.Lines {
+		if line.Old() {
+			if string(preimage[used]) != line.Line {
+				return false, nil
+			}
+			used++
+		}
+	}
+
+	start, err := tail.LineOffset(a.nextLine)
+	if err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	if start > offset {
+		return false, nil
 	}
-	if line.New() {
+
+	if _, err := io.Copy(dst, io.NewSectionReader(a.src, start, offset-start)); err != nil {
+		return false, err
+	}
+	used = 0
+	for _, line := range f.Lines {
+		if err := applyTextLine(dst, line, preimage, int64(used), true); err != nil {
+			return false, err
+		}
+		if line.Old() {
+			used++
+		}
+	}
+	a.atEOF = true
+	return true, nil
+}
+
+// excerpt adds an excerpt of the lines of f around the line of err if the
+// Applier has an error context.
+func (a *Applier) excerpt(err error, f *TextFragment) error {
+	e, ok := err.(*ApplyError)
+	if !ok || a.opts.ErrorContext <= 0 || e.FragmentLine == 0 {
+		return err
+	}
+
+	lines := make([]string, len(f.Lines))
+	for i, line := range f.Lines {
+		lines[i] = line.String()
+	}
+	e.Excerpt = formatExcerpt(lines, 1, int64(e.FragmentLine), a.opts.ErrorContext)
+	return e
+}
+
+func applyTextLine(dst io.Writer, line Line, preimage [][]byte, i int64, matched bool) (err error) {
+	if line.Old() && !matched && string(preimage[i]) != line.Line {
+		return &Conflict{msg: "fragment line does not match src line"}
+	}
+	switch {
+	case line.Op == OpContext:
+		_, err = dst.Write(preimage[i])
+	case line.New():
 		_, err = io.WriteString(dst, line.Line)
 	}
 	return err
 }
 
+// locateTextFragment finds the position where f matches the source using the
+// Matcher and search distance from the options. It returns false if the
+// options require strict matching or if there is no matching position.
+func (a *Applier) locateTextFragment(f *TextFragment, fragStart int64) (int64, bool, error) {
+	if a.opts.Matcher == nil && a.opts.MaxOffset == 0 {
+		return 0, false, nil
+	}
+
+	m := a.opts.Matcher
+	if m == nil {
+		m = ExactMatcher
+	}
+
+	hint := fragStart + a.offset
+	if hint < a.nextLine {
+		hint = a.nextLine
+	}
+
+	lines := make([][]byte, f.OldLines)
+	tryMatch := func(pos int64) (ok bool, eof bool, err error) {
+		n, err := a.lineSrc.ReadLinesAt(lines, pos)
+		if err != nil && err != io.EOF {
+			return false, false, err
+		}
+		if n < len(lines) {
+			return false, true, nil
+		}
+		return m.Match(f, lines), false, nil
+	}
+
+	// without context, a fragment could match at many unrelated positions,
+	// so it only applies at its position adjusted by the current offset
+	if f.LeadingContext == 0 && f.TrailingContext == 0 {
+		ok, _, err := tryMatch(hint)
+		return hint, ok, err
+	}
+
+	if a.opts.IndexSource && a.opts.Matcher == nil {
+		if ok, _, err := tryMatch(hint); err != nil || ok {
+			return hint, ok, err
+		}
+		return a.locateIndexed(f, hint, tryMatch)
+	}
+
+	forward, backward := true, true
+	for d := int64(0); forward || backward; d++ {
+		if a.opts.MaxOffset >= 0 && d > a.opts.MaxOffset {
+			break
+		}
+
+		if forward {
+			ok, eof, err := tryMatch(hint + d)
+			if err != nil {
+				return 0, false, err
+			}
+			if ok {
+				return hint + d, true, nil
+			}
+			forward = !eof
+		}
+
+		if backward && d > 0 {
+			if pos := hint - d; pos >= a.nextLine {
+				ok, _, err := tryMatch(pos)
+				if err != nil {
+					return 0, false, err
+				}
+				if ok {
+					return pos, true, nil
+				}
+			} else {
+				backward = false
+			}
+		}
+	}
+	return 0, false, nil
+}
+
+// locateIndexed finds the position where f matches the source by testing
+// the candidates from the source index that are closest to hint.
+func (a *Applier) locateIndexed(f *TextFragment, hint int64, tryMatch func(int64) (bool, bool, error)) (int64, bool, error) {
+	if a.index == nil {
+		index, err := NewLineIndex(a.lineSrc)
+		if err != nil {
+			return 0, false, err
+		}
+		a.index = index
+	}
+
+	for _, pos := range nearest(a.index.Candidates(f), hint) {
+		if pos < a.nextLine {
+			continue
+		}
+		if d := pos - hint; a.opts.MaxOffset >= 0 && (d > a.opts.MaxOffset || -d > a.opts.MaxOffset) {
+			continue
+		}
+		ok, _, err := tryMatch(pos)
+		if err != nil || ok {
+			return pos, ok, err
+		}
+	}
+	return 0, false, nil
+}
+
 // Flush writes any data following the last applied fragment to dst.
 func (a *Applier) Flush(dst io.Writer) (err error) {
 	switch a.applyType {
 	case applyInitial:
 		_, err = copyFrom(dst, a.src, 0)
 	case applyText:
-		_, err = copyLinesFrom(dst, a.lineSrc, a.nextLine)
+		if !a.atEOF {
+			_, err = copyLinesFrom(dst, a.lineSrc, a.nextLine)
+		}
 	case applyBinary:
 		// nothing to flush, binary apply "consumes" full source
 	}
@@ -445,7 +916,7 @@ func checkBinarySrcSize(r io.ReaderAt, size int64) error {
 		return err
 	}
 	if !ok {
-		return &Conflict{"fragment src size does not match actual src size"}
+		return &Conflict{msg: "fragment src size does not match actual src size"}
 	}
 	return nil
 }
@@ -206,12 +206,43 @@ func TestApplyFile(t *testing.T) {
 			},
 			Err: &Conflict{},
 		},
+		"textErrorIrreversibleDelete": {
+			Files: applyFiles{
+				Src:   "file_text.src",
+				Patch: "file_text_irreversible_delete.patch",
+			},
+			Err: "content omitted",
+		},
+		"textIrreversibleDelete": {
+			Files: applyFiles{
+				Src:   "file_text.src",
+				Patch: "file_text_irreversible_delete.patch",
+				Out:   "file_text_delete.out",
+			},
+			Options: ApplyOptions{AllowContentOmitted: true},
+		},
 		"binaryModify": {
 			Files: getApplyFiles("file_bin_modify"),
 		},
 		"modeChange": {
 			Files: getApplyFiles("file_mode_change"),
 		},
+		"textErrorMaxFileBytes": {
+			Files: applyFiles{
+				Src:   "file_text.src",
+				Patch: "file_text_modify.patch",
+			},
+			Options: ApplyOptions{MaxFileBytes: 16},
+			Err:     ErrLimitExceeded,
+		},
+		"binaryErrorMaxFileBytes": {
+			Files: applyFiles{
+				Src:   "file_bin_modify.src",
+				Patch: "file_bin_modify.patch",
+			},
+			Options: ApplyOptions{MaxFileBytes: 16},
+			Err:     ErrLimitExceeded,
+		},
 	}
 
 	for name, test := range tests {
@@ -224,8 +255,9 @@ func TestApplyFile(t *testing.T) {
 }
 
 type applyTest struct {
-	Files applyFiles
-	Err   interface{}
+	Files   applyFiles
+	Options ApplyOptions
+	Err     interface{}
 }
 
 func (at applyTest) run(t *testing.T, apply func(io.Writer, *Applier, *File) error) {
@@ -243,7 +275,7 @@ func (at applyTest) run(t *testing.T, apply func(io.Writer, *Applier, *File) err
 		t.Fatalf("patch should contain exactly one file, but it has %d", len(files))
 	}
 
-	applier := NewApplier(bytes.NewReader(src))
+	applier := NewApplierWithOptions(bytes.NewReader(src), at.Options)
 
 	var dst bytes.Buffer
 	err = apply(&dst, applier, files[0])
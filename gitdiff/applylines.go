@@ -0,0 +1,82 @@
+package gitdiff
+
+import (
+	"errors"
+	"io"
+)
+
+// lineSlice is a LineReaderAt for lines that are already split.
+type lineSlice [][]byte
+
+func (s lineSlice) ReadLinesAt(lines [][]byte, offset int64) (n int, err error) {
+	if offset < 0 {
+		return 0, errors.New("ReadLinesAt: negative offset")
+	}
+	if offset >= int64(len(s)) {
+		if len(lines) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n = copy(lines, s[offset:])
+	if n < len(lines) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// ApplyFragmentLines applies the text fragment f to old, the lines of a file
+// including their newlines, and returns the lines of the result. It finds the
+// position of the fragment and reports conflicts like ApplyTextFragment with
+// the same options, but works on lines in memory for callers, such as
+// editors, that manage the content of files themselves. The result shares
+// the unchanged lines with old and old is not modified.
+func ApplyFragmentLines(old [][]byte, f *TextFragment, opts ApplyOptions) ([][]byte, error) {
+	if err := f.Validate(); err != nil {
+		return nil, applyError(err)
+	}
+
+	fragStart := fragmentOldStart(f)
+	if f.OldPosition == 0 && !opts.UnidiffZero && len(old) > 0 {
+		return nil, applyError(&Conflict{msg: "cannot create new file from non-empty src"})
+	}
+
+	a := &Applier{lineSrc: lineSlice(old), opts: opts}
+	pos, matched, err := a.locateTextFragment(f, fragStart)
+	if err != nil {
+		return nil, applyError(err)
+	}
+	if matched {
+		fragStart = pos
+	}
+	fragEnd := fragStart + f.OldLines
+	if fragEnd > int64(len(old)) {
+		return nil, applyError(io.ErrUnexpectedEOF, lineNum(len(old)))
+	}
+
+	preimage := old[fragStart:fragEnd]
+	result := make([][]byte, 0, int64(len(old))-f.OldLines+f.NewLines)
+	result = append(result, old[:fragStart]...)
+
+	used := int64(0)
+	for i, line := range f.Lines {
+		if line.Old() && !matched && string(preimage[used]) != line.Line {
+			err := &Conflict{msg: "fragment line does not match src line"}
+			return nil, a.excerpt(applyError(err, lineNum(fragStart+used), fragLineNum(i)), f)
+		}
+		switch {
+		case line.Op == OpContext:
+			result = append(result, preimage[used])
+		case line.New():
+			result = append(result, []byte(line.Line))
+		}
+		if line.Old() {
+			used++
+		}
+	}
+
+	if f.NewPosition == 0 && f.NewLines == 0 && fragEnd < int64(len(old)) {
+		return nil, applyError(&Conflict{msg: "src still has content after full delete"}, lineNum(fragEnd))
+	}
+	return append(result, old[fragEnd:]...), nil
+}
@@ -0,0 +1,120 @@
+package gitdiff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestApplyFragmentLines(t *testing.T) {
+	splitLines := func(s string) [][]byte {
+		var lines [][]byte
+		for _, line := range strings.SplitAfter(s, "\n") {
+			if line != "" {
+				lines = append(lines, []byte(line))
+			}
+		}
+		return lines
+	}
+
+	tests := map[string]struct {
+		Src      string
+		Fragment string
+		Opts     ApplyOptions
+		Output   string
+		Err      interface{}
+	}{
+		"middle": {
+			Src:      "a\nb\nc\nd\ne\n",
+			Fragment: "@@ -2,3 +2,3 @@\n b\n-c\n+C\n d\n",
+			Output:   "a\nb\nC\nd\ne\n",
+		},
+		"start": {
+			Src:      "a\nb\nc\n",
+			Fragment: "@@ -1,2 +1,3 @@\n+new\n a\n b\n",
+			Output:   "new\na\nb\nc\n",
+		},
+		"noFinalNewline": {
+			Src:      "a\nb",
+			Fragment: "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+			Output:   "a\nc",
+		},
+		"newFile": {
+			Src:      "",
+			Fragment: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+			Output:   "a\nb\n",
+		},
+		"deleteAll": {
+			Src:      "a\nb\n",
+			Fragment: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+			Output:   "",
+		},
+		"offset": {
+			Src:      "x\ny\na\nb\nc\n",
+			Fragment: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			Opts:     ApplyOptions{MaxOffset: -1},
+			Output:   "x\ny\na\nB\nc\n",
+		},
+		"conflict": {
+			Src:      "a\nb\nc\n",
+			Fragment: "@@ -1,3 +1,3 @@\n a\n-x\n+y\n c\n",
+			Err:      &Conflict{},
+		},
+		"pastEnd": {
+			Src:      "a\n",
+			Fragment: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			Err:      "unexpected EOF",
+		},
+		"newFileNotEmpty": {
+			Src:      "a\n",
+			Fragment: "@@ -0,0 +1 @@\n+b\n",
+			Err:      "non-empty src",
+		},
+		"deleteAllLeftovers": {
+			Src:      "a\nb\n",
+			Fragment: "@@ -1 +0,0 @@\n-a\n",
+			Err:      "still has content",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader("--- a/f\n+++ b/f\n" + test.Fragment))
+			if err != nil {
+				t.Fatalf("unexpected error parsing fragment: %v", err)
+			}
+			frag := files[0].TextFragments[0]
+
+			old := splitLines(test.Src)
+			oldCopy := splitLines(test.Src)
+
+			lines, err := ApplyFragmentLines(old, frag, test.Opts)
+			if test.Err != nil {
+				assertError(t, test.Err, err, "applying fragment")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error applying fragment: %v", err)
+			}
+			if out := string(bytes.Join(lines, nil)); out != test.Output {
+				t.Errorf("incorrect output\nexpected: %q\n  actual: %q", test.Output, out)
+			}
+			if !bytes.Equal(bytes.Join(old, nil), bytes.Join(oldCopy, nil)) {
+				t.Error("old lines were modified")
+			}
+
+			// the result must match applying the fragment from a reader
+			var dst bytes.Buffer
+			a := NewApplierWithOptions(strings.NewReader(test.Src), test.Opts)
+			if err := a.ApplyTextFragment(&dst, frag); err != nil {
+				t.Fatalf("unexpected error applying fragment with Applier: %v", err)
+			}
+			if err := a.Flush(&dst); err != nil {
+				t.Fatalf("unexpected error flushing Applier: %v", err)
+			}
+			if dst.String() != test.Output {
+				t.Errorf("output differs from Applier: %q", dst.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	attrSet   = "set"
+	attrUnset = "unset"
+)
+
+// AttributeSet contains the Git attributes that apply to a path. Set
+// attributes have the value "set", unset attributes (written as "-attr")
+// have the value "unset", and other attributes have the value assigned to
+// them. Unspecified attributes are not present.
+type AttributeSet map[string]string
+
+// IsSet returns true if the attribute is set.
+func (s AttributeSet) IsSet(name string) bool {
+	return s[name] == attrSet
+}
+
+// IsUnset returns true if the attribute is explicitly unset.
+func (s AttributeSet) IsUnset(name string) bool {
+	return s[name] == attrUnset
+}
+
+// Value returns the value of an attribute that is assigned a value. It
+// returns false if the attribute is set, unset, or unspecified.
+func (s AttributeSet) Value(name string) (string, bool) {
+	v, ok := s[name]
+	if !ok || v == attrSet || v == attrUnset {
+		return "", false
+	}
+	return v, true
+}
+
+// Attributes holds the rules from one or more .gitattributes files.
+//
+// Patterns follow the rules for .gitattributes files: patterns without a
+// slash match the name of a file in any directory below the file that defines
+// them, other patterns match paths relative to that directory, and "**"
+// matches any number of directories. Later rules override earlier rules and
+// rules from files in subdirectories override rules from their parents.
+// Macros defined with "[attr]" are expanded, including the built-in "binary"
+// macro, which is equivalent to "-diff -merge -text".
+type Attributes struct {
+	rules  []attrRule
+	macros map[string][]attrAssignment
+}
+
+type attrRule struct {
+	dir     string
+	pattern *regexp.Regexp
+	attrs   []attrAssignment
+}
+
+type attrAssignment struct {
+	name  string
+	value string // empty for unspecified
+}
+
+// ParseAttributes parses the content of a .gitattributes file at the root of
+// a repository.
+func ParseAttributes(r io.Reader) (*Attributes, error) {
+	a := newAttributes()
+	if err := a.parse("", r); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ReadAttributes reads every .gitattributes file in fsys.
+func ReadAttributes(fsys fs.FS) (*Attributes, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && path.Base(name) == ".gitattributes" {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gitdiff: reading attributes: %v", err)
+	}
+
+	// parents must come before their subdirectories
+	sort.SliceStable(names, func(i, j int) bool {
+		return strings.Count(names[i], "/") < strings.Count(names[j], "/")
+	})
+
+	a := newAttributes()
+	for _, name := range names {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("gitdiff: reading attributes: %v", err)
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		err = a.parse(dir, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func newAttributes() *Attributes {
+	return &Attributes{
+		macros: map[string][]attrAssignment{
+			"binary": {{"diff", attrUnset}, {"merge", attrUnset}, {"text", attrUnset}},
+		},
+	}
+}
+
+func (a *Attributes) parse(dir string, r io.Reader) error {
+	br := bufio.NewReader(r)
+	for lineno := 1; ; lineno++ {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if perr := a.parseLine(dir, line); perr != nil {
+			name := path.Join(dir, ".gitattributes")
+			return fmt.Errorf("gitdiff: %s:%d: %v", name, lineno, perr)
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func (a *Attributes) parseLine(dir, line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return nil
+	}
+
+	var pattern string
+	if line[0] == '"' {
+		name, n, err := parseQuotedName(line)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+		pattern, line = name, line[n:]
+	} else {
+		fields := strings.Fields(line)
+		pattern, line = fields[0], line[len(fields[0]):]
+	}
+
+	var attrs []attrAssignment
+	for _, field := range strings.Fields(line) {
+		attrs = append(attrs, parseAttrAssignment(field))
+	}
+
+	if strings.HasPrefix(pattern, "[attr]") {
+		a.macros[strings.TrimPrefix(pattern, "[attr]")] = attrs
+		return nil
+	}
+	if strings.HasPrefix(pattern, "!") {
+		return fmt.Errorf("negative patterns are not allowed: %s", pattern)
+	}
+	if strings.HasSuffix(pattern, "/") {
+		// patterns that only match directories never apply to files
+		return nil
+	}
+
+	re, err := attrPatternRegexp(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %s: %v", pattern, err)
+	}
+	a.rules = append(a.rules, attrRule{dir: dir, pattern: re, attrs: attrs})
+	return nil
+}
+
+func parseAttrAssignment(s string) attrAssignment {
+	switch {
+	case strings.HasPrefix(s, "-"):
+		return attrAssignment{s[1:], attrUnset}
+	case strings.HasPrefix(s, "!"):
+		return attrAssignment{s[1:], ""}
+	}
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		return attrAssignment{s[:i], s[i+1:]}
+	}
+	return attrAssignment{s, attrSet}
+}
+
+// attrPatternRegexp converts a .gitattributes pattern into a regular
+// expression that matches paths relative to the directory of the file.
+func attrPatternRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+
+	if strings.Contains(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		b.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			switch {
+			case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+				b.WriteString("(?:.*/)?")
+				i += 2
+			case pattern[i:] == "**" && i > 0 && pattern[i-1] == '/':
+				b.WriteString(".*")
+				i++
+			default:
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// Lookup returns the attributes that apply to the file at name, a
+// slash-separated path relative to the root of the repository. Lookup may be
+// called on a nil *Attributes, in which case it returns an empty set.
+func (a *Attributes) Lookup(name string) AttributeSet {
+	attrs := make(AttributeSet)
+	if a == nil {
+		return attrs
+	}
+
+	for _, rule := range a.rules {
+		rel := name
+		if rule.dir != "" {
+			if !strings.HasPrefix(name, rule.dir+"/") {
+				continue
+			}
+			rel = name[len(rule.dir)+1:]
+		}
+		if rule.pattern.MatchString(rel) {
+			a.assign(attrs, rule.attrs, 0)
+		}
+	}
+	return attrs
+}
+
+func (a *Attributes) assign(attrs AttributeSet, assignments []attrAssignment, depth int) {
+	for _, as := range assignments {
+		if as.value == "" {
+			delete(attrs, as.name)
+		} else {
+			attrs[as.name] = as.value
+		}
+		if macro, ok := a.macros[as.name]; ok && as.value == attrSet && depth < 10 {
+			a.assign(attrs, macro, depth+1)
+		}
+	}
+}
+
+// normalizesEOL returns true if Git converts CRLF line endings in data to LF
+// when storing a file with these attributes in a repository.
+func (s AttributeSet) normalizesEOL(data []byte) bool {
+	switch {
+	case s.IsUnset("text"):
+		return false
+	case s.IsSet("text"):
+		return true
+	}
+	if v, ok := s.Value("text"); ok && v == "auto" {
+		return !isBinary(data)
+	}
+	_, ok := s.Value("eol")
+	return ok
+}
+
+func toLF(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+func toCRLF(data []byte) []byte {
+	return bytes.ReplaceAll(toLF(data), []byte("\n"), []byte("\r\n"))
+}
@@ -0,0 +1,183 @@
+package gitdiff
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAttributesLookup(t *testing.T) {
+	attrs, err := ParseAttributes(strings.NewReader(`# comment
+*.txt text eol=crlf
+*.png binary
+/root.md diff=markdown
+docs/**/*.md -text
+**/gen/** linguist-generated
+"name with space.txt" -diff
+[attr]generated -diff merge=ours
+*.pb.go generated
+*.txt !eol
+special.txt eol=lf
+`))
+	if err != nil {
+		t.Fatalf("unexpected error parsing attributes: %v", err)
+	}
+
+	tests := map[string]AttributeSet{
+		"a.txt":               {"text": "set"},
+		"dir/special.txt":     {"text": "set", "eol": "lf"},
+		"img/logo.png":        {"binary": "set", "diff": "unset", "merge": "unset", "text": "unset"},
+		"root.md":             {"diff": "markdown"},
+		"sub/root.md":         {},
+		"docs/a/b/guide.md":   {"text": "unset"},
+		"docs/guide.md":       {"text": "unset"},
+		"src/gen/x/y.go":      {"linguist-generated": "set"},
+		"name with space.txt": {"text": "set", "diff": "unset"},
+		"api/service.pb.go":   {"generated": "set", "diff": "unset", "merge": "ours"},
+		"other/file.go":       {},
+	}
+
+	for name, expected := range tests {
+		t.Run(name, func(t *testing.T) {
+			if actual := attrs.Lookup(name); !reflect.DeepEqual(expected, actual) {
+				t.Errorf("incorrect attributes\nexpected: %v\n  actual: %v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestAttributeSet(t *testing.T) {
+	s := AttributeSet{"text": "set", "diff": "unset", "eol": "crlf"}
+
+	if !s.IsSet("text") || s.IsSet("diff") || s.IsSet("eol") {
+		t.Errorf("incorrect result from IsSet")
+	}
+	if !s.IsUnset("diff") || s.IsUnset("text") || s.IsUnset("merge") {
+		t.Errorf("incorrect result from IsUnset")
+	}
+	if v, ok := s.Value("eol"); !ok || v != "crlf" {
+		t.Errorf("incorrect value for eol: %q, %t", v, ok)
+	}
+	if _, ok := s.Value("text"); ok {
+		t.Errorf("set attribute has a value")
+	}
+}
+
+func TestReadAttributes(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitattributes":     {Data: []byte("*.c diff=cpp\n*.h text\n")},
+		"lib/.gitattributes": {Data: []byte("*.c -diff\n/local.h eol=crlf\n")},
+	}
+
+	attrs, err := ReadAttributes(fsys)
+	if err != nil {
+		t.Fatalf("unexpected error reading attributes: %v", err)
+	}
+
+	tests := map[string]AttributeSet{
+		"main.c":          {"diff": "cpp"},
+		"lib/util.c":      {"diff": "unset"},
+		"lib/local.h":     {"text": "set", "eol": "crlf"},
+		"lib/sub/local.h": {"text": "set"},
+	}
+	for name, expected := range tests {
+		if actual := attrs.Lookup(name); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s: incorrect attributes\nexpected: %v\n  actual: %v", name, expected, actual)
+		}
+	}
+
+	if _, err := ParseAttributes(strings.NewReader("!negated text\n")); err == nil {
+		t.Errorf("expected error parsing negative pattern, but got nil")
+	}
+}
+
+func TestDiffTreesAttributes(t *testing.T) {
+	attrs, err := ParseAttributes(strings.NewReader("*.lock -diff\n*.dat diff\n*.txt eol=crlf\n*.go diff=golang\n"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing attributes: %v", err)
+	}
+
+	oldFS := fstest.MapFS{
+		"deps.lock": {Data: []byte("a\n")},
+		"data.dat":  {Data: []byte("x\x00y\n")},
+		"crlf.txt":  {Data: []byte("one\r\ntwo\r\n")},
+		"same.txt":  {Data: []byte("one\ntwo\n")},
+		"main.go":   {Data: []byte("package main\n\nfunc main() {\n\ta := 1\n\tb := 2\n\tc := 3\n\td := 4\n}\n")},
+	}
+	newFS := fstest.MapFS{
+		"deps.lock": {Data: []byte("b\n")},
+		"data.dat":  {Data: []byte("x\x00z\n")},
+		"crlf.txt":  {Data: []byte("one\r\n2\r\n")},
+		"same.txt":  {Data: []byte("one\r\ntwo\r\n")},
+		"main.go":   {Data: []byte("package main\n\nfunc main() {\n\ta := 1\n\tb := 2\n\tc := 3\n\td := 5\n}\n")},
+	}
+
+	files, err := DiffTrees(oldFS, newFS, DiffOptions{
+		Context:    1,
+		Attributes: attrs,
+		Drivers: map[string]DiffDriver{
+			"golang": {FuncName: regexp.MustCompile(`^(func .*)\{`)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error diffing trees: %v", err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("incorrect number of files: expected 4, actual %d", len(files))
+	}
+	crlf, data, deps, main := files[0], files[1], files[2], files[3]
+
+	if crlf.IsBinary || len(crlf.TextFragments) != 1 || crlf.TextFragments[0].Lines[1].Line != "two\n" {
+		t.Errorf("line endings were not normalized: %+v", crlf.TextFragments)
+	}
+	if data.IsBinary || len(data.TextFragments) != 1 {
+		t.Errorf("file with diff attribute was not compared as text: %+v", data)
+	}
+	if !deps.IsBinary || len(deps.TextFragments) > 0 {
+		t.Errorf("file with -diff attribute was compared as text: %+v", deps)
+	}
+	if len(main.TextFragments) != 1 || main.TextFragments[0].Comment != "func main()" {
+		t.Errorf("incorrect function name comment: %+v", main.TextFragments)
+	}
+}
+
+func TestApplyAttributes(t *testing.T) {
+	attrs, err := ParseAttributes(strings.NewReader("*.txt eol=crlf\n"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing attributes: %v", err)
+	}
+
+	f := &File{
+		OldName: "file.txt",
+		NewName: "file.txt",
+		TextFragments: []*TextFragment{
+			{
+				OldPosition: 1, OldLines: 2,
+				NewPosition: 1, NewLines: 2,
+				LinesAdded: 1, LinesDeleted: 1,
+				LeadingContext: 1,
+				Lines: []Line{
+					{OpContext, "one\n"},
+					{OpDelete, "two\n"},
+					{OpAdd, "2\n"},
+				},
+			},
+		},
+	}
+
+	src := bytes.NewReader([]byte("one\r\ntwo\r\n"))
+	if err := Apply(&bytes.Buffer{}, src, f); err == nil {
+		t.Fatalf("expected error applying without attributes, but got nil")
+	}
+
+	var dst bytes.Buffer
+	if err := NewApplierWithOptions(src, ApplyOptions{Attributes: attrs}).ApplyFile(&dst, f); err != nil {
+		t.Fatalf("unexpected error applying with attributes: %v", err)
+	}
+	if expected := "one\r\n2\r\n"; dst.String() != expected {
+		t.Errorf("incorrect result: expected %q, actual %q", expected, dst.String())
+	}
+}
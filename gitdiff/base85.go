@@ -50,3 +50,30 @@ func base85Decode(dst, src []byte) error {
 	}
 	return nil
 }
+
+// base85Encode encodes src in Base85, writing the result to dst. It uses the
+// alphabet defined by base85.c in the Git source tree. If the length of src is
+// not a multiple of 4, it is padded with zero bytes. dst must contain at least
+// base85Len(len(src)) bytes.
+func base85Encode(dst, src []byte) {
+	var di int
+	for si := 0; si < len(src); si += 4 {
+		var v uint32
+		for j := 0; j < 4; j++ {
+			v <<= 8
+			if si+j < len(src) {
+				v |= uint32(src[si+j])
+			}
+		}
+		for j := 4; j >= 0; j-- {
+			dst[di+j] = b85Alpha[v%85]
+			v /= 85
+		}
+		di += 5
+	}
+}
+
+// base85Len returns the length of n bytes of Base85-encoded data.
+func base85Len(n int) int {
+	return (n + 3) / 4 * 5
+}
@@ -29,6 +29,9 @@ func (p *parser) ParseBinaryFragments(f *File) (n int, err error) {
 		return 0, err
 	}
 	if forward == nil {
+		if p.eof {
+			return 0, p.Truncated("missing data for binary patch")
+		}
 		return 0, p.Errorf(0, "missing data for binary patch")
 	}
 	if err := p.ParseBinaryChunk(forward); err != nil {
@@ -61,7 +64,7 @@ func (p *parser) ParseBinaryMarker() (isBinary bool, hasData bool, err error) {
 	case line == "Files differ\n":
 	case strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, "differ\n"):
 	default:
-		if !binaryRegexp.MatchString(p.Line(0)) {
+		if !binaryRegexp.MatchString(p.Line(0)) && parseBinaryNotice(line, p.binaryNotices) == nil {
 			return false, false, nil
 		}
 	}
@@ -147,7 +150,7 @@ func (p *parser) ParseBinaryChunk(frag *BinaryFragment) error {
 
 		if err := p.Next(); err != nil {
 			if err == io.EOF {
-				return p.Errorf(0, "binary patch: unexpected EOF")
+				return p.Truncated("binary patch: unexpected EOF")
 			}
 			return err
 		}
@@ -0,0 +1,153 @@
+package gitdiff
+
+const (
+	// deltaWindow is the size of the blocks of the source that are indexed
+	// to find copies, like the window of the delta encoder in Git
+	deltaWindow = 16
+
+	// deltaMaxChain is the maximum number of source positions kept for each
+	// hash, which limits the time spent on repetitive sources
+	deltaMaxChain = 64
+
+	// deltaMaxCopy is the maximum size of a single copy instruction; longer
+	// copies are split like in Git
+	deltaMaxCopy = 0x10000
+
+	// deltaMaxInsert is the maximum size of a single insert instruction
+	deltaMaxInsert = 0x7F
+)
+
+// DiffBinary computes the binary fragments that transform old into new and
+// back, as in patches from git diff --binary. Each fragment uses a delta in
+// Git's packfile format if it is smaller than the literal content, and the
+// literal content otherwise. Both fragments are nil if old and new are equal.
+func DiffBinary(old, new []byte) (forward, reverse *BinaryFragment) {
+	if string(old) == string(new) {
+		return nil, nil
+	}
+	return binaryFragment(old, new), binaryFragment(new, old)
+}
+
+// binaryFragment returns a delta fragment from src to dst if it is smaller
+// than a literal fragment with the content of dst.
+func binaryFragment(src, dst []byte) *BinaryFragment {
+	if len(src) >= deltaWindow && len(dst) > 0 {
+		if delta := encodeBinaryDelta(src, dst); len(delta) < len(dst) {
+			return &BinaryFragment{Method: BinaryPatchDelta, Size: int64(len(delta)), Data: delta}
+		}
+	}
+	return &BinaryFragment{Method: BinaryPatchLiteral, Size: int64(len(dst)), Data: dst}
+}
+
+// encodeBinaryDelta returns a delta that creates dst from src. The delta
+// starts with the sizes of src and dst and has instructions to copy ranges of
+// src and to insert new data. Copies are found by indexing the blocks of src
+// at multiples of the window size and looking up the hash of each window of
+// dst, then extending matches forward and backward.
+func encodeBinaryDelta(src, dst []byte) []byte {
+	index := make(map[uint32][]int)
+	for i := 0; i+deltaWindow <= len(src); i += deltaWindow {
+		h := deltaHash(src[i : i+deltaWindow])
+		if chain := index[h]; len(chain) < deltaMaxChain {
+			index[h] = append(chain, i)
+		}
+	}
+
+	delta := appendDeltaSize(nil, int64(len(src)))
+	delta = appendDeltaSize(delta, int64(len(dst)))
+
+	insertStart := 0
+	for i := 0; i+deltaWindow <= len(dst); {
+		pos, n := -1, 0
+		for _, p := range index[deltaHash(dst[i:i+deltaWindow])] {
+			m := matchLen(src[p:], dst[i:])
+			if m > n {
+				pos, n = p, m
+			}
+		}
+		if n < deltaWindow {
+			i++
+			continue
+		}
+
+		// extend the match backward over data that would be inserted
+		for pos > 0 && i > insertStart && src[pos-1] == dst[i-1] {
+			pos, i, n = pos-1, i-1, n+1
+		}
+
+		delta = appendDeltaInsert(delta, dst[insertStart:i])
+		delta = appendDeltaCopy(delta, pos, n)
+		i += n
+		insertStart = i
+	}
+	return appendDeltaInsert(delta, dst[insertStart:])
+}
+
+// deltaHash returns a hash of a window of data.
+func deltaHash(b []byte) uint32 {
+	// FNV-1a
+	h := uint32(2166136261)
+	for _, c := range b {
+		h ^= uint32(c)
+		h *= 16777619
+	}
+	return h
+}
+
+func matchLen(a, b []byte) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// appendDeltaSize appends a size in the variable length encoding read by
+// readBinaryDeltaSize.
+func appendDeltaSize(delta []byte, size int64) []byte {
+	for size > 0x7F {
+		delta = append(delta, byte(size&0x7F)|0x80)
+		size >>= 7
+	}
+	return append(delta, byte(size))
+}
+
+// appendDeltaInsert appends instructions to insert data, which are read by
+// applyBinaryDeltaAdd.
+func appendDeltaInsert(delta, data []byte) []byte {
+	for len(data) > 0 {
+		n := minInt(len(data), deltaMaxInsert)
+		delta = append(delta, byte(n))
+		delta = append(delta, data[:n]...)
+		data = data[n:]
+	}
+	return delta
+}
+
+// appendDeltaCopy appends instructions to copy size bytes of the source at
+// offset, which are read by applyBinaryDeltaCopy.
+func appendDeltaCopy(delta []byte, offset, size int) []byte {
+	for size > 0 {
+		n := minInt(size, deltaMaxCopy)
+
+		op := len(delta)
+		delta = append(delta, 0x80)
+		for i := uint(0); i < 4; i++ {
+			if b := byte(offset >> (8 * i)); b != 0 {
+				delta[op] |= 1 << i
+				delta = append(delta, b)
+			}
+		}
+		// a size of 0x10000 is encoded with no size bytes
+		for i := uint(0); i < 3 && n < deltaMaxCopy; i++ {
+			if b := byte(n >> (8 * i)); b != 0 {
+				delta[op] |= 1 << (4 + i)
+				delta = append(delta, b)
+			}
+		}
+
+		offset += n
+		size -= n
+	}
+	return delta
+}
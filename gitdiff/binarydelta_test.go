@@ -0,0 +1,89 @@
+package gitdiff
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestDiffBinary(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func(n int) []byte {
+		b := make([]byte, n)
+		rng.Read(b)
+		return b
+	}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	base := random(200000)
+
+	tests := map[string]struct {
+		Old, New []byte
+		Method   BinaryPatchMethod
+	}{
+		"insertMiddle": {
+			Old:    base,
+			New:    join(base[:1000], []byte("inserted data"), base[1000:]),
+			Method: BinaryPatchDelta,
+		},
+		"changeBytes": {
+			Old:    base,
+			New:    join(base[:5000], random(300), base[5300:150000], random(10), base[150010:]),
+			Method: BinaryPatchDelta,
+		},
+		"moveBlocks": {
+			Old:    base,
+			New:    join(base[100000:], base[:100000]),
+			Method: BinaryPatchDelta,
+		},
+		"unaligned": {
+			Old:    base[:10000],
+			New:    join([]byte("x"), base[3:9000], []byte("y")),
+			Method: BinaryPatchDelta,
+		},
+		"unrelated": {
+			Old:    random(1000),
+			New:    random(1000),
+			Method: BinaryPatchLiteral,
+		},
+		"smallSource": {
+			Old:    []byte("short"),
+			New:    []byte("short and longer"),
+			Method: BinaryPatchLiteral,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			forward, reverse := DiffBinary(test.Old, test.New)
+			if forward.Method != test.Method {
+				t.Errorf("incorrect method: expected %v, actual %v", test.Method, forward.Method)
+			}
+			if forward.Method == BinaryPatchDelta && len(forward.Data) > len(test.New)/10 {
+				t.Errorf("delta is not compact: %d bytes for %d bytes of content", len(forward.Data), len(test.New))
+			}
+
+			for _, c := range []struct {
+				Frag     *BinaryFragment
+				Src, Dst []byte
+			}{
+				{forward, test.Old, test.New},
+				{reverse, test.New, test.Old},
+			} {
+				var b bytes.Buffer
+				if err := NewApplier(bytes.NewReader(c.Src)).ApplyBinaryFragment(&b, c.Frag); err != nil {
+					t.Fatalf("unexpected error applying fragment: %v", err)
+				}
+				if !bytes.Equal(c.Dst, b.Bytes()) {
+					t.Errorf("incorrect result applying fragment: %d bytes, expected %d", b.Len(), len(c.Dst))
+				}
+			}
+		})
+	}
+
+	if forward, reverse := DiffBinary(base, base); forward != nil || reverse != nil {
+		t.Errorf("expected no fragments for equal content, but got %+v, %+v", forward, reverse)
+	}
+}
@@ -0,0 +1,66 @@
+package gitdiff
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BlameLine records the origin of a line of a file, like a line in the
+// output of git blame.
+type BlameLine struct {
+	// Header is the header of the patch that added the line, which
+	// identifies its author and commit. It is nil if the origin is unknown.
+	Header *PatchHeader
+
+	// Line is the one-indexed number of the line in the file after the patch
+	// that added it.
+	Line int64
+}
+
+// CarryBlame computes the origin of each line of the file after f given the
+// origin of each line of the file before f. Unchanged lines keep their
+// origin from old and lines added by f are attributed to f.PatchHeader.
+// Calling CarryBlame with each patch of a series in order maintains the
+// blame of a file incrementally, without rerunning git blame.
+//
+// CarryBlame returns nil for deleted files and an error for binary files,
+// combined diffs, and fragments that do not fit in old.
+func CarryBlame(old []BlameLine, f *File) ([]BlameLine, error) {
+	if f.IsBinary {
+		return nil, errors.New("gitdiff: cannot carry blame through a binary file")
+	}
+	if f.ParentCount > 0 {
+		return nil, errors.New("gitdiff: cannot carry blame through a combined diff")
+	}
+	if f.IsDelete {
+		return nil, nil
+	}
+
+	var blame []BlameLine
+	var next int64
+	for _, frag := range f.TextFragments {
+		if err := frag.Validate(); err != nil {
+			return nil, fmt.Errorf("gitdiff: %s: %w", fileName(f), err)
+		}
+
+		start := fragmentOldStart(frag)
+		if start < next || start+frag.OldLines > int64(len(old)) {
+			return nil, fmt.Errorf("gitdiff: %s: fragment at line %d does not fit in the old file of %d lines", fileName(f), frag.OldPosition, len(old))
+		}
+		blame = append(blame, old[next:start]...)
+
+		for _, line := range frag.Lines {
+			switch line.Op {
+			case OpContext:
+				blame = append(blame, old[start])
+				start++
+			case OpDelete:
+				start++
+			case OpAdd:
+				blame = append(blame, BlameLine{Header: f.PatchHeader, Line: int64(len(blame) + 1)})
+			}
+		}
+		next = start
+	}
+	return append(blame, old[next:]...), nil
+}
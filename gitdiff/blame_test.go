@@ -0,0 +1,125 @@
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCarryBlame(t *testing.T) {
+	first := &PatchHeader{Title: "first"}
+	second := &PatchHeader{Title: "second"}
+
+	old := []BlameLine{
+		{Header: first, Line: 1},
+		{Header: first, Line: 2},
+		{Header: first, Line: 3},
+		{Header: first, Line: 4},
+		{Header: first, Line: 5},
+	}
+
+	tests := map[string]struct {
+		Patch string
+		Blame []BlameLine
+		Err   bool
+	}{
+		"modify": {
+			Patch: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,4 @@
+ line 1
+-line 2
++line two
++line 2.5
+ line 3
+`,
+			Blame: []BlameLine{
+				{Header: first, Line: 1},
+				{Header: second, Line: 2},
+				{Header: second, Line: 3},
+				{Header: first, Line: 3},
+				{Header: first, Line: 4},
+				{Header: first, Line: 5},
+			},
+		},
+		"multipleFragments": {
+			Patch: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,1 @@
+-line 1
+ line 2
+@@ -5,0 +5,1 @@
++line 6
+`,
+			Blame: []BlameLine{
+				{Header: first, Line: 2},
+				{Header: first, Line: 3},
+				{Header: first, Line: 4},
+				{Header: first, Line: 5},
+				{Header: second, Line: 5},
+			},
+		},
+		"delete": {
+			Patch: `diff --git a/file.txt b/file.txt
+deleted file mode 100644
+--- a/file.txt
++++ /dev/null
+@@ -1,5 +0,0 @@
+-line 1
+-line 2
+-line 3
+-line 4
+-line 5
+`,
+			Blame: nil,
+		},
+		"pastEnd": {
+			Patch: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -5,2 +5,2 @@
+ line 5
+-line 6
++line six
+`,
+			Err: true,
+		},
+		"binary": {
+			Patch: `diff --git a/file.bin b/file.bin
+Binary files a/file.bin and b/file.bin differ
+`,
+			Err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			files[0].PatchHeader = second
+
+			blame, err := CarryBlame(old, files[0])
+			if test.Err {
+				if err == nil {
+					t.Fatal("expected error carrying blame, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(blame) != len(test.Blame) {
+				t.Fatalf("incorrect number of lines: expected %d, actual %d", len(test.Blame), len(blame))
+			}
+			for i, b := range blame {
+				if b != test.Blame[i] {
+					t.Errorf("incorrect blame for line %d: expected %s:%d, actual %s:%d", i+1, test.Blame[i].Header.Title, test.Blame[i].Line, b.Header.Title, b.Line)
+				}
+			}
+		})
+	}
+}
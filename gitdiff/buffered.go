@@ -0,0 +1,147 @@
+package gitdiff
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// BufferedReaderAt is an io.ReaderAt for data read from a stream, such as a
+// pipe. Data is read from the stream only as far as needed to satisfy calls
+// to ReadAt and is kept in memory until it exceeds a limit, after which it is
+// moved to a temporary file. Call Close to remove the file.
+type BufferedReaderAt struct {
+	mu sync.Mutex
+
+	r        io.Reader
+	err      error
+	memLimit int64
+
+	buf  []byte
+	file *os.File
+	size int64
+}
+
+// NewBufferedReaderAt returns a BufferedReaderAt that reads from r and keeps
+// up to memLimit bytes in memory. If memLimit is zero or negative, all data
+// stays in memory. Use it to apply patches to old content that is only
+// available as a stream; the source of an Applier must support random access.
+func NewBufferedReaderAt(r io.Reader, memLimit int64) *BufferedReaderAt {
+	return &BufferedReaderAt{r: r, memLimit: memLimit}
+}
+
+// ReadAt reads len(p) bytes starting at off, reading more data from the
+// stream if needed. It is safe to call from multiple goroutines.
+func (b *BufferedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// a stream error only matters for data that was never buffered
+	ferr := b.fill(off + int64(len(p)))
+	if ferr == nil {
+		ferr = io.EOF
+	}
+	if off >= b.size {
+		return 0, ferr
+	}
+
+	var n int
+	if b.file != nil {
+		end := off + int64(len(p))
+		if end > b.size {
+			end = b.size
+		}
+		var err error
+		if n, err = b.file.ReadAt(p[:end-off], off); err != nil {
+			return n, err
+		}
+	} else {
+		n = copy(p, b.buf[off:])
+	}
+
+	if n < len(p) {
+		return n, ferr
+	}
+	return n, nil
+}
+
+// Size returns the number of bytes read from the stream so far.
+func (b *BufferedReaderAt) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// Close removes the temporary file, if any. The BufferedReaderAt must not be
+// used after it is closed. Close does not close the stream.
+func (b *BufferedReaderAt) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = nil
+	if b.file == nil {
+		return nil
+	}
+	f := b.file
+	b.file = nil
+
+	err := f.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// fill reads from the stream until size bytes are buffered or the stream
+// ends. It returns an error if fewer than size bytes are buffered because
+// reading or writing the temporary file failed.
+func (b *BufferedReaderAt) fill(size int64) error {
+	var chunk [byteBufferSize]byte
+	for b.size < size && b.err == nil {
+		n, err := b.r.Read(chunk[:])
+		if n > 0 {
+			if werr := b.write(chunk[:n]); werr != nil {
+				b.err = werr
+				return werr
+			}
+		}
+		if err != nil {
+			b.err = err
+		}
+	}
+	if b.size < size && b.err != nil && b.err != io.EOF {
+		return b.err
+	}
+	return nil
+}
+
+func (b *BufferedReaderAt) write(data []byte) error {
+	if b.file == nil && b.memLimit > 0 && b.size+int64(len(data)) > b.memLimit {
+		f, err := ioutil.TempFile("", "gitdiff-buffer-")
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(b.buf); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+		b.file = f
+		b.buf = nil
+	}
+
+	if b.file != nil {
+		if _, err := b.file.WriteAt(data, b.size); err != nil {
+			return err
+		}
+	} else {
+		b.buf = append(b.buf, data...)
+	}
+	b.size += int64(len(data))
+	return nil
+}
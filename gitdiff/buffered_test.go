@@ -0,0 +1,135 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBufferedReaderAt(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789", 10))
+
+	tests := map[string]struct {
+		MemLimit int64
+		Spill    bool
+	}{
+		"memory":     {MemLimit: 0},
+		"underLimit": {MemLimit: 1000},
+		"spill":      {MemLimit: 16, Spill: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := NewBufferedReaderAt(iotest.OneByteReader(bytes.NewReader(data)), test.MemLimit)
+			defer b.Close()
+
+			reads := []struct {
+				Off int64
+				Len int
+				Out string
+				Err error
+			}{
+				{Off: 5, Len: 5, Out: "56789"},
+				{Off: 0, Len: 3, Out: "012"},
+				{Off: 48, Len: 4, Out: "8901"},
+				{Off: 95, Len: 10, Out: "56789", Err: io.EOF},
+				{Off: 100, Len: 1, Out: "", Err: io.EOF},
+				{Off: 10, Len: 2, Out: "01"},
+			}
+			for _, r := range reads {
+				p := make([]byte, r.Len)
+				n, err := b.ReadAt(p, r.Off)
+				if err != r.Err {
+					t.Errorf("read at %d: expected error %v, but got %v", r.Off, r.Err, err)
+				}
+				if out := string(p[:n]); out != r.Out {
+					t.Errorf("read at %d: incorrect output: expected %q, actual %q", r.Off, r.Out, out)
+				}
+			}
+
+			if b.Size() != int64(len(data)) {
+				t.Errorf("incorrect size: expected %d, actual %d", len(data), b.Size())
+			}
+			if spilled := b.file != nil; spilled != test.Spill {
+				t.Errorf("incorrect spill: expected %t, actual %t", test.Spill, spilled)
+			}
+
+			var name string
+			if b.file != nil {
+				name = b.file.Name()
+			}
+			if err := b.Close(); err != nil {
+				t.Fatalf("unexpected error closing: %v", err)
+			}
+			if name != "" {
+				if _, err := os.Stat(name); !os.IsNotExist(err) {
+					t.Errorf("temporary file was not removed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestBufferedReaderAtError(t *testing.T) {
+	readErr := errors.New("read failed")
+	b := NewBufferedReaderAt(io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(readErr)), 0)
+
+	p := make([]byte, 2)
+	if n, err := b.ReadAt(p, 0); err != nil || string(p[:n]) != "ab" {
+		t.Fatalf("incorrect read: %q, %v", p[:n], err)
+	}
+	if n, err := b.ReadAt(p, 2); err != readErr || string(p[:n]) != "c" {
+		t.Fatalf("expected partial read and read error, but got %q, %v", p[:n], err)
+	}
+
+	// data buffered before the error is still readable
+	if n, err := b.ReadAt(p, 0); err != nil || string(p[:n]) != "ab" {
+		t.Fatalf("incorrect read after error: %q, %v", p[:n], err)
+	}
+	if n, err := b.ReadAt(p[:1], 2); err != nil || string(p[:n]) != "c" {
+		t.Fatalf("incorrect read after error: %q, %v", p[:n], err)
+	}
+	if _, err := b.ReadAt(p, 3); err != readErr {
+		t.Fatalf("expected read error past buffered data, but got %v", err)
+	}
+}
+
+func TestBufferedReaderAtApply(t *testing.T) {
+	src := strings.Repeat("line\n", 1000)
+	patch := `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -500,3 +500,3 @@
+ line
+-line
++changed
+ line
+`
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = io.Copy(pw, strings.NewReader(src))
+		pw.Close()
+	}()
+
+	b := NewBufferedReaderAt(pr, 1024)
+	defer b.Close()
+
+	var dst bytes.Buffer
+	if err := Apply(&dst, b, files[0]); err != nil {
+		t.Fatalf("unexpected error applying patch: %v", err)
+	}
+
+	expected := strings.Repeat("line\n", 500) + "changed\n" + strings.Repeat("line\n", 499)
+	if dst.String() != expected {
+		t.Errorf("incorrect result: expected %d bytes, actual %d bytes", len(expected), dst.Len())
+	}
+}
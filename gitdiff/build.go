@@ -0,0 +1,209 @@
+package gitdiff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// TreeWriter is a mutable tree of files, such as the index of a Git
+// repository or a tree being built in an object database. BuildTree uses it to
+// apply a patch without checking files out to a working tree. All paths are
+// slash-separated and relative to the root of the tree.
+type TreeWriter interface {
+	// ReadBlob returns the content and mode of the file at path in the tree.
+	ReadBlob(path string) ([]byte, os.FileMode, error)
+
+	// WriteBlob sets the content of the file at path, creating it if it does
+	// not exist.
+	WriteBlob(path string, data []byte) error
+
+	// SetMode sets the mode of the file at path.
+	SetMode(path string, mode os.FileMode) error
+
+	// DeletePath removes the file at path from the tree.
+	DeletePath(path string) error
+}
+
+// ApplyTarget is a TreeWriter that can also rename files. When the tree
+// passed to BuildTree implements ApplyTarget, renamed files are moved with
+// RenamePath instead of being deleted and written again, so the tree can keep
+// any state it associates with the file.
+type ApplyTarget interface {
+	TreeWriter
+
+	// RenamePath moves the file at oldPath to newPath, keeping its content
+	// and mode and replacing any file at newPath.
+	RenamePath(oldPath, newPath string) error
+}
+
+// treeUpdate is the result of applying one file from a patch.
+type treeUpdate struct {
+	name     string
+	oldName  string
+	data     []byte
+	mode     os.FileMode
+	modified bool
+}
+
+// BuildTree applies the changes in files to the tree in base. All files are
+// read and patched before base is modified, so an error applying any file
+// leaves base unchanged and renames may swap names. Like ApplyTree, BuildTree
+// checks that the content of deleted files matches the patch, unless the
+// patch omits the content. Then the old names of
+// deleted and renamed files are removed and the new content and modes are
+// written. The mode of a new file or a renamed file without a mode in the
+// patch is always set explicitly, using 0100644 if the mode is unknown.
+//
+// If base is an ApplyTarget, renamed files are moved with RenamePath after
+// the deletions, unless another file in the patch uses the old or new name,
+// as when renames swap names. Their content and mode are only written if the
+// patch changes them.
+func BuildTree(files []*File, base TreeWriter) error {
+	return BuildTreeWithOptions(files, base, ApplyOptions{})
+}
+
+// BuildTreeWithOptions is like BuildTree, but applies each file with the
+// given options, including the limits on the size of the results and on the
+// number of files.
+func BuildTreeWithOptions(files []*File, base TreeWriter, opts ApplyOptions) error {
+	if err := checkMaxFiles(files, opts); err != nil {
+		return err
+	}
+	target, canRename := base.(ApplyTarget)
+	total := totalLimit{max: opts.MaxTotalBytes}
+
+	// count the files that read and write each name to find safe renames;
+	// deletions happen first, so they do not count
+	reads := make(map[string]int)
+	writes := make(map[string]int)
+	for _, f := range files {
+		if !f.IsNew && !f.IsDelete {
+			reads[f.OldName]++
+		}
+		if !f.IsDelete {
+			writes[f.NewName]++
+		}
+	}
+
+	var deletes []string
+	var updates []treeUpdate
+
+	for _, f := range files {
+		// without the old content, a deletion cannot be checked
+		if f.IsDelete && f.ContentOmitted {
+			deletes = append(deletes, f.OldName)
+			continue
+		}
+
+		var src []byte
+		var mode os.FileMode
+		if !f.IsNew {
+			var err error
+			if src, mode, err = base.ReadBlob(f.OldName); err != nil {
+				return fmt.Errorf("gitdiff: %s: %w", f.OldName, err)
+			}
+		}
+
+		var dst bytes.Buffer
+		if err := NewApplierWithOptions(bytes.NewReader(src), opts).ApplyFile(total.writer(&dst), f); err != nil {
+			return fmt.Errorf("gitdiff: %s: %w", fileName(f), err)
+		}
+		if f.IsDelete {
+			deletes = append(deletes, f.OldName)
+			continue
+		}
+
+		u := treeUpdate{
+			name:     f.NewName,
+			data:     dst.Bytes(),
+			modified: f.IsNew || f.IsRename || f.IsCopy || !bytes.Equal(src, dst.Bytes()),
+		}
+		if f.IsRename && canRename && writes[f.OldName] == 0 && reads[f.NewName] == 0 {
+			u.oldName = f.OldName
+			u.modified = !bytes.Equal(src, dst.Bytes())
+		}
+		switch {
+		case f.NewMode != 0:
+			u.mode = f.NewMode
+		case u.oldName != "":
+			// RenamePath keeps the old mode
+		case f.IsNew || f.IsRename || f.IsCopy:
+			u.mode = mode
+			if u.mode == 0 {
+				u.mode = 0100644
+			}
+		}
+		if f.IsRename && u.oldName == "" {
+			deletes = append(deletes, f.OldName)
+		}
+		updates = append(updates, u)
+	}
+
+	for _, name := range deletes {
+		if err := base.DeletePath(name); err != nil {
+			return fmt.Errorf("gitdiff: %s: %w", name, err)
+		}
+	}
+	for _, u := range updates {
+		if u.oldName != "" {
+			if err := target.RenamePath(u.oldName, u.name); err != nil {
+				return fmt.Errorf("gitdiff: %s: %w", u.name, err)
+			}
+		}
+	}
+	for _, u := range updates {
+		if u.modified {
+			if err := base.WriteBlob(u.name, u.data); err != nil {
+				return fmt.Errorf("gitdiff: %s: %w", u.name, err)
+			}
+		}
+		if u.mode != 0 {
+			if err := base.SetMode(u.name, u.mode); err != nil {
+				return fmt.Errorf("gitdiff: %s: %w", u.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkMaxFiles returns an error matching ErrLimitExceeded if files changes
+// more files than opts.MaxFiles.
+func checkMaxFiles(files []*File, opts ApplyOptions) error {
+	if max := opts.MaxFiles; max > 0 && len(files) > max {
+		return fmt.Errorf("%w: patch changes %d files, more than %d", ErrLimitExceeded, len(files), max)
+	}
+	return nil
+}
+
+// totalLimit enforces the MaxTotalBytes option across all of the results of
+// a patch. If max is zero or less, the total size is not limited.
+type totalLimit struct {
+	max     int64
+	written int64
+}
+
+// writer returns a writer that writes to w and fails with an error matching
+// ErrLimitExceeded before the total size of the results exceeds the limit,
+// so results are never buffered beyond the remaining budget.
+func (l *totalLimit) writer(w io.Writer) io.Writer {
+	if l.max <= 0 {
+		return w
+	}
+	return &totalLimitWriter{w: w, limit: l}
+}
+
+type totalLimitWriter struct {
+	w     io.Writer
+	limit *totalLimit
+}
+
+func (tw *totalLimitWriter) Write(p []byte) (int, error) {
+	if tw.limit.written+int64(len(p)) > tw.limit.max {
+		return 0, fmt.Errorf("%w: results are larger than %d bytes", ErrLimitExceeded, tw.limit.max)
+	}
+	n, err := tw.w.Write(p)
+	tw.limit.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,336 @@
+package gitdiff
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type memTree struct {
+	blobs map[string]string
+	modes map[string]os.FileMode
+	ops   []string
+}
+
+func (t *memTree) ReadBlob(path string) ([]byte, os.FileMode, error) {
+	data, ok := t.blobs[path]
+	if !ok {
+		return nil, 0, os.ErrNotExist
+	}
+	return []byte(data), t.modes[path], nil
+}
+
+func (t *memTree) WriteBlob(path string, data []byte) error {
+	t.ops = append(t.ops, "write "+path)
+	t.blobs[path] = string(data)
+	return nil
+}
+
+func (t *memTree) SetMode(path string, mode os.FileMode) error {
+	t.ops = append(t.ops, "mode "+path)
+	t.modes[path] = mode
+	return nil
+}
+
+func (t *memTree) DeletePath(path string) error {
+	t.ops = append(t.ops, "delete "+path)
+	delete(t.blobs, path)
+	delete(t.modes, path)
+	return nil
+}
+
+func TestBuildTree(t *testing.T) {
+	patch := `diff --git a/file.txt b/file.txt
+index 1111111..2222222 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@
+ line 1
+-line 2
++line two
+diff --git a/old.txt b/new.txt
+similarity index 100%
+rename from old.txt
+rename to new.txt
+diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+diff --git a/added.txt b/added.txt
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/added.txt
+@@ -0,0 +1 @@
++added
+diff --git a/removed.txt b/removed.txt
+deleted file mode 100644
+index 4444444..0000000
+--- a/removed.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-removed
+`
+
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	tree := &memTree{
+		blobs: map[string]string{
+			"file.txt":    "line 1\nline 2\n",
+			"old.txt":     "old\n",
+			"script.sh":   "#!/bin/sh\n",
+			"removed.txt": "removed\n",
+		},
+		modes: map[string]os.FileMode{
+			"file.txt":    0100644,
+			"old.txt":     0100755,
+			"script.sh":   0100644,
+			"removed.txt": 0100644,
+		},
+	}
+
+	if err := BuildTree(files, tree); err != nil {
+		t.Fatalf("unexpected error building tree: %v", err)
+	}
+
+	expectedBlobs := map[string]string{
+		"file.txt":  "line 1\nline two\n",
+		"new.txt":   "old\n",
+		"script.sh": "#!/bin/sh\n",
+		"added.txt": "added\n",
+	}
+	if !reflect.DeepEqual(expectedBlobs, tree.blobs) {
+		t.Errorf("incorrect blobs\nexpected: %q\n  actual: %q", expectedBlobs, tree.blobs)
+	}
+
+	expectedModes := map[string]os.FileMode{
+		"file.txt":  0100644,
+		"new.txt":   0100755,
+		"script.sh": 0100755,
+		"added.txt": 0100644,
+	}
+	if !reflect.DeepEqual(expectedModes, tree.modes) {
+		t.Errorf("incorrect modes\nexpected: %v\n  actual: %v", expectedModes, tree.modes)
+	}
+
+	expectedOps := []string{
+		"delete old.txt",
+		"delete removed.txt",
+		"write file.txt",
+		"write new.txt",
+		"mode new.txt",
+		"mode script.sh",
+		"write added.txt",
+		"mode added.txt",
+	}
+	if !reflect.DeepEqual(expectedOps, tree.ops) {
+		t.Errorf("incorrect operations\nexpected: %q\n  actual: %q", expectedOps, tree.ops)
+	}
+}
+
+func TestBuildTreeConflict(t *testing.T) {
+	files := []*File{
+		{OldName: "gone.txt", IsDelete: true},
+		{
+			OldName: "file.txt",
+			NewName: "file.txt",
+			TextFragments: []*TextFragment{{
+				OldPosition: 1, OldLines: 1, NewPosition: 1, NewLines: 1,
+				LinesAdded: 1, LinesDeleted: 1,
+				Lines: []Line{{OpDelete, "expected\n"}, {OpAdd, "new\n"}},
+			}},
+		},
+	}
+
+	tree := &memTree{
+		blobs: map[string]string{"gone.txt": "", "file.txt": "actual\n"},
+		modes: map[string]os.FileMode{},
+	}
+
+	err := BuildTree(files, tree)
+	if !errors.Is(err, &Conflict{}) {
+		t.Fatalf("expected conflict building tree, but got: %v", err)
+	}
+	if len(tree.ops) > 0 {
+		t.Errorf("tree was modified after error: %q", tree.ops)
+	}
+
+	err = BuildTree([]*File{{OldName: "missing.txt", NewName: "missing.txt"}}, tree)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected missing file error, but got: %v", err)
+	}
+}
+
+func TestBuildTreeLimits(t *testing.T) {
+	newFile := func(name, content string) *File {
+		return &File{
+			NewName: name,
+			IsNew:   true,
+			TextFragments: []*TextFragment{{
+				NewPosition: 1, NewLines: 1, LinesAdded: 1,
+				Lines: []Line{{OpAdd, content}},
+			}},
+		}
+	}
+	files := []*File{newFile("a.txt", "aaaa\n"), newFile("b.txt", "bbbb\n")}
+
+	tests := map[string]struct {
+		Options ApplyOptions
+		Err     string
+	}{
+		"maxFiles":      {Options: ApplyOptions{MaxFiles: 1}, Err: "patch changes 2 files, more than 1"},
+		"maxTotalBytes": {Options: ApplyOptions{MaxTotalBytes: 8}, Err: "b.txt: gitdiff: apply limit exceeded: results are larger than 8 bytes"},
+		"withinLimits":  {Options: ApplyOptions{MaxFiles: 2, MaxTotalBytes: 10}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tree := &memTree{blobs: map[string]string{}, modes: map[string]os.FileMode{}}
+
+			err := BuildTreeWithOptions(files, tree, test.Options)
+			if test.Err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error building tree: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), test.Err) {
+				t.Fatalf("incorrect error: %v", err)
+			}
+			if len(tree.ops) > 0 {
+				t.Errorf("tree was modified after error: %q", tree.ops)
+			}
+		})
+	}
+}
+
+func TestBuildTreeDelete(t *testing.T) {
+	deleteFile := func(content string, omitted bool) *File {
+		f := &File{OldName: "gone.txt", IsDelete: true, ContentOmitted: omitted}
+		if !omitted {
+			f.TextFragments = []*TextFragment{{
+				OldPosition: 1, OldLines: 1,
+				LinesDeleted: 1,
+				Lines:        []Line{{OpDelete, content}},
+			}}
+		}
+		return f
+	}
+
+	tests := map[string]struct {
+		File *File
+		Err  interface{}
+	}{
+		"matches": {
+			File: deleteFile("expected\n", false),
+		},
+		"stale": {
+			File: deleteFile("other\n", false),
+			Err:  &Conflict{},
+		},
+		"contentOmitted": {
+			File: deleteFile("", true),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tree := &memTree{
+				blobs: map[string]string{"gone.txt": "expected\n", "file.txt": "actual\n"},
+				modes: map[string]os.FileMode{},
+			}
+			rename := &File{OldName: "file.txt", NewName: "moved.txt", IsRename: true}
+
+			err := BuildTree([]*File{rename, test.File}, tree)
+			if test.Err != nil {
+				assertError(t, test.Err, err, "building tree")
+				if len(tree.ops) > 0 {
+					t.Errorf("tree was modified after error: %q", tree.ops)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error building tree: %v", err)
+			}
+			if _, ok := tree.blobs["gone.txt"]; ok {
+				t.Error("deleted file is still in the tree")
+			}
+		})
+	}
+}
+
+type renameTree struct {
+	*memTree
+}
+
+func (t renameTree) RenamePath(oldPath, newPath string) error {
+	t.ops = append(t.ops, "rename "+oldPath+" "+newPath)
+	t.blobs[newPath] = t.blobs[oldPath]
+	t.modes[newPath] = t.modes[oldPath]
+	delete(t.blobs, oldPath)
+	delete(t.modes, oldPath)
+	return nil
+}
+
+func TestBuildTreeRename(t *testing.T) {
+	rename := func(from, to string, frags ...*TextFragment) *File {
+		return &File{OldName: from, NewName: to, IsRename: true, TextFragments: frags}
+	}
+	change := &TextFragment{
+		OldPosition: 1, OldLines: 1, NewPosition: 1, NewLines: 1,
+		LinesAdded: 1, LinesDeleted: 1,
+		Lines: []Line{{OpDelete, "a\n"}, {OpAdd, "A\n"}},
+	}
+
+	tests := map[string]struct {
+		Files []*File
+		Ops   []string
+		Blobs map[string]string
+	}{
+		"pure": {
+			Files: []*File{rename("a.txt", "c.txt")},
+			Ops:   []string{"rename a.txt c.txt"},
+			Blobs: map[string]string{"b.txt": "b\n", "c.txt": "a\n"},
+		},
+		"modified": {
+			Files: []*File{rename("a.txt", "c.txt", change)},
+			Ops:   []string{"rename a.txt c.txt", "write c.txt"},
+			Blobs: map[string]string{"b.txt": "b\n", "c.txt": "A\n"},
+		},
+		"replaceDeleted": {
+			Files: []*File{{OldName: "b.txt", IsDelete: true, ContentOmitted: true}, rename("a.txt", "b.txt")},
+			Ops:   []string{"delete b.txt", "rename a.txt b.txt"},
+			Blobs: map[string]string{"b.txt": "a\n"},
+		},
+		"swap": {
+			Files: []*File{rename("a.txt", "b.txt"), rename("b.txt", "a.txt")},
+			Ops: []string{
+				"delete a.txt", "delete b.txt",
+				"write b.txt", "mode b.txt", "write a.txt", "mode a.txt",
+			},
+			Blobs: map[string]string{"a.txt": "b\n", "b.txt": "a\n"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tree := renameTree{&memTree{
+				blobs: map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+				modes: map[string]os.FileMode{"a.txt": 0100644, "b.txt": 0100755},
+			}}
+			if err := BuildTree(test.Files, tree); err != nil {
+				t.Fatalf("unexpected error building tree: %v", err)
+			}
+			if !reflect.DeepEqual(test.Ops, tree.ops) {
+				t.Errorf("incorrect operations\nexpected: %q\n  actual: %q", test.Ops, tree.ops)
+			}
+			if !reflect.DeepEqual(test.Blobs, tree.blobs) {
+				t.Errorf("incorrect blobs\nexpected: %q\n  actual: %q", test.Blobs, tree.blobs)
+			}
+		})
+	}
+}
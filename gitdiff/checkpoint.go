@@ -0,0 +1,133 @@
+package gitdiff
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// lineIndexMagic starts the data written by SaveIndex. The last byte is the
+// version of the format.
+const lineIndexMagic = "GDLI\x01"
+
+// IndexedLineReader is a LineReaderAt for an io.ReaderAt that keeps the byte
+// offsets of the lines it reads. The offsets can be saved with SaveIndex and
+// loaded with LoadIndex, so applying several patches to the same large file
+// over time does not scan the file again each time. Pass it directly as the
+// source of an Applier.
+type IndexedLineReader struct {
+	lineReaderAt
+}
+
+// NewIndexedLineReader returns an IndexedLineReader for r with an empty
+// index.
+func NewIndexedLineReader(r io.ReaderAt) *IndexedLineReader {
+	return &IndexedLineReader{lineReaderAt{r: r}}
+}
+
+// ReadAt reads from the underlying source.
+func (r *IndexedLineReader) ReadAt(p []byte, off int64) (int, error) {
+	return r.r.ReadAt(p, off)
+}
+
+// Index indexes every line of the source, so that a saved index covers the
+// whole source.
+func (r *IndexedLineReader) Index() error {
+	for !r.eof {
+		if err := r.indexTo(int64(len(r.index)) + indexBufferSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lines returns the number of lines in the index.
+func (r *IndexedLineReader) Lines() int64 {
+	return int64(len(r.index))
+}
+
+// SaveIndex writes the line index to w in a compact binary format. The index
+// is only valid for the same content; the caller is responsible for
+// detecting changes to the source, for example by storing its hash or
+// modification time with the index.
+func (r *IndexedLineReader) SaveIndex(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(lineIndexMagic); err != nil {
+		return err
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	writeUvarint := func(v uint64) error {
+		_, err := bw.Write(buf[:binary.PutUvarint(buf[:], v)])
+		return err
+	}
+
+	var eof uint64
+	if r.eof {
+		eof = 1
+	}
+	if err := writeUvarint(eof); err != nil {
+		return err
+	}
+	if err := writeUvarint(uint64(len(r.index))); err != nil {
+		return err
+	}
+
+	// store line lengths, which are small, instead of offsets
+	last := int64(0)
+	for _, end := range r.index {
+		if err := writeUvarint(uint64(end - last)); err != nil {
+			return err
+		}
+		last = end
+	}
+	return bw.Flush()
+}
+
+// LoadIndex replaces the line index with one read from data written by
+// SaveIndex. It returns an error if the data is invalid or if the index
+// extends past the end of the source, when the size of the source is known.
+func (r *IndexedLineReader) LoadIndex(rd io.Reader) error {
+	br := bufio.NewReader(rd)
+
+	magic := make([]byte, len(lineIndexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != lineIndexMagic {
+		return errors.New("gitdiff: invalid line index: unknown format")
+	}
+
+	eof, err := binary.ReadUvarint(br)
+	if err != nil || eof > 1 {
+		return errors.New("gitdiff: invalid line index: invalid header")
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return errors.New("gitdiff: invalid line index: invalid header")
+	}
+
+	size, sized := readerSize(r.r)
+
+	var index []int64
+	last := int64(0)
+	for i := uint64(0); i < count; i++ {
+		length, err := binary.ReadUvarint(br)
+		if err != nil || length == 0 || length > uint64(math.MaxInt64-last) {
+			return fmt.Errorf("gitdiff: invalid line index: invalid line %d", i+1)
+		}
+		last += int64(length)
+		if sized && last > size {
+			return errors.New("gitdiff: invalid line index: does not match source size")
+		}
+		index = append(index, last)
+	}
+
+	if sized && eof == 1 && last != size {
+		return errors.New("gitdiff: invalid line index: does not match source size")
+	}
+
+	r.index = index
+	r.eof = eof == 1
+	return nil
+}
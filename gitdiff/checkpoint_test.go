@@ -0,0 +1,169 @@
+package gitdiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+type countingReaderAt struct {
+	r     io.ReaderAt
+	bytes int64
+}
+
+func (r *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.r.ReadAt(p, off)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func TestIndexedLineReaderSaveLoad(t *testing.T) {
+	var src bytes.Buffer
+	for i := 1; i <= 10000; i++ {
+		fmt.Fprintf(&src, "generated line %d\n", i)
+	}
+	src.WriteString("no newline")
+
+	r := NewIndexedLineReader(bytes.NewReader(src.Bytes()))
+	if err := r.Index(); err != nil {
+		t.Fatalf("unexpected error indexing: %v", err)
+	}
+	if r.Lines() != 10001 {
+		t.Fatalf("incorrect number of lines: expected 10001, actual %d", r.Lines())
+	}
+
+	var saved bytes.Buffer
+	if err := r.SaveIndex(&saved); err != nil {
+		t.Fatalf("unexpected error saving index: %v", err)
+	}
+
+	counter := &countingReaderAt{r: bytes.NewReader(src.Bytes())}
+	loaded := NewIndexedLineReader(counter)
+	if err := loaded.LoadIndex(bytes.NewReader(saved.Bytes())); err != nil {
+		t.Fatalf("unexpected error loading index: %v", err)
+	}
+	if loaded.Lines() != r.Lines() {
+		t.Fatalf("incorrect number of loaded lines: expected %d, actual %d", r.Lines(), loaded.Lines())
+	}
+
+	lines := make([][]byte, 3)
+	n, err := loaded.ReadLinesAt(lines, 9998)
+	if err != nil || n != 3 {
+		t.Fatalf("incorrect read: %d lines, %v", n, err)
+	}
+	if string(lines[0]) != "generated line 9999\n" || string(lines[2]) != "no newline" {
+		t.Errorf("incorrect lines: %q", lines[:n])
+	}
+	if counter.bytes > 100 {
+		t.Errorf("read %d bytes of the source with a loaded index", counter.bytes)
+	}
+
+	files, _, err := ParseAll(strings.NewReader(`--- a/file.txt
++++ b/file.txt
+@@ -5000,3 +5000,3 @@
+ generated line 5000
+-generated line 5001
++changed line
+ generated line 5002
+`))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	var dst bytes.Buffer
+	if err := Apply(&dst, loaded, files[0]); err != nil {
+		t.Fatalf("unexpected error applying patch: %v", err)
+	}
+	if expected := strings.Replace(src.String(), "generated line 5001\n", "changed line\n", 1); dst.String() != expected {
+		t.Errorf("incorrect result: expected %d bytes, actual %d bytes", len(expected), dst.Len())
+	}
+}
+
+func TestIndexedLineReaderLoadInvalid(t *testing.T) {
+	src := "a\nb\nc\n"
+
+	var valid bytes.Buffer
+	r := NewIndexedLineReader(strings.NewReader(src))
+	if err := r.Index(); err != nil {
+		t.Fatalf("unexpected error indexing: %v", err)
+	}
+	if err := r.SaveIndex(&valid); err != nil {
+		t.Fatalf("unexpected error saving index: %v", err)
+	}
+
+	index := func(eof uint64, lengths ...uint64) []byte {
+		data := []byte(lineIndexMagic)
+		buf := make([]byte, binary.MaxVarintLen64)
+		for _, n := range append([]uint64{eof, uint64(len(lengths))}, lengths...) {
+			data = append(data, buf[:binary.PutUvarint(buf, n)]...)
+		}
+		return data
+	}
+
+	tests := map[string]struct {
+		Data    []byte
+		Source  string
+		Unsized bool
+		Err     string
+	}{
+		"overflow": {
+			Data:    index(0, 20, math.MaxUint64-14),
+			Source:  "0123456789",
+			Unsized: true,
+			Err:     "invalid line 2",
+		},
+		"overflowSized": {
+			Data:   index(0, 20, math.MaxUint64-14),
+			Source: "0123456789",
+			Err:    "does not match source size",
+		},
+		"overflowMaxInt": {
+			Data:    index(0, math.MaxInt64, 1),
+			Unsized: true,
+			Err:     "invalid line 2",
+		},
+		"pastEnd": {
+			Data:   index(0, 2, 20),
+			Source: src,
+			Err:    "does not match source size",
+		},
+		"unknownFormat": {
+			Data:   []byte("not an index"),
+			Source: src,
+			Err:    "unknown format",
+		},
+		"truncated": {
+			Data:   valid.Bytes()[:valid.Len()-1],
+			Source: src,
+			Err:    "invalid line 3",
+		},
+		"sourceChanged": {
+			Data:   valid.Bytes(),
+			Source: "a\nb\n",
+			Err:    "does not match source size",
+		},
+		"sourceGrew": {
+			Data:   valid.Bytes(),
+			Source: src + "d\n",
+			Err:    "does not match source size",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var src io.ReaderAt = strings.NewReader(test.Source)
+			if test.Unsized {
+				src = &countingReaderAt{r: src}
+			}
+			r := NewIndexedLineReader(src)
+			err := r.LoadIndex(bytes.NewReader(test.Data))
+			assertError(t, test.Err, err, "loading index")
+			if r.Lines() != 0 {
+				t.Errorf("index changed after error: %d lines", r.Lines())
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package gitdiff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CoalesceHunks merges text fragments of f that are separated by fewer than
+// maxGap unchanged lines. The unchanged lines between merged fragments are
+// read from src, the content of the file before the patch, and added to the
+// merged fragment as context. The merged fragment keeps the comment of the
+// first fragment and the annotations of both fragments.
+//
+// Merging nearby fragments produces patches with fewer, larger hunks, which
+// are often easier to review. Combined diffs cannot be coalesced.
+func (f *File) CoalesceHunks(src LineReaderAt, maxGap int) error {
+	if f.ParentCount > 0 {
+		return errors.New("gitdiff: cannot coalesce the hunks of a combined diff")
+	}
+	if len(f.TextFragments) < 2 {
+		return nil
+	}
+
+	frags := []*TextFragment{f.TextFragments[0]}
+	for _, next := range f.TextFragments[1:] {
+		last := frags[len(frags)-1]
+
+		gap := fragmentOldStart(next) - (fragmentOldStart(last) + last.OldLines)
+		if gap < 0 {
+			return fmt.Errorf("gitdiff: %s: fragments overlap at line %d", fileName(f), next.OldPosition)
+		}
+		if gap >= int64(maxGap) {
+			frags = append(frags, next)
+			continue
+		}
+
+		merged, err := mergeFragments(last, next, src, gap)
+		if err != nil {
+			return fmt.Errorf("gitdiff: %s: %w", fileName(f), err)
+		}
+		frags[len(frags)-1] = merged
+	}
+
+	f.TextFragments = frags
+	return nil
+}
+
+// fragmentOldStart returns the zero-indexed line of the source where the
+// fragment starts. Fragments without old lines refer to the line before the
+// fragment, so their position is already the zero-indexed start.
+func fragmentOldStart(f *TextFragment) int64 {
+	if f.OldLines == 0 {
+		return f.OldPosition
+	}
+	return f.OldPosition - 1
+}
+
+func fragmentNewStart(f *TextFragment) int64 {
+	if f.NewLines == 0 {
+		return f.NewPosition
+	}
+	return f.NewPosition - 1
+}
+
+// mergeFragments returns a fragment with the lines of a, gap lines of
+// context read from src, and the lines of b.
+func mergeFragments(a, b *TextFragment, src LineReaderAt, gap int64) (*TextFragment, error) {
+	context := make([][]byte, gap)
+	n, err := src.ReadLinesAt(context, fragmentOldStart(a)+a.OldLines)
+	if err != nil && !(err == io.EOF && int64(n) == gap) {
+		return nil, fmt.Errorf("reading context at line %d: %w", fragmentOldStart(a)+a.OldLines+int64(n)+1, err)
+	}
+
+	merged := &TextFragment{
+		Comment:         a.Comment,
+		OldLines:        a.OldLines + gap + b.OldLines,
+		NewLines:        a.NewLines + gap + b.NewLines,
+		LinesAdded:      a.LinesAdded + b.LinesAdded,
+		LinesDeleted:    a.LinesDeleted + b.LinesDeleted,
+		LeadingContext:  a.LeadingContext,
+		TrailingContext: b.TrailingContext,
+	}
+
+	merged.OldPosition = fragmentOldStart(a)
+	if merged.OldLines > 0 {
+		merged.OldPosition++
+	}
+	merged.NewPosition = fragmentNewStart(a)
+	if merged.NewLines > 0 {
+		merged.NewPosition++
+	}
+
+	// a fragment without changes is all context, so the context of the
+	// merged fragment extends through the gap
+	if a.LinesAdded+a.LinesDeleted == 0 {
+		merged.LeadingContext += gap + b.LeadingContext
+	}
+	if b.LinesAdded+b.LinesDeleted == 0 {
+		merged.TrailingContext += gap + a.TrailingContext
+	}
+
+	merged.Lines = make([]Line, 0, len(a.Lines)+int(gap)+len(b.Lines))
+	merged.Lines = append(merged.Lines, a.Lines...)
+	for _, line := range context {
+		merged.Lines = append(merged.Lines, Line{OpContext, string(line)})
+	}
+	merged.Lines = append(merged.Lines, b.Lines...)
+
+	merged.Annotations = joinAnnotations(a.Annotations, nil)
+	shift := len(a.Lines) + int(gap)
+	for _, an := range b.Annotations {
+		if an.FragmentLine > 0 {
+			an.FragmentLine += shift
+		}
+		merged.Annotations = append(merged.Annotations, an)
+	}
+
+	return merged, nil
+}
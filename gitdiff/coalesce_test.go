@@ -0,0 +1,96 @@
+package gitdiff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCoalesceHunks(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 1; i <= 20; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		oldLines = append(oldLines, line)
+		switch i {
+		case 5, 12:
+			newLines = append(newLines, fmt.Sprintf("changed %d\n", i))
+		case 19:
+		default:
+			newLines = append(newLines, line)
+		}
+	}
+	old, new := strings.Join(oldLines, ""), strings.Join(newLines, "")
+
+	tests := map[string]struct {
+		MaxGap    int
+		Positions [][4]int64
+	}{
+		"none": {
+			MaxGap:    0,
+			Positions: [][4]int64{{4, 3, 4, 3}, {11, 3, 11, 3}, {18, 3, 18, 2}},
+		},
+		"exact": {
+			MaxGap:    4,
+			Positions: [][4]int64{{4, 3, 4, 3}, {11, 3, 11, 3}, {18, 3, 18, 2}},
+		},
+		"all": {
+			MaxGap:    5,
+			Positions: [][4]int64{{4, 17, 4, 16}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := &File{
+				OldName:       "file.txt",
+				NewName:       "file.txt",
+				TextFragments: DiffText([]byte(old), []byte(new), DiffOptions{Context: 1}),
+			}
+			f.TextFragments[2].AnnotateLine(2, Annotation{Body: "deleted"})
+
+			if err := f.CoalesceHunks(&lineReaderAt{r: strings.NewReader(old)}, test.MaxGap); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var positions [][4]int64
+			for _, frag := range f.TextFragments {
+				if err := frag.Validate(); err != nil {
+					t.Errorf("invalid fragment: %v", err)
+				}
+				positions = append(positions, [4]int64{frag.OldPosition, frag.OldLines, frag.NewPosition, frag.NewLines})
+			}
+			if fmt.Sprint(positions) != fmt.Sprint(test.Positions) {
+				t.Fatalf("incorrect fragments\nexpected: %v\n  actual: %v", test.Positions, positions)
+			}
+
+			last := f.TextFragments[len(f.TextFragments)-1]
+			if len(last.Annotations) != 1 {
+				t.Fatalf("incorrect annotations: %+v", last.Annotations)
+			}
+			if line := last.Lines[last.Annotations[0].FragmentLine-1]; line.Op != OpDelete || line.Line != "line 19\n" {
+				t.Errorf("annotation is on the wrong line: %v", line)
+			}
+
+			var dst bytes.Buffer
+			if err := Apply(&dst, strings.NewReader(old), f); err != nil {
+				t.Fatalf("unexpected error applying coalesced file: %v", err)
+			}
+			if dst.String() != new {
+				t.Errorf("incorrect result applying coalesced file\n%s", dst.String())
+			}
+		})
+	}
+}
+
+func TestCoalesceHunksShortSource(t *testing.T) {
+	f := &File{
+		TextFragments: DiffText([]byte("a\nb\nc\nd\ne\n"), []byte("x\nb\nc\nd\ny\n"), DiffOptions{Context: 1}),
+	}
+	if len(f.TextFragments) != 2 {
+		t.Fatalf("expected 2 fragments, but got %d", len(f.TextFragments))
+	}
+	if err := f.CoalesceHunks(&lineReaderAt{r: strings.NewReader("a\nb\n")}, 10); err == nil {
+		t.Fatal("expected error coalescing with a short source, but got nil")
+	}
+}
@@ -0,0 +1,511 @@
+package gitdiff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// CodecVersion is the version of the binary encoding written by
+// File.MarshalBinary.
+//
+// The encoding is a sequence of fields, each a tag followed by a value. A tag
+// is a varint holding the field number and the wire type of the value, which
+// is either a zig-zag varint or a length-prefixed byte string; nested values,
+// such as fragments, are length-prefixed encodings of their own fields. This
+// makes the encoding forward-compatible: decoders skip fields with unknown
+// numbers, so new fields can be added without changing the version. Field
+// numbers are never reused. The version only changes if existing fields
+// change meaning, and decoders reject versions newer than CodecVersion.
+const CodecVersion = 1
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// File fields
+const (
+	fileOldName = iota + 1
+	fileNewName
+	fileFlags
+	fileOldMode
+	fileNewMode
+	fileOldOIDPrefix
+	fileNewOIDPrefix
+	fileScore
+	fileExtendedHeader
+	filePatchHeader
+	fileRaw
+	fileTextFragment
+	fileParentCount
+	fileCombinedFragment
+	fileBinaryFragment
+	fileReverseBinaryFragment
+)
+
+// File flags
+const (
+	flagIsNew = 1 << iota
+	flagIsDelete
+	flagIsCopy
+	flagIsRename
+	flagIsBinary
+	flagContentOmitted
+)
+
+// TextFragment fields
+const (
+	fragComment = iota + 1
+	fragOldPosition
+	fragOldLines
+	fragNewPosition
+	fragNewLines
+	fragLinesAdded
+	fragLinesDeleted
+	fragLeadingContext
+	fragTrailingContext
+	fragLine
+)
+
+// PatchHeader fields
+const (
+	headerSHA = iota + 1
+	headerAuthor
+	headerAuthorDate
+	headerCommitter
+	headerCommitterDate
+	headerTitle
+	headerBody
+	headerSubjectPrefix
+	headerBodyAppendix
+)
+
+// PatchIdentity fields
+const (
+	identityName = iota + 1
+	identityEmail
+)
+
+// RawEntry fields
+const (
+	rawOldMode = iota + 1
+	rawNewMode
+	rawOldOID
+	rawNewOID
+	rawStatus
+	rawScore
+	rawOldName
+	rawNewName
+)
+
+// BinaryFragment fields
+const (
+	binaryMethod = iota + 1
+	binarySize
+	binaryData
+)
+
+// errCodecTruncated is returned when encoded data ends in the middle of a
+// field.
+var errCodecTruncated = errors.New("gitdiff: truncated binary encoding")
+
+// MarshalBinary encodes f in a compact binary format, suitable for caching
+// parsed files. The encoding starts with CodecVersion. Fragments shared by
+// TextFragments and CombinedFragments are only encoded once. Dates in the
+// PatchHeader keep their offset from UTC, but not the name of their zone.
+func (f *File) MarshalBinary() ([]byte, error) {
+	e := &encoder{buf: []byte{CodecVersion}}
+	e.file(f)
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes a file encoded by MarshalBinary, replacing the
+// content of f. It returns an error if the data uses a newer version of the
+// encoding or is corrupt.
+func (f *File) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errCodecTruncated
+	}
+	if data[0] > CodecVersion {
+		return fmt.Errorf("gitdiff: unsupported binary encoding version %d", data[0])
+	}
+
+	*f = File{}
+	return decodeFields(data[1:], f.decodeField)
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(num, wire int) {
+	e.buf = appendUvarint(e.buf, uint64(num<<3|wire))
+}
+
+func (e *encoder) int(num int, v int64) {
+	if v != 0 {
+		e.tag(num, wireVarint)
+		e.buf = appendVarint(e.buf, v)
+	}
+}
+
+func (e *encoder) bytes(num int, b []byte) {
+	e.tag(num, wireBytes)
+	e.buf = appendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) string(num int, s string) {
+	if s != "" {
+		e.bytes(num, []byte(s))
+	}
+}
+
+func (e *encoder) message(num int, fn func(*encoder)) {
+	var sub encoder
+	fn(&sub)
+	e.bytes(num, sub.buf)
+}
+
+func (e *encoder) file(f *File) {
+	var flags int64
+	for _, flag := range []struct {
+		set bool
+		bit int64
+	}{
+		{f.IsNew, flagIsNew},
+		{f.IsDelete, flagIsDelete},
+		{f.IsCopy, flagIsCopy},
+		{f.IsRename, flagIsRename},
+		{f.IsBinary, flagIsBinary},
+		{f.ContentOmitted, flagContentOmitted},
+	} {
+		if flag.set {
+			flags |= flag.bit
+		}
+	}
+
+	e.string(fileOldName, f.OldName)
+	e.string(fileNewName, f.NewName)
+	e.int(fileFlags, flags)
+	e.int(fileOldMode, int64(f.OldMode))
+	e.int(fileNewMode, int64(f.NewMode))
+	e.string(fileOldOIDPrefix, f.OldOIDPrefix)
+	e.string(fileNewOIDPrefix, f.NewOIDPrefix)
+	e.int(fileScore, int64(f.Score))
+	for _, hdr := range f.ExtendedHeaders {
+		e.bytes(fileExtendedHeader, []byte(hdr))
+	}
+	if f.PatchHeader != nil {
+		e.message(filePatchHeader, func(e *encoder) { e.patchHeader(f.PatchHeader) })
+	}
+	if f.Raw != nil {
+		e.message(fileRaw, func(e *encoder) { e.rawEntry(f.Raw) })
+	}
+
+	index := make(map[*TextFragment]int64, len(f.TextFragments))
+	for i, frag := range f.TextFragments {
+		index[frag] = int64(i)
+		e.message(fileTextFragment, func(e *encoder) { e.textFragment(frag) })
+	}
+	e.int(fileParentCount, int64(f.ParentCount))
+	for _, frags := range f.CombinedFragments {
+		// combined fragments refer to entries in TextFragments by position
+		var b []byte
+		for _, frag := range frags {
+			i, ok := index[frag]
+			if !ok {
+				i = -1
+			}
+			b = appendVarint(b, i)
+		}
+		e.bytes(fileCombinedFragment, b)
+	}
+
+	if f.BinaryFragment != nil {
+		e.message(fileBinaryFragment, func(e *encoder) { e.binaryFragment(f.BinaryFragment) })
+	}
+	if f.ReverseBinaryFragment != nil {
+		e.message(fileReverseBinaryFragment, func(e *encoder) { e.binaryFragment(f.ReverseBinaryFragment) })
+	}
+}
+
+func (e *encoder) textFragment(frag *TextFragment) {
+	e.string(fragComment, frag.Comment)
+	e.int(fragOldPosition, frag.OldPosition)
+	e.int(fragOldLines, frag.OldLines)
+	e.int(fragNewPosition, frag.NewPosition)
+	e.int(fragNewLines, frag.NewLines)
+	e.int(fragLinesAdded, frag.LinesAdded)
+	e.int(fragLinesDeleted, frag.LinesDeleted)
+	e.int(fragLeadingContext, frag.LeadingContext)
+	e.int(fragTrailingContext, frag.TrailingContext)
+	for _, line := range frag.Lines {
+		// the first byte of each line is the operation
+		b := make([]byte, 0, len(line.Line)+1)
+		b = append(b, byte(line.Op))
+		e.bytes(fragLine, append(b, line.Line...))
+	}
+}
+
+func (e *encoder) patchHeader(h *PatchHeader) {
+	e.string(headerSHA, h.SHA)
+	if h.Author != nil {
+		e.message(headerAuthor, func(e *encoder) { e.identity(h.Author) })
+	}
+	e.time(headerAuthorDate, h.AuthorDate)
+	if h.Committer != nil {
+		e.message(headerCommitter, func(e *encoder) { e.identity(h.Committer) })
+	}
+	e.time(headerCommitterDate, h.CommitterDate)
+	e.string(headerTitle, h.Title)
+	e.string(headerBody, h.Body)
+	e.string(headerSubjectPrefix, h.SubjectPrefix)
+	e.string(headerBodyAppendix, h.BodyAppendix)
+}
+
+func (e *encoder) identity(id *PatchIdentity) {
+	e.string(identityName, id.Name)
+	e.string(identityEmail, id.Email)
+}
+
+func (e *encoder) time(num int, t time.Time) {
+	if !t.IsZero() {
+		b, _ := t.MarshalBinary()
+		e.bytes(num, b)
+	}
+}
+
+func (e *encoder) rawEntry(r *RawEntry) {
+	e.int(rawOldMode, int64(r.OldMode))
+	e.int(rawNewMode, int64(r.NewMode))
+	e.string(rawOldOID, r.OldOID)
+	e.string(rawNewOID, r.NewOID)
+	e.int(rawStatus, int64(r.Status))
+	e.int(rawScore, int64(r.Score))
+	e.string(rawOldName, r.OldName)
+	e.string(rawNewName, r.NewName)
+}
+
+func (e *encoder) binaryFragment(frag *BinaryFragment) {
+	e.int(binaryMethod, int64(frag.Method))
+	e.int(binarySize, frag.Size)
+	if frag.Data != nil {
+		e.bytes(binaryData, frag.Data)
+	}
+}
+
+// field is a decoded field. Varint fields set n and byte string fields set b.
+type field struct {
+	num int
+	n   int64
+	b   []byte
+}
+
+// decodeFields calls fn for each field in data. Fields with an unknown wire
+// type are an error, because their size is unknown.
+func decodeFields(data []byte, fn func(field) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errCodecTruncated
+		}
+		data = data[n:]
+
+		fd := field{num: int(tag >> 3)}
+		switch tag & 7 {
+		case wireVarint:
+			if fd.n, n = binary.Varint(data); n <= 0 {
+				return errCodecTruncated
+			}
+			data = data[n:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return errCodecTruncated
+			}
+			fd.b = data[n : n+int(size) : n+int(size)]
+			data = data[n+int(size):]
+		default:
+			return fmt.Errorf("gitdiff: invalid binary encoding: unknown wire type %d", tag&7)
+		}
+
+		if err := fn(fd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *File) decodeField(fd field) error {
+	switch fd.num {
+	case fileOldName:
+		f.OldName = string(fd.b)
+	case fileNewName:
+		f.NewName = string(fd.b)
+	case fileFlags:
+		f.IsNew = fd.n&flagIsNew != 0
+		f.IsDelete = fd.n&flagIsDelete != 0
+		f.IsCopy = fd.n&flagIsCopy != 0
+		f.IsRename = fd.n&flagIsRename != 0
+		f.IsBinary = fd.n&flagIsBinary != 0
+		f.ContentOmitted = fd.n&flagContentOmitted != 0
+	case fileOldMode:
+		f.OldMode = os.FileMode(fd.n)
+	case fileNewMode:
+		f.NewMode = os.FileMode(fd.n)
+	case fileOldOIDPrefix:
+		f.OldOIDPrefix = string(fd.b)
+	case fileNewOIDPrefix:
+		f.NewOIDPrefix = string(fd.b)
+	case fileScore:
+		f.Score = int(fd.n)
+	case fileExtendedHeader:
+		f.ExtendedHeaders = append(f.ExtendedHeaders, string(fd.b))
+	case filePatchHeader:
+		f.PatchHeader = &PatchHeader{}
+		return decodeFields(fd.b, f.PatchHeader.decodeField)
+	case fileRaw:
+		f.Raw = &RawEntry{}
+		return decodeFields(fd.b, f.Raw.decodeField)
+	case fileTextFragment:
+		frag := &TextFragment{}
+		f.TextFragments = append(f.TextFragments, frag)
+		return decodeFields(fd.b, frag.decodeField)
+	case fileParentCount:
+		f.ParentCount = int(fd.n)
+	case fileCombinedFragment:
+		var frags []*TextFragment
+		for b := fd.b; len(b) > 0; {
+			i, n := binary.Varint(b)
+			if n <= 0 {
+				return errCodecTruncated
+			}
+			b = b[n:]
+			if i < 0 || i >= int64(len(f.TextFragments)) {
+				return fmt.Errorf("gitdiff: invalid binary encoding: fragment index %d out of range", i)
+			}
+			frags = append(frags, f.TextFragments[i])
+		}
+		f.CombinedFragments = append(f.CombinedFragments, frags)
+	case fileBinaryFragment:
+		f.BinaryFragment = &BinaryFragment{}
+		return decodeFields(fd.b, f.BinaryFragment.decodeField)
+	case fileReverseBinaryFragment:
+		f.ReverseBinaryFragment = &BinaryFragment{}
+		return decodeFields(fd.b, f.ReverseBinaryFragment.decodeField)
+	}
+	return nil
+}
+
+func (frag *TextFragment) decodeField(fd field) error {
+	switch fd.num {
+	case fragComment:
+		frag.Comment = string(fd.b)
+	case fragOldPosition:
+		frag.OldPosition = fd.n
+	case fragOldLines:
+		frag.OldLines = fd.n
+	case fragNewPosition:
+		frag.NewPosition = fd.n
+	case fragNewLines:
+		frag.NewLines = fd.n
+	case fragLinesAdded:
+		frag.LinesAdded = fd.n
+	case fragLinesDeleted:
+		frag.LinesDeleted = fd.n
+	case fragLeadingContext:
+		frag.LeadingContext = fd.n
+	case fragTrailingContext:
+		frag.TrailingContext = fd.n
+	case fragLine:
+		if len(fd.b) == 0 {
+			return errCodecTruncated
+		}
+		frag.Lines = append(frag.Lines, Line{LineOp(fd.b[0]), string(fd.b[1:])})
+	}
+	return nil
+}
+
+func (h *PatchHeader) decodeField(fd field) error {
+	switch fd.num {
+	case headerSHA:
+		h.SHA = string(fd.b)
+	case headerAuthor:
+		h.Author = &PatchIdentity{}
+		return decodeFields(fd.b, h.Author.decodeField)
+	case headerAuthorDate:
+		return h.AuthorDate.UnmarshalBinary(fd.b)
+	case headerCommitter:
+		h.Committer = &PatchIdentity{}
+		return decodeFields(fd.b, h.Committer.decodeField)
+	case headerCommitterDate:
+		return h.CommitterDate.UnmarshalBinary(fd.b)
+	case headerTitle:
+		h.Title = string(fd.b)
+	case headerBody:
+		h.Body = string(fd.b)
+	case headerSubjectPrefix:
+		h.SubjectPrefix = string(fd.b)
+	case headerBodyAppendix:
+		h.BodyAppendix = string(fd.b)
+	}
+	return nil
+}
+
+func (id *PatchIdentity) decodeField(fd field) error {
+	switch fd.num {
+	case identityName:
+		id.Name = string(fd.b)
+	case identityEmail:
+		id.Email = string(fd.b)
+	}
+	return nil
+}
+
+func (r *RawEntry) decodeField(fd field) error {
+	switch fd.num {
+	case rawOldMode:
+		r.OldMode = os.FileMode(fd.n)
+	case rawNewMode:
+		r.NewMode = os.FileMode(fd.n)
+	case rawOldOID:
+		r.OldOID = string(fd.b)
+	case rawNewOID:
+		r.NewOID = string(fd.b)
+	case rawStatus:
+		r.Status = byte(fd.n)
+	case rawScore:
+		r.Score = int(fd.n)
+	case rawOldName:
+		r.OldName = string(fd.b)
+	case rawNewName:
+		r.NewName = string(fd.b)
+	}
+	return nil
+}
+
+func (frag *BinaryFragment) decodeField(fd field) error {
+	switch fd.num {
+	case binaryMethod:
+		frag.Method = BinaryPatchMethod(fd.n)
+	case binarySize:
+		frag.Size = fd.n
+	case binaryData:
+		frag.Data = append([]byte{}, fd.b...)
+	}
+	return nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(b, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(b, tmp[:binary.PutVarint(tmp[:], v)]...)
+}
@@ -0,0 +1,135 @@
+package gitdiff
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileMarshalBinary(t *testing.T) {
+	combined := []*TextFragment{
+		{OldPosition: 1, OldLines: 1, NewPosition: 1, NewLines: 1, LinesAdded: 1, LinesDeleted: 1,
+			Lines: []Line{{OpDelete, "one\n"}, {OpAdd, "merged\n"}}},
+		{OldPosition: 1, OldLines: 1, NewPosition: 1, NewLines: 1, LinesAdded: 1, LinesDeleted: 1,
+			Lines: []Line{{OpDelete, "two\n"}, {OpAdd, "merged"}}},
+	}
+
+	tests := map[string]*File{
+		"empty": {},
+		"text": {
+			OldName:         "dir/old.txt",
+			NewName:         "dir/new.txt",
+			IsRename:        true,
+			OldMode:         os.FileMode(0100644),
+			NewMode:         os.FileMode(0100755),
+			OldOIDPrefix:    "1111111",
+			NewOIDPrefix:    "2222222",
+			Score:           90,
+			ExtendedHeaders: []string{"x-tool value"},
+			PatchHeader: &PatchHeader{
+				SHA:           "61f5cd90bed4d204ee3feb3aa41ee91d4734855b",
+				Author:        &PatchIdentity{Name: "Morton Haypenny", Email: "mhaypenny@example.com"},
+				AuthorDate:    time.Date(2020, 4, 11, 15, 21, 23, 0, time.FixedZone("", -7*60*60)),
+				Committer:     &PatchIdentity{Name: "Committer", Email: "committer@example.com"},
+				CommitterDate: time.Date(2020, 4, 12, 1, 2, 3, 0, time.UTC),
+				Title:         "A sample commit",
+				Body:          "With a body.",
+				SubjectPrefix: "[PATCH]",
+				BodyAppendix:  "appendix",
+			},
+			Raw: &RawEntry{
+				OldMode: 0100644, NewMode: 0100755,
+				OldOID: "1111111", NewOID: "2222222",
+				Status: 'R', Score: 90,
+				OldName: "dir/old.txt", NewName: "dir/new.txt",
+			},
+			TextFragments: []*TextFragment{{
+				Comment:     "func()",
+				OldPosition: 3, OldLines: 2, NewPosition: 3, NewLines: 2,
+				LinesAdded: 1, LinesDeleted: 1, LeadingContext: 1,
+				Lines: []Line{{OpContext, "ctx\n"}, {OpDelete, "old\n"}, {OpAdd, "new"}},
+			}},
+		},
+		"combined": {
+			OldName:           "file.txt",
+			NewName:           "file.txt",
+			TextFragments:     combined,
+			ParentCount:       2,
+			CombinedFragments: [][]*TextFragment{combined},
+		},
+		"binary": {
+			NewName:               "image.png",
+			IsNew:                 true,
+			IsBinary:              true,
+			BinaryFragment:        &BinaryFragment{Method: BinaryPatchLiteral, Size: 3, Data: []byte{0, 1, 2}},
+			ReverseBinaryFragment: &BinaryFragment{Method: BinaryPatchLiteral, Data: []byte{}},
+		},
+		"omitted": {
+			OldName:        "gone.txt",
+			IsDelete:       true,
+			ContentOmitted: true,
+		},
+	}
+
+	for name, f := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := f.MarshalBinary()
+			if err != nil {
+				t.Fatalf("unexpected error encoding file: %v", err)
+			}
+
+			var decoded File
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatalf("unexpected error decoding file: %v", err)
+			}
+			if !reflect.DeepEqual(f, &decoded) {
+				t.Errorf("decoded file does not match\nexpected: %+v\n  actual: %+v", f, &decoded)
+			}
+			for i, frags := range decoded.CombinedFragments {
+				for j, frag := range frags {
+					if frag != decoded.TextFragments[i*decoded.ParentCount+j] {
+						t.Errorf("combined fragment %d,%d is not shared with TextFragments", i, j)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFileUnmarshalBinaryCompatibility(t *testing.T) {
+	var e encoder
+	e.buf = []byte{CodecVersion}
+	e.string(fileOldName, "file.txt")
+	e.int(99, 42)
+	e.bytes(100, []byte("future field"))
+	e.string(fileNewName, "file.txt")
+
+	var f File
+	if err := f.UnmarshalBinary(e.buf); err != nil {
+		t.Fatalf("unexpected error decoding file with unknown fields: %v", err)
+	}
+	if f.OldName != "file.txt" || f.NewName != "file.txt" {
+		t.Errorf("incorrect file: %+v", f)
+	}
+
+	tests := map[string]struct {
+		Data []byte
+		Err  string
+	}{
+		"empty":       {nil, "truncated"},
+		"newer":       {[]byte{CodecVersion + 1}, "unsupported binary encoding version"},
+		"truncated":   {e.buf[:len(e.buf)-2], "truncated"},
+		"wireType":    {[]byte{CodecVersion, fileOldName<<3 | 5}, "unknown wire type"},
+		"badFragment": {[]byte{CodecVersion, fileCombinedFragment<<3 | wireBytes, 1, 0}, "out of range"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := f.UnmarshalBinary(test.Data)
+			if err == nil || !strings.Contains(err.Error(), test.Err) {
+				t.Errorf("expected error containing %q, but got %v", test.Err, err)
+			}
+		})
+	}
+}
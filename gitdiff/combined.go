@@ -0,0 +1,294 @@
+package gitdiff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseCombinedFileHeader parses a file header for a combined diff, as
+// generated by git for merge commits with the --cc or -c options.
+func (p *parser) ParseCombinedFileHeader() (*File, error) {
+	var header string
+	for _, prefix := range []string{"diff --cc ", "diff --combined "} {
+		if strings.HasPrefix(p.Line(0), prefix) {
+			header = p.Line(0)[len(prefix):]
+			break
+		}
+	}
+	if header == "" {
+		return nil, nil
+	}
+
+	name, _, err := parseName(header, 0, 0)
+	if err != nil {
+		return nil, p.Errorf(0, "combined file header: %v", err)
+	}
+
+	f := &File{OldName: name, NewName: name}
+	for {
+		end, err := parseCombinedHeaderData(f, p.Line(1))
+		if err != nil {
+			return nil, p.Errorf(1, "combined file header: %v", err)
+		}
+
+		if err := p.Next(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		if end {
+			break
+		}
+	}
+	return f, nil
+}
+
+// parseCombinedHeaderData parses a single line of metadata from a combined
+// file header. Fields that have a value for each parent use the value for the
+// first parent. It returns true when header parsing is complete.
+func parseCombinedHeaderData(f *File, line string) (end bool, err error) {
+	line = strings.TrimSuffix(line, "\n")
+
+	switch {
+	case strings.HasPrefix(line, "@@@"):
+		return true, nil
+
+	case strings.HasPrefix(line, "index "):
+		parents, result, err := splitCombinedValues(line[len("index "):])
+		if err != nil {
+			return false, fmt.Errorf("invalid index line: %v", err)
+		}
+		f.OldOIDPrefix, f.NewOIDPrefix = parents[0], result
+		f.ParentCount = len(parents)
+
+	case strings.HasPrefix(line, "mode "):
+		parents, result, err := splitCombinedValues(line[len("mode "):])
+		if err != nil {
+			return false, fmt.Errorf("invalid mode line: %v", err)
+		}
+		if f.OldMode, err = parseMode(parents[0]); err != nil {
+			return false, err
+		}
+		if f.NewMode, err = parseMode(result); err != nil {
+			return false, err
+		}
+
+	case strings.HasPrefix(line, "new file mode "):
+		f.IsNew = true
+		f.NewMode, err = parseMode(line[len("new file mode "):])
+
+	case strings.HasPrefix(line, "deleted file mode "):
+		f.IsDelete = true
+		modes := strings.Split(line[len("deleted file mode "):], ",")
+		f.OldMode, err = parseMode(modes[0])
+		f.ParentCount = len(modes)
+
+	case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+		// names are the same as the name in the header line
+
+	default:
+		return true, nil
+	}
+	return false, err
+}
+
+// splitCombinedValues splits a "a,b..c" value into the parent and result parts.
+func splitCombinedValues(s string) (parents []string, result string, err error) {
+	parts := strings.SplitN(s, "..", 2)
+	if len(parts) < 2 {
+		return nil, "", fmt.Errorf("missing %q", "..")
+	}
+	return strings.Split(parts[0], ","), strings.SplitN(parts[1], " ", 2)[0], nil
+}
+
+// ParseCombinedTextFragments parses combined text fragments until the next
+// file header or the end of the stream and attaches them to the given file.
+// Each combined fragment produces one TextFragment per parent, describing the
+// changes between that parent and the result. It returns the number of
+// combined fragments that were parsed.
+func (p *parser) ParseCombinedTextFragments(f *File) (n int, err error) {
+	for {
+		start := p.offset
+		frags, err := p.ParseCombinedTextFragmentHeader()
+		if err != nil {
+			return n, err
+		}
+		if frags == nil {
+			return n, nil
+		}
+
+		if err := p.ParseCombinedTextChunk(frags); err != nil {
+			return n, err
+		}
+		if p.onFragment != nil {
+			p.onFragment(frags, start, p.offset)
+		}
+
+		f.TextFragments = append(f.TextFragments, frags...)
+		f.CombinedFragments = append(f.CombinedFragments, frags)
+		f.ParentCount = len(frags)
+		n++
+	}
+}
+
+// ParseCombinedTextFragmentHeader parses a combined fragment header, which
+// starts and ends with one more '@' character than the number of parents. It
+// returns one fragment for each parent.
+func (p *parser) ParseCombinedTextFragmentHeader() ([]*TextFragment, error) {
+	line := p.Line(0)
+	if !strings.HasPrefix(line, "@@@") {
+		return nil, nil
+	}
+
+	marks := strings.IndexFunc(line, func(c rune) bool { return c != '@' })
+	if marks < 0 {
+		return nil, p.Errorf(0, "invalid combined fragment header")
+	}
+	endMark := " " + line[:marks]
+
+	parts := strings.SplitAfterN(line[marks:], endMark, 2)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], " -") {
+		return nil, p.Errorf(0, "invalid combined fragment header")
+	}
+	comment := strings.TrimSpace(parts[1])
+
+	ranges := strings.Fields(parts[0][:len(parts[0])-len(endMark)])
+	if len(ranges) != marks {
+		return nil, p.Errorf(0, "invalid combined fragment header: expected %d ranges, found %d", marks, len(ranges))
+	}
+
+	result := ranges[len(ranges)-1]
+	if !strings.HasPrefix(result, "+") {
+		return nil, p.Errorf(0, "invalid combined fragment header: missing new range")
+	}
+	newPos, newLines, err := parseRange(result[1:])
+	if err != nil {
+		return nil, p.Errorf(0, "invalid combined fragment header: %v", err)
+	}
+
+	frags := make([]*TextFragment, marks-1)
+	for i, r := range ranges[:len(ranges)-1] {
+		if !strings.HasPrefix(r, "-") {
+			return nil, p.Errorf(0, "invalid combined fragment header: missing old range")
+		}
+
+		frag := &TextFragment{Comment: comment, NewPosition: newPos, NewLines: newLines}
+		if frag.OldPosition, frag.OldLines, err = parseRange(r[1:]); err != nil {
+			return nil, p.Errorf(0, "invalid combined fragment header: %v", err)
+		}
+		frags[i] = frag
+	}
+
+	if err := p.Next(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return frags, nil
+}
+
+// ParseCombinedTextChunk parses the lines of a combined fragment. Each line
+// starts with one operation column per parent: '-' if the line only appears
+// in that parent, '+' if the line is in the result but not in that parent,
+// and ' ' otherwise.
+func (p *parser) ParseCombinedTextChunk(frags []*TextFragment) error {
+	if p.Line(0) == "" {
+		return p.truncatedFragment(frags[0], frags[0].OldLines, frags[0].NewLines)
+	}
+
+	parents := len(frags)
+	oldLines := make([]int64, parents)
+	for i, frag := range frags {
+		oldLines[i] = frag.OldLines
+	}
+	newLines := frags[0].NewLines
+
+	remaining := func() bool {
+		for _, n := range oldLines {
+			if n > 0 {
+				return true
+			}
+		}
+		return newLines > 0
+	}
+
+	touched := make([]bool, parents)
+	for remaining() {
+		line := p.Line(0)
+		if len(line) <= parents {
+			return p.Errorf(0, "invalid combined line: %q", line)
+		}
+
+		ops, data := line[:parents], p.content(line[parents:])
+		if ops[0] == '\\' && isNoNewlineMarker(line) {
+			for i, frag := range frags {
+				if touched[i] {
+					removeLastNewline(frag)
+				}
+			}
+		} else {
+			deleted := strings.IndexByte(ops, '-') >= 0
+			if p.onAdd != nil && !deleted && strings.IndexByte(ops, '+') >= 0 {
+				p.onAdd(frags[0].NewPosition+frags[0].NewLines-newLines, p.addedLine(data))
+			}
+			for i, frag := range frags {
+				touched[i] = true
+				switch {
+				case ops[i] == '-':
+					oldLines[i]--
+					frag.LinesDeleted++
+					frag.TrailingContext = 0
+					p.appendLine(frag, OpDelete, data)
+				case ops[i] == ' ' && deleted:
+					touched[i] = false
+				case ops[i] == ' ':
+					oldLines[i]--
+					if frag.LinesAdded == 0 && frag.LinesDeleted == 0 {
+						frag.LeadingContext++
+					} else {
+						frag.TrailingContext++
+					}
+					p.appendLine(frag, OpContext, data)
+				case ops[i] == '+' && !deleted:
+					frag.LinesAdded++
+					frag.TrailingContext = 0
+					p.appendLine(frag, OpAdd, data)
+				default:
+					return p.Errorf(0, "invalid line operation: %q", ops)
+				}
+			}
+			if !deleted {
+				newLines--
+			}
+		}
+
+		if err := p.Next(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+	}
+
+	for i, frag := range frags {
+		if p.eof && (oldLines[i] > 0 || newLines > 0) {
+			return p.truncatedFragment(frag, oldLines[i], newLines)
+		}
+		if oldLines[i] != 0 || newLines != 0 {
+			return p.Errorf(0, "combined fragment header miscounts lines for parent %d: %+d old, %+d new", i+1, -oldLines[i], -newLines)
+		}
+	}
+
+	if isNoNewlineMarker(p.Line(0)) {
+		for i, frag := range frags {
+			if touched[i] {
+				removeLastNewline(frag)
+			}
+		}
+		if err := p.Next(); err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
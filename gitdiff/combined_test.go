@@ -0,0 +1,299 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCombinedFileHeader(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output *File
+		Err    bool
+	}{
+		"contentChange": {
+			Input: `diff --cc dir/file.txt
+index 1111111,2222222..3333333
+--- a/dir/file.txt
++++ b/dir/file.txt
+@@@ -1,3 -1,3 +1,3 @@@
+`,
+			Output: &File{
+				OldName:      "dir/file.txt",
+				NewName:      "dir/file.txt",
+				OldOIDPrefix: "1111111",
+				NewOIDPrefix: "3333333",
+				ParentCount:  2,
+			},
+		},
+		"modeChange": {
+			Input: `diff --combined file.sh
+index 1111111,2222222..3333333
+mode 100644,100644..100755
+`,
+			Output: &File{
+				OldName:      "file.sh",
+				NewName:      "file.sh",
+				OldMode:      os.FileMode(0100644),
+				NewMode:      os.FileMode(0100755),
+				OldOIDPrefix: "1111111",
+				NewOIDPrefix: "3333333",
+				ParentCount:  2,
+			},
+		},
+		"newFile": {
+			Input: `diff --cc new.txt
+new file mode 100644
+index 0000000,1111111..2222222
+`,
+			Output: &File{
+				OldName:      "new.txt",
+				NewName:      "new.txt",
+				NewMode:      os.FileMode(0100644),
+				OldOIDPrefix: "0000000",
+				NewOIDPrefix: "2222222",
+				IsNew:        true,
+				ParentCount:  2,
+			},
+		},
+		"invalidIndex": {
+			Input: `diff --cc file.txt
+index 1111111,2222222
+`,
+			Err: true,
+		},
+		"notCombined": {
+			Input: `diff --git a/file.txt b/file.txt
+`,
+			Output: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestParser(test.Input, true)
+
+			f, err := p.ParseCombinedFileHeader()
+			if test.Err {
+				if err == nil {
+					t.Fatalf("expected error parsing combined header, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing combined header: %v", err)
+			}
+			if !reflect.DeepEqual(test.Output, f) {
+				t.Errorf("incorrect file\nexpected: %+v\n  actual: %+v", test.Output, f)
+			}
+		})
+	}
+}
+
+func TestParseCombinedTextFragments(t *testing.T) {
+	tests := map[string]struct {
+		Input     string
+		Fragments []*TextFragment
+		Err       bool
+	}{
+		"twoParents": {
+			Input: `@@@ -1,3 -1,3 +1,3 @@@ comment
+  line 1
+- parent one
+ -parent two
+++merged
+  line 3
+`,
+			Fragments: []*TextFragment{
+				{
+					Comment:     "comment",
+					OldPosition: 1, OldLines: 3,
+					NewPosition: 1, NewLines: 3,
+					Lines: []Line{
+						{OpContext, "line 1\n"},
+						{OpDelete, "parent one\n"},
+						{OpAdd, "merged\n"},
+						{OpContext, "line 3\n"},
+					},
+					LinesAdded:      1,
+					LinesDeleted:    1,
+					LeadingContext:  1,
+					TrailingContext: 1,
+				},
+				{
+					Comment:     "comment",
+					OldPosition: 1, OldLines: 3,
+					NewPosition: 1, NewLines: 3,
+					Lines: []Line{
+						{OpContext, "line 1\n"},
+						{OpDelete, "parent two\n"},
+						{OpAdd, "merged\n"},
+						{OpContext, "line 3\n"},
+					},
+					LinesAdded:      1,
+					LinesDeleted:    1,
+					LeadingContext:  1,
+					TrailingContext: 1,
+				},
+			},
+		},
+		"addedInOneParent": {
+			Input: `@@@ -1,1 -1,2 +1,2 @@@
+  line 1
++ line 2
+\ No newline at end of file
+`,
+			Fragments: []*TextFragment{
+				{
+					OldPosition: 1, OldLines: 1,
+					NewPosition: 1, NewLines: 2,
+					Lines: []Line{
+						{OpContext, "line 1\n"},
+						{OpAdd, "line 2"},
+					},
+					LinesAdded:     1,
+					LeadingContext: 1,
+				},
+				{
+					OldPosition: 1, OldLines: 2,
+					NewPosition: 1, NewLines: 2,
+					Lines: []Line{
+						{OpContext, "line 1\n"},
+						{OpContext, "line 2"},
+					},
+					LeadingContext: 2,
+				},
+			},
+		},
+		"miscount": {
+			Input: `@@@ -1,3 -1,3 +1,3 @@@
+  line 1
+`,
+			Err: true,
+		},
+		"badRanges": {
+			Input: `@@@ -1,3 +1,3 @@@
+  line 1
+`,
+			Err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestParser(test.Input, true)
+
+			var f File
+			_, err := p.ParseCombinedTextFragments(&f)
+			if test.Err {
+				if err == nil {
+					t.Fatalf("expected error parsing combined fragments, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing combined fragments: %v", err)
+			}
+			if !reflect.DeepEqual(test.Fragments, f.TextFragments) {
+				t.Errorf("incorrect fragments\nexpected: %+v\n  actual: %+v", test.Fragments, f.TextFragments)
+			}
+			for i, frag := range f.TextFragments {
+				if err := frag.Validate(); err != nil {
+					t.Errorf("fragment %d is invalid: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyParent(t *testing.T) {
+	patch := `diff --cc file.txt
+index 1111111,2222222..3333333
+--- a/file.txt
++++ b/file.txt
+@@@ -1,3 -1,3 +1,3 @@@ comment
+  line 1
+- parent one
+ -parent two
+++merged
+  line 3
+`
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, but got %d", len(files))
+	}
+	f := files[0]
+
+	sources := []string{
+		"line 1\nparent one\nline 3\n",
+		"line 1\nparent two\nline 3\n",
+	}
+	for parent, src := range sources {
+		var dst bytes.Buffer
+		if err := f.ApplyParent(&dst, strings.NewReader(src), parent); err != nil {
+			t.Fatalf("unexpected error applying parent %d: %v", parent, err)
+		}
+		if expected := "line 1\nmerged\nline 3\n"; dst.String() != expected {
+			t.Errorf("incorrect result for parent %d\nexpected: %q\n  actual: %q", parent, expected, dst.String())
+		}
+	}
+
+	var dst bytes.Buffer
+	if err := f.ApplyParent(&dst, strings.NewReader(sources[1]), 0); !errors.Is(err, &Conflict{}) {
+		t.Errorf("expected conflict applying with wrong parent, but got %v", err)
+	}
+	if err := f.ApplyParent(&dst, strings.NewReader(sources[0]), 2); err == nil {
+		t.Errorf("expected error applying invalid parent, but got nil")
+	}
+}
+
+func TestParseCombinedFile(t *testing.T) {
+	patch := `diff --cc file.txt
+index 1111111,2222222,3333333..4444444
+--- a/file.txt
++++ b/file.txt
+@@@@ -1,2 -1,2 -1,2 +1,2 @@@@
+   line 1
+-  parent one
+ - parent two
+++ merged
+@@@@ -10,1 -10,1 -10,2 +10,1 @@@@ section
+   line 10
+  -line 11
+`
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, but got %d", len(files))
+	}
+	f := files[0]
+
+	if f.ParentCount != 3 {
+		t.Errorf("incorrect parent count: expected 3, actual %d", f.ParentCount)
+	}
+	if len(f.CombinedFragments) != 2 {
+		t.Fatalf("incorrect number of combined fragments: expected 2, actual %d", len(f.CombinedFragments))
+	}
+	for i, frags := range f.CombinedFragments {
+		if len(frags) != 3 {
+			t.Fatalf("combined fragment %d: expected 3 fragments, got %d", i, len(frags))
+		}
+		for j, frag := range frags {
+			if frag != f.TextFragments[3*i+j] {
+				t.Errorf("combined fragment %d: fragment %d is not the same as in TextFragments", i, j)
+			}
+		}
+	}
+	if frag := f.CombinedFragments[1][2]; frag.OldLines != 2 || frag.LinesDeleted != 1 || frag.Comment != "section" {
+		t.Errorf("incorrect fragment for third parent: %+v", frag)
+	}
+}
@@ -0,0 +1,45 @@
+package gitdiff
+
+// Compat is a set of parsing options for the quirks of patches from a
+// specific producer. Use Apply to set the options of a profile, like
+// CompatJGit, in the options for a parser. The zero ParseOptions are suited
+// to patches from current versions of Git and GNU diff.
+type Compat struct {
+	RejectUnknownHeaders    bool
+	RejectEmptyContextLines bool
+	Recount                 bool
+	LenientFragmentHeaders  bool
+}
+
+var (
+	// CompatGit1x sets Recount, so the line counts in the headers of text
+	// fragments are ignored and the lines of each fragment are counted
+	// instead.
+	CompatGit1x = Compat{
+		Recount: true,
+	}
+
+	// CompatJGit sets LenientFragmentHeaders, so text fragment headers
+	// with extra whitespace between their fields are accepted.
+	CompatJGit = Compat{
+		LenientFragmentHeaders: true,
+	}
+
+	// CompatStrict sets RejectUnknownHeaders and RejectEmptyContextLines,
+	// so unrecognized extended header lines and empty context lines are
+	// errors.
+	CompatStrict = Compat{
+		RejectUnknownHeaders:    true,
+		RejectEmptyContextLines: true,
+	}
+)
+
+// Apply returns opts with the leniency options set to those of the profile.
+// Other options, like ErrorContext and Offsets, are not changed.
+func (c Compat) Apply(opts ParseOptions) ParseOptions {
+	opts.RejectUnknownHeaders = c.RejectUnknownHeaders
+	opts.RejectEmptyContextLines = c.RejectEmptyContextLines
+	opts.Recount = c.Recount
+	opts.LenientFragmentHeaders = c.LenientFragmentHeaders
+	return opts
+}
@@ -0,0 +1,134 @@
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompat(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Compat Compat
+		Lines  [2]int64
+		Err    interface{}
+	}{
+		"default": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,3 @@
+ line 1
+
++line 3
+`,
+			Lines: [2]int64{2, 3},
+		},
+		"strictEmptyContext": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,3 @@
+ line 1
+
++line 3
+`,
+			Compat: CompatStrict,
+			Err:    "line 6: empty context line",
+		},
+		"strictUnknownHeader": {
+			Input: `diff --git a/file.txt b/file.txt
+mode 100644,100644..100644
+--- a/file.txt
++++ b/file.txt
+@@ -1 +1,2 @@
+ line 1
++line 2
+`,
+			Compat: CompatStrict,
+			Err:    "unknown extended header",
+		},
+		"git1xRecount": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,5 +1,2 @@
+ line 1
++line 2
++line 3
+diff --git a/other.txt b/other.txt
+--- a/other.txt
++++ b/other.txt
+@@ -1 +1 @@
+-a
++b
+`,
+			Compat: CompatGit1x,
+			Lines:  [2]int64{1, 3},
+		},
+		"git1xRecountTraditional": {
+			Input: `--- a/file.txt
++++ b/file.txt
+@@ -1 +1 @@
+-line 1
++line 2
+ line 3
+--- a/other.txt
++++ b/other.txt
+@@ -1 +1 @@
+-a
++b
+`,
+			Compat: CompatGit1x,
+			Lines:  [2]int64{2, 2},
+		},
+		"defaultMiscount": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,5 +1,2 @@
+ line 1
++line 2
++line 3
+diff --git a/other.txt b/other.txt
+`,
+			Err: "line 8: invalid line operation",
+		},
+		"jgitHeaderSpacing": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@  -1,2  +1,2  @@ func
+ line 1
+-line 2
++line 3
+`,
+			Compat: CompatJGit,
+			Lines:  [2]int64{2, 2},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := test.Compat.Apply(ParseOptions{})
+			files, _, err := ParseAllWithOptions(strings.NewReader(test.Input), opts)
+			if test.Err != nil {
+				assertError(t, test.Err, err, "parsing patch")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			if len(files) == 0 || len(files[0].TextFragments) != 1 {
+				t.Fatalf("incorrect files: %+v", files)
+			}
+
+			frag := files[0].TextFragments[0]
+			if lines := [2]int64{frag.OldLines, frag.NewLines}; lines != test.Lines {
+				t.Errorf("incorrect line counts: expected %v, actual %v", test.Lines, lines)
+			}
+			if err := frag.Validate(); err != nil {
+				t.Errorf("recounted fragment is invalid: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// Decompressor returns a reader for the decompressed content of r.
+type Decompressor func(r io.Reader) (io.Reader, error)
+
+type decompressor struct {
+	magic []byte
+	fn    Decompressor
+}
+
+var decompressors = struct {
+	sync.RWMutex
+	list []decompressor
+}{
+	list: []decompressor{
+		{[]byte{0x1f, 0x8b}, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{[]byte("BZh"), func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil }},
+	},
+}
+
+// RegisterDecompressor adds a decompressor for input that starts with the
+// bytes in magic, replacing any decompressor with the same magic. Parse and
+// Parser decompress their input with the first registered decompressor that
+// matches, so patches stored compressed, like "fix.patch.gz", can be parsed
+// directly, up to the MaxDecompressedBytes of the ParseOptions. Decompressors
+// for gzip and bzip2 are registered by default.
+// Register others to support more formats without adding their dependencies
+// to this package; for example, for zstd:
+//
+//	gitdiff.RegisterDecompressor([]byte{0x28, 0xb5, 0x2f, 0xfd}, func(r io.Reader) (io.Reader, error) {
+//		return zstd.NewReader(r)
+//	})
+//
+// A nil fn removes the decompressor for magic.
+func RegisterDecompressor(magic []byte, fn Decompressor) {
+	decompressors.Lock()
+	defer decompressors.Unlock()
+
+	list := make([]decompressor, 0, len(decompressors.list)+1)
+	for _, d := range decompressors.list {
+		if !bytes.Equal(d.magic, magic) {
+			list = append(list, d)
+		}
+	}
+	if fn != nil && len(magic) > 0 {
+		list = append(list, decompressor{append([]byte(nil), magic...), fn})
+	}
+	decompressors.list = list
+}
+
+// DefaultMaxDecompressedBytes is the limit on the size of decompressed input
+// used by Parse and by parsers with a zero MaxDecompressedBytes option.
+const DefaultMaxDecompressedBytes = 1 << 30
+
+// decompressInput checks if the input in br starts with the magic bytes of a
+// registered decompressor and returns a reader for the decompressed input if
+// it does. Otherwise, it returns br. Errors from the decompressor are returned
+// by the first read. If max is positive, reading more than max decompressed
+// bytes returns an error matching ErrPatchTooLarge.
+func decompressInput(br *bufio.Reader, max int64) *bufio.Reader {
+	decompressors.RLock()
+	defer decompressors.RUnlock()
+
+	for _, d := range decompressors.list {
+		// errors are returned again by the next read
+		if prefix, _ := br.Peek(len(d.magic)); bytes.Equal(prefix, d.magic) {
+			fn := d.fn
+			return bufio.NewReader(&lazyReader{open: func() (io.Reader, error) {
+				r, err := fn(br)
+				if err != nil || max <= 0 {
+					return r, err
+				}
+				return &limitReadCloser{ReadCloser: io.NopCloser(r), remaining: max, max: max}, nil
+			}})
+		}
+	}
+	return br
+}
+
+// lazyReader opens its reader on the first read, so errors from opening are
+// returned by Read.
+type lazyReader struct {
+	open func() (io.Reader, error)
+	r    io.Reader
+	err  error
+}
+
+func (lr *lazyReader) Read(p []byte) (int, error) {
+	if lr.r == nil && lr.err == nil {
+		lr.r, lr.err = lr.open()
+	}
+	if lr.err != nil {
+		return 0, lr.err
+	}
+	return lr.r.Read(p)
+}
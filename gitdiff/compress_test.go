@@ -0,0 +1,134 @@
+package gitdiff
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDecompressInput(t *testing.T) {
+	patch, err := os.ReadFile("testdata/one_file.patch")
+	if err != nil {
+		t.Fatalf("unexpected error reading patch: %v", err)
+	}
+	bz2, err := os.ReadFile("testdata/one_file.patch.bz2")
+	if err != nil {
+		t.Fatalf("unexpected error reading patch: %v", err)
+	}
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, _ = zw.Write(patch)
+	_ = zw.Close()
+
+	// a test format that stores the patch in reverse
+	RegisterDecompressor([]byte("REV!"), func(r io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		data = data[len("REV!"):]
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+		return bytes.NewReader(data), nil
+	})
+	defer RegisterDecompressor([]byte("REV!"), nil)
+
+	reversed := []byte("REV!")
+	for i := len(patch) - 1; i >= 0; i-- {
+		reversed = append(reversed, patch[i])
+	}
+
+	expected, _, err := ParseAll(bytes.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	tests := map[string]struct {
+		Input   []byte
+		Options ParseOptions
+		Err     interface{}
+	}{
+		"gzip":       {Input: gz.Bytes()},
+		"bzip2":      {Input: bz2},
+		"registered": {Input: reversed},
+		"uncompressed": {
+			Input: patch,
+		},
+		"corrupt": {
+			Input: append([]byte{0x1f, 0x8b}, "not gzip data"...),
+			Err:   gzip.ErrHeader,
+		},
+		"keepCompressed": {
+			Input:   gz.Bytes(),
+			Options: ParseOptions{KeepCompressed: true},
+		},
+		"tooLarge": {
+			Input:   gz.Bytes(),
+			Options: ParseOptions{MaxDecompressedBytes: int64(len(patch) - 1)},
+			Err:     ErrPatchTooLarge,
+		},
+		"exactLimit": {
+			Input:   gz.Bytes(),
+			Options: ParseOptions{MaxDecompressedBytes: int64(len(patch))},
+		},
+		"unlimited": {
+			Input:   gz.Bytes(),
+			Options: ParseOptions{MaxDecompressedBytes: -1},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAllWithOptions(bytes.NewReader(test.Input), test.Options)
+			if test.Err != nil {
+				assertError(t, test.Err, err, "parsing patch")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			if test.Options.KeepCompressed {
+				if len(files) != 0 {
+					t.Errorf("expected no files in compressed input, but got %d", len(files))
+				}
+				return
+			}
+			if !Equal(expected, files) {
+				t.Errorf("incorrect files\nexpected: %v\n  actual: %v", expected, files)
+			}
+
+			ch, err := Parse(bytes.NewReader(test.Input))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			var streamed []*File
+			for f := range ch {
+				streamed = append(streamed, f)
+			}
+			if !Equal(expected, streamed) {
+				t.Errorf("incorrect streamed files\nexpected: %v\n  actual: %v", expected, streamed)
+			}
+		})
+	}
+}
+
+func TestRegisterDecompressorRemove(t *testing.T) {
+	RegisterDecompressor([]byte("diff"), func(r io.Reader) (io.Reader, error) {
+		return strings.NewReader(""), nil
+	})
+	RegisterDecompressor([]byte("diff"), nil)
+
+	files, _, err := ParseAll(strings.NewReader("diff --git a/f b/f\nnew file mode 100644\n"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected 1 file after removing decompressor, but got %d", len(files))
+	}
+}
@@ -0,0 +1,87 @@
+package gitdiff
+
+import (
+	"errors"
+)
+
+// ReduceContext removes context lines from the text fragments of f so each
+// fragment has at most context unchanged lines before and after each change,
+// like regenerating the patch with git diff -U<context>. Fragments that have
+// runs of more than 2*context unchanged lines between changes are split. A
+// context of zero or less removes all context, producing fragments that
+// apply with ApplyOptions.UnidiffZero when they add lines at the start of
+// the file.
+//
+// The fragments after a split keep the comment of the original fragment and
+// the annotations of the lines they contain. Annotations of a whole fragment
+// are kept by the first fragment. Context cannot be added, because the
+// patch does not have the lines around a fragment; fragments that have less
+// context are not changed. Combined diffs cannot be reduced.
+func (f *File) ReduceContext(context int) error {
+	if f.ParentCount > 0 {
+		return errors.New("gitdiff: cannot reduce the context of a combined diff")
+	}
+	if context < 0 {
+		context = 0
+	}
+
+	var frags []*TextFragment
+	for _, frag := range f.TextFragments {
+		frags = append(frags, reduceFragmentContext(frag, context)...)
+	}
+	f.TextFragments = frags
+	return nil
+}
+
+// reduceFragmentContext returns the fragments with the changes of frag and at
+// most context lines of context.
+func reduceFragmentContext(frag *TextFragment, context int) []*TextFragment {
+	if frag.LinesAdded+frag.LinesDeleted == 0 {
+		return []*TextFragment{frag}
+	}
+
+	reduced := fragmentsFromLines(frag.Lines, context)
+
+	// fragmentsFromLines numbers lines from the start of frag, so the
+	// difference between the starts of frag and of the first line is the
+	// offset of every position
+	oldBase, newBase := fragmentOldStart(frag), fragmentNewStart(frag)
+
+	for i, r := range reduced {
+		start := fragmentLineIndex(frag, fragmentOldStart(r), fragmentNewStart(r))
+
+		r.Comment = frag.Comment
+		r.OldPosition += oldBase
+		r.NewPosition += newBase
+
+		for _, an := range frag.Annotations {
+			switch {
+			case an.FragmentLine == 0 && i == 0:
+				r.Annotations = append(r.Annotations, an)
+			case an.FragmentLine > start && an.FragmentLine <= start+len(r.Lines):
+				an.FragmentLine -= start
+				r.Annotations = append(r.Annotations, an)
+			}
+		}
+	}
+	return reduced
+}
+
+// fragmentLineIndex returns the index of the line in the lines of frag that
+// follows oldLines old lines and newLines new lines. Every line is an old
+// line, a new line, or both, so the index is unique.
+func fragmentLineIndex(frag *TextFragment, oldLines, newLines int64) int {
+	var o, n int64
+	for i, line := range frag.Lines {
+		if o == oldLines && n == newLines {
+			return i
+		}
+		if line.Old() {
+			o++
+		}
+		if line.New() {
+			n++
+		}
+	}
+	return len(frag.Lines)
+}
@@ -0,0 +1,156 @@
+package gitdiff
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReduceContext(t *testing.T) {
+	const patch = `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -2,12 +2,12 @@ func
+ b
+ c
+ d
+-e
++E
+ f
+ g
+ h
+ i
+-j
++J
+ k
+ l
+ m
+`
+
+	tests := map[string]struct {
+		Context int
+		Headers []string
+	}{
+		"unchanged": {
+			Context: 3,
+			Headers: []string{"@@ -2,12 +2,12 @@ func"},
+		},
+		"split": {
+			Context: 1,
+			Headers: []string{"@@ -4,3 +4,3 @@ func", "@@ -9,3 +9,3 @@ func"},
+		},
+		"merged": {
+			Context: 2,
+			Headers: []string{"@@ -3,10 +3,10 @@ func"},
+		},
+		"zero": {
+			Context: 0,
+			Headers: []string{"@@ -5,1 +5,1 @@ func", "@@ -10,1 +10,1 @@ func"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			f := files[0]
+			f.TextFragments[0].Annotations = append(f.TextFragments[0].Annotations, Annotation{Body: "fragment"})
+			f.TextFragments[0].AnnotateLine(10, Annotation{Body: "line j"})
+
+			if err := f.ReduceContext(test.Context); err != nil {
+				t.Fatalf("unexpected error reducing context: %v", err)
+			}
+
+			var headers []string
+			for _, frag := range f.TextFragments {
+				headers = append(headers, strings.TrimSuffix(frag.Header(), "\n"))
+				if err := frag.Validate(); err != nil {
+					t.Errorf("invalid fragment %s: %v", frag.Header(), err)
+				}
+			}
+			if !reflect.DeepEqual(test.Headers, headers) {
+				t.Errorf("incorrect fragments\nexpected: %q\n  actual: %q", test.Headers, headers)
+			}
+
+			first := f.TextFragments[0]
+			if len(first.LineAnnotations(0)) != 1 {
+				t.Errorf("fragment annotation was not kept: %+v", first.Annotations)
+			}
+			last := f.TextFragments[len(f.TextFragments)-1]
+			var found bool
+			for i, line := range last.Lines {
+				if an := last.LineAnnotations(i + 1); len(an) > 0 {
+					found = line.Line == "j\n" && an[0].Body == "line j"
+				}
+			}
+			if !found {
+				t.Errorf("line annotation was not moved: %+v", last.Annotations)
+			}
+		})
+	}
+}
+
+func TestZeroContextRoundTrip(t *testing.T) {
+	const src = "a\nb\nc\nd\ne\nf\ng\nh\n"
+
+	tests := map[string]string{
+		"insertStart":  "new\na\nb\nc\nd\ne\nf\ng\nh\n",
+		"insertMiddle": "a\nb\nc\nnew 1\nnew 2\nd\ne\nf\ng\nh\n",
+		"insertEnd":    "a\nb\nc\nd\ne\nf\ng\nh\nnew\n",
+		"delete":       "a\nc\nd\ne\nf\nh\n",
+		"change":       "a\nB\nc\nd\ne\nF\ng\nh\n",
+		"mixed":        "new\na\nc\nd\nE\ne\nf\ng\n",
+	}
+
+	for name, dst := range tests {
+		t.Run(name, func(t *testing.T) {
+			frags := DiffText([]byte(src), []byte(dst), DiffOptions{Context: -1})
+			for _, frag := range frags {
+				if frag.LeadingContext != 0 || frag.TrailingContext != 0 {
+					t.Fatalf("fragment has context: %s", frag)
+				}
+			}
+
+			f := &File{OldName: "file.txt", NewName: "file.txt", TextFragments: frags}
+			files, _, err := ParseAll(strings.NewReader(f.String()))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v\n%s", err, f)
+			}
+
+			for _, opts := range []ApplyOptions{
+				{UnidiffZero: true},
+				{UnidiffZero: true, MaxOffset: 10},
+			} {
+				var b bytes.Buffer
+				if err := NewApplierWithOptions(strings.NewReader(src), opts).ApplyFile(&b, files[0]); err != nil {
+					t.Fatalf("unexpected error applying patch: %v\n%s", err, f)
+				}
+				if b.String() != dst {
+					t.Errorf("incorrect result\nexpected: %q\n  actual: %q\npatch:\n%s", dst, b.String(), f)
+				}
+			}
+		})
+	}
+}
+
+func TestZeroContextOffset(t *testing.T) {
+	// the deleted line also appears earlier in the source, but fragments
+	// without context only apply at their positions
+	patch := `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -5 +4,0 @@
+-x
+`
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	var b bytes.Buffer
+	err = NewApplierWithOptions(strings.NewReader("x\na\nb\nc\nd\n"), ApplyOptions{MaxOffset: -1}).ApplyFile(&b, files[0])
+	assertError(t, &Conflict{}, err, "applying patch")
+}
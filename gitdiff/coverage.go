@@ -0,0 +1,132 @@
+package gitdiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Coverage records which lines of a set of files are covered by tests. It
+// maps the slash-separated path of each file to the numbers of its
+// executable lines, with true for lines that are covered. Lines that are not
+// in the map are not executable and are ignored.
+type Coverage map[string]map[int64]bool
+
+// ParseCoverProfile parses a coverage profile created by go test with the
+// -coverprofile option. The profile names files by their import path, so
+// prefix, usually the module path followed by a slash, is removed from each
+// name to get the path of the file in the repository. A line is covered if
+// any block that contains it was executed.
+func ParseCoverProfile(r io.Reader, prefix string) (Coverage, error) {
+	cov := make(Coverage)
+
+	s := bufio.NewScanner(r)
+	lineno := 0
+	for s.Scan() {
+		lineno++
+		line := strings.TrimSpace(s.Text())
+		if lineno == 1 {
+			if !strings.HasPrefix(line, "mode: ") {
+				return nil, fmt.Errorf("gitdiff: cover profile line %d: missing mode", lineno)
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+
+		name, start, end, count, err := parseCoverBlock(line)
+		if err != nil {
+			return nil, fmt.Errorf("gitdiff: cover profile line %d: %v", lineno, err)
+		}
+		name = strings.TrimPrefix(name, prefix)
+
+		lines := cov[name]
+		if lines == nil {
+			lines = make(map[int64]bool)
+			cov[name] = lines
+		}
+		for n := start; n <= end; n++ {
+			lines[n] = lines[n] || count > 0
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return cov, nil
+}
+
+// parseCoverBlock parses a block of a cover profile, like
+// "example.com/pkg/file.go:10.2,12.16 2 1".
+func parseCoverBlock(line string) (name string, start, end, count int64, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return "", 0, 0, 0, fmt.Errorf("invalid block: %q", line)
+	}
+	loc := strings.Join(fields[:len(fields)-2], " ")
+
+	if count, err = strconv.ParseInt(fields[len(fields)-1], 10, 64); err != nil {
+		return "", 0, 0, 0, fmt.Errorf("invalid count: %q", fields[len(fields)-1])
+	}
+
+	i := strings.LastIndexByte(loc, ':')
+	if i < 0 {
+		return "", 0, 0, 0, fmt.Errorf("invalid block: %q", line)
+	}
+	name = loc[:i]
+
+	var startCol, endCol int64
+	if n, _ := fmt.Sscanf(loc[i+1:], "%d.%d,%d.%d", &start, &startCol, &end, &endCol); n != 4 || start > end {
+		return "", 0, 0, 0, fmt.Errorf("invalid range: %q", loc[i+1:])
+	}
+	return name, start, end, count, nil
+}
+
+// FileCoverage reports the coverage of the lines added to a file by a patch.
+type FileCoverage struct {
+	// Name is the new name of the file.
+	Name string
+
+	// Covered and Uncovered contain the numbers of the added executable
+	// lines in the new version of the file.
+	Covered   []int64
+	Uncovered []int64
+}
+
+// PatchCoverage returns the coverage of the lines added by the patch in files,
+// for patch coverage checks like those of code review tools. cov must describe
+// the files after the patch is applied. Files without added executable lines
+// are omitted.
+func PatchCoverage(files []*File, cov Coverage) []FileCoverage {
+	var result []FileCoverage
+	for _, f := range files {
+		lines, ok := cov[f.NewName]
+		if f.IsDelete || !ok {
+			continue
+		}
+
+		fc := FileCoverage{Name: f.NewName}
+		for _, frag := range f.TextFragments {
+			next := fragmentNewStart(frag) + 1
+			for _, line := range frag.Lines {
+				if !line.New() {
+					continue
+				}
+				if covered, ok := lines[next]; ok && line.Op == OpAdd {
+					if covered {
+						fc.Covered = append(fc.Covered, next)
+					} else {
+						fc.Uncovered = append(fc.Uncovered, next)
+					}
+				}
+				next++
+			}
+		}
+		if len(fc.Covered)+len(fc.Uncovered) > 0 {
+			result = append(result, fc)
+		}
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCoverProfile(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output Coverage
+		Err    interface{}
+	}{
+		"blocks": {
+			Input: `mode: set
+example.com/mod/pkg/file.go:3.14,5.2 2 1
+example.com/mod/pkg/file.go:5.2,7.3 1 0
+example.com/mod/main.go:10.1,10.20 1 0
+`,
+			Output: Coverage{
+				"pkg/file.go": {3: true, 4: true, 5: true, 6: false, 7: false},
+				"main.go":     {10: false},
+			},
+		},
+		"missingMode": {
+			Input: "example.com/mod/main.go:10.1,10.20 1 0\n",
+			Err:   "missing mode",
+		},
+		"invalidRange": {
+			Input: "mode: count\nexample.com/mod/main.go:10.1 1 0\n",
+			Err:   "invalid range",
+		},
+		"invalidCount": {
+			Input: "mode: count\nexample.com/mod/main.go:10.1,11.2 1 x\n",
+			Err:   "invalid count",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cov, err := ParseCoverProfile(strings.NewReader(test.Input), "example.com/mod/")
+			if test.Err != nil {
+				assertError(t, test.Err, err, "parsing profile")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing profile: %v", err)
+			}
+			if !reflect.DeepEqual(test.Output, cov) {
+				t.Errorf("incorrect coverage\nexpected: %v\n  actual: %v", test.Output, cov)
+			}
+		})
+	}
+}
+
+func TestPatchCoverage(t *testing.T) {
+	patch := `diff --git a/pkg/file.go b/pkg/file.go
+--- a/pkg/file.go
++++ b/pkg/file.go
+@@ -2,3 +2,5 @@
+ func f() {
+-	old()
++	a()
++	b()
++	c()
+ }
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-old
++new
+diff --git a/pkg/gone.go b/pkg/gone.go
+deleted file mode 100644
+--- a/pkg/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package pkg
+diff --git a/pkg/other.go b/pkg/other.go
+--- a/pkg/other.go
++++ b/pkg/other.go
+@@ -1 +1 @@
+-// old comment
++// new comment
+`
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	cov := Coverage{
+		"pkg/file.go":  {2: true, 3: true, 4: false, 6: true},
+		"pkg/gone.go":  {1: true},
+		"pkg/other.go": {3: true},
+	}
+
+	expected := []FileCoverage{
+		{Name: "pkg/file.go", Covered: []int64{3}, Uncovered: []int64{4}},
+	}
+	if result := PatchCoverage(files, cov); !reflect.DeepEqual(expected, result) {
+		t.Errorf("incorrect coverage\nexpected: %+v\n  actual: %+v", expected, result)
+	}
+}
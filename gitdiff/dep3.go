@@ -0,0 +1,170 @@
+package gitdiff
+
+import (
+	"strings"
+	"time"
+)
+
+// dep3DateLayout is the format of the Last-Update field.
+const dep3DateLayout = "2006-01-02"
+
+// DEP3Field is a field of a DEP3Header. Values of fields that span multiple
+// lines contain the lines separated by newlines, without the leading space of
+// the continuation lines.
+type DEP3Field struct {
+	Name  string
+	Value string
+}
+
+// DEP3Header is the metadata at the start of a patch in a Debian package, as
+// described by DEP-3 (https://dep-team.pages.debian.net/deps/dep3/). It keeps
+// every field in order so that formatting a parsed header preserves fields
+// that the helper methods do not cover, such as Author or Bug-Ubuntu.
+type DEP3Header struct {
+	Fields []DEP3Field
+
+	// Text is the content after the fields and before the diff, including
+	// the empty line or "---" line that ends the fields.
+	Text string
+}
+
+// ParseDEP3Header parses the DEP-3 fields at the start of s, usually the
+// preamble returned by ParseAll. The fields end at an empty line, a line
+// starting with "---", or the first line that is not a field. It returns
+// false if s does not start with a field.
+func ParseDEP3Header(s string) (*DEP3Header, bool) {
+	h := &DEP3Header{}
+
+	for len(s) > 0 {
+		line := s
+		next := len(s)
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			line, next = s[:i], i+1
+		}
+
+		if n := len(h.Fields); n > 0 && (line != "" && (line[0] == ' ' || line[0] == '\t')) {
+			value := strings.TrimRight(line[1:], " \t\r")
+			if value == "." {
+				value = ""
+			}
+			h.Fields[n-1].Value += "\n" + value
+		} else if name, value, ok := parseDEP3Field(line); ok {
+			h.Fields = append(h.Fields, DEP3Field{Name: name, Value: value})
+		} else {
+			break
+		}
+		s = s[next:]
+	}
+
+	if len(h.Fields) == 0 {
+		return nil, false
+	}
+	h.Text = s
+	return h, true
+}
+
+func parseDEP3Field(line string) (name, value string, ok bool) {
+	i := strings.IndexByte(line, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	for _, c := range line[:i] {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return "", "", false
+		}
+	}
+	return line[:i], strings.TrimSpace(line[i+1:]), true
+}
+
+// Get returns the value of the first field with the given name, ignoring
+// case, or an empty string if there is no such field.
+func (h *DEP3Header) Get(name string) string {
+	for _, f := range h.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set sets the value of the first field with the given name, ignoring case,
+// or adds the field at the end of the header if there is no such field.
+func (h *DEP3Header) Set(name, value string) {
+	for i, f := range h.Fields {
+		if strings.EqualFold(f.Name, name) {
+			h.Fields[i].Value = value
+			return
+		}
+	}
+	h.Fields = append(h.Fields, DEP3Field{Name: name, Value: value})
+}
+
+// Description returns the Description field or, if the header does not have
+// one, the Subject field, which DEP-3 accepts as an alias. The first line is
+// the summary and any other lines are the long description.
+func (h *DEP3Header) Description() string {
+	if d := h.Get("Description"); d != "" {
+		return d
+	}
+	return h.Get("Subject")
+}
+
+// Origin returns the Origin field, which says where the patch comes from,
+// such as "upstream, https://example.com/commit/abc123".
+func (h *DEP3Header) Origin() string {
+	return h.Get("Origin")
+}
+
+// Bug returns the Bug field, the URL of the upstream bug report.
+func (h *DEP3Header) Bug() string {
+	return h.Get("Bug")
+}
+
+// Forwarded returns the Forwarded field, which is "no", "not-needed", or
+// where the patch was sent upstream.
+func (h *DEP3Header) Forwarded() string {
+	return h.Get("Forwarded")
+}
+
+// LastUpdate returns the date of the Last-Update field. It returns the zero
+// time and no error if the header does not have the field.
+func (h *DEP3Header) LastUpdate() (time.Time, error) {
+	v := h.Get("Last-Update")
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dep3DateLayout, v)
+}
+
+// SetLastUpdate sets the Last-Update field to the date of t.
+func (h *DEP3Header) SetLastUpdate(t time.Time) {
+	h.Set("Last-Update", t.Format(dep3DateLayout))
+}
+
+// String returns the header as it appears in a patch, followed by Text. Empty
+// lines in multi-line values are written as " .".
+func (h *DEP3Header) String() string {
+	var b strings.Builder
+	for _, f := range h.Fields {
+		lines := strings.Split(f.Value, "\n")
+
+		b.WriteString(f.Name)
+		b.WriteString(":")
+		if lines[0] != "" {
+			b.WriteString(" ")
+			b.WriteString(lines[0])
+		}
+		b.WriteString("\n")
+
+		for _, line := range lines[1:] {
+			if line == "" {
+				line = "."
+			}
+			b.WriteString(" ")
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString(h.Text)
+	return b.String()
+}
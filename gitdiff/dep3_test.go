@@ -0,0 +1,160 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDEP3Header(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Header *DEP3Header
+		Output string
+	}{
+		"fields": {
+			Input: `Description: Fix crash on empty input
+ The parser dereferenced a nil pointer when the input was empty.
+ .
+ Check the length first.
+Origin: upstream, https://example.com/commit/abc123
+Bug: https://example.com/issues/42
+Bug-Debian: https://bugs.debian.org/123456
+Forwarded: not-needed
+Author: Jane Doe <jane@example.com>
+Last-Update: 2024-03-15
+---
+`,
+			Header: &DEP3Header{
+				Fields: []DEP3Field{
+					{"Description", "Fix crash on empty input\nThe parser dereferenced a nil pointer when the input was empty.\n\nCheck the length first."},
+					{"Origin", "upstream, https://example.com/commit/abc123"},
+					{"Bug", "https://example.com/issues/42"},
+					{"Bug-Debian", "https://bugs.debian.org/123456"},
+					{"Forwarded", "not-needed"},
+					{"Author", "Jane Doe <jane@example.com>"},
+					{"Last-Update", "2024-03-15"},
+				},
+				Text: "---\n",
+			},
+		},
+		"freeText": {
+			Input: "Subject: Use the system library\nForwarded: no\n\nThis patch is Debian specific.\n",
+			Header: &DEP3Header{
+				Fields: []DEP3Field{
+					{"Subject", "Use the system library"},
+					{"Forwarded", "no"},
+				},
+				Text: "\nThis patch is Debian specific.\n",
+			},
+		},
+		"noFinalNewline": {
+			Input: "Forwarded: no",
+			Header: &DEP3Header{
+				Fields: []DEP3Field{{"Forwarded", "no"}},
+			},
+			Output: "Forwarded: no\n",
+		},
+		"notHeader": {
+			Input: "This patch fixes the build.\nForwarded: no\n",
+		},
+		"empty": {
+			Input: "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h, ok := ParseDEP3Header(test.Input)
+			if test.Header == nil {
+				if ok {
+					t.Fatalf("expected no header, but got %+v", h)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("expected header, but got none")
+			}
+			if !reflect.DeepEqual(test.Header, h) {
+				t.Errorf("incorrect header\nexpected: %+v\n  actual: %+v", test.Header, h)
+			}
+			output := test.Output
+			if output == "" {
+				output = test.Input
+			}
+			if s := h.String(); s != output {
+				t.Errorf("incorrect formatted header\nexpected: %q\n  actual: %q", output, s)
+			}
+		})
+	}
+}
+
+func TestDEP3HeaderFields(t *testing.T) {
+	h := &DEP3Header{}
+	if d, err := h.LastUpdate(); err != nil || !d.IsZero() {
+		t.Errorf("incorrect empty Last-Update: %v, %v", d, err)
+	}
+
+	h.Set("Subject", "Old summary")
+	h.Set("origin", "vendor")
+	h.Set("Description", "Summary\nLong text")
+	h.Set("Origin", "upstream")
+	h.SetLastUpdate(time.Date(2023, time.December, 1, 12, 0, 0, 0, time.UTC))
+
+	if d := h.Description(); d != "Summary\nLong text" {
+		t.Errorf("incorrect description: %q", d)
+	}
+	if o := h.Origin(); o != "upstream" {
+		t.Errorf("incorrect origin: %q", o)
+	}
+	if b := h.Bug(); b != "" {
+		t.Errorf("incorrect bug: %q", b)
+	}
+	if d, err := h.LastUpdate(); err != nil || !d.Equal(time.Date(2023, time.December, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("incorrect Last-Update: %v, %v", d, err)
+	}
+
+	expected := "Subject: Old summary\norigin: upstream\nDescription: Summary\n Long text\nLast-Update: 2023-12-01\n"
+	if s := h.String(); s != expected {
+		t.Errorf("incorrect formatted header\nexpected: %q\n  actual: %q", expected, s)
+	}
+
+	h.Set("Last-Update", "yesterday")
+	if _, err := h.LastUpdate(); err == nil {
+		t.Error("expected error parsing invalid Last-Update, but got nil")
+	}
+}
+
+func TestDEP3HeaderPreamble(t *testing.T) {
+	patch := `Description: Fix typo
+Forwarded: no
+---
+diff --git a/README b/README
+--- a/README
++++ b/README
+@@ -1 +1 @@
+-teh
++the
+`
+	files, preamble, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	h, ok := ParseDEP3Header(preamble)
+	if !ok {
+		t.Fatalf("expected DEP-3 header in preamble %q", preamble)
+	}
+	if h.Description() != "Fix typo" || h.Forwarded() != "no" {
+		t.Errorf("incorrect header: %+v", h)
+	}
+
+	out := h.String()
+	for _, f := range files {
+		out += f.String()
+	}
+	if out != patch {
+		t.Errorf("incorrect patch\nexpected: %q\n  actual: %q", patch, out)
+	}
+}
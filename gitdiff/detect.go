@@ -0,0 +1,118 @@
+package gitdiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// detectSize is the number of bytes DetectFormat reads from a stream.
+const detectSize = 32 * 1024
+
+// Format identifies the format of a patch.
+type Format int
+
+const (
+	// FormatUnknown is a stream that is not recognized as a patch.
+	FormatUnknown Format = iota
+
+	// FormatGit is a patch generated by git diff, git show, or git log.
+	FormatGit
+
+	// FormatCombined is a combined diff, as generated by git for merge
+	// commits with the --cc or -c options.
+	FormatCombined
+
+	// FormatUnified is a unified diff without Git file headers, as
+	// generated by diff -u.
+	FormatUnified
+
+	// FormatContext is a context diff, as generated by diff -c. The gitdiff
+	// package cannot parse context diffs.
+	FormatContext
+
+	// FormatMbox is a series of patches in the UNIX mailbox format, as
+	// generated by git format-patch.
+	FormatMbox
+
+	// FormatBinary is a patch generated by git diff that only contains
+	// changes to binary files.
+	FormatBinary
+)
+
+var formatNames = []string{
+	FormatUnknown:  "unknown",
+	FormatGit:      "git",
+	FormatCombined: "combined",
+	FormatUnified:  "unified",
+	FormatContext:  "context",
+	FormatMbox:     "mbox",
+	FormatBinary:   "binary",
+}
+
+func (f Format) String() string {
+	if f >= 0 && int(f) < len(formatNames) {
+		return formatNames[f]
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// DetectFormat reads the start of r to determine the format of the patch it
+// contains. It returns the format and a reader that produces the full
+// content of r, including the data read by DetectFormat, so the patch can be
+// passed to a parser. Only the first 32 KiB of the stream are examined.
+func DetectFormat(r io.Reader) (Format, io.Reader, error) {
+	br := bufio.NewReaderSize(r, detectSize)
+	data, err := br.Peek(detectSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return FormatUnknown, br, err
+	}
+	return detectFormat(data), br, nil
+}
+
+func detectFormat(data []byte) Format {
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], mailHeaderPrefix) {
+		return FormatMbox
+	}
+
+	for i, line := range lines {
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1]
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --cc "), strings.HasPrefix(line, "diff --combined "):
+			return FormatCombined
+		case strings.HasPrefix(line, "diff --git "):
+			if isBinaryOnly(lines[i:]) {
+				return FormatBinary
+			}
+			return FormatGit
+		case strings.HasPrefix(line, "--- ") && strings.HasPrefix(next, "+++ "):
+			return FormatUnified
+		case strings.HasPrefix(line, "*** ") && strings.HasPrefix(next, "--- "):
+			return FormatContext
+		case strings.TrimRight(line, "\r\n") == "***************":
+			return FormatContext
+		}
+	}
+	return FormatUnknown
+}
+
+// isBinaryOnly returns true if lines contain changes to binary files and do
+// not contain any text fragments.
+func isBinaryOnly(lines []string) bool {
+	binary := false
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			return false
+		case strings.HasPrefix(line, "GIT binary patch"), strings.HasPrefix(line, "Binary files "):
+			binary = true
+		}
+	}
+	return binary
+}
@@ -0,0 +1,136 @@
+package gitdiff
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Format Format
+	}{
+		"git": {
+			Input: `commit 1111111111111111111111111111111111111111
+Author: Morton Haypenny <mhaypenny@example.com>
+
+    A commit.
+
+diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1 +1 @@
+-a
++b
+`,
+			Format: FormatGit,
+		},
+		"combined": {
+			Input: `diff --cc file.txt
+index 1111111,2222222..3333333
+`,
+			Format: FormatCombined,
+		},
+		"unified": {
+			Input: `--- file.txt.orig	2019-04-01 10:00:00
++++ file.txt	2019-04-01 10:00:00
+@@ -1 +1 @@
+-a
++b
+`,
+			Format: FormatUnified,
+		},
+		"context": {
+			Input: `*** file.txt.orig	2019-04-01 10:00:00
+--- file.txt	2019-04-01 10:00:00
+***************
+*** 1 ****
+! a
+--- 1 ----
+! b
+`,
+			Format: FormatContext,
+		},
+		"mbox": {
+			Input: `From 61f5cd90bed4d204ee3feb3aa41ee91d4734855b Mon Sep 17 00:00:00 2001
+From: Morton Haypenny <mhaypenny@example.com>
+Subject: [PATCH] A commit
+
+diff --git a/file.txt b/file.txt
+`,
+			Format: FormatMbox,
+		},
+		"binary": {
+			Input: `diff --git a/image.png b/image.png
+index 1111111..2222222 100644
+GIT binary patch
+literal 1
+IcmZo*000;L0RR91
+`,
+			Format: FormatBinary,
+		},
+		"gitWithBinary": {
+			Input: `diff --git a/image.png b/image.png
+index 1111111..2222222 100644
+Binary files a/image.png and b/image.png differ
+diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1 +1 @@
+-a
++b
+`,
+			Format: FormatGit,
+		},
+		"unknown": {
+			Input:  "this is not a patch\n",
+			Format: FormatUnknown,
+		},
+		"empty": {
+			Input:  "",
+			Format: FormatUnknown,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			format, r, err := DetectFormat(strings.NewReader(test.Input))
+			if err != nil {
+				t.Fatalf("unexpected error detecting format: %v", err)
+			}
+			if format != test.Format {
+				t.Errorf("incorrect format: expected %v, actual %v", test.Format, format)
+			}
+
+			data, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("unexpected error reading stream: %v", err)
+			}
+			if string(data) != test.Input {
+				t.Errorf("reader does not replay the input\nexpected: %q\n  actual: %q", test.Input, data)
+			}
+		})
+	}
+}
+
+func TestDetectFormatLargeInput(t *testing.T) {
+	input := "diff --git a/file.txt b/file.txt\n" + strings.Repeat("+line\n", 2*detectSize)
+
+	format, r, err := DetectFormat(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error detecting format: %v", err)
+	}
+	if format != FormatGit {
+		t.Errorf("incorrect format: expected %v, actual %v", FormatGit, format)
+	}
+
+	n, err := io.Copy(ioutil.Discard, r)
+	if err != nil {
+		t.Fatalf("unexpected error reading stream: %v", err)
+	}
+	if n != int64(len(input)) {
+		t.Errorf("incorrect length: expected %d, actual %d", len(input), n)
+	}
+}
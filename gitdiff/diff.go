@@ -0,0 +1,361 @@
+package gitdiff
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultContextLines    = 3
+	defaultRenameThreshold = 50
+)
+
+// DiffOptions configures how DiffText and DiffTrees compute differences.
+type DiffOptions struct {
+	// Context is the number of unchanged lines to include around each change.
+	// If zero, 3 lines are included, like the default for git diff. Use a
+	// negative value to include no context, like git diff -U0, and apply
+	// the result with the UnidiffZero option.
+	Context int
+
+	// DetectRenames pairs deleted and created files with similar content into
+	// renames, as with the --find-renames option of git diff. RenameThreshold
+	// is the minimum similarity for a rename; if zero, 50 is used.
+	DetectRenames   bool
+	RenameThreshold int
+
+	// Binary includes the changes to binary files as binary fragments, as
+	// with the --binary option of git diff. Like Git, each fragment is a
+	// delta if that is smaller than the literal content. Otherwise, binary
+	// files are only marked with IsBinary.
+	Binary bool
+
+	// Attributes, if non-nil, changes how DiffTrees compares files based on
+	// their Git attributes. Files with the binary attribute or with the diff
+	// attribute unset are compared as binary files, files with the diff
+	// attribute set are always compared as text, and files with the text or
+	// eol attributes have CRLF line endings converted to LF before they are
+	// compared. If the diff attribute names a driver in Drivers, the driver
+	// controls the comparison. Otherwise, if it names a language registered
+	// with RegisterLanguage, the language finds the comments of fragment
+	// headers.
+	Attributes *Attributes
+	Drivers    map[string]DiffDriver
+
+	// Algorithm selects the algorithm used to compare text files, as with the
+	// --diff-algorithm option of git diff. The default is DiffMyers.
+	Algorithm DiffAlgorithm
+
+	// IndentHeuristic moves groups of added or deleted lines that could be
+	// placed in several positions to the position that best matches the
+	// surrounding indentation, as with the --indent-heuristic option of git
+	// diff. Unlike Git, the heuristic is disabled by default.
+	IndentHeuristic bool
+
+	// MaxCost limits the number of added and deleted lines the Myers
+	// algorithm considers when searching for a minimal diff. If a range
+	// needs more changes, all of its lines are deleted and added instead,
+	// which is fast but produces a larger diff. If zero or negative, there is
+	// no limit. MaxCost is ignored by DiffMinimal.
+	MaxCost int
+}
+
+// DiffDriver configures the comparison of files that select the driver with
+// the diff attribute, like the diff.<driver> settings in Git configuration.
+type DiffDriver struct {
+	// Binary treats all files that use the driver as binary files.
+	Binary bool
+
+	// FuncName matches the lines used as the comments of fragment headers.
+	// The comment of each fragment is the closest line before the fragment
+	// that matches. If FuncName has a capturing group, the comment is the
+	// text of the first group instead of the whole line.
+	FuncName *regexp.Regexp
+}
+
+func (opts DiffOptions) context() int {
+	switch {
+	case opts.Context == 0:
+		return defaultContextLines
+	case opts.Context < 0:
+		return 0
+	}
+	return opts.Context
+}
+
+func (opts DiffOptions) renameThreshold() int {
+	if opts.RenameThreshold == 0 {
+		return defaultRenameThreshold
+	}
+	return opts.RenameThreshold
+}
+
+// DiffText computes the text fragments that transform old into new. Changes
+// separated by at most twice the amount of context share a fragment. The
+// result is empty if old and new are equal.
+func DiffText(old, new []byte, opts DiffOptions) []*TextFragment {
+	return opts.textFragments(old, new)
+}
+
+// splitLines splits data into lines, including the newline character at the
+// end of each line. The last line does not have a newline if data does not
+// end with one.
+func splitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			lines = append(lines, string(data))
+			break
+		}
+		lines = append(lines, string(data[:i+1]))
+		data = data[i+1:]
+	}
+	return lines
+}
+
+// diffLines computes a minimal edit script that transforms a into b using
+// the algorithm described in "An O(ND) Difference Algorithm and Its
+// Variations" by Eugene Myers. The result contains every line of a and b,
+// in order, labeled as context, deleted, or added. Like Git, it uses the
+// linear space variation of the algorithm, which splits the inputs at the
+// middle of an optimal edit path and compares each half recursively.
+func diffLines(a, b []string) []Line {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+	return appendDiffLines(make([]Line, 0, len(a)+len(b)), a, b)
+}
+
+// appendDiffLines appends a minimal edit script that transforms a into b to
+// lines.
+func appendDiffLines(lines []Line, a, b []string) []Line {
+	prefix, suffix := commonAffixes(a, b)
+	for _, line := range a[:prefix] {
+		lines = append(lines, Line{OpContext, line})
+	}
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if x, y, ok := middleSnake(ma, mb); ok {
+		lines = appendDiffLines(lines, ma[:x], mb[:y])
+		lines = appendDiffLines(lines, ma[x:], mb[y:])
+	} else {
+		lines = append(lines, replaceLines(ma, mb)...)
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, Line{OpContext, line})
+	}
+	return lines
+}
+
+// middleSnake finds a point (x, y) on a minimal edit path from a to b by
+// searching forward from the start and backward from the end until the
+// paths overlap. The first and last lines of a and b must differ. It returns
+// false if a or b is empty or if they have no lines in common, in which case
+// the minimal edit script deletes all of a and adds all of b.
+func middleSnake(a, b []string) (x, y int, ok bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	odd := delta%2 != 0
+
+	// the bounds of the diagonals that are still inside the edit graph
+	var fStart, fEnd, bStart, bEnd int
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if i := offset + delta - k; i >= 0 && i < len(backward) && backward[i] != -1 {
+					if x >= n-backward[i] {
+						return x, y, true
+					}
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				if i := offset + delta - k; i >= 0 && i < len(forward) && forward[i] != -1 {
+					fx := forward[i]
+					if fx >= n-x {
+						return fx, fx - (i - offset), true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// diffTextFragments computes the text fragments that transform old into new,
+// including up to context lines of unchanged content around each change.
+// Changes separated by at most 2*context unchanged lines share a fragment.
+func diffTextFragments(old, new []byte, context int) []*TextFragment {
+	return fragmentsFromLines(diffLines(splitLines(old), splitLines(new)), context)
+}
+
+// fragmentsFromLines groups an edit script into fragments with the given
+// amount of context.
+func fragmentsFromLines(lines []Line, context int) []*TextFragment {
+	if context < 0 {
+		context = 0
+	}
+
+	var frags []*TextFragment
+	var oldLine, newLine int64
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == OpContext {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// found a change; include up to context preceding lines
+		start := i
+		for start > 0 && i-start < context && lines[start-1].Op == OpContext {
+			start--
+		}
+
+		// extend through changes separated by at most 2*context lines
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != OpContext {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Op == OpContext {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				end += minInt(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		lead := int64(i - start)
+		frag := &TextFragment{
+			OldPosition: oldLine - lead + 1,
+			NewPosition: newLine - lead + 1,
+			Lines:       append([]Line(nil), lines[start:end]...),
+		}
+		for _, line := range frag.Lines {
+			switch line.Op {
+			case OpContext:
+				frag.OldLines++
+				frag.NewLines++
+				if frag.LinesAdded == 0 && frag.LinesDeleted == 0 {
+					frag.LeadingContext++
+				} else {
+					frag.TrailingContext++
+				}
+			case OpAdd:
+				frag.NewLines++
+				frag.LinesAdded++
+				frag.TrailingContext = 0
+			case OpDelete:
+				frag.OldLines++
+				frag.LinesDeleted++
+				frag.TrailingContext = 0
+			}
+		}
+
+		// empty ranges refer to the line before the range
+		if frag.OldLines == 0 {
+			frag.OldPosition--
+		}
+		if frag.NewLines == 0 {
+			frag.NewPosition--
+		}
+		frags = append(frags, frag)
+
+		oldLine += frag.OldLines - lead
+		newLine += frag.NewLines - lead
+		i = end
+	}
+	return frags
+}
+
+// setFuncNames sets the comment of each fragment to the function name in
+// the closest line before the fragment in old that matches p. Comments are
+// limited to 80 bytes, like in Git.
+func setFuncNames(frags []*TextFragment, old []string, p *FuncNamePattern) {
+	const maxComment = 80
+
+	for _, frag := range frags {
+		start := int(frag.OldPosition) - 1
+		if frag.OldLines == 0 {
+			start++
+		}
+		for i := minInt(start, len(old)) - 1; i >= 0; i-- {
+			comment, ok := p.Match(strings.TrimRight(old[i], "\r\n"))
+			if !ok {
+				continue
+			}
+			if len(comment) > maxComment {
+				comment = comment[:maxComment]
+			}
+			frag.Comment = comment
+			break
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,575 @@
+package gitdiff
+
+import (
+	"fmt"
+)
+
+// DiffAlgorithm selects the algorithm used to compute the differences
+// between two versions of a text file.
+type DiffAlgorithm int
+
+const (
+	// DiffMyers is the basic greedy algorithm from "An O(ND) Difference
+	// Algorithm and Its Variations" by Eugene Myers. It produces a minimal
+	// diff unless the cost limit in DiffOptions is reached.
+	DiffMyers DiffAlgorithm = iota
+
+	// DiffMinimal is the Myers algorithm without a cost limit, so it always
+	// produces the smallest possible diff.
+	DiffMinimal
+
+	// DiffPatience uses the patience algorithm, which matches lines that
+	// are unique in both versions before comparing the lines between them.
+	DiffPatience
+
+	// DiffHistogram extends the patience algorithm to match lines that occur
+	// a small number of times, preferring the lines that occur the least.
+	DiffHistogram
+)
+
+var diffAlgorithmNames = []string{
+	DiffMyers:     "myers",
+	DiffMinimal:   "minimal",
+	DiffPatience:  "patience",
+	DiffHistogram: "histogram",
+}
+
+// ParseDiffAlgorithm returns the algorithm with a name accepted by the
+// --diff-algorithm option of git diff: "default", "myers", "minimal",
+// "patience", or "histogram".
+func ParseDiffAlgorithm(name string) (DiffAlgorithm, error) {
+	if name == "default" {
+		return DiffMyers, nil
+	}
+	for a, n := range diffAlgorithmNames {
+		if n == name {
+			return DiffAlgorithm(a), nil
+		}
+	}
+	return 0, fmt.Errorf("gitdiff: unknown diff algorithm: %s", name)
+}
+
+func (a DiffAlgorithm) String() string {
+	if a >= 0 && int(a) < len(diffAlgorithmNames) {
+		return diffAlgorithmNames[a]
+	}
+	return fmt.Sprintf("DiffAlgorithm(%d)", int(a))
+}
+
+// histogramMaxChain is the maximum number of times a line may occur in the
+// old version for the histogram algorithm to use it to match the versions.
+const histogramMaxChain = 64
+
+// textFragments computes the text fragments that transform old into new
+// using the algorithm and heuristics selected by opts.
+func (opts DiffOptions) textFragments(old, new []byte) []*TextFragment {
+	return fragmentsFromLines(opts.diffLines(splitLines(old), splitLines(new)), opts.context())
+}
+
+// diffLines computes an edit script that transforms a into b using the
+// algorithm and heuristics selected by opts.
+func (opts DiffOptions) diffLines(a, b []string) []Line {
+	maxCost := opts.MaxCost
+	if opts.Algorithm == DiffMinimal || maxCost < 0 {
+		maxCost = 0
+	}
+	myers := func(a, b []string) []Line {
+		return diffLinesMyers(a, b, maxCost)
+	}
+
+	var lines []Line
+	switch opts.Algorithm {
+	case DiffPatience:
+		lines = diffLinesPatience(a, b, myers)
+	case DiffHistogram:
+		lines = diffLinesHistogram(a, b, myers)
+	default:
+		lines = myers(a, b)
+	}
+	if opts.IndentHeuristic {
+		applyIndentHeuristic(lines)
+	}
+	return lines
+}
+
+// diffLinesMyers is like diffLines, but if maxCost is positive and more than
+// maxCost lines are added or deleted, it stops searching for a minimal diff
+// and deletes and adds all lines between the common prefix and suffix of a
+// and b instead.
+func diffLinesMyers(a, b []string, maxCost int) []Line {
+	if maxCost <= 0 {
+		return diffLines(a, b)
+	}
+
+	prefix, suffix := commonAffixes(a, b)
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var middle []Line
+	if editDistanceAtMost(ma, mb, maxCost) {
+		middle = diffLines(ma, mb)
+	} else {
+		middle = replaceLines(ma, mb)
+	}
+	return joinAffixes(a, prefix, suffix, middle)
+}
+
+// editDistanceAtMost returns true if b can be produced from a by adding and
+// deleting at most max lines.
+func editDistanceAtMost(a, b []string, max int) bool {
+	n, m := len(a), len(b)
+	if n+m <= max {
+		return true
+	}
+
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// diffLinesPatience computes an edit script using the patience algorithm.
+// Ranges without any lines that are unique in both a and b are compared with
+// fallback.
+func diffLinesPatience(a, b []string, fallback func(a, b []string) []Line) []Line {
+	prefix, suffix := commonAffixes(a, b)
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	type occurrence struct {
+		countA, countB int
+		indexA, indexB int
+	}
+	occurrences := make(map[string]*occurrence)
+	for i, line := range ma {
+		o := occurrences[line]
+		if o == nil {
+			o = &occurrence{}
+			occurrences[line] = o
+		}
+		o.countA++
+		o.indexA = i
+	}
+	for j, line := range mb {
+		if o := occurrences[line]; o != nil {
+			o.countB++
+			o.indexB = j
+		}
+	}
+
+	// unique lines in the order they appear in a
+	var unique [][2]int
+	for i, line := range ma {
+		if o := occurrences[line]; o.countA == 1 && o.countB == 1 {
+			unique = append(unique, [2]int{i, o.indexB})
+		}
+	}
+
+	anchors := longestIncreasing(unique)
+	if len(anchors) == 0 {
+		return joinAffixes(a, prefix, suffix, fallback(ma, mb))
+	}
+
+	var middle []Line
+	i, j := 0, 0
+	for _, anchor := range anchors {
+		middle = append(middle, diffLinesPatience(ma[i:anchor[0]], mb[j:anchor[1]], fallback)...)
+		middle = append(middle, Line{OpContext, ma[anchor[0]]})
+		i, j = anchor[0]+1, anchor[1]+1
+	}
+	middle = append(middle, diffLinesPatience(ma[i:], mb[j:], fallback)...)
+	return joinAffixes(a, prefix, suffix, middle)
+}
+
+// longestIncreasing returns the longest subsequence of pairs, which are
+// sorted by their first element, in which the second elements increase. It
+// uses patience sorting.
+func longestIncreasing(pairs [][2]int) [][2]int {
+	var tails []int // index in pairs of the last element of each pile
+	prev := make([]int, len(pairs))
+	for p, pair := range pairs {
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if pairs[tails[mid]][1] < pair[1] {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		if lo > 0 {
+			prev[p] = tails[lo-1]
+		} else {
+			prev[p] = -1
+		}
+		if lo == len(tails) {
+			tails = append(tails, p)
+		} else {
+			tails[lo] = p
+		}
+	}
+	if len(tails) == 0 {
+		return nil
+	}
+
+	result := make([][2]int, len(tails))
+	for k, p := len(tails)-1, tails[len(tails)-1]; k >= 0; k, p = k-1, prev[p] {
+		result[k] = pairs[p]
+	}
+	return result
+}
+
+// diffLinesHistogram computes an edit script using the histogram algorithm.
+// Ranges without any common lines that occur few enough times in a are
+// compared with fallback.
+func diffLinesHistogram(a, b []string, fallback func(a, b []string) []Line) []Line {
+	prefix, suffix := commonAffixes(a, b)
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(ma) == 0 || len(mb) == 0 {
+		return joinAffixes(a, prefix, suffix, replaceLines(ma, mb))
+	}
+
+	positions := make(map[string][]int)
+	for i, line := range ma {
+		positions[line] = append(positions[line], i)
+	}
+
+	// find the longest common region containing the line with the fewest
+	// occurrences in a
+	bestA, bestB, bestLen, bestCount := 0, 0, 0, histogramMaxChain+1
+	for j := 0; j < len(mb); {
+		next := j + 1
+		occ := positions[mb[j]]
+		if len(occ) > 0 && len(occ) <= bestCount {
+			for _, i := range occ {
+				sa, sb := i, j
+				for sa > 0 && sb > 0 && ma[sa-1] == mb[sb-1] {
+					sa--
+					sb--
+				}
+				ea, eb := i+1, j+1
+				for ea < len(ma) && eb < len(mb) && ma[ea] == mb[eb] {
+					ea++
+					eb++
+				}
+
+				count := len(occ)
+				for k := sa; k < ea; k++ {
+					if c := len(positions[ma[k]]); c < count {
+						count = c
+					}
+				}
+				if count < bestCount || (count == bestCount && ea-sa > bestLen) {
+					bestA, bestB, bestLen, bestCount = sa, sb, ea-sa, count
+				}
+				if eb > next {
+					next = eb
+				}
+			}
+		}
+		j = next
+	}
+
+	if bestLen == 0 {
+		return joinAffixes(a, prefix, suffix, fallback(ma, mb))
+	}
+
+	middle := diffLinesHistogram(ma[:bestA], mb[:bestB], fallback)
+	for _, line := range ma[bestA : bestA+bestLen] {
+		middle = append(middle, Line{OpContext, line})
+	}
+	middle = append(middle, diffLinesHistogram(ma[bestA+bestLen:], mb[bestB+bestLen:], fallback)...)
+	return joinAffixes(a, prefix, suffix, middle)
+}
+
+// commonAffixes returns the number of lines at the start and at the end of a
+// and b that are the same. The prefix and suffix do not overlap.
+func commonAffixes(a, b []string) (prefix, suffix int) {
+	n := minInt(len(a), len(b))
+	for prefix < n && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < n-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return prefix, suffix
+}
+
+// joinAffixes surrounds middle with the common prefix and suffix of a as
+// context lines.
+func joinAffixes(a []string, prefix, suffix int, middle []Line) []Line {
+	lines := make([]Line, 0, prefix+len(middle)+suffix)
+	for _, line := range a[:prefix] {
+		lines = append(lines, Line{OpContext, line})
+	}
+	lines = append(lines, middle...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, Line{OpContext, line})
+	}
+	return lines
+}
+
+// replaceLines returns an edit script that deletes all of a and adds all of
+// b.
+func replaceLines(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, line := range a {
+		lines = append(lines, Line{OpDelete, line})
+	}
+	for _, line := range b {
+		lines = append(lines, Line{OpAdd, line})
+	}
+	return lines
+}
+
+// Constants for the indent heuristic, from Git's xdiff/xdiffi.c.
+const (
+	indentMaxSliding = 100
+	indentMaxIndent  = 200
+	indentMaxBlanks  = 20
+
+	indentStartOfFilePenalty       = 1
+	indentEndOfFilePenalty         = 21
+	indentTotalBlankWeight         = -30
+	indentPostBlankWeight          = 6
+	indentRelativeIndentPenalty    = -4
+	indentRelativeIndentWithBlank  = 10
+	indentRelativeOutdentPenalty   = 24
+	indentRelativeOutdentWithBlank = 17
+	indentRelativeDedentPenalty    = 23
+	indentRelativeDedentWithBlank  = 17
+	indentWeight                   = 60
+)
+
+// applyIndentHeuristic moves groups of added or deleted lines that are
+// surrounded by context lines and could be placed in several positions to
+// the position that best matches the indentation of the surrounding lines,
+// like the --indent-heuristic option of git diff. For example, it prefers to
+// add a complete function instead of the end of one function and the start
+// of the next.
+func applyIndentHeuristic(lines []Line) {
+	for start := 0; start < len(lines); {
+		op := lines[start].Op
+		if op == OpContext {
+			start++
+			continue
+		}
+		end := start
+		for end < len(lines) && lines[end].Op == op {
+			end++
+		}
+
+		// only move groups that are not next to another kind of change
+		if (start == 0 || lines[start-1].Op == OpContext) && (end == len(lines) || lines[end].Op == OpContext) {
+			end = slideGroup(lines, start, end)
+		}
+		start = end
+	}
+}
+
+// slideGroup moves the group of changed lines at lines[start:end] to its best
+// position and returns the index of the first line after the lines the group
+// could move within.
+func slideGroup(lines []Line, start, end int) int {
+	op := lines[start].Op
+
+	// the version of the file that contains the group
+	var recs []string
+	var groupStart int
+	for i, line := range lines {
+		if line.Op == OpContext || line.Op == op {
+			if i == start {
+				groupStart = len(recs)
+			}
+			recs = append(recs, line.Line)
+		}
+	}
+	size := end - start
+	groupEnd := groupStart + size
+
+	up := 0
+	for start-up > 0 && lines[start-up-1].Op == OpContext && recs[groupStart-up-1] == recs[groupEnd-up-1] {
+		up++
+	}
+	down := 0
+	for end+down < len(lines) && lines[end+down].Op == OpContext && recs[groupStart+down] == recs[groupEnd+down] {
+		down++
+	}
+	if up == 0 && down == 0 {
+		return end
+	}
+
+	lowestEnd := groupEnd + down
+	earliestEnd := groupEnd - up
+	shift := earliestEnd
+	if s := lowestEnd - size - 1; s > shift {
+		shift = s
+	}
+	if s := lowestEnd - indentMaxSliding; s > shift {
+		shift = s
+	}
+
+	var best int
+	var bestScore splitScore
+	for found := false; shift <= lowestEnd; shift++ {
+		var score splitScore
+		score.add(measureSplit(recs, shift))
+		score.add(measureSplit(recs, shift-size))
+		if !found || score.cmp(bestScore) <= 0 {
+			best, bestScore, found = shift, score, true
+		}
+	}
+
+	// rewrite the region the group can move within
+	first := start - up
+	for k := 0; k < up+size+down; k++ {
+		rec := groupEnd - up - size + k
+		if rec >= best-size && rec < best {
+			lines[first+k] = Line{op, recs[rec]}
+		} else {
+			lines[first+k] = Line{OpContext, recs[rec]}
+		}
+	}
+	return end + down
+}
+
+type splitMeasurement struct {
+	endOfFile  bool
+	indent     int
+	preBlank   int
+	preIndent  int
+	postBlank  int
+	postIndent int
+}
+
+// measureSplit describes the lines around the position between
+// recs[split-1] and recs[split].
+func measureSplit(recs []string, split int) splitMeasurement {
+	var m splitMeasurement
+	if split >= len(recs) {
+		m.endOfFile = true
+		m.indent = -1
+	} else {
+		m.indent = lineIndent(recs[split])
+	}
+
+	m.preIndent = -1
+	for i := split - 1; i >= 0; i-- {
+		if m.preIndent = lineIndent(recs[i]); m.preIndent != -1 {
+			break
+		}
+		if m.preBlank++; m.preBlank == indentMaxBlanks {
+			m.preIndent = 0
+			break
+		}
+	}
+
+	m.postIndent = -1
+	for i := split + 1; i < len(recs); i++ {
+		if m.postIndent = lineIndent(recs[i]); m.postIndent != -1 {
+			break
+		}
+		if m.postBlank++; m.postBlank == indentMaxBlanks {
+			m.postIndent = 0
+			break
+		}
+	}
+	return m
+}
+
+// lineIndent returns the width of the leading whitespace in line, with tabs
+// advancing to the next multiple of 8, or -1 if line contains only
+// whitespace.
+func lineIndent(line string) int {
+	indent := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			indent++
+		case '\t':
+			indent += 8 - indent%8
+		case '\n', '\r', '\f', '\v':
+		default:
+			return indent
+		}
+		if indent >= indentMaxIndent {
+			return indentMaxIndent
+		}
+	}
+	return -1
+}
+
+type splitScore struct {
+	effectiveIndent int
+	penalty         int
+}
+
+func (s *splitScore) add(m splitMeasurement) {
+	if m.preIndent == -1 && m.preBlank == 0 {
+		s.penalty += indentStartOfFilePenalty
+	}
+	if m.endOfFile {
+		s.penalty += indentEndOfFilePenalty
+	}
+
+	postBlank := 0
+	if m.indent == -1 {
+		postBlank = 1 + m.postBlank
+	}
+	totalBlank := m.preBlank + postBlank
+	s.penalty += indentTotalBlankWeight * totalBlank
+	s.penalty += indentPostBlankWeight * postBlank
+
+	indent := m.indent
+	if indent == -1 {
+		indent = m.postIndent
+	}
+	anyBlanks := totalBlank != 0
+	s.effectiveIndent += indent
+
+	switch {
+	case indent == -1 || m.preIndent == -1 || indent == m.preIndent:
+	case indent > m.preIndent:
+		s.penalty += pick(anyBlanks, indentRelativeIndentWithBlank, indentRelativeIndentPenalty)
+	case m.postIndent != -1 && m.postIndent > indent:
+		s.penalty += pick(anyBlanks, indentRelativeOutdentWithBlank, indentRelativeOutdentPenalty)
+	default:
+		s.penalty += pick(anyBlanks, indentRelativeDedentWithBlank, indentRelativeDedentPenalty)
+	}
+}
+
+// cmp returns a negative number if s is better than other, zero if they are
+// equal, and a positive number otherwise.
+func (s splitScore) cmp(other splitScore) int {
+	cmpIndents := 0
+	switch {
+	case s.effectiveIndent > other.effectiveIndent:
+		cmpIndents = 1
+	case s.effectiveIndent < other.effectiveIndent:
+		cmpIndents = -1
+	}
+	return indentWeight*cmpIndents + (s.penalty - other.penalty)
+}
+
+func pick(cond bool, a, b int) int {
+	if cond {
+		return a
+	}
+	return b
+}
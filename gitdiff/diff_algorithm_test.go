@@ -0,0 +1,170 @@
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDiffAlgorithm(t *testing.T) {
+	tests := map[string]DiffAlgorithm{
+		"default":   DiffMyers,
+		"myers":     DiffMyers,
+		"minimal":   DiffMinimal,
+		"patience":  DiffPatience,
+		"histogram": DiffHistogram,
+	}
+	for name, expected := range tests {
+		a, err := ParseDiffAlgorithm(name)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", name, err)
+			continue
+		}
+		if a != expected {
+			t.Errorf("incorrect algorithm for %q: expected %v, actual %v", name, expected, a)
+		}
+	}
+
+	if _, err := ParseDiffAlgorithm("unknown"); err == nil {
+		t.Errorf("expected error parsing unknown algorithm, but got nil")
+	}
+}
+
+func TestDiffAlgorithms(t *testing.T) {
+	tests := map[string]struct {
+		Old, New string
+		Opts     DiffOptions
+		Script   string
+	}{
+		"myers": {
+			Old:    "a\nb\nc\n",
+			New:    "a\nc\nd\n",
+			Script: " a\n-b\n c\n+d\n",
+		},
+		"patienceUniqueLines": {
+			Old:    "}\nfunc a() {\n}\n",
+			New:    "}\nfunc b() {\n}\nfunc a() {\n}\n",
+			Opts:   DiffOptions{Algorithm: DiffPatience},
+			Script: " }\n+func b() {\n+}\n func a() {\n }\n",
+		},
+		"histogram": {
+			Old:    "x\nx\nunique\nx\n",
+			New:    "unique\nx\ny\n",
+			Opts:   DiffOptions{Algorithm: DiffHistogram},
+			Script: "-x\n-x\n unique\n x\n+y\n",
+		},
+		"maxCostFallback": {
+			Old:    "p\nx\nq\n",
+			New:    "x\nr\ns\n",
+			Opts:   DiffOptions{MaxCost: 3},
+			Script: "-p\n-x\n-q\n+x\n+r\n+s\n",
+		},
+		"maxCostUnderLimit": {
+			Old:    "p\nx\nq\n",
+			New:    "x\nr\ns\n",
+			Opts:   DiffOptions{MaxCost: 4},
+			Script: "-p\n x\n-q\n+r\n+s\n",
+		},
+		"minimalIgnoresMaxCost": {
+			Old:    "p\nx\nq\n",
+			New:    "x\nr\ns\n",
+			Opts:   DiffOptions{Algorithm: DiffMinimal, MaxCost: 1},
+			Script: "-p\n x\n-q\n+r\n+s\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lines := test.Opts.diffLines(splitLines([]byte(test.Old)), splitLines([]byte(test.New)))
+			if script := formatScript(lines); script != test.Script {
+				t.Errorf("incorrect edit script\nexpected:\n%s\nactual:\n%s", test.Script, script)
+			}
+		})
+	}
+}
+
+func TestDiffAlgorithmsValid(t *testing.T) {
+	inputs := [][2]string{
+		{"", "a\nb\n"},
+		{"a\nb\n", ""},
+		{"a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n"},
+		{"x\ny\nx\ny\nz\n", "y\nx\nz\ny\nx\n"},
+		{"1\n2\n3\n4\n5\n", "1\n3\n2\n5\n4\n"},
+		{"if x {\n}\n\n}\n", "if x {\n\ty\n}\n\n}\n\n"},
+	}
+	algorithms := []DiffAlgorithm{DiffMyers, DiffMinimal, DiffPatience, DiffHistogram}
+
+	for _, input := range inputs {
+		for _, algorithm := range algorithms {
+			for _, heuristic := range []bool{false, true} {
+				opts := DiffOptions{Algorithm: algorithm, IndentHeuristic: heuristic, MaxCost: 2}
+				lines := opts.diffLines(splitLines([]byte(input[0])), splitLines([]byte(input[1])))
+
+				var old, new strings.Builder
+				for _, line := range lines {
+					if line.Old() {
+						old.WriteString(line.Line)
+					}
+					if line.New() {
+						new.WriteString(line.Line)
+					}
+				}
+				if old.String() != input[0] || new.String() != input[1] {
+					t.Errorf("%v (heuristic: %t): invalid edit script for %q -> %q:\n%s",
+						algorithm, heuristic, input[0], input[1], formatScript(lines))
+				}
+			}
+		}
+	}
+}
+
+func TestIndentHeuristic(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output string
+	}{
+		"moveDown": {
+			Input:  " func a() {\n \tx\n }\n+\n+func b() {\n+\ty\n+}\n \n func c() {\n",
+			Output: " func a() {\n \tx\n }\n \n+func b() {\n+\ty\n+}\n+\n func c() {\n",
+		},
+		"moveUp": {
+			Input:  " if a {\n \tx\n+\ty\n+}\n+\n+if b {\n \ty\n }\n",
+			Output: " if a {\n \tx\n \ty\n }\n+\n+if b {\n+\ty\n+}\n",
+		},
+		"keepBest": {
+			Input:  " }\n \n+func b() {\n+}\n+\n func c() {\n",
+			Output: " }\n \n+func b() {\n+}\n+\n func c() {\n",
+		},
+		"mixedChange": {
+			Input:  " a\n-b\n+c\n+a\n",
+			Output: " a\n-b\n+c\n+a\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lines := parseScript(test.Input)
+			applyIndentHeuristic(lines)
+			if script := formatScript(lines); script != test.Output {
+				t.Errorf("incorrect edit script\nexpected:\n%s\nactual:\n%s", test.Output, script)
+			}
+		})
+	}
+}
+
+func parseScript(s string) []Line {
+	var lines []Line
+	for _, line := range splitLines([]byte(s)) {
+		op := map[byte]LineOp{' ': OpContext, '-': OpDelete, '+': OpAdd}[line[0]]
+		lines = append(lines, Line{op, line[1:]})
+	}
+	return lines
+}
+
+func formatScript(lines []Line) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line.Op.String())
+		b.WriteString(line.Line)
+	}
+	return b.String()
+}
@@ -0,0 +1,243 @@
+package gitdiff
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffTextFragments(t *testing.T) {
+	tests := map[string]struct {
+		Old, New  string
+		Context   int
+		Fragments []*TextFragment
+	}{
+		"identical": {
+			Old: "a\nb\nc\n",
+			New: "a\nb\nc\n",
+		},
+		"createFile": {
+			Old:     "",
+			New:     "a\nb\n",
+			Context: 3,
+			Fragments: []*TextFragment{
+				{
+					OldPosition: 0, OldLines: 0,
+					NewPosition: 1, NewLines: 2,
+					LinesAdded: 2,
+					Lines: []Line{
+						{OpAdd, "a\n"},
+						{OpAdd, "b\n"},
+					},
+				},
+			},
+		},
+		"changeMiddle": {
+			Old:     "1\n2\n3\n4\n5\n6\n7\n",
+			New:     "1\n2\n3\nfour\n5\n6\n7\n",
+			Context: 2,
+			Fragments: []*TextFragment{
+				{
+					OldPosition: 2, OldLines: 5,
+					NewPosition: 2, NewLines: 5,
+					LinesAdded: 1, LinesDeleted: 1,
+					LeadingContext: 2, TrailingContext: 2,
+					Lines: []Line{
+						{OpContext, "2\n"},
+						{OpContext, "3\n"},
+						{OpDelete, "4\n"},
+						{OpAdd, "four\n"},
+						{OpContext, "5\n"},
+						{OpContext, "6\n"},
+					},
+				},
+			},
+		},
+		"separateFragments": {
+			Old:     "1\n2\n3\n4\n5\n6\n7\n8\n",
+			New:     "one\n2\n3\n4\n5\n6\n7\n",
+			Context: 1,
+			Fragments: []*TextFragment{
+				{
+					OldPosition: 1, OldLines: 2,
+					NewPosition: 1, NewLines: 2,
+					LinesAdded: 1, LinesDeleted: 1,
+					TrailingContext: 1,
+					Lines: []Line{
+						{OpDelete, "1\n"},
+						{OpAdd, "one\n"},
+						{OpContext, "2\n"},
+					},
+				},
+				{
+					OldPosition: 7, OldLines: 2,
+					NewPosition: 7, NewLines: 1,
+					LinesDeleted: 1, LeadingContext: 1,
+					Lines: []Line{
+						{OpContext, "7\n"},
+						{OpDelete, "8\n"},
+					},
+				},
+			},
+		},
+		"pureAddition": {
+			Old:     "1\n2\n",
+			New:     "1\n1.5\n2\n",
+			Context: 0,
+			Fragments: []*TextFragment{
+				{
+					OldPosition: 1, OldLines: 0,
+					NewPosition: 2, NewLines: 1,
+					LinesAdded: 1,
+					Lines: []Line{
+						{OpAdd, "1.5\n"},
+					},
+				},
+			},
+		},
+		"noNewline": {
+			Old:     "a\nb",
+			New:     "a\nb\n",
+			Context: 3,
+			Fragments: []*TextFragment{
+				{
+					OldPosition: 1, OldLines: 2,
+					NewPosition: 1, NewLines: 2,
+					LinesAdded: 1, LinesDeleted: 1,
+					LeadingContext: 1,
+					Lines: []Line{
+						{OpContext, "a\n"},
+						{OpDelete, "b"},
+						{OpAdd, "b\n"},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			frags := diffTextFragments([]byte(test.Old), []byte(test.New), test.Context)
+			if !reflect.DeepEqual(test.Fragments, frags) {
+				t.Fatalf("incorrect fragments\nexpected: %+v\n  actual: %+v", test.Fragments, frags)
+			}
+			for i, frag := range frags {
+				if err := frag.Validate(); err != nil {
+					t.Errorf("fragment %d is invalid: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffTextFragmentsApply(t *testing.T) {
+	var old, new strings.Builder
+	for i := 0; i < 200; i++ {
+		switch {
+		case i%17 == 0:
+			old.WriteString("removed\n")
+		case i%23 == 0:
+			new.WriteString("added\n")
+		case i%31 == 0:
+			old.WriteString("before\n")
+			new.WriteString("after\n")
+		default:
+			old.WriteString("same\n")
+			new.WriteString("same\n")
+		}
+	}
+
+	file := &File{TextFragments: diffTextFragments([]byte(old.String()), []byte(new.String()), 3)}
+
+	var out bytes.Buffer
+	if err := Apply(&out, strings.NewReader(old.String()), file); err != nil {
+		t.Fatalf("unexpected error applying generated fragments: %v", err)
+	}
+	if out.String() != new.String() {
+		t.Errorf("incorrect result after apply\nexpected:\n%s\nactual:\n%s", new.String(), out.String())
+	}
+}
+
+func TestDiffText(t *testing.T) {
+	old := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n")
+	new := []byte("1\n2\n3\n4\nfive\n6\n7\n8\n9\n")
+
+	tests := map[string]struct {
+		Context int
+		Leading int64
+	}{
+		"default":   {Context: 0, Leading: 3},
+		"noContext": {Context: -1, Leading: 0},
+		"custom":    {Context: 1, Leading: 1},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			frags := DiffText(old, new, DiffOptions{Context: test.Context})
+			if len(frags) != 1 {
+				t.Fatalf("incorrect number of fragments: expected 1, actual %d", len(frags))
+			}
+			if frags[0].LeadingContext != test.Leading || frags[0].TrailingContext != test.Leading {
+				t.Errorf("incorrect context: expected %d, actual %d/%d", test.Leading, frags[0].LeadingContext, frags[0].TrailingContext)
+			}
+		})
+	}
+}
+
+func TestDiffLinesMinimal(t *testing.T) {
+	// pseudo-random inputs from a small alphabet have many equal lines, so
+	// there are many edit paths to choose from
+	seed := uint32(1)
+	randomLines := func(n int) []string {
+		lines := make([]string, n)
+		for i := range lines {
+			seed = seed*1103515245 + 12345
+			lines[i] = string(rune('a'+(seed>>16)%4)) + "\n"
+		}
+		return lines
+	}
+
+	for i := 0; i < 50; i++ {
+		a, b := randomLines(i), randomLines(50-i/2)
+		lines := diffLines(a, b)
+
+		var old, new []string
+		changes := 0
+		for _, line := range lines {
+			if line.Old() {
+				old = append(old, line.Line)
+			}
+			if line.New() {
+				new = append(new, line.Line)
+			}
+			if line.Op != OpContext {
+				changes++
+			}
+		}
+		if strings.Join(old, "") != strings.Join(a, "") || strings.Join(new, "") != strings.Join(b, "") {
+			t.Fatalf("invalid edit script for %q -> %q:\n%s", a, b, formatScript(lines))
+		}
+		if expected := len(a) + len(b) - 2*lcsLength(a, b); changes != expected {
+			t.Errorf("edit script for %q -> %q is not minimal: expected %d changes, actual %d", a, b, expected, changes)
+		}
+	}
+}
+
+func lcsLength(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
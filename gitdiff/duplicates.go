@@ -0,0 +1,131 @@
+package gitdiff
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DuplicatePathOptions configures MergeDuplicatePaths.
+type DuplicatePathOptions struct {
+	// Reject makes MergeDuplicatePaths return an error for any path that has
+	// more than one file instead of merging the files.
+	Reject bool
+}
+
+// DuplicatePathError is returned by MergeDuplicatePaths when files for the
+// same path cannot be merged.
+type DuplicatePathError struct {
+	Path   string
+	Reason string
+}
+
+func (e *DuplicatePathError) Error() string {
+	return fmt.Sprintf("gitdiff: duplicate path %q: %s", e.Path, e.Reason)
+}
+
+// MergeDuplicatePaths combines files in a patch that describe changes to the
+// same path, as in some generated patches that split the fragments of a file
+// across several "diff --git" sections. The fragments of each duplicate are
+// added to the first file for the path, in order of their old positions, and
+// the merged file replaces the first file in the result, with the annotations
+// of all of the files. The files are not modified.
+//
+// Files for the same path must agree on their names, modes, object IDs, and
+// whether they are new, deleted, copied, renamed, or binary, and their
+// fragments must not overlap. Otherwise, or if opts.Reject is set, it returns
+// a *DuplicatePathError. Binary files and files from combined diffs are
+// never merged.
+func MergeDuplicatePaths(files []*File, opts DuplicatePathOptions) ([]*File, error) {
+	merged := make([]*File, 0, len(files))
+	index := make(map[string]int)
+
+	for _, f := range files {
+		name := fileName(f)
+		i, ok := index[name]
+		if !ok {
+			index[name] = len(merged)
+			merged = append(merged, f)
+			continue
+		}
+		if opts.Reject {
+			return nil, &DuplicatePathError{name, "path appears in more than one file"}
+		}
+
+		m, err := mergeFiles(merged[i], f)
+		if err != nil {
+			return nil, &DuplicatePathError{name, err.Error()}
+		}
+		merged[i] = m
+	}
+	return merged, nil
+}
+
+// mergeFiles returns a copy of a with the fragments of b.
+func mergeFiles(a, b *File) (*File, error) {
+	switch {
+	case a.OldName != b.OldName || a.NewName != b.NewName:
+		return nil, fmt.Errorf("names do not match")
+	case a.IsNew != b.IsNew || a.IsDelete != b.IsDelete || a.IsCopy != b.IsCopy || a.IsRename != b.IsRename:
+		return nil, fmt.Errorf("files describe different kinds of changes")
+	case a.IsBinary || b.IsBinary:
+		return nil, fmt.Errorf("binary files cannot be merged")
+	case a.ParentCount > 0 || b.ParentCount > 0:
+		return nil, fmt.Errorf("files from combined diffs cannot be merged")
+	}
+
+	m := *a
+	var ok bool
+	if m.OldMode, ok = mergeMode(a.OldMode, b.OldMode); !ok {
+		return nil, fmt.Errorf("old modes do not match")
+	}
+	if m.NewMode, ok = mergeMode(a.NewMode, b.NewMode); !ok {
+		return nil, fmt.Errorf("new modes do not match")
+	}
+	if m.OldOIDPrefix, ok = mergeOID(a.OldOIDPrefix, b.OldOIDPrefix); !ok {
+		return nil, fmt.Errorf("old object IDs do not match")
+	}
+	if m.NewOIDPrefix, ok = mergeOID(a.NewOIDPrefix, b.NewOIDPrefix); !ok {
+		return nil, fmt.Errorf("new object IDs do not match")
+	}
+	m.ContentOmitted = a.ContentOmitted && b.ContentOmitted
+	m.Annotations = joinAnnotations(a.Annotations, b.Annotations)
+	m.Meta = joinMeta(a.Meta, b.Meta)
+
+	frags := make([]*TextFragment, 0, len(a.TextFragments)+len(b.TextFragments))
+	frags = append(frags, a.TextFragments...)
+	frags = append(frags, b.TextFragments...)
+	sort.SliceStable(frags, func(i, j int) bool {
+		return frags[i].OldPosition < frags[j].OldPosition
+	})
+	for i := 1; i < len(frags); i++ {
+		prev, next := frags[i-1], frags[i]
+		if next.OldPosition < prev.OldPosition+prev.OldLines || next.OldPosition == prev.OldPosition {
+			return nil, fmt.Errorf("fragments %q and %q overlap", strings.TrimSpace(prev.Header()), strings.TrimSpace(next.Header()))
+		}
+	}
+	m.TextFragments = frags
+	return &m, nil
+}
+
+// mergeMode returns the mode set in a or b, or false if both are set to
+// different modes.
+func mergeMode(a, b os.FileMode) (os.FileMode, bool) {
+	switch {
+	case a == 0:
+		return b, true
+	case b == 0 || a == b:
+		return a, true
+	}
+	return 0, false
+}
+
+// mergeOID returns the longer of two object ID prefixes, or false if they
+// identify different objects.
+func mergeOID(a, b string) (string, bool) {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	return a, strings.HasPrefix(a, b)
+}
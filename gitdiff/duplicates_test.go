@@ -0,0 +1,121 @@
+package gitdiff
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMergeDuplicatePaths(t *testing.T) {
+	const first = `diff --git a/file.txt b/file.txt
+index 1c23fcc..40a1b33 100644
+--- a/file.txt
++++ b/file.txt
+@@ -2,3 +2,3 @@
+ b
+-c
++C
+ d
+`
+	const second = `diff --git a/file.txt b/file.txt
+index 1c23fcc..40a1b33 100644
+--- a/file.txt
++++ b/file.txt
+@@ -10,3 +10,3 @@
+ j
+-k
++K
+ l
+`
+	const other = `diff --git a/other.txt b/other.txt
+index 1c23fcc..40a1b33 100644
+--- a/other.txt
++++ b/other.txt
+@@ -1,1 +1,1 @@
+-a
++A
+`
+
+	tests := map[string]struct {
+		Patch   string
+		Options DuplicatePathOptions
+
+		Names     []string
+		Positions [][]int64
+		Err       string
+	}{
+		"noDuplicates": {
+			Patch:     first + other,
+			Names:     []string{"file.txt", "other.txt"},
+			Positions: [][]int64{{2}, {1}},
+		},
+		"merge": {
+			Patch:     second + other + first,
+			Names:     []string{"file.txt", "other.txt"},
+			Positions: [][]int64{{2, 10}, {1}},
+		},
+		"reject": {
+			Patch:   first + second,
+			Options: DuplicatePathOptions{Reject: true},
+			Err:     "appears in more than one file",
+		},
+		"overlap": {
+			Patch: first + first,
+			Err:   "overlap",
+		},
+		"modeMismatch": {
+			Patch: first + strings.Replace(second, "100644", "100755", 1),
+			Err:   "old modes do not match",
+		},
+		"oidMismatch": {
+			Patch: first + strings.Replace(second, "1c23fcc", "2d34fdd", 1),
+			Err:   "old object IDs do not match",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			original := len(files[0].TextFragments)
+
+			merged, err := MergeDuplicatePaths(files, test.Options)
+			if test.Err != "" {
+				var derr *DuplicatePathError
+				if !errors.As(err, &derr) || derr.Path != "file.txt" {
+					t.Fatalf("expected duplicate path error, but got %v", err)
+				}
+				if !strings.Contains(err.Error(), test.Err) {
+					t.Errorf("incorrect error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error merging files: %v", err)
+			}
+
+			if len(merged) != len(test.Names) {
+				t.Fatalf("incorrect number of files: expected %d, actual %d", len(test.Names), len(merged))
+			}
+			for i, f := range merged {
+				if f.NewName != test.Names[i] {
+					t.Errorf("file %d: incorrect name: expected %q, actual %q", i, test.Names[i], f.NewName)
+				}
+				if len(f.TextFragments) != len(test.Positions[i]) {
+					t.Errorf("file %d: incorrect number of fragments: expected %d, actual %d", i, len(test.Positions[i]), len(f.TextFragments))
+					continue
+				}
+				for j, frag := range f.TextFragments {
+					if frag.OldPosition != test.Positions[i][j] {
+						t.Errorf("file %d, fragment %d: incorrect position: expected %d, actual %d", i, j, test.Positions[i][j], frag.OldPosition)
+					}
+				}
+			}
+			if len(files[0].TextFragments) != original {
+				t.Errorf("input file was modified")
+			}
+		})
+	}
+}
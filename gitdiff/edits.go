@@ -0,0 +1,178 @@
+package gitdiff
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// PositionEncoding selects the unit of the Character field of a Position,
+// using the names of the position encodings of the Language Server Protocol.
+type PositionEncoding string
+
+const (
+	// PositionUTF16 counts UTF-16 code units, the default of the Language
+	// Server Protocol.
+	PositionUTF16 PositionEncoding = "utf-16"
+	// PositionUTF8 counts bytes.
+	PositionUTF8 PositionEncoding = "utf-8"
+	// PositionUTF32 counts Unicode code points.
+	PositionUTF32 PositionEncoding = "utf-32"
+)
+
+// Position is a location in a text document, like a Position of the Language
+// Server Protocol. Line and Character are zero-indexed.
+type Position struct {
+	Line      int64 `json:"line"`
+	Character int64 `json:"character"`
+}
+
+// TextRange is a range in a text document, like a Range of the Language
+// Server Protocol. The end is exclusive.
+type TextRange struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// EditOp is the kind of an Edit.
+type EditOp int
+
+const (
+	// EditInsert adds text without removing any.
+	EditInsert EditOp = iota
+	// EditDelete removes text without adding any.
+	EditDelete
+	// EditReplace removes text and adds other text in its place.
+	EditReplace
+)
+
+func (op EditOp) String() string {
+	switch op {
+	case EditInsert:
+		return "insert"
+	case EditDelete:
+		return "delete"
+	case EditReplace:
+		return "replace"
+	}
+	return fmt.Sprintf("EditOp(%d)", int(op))
+}
+
+// Edit is a change to the content of a file. Applying Text to OldRange of
+// the old content of every edit of a file, like the TextEdits of the Language
+// Server Protocol, produces the new content. NewRange is the location of
+// Text in the new content.
+type Edit struct {
+	Op       EditOp
+	OldRange TextRange
+	NewRange TextRange
+	Text     string
+}
+
+// EditOptions configures EditsWithOptions.
+type EditOptions struct {
+	// Encoding is the unit of character offsets. If empty, PositionUTF16
+	// is used.
+	Encoding PositionEncoding
+}
+
+// Edits returns the changes in the text fragments of f as edits sorted by
+// position, with character offsets in UTF-16 code units as expected by most
+// language servers and editors. Consecutive deleted and added lines form one
+// edit. It returns nil for binary files and combined diffs.
+func (f *File) Edits() []Edit {
+	return f.EditsWithOptions(EditOptions{})
+}
+
+// EditsWithOptions is like Edits, but uses the given options.
+func (f *File) EditsWithOptions(opts EditOptions) []Edit {
+	if f.IsBinary || f.ParentCount > 0 {
+		return nil
+	}
+
+	enc := opts.Encoding
+	if enc == "" {
+		enc = PositionUTF16
+	}
+
+	var edits []Edit
+	var delta int64
+	for _, frag := range f.TextFragments {
+		start := fragmentOldStart(frag)
+		for _, r := range changedRanges(frag.Lines) {
+			oldStart := start + int64(r.start)
+			newStart := oldStart + delta
+
+			e := Edit{Text: strings.Join(r.lines, "")}
+			e.OldRange = TextRange{
+				Start: Position{Line: oldStart},
+				End:   lineEnd(oldStart, deletedLines(frag.Lines, r.start, r.end), enc),
+			}
+			e.NewRange = TextRange{
+				Start: Position{Line: newStart},
+				End:   lineEnd(newStart, r.lines, enc),
+			}
+
+			switch {
+			case r.start == r.end:
+				e.Op = EditInsert
+			case len(r.lines) == 0:
+				e.Op = EditDelete
+			default:
+				e.Op = EditReplace
+			}
+			edits = append(edits, e)
+			delta += int64(len(r.lines)) - int64(r.end-r.start)
+		}
+	}
+	return edits
+}
+
+// deletedLines returns the old lines of the fragment with zero-indexed
+// positions among the old lines from start to end.
+func deletedLines(lines []Line, start, end int) []string {
+	var deleted []string
+	old := 0
+	for _, line := range lines {
+		if !line.Old() {
+			continue
+		}
+		if old >= start && old < end {
+			deleted = append(deleted, line.Line)
+		}
+		old++
+	}
+	return deleted
+}
+
+// lineEnd returns the position after lines that start at line.
+func lineEnd(line int64, lines []string, enc PositionEncoding) Position {
+	if len(lines) == 0 {
+		return Position{Line: line}
+	}
+	last := lines[len(lines)-1]
+	if strings.HasSuffix(last, "\n") {
+		return Position{Line: line + int64(len(lines))}
+	}
+	return Position{Line: line + int64(len(lines)) - 1, Character: textWidth(last, enc)}
+}
+
+// textWidth returns the length of s in the units of the encoding.
+func textWidth(s string, enc PositionEncoding) int64 {
+	switch enc {
+	case PositionUTF8:
+		return int64(len(s))
+	case PositionUTF32:
+		return int64(utf8.RuneCountInString(s))
+	}
+
+	var n int64
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
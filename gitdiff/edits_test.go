@@ -0,0 +1,199 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileEdits(t *testing.T) {
+	tests := map[string]struct {
+		Patch  string
+		Opts   EditOptions
+		Src    string
+		Dst    string
+		Output []Edit
+	}{
+		"replace": {
+			Patch: "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			Src:   "a\nb\nc\n",
+			Dst:   "a\nB\nc\n",
+			Output: []Edit{
+				{
+					Op:       EditReplace,
+					OldRange: TextRange{Position{1, 0}, Position{2, 0}},
+					NewRange: TextRange{Position{1, 0}, Position{2, 0}},
+					Text:     "B\n",
+				},
+			},
+		},
+		"insertAndDelete": {
+			Patch: "--- a/f\n+++ b/f\n@@ -1,4 +1,5 @@\n+x\n+y\n a\n b\n-c\n d\n",
+			Src:   "a\nb\nc\nd\n",
+			Dst:   "x\ny\na\nb\nd\n",
+			Output: []Edit{
+				{
+					Op:       EditInsert,
+					OldRange: TextRange{Position{0, 0}, Position{0, 0}},
+					NewRange: TextRange{Position{0, 0}, Position{2, 0}},
+					Text:     "x\ny\n",
+				},
+				{
+					Op:       EditDelete,
+					OldRange: TextRange{Position{2, 0}, Position{3, 0}},
+					NewRange: TextRange{Position{4, 0}, Position{4, 0}},
+				},
+			},
+		},
+		"multipleFragments": {
+			Patch: "--- a/f\n+++ b/f\n@@ -1,2 +1,3 @@\n a\n+a2\n b\n@@ -9,2 +10,2 @@\n i\n-j\n+J\n",
+			Src:   "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+			Dst:   "a\na2\nb\nc\nd\ne\nf\ng\nh\ni\nJ\n",
+			Output: []Edit{
+				{
+					Op:       EditInsert,
+					OldRange: TextRange{Position{1, 0}, Position{1, 0}},
+					NewRange: TextRange{Position{1, 0}, Position{2, 0}},
+					Text:     "a2\n",
+				},
+				{
+					Op:       EditReplace,
+					OldRange: TextRange{Position{9, 0}, Position{10, 0}},
+					NewRange: TextRange{Position{10, 0}, Position{11, 0}},
+					Text:     "J\n",
+				},
+			},
+		},
+		"noFinalNewline": {
+			Patch: "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\U0001F600\n\\ No newline at end of file\n+cé\n\\ No newline at end of file\n",
+			Src:   "a\nb\U0001F600",
+			Dst:   "a\ncé",
+			Output: []Edit{
+				{
+					Op:       EditReplace,
+					OldRange: TextRange{Position{1, 0}, Position{1, 3}},
+					NewRange: TextRange{Position{1, 0}, Position{1, 2}},
+					Text:     "cé",
+				},
+			},
+		},
+		"noFinalNewlineUTF8": {
+			Patch: "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\U0001F600\n\\ No newline at end of file\n+cé\n\\ No newline at end of file\n",
+			Opts:  EditOptions{Encoding: PositionUTF8},
+			Src:   "a\nb\U0001F600",
+			Dst:   "a\ncé",
+			Output: []Edit{
+				{
+					Op:       EditReplace,
+					OldRange: TextRange{Position{1, 0}, Position{1, 5}},
+					NewRange: TextRange{Position{1, 0}, Position{1, 3}},
+					Text:     "cé",
+				},
+			},
+		},
+		"noFinalNewlineUTF32": {
+			Patch: "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\U0001F600\n\\ No newline at end of file\n+cé\n\\ No newline at end of file\n",
+			Opts:  EditOptions{Encoding: PositionUTF32},
+			Output: []Edit{
+				{
+					Op:       EditReplace,
+					OldRange: TextRange{Position{1, 0}, Position{1, 2}},
+					NewRange: TextRange{Position{1, 0}, Position{1, 2}},
+					Text:     "cé",
+				},
+			},
+		},
+		"newFile": {
+			Patch: "--- /dev/null\n+++ b/f\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+			Src:   "",
+			Dst:   "a\nb\n",
+			Output: []Edit{
+				{
+					Op:       EditInsert,
+					OldRange: TextRange{Position{0, 0}, Position{0, 0}},
+					NewRange: TextRange{Position{0, 0}, Position{2, 0}},
+					Text:     "a\nb\n",
+				},
+			},
+		},
+		"binary": {
+			Patch: "diff --git a/f b/f\nindex 1234567..89abcde 100644\nBinary files a/f and b/f differ\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			edits := files[0].EditsWithOptions(test.Opts)
+			if !reflect.DeepEqual(test.Output, edits) {
+				t.Errorf("incorrect edits\nexpected: %+v\n  actual: %+v", test.Output, edits)
+			}
+
+			if test.Opts.Encoding == "" && test.Dst != "" {
+				if out := applyEdits(t, test.Src, edits); out != test.Dst {
+					t.Errorf("incorrect result of applying edits\nexpected: %q\n  actual: %q", test.Dst, out)
+				}
+			}
+		})
+	}
+}
+
+func TestFileEditsDefaultEncoding(t *testing.T) {
+	files, _, err := ParseAll(strings.NewReader("--- a/f\n+++ b/f\n@@ -1 +1 @@\n-\U0001F600\n\\ No newline at end of file\n+x\n\\ No newline at end of file\n"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	edits := files[0].Edits()
+	if len(edits) != 1 || edits[0].OldRange.End != (Position{0, 2}) {
+		t.Errorf("expected UTF-16 positions by default, got %+v", edits)
+	}
+}
+
+func TestEditOpString(t *testing.T) {
+	tests := map[EditOp]string{
+		EditInsert:  "insert",
+		EditDelete:  "delete",
+		EditReplace: "replace",
+		EditOp(9):   "EditOp(9)",
+	}
+	for op, expected := range tests {
+		if s := op.String(); s != expected {
+			t.Errorf("incorrect string for %d: expected %q, got %q", int(op), expected, s)
+		}
+	}
+}
+
+// applyEdits applies edits with UTF-16 positions to src, in the way of an LSP
+// client: all ranges refer to the original content.
+func applyEdits(t *testing.T, src string, edits []Edit) string {
+	lines := strings.SplitAfter(src, "\n")
+	offset := func(p Position) int {
+		n := 0
+		for i := int64(0); i < p.Line; i++ {
+			n += len(lines[i])
+		}
+		units := int64(0)
+		for i, r := range lines[p.Line] {
+			if units >= p.Character {
+				return n + i
+			}
+			units += textWidth(string(r), PositionUTF16)
+		}
+		return n + len(lines[p.Line])
+	}
+
+	out := src
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		start, end := offset(e.OldRange.Start), offset(e.OldRange.End)
+		if start > end {
+			t.Fatalf("invalid range in edit %d: %+v", i, e.OldRange)
+		}
+		out = out[:start] + e.Text + out[end:]
+	}
+	return out
+}
@@ -0,0 +1,252 @@
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// decodeInput checks if the input in br starts with a byte order mark, as in
+// patches saved by some Windows editors, or is an email message with a body
+// in a content transfer encoding. It returns a reader for the input as UTF-8
+// without the mark and with the body decoded. If the input is only changed
+// by removing a UTF-8 mark, the reader is br itself and the second value is
+// the length of the mark, so offsets in the result can be converted to
+// offsets in the input. Otherwise, offsets in the result refer to the
+// decoded text and the second value is zero.
+func decodeInput(br *bufio.Reader) (stringReader, int64) {
+	// errors are returned again by the next read
+	prefix, _ := br.Peek(len(bomUTF8))
+
+	var skip int64
+	switch {
+	case bytes.HasPrefix(prefix, bomUTF8):
+		_, _ = br.Discard(len(bomUTF8))
+		skip = int64(len(bomUTF8))
+	case bytes.HasPrefix(prefix, bomUTF16LE):
+		_, _ = br.Discard(len(bomUTF16LE))
+		br = bufio.NewReader(&utf16Reader{r: br, order: binary.LittleEndian, next: -1})
+	case bytes.HasPrefix(prefix, bomUTF16BE):
+		_, _ = br.Discard(len(bomUTF16BE))
+		br = bufio.NewReader(&utf16Reader{r: br, order: binary.BigEndian, next: -1})
+	}
+
+	if r := decodeMail(br); r != nil {
+		return bufio.NewReader(r), 0
+	}
+	return br, skip
+}
+
+// decodeMail checks if the input in br is an email message, like those
+// created by git format-patch. If so, it returns a reader for the messages in
+// the input with each body in the quoted-printable or base64 content transfer
+// encoding, as sent by some mail clients and servers, decoded and the
+// encoding in the header changed to 8bit. Otherwise, it returns nil.
+func decodeMail(br *bufio.Reader) io.Reader {
+	data, _ := br.Peek(br.Size())
+	if !bytes.HasPrefix(data, []byte(mailHeaderPrefix)) && !bytes.HasPrefix(data, []byte(mailMinimumHeaderPrefix)) {
+		return nil
+	}
+	return &mailReader{br: br}
+}
+
+// mailReader decodes the messages in a mailbox one at a time, so that each
+// body is decoded with the encoding in the header of its own message.
+type mailReader struct {
+	br  *bufio.Reader
+	msg io.Reader
+}
+
+func (m *mailReader) Read(p []byte) (int, error) {
+	for {
+		if m.msg == nil {
+			if _, err := m.br.Peek(1); err != nil {
+				return 0, err
+			}
+			m.msg = nextMailMessage(m.br)
+		}
+
+		n, err := m.msg.Read(p)
+		if err == io.EOF {
+			m.msg = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// nextMailMessage returns a reader for the message at the start of br that
+// ends before the next "From " line. If the message has a header with a
+// supported content transfer encoding, the reader decodes the body.
+// Otherwise, it returns the message unchanged.
+func nextMailMessage(br *bufio.Reader) io.Reader {
+	data, _ := br.Peek(br.Size())
+
+	end := bytes.Index(data, []byte("\n\n"))
+	if crlf := bytes.Index(data, []byte("\n\r\n")); crlf >= 0 && (end < 0 || crlf < end) {
+		end = crlf + 1
+	}
+	if end < 0 {
+		return &mailBodyReader{br: br}
+	}
+	header := append([]byte(nil), data[:end+2]...)
+
+	fields := header
+	if bytes.HasPrefix(fields, []byte(mailHeaderPrefix)) {
+		fields = fields[bytes.IndexByte(fields, '\n')+1:]
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(fields))
+	if err != nil {
+		return &mailBodyReader{br: br}
+	}
+
+	body := decodeTransferEncoding(msg.Header.Get("Content-Transfer-Encoding"), &mailBodyReader{br: br, start: true})
+	if body == nil {
+		return &mailBodyReader{br: br}
+	}
+	_, _ = br.Discard(len(header))
+
+	var b bytes.Buffer
+	for _, line := range strings.SplitAfter(string(header), "\n") {
+		if i := strings.IndexByte(line, ':'); i > 0 && strings.EqualFold(line[:i], "Content-Transfer-Encoding") {
+			line = line[:i+1] + " 8bit" + line[len(strings.TrimRight(line, "\r\n")):]
+		}
+		b.WriteString(line)
+	}
+	return io.MultiReader(&b, body)
+}
+
+// mailBodyReader reads from br until the start of a line that starts the next
+// message in a mailbox. The first line is never treated as the start of a
+// message unless start is true.
+type mailBodyReader struct {
+	br    *bufio.Reader
+	start bool
+}
+
+func (r *mailBodyReader) Read(p []byte) (int, error) {
+	if r.start {
+		if prefix, _ := r.br.Peek(len(mailHeaderPrefix)); string(prefix) == mailHeaderPrefix {
+			return 0, io.EOF
+		}
+	}
+	if r.br.Buffered() == 0 {
+		if _, err := r.br.Peek(1); err != nil {
+			return 0, err
+		}
+	}
+
+	buf, _ := r.br.Peek(r.br.Buffered())
+	if len(buf) > len(p) {
+		buf = buf[:len(p)]
+	}
+	r.start = false
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		buf = buf[:i+1]
+		r.start = true
+	}
+	n := copy(p, buf)
+	_, _ = r.br.Discard(n)
+	return n, nil
+}
+
+// utf16Reader transcodes UTF-16 input to UTF-8. Invalid surrogates are
+// replaced by utf8.RuneError.
+type utf16Reader struct {
+	r     io.Reader
+	order binary.ByteOrder
+
+	// next is a unit read after an unpaired surrogate, or -1 if none
+	next rune
+	buf  []byte
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	var n int
+	for n < len(p) {
+		if len(u.buf) == 0 {
+			r, err := u.readRune()
+			if err != nil {
+				if n > 0 {
+					return n, nil
+				}
+				return 0, err
+			}
+
+			var b [utf8.UTFMax]byte
+			u.buf = b[:utf8.EncodeRune(b[:], r)]
+		}
+
+		c := copy(p[n:], u.buf)
+		u.buf = u.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+func (u *utf16Reader) readRune() (rune, error) {
+	r, err := u.readUnit()
+	if err != nil || !utf16.IsSurrogate(r) {
+		return r, err
+	}
+	if r >= 0xDC00 {
+		// a low surrogate without a high surrogate
+		return utf8.RuneError, nil
+	}
+
+	r2, err := u.readUnit()
+	if err == io.EOF {
+		return utf8.RuneError, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+		return dec, nil
+	}
+	u.next = r2
+	return utf8.RuneError, nil
+}
+
+func (u *utf16Reader) readUnit() (rune, error) {
+	if u.next >= 0 {
+		r := u.next
+		u.next = -1
+		return r, nil
+	}
+
+	var b [2]byte
+	if _, err := io.ReadFull(u.r, b[:]); err != nil {
+		return 0, err
+	}
+	return rune(u.order.Uint16(b[:])), nil
+}
+
+// decodeTransferEncoding returns a reader that decodes r, the body of an
+// email message with the content transfer encoding in encoding. It returns
+// nil if the encoding is not quoted-printable or base64.
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	}
+	return nil
+}
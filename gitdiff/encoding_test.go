@@ -0,0 +1,236 @@
+package gitdiff
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeUTF16(s string, order binary.ByteOrder) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(b[2*i:], u)
+	}
+	return b
+}
+
+func TestParseEncodedInput(t *testing.T) {
+	const patch = `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1 +1 @@
+-caf` + "\u00e9" + `
++caf` + "\u00e9 \U0001F600" + `
+`
+
+	expected, _, err := ParseAll(bytes.NewReader([]byte(patch)))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	tests := map[string][]byte{
+		"utf8":    append([]byte("\xEF\xBB\xBF"), patch...),
+		"utf16LE": append([]byte("\xFF\xFE"), encodeUTF16(patch, binary.LittleEndian)...),
+		"utf16BE": append([]byte("\xFE\xFF"), encodeUTF16(patch, binary.BigEndian)...),
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, preamble, err := ParseAll(bytes.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			if preamble != "" {
+				t.Errorf("unexpected preamble: %q", preamble)
+			}
+			if !reflect.DeepEqual(expected, files) {
+				t.Errorf("incorrect files\nexpected: %+v\n  actual: %+v", expected[0], files[0])
+			}
+
+			files, _, _ = ParseAllWithOptions(bytes.NewReader(input), ParseOptions{KeepEncoding: true})
+			if reflect.DeepEqual(expected, files) {
+				t.Errorf("input was decoded with KeepEncoding")
+			}
+
+			ch, err := Parse(bytes.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch with Parse: %v", err)
+			}
+			var fragments []*TextFragment
+			for f := range ch {
+				fragments = append(fragments, f.TextFragments...)
+			}
+			if !reflect.DeepEqual(expected[0].TextFragments, fragments) {
+				t.Errorf("incorrect fragments from Parse\nexpected: %+v\n  actual: %+v", expected[0].TextFragments, fragments)
+			}
+		})
+	}
+
+	t.Run("offsets", func(t *testing.T) {
+		var offsets Offsets
+		files, _, err := ParseAllWithOptions(bytes.NewReader(tests["utf8"]), ParseOptions{Offsets: &offsets})
+		if err != nil {
+			t.Fatalf("unexpected error parsing patch: %v", err)
+		}
+		if span, _ := offsets.File(files[0]); span.Start != 3 || span.End != int64(len(tests["utf8"])) {
+			t.Errorf("incorrect file span: %+v", span)
+		}
+	})
+}
+
+func TestUTF16Reader(t *testing.T) {
+	tests := map[string]struct {
+		Input  []uint16
+		Output string
+	}{
+		"ascii":           {Input: []uint16{'a', 'b'}, Output: "ab"},
+		"surrogatePair":   {Input: []uint16{0xD83D, 0xDE00, 'a'}, Output: "\U0001F600a"},
+		"unpairedHigh":    {Input: []uint16{0xD83D, 'a'}, Output: "\uFFFDa"},
+		"unpairedLow":     {Input: []uint16{0xDE00, 'a'}, Output: "\uFFFDa"},
+		"highAtEnd":       {Input: []uint16{'a', 0xD83D}, Output: "a\uFFFD"},
+		"consecutiveHigh": {Input: []uint16{0xD83D, 0xD83D, 0xDE00}, Output: "\uFFFD\U0001F600"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := make([]byte, 2*len(test.Input))
+			for i, u := range test.Input {
+				binary.LittleEndian.PutUint16(b[2*i:], u)
+			}
+
+			out, err := ioutil.ReadAll(&utf16Reader{r: bytes.NewReader(b), order: binary.LittleEndian, next: -1})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(out) != test.Output {
+				t.Errorf("incorrect output: expected %q, actual %q", test.Output, out)
+			}
+		})
+	}
+}
+
+func TestParseEncodedMail(t *testing.T) {
+	const header = `From 61f5cd90bed4d204ee3feb3aa41ee91d4734855b Mon Sep 17 00:00:00 2001
+From: Morton Haypenny <mhaypenny@example.com>
+Date: Sat, 11 Apr 2020 15:21:23 -0700
+Subject: [PATCH] Change the value
+MIME-Version: 1.0
+Content-Type: text/plain; charset=UTF-8
+`
+
+	const body = `Set x to a new value that is long enough to need a soft line break in quoted-printable.
+---
+diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -1 +1 @@
+-x := 1
++x := 2 // a comment that is long enough to need a soft line break when encoded
+--
+2.26.0
+`
+
+	const qpBody = `Set x to a new value that is long enough to need a soft line break in quo=
+ted-printable.
+---
+diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -1 +1 @@
+-x :=3D 1
++x :=3D 2 // a comment that is long enough to need a soft line break when =
+encoded
+--
+2.26.0
+`
+
+	expected, preamble, err := ParseAll(strings.NewReader(header + "\n" + body))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	expectedHeader, err := ParsePatchHeader(preamble)
+	if err != nil {
+		t.Fatalf("unexpected error parsing header: %v", err)
+	}
+
+	tests := map[string]string{
+		"quotedPrintable": header + "Content-Transfer-Encoding: quoted-printable\n\n" + qpBody,
+		"base64":          header + "Content-Transfer-Encoding: base64\n\n" + base64.StdEncoding.EncodeToString([]byte(body)),
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, preamble, err := ParseAll(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			if len(files) != 1 || !reflect.DeepEqual(expected[0].TextFragments, files[0].TextFragments) {
+				t.Fatalf("incorrect files\nexpected: %+v\n  actual: %+v", expected, files)
+			}
+
+			h, err := ParsePatchHeader(preamble)
+			if err != nil {
+				t.Fatalf("unexpected error parsing header: %v", err)
+			}
+			if !reflect.DeepEqual(expectedHeader, h) {
+				t.Errorf("incorrect patch header\nexpected: %+v\n  actual: %+v", expectedHeader, h)
+			}
+
+			// headers are decoded when parsed directly from the encoded message
+			end := strings.Index(input, "\n---\n")
+			if end < 0 {
+				end = len(input)
+			}
+			if h, err := ParsePatchHeader(input[:end]); err != nil || h.Body == "" || !strings.HasPrefix(expectedHeader.Body, h.Body) {
+				t.Errorf("incorrect patch header parsed from encoded message: %+v (%v)", h, err)
+			}
+
+			files, _, _ = ParseAllWithOptions(strings.NewReader(input), ParseOptions{KeepEncoding: true})
+			if len(files) == 1 && reflect.DeepEqual(expected[0].TextFragments, files[0].TextFragments) {
+				t.Errorf("body was decoded with KeepEncoding")
+			}
+		})
+	}
+}
+
+func TestParseEncodedMailbox(t *testing.T) {
+	message := func(n int, encoding, body string) string {
+		return fmt.Sprintf(`From 61f5cd90bed4d204ee3feb3aa41ee91d4734855b Mon Sep 17 00:00:00 2001
+From: Morton Haypenny <mhaypenny@example.com>
+Date: Sat, 11 Apr 2020 15:21:23 -0700
+Subject: [PATCH %d/3] Change a value
+MIME-Version: 1.0
+Content-Type: text/plain; charset=UTF-8
+Content-Transfer-Encoding: %s
+
+%s`, n, encoding, body)
+	}
+	diff := func(name, old, new string) string {
+		return fmt.Sprintf("---\ndiff --git a/%[1]s b/%[1]s\n--- a/%[1]s\n+++ b/%[1]s\n@@ -1 +1 @@\n-%[2]s\n+%[3]s\n--\n2.26.0\n\n", name, old, new)
+	}
+
+	input := message(1, "quoted-printable", "Change x.\n"+strings.Replace(diff("x.txt", "x=10", "x=20"), "=", "=3D", -1)) +
+		message(2, "8bit", "Change y.\n"+diff("y.txt", "y=10", "y=20")) +
+		message(3, "base64", base64.StdEncoding.EncodeToString([]byte("Change z.\n"+diff("z.txt", "z=10", "z=20")))+"\n")
+
+	files, _, err := ParseAll(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("incorrect number of files: %d", len(files))
+	}
+	for i, name := range []string{"x", "y", "z"} {
+		expected := []Line{{OpDelete, name + "=10\n"}, {OpAdd, name + "=20\n"}}
+		if f := files[i]; f.NewName != name+".txt" || !reflect.DeepEqual(expected, f.TextFragments[0].Lines) {
+			t.Errorf("incorrect file %d: %s: %q", i+1, f.NewName, f.TextFragments[0].Lines)
+		}
+	}
+}
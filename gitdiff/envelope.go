@@ -0,0 +1,166 @@
+package gitdiff
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// envelopeMagic starts every encoded patch.
+const envelopeMagic = "GDPE"
+
+// EnvelopeVersion is the format version written by EncodePatch. Version 1
+// envelopes encode files with encoding/gob and version 2 envelopes use the
+// encoding of File.MarshalBinary. DecodePatch reads both versions.
+const EnvelopeVersion = 2
+
+const (
+	envelopeGob    = 1
+	envelopeBinary = 2
+)
+
+const (
+	envelopeGzip = 1 << iota
+)
+
+// ErrInvalidEnvelope is returned by DecodePatch when data is not an encoded
+// patch or is corrupt.
+var ErrInvalidEnvelope = errors.New("gitdiff: invalid patch envelope")
+
+// EncodeOptions configures EncodePatch.
+type EncodeOptions struct {
+	// Compress compresses the encoded files with gzip.
+	Compress bool
+}
+
+// EncodePatch serializes parsed files into a binary envelope that DecodePatch
+// can restore without parsing the patch text again. The envelope starts with
+// a magic number and the format version, so stored patches can be detected
+// and migrated when the format changes.
+func EncodePatch(files []*File, opts EncodeOptions) ([]byte, error) {
+	var flags byte
+	if opts.Compress {
+		flags |= envelopeGzip
+	}
+
+	var b bytes.Buffer
+	b.WriteString(envelopeMagic)
+	b.WriteByte(EnvelopeVersion)
+	b.WriteByte(flags)
+
+	var w io.Writer = &b
+	var zw *gzip.Writer
+	if opts.Compress {
+		zw = gzip.NewWriter(&b)
+		w = zw
+	}
+
+	// each file is prefixed by the length of its encoding
+	for _, f := range files {
+		data, err := f.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("gitdiff: encoding patch: %v", err)
+		}
+		if _, err := w.Write(appendUvarint(nil, uint64(len(data)))); err != nil {
+			return nil, fmt.Errorf("gitdiff: encoding patch: %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("gitdiff: encoding patch: %v", err)
+		}
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("gitdiff: encoding patch: %v", err)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// DefaultMaxDecodedSize is the limit on the decompressed size of a compressed
+// envelope used by DecodePatch.
+const DefaultMaxDecodedSize = 256 << 20
+
+// DecodeOptions configures DecodePatchWithOptions.
+type DecodeOptions struct {
+	// MaxSize is the maximum size of the payload of a compressed envelope
+	// after decompression. If the payload is larger, decoding stops with an
+	// error matching ErrPatchTooLarge. If zero, DefaultMaxDecodedSize is
+	// used. If negative, the size is not limited.
+	MaxSize int64
+}
+
+// DecodePatch restores the files serialized by EncodePatch. In version 1
+// envelopes, empty slices, such as the data of an empty binary fragment, are
+// restored as nil. It returns an error wrapping ErrInvalidEnvelope if data is
+// not a valid envelope and an error if the envelope uses an unsupported
+// version. Compressed envelopes are limited to DefaultMaxDecodedSize bytes
+// after decompression; use DecodePatchWithOptions to change the limit.
+func DecodePatch(data []byte) ([]*File, error) {
+	return DecodePatchWithOptions(data, DecodeOptions{})
+}
+
+// DecodePatchWithOptions is like DecodePatch, but uses the given options.
+func DecodePatchWithOptions(data []byte, opts DecodeOptions) ([]*File, error) {
+	header := len(envelopeMagic) + 2
+	if len(data) < header || string(data[:len(envelopeMagic)]) != envelopeMagic {
+		return nil, ErrInvalidEnvelope
+	}
+
+	version, flags := data[len(envelopeMagic)], data[len(envelopeMagic)+1]
+	if version != envelopeGob && version != envelopeBinary {
+		return nil, fmt.Errorf("gitdiff: unsupported patch envelope version %d", version)
+	}
+	if flags&^envelopeGzip != 0 {
+		return nil, fmt.Errorf("%w: unknown flags %#x", ErrInvalidEnvelope, flags)
+	}
+
+	payload := data[header:]
+	if flags&envelopeGzip != 0 {
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+		}
+		max := opts.MaxSize
+		if max == 0 {
+			max = DefaultMaxDecodedSize
+		}
+		var r io.Reader = zr
+		if max > 0 {
+			r = io.LimitReader(zr, max+1)
+		}
+		if payload, err = ioutil.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+		}
+		if max > 0 && int64(len(payload)) > max {
+			return nil, fmt.Errorf("%w: decompressed envelope is larger than %d bytes", ErrPatchTooLarge, max)
+		}
+	}
+
+	var files []*File
+	if version == envelopeGob {
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&files); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+		}
+		return files, nil
+	}
+
+	for len(payload) > 0 {
+		size, n := binary.Uvarint(payload)
+		if n <= 0 || uint64(len(payload)-n) < size {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, errCodecTruncated)
+		}
+
+		f := &File{}
+		if err := f.UnmarshalBinary(payload[n : n+int(size)]); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+		}
+		files = append(files, f)
+		payload = payload[n+int(size):]
+	}
+	return files, nil
+}
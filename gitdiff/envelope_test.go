@@ -0,0 +1,154 @@
+package gitdiff
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodePatch(t *testing.T) {
+	patches, err := filepath.Glob("testdata/*.patch")
+	if err != nil {
+		t.Fatalf("unexpected error listing patches: %v", err)
+	}
+
+	for _, patch := range patches {
+		for _, compress := range []bool{false, true} {
+			name := filepath.Base(patch)
+			if compress {
+				name += "/gzip"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				f, err := os.Open(patch)
+				if err != nil {
+					t.Fatalf("unexpected error opening patch: %v", err)
+				}
+				defer f.Close()
+
+				files, _, err := ParseAll(f)
+				if err != nil {
+					t.Fatalf("unexpected error parsing patch: %v", err)
+				}
+
+				data, err := EncodePatch(files, EncodeOptions{Compress: compress})
+				if err != nil {
+					t.Fatalf("unexpected error encoding patch: %v", err)
+				}
+
+				decoded, err := DecodePatch(data)
+				if err != nil {
+					t.Fatalf("unexpected error decoding patch: %v", err)
+				}
+
+				if !reflect.DeepEqual(files, decoded) {
+					t.Errorf("decoded files do not match\nexpected: %+v\n  actual: %+v", files, decoded)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodePatchVersion1(t *testing.T) {
+	files := []*File{{
+		OldName: "file.txt",
+		NewName: "file.txt",
+		TextFragments: []*TextFragment{{
+			OldPosition: 1, OldLines: 1, NewPosition: 1, NewLines: 1,
+			LinesAdded: 1, LinesDeleted: 1,
+			Lines: []Line{{OpDelete, "old\n"}, {OpAdd, "new\n"}},
+		}},
+	}}
+
+	var b bytes.Buffer
+	b.WriteString("GDPE\x01\x00")
+	if err := gob.NewEncoder(&b).Encode(files); err != nil {
+		t.Fatalf("unexpected error encoding files: %v", err)
+	}
+
+	decoded, err := DecodePatch(b.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error decoding patch: %v", err)
+	}
+	if !reflect.DeepEqual(files, decoded) {
+		t.Errorf("decoded files do not match\nexpected: %+v\n  actual: %+v", files, decoded)
+	}
+}
+
+func TestDecodePatchInvalid(t *testing.T) {
+	valid, err := EncodePatch([]*File{{OldName: "file.txt", NewName: "file.txt"}}, EncodeOptions{Compress: true})
+	if err != nil {
+		t.Fatalf("unexpected error encoding patch: %v", err)
+	}
+
+	tests := map[string]struct {
+		Data    []byte
+		Invalid bool
+	}{
+		"empty":        {Data: nil, Invalid: true},
+		"text":         {Data: []byte("diff --git a/file.txt b/file.txt\n"), Invalid: true},
+		"version":      {Data: []byte("GDPE\x09\x00")},
+		"flags":        {Data: []byte("GDPE\x01\x80"), Invalid: true},
+		"truncated":    {Data: valid[:len(valid)-8], Invalid: true},
+		"uncompressed": {Data: append([]byte("GDPE\x01\x01"), "not gzip"...), Invalid: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodePatch(test.Data)
+			if err == nil {
+				t.Fatal("expected error decoding patch, but got nil")
+			}
+			if errors.Is(err, ErrInvalidEnvelope) != test.Invalid {
+				t.Errorf("incorrect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecodePatchMaxSize(t *testing.T) {
+	f := &File{
+		OldName: "file.txt",
+		NewName: "file.txt",
+		TextFragments: []*TextFragment{{
+			OldPosition: 1, OldLines: 1, NewPosition: 1, NewLines: 1,
+			LinesAdded: 1, LinesDeleted: 1,
+			Lines: []Line{{OpDelete, "old\n"}, {OpAdd, strings.Repeat("x", 4096) + "\n"}},
+		}},
+	}
+	data, err := EncodePatch([]*File{f}, EncodeOptions{Compress: true})
+	if err != nil {
+		t.Fatalf("unexpected error encoding patch: %v", err)
+	}
+
+	tests := map[string]struct {
+		MaxSize int64
+		Err     interface{}
+	}{
+		"default":   {},
+		"unlimited": {MaxSize: -1},
+		"large":     {MaxSize: 8192},
+		"small":     {MaxSize: 1024, Err: ErrPatchTooLarge},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := DecodePatchWithOptions(data, DecodeOptions{MaxSize: test.MaxSize})
+			if test.Err != nil {
+				assertError(t, test.Err, err, "decoding patch")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error decoding patch: %v", err)
+			}
+			if len(files) != 1 || len(files[0].TextFragments) != 1 {
+				t.Errorf("incorrect files: %+v", files)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package gitdiff
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// Divergence describes the first difference found by Equivalent.
+type Divergence struct {
+	// Path is the name of the first file, in sorted order, that differs.
+	Path string
+
+	// Line is the one-indexed number of the first line that differs, or zero
+	// if the content of the file is the same or the file is missing from one
+	// of the results.
+	Line int64
+
+	// Reason describes the difference.
+	Reason string
+}
+
+func (d Divergence) String() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", d.Path, d.Line, d.Reason)
+	}
+	return fmt.Sprintf("%s: %s", d.Path, d.Reason)
+}
+
+// Equivalent applies the patches a and b separately to the regular files in
+// base and returns true if they produce the same files with the same content
+// and modes. Otherwise, it reports the first difference between the results.
+// This is useful to check that a tool that rewrites patches, such as one that
+// splits or reorders fragments, preserves their effect. Files are read and
+// patched as by BuildTree; base is not modified and may be nil to represent
+// an empty tree. It returns an error if either patch does not apply.
+func Equivalent(a, b []*File, base fs.FS) (bool, Divergence, error) {
+	tree, err := readTree(base)
+	if err != nil {
+		return false, Divergence{}, err
+	}
+
+	treeA, treeB := entryTree(tree).clone(), entryTree(tree).clone()
+	if err := BuildTree(a, treeA); err != nil {
+		return false, Divergence{}, fmt.Errorf("gitdiff: applying first patch: %w", err)
+	}
+	if err := BuildTree(b, treeB); err != nil {
+		return false, Divergence{}, fmt.Errorf("gitdiff: applying second patch: %w", err)
+	}
+
+	names := make([]string, 0, len(treeA)+len(treeB))
+	for name := range treeA {
+		names = append(names, name)
+	}
+	for name := range treeB {
+		if _, ok := treeA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ea, eb := treeA[name], treeB[name]
+		switch {
+		case eb == nil:
+			return false, Divergence{Path: name, Reason: "only exists after the first patch"}, nil
+		case ea == nil:
+			return false, Divergence{Path: name, Reason: "only exists after the second patch"}, nil
+		}
+
+		if line := firstDifferentLine(ea.data, eb.data); line > 0 {
+			return false, Divergence{Path: name, Line: line, Reason: "content differs"}, nil
+		}
+		if ea.mode != eb.mode {
+			reason := fmt.Sprintf("mode differs: %o != %o", ea.mode, eb.mode)
+			return false, Divergence{Path: name, Reason: reason}, nil
+		}
+	}
+	return true, Divergence{}, nil
+}
+
+// firstDifferentLine returns the one-indexed number of the first line that
+// differs between a and b, or zero if they are equal.
+func firstDifferentLine(a, b []byte) int64 {
+	la, lb := splitLines(a), splitLines(b)
+	for i := 0; i < len(la) || i < len(lb); i++ {
+		if i >= len(la) || i >= len(lb) || la[i] != lb[i] {
+			return int64(i + 1)
+		}
+	}
+	return 0
+}
+
+// entryTree is a TreeWriter that stores files in memory.
+type entryTree map[string]*treeEntry
+
+func (t entryTree) clone() entryTree {
+	c := make(entryTree, len(t))
+	for name, e := range t {
+		c[name] = &treeEntry{mode: e.mode, data: e.data}
+	}
+	return c
+}
+
+func (t entryTree) ReadBlob(path string) ([]byte, os.FileMode, error) {
+	e, ok := t[path]
+	if !ok {
+		return nil, 0, os.ErrNotExist
+	}
+	return e.data, e.mode, nil
+}
+
+func (t entryTree) WriteBlob(path string, data []byte) error {
+	if e, ok := t[path]; ok {
+		e.data = data
+	} else {
+		t[path] = &treeEntry{data: data}
+	}
+	return nil
+}
+
+func (t entryTree) SetMode(path string, mode os.FileMode) error {
+	e, ok := t[path]
+	if !ok {
+		return os.ErrNotExist
+	}
+	e.mode = mode
+	return nil
+}
+
+func (t entryTree) DeletePath(path string) error {
+	delete(t, path)
+	return nil
+}
@@ -0,0 +1,131 @@
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEquivalent(t *testing.T) {
+	base := fstest.MapFS{
+		"file.txt":  &fstest.MapFile{Data: []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")},
+		"other.txt": &fstest.MapFile{Data: []byte("other\n")},
+	}
+
+	const twoFragments = `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@
+-1
++one
+ 2
+@@ -9,2 +9,2 @@
+ 9
+-10
++ten
+`
+	const oneFragment = `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,10 +1,10 @@
+-1
++one
+ 2
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+ 9
+-10
++ten
+`
+	const firstFragment = `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@
+-1
++one
+ 2
+`
+	const modeChange = `diff --git a/other.txt b/other.txt
+old mode 100644
+new mode 100755
+`
+	const deleteOther = `diff --git a/other.txt b/other.txt
+deleted file mode 100644
+--- a/other.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-other
+`
+
+	tests := map[string]struct {
+		A, B       string
+		Equivalent bool
+		Divergence Divergence
+		Err        bool
+	}{
+		"sameChanges": {
+			A:          twoFragments,
+			B:          oneFragment,
+			Equivalent: true,
+		},
+		"contentDiffers": {
+			A:          twoFragments,
+			B:          strings.Replace(oneFragment, "+ten", "+TEN", 1),
+			Divergence: Divergence{Path: "file.txt", Line: 10, Reason: "content differs"},
+		},
+		"missingFragment": {
+			A:          twoFragments,
+			B:          firstFragment,
+			Divergence: Divergence{Path: "file.txt", Line: 10, Reason: "content differs"},
+		},
+		"modeDiffers": {
+			A:          twoFragments + modeChange,
+			B:          twoFragments,
+			Divergence: Divergence{Path: "other.txt", Reason: "mode differs: 100755 != 100644"},
+		},
+		"deleted": {
+			A:          twoFragments,
+			B:          twoFragments + deleteOther,
+			Divergence: Divergence{Path: "other.txt", Reason: "only exists after the first patch"},
+		},
+		"doesNotApply": {
+			A:   twoFragments,
+			B:   strings.Replace(oneFragment, " 5\n", " five\n", 1),
+			Err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, _, err := ParseAll(strings.NewReader(test.A))
+			if err != nil {
+				t.Fatalf("unexpected error parsing first patch: %v", err)
+			}
+			b, _, err := ParseAll(strings.NewReader(test.B))
+			if err != nil {
+				t.Fatalf("unexpected error parsing second patch: %v", err)
+			}
+
+			eq, div, err := Equivalent(a, b, base)
+			if test.Err {
+				if err == nil {
+					t.Fatal("expected error comparing patches, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error comparing patches: %v", err)
+			}
+			if eq != test.Equivalent {
+				t.Errorf("incorrect result: expected %t, actual %t", test.Equivalent, eq)
+			}
+			if div != test.Divergence {
+				t.Errorf("incorrect divergence\nexpected: %v\n  actual: %v", test.Divergence, div)
+			}
+		})
+	}
+}
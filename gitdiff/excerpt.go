@@ -0,0 +1,39 @@
+package gitdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formatExcerpt formats the lines within context lines of target, marking the
+// target line with ">" and a caret, like a compiler diagnostic. The line
+// number of lines[0] is first. It returns an empty string if target is not in
+// lines.
+func formatExcerpt(lines []string, first, target int64, context int) string {
+	if target < first || target >= first+int64(len(lines)) {
+		return ""
+	}
+
+	start, end := target-int64(context), target+int64(context)+1
+	if start < first {
+		start = first
+	}
+	if last := first + int64(len(lines)); end > last {
+		end = last
+	}
+
+	width := len(fmt.Sprint(end - 1))
+	var b strings.Builder
+	for n := start; n < end; n++ {
+		marker := " "
+		if n == target {
+			marker = ">"
+		}
+		line := strings.TrimRight(lines[n-first], "\r\n")
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, n, line)
+		if n == target {
+			fmt.Fprintf(&b, "  %s | ^\n", strings.Repeat(" ", width))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,106 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormatExcerpt(t *testing.T) {
+	lines := []string{"one\n", "two\n", "three\r\n", "four\n"}
+
+	tests := map[string]struct {
+		Target  int64
+		Context int
+		Output  string
+	}{
+		"middle": {
+			Target:  10,
+			Context: 1,
+			Output:  "   9 | one\n> 10 | two\n     | ^\n  11 | three",
+		},
+		"start": {
+			Target:  9,
+			Context: 2,
+			Output:  ">  9 | one\n     | ^\n  10 | two\n  11 | three",
+		},
+		"end": {
+			Target:  12,
+			Context: 1,
+			Output:  "  11 | three\n> 12 | four\n     | ^",
+		},
+		"outside": {
+			Target:  13,
+			Context: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if out := formatExcerpt(lines, 9, test.Target, test.Context); out != test.Output {
+				t.Errorf("incorrect excerpt\nexpected:\n%s\nactual:\n%s", test.Output, out)
+			}
+		})
+	}
+}
+
+func TestParseErrorContext(t *testing.T) {
+	input := `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ line 1
+-line 2
+*line 3
+ line 4
+`
+
+	_, _, err := ParseAll(strings.NewReader(input))
+	if err == nil || strings.Contains(err.Error(), "\n") {
+		t.Fatalf("expected error without excerpt, but got %v", err)
+	}
+
+	_, _, err = ParseAllWithOptions(strings.NewReader(input), ParseOptions{ErrorContext: 1})
+	expected := "gitdiff: line 7: invalid line operation: '*'\n" +
+		"  6 | -line 2\n" +
+		"> 7 | *line 3\n" +
+		"    | ^\n" +
+		"  8 |  line 4"
+	if err == nil || err.Error() != expected {
+		t.Errorf("incorrect error\nexpected:\n%s\nactual:\n%v", expected, err)
+	}
+}
+
+func TestApplyErrorContext(t *testing.T) {
+	files, _, err := ParseAll(strings.NewReader(`diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ line 1
+-line 2
++line two
+ line 3
+`))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	src := strings.NewReader("line 1\nline 2 changed\nline 3\n")
+	err = NewApplierWithOptions(src, ApplyOptions{ErrorContext: 1}).ApplyFile(&bytes.Buffer{}, files[0])
+
+	var aerr *ApplyError
+	if !errors.As(err, &aerr) {
+		t.Fatalf("expected *ApplyError, but got %T: %v", err, err)
+	}
+	expected := "  1 |  line 1\n" +
+		"> 2 | -line 2\n" +
+		"    | ^\n" +
+		"  3 | +line two"
+	if aerr.Excerpt != expected {
+		t.Errorf("incorrect excerpt\nexpected:\n%s\nactual:\n%s", expected, aerr.Excerpt)
+	}
+	if !strings.HasSuffix(err.Error(), "\n"+expected) {
+		t.Errorf("error message does not include excerpt: %v", err)
+	}
+}
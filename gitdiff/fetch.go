@@ -0,0 +1,220 @@
+package gitdiff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrPatchTooLarge matches errors returned when a fetched patch is larger than
+// the MaxBytes of the FetchOptions, when a decoded envelope is larger than the
+// MaxSize of the DecodeOptions, or when decompressed input is larger than the
+// MaxDecompressedBytes of the ParseOptions. Use errors.Is to test for it.
+var ErrPatchTooLarge = errors.New("gitdiff: patch is too large")
+
+// FetchOptions configures FetchPatch and FetchIndex.
+type FetchOptions struct {
+	// Client sends the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// MaxBytes is the maximum size of the patch. If the response is larger,
+	// fetching stops with an error matching ErrPatchTooLarge. Unless the
+	// MaxDecompressedBytes of ParseOptions is set, it also limits the size
+	// of a compressed patch after decompression. If zero or negative, the
+	// size is not limited. Set a limit when fetching patches from untrusted
+	// locations.
+	MaxBytes int64
+
+	// ParseOptions configures how FetchPatch parses the patch.
+	ParseOptions ParseOptions
+}
+
+func (opts FetchOptions) client() *http.Client {
+	if opts.Client == nil {
+		return http.DefaultClient
+	}
+	return opts.Client
+}
+
+// FetchPatch downloads the patch at url and parses it like
+// ParseAllWithOptions. The patch is parsed as it is received, so it is never
+// held in memory. Canceling ctx stops the download and parsing.
+func FetchPatch(ctx context.Context, url string, opts FetchOptions) ([]*File, string, error) {
+	body, err := fetch(ctx, url, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer body.Close()
+
+	parseOpts := opts.ParseOptions
+	if opts.MaxBytes > 0 && parseOpts.MaxDecompressedBytes == 0 {
+		parseOpts.MaxDecompressedBytes = opts.MaxBytes
+	}
+	return ParseAllWithOptions(body, parseOpts)
+}
+
+// FetchIndex downloads the patch at url and indexes it like IndexPatch. Use
+// an HTTPReaderAt for the same url to load the hunks of the files on demand
+// without downloading the whole patch again. The ParseOptions of opts are not
+// used.
+func FetchIndex(ctx context.Context, url string, opts FetchOptions) ([]*LazyFile, string, error) {
+	body, err := fetch(ctx, url, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer body.Close()
+	return IndexPatch(body)
+}
+
+func fetch(ctx context.Context, url string, opts FetchOptions) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("gitdiff: fetching patch: %w", err)
+	}
+
+	resp, err := opts.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gitdiff: fetching patch: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("gitdiff: fetching patch: unexpected status: %s", resp.Status)
+	}
+
+	if opts.MaxBytes > 0 {
+		if resp.ContentLength > opts.MaxBytes {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %d bytes is more than %d bytes", ErrPatchTooLarge, resp.ContentLength, opts.MaxBytes)
+		}
+		return &limitReadCloser{ReadCloser: resp.Body, remaining: opts.MaxBytes, max: opts.MaxBytes}, nil
+	}
+	return resp.Body, nil
+}
+
+// limitReadCloser returns an error matching ErrPatchTooLarge if more than max
+// bytes are read from its reader.
+type limitReadCloser struct {
+	io.ReadCloser
+	remaining int64
+	max       int64
+}
+
+func (lr *limitReadCloser) Read(p []byte) (int, error) {
+	if lr.remaining < 0 {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrPatchTooLarge, lr.max)
+	}
+
+	// read one byte past the limit to tell if the input is exactly max bytes
+	if int64(len(p)) > lr.remaining+1 {
+		p = p[:lr.remaining+1]
+	}
+	n, err := lr.ReadCloser.Read(p)
+	lr.remaining -= int64(n)
+	if lr.remaining < 0 {
+		return n + int(lr.remaining), fmt.Errorf("%w: more than %d bytes", ErrPatchTooLarge, lr.max)
+	}
+	return n, err
+}
+
+// HTTPReaderAt is an io.ReaderAt for a file at a URL that reads with HTTP
+// range requests, so LazyHunk.Load can load hunks of a large remote patch
+// indexed with FetchIndex without downloading the whole patch. Each call to
+// ReadAt sends one request. If the server does not support range requests,
+// ReadAt reads and discards the start of the file, which works but is slow.
+type HTTPReaderAt struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+}
+
+// NewHTTPReaderAt returns an HTTPReaderAt for the file at url. Requests use
+// ctx and client; if client is nil, http.DefaultClient is used.
+func NewHTTPReaderAt(ctx context.Context, client *http.Client, url string) *HTTPReaderAt {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPReaderAt{ctx: ctx, client: client, url: url}
+}
+
+// ReadAt reads len(p) bytes of the file starting at off.
+func (r *HTTPReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("gitdiff: negative offset")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(off, 10)+"-"+strconv.FormatInt(off+int64(len(p))-1, 10))
+
+	// offsets refer to the content without any transfer compression
+	req.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, end, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != off || end >= off+int64(len(p)) {
+			return 0, fmt.Errorf("gitdiff: reading %s: response range %q does not match the requested range", r.url, resp.Header.Get("Content-Range"))
+		}
+		if size := end - start + 1; size < int64(len(p)) {
+			n, err := io.ReadFull(resp.Body, p[:size])
+			if err == nil || err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return n, err
+		}
+	case http.StatusOK:
+		if _, err := io.CopyN(io.Discard, resp.Body, off); err != nil {
+			if err == io.EOF {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, io.EOF
+	default:
+		return 0, fmt.Errorf("gitdiff: reading %s: unexpected status: %s", r.url, resp.Status)
+	}
+
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// parseContentRange returns the first and last byte in the value of a
+// Content-Range header, like "bytes 0-499/1234".
+func parseContentRange(s string) (start, end int64, ok bool) {
+	s = strings.TrimPrefix(s, "bytes ")
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		s = s[:i]
+	}
+	i := strings.IndexByte(s, '-')
+	if i < 0 {
+		return 0, 0, false
+	}
+
+	var err error
+	if start, err = strconv.ParseInt(s[:i], 10, 64); err != nil {
+		return 0, 0, false
+	}
+	if end, err = strconv.ParseInt(s[i+1:], 10, 64); err != nil || end < start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
@@ -0,0 +1,241 @@
+package gitdiff
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newPatchServer(t *testing.T, data []byte, ranges bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/patch":
+			if ranges {
+				http.ServeContent(w, r, "patch", time.Time{}, bytes.NewReader(data))
+			} else {
+				_, _ = w.Write(data)
+			}
+		case "/gzip":
+			zw := gzip.NewWriter(w)
+			_, _ = zw.Write(data)
+			_ = zw.Close()
+		case "/chunked":
+			// writing in pieces without a length forces a chunked response
+			for i := 0; i < len(data); i += 64 {
+				_, _ = w.Write(data[i:minInt(i+64, len(data))])
+				w.(http.Flusher).Flush()
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchPatch(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/two_files.patch")
+	if err != nil {
+		t.Fatalf("unexpected error reading patch: %v", err)
+	}
+	expected, expectedPreamble, err := ParseAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	srv := newPatchServer(t, data, true)
+
+	tests := map[string]struct {
+		Path     string
+		MaxBytes int64
+		Err      interface{}
+	}{
+		"success": {
+			Path: "/patch",
+		},
+		"exactlyMaxBytes": {
+			Path:     "/chunked",
+			MaxBytes: int64(len(data)),
+		},
+		"tooLarge": {
+			Path:     "/patch",
+			MaxBytes: 100,
+			Err:      ErrPatchTooLarge,
+		},
+		"tooLargeChunked": {
+			Path:     "/chunked",
+			MaxBytes: 100,
+			Err:      ErrPatchTooLarge,
+		},
+		"compressedTooLarge": {
+			Path:     "/gzip",
+			MaxBytes: int64(len(data)) - 1,
+			Err:      ErrPatchTooLarge,
+		},
+		"compressed": {
+			Path:     "/gzip",
+			MaxBytes: int64(len(data)),
+		},
+		"notFound": {
+			Path: "/missing",
+			Err:  "unexpected status",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, preamble, err := FetchPatch(context.Background(), srv.URL+test.Path, FetchOptions{
+				Client:   srv.Client(),
+				MaxBytes: test.MaxBytes,
+			})
+			if test.Err != nil {
+				assertError(t, test.Err, err, "fetching patch")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error fetching patch: %v", err)
+			}
+			if preamble != expectedPreamble {
+				t.Errorf("incorrect preamble\nexpected: %q\n  actual: %q", expectedPreamble, preamble)
+			}
+			if !reflect.DeepEqual(expected, files) {
+				t.Errorf("incorrect files\nexpected: %+v\n  actual: %+v", expected, files)
+			}
+		})
+	}
+}
+
+func TestFetchPatchCanceled(t *testing.T) {
+	srv := newPatchServer(t, nil, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := FetchPatch(ctx, srv.URL+"/patch", FetchOptions{Client: srv.Client()})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, but got %v", err)
+	}
+}
+
+func TestFetchIndexHTTPReaderAt(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/two_files.patch")
+	if err != nil {
+		t.Fatalf("unexpected error reading patch: %v", err)
+	}
+	files, _, err := ParseAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	for _, ranges := range []bool{true, false} {
+		name := "ranges"
+		if !ranges {
+			name = "noRanges"
+		}
+		t.Run(name, func(t *testing.T) {
+			srv := newPatchServer(t, data, ranges)
+			url := srv.URL + "/patch"
+
+			lazy, _, err := FetchIndex(context.Background(), url, FetchOptions{Client: srv.Client()})
+			if err != nil {
+				t.Fatalf("unexpected error indexing patch: %v", err)
+			}
+			if len(lazy) != len(files) {
+				t.Fatalf("incorrect number of files: expected %d, actual %d", len(files), len(lazy))
+			}
+
+			r := NewHTTPReaderAt(context.Background(), srv.Client(), url)
+			for i, lf := range lazy {
+				hunks := files[i].Hunks()
+				for j, h := range lf.Hunks {
+					loaded, err := h.Load(r)
+					if err != nil {
+						t.Fatalf("file %d, hunk %d: unexpected error loading hunk: %v", i, j, err)
+					}
+					if !reflect.DeepEqual(hunks[j], loaded) {
+						t.Errorf("file %d, hunk %d: incorrect hunk\nexpected: %+v\n  actual: %+v", i, j, hunks[j], loaded)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPReaderAt(t *testing.T) {
+	data := []byte("0123456789")
+
+	tests := map[string]struct {
+		Off    int64
+		Len    int
+		Output string
+		Err    error
+	}{
+		"start":   {Off: 0, Len: 4, Output: "0123"},
+		"middle":  {Off: 3, Len: 4, Output: "3456"},
+		"short":   {Off: 8, Len: 4, Output: "89", Err: io.EOF},
+		"pastEnd": {Off: 12, Len: 4, Output: "", Err: io.EOF},
+	}
+
+	for _, ranges := range []bool{true, false} {
+		srv := newPatchServer(t, data, ranges)
+		r := NewHTTPReaderAt(context.Background(), srv.Client(), srv.URL+"/patch")
+
+		for name, test := range tests {
+			p := make([]byte, test.Len)
+			n, err := r.ReadAt(p, test.Off)
+			if err != test.Err {
+				t.Errorf("%s (ranges=%t): expected error %v, but got %v", name, ranges, test.Err, err)
+			}
+			if out := string(p[:n]); out != test.Output {
+				t.Errorf("%s (ranges=%t): incorrect output: expected %q, actual %q", name, ranges, test.Output, out)
+			}
+		}
+	}
+}
+
+func TestHTTPReaderAtContentRange(t *testing.T) {
+	tests := map[string]struct {
+		ContentRange string
+		Output       string
+		Err          interface{}
+	}{
+		"match":      {ContentRange: "bytes 2-5/10", Output: "2345"},
+		"wrongStart": {ContentRange: "bytes 0-3/10", Err: "does not match the requested range"},
+		"tooLong":    {ContentRange: "bytes 2-9/10", Err: "does not match the requested range"},
+		"missing":    {Err: "does not match the requested range"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.ContentRange != "" {
+					w.Header().Set("Content-Range", test.ContentRange)
+				}
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write([]byte("2345"))
+			}))
+			defer srv.Close()
+
+			p := make([]byte, 4)
+			n, err := NewHTTPReaderAt(context.Background(), srv.Client(), srv.URL).ReadAt(p, 2)
+			if test.Err != nil {
+				assertError(t, test.Err, err, "reading range")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error reading range: %v", err)
+			}
+			if out := string(p[:n]); out != test.Output {
+				t.Errorf("incorrect output: expected %q, actual %q", test.Output, out)
+			}
+		})
+	}
+}
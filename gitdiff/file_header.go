@@ -20,6 +20,8 @@ func (p *parser) ParseNextFileHeader() (*File, string, error) {
 	var preamble strings.Builder
 	var file *File
 	for {
+		p.fileStart = p.offset
+
 		// check for disconnected fragment headers (corrupt patch)
 		frag, err := p.ParseTextFragmentHeader()
 		if err != nil {
@@ -39,6 +41,15 @@ func (p *parser) ParseNextFileHeader() (*File, string, error) {
 			return file, preamble.String(), nil
 		}
 
+		// check for a combined diff of a merge commit
+		file, err = p.ParseCombinedFileHeader()
+		if err != nil {
+			return nil, "", err
+		}
+		if file != nil {
+			return file, preamble.String(), nil
+		}
+
 		// check for a "traditional" patch
 		file, err = p.ParseTraditionalFileHeader()
 		if err != nil {
@@ -48,6 +59,25 @@ func (p *parser) ParseNextFileHeader() (*File, string, error) {
 			return file, preamble.String(), nil
 		}
 
+		// check for a binary file notice from a recursive diff
+		file, err = p.ParseBinaryNoticeHeader()
+		if err != nil {
+			return nil, "", err
+		}
+		if file != nil {
+			return file, preamble.String(), nil
+		}
+
+		// check for a file that only exists on one side of a recursive diff
+		file, err = p.ParseOnlyInHeader()
+		if err != nil {
+			return nil, "", err
+		}
+		if file != nil {
+			return file, preamble.String(), nil
+		}
+		p.setRootsFromCommand(p.Line(0))
+
 	NextLine:
 		preamble.WriteString(p.Line(0))
 		if err := p.Next(); err != nil {
@@ -74,11 +104,23 @@ func (p *parser) ParseGitFileHeader() (*File, error) {
 	}
 
 	f := &File{}
+	p.fragmentsExpected = false
 	for {
+		if p.Line(1) == "" {
+			// names are only present if fragments follow them
+			last := p.Line(0)
+			p.fragmentsExpected = strings.HasPrefix(last, "--- ") || strings.HasPrefix(last, "+++ ")
+		}
+
 		end, err := parseGitHeaderData(f, p.Line(1), defaultName)
 		if err != nil {
 			return nil, p.Errorf(1, "git file header: %v", err)
 		}
+		if n := len(f.ExtendedHeaders); n > 0 && p.rejectUnknownHeaders {
+			if fn, _ := lookupHeaderParser(f.ExtendedHeaders[n-1]); fn == nil {
+				return nil, p.Errorf(1, "git file header: unknown extended header: %q", f.ExtendedHeaders[n-1])
+			}
+		}
 
 		if err := p.Next(); err != nil {
 			if err == io.EOF {
@@ -143,12 +185,20 @@ func (p *parser) ParseTraditionalFileHeader() (*File, error) {
 		return nil, p.Errorf(1, "file header: %v", err)
 	}
 
+	p.setRoots(oldName, newName)
+	return traditionalFile(oldName, newName, hasEpochTimestamp(oldLine), hasEpochTimestamp(newLine)), nil
+}
+
+// traditionalFile returns a file for the names from a traditional file
+// header. If isNew or isDelete is true, or if a name is /dev/null, the file is
+// created or deleted.
+func traditionalFile(oldName, newName string, isNew, isDelete bool) *File {
 	f := &File{}
 	switch {
-	case oldName == devNull || hasEpochTimestamp(oldLine):
+	case oldName == devNull || isNew:
 		f.IsNew = true
 		f.NewName = newName
-	case newName == devNull || hasEpochTimestamp(newLine):
+	case newName == devNull || isDelete:
 		f.IsDelete = true
 		f.OldName = oldName
 	default:
@@ -162,7 +212,25 @@ func (p *parser) ParseTraditionalFileHeader() (*File, error) {
 			f.NewName = newName
 		}
 	}
+	return f
+}
 
+// ParseBinaryNoticeHeader parses a "Binary files x and y differ" line that is
+// not part of a Git file header, as printed by diff -r for binary files
+// instead of a traditional file header and fragments, or a line in one of the
+// other formats of the BinaryNotices option. The file has the names
+// from the notice, like a traditional file, and is marked as binary. If the
+// names have the "a/" and "b/" prefixes used by Git, the prefixes are removed.
+func (p *parser) ParseBinaryNoticeHeader() (*File, error) {
+	f := parseBinaryNotice(p.Line(0), p.binaryNotices)
+	if f == nil {
+		return nil, nil
+	}
+
+	if err := p.Next(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	p.fragmentsExpected = false
 	return f, nil
 }
 
@@ -294,11 +362,52 @@ func parseGitHeaderData(f *File, line, defaultName string) (end bool, err error)
 		}
 	}
 
+	// headers with registered parsers do not need to look like extended
+	// headers, but are kept like them so they can be written back out
+	if fn, n := lookupHeaderParser(line); fn != nil {
+		f.ExtendedHeaders = append(f.ExtendedHeaders, line)
+		return false, fn(f, line[n:])
+	}
+
+	// keep lines with other Git keywords, like the mode of a combined diff,
+	// so they can be written back out
+	if isExtendedHeader(line) {
+		f.ExtendedHeaders = append(f.ExtendedHeaders, line)
+		return false, nil
+	}
+
 	// unknown line indicates the end of the header
 	// this usually happens if the diff is empty
 	return true, nil
 }
 
+// isExtendedHeader returns true if line starts with one of the keywords of
+// the extended headers that Git writes in diffs. Like Git, lines with other
+// keywords end the header unless they match a registered header parser.
+func isExtendedHeader(line string) bool {
+	for _, keyword := range extendedHeaderKeywords {
+		if strings.HasPrefix(line, keyword+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+var extendedHeaderKeywords = []string{
+	"old mode",
+	"new mode",
+	"deleted file mode",
+	"new file mode",
+	"copy from",
+	"copy to",
+	"rename from",
+	"rename to",
+	"similarity index",
+	"dissimilarity index",
+	"index",
+	"mode",
+}
+
 func parseGitHeaderOldName(f *File, line, defaultName string) error {
 	name, _, err := parseName(line, '\t', 1)
 	if err != nil {
@@ -43,6 +43,23 @@ index 0000000..f5711e4
 				IsNew:        true,
 			},
 		},
+		"extendedHeaders": {
+			Input: `diff --git a/dir/file.txt b/dir/file.txt
+index 1c23fcc..40a1b33 100644
+mode 100644,100644..100644
+--- a/dir/file.txt
++++ b/dir/file.txt
+@@ -2,3 +4,5 @@
+`,
+			Output: &File{
+				OldName:         "dir/file.txt",
+				NewName:         "dir/file.txt",
+				OldMode:         os.FileMode(0100644),
+				OldOIDPrefix:    "1c23fcc",
+				NewOIDPrefix:    "40a1b33",
+				ExtendedHeaders: []string{"mode 100644,100644..100644"},
+			},
+		},
 		"newEmptyFile": {
 			Input: `diff --git a/empty.txt b/empty.txt
 new file mode 100644
@@ -274,6 +291,72 @@ context line
 	}
 }
 
+func TestParseBinaryNoticeHeader(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output *File
+	}{
+		"modified": {
+			Input: "Binary files old/image.png and new/image.png differ\n",
+			Output: &File{
+				OldName:  "new/image.png",
+				NewName:  "new/image.png",
+				IsBinary: true,
+			},
+		},
+		"newFile": {
+			Input: "Binary files /dev/null and b/image.png differ\n",
+			Output: &File{
+				NewName:  "image.png",
+				IsNew:    true,
+				IsBinary: true,
+			},
+		},
+		"deletedFile": {
+			Input: "Binary files a/image.png and /dev/null differ\n",
+			Output: &File{
+				OldName:  "image.png",
+				IsDelete: true,
+				IsBinary: true,
+			},
+		},
+		"namesWithSeparator": {
+			Input: "Binary files a/this and that.bin and b/this and that.bin differ\n",
+			Output: &File{
+				OldName:  "this and that.bin",
+				NewName:  "this and that.bin",
+				IsBinary: true,
+			},
+		},
+		"quotedNames": {
+			Input: "Binary files \"a/\\303\\251.bin\" and \"b/\\303\\251.bin\" differ\n",
+			Output: &File{
+				OldName:  "\u00e9.bin",
+				NewName:  "\u00e9.bin",
+				IsBinary: true,
+			},
+		},
+		"notNotice": {
+			Input:  "Binary files are great\n",
+			Output: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestParser(test.Input, true)
+
+			f, err := p.ParseBinaryNoticeHeader()
+			if err != nil {
+				t.Fatalf("unexpected error parsing binary notice: %v", err)
+			}
+			if !reflect.DeepEqual(test.Output, f) {
+				t.Errorf("incorrect file\nexpected: %+v\n  actual: %+v", test.Output, f)
+			}
+		})
+	}
+}
+
 func TestCleanName(t *testing.T) {
 	tests := map[string]struct {
 		Input  string
@@ -398,6 +481,23 @@ func TestParseGitHeaderData(t *testing.T) {
 			Line: "GIT binary file\n",
 			End:  true,
 		},
+		"unknownKeywordEndsParsing": {
+			Line: "x-review-status approved\n",
+			End:  true,
+		},
+		"nextFileEndsParsing": {
+			Line: "diff --git a/file.txt b/file.txt\n",
+			End:  true,
+		},
+		"extendedHeader": {
+			InputFile: &File{
+				ExtendedHeaders: []string{"x-tool value"},
+			},
+			Line: "mode 100644,100644..100644\n",
+			OutputFile: &File{
+				ExtendedHeaders: []string{"x-tool value", "mode 100644,100644..100644"},
+			},
+		},
 		"oldFileName": {
 			Line: "--- a/dir/file.txt\n",
 			OutputFile: &File{
@@ -0,0 +1,93 @@
+package gitdiff
+
+import (
+	"regexp"
+)
+
+// Filter transforms file content between the form stored in a repository and
+// the form used in a working tree, like the clean and smudge filters
+// configured with Git attributes. Clean converts working tree content into
+// the form that patches describe and Smudge converts it back. The name is the
+// slash-separated path of the file.
+type Filter interface {
+	Clean(name string, data []byte) ([]byte, error)
+	Smudge(name string, data []byte) ([]byte, error)
+}
+
+// FilterFuncs is an adapter to allow the use of ordinary functions as a
+// Filter. A nil function leaves content unchanged.
+type FilterFuncs struct {
+	CleanFunc  func(name string, data []byte) ([]byte, error)
+	SmudgeFunc func(name string, data []byte) ([]byte, error)
+}
+
+// Clean calls fn.CleanFunc(name, data).
+func (fn FilterFuncs) Clean(name string, data []byte) ([]byte, error) {
+	if fn.CleanFunc == nil {
+		return data, nil
+	}
+	return fn.CleanFunc(name, data)
+}
+
+// Smudge calls fn.SmudgeFunc(name, data).
+func (fn FilterFuncs) Smudge(name string, data []byte) ([]byte, error) {
+	if fn.SmudgeFunc == nil {
+		return data, nil
+	}
+	return fn.SmudgeFunc(name, data)
+}
+
+// IdentFilter expands $Id$ keywords like the ident attribute in Git. Clean
+// collapses "$Id: <anything>$" to "$Id$" and Smudge replaces "$Id$" with
+// "$Id: <oid> $", where <oid> is the hash of the cleaned content as a Git
+// blob.
+var IdentFilter Filter = identFilter{}
+
+var (
+	identRegexp        = regexp.MustCompile(`\$Id:[^$\n]*\$`)
+	identKeywordRegexp = regexp.MustCompile(`\$Id\$`)
+)
+
+type identFilter struct{}
+
+func (identFilter) Clean(name string, data []byte) ([]byte, error) {
+	return identRegexp.ReplaceAllLiteral(data, []byte("$Id$")), nil
+}
+
+func (identFilter) Smudge(name string, data []byte) ([]byte, error) {
+	oid := blobOID(data)
+	return identKeywordRegexp.ReplaceAllLiteral(data, []byte("$Id: "+oid+" $")), nil
+}
+
+// eolFilter converts line endings as requested by the text and eol
+// attributes.
+type eolFilter struct {
+	attrs AttributeSet
+}
+
+func (f eolFilter) Clean(name string, data []byte) ([]byte, error) {
+	if f.attrs.normalizesEOL(data) {
+		return toLF(data), nil
+	}
+	return data, nil
+}
+
+func (f eolFilter) Smudge(name string, data []byte) ([]byte, error) {
+	if eol, _ := f.attrs.Value("eol"); eol == "crlf" && f.attrs.normalizesEOL(data) {
+		return toCRLF(data), nil
+	}
+	return data, nil
+}
+
+// attributeFilters returns the filters selected by attrs.
+func attributeFilters(attrs AttributeSet) []Filter {
+	var filters []Filter
+	if attrs.IsSet("ident") {
+		filters = append(filters, IdentFilter)
+	}
+	// content is not known yet, so include the filter if it could convert
+	if attrs.normalizesEOL(nil) {
+		filters = append(filters, eolFilter{attrs})
+	}
+	return filters
+}
@@ -0,0 +1,124 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIdentFilter(t *testing.T) {
+	cleaned, err := IdentFilter.Clean("file.c", []byte("/* $Id: 1234 $ */\n/* $Id$ */\n"))
+	if err != nil {
+		t.Fatalf("unexpected error cleaning content: %v", err)
+	}
+	if expected := "/* $Id$ */\n/* $Id$ */\n"; string(cleaned) != expected {
+		t.Errorf("incorrect cleaned content: expected %q, actual %q", expected, cleaned)
+	}
+
+	smudged, err := IdentFilter.Smudge("file.c", []byte("$Id$\n"))
+	if err != nil {
+		t.Fatalf("unexpected error smudging content: %v", err)
+	}
+	if expected := "$Id: " + blobOID([]byte("$Id$\n")) + " $\n"; string(smudged) != expected {
+		t.Errorf("incorrect smudged content: expected %q, actual %q", expected, smudged)
+	}
+}
+
+func TestApplyFilters(t *testing.T) {
+	f := &File{
+		OldName: "file.c",
+		NewName: "file.c",
+		TextFragments: []*TextFragment{
+			{
+				OldPosition: 1, OldLines: 2,
+				NewPosition: 1, NewLines: 2,
+				LinesAdded: 1, LinesDeleted: 1,
+				LeadingContext: 1,
+				Lines: []Line{
+					{OpContext, "/* $Id$ */\n"},
+					{OpDelete, "int x = 1;\n"},
+					{OpAdd, "int x = 2;\n"},
+				},
+			},
+		},
+	}
+	src := "/* $Id: 0123456789 $ */\nint x = 1;\n"
+	result := "/* $Id$ */\nint x = 2;\n"
+	expanded := "/* $Id: " + blobOID([]byte(result)) + " $ */\nint x = 2;\n"
+
+	attrs, err := ParseAttributes(strings.NewReader("*.c ident\n"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing attributes: %v", err)
+	}
+
+	var order []string
+	tracing := func(name string) Filter {
+		return FilterFuncs{
+			CleanFunc: func(path string, data []byte) ([]byte, error) {
+				order = append(order, "clean "+name)
+				return data, nil
+			},
+			SmudgeFunc: func(path string, data []byte) ([]byte, error) {
+				order = append(order, "smudge "+name)
+				return data, nil
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		Options ApplyOptions
+		Output  string
+		Order   []string
+		Err     bool
+	}{
+		"noFilter": {
+			Err: true,
+		},
+		"identFilter": {
+			Options: ApplyOptions{Filters: []Filter{IdentFilter}},
+			Output:  expanded,
+		},
+		"identAttribute": {
+			Options: ApplyOptions{Attributes: attrs},
+			Output:  expanded,
+		},
+		"order": {
+			Options: ApplyOptions{Filters: []Filter{tracing("a"), IdentFilter, tracing("b")}},
+			Output:  expanded,
+			Order:   []string{"clean a", "clean b", "smudge b", "smudge a"},
+		},
+		"cleanError": {
+			Options: ApplyOptions{Filters: []Filter{FilterFuncs{
+				CleanFunc: func(name string, data []byte) ([]byte, error) {
+					return nil, errors.New("failed")
+				},
+			}}},
+			Err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			order = nil
+
+			var dst bytes.Buffer
+			err := NewApplierWithOptions(strings.NewReader(src), test.Options).ApplyFile(&dst, f)
+			if test.Err {
+				if err == nil {
+					t.Fatalf("expected error applying file, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error applying file: %v", err)
+			}
+			if dst.String() != test.Output {
+				t.Errorf("incorrect result\nexpected: %q\n  actual: %q", test.Output, dst.String())
+			}
+			if test.Order != nil && strings.Join(order, ",") != strings.Join(test.Order, ",") {
+				t.Errorf("incorrect filter order: expected %v, actual %v", test.Order, order)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package gitdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// GitHubFile is an entry in the "files" list returned by the GitHub APIs for
+// pull requests, commits, and comparisons. Only the fields needed to build a
+// File are included.
+type GitHubFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename,omitempty"`
+
+	// Status is one of "added", "removed", "modified", "renamed", "copied",
+	// "changed", or "unchanged".
+	Status string `json:"status"`
+
+	// SHA is the blob ID of the new content of the file.
+	SHA string `json:"sha,omitempty"`
+
+	// Patch contains the fragments of the file, without a file header. GitHub
+	// omits it for binary files and files with very large changes.
+	Patch     string `json:"patch,omitempty"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// GitLabDiff is an entry in the diffs returned by the GitLab APIs for merge
+// requests, commits, and comparisons. Only the fields needed to build a File
+// are included.
+type GitLabDiff struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	AMode       string `json:"a_mode"`
+	BMode       string `json:"b_mode"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+
+	// Diff contains the fragments of the file, without a file header. GitLab
+	// leaves it empty for files with very large changes, which are marked as
+	// too large or collapsed.
+	Diff      string `json:"diff"`
+	TooLarge  bool   `json:"too_large"`
+	Collapsed bool   `json:"collapsed"`
+}
+
+// File converts the entry to a File. It returns an error if the patch of the
+// entry is invalid. Files without a patch have no fragments. If the entry has
+// changes but no patch, as with binary files and very large changes, the
+// ContentOmitted field of the file is true, so applying it fails instead of
+// leaving the file unchanged. Deleted files are an exception, which apply
+// when the AllowContentOmitted option is set.
+func (gf GitHubFile) File() (*File, error) {
+	f := &File{OldName: gf.Filename, NewName: gf.Filename}
+
+	switch gf.Status {
+	case "added":
+		f.IsNew = true
+		f.OldName = ""
+	case "removed":
+		f.IsDelete = true
+		f.NewName = ""
+	case "renamed":
+		f.IsRename = true
+		f.OldName = gf.PreviousFilename
+	case "copied":
+		f.IsCopy = true
+		f.OldName = gf.PreviousFilename
+	case "modified", "changed", "unchanged", "":
+	default:
+		return nil, fmt.Errorf("gitdiff: %s: unknown status %q", gf.Filename, gf.Status)
+	}
+	if !f.IsDelete {
+		f.NewOIDPrefix = gf.SHA
+	}
+
+	if err := parseForgePatch(f, gf.Patch); err != nil {
+		return nil, err
+	}
+	if gf.Patch == "" {
+		switch {
+		case f.IsDelete:
+			f.ContentOmitted = gf.Deletions > 0
+		case gf.Additions > 0 || gf.Deletions > 0 || gf.Status == "modified":
+			f.ContentOmitted = true
+		case f.IsNew:
+			f.ContentOmitted = !isEmptyBlobOID(gf.SHA)
+		}
+	}
+	return f, nil
+}
+
+// File converts the entry to a File. It returns an error if the modes or the
+// patch of the entry are invalid. If the diff is empty because it is too
+// large or collapsed, the ContentOmitted field of the file is true, so
+// applying it fails instead of leaving the file unchanged.
+func (gd GitLabDiff) File() (*File, error) {
+	f := &File{
+		OldName:  gd.OldPath,
+		NewName:  gd.NewPath,
+		IsNew:    gd.NewFile,
+		IsDelete: gd.DeletedFile,
+		IsRename: gd.RenamedFile,
+	}
+	if f.IsNew {
+		f.OldName = ""
+	}
+	if f.IsDelete {
+		f.NewName = ""
+	}
+
+	oldMode, err := parseForgeMode(gd.AMode)
+	if err != nil {
+		return nil, fmt.Errorf("gitdiff: %s: %v", fileName(f), err)
+	}
+	newMode, err := parseForgeMode(gd.BMode)
+	if err != nil {
+		return nil, fmt.Errorf("gitdiff: %s: %v", fileName(f), err)
+	}
+	switch {
+	case f.IsNew:
+		f.NewMode = newMode
+	case f.IsDelete:
+		f.OldMode = oldMode
+	default:
+		f.OldMode = oldMode
+		if newMode != oldMode {
+			f.NewMode = newMode
+		}
+	}
+
+	if err := parseForgePatch(f, gd.Diff); err != nil {
+		return nil, err
+	}
+	if gd.Diff == "" && (gd.TooLarge || gd.Collapsed) {
+		f.ContentOmitted = true
+	}
+	return f, nil
+}
+
+// ParseGitHubFiles reads a GitHub API response containing changed files and
+// converts the files with GitHubFile.File. The response is either a list of
+// files or an object with a "files" field, like the responses for commits and
+// comparisons.
+func ParseGitHubFiles(r io.Reader) ([]*File, error) {
+	var entries []GitHubFile
+	if err := decodeForgeList(r, "files", &entries); err != nil {
+		return nil, err
+	}
+
+	files := make([]*File, 0, len(entries))
+	for _, e := range entries {
+		f, err := e.File()
+		if err != nil {
+			return files, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// ParseGitLabDiffs reads a GitLab API response containing diffs and converts
+// them with GitLabDiff.File. The response is either a list of diffs or an
+// object with a "diffs" field, like the responses for comparisons.
+func ParseGitLabDiffs(r io.Reader) ([]*File, error) {
+	var entries []GitLabDiff
+	if err := decodeForgeList(r, "diffs", &entries); err != nil {
+		return nil, err
+	}
+
+	files := make([]*File, 0, len(entries))
+	for _, e := range entries {
+		f, err := e.File()
+		if err != nil {
+			return files, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func decodeForgeList(r io.Reader, field string, v interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &obj); err != nil {
+			return fmt.Errorf("gitdiff: invalid API response: %v", err)
+		}
+		list, ok := obj[field]
+		if !ok {
+			return fmt.Errorf("gitdiff: invalid API response: missing %q field", field)
+		}
+		data = list
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("gitdiff: invalid API response: %v", err)
+	}
+	return nil
+}
+
+func parseForgeMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return parseMode(s)
+}
+
+// parseForgePatch parses the fragments of a file from an API response. These
+// patches have no file header and may not end with a newline.
+func parseForgePatch(f *File, patch string) error {
+	if patch == "" {
+		return nil
+	}
+	if !strings.HasSuffix(patch, "\n") {
+		patch += "\n"
+	}
+
+	firstLine := patch[:strings.IndexByte(patch, '\n')]
+	if parseBinaryNotice(firstLine, defaultBinaryNotices) != nil {
+		f.IsBinary = true
+		return nil
+	}
+
+	p := newParser(strings.NewReader(patch))
+	if err := p.Next(); err != nil && err != io.EOF {
+		return err
+	}
+	if err := p.ParseFragments(f); err != nil {
+		return err
+	}
+	if p.Line(0) != "" {
+		return p.Errorf(0, "%s: unexpected content after fragments", fileName(f))
+	}
+	return nil
+}
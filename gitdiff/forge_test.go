@@ -0,0 +1,258 @@
+package gitdiff
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGitHubFiles(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output []*File
+		Err    interface{}
+	}{
+		"list": {
+			Input: `[
+				{"filename": "dir/file.txt", "status": "modified", "sha": "abc123",
+				 "patch": "@@ -1,2 +1,2 @@\n line 1\n-line 2\n+line two"},
+				{"filename": "new.txt", "status": "added", "sha": "def456",
+				 "patch": "@@ -0,0 +1 @@\n+new\n\\ No newline at end of file"},
+				{"filename": "gone.txt", "status": "removed", "deletions": 3},
+				{"filename": "moved.txt", "previous_filename": "old.txt", "status": "renamed"},
+				{"filename": "image.png", "status": "modified"},
+				{"filename": "large.txt", "status": "added", "sha": "fed789", "additions": 20000},
+				{"filename": "empty.txt", "status": "added", "sha": "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"}
+			]`,
+			Output: []*File{
+				{
+					OldName:      "dir/file.txt",
+					NewName:      "dir/file.txt",
+					NewOIDPrefix: "abc123",
+					TextFragments: []*TextFragment{
+						{
+							OldPosition: 1, OldLines: 2, NewPosition: 1, NewLines: 2,
+							LinesAdded: 1, LinesDeleted: 1, LeadingContext: 1,
+							Lines: []Line{
+								{OpContext, "line 1\n"},
+								{OpDelete, "line 2\n"},
+								{OpAdd, "line two\n"},
+							},
+						},
+					},
+				},
+				{
+					NewName:      "new.txt",
+					IsNew:        true,
+					NewOIDPrefix: "def456",
+					TextFragments: []*TextFragment{
+						{
+							OldPosition: 0, OldLines: 0, NewPosition: 1, NewLines: 1,
+							LinesAdded: 1,
+							Lines: []Line{
+								{OpAdd, "new"},
+							},
+						},
+					},
+				},
+				{
+					OldName:        "gone.txt",
+					IsDelete:       true,
+					ContentOmitted: true,
+				},
+				{
+					OldName:  "old.txt",
+					NewName:  "moved.txt",
+					IsRename: true,
+				},
+				{
+					OldName:        "image.png",
+					NewName:        "image.png",
+					ContentOmitted: true,
+				},
+				{
+					NewName:        "large.txt",
+					IsNew:          true,
+					NewOIDPrefix:   "fed789",
+					ContentOmitted: true,
+				},
+				{
+					NewName:      "empty.txt",
+					IsNew:        true,
+					NewOIDPrefix: "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+				},
+			},
+		},
+		"object": {
+			Input: `{"sha": "0123abc", "files": [{"filename": "a.txt", "status": "modified", "patch": "@@ -1 +1 @@\n-a\n+b"}]}`,
+			Output: []*File{
+				{
+					OldName: "a.txt",
+					NewName: "a.txt",
+					TextFragments: []*TextFragment{
+						{
+							OldPosition: 1, OldLines: 1, NewPosition: 1, NewLines: 1,
+							LinesAdded: 1, LinesDeleted: 1,
+							Lines: []Line{{OpDelete, "a\n"}, {OpAdd, "b\n"}},
+						},
+					},
+				},
+			},
+		},
+		"missingField": {
+			Input: `{"sha": "0123abc"}`,
+			Err:   `missing "files" field`,
+		},
+		"invalidJSON": {
+			Input: `[{"filename": `,
+			Err:   "invalid API response",
+		},
+		"unknownStatus": {
+			Input: `[{"filename": "a.txt", "status": "exploded"}]`,
+			Err:   "unknown status",
+		},
+		"invalidPatch": {
+			Input: `[{"filename": "a.txt", "status": "modified", "patch": "@@ -1 +1 @@\n-a\n+b\ntrailing"}]`,
+			Err:   "unexpected content after fragments",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := ParseGitHubFiles(strings.NewReader(test.Input))
+			if test.Err != nil {
+				assertError(t, test.Err, err, "parsing GitHub files")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing GitHub files: %v", err)
+			}
+			if !reflect.DeepEqual(test.Output, files) {
+				t.Errorf("incorrect files\nexpected: %+v\n  actual: %+v", test.Output, files)
+			}
+		})
+	}
+}
+
+func TestApplyForgeContentOmitted(t *testing.T) {
+	files, err := ParseGitHubFiles(strings.NewReader(`[{"filename": "large.txt", "status": "modified", "additions": 20000}]`))
+	if err != nil {
+		t.Fatalf("unexpected error parsing GitHub files: %v", err)
+	}
+
+	src := strings.NewReader("old content\n")
+	err = NewApplierWithOptions(src, ApplyOptions{AllowContentOmitted: true}).ApplyFile(&bytes.Buffer{}, files[0])
+	assertError(t, "content omitted", err, "applying file without content")
+}
+
+func TestParseGitLabDiffs(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output []*File
+		Err    interface{}
+	}{
+		"list": {
+			Input: `[
+				{"old_path": "file.txt", "new_path": "file.txt", "a_mode": "100644", "b_mode": "100755",
+				 "diff": "@@ -1 +1 @@\n-old\n+new\n"},
+				{"old_path": "new.txt", "new_path": "new.txt", "a_mode": "0", "b_mode": "100644", "new_file": true,
+				 "diff": "@@ -0,0 +1 @@\n+content\n"},
+				{"old_path": "old.bin", "new_path": "old.bin", "a_mode": "100644", "b_mode": "0", "deleted_file": true,
+				 "diff": "Binary files a/old.bin and /dev/null differ\n"},
+				{"old_path": "a.txt", "new_path": "b.txt", "a_mode": "100644", "b_mode": "100644", "renamed_file": true,
+				 "diff": ""},
+				{"old_path": "large.txt", "new_path": "large.txt", "a_mode": "100644", "b_mode": "100644",
+				 "diff": "", "too_large": true},
+				{"old_path": "long.txt", "new_path": "long.txt", "a_mode": "100644", "b_mode": "100644",
+				 "diff": "", "collapsed": true}
+			]`,
+			Output: []*File{
+				{
+					OldName: "file.txt",
+					NewName: "file.txt",
+					OldMode: 0100644,
+					NewMode: 0100755,
+					TextFragments: []*TextFragment{
+						{
+							OldPosition: 1, OldLines: 1, NewPosition: 1, NewLines: 1,
+							LinesAdded: 1, LinesDeleted: 1,
+							Lines: []Line{
+								{OpDelete, "old\n"},
+								{OpAdd, "new\n"},
+							},
+						},
+					},
+				},
+				{
+					NewName: "new.txt",
+					IsNew:   true,
+					NewMode: 0100644,
+					TextFragments: []*TextFragment{
+						{
+							OldPosition: 0, OldLines: 0, NewPosition: 1, NewLines: 1,
+							LinesAdded: 1,
+							Lines: []Line{
+								{OpAdd, "content\n"},
+							},
+						},
+					},
+				},
+				{
+					OldName:  "old.bin",
+					IsDelete: true,
+					IsBinary: true,
+					OldMode:  0100644,
+				},
+				{
+					OldName:  "a.txt",
+					NewName:  "b.txt",
+					IsRename: true,
+					OldMode:  0100644,
+				},
+				{
+					OldName:        "large.txt",
+					NewName:        "large.txt",
+					OldMode:        0100644,
+					ContentOmitted: true,
+				},
+				{
+					OldName:        "long.txt",
+					NewName:        "long.txt",
+					OldMode:        0100644,
+					ContentOmitted: true,
+				},
+			},
+		},
+		"object": {
+			Input: `{"commit": {}, "diffs": [{"old_path": "a.txt", "new_path": "a.txt"}]}`,
+			Output: []*File{
+				{OldName: "a.txt", NewName: "a.txt"},
+			},
+		},
+		"invalidMode": {
+			Input: `[{"old_path": "a.txt", "new_path": "a.txt", "a_mode": "rwx"}]`,
+			Err:   "invalid mode",
+		},
+		"newFileWithOldContent": {
+			Input: `[{"old_path": "a.txt", "new_path": "a.txt", "new_file": true, "diff": "@@ -1 +1 @@\n-a\n+b\n"}]`,
+			Err:   "new file depends on old contents",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := ParseGitLabDiffs(strings.NewReader(test.Input))
+			if test.Err != nil {
+				assertError(t, test.Err, err, "parsing GitLab diffs")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing GitLab diffs: %v", err)
+			}
+			if !reflect.DeepEqual(test.Output, files) {
+				t.Errorf("incorrect files\nexpected: %+v\n  actual: %+v", test.Output, files)
+			}
+		})
+	}
+}
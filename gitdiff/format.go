@@ -0,0 +1,343 @@
+package gitdiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// formatter writes the parts of a patch in the format used by Git. Errors
+// from the underlying writer are recorded and stop all further output.
+type formatter struct {
+	w   io.Writer
+	err error
+}
+
+func newFormatter(w io.Writer) *formatter {
+	return &formatter{w: w}
+}
+
+func (fm *formatter) Write(p []byte) (int, error) {
+	if fm.err != nil {
+		return len(p), nil
+	}
+	if _, err := fm.w.Write(p); err != nil {
+		fm.err = err
+	}
+	return len(p), nil
+}
+
+func (fm *formatter) WriteString(s string) (int, error) {
+	return fm.Write([]byte(s))
+}
+
+func (fm *formatter) WriteByte(c byte) error {
+	_, _ = fm.Write([]byte{c})
+	return nil
+}
+
+func (fm *formatter) Printf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(fm, format, args...)
+}
+
+// WriteName writes a name with an optional prefix, quoting the result if it
+// contains special characters.
+func (fm *formatter) WriteName(prefix, name string) {
+	_, _ = fm.WriteString(quoteName(prefix + name))
+}
+
+func (fm *formatter) FormatFile(f *File) {
+	if f.ParentCount > 0 {
+		fm.FormatCombinedFile(f)
+		return
+	}
+
+	oldName, newName := f.OldName, f.NewName
+	if f.IsNew {
+		oldName = newName
+	}
+	if f.IsDelete {
+		newName = oldName
+	}
+
+	_, _ = fm.WriteString("diff --git ")
+	fm.WriteName("a/", oldName)
+	_ = fm.WriteByte(' ')
+	fm.WriteName("b/", newName)
+	_ = fm.WriteByte('\n')
+
+	switch {
+	case f.IsNew:
+		fm.Printf("new file mode %o\n", f.NewMode)
+	case f.IsDelete:
+		fm.Printf("deleted file mode %o\n", f.OldMode)
+	case f.NewMode != 0 && f.OldMode != f.NewMode:
+		if f.OldMode != 0 {
+			fm.Printf("old mode %o\n", f.OldMode)
+		}
+		fm.Printf("new mode %o\n", f.NewMode)
+	}
+
+	switch {
+	case f.IsRename:
+		fm.Printf("similarity index %d%%\n", f.Score)
+		_, _ = fm.WriteString("rename from ")
+		fm.WriteName("", f.OldName)
+		_, _ = fm.WriteString("\nrename to ")
+		fm.WriteName("", f.NewName)
+		_ = fm.WriteByte('\n')
+	case f.IsCopy:
+		fm.Printf("similarity index %d%%\n", f.Score)
+		_, _ = fm.WriteString("copy from ")
+		fm.WriteName("", f.OldName)
+		_, _ = fm.WriteString("\ncopy to ")
+		fm.WriteName("", f.NewName)
+		_ = fm.WriteByte('\n')
+	case f.Score > 0:
+		fm.Printf("dissimilarity index %d%%\n", f.Score)
+	}
+
+	if f.OldOIDPrefix != "" && f.NewOIDPrefix != "" {
+		fm.Printf("index %s..%s", f.OldOIDPrefix, f.NewOIDPrefix)
+		if !f.IsNew && !f.IsDelete && f.OldMode != 0 && (f.NewMode == 0 || f.NewMode == f.OldMode) {
+			fm.Printf(" %o", f.OldMode)
+		}
+		_ = fm.WriteByte('\n')
+	}
+
+	for _, hdr := range f.ExtendedHeaders {
+		_, _ = fm.WriteString(hdr)
+		_ = fm.WriteByte('\n')
+	}
+
+	if f.IsBinary {
+		if f.BinaryFragment == nil {
+			_, _ = fm.WriteString("Binary files ")
+			fm.writeFileName("a/", oldName, f.IsNew)
+			_, _ = fm.WriteString(" and ")
+			fm.writeFileName("b/", newName, f.IsDelete)
+			_, _ = fm.WriteString(" differ\n")
+			return
+		}
+		_, _ = fm.WriteString("GIT binary patch\n")
+		fm.FormatBinaryFragment(f.BinaryFragment)
+		if f.ReverseBinaryFragment != nil {
+			fm.FormatBinaryFragment(f.ReverseBinaryFragment)
+		}
+		return
+	}
+
+	if len(f.TextFragments) > 0 {
+		_, _ = fm.WriteString("--- ")
+		fm.writeFileName("a/", oldName, f.IsNew)
+		_, _ = fm.WriteString("\n+++ ")
+		fm.writeFileName("b/", newName, f.IsDelete)
+		_ = fm.WriteByte('\n')
+
+		for _, frag := range f.TextFragments {
+			fm.FormatTextFragment(frag)
+		}
+	}
+}
+
+// FormatCombinedFile writes a file from a combined diff. Only the object ID
+// and mode of the first parent are known, so the index and mode lines only
+// include values for that parent.
+func (fm *formatter) FormatCombinedFile(f *File) {
+	_, _ = fm.WriteString("diff --cc ")
+	fm.WriteName("", f.NewName)
+	_ = fm.WriteByte('\n')
+
+	switch {
+	case f.IsNew:
+		fm.Printf("new file mode %o\n", f.NewMode)
+	case f.IsDelete:
+		fm.Printf("deleted file mode %o\n", f.OldMode)
+	}
+	if f.OldOIDPrefix != "" && f.NewOIDPrefix != "" {
+		fm.Printf("index %s..%s\n", f.OldOIDPrefix, f.NewOIDPrefix)
+	}
+	if !f.IsNew && !f.IsDelete && f.OldMode != 0 && f.NewMode != 0 && f.OldMode != f.NewMode {
+		fm.Printf("mode %o..%o\n", f.OldMode, f.NewMode)
+	}
+
+	if len(f.CombinedFragments) > 0 {
+		_, _ = fm.WriteString("--- ")
+		fm.writeFileName("a/", f.OldName, f.IsNew)
+		_, _ = fm.WriteString("\n+++ ")
+		fm.writeFileName("b/", f.NewName, f.IsDelete)
+		_ = fm.WriteByte('\n')
+
+		for _, frags := range f.CombinedFragments {
+			fm.FormatCombinedFragment(frags)
+		}
+	}
+}
+
+// FormatCombinedFragment writes the fragments for each parent of a combined
+// fragment as a single fragment. Lines deleted from a parent are written
+// before the next line of the result, in the order of the parents.
+func (fm *formatter) FormatCombinedFragment(frags []*TextFragment) {
+	if len(frags) == 0 {
+		return
+	}
+
+	fm.writeFragmentHeader(frags)
+	_ = fm.WriteByte('\n')
+
+	ops := make([]byte, len(frags))
+	writeLine := func(line string) {
+		_, _ = fm.Write(ops)
+		_, _ = fm.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			_, _ = fm.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	next := make([]int, len(frags))
+	for {
+		for i, frag := range frags {
+			for next[i] < len(frag.Lines) && frag.Lines[next[i]].Op == OpDelete {
+				for j := range ops {
+					ops[j] = ' '
+				}
+				ops[i] = '-'
+				writeLine(frag.Lines[next[i]].Line)
+				next[i]++
+			}
+		}
+
+		var line string
+		for i, frag := range frags {
+			if next[i] >= len(frag.Lines) {
+				return
+			}
+			if frag.Lines[next[i]].Op == OpAdd {
+				ops[i] = '+'
+			} else {
+				ops[i] = ' '
+			}
+			line = frag.Lines[next[i]].Line
+			next[i]++
+		}
+		writeLine(line)
+	}
+}
+
+func (fm *formatter) writeFileName(prefix, name string, isNull bool) {
+	if isNull {
+		_, _ = fm.WriteString(devNull)
+		return
+	}
+	fm.WriteName(prefix, name)
+}
+
+func (fm *formatter) FormatTextFragment(f *TextFragment) {
+	fm.writeFragmentHeader([]*TextFragment{f})
+	_ = fm.WriteByte('\n')
+
+	for _, line := range f.Lines {
+		_, _ = fm.WriteString(line.Op.String())
+		_, _ = fm.WriteString(line.Line)
+		if line.NoEOL() {
+			_, _ = fm.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// writeFragmentHeader writes the header line, without a newline, of a
+// fragment with one fragment for each parent. If there is more than one
+// parent, it writes a combined fragment header.
+func (fm *formatter) writeFragmentHeader(frags []*TextFragment) {
+	marks := strings.Repeat("@", len(frags)+1)
+	_, _ = fm.WriteString(marks)
+	for _, frag := range frags {
+		_, _ = fm.WriteString(" -")
+		fm.writeRange(frag.OldPosition, frag.OldLines)
+	}
+	_, _ = fm.WriteString(" +")
+	fm.writeRange(frags[0].NewPosition, frags[0].NewLines)
+	_ = fm.WriteByte(' ')
+	_, _ = fm.WriteString(marks)
+	if frags[0].Comment != "" {
+		_ = fm.WriteByte(' ')
+		_, _ = fm.WriteString(frags[0].Comment)
+	}
+}
+
+func (fm *formatter) writeRange(start, lines int64) {
+	if lines == 1 {
+		fm.Printf("%d", start)
+	} else {
+		fm.Printf("%d,%d", start, lines)
+	}
+}
+
+func (fm *formatter) FormatBinaryFragment(f *BinaryFragment) {
+	const maxBytesPerLine = 52
+
+	switch f.Method {
+	case BinaryPatchDelta:
+		_, _ = fm.WriteString("delta ")
+	case BinaryPatchLiteral:
+		_, _ = fm.WriteString("literal ")
+	}
+	fm.Printf("%d\n", f.Size)
+
+	var data bytes.Buffer
+	zw := zlib.NewWriter(&data)
+	_, _ = zw.Write(f.Data)
+	_ = zw.Close()
+
+	buf := make([]byte, base85Len(maxBytesPerLine))
+	for b := data.Bytes(); len(b) > 0; {
+		n := minInt(len(b), maxBytesPerLine)
+		if n <= 26 {
+			_ = fm.WriteByte(byte('A' + n - 1))
+		} else {
+			_ = fm.WriteByte(byte('a' + n - 27))
+		}
+		base85Encode(buf, b[:n])
+		_, _ = fm.Write(buf[:base85Len(n)])
+		_ = fm.WriteByte('\n')
+		b = b[n:]
+	}
+	_ = fm.WriteByte('\n')
+}
+
+// String returns a git diff representation of the file. The result can be
+// parsed to recover an equivalent File, although the patch header, if any, is
+// not included. Binary data is compressed again, so it may not match the
+// bytes in the original patch.
+func (f *File) String() string {
+	var b strings.Builder
+	newFormatter(&b).FormatFile(f)
+	return b.String()
+}
+
+// String returns a git diff representation of the fragment, starting with its
+// header.
+func (f *TextFragment) String() string {
+	var b strings.Builder
+	newFormatter(&b).FormatTextFragment(f)
+	return b.String()
+}
+
+// String returns a git diff representation of the binary fragment, starting
+// with its method and size.
+func (f *BinaryFragment) String() string {
+	var b strings.Builder
+	newFormatter(&b).FormatBinaryFragment(f)
+	return b.String()
+}
+
+// FormatFiles writes the git diff representation of each file in files to w.
+func FormatFiles(w io.Writer, files []*File) error {
+	fm := newFormatter(w)
+	for _, f := range files {
+		fm.FormatFile(f)
+	}
+	return fm.err
+}
@@ -0,0 +1,206 @@
+package gitdiff
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFormatFile(t *testing.T) {
+	combined := []*TextFragment{
+		{
+			Comment:     "comment",
+			OldPosition: 1, OldLines: 3,
+			NewPosition: 1, NewLines: 3,
+			Lines: []Line{
+				{OpContext, "line 1\n"},
+				{OpDelete, "parent one\n"},
+				{OpAdd, "merged\n"},
+				{OpContext, "line 3\n"},
+			},
+		},
+		{
+			Comment:     "comment",
+			OldPosition: 1, OldLines: 3,
+			NewPosition: 1, NewLines: 3,
+			Lines: []Line{
+				{OpContext, "line 1\n"},
+				{OpDelete, "parent two\n"},
+				{OpAdd, "merged\n"},
+				{OpContext, "line 3\n"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		Input  *File
+		Output string
+	}{
+		"modify": {
+			Input: &File{
+				OldName:      "file.txt",
+				NewName:      "file.txt",
+				OldMode:      os.FileMode(0100644),
+				OldOIDPrefix: "ebe9fa5",
+				NewOIDPrefix: "fe103e1",
+				TextFragments: []*TextFragment{
+					{
+						Comment:     "func()",
+						OldPosition: 1, OldLines: 2,
+						NewPosition: 1, NewLines: 1,
+						Lines: []Line{
+							{OpContext, "line 1\n"},
+							{OpDelete, "line 2\n"},
+						},
+					},
+				},
+			},
+			Output: "diff --git a/file.txt b/file.txt\n" +
+				"index ebe9fa5..fe103e1 100644\n" +
+				"--- a/file.txt\n" +
+				"+++ b/file.txt\n" +
+				"@@ -1,2 +1 @@ func()\n" +
+				" line 1\n" +
+				"-line 2\n",
+		},
+		"create": {
+			Input: &File{
+				NewName:      "dir/new file.txt",
+				IsNew:        true,
+				NewMode:      os.FileMode(0100644),
+				OldOIDPrefix: "0000000",
+				NewOIDPrefix: "3333333",
+				TextFragments: []*TextFragment{
+					{
+						NewPosition: 1, NewLines: 1,
+						Lines: []Line{{OpAdd, "no newline"}},
+					},
+				},
+			},
+			Output: "diff --git a/dir/new file.txt b/dir/new file.txt\n" +
+				"new file mode 100644\n" +
+				"index 0000000..3333333\n" +
+				"--- /dev/null\n" +
+				"+++ b/dir/new file.txt\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+no newline\n" +
+				"\\ No newline at end of file\n",
+		},
+		"renameModeChange": {
+			Input: &File{
+				OldName:  "old\tname.sh",
+				NewName:  "new.sh",
+				IsRename: true,
+				Score:    100,
+				OldMode:  os.FileMode(0100644),
+				NewMode:  os.FileMode(0100755),
+			},
+			Output: "diff --git \"a/old\\tname.sh\" b/new.sh\n" +
+				"old mode 100644\n" +
+				"new mode 100755\n" +
+				"similarity index 100%\n" +
+				"rename from \"old\\tname.sh\"\n" +
+				"rename to new.sh\n",
+		},
+		"extendedHeaders": {
+			Input: &File{
+				OldName:         "file.txt",
+				NewName:         "file.txt",
+				OldMode:         os.FileMode(0100644),
+				NewMode:         os.FileMode(0100755),
+				ExtendedHeaders: []string{"x-review-status approved"},
+			},
+			Output: "diff --git a/file.txt b/file.txt\n" +
+				"old mode 100644\n" +
+				"new mode 100755\n" +
+				"x-review-status approved\n",
+		},
+		"binaryNoData": {
+			Input: &File{
+				OldName:      "image.png",
+				IsDelete:     true,
+				OldMode:      os.FileMode(0100644),
+				OldOIDPrefix: "4444444",
+				NewOIDPrefix: "0000000",
+				IsBinary:     true,
+			},
+			Output: "diff --git a/image.png b/image.png\n" +
+				"deleted file mode 100644\n" +
+				"index 4444444..0000000\n" +
+				"Binary files a/image.png and /dev/null differ\n",
+		},
+		"combined": {
+			Input: &File{
+				OldName:           "file.txt",
+				NewName:           "file.txt",
+				OldOIDPrefix:      "1111111",
+				NewOIDPrefix:      "3333333",
+				TextFragments:     combined,
+				ParentCount:       2,
+				CombinedFragments: [][]*TextFragment{combined},
+			},
+			Output: "diff --cc file.txt\n" +
+				"index 1111111..3333333\n" +
+				"--- a/file.txt\n" +
+				"+++ b/file.txt\n" +
+				"@@@ -1,3 -1,3 +1,3 @@@ comment\n" +
+				"  line 1\n" +
+				"- parent one\n" +
+				" -parent two\n" +
+				"++merged\n" +
+				"  line 3\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if out := test.Input.String(); out != test.Output {
+				t.Errorf("incorrect output\nexpected: %q\n  actual: %q", test.Output, out)
+			}
+		})
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	patches, err := filepath.Glob("testdata/*.patch")
+	if err != nil {
+		t.Fatalf("unexpected error listing patches: %v", err)
+	}
+
+	for _, patch := range patches {
+		t.Run(filepath.Base(patch), func(t *testing.T) {
+			f, err := os.Open(patch)
+			if err != nil {
+				t.Fatalf("unexpected error opening patch: %v", err)
+			}
+			defer f.Close()
+
+			files, _, err := newParser(f).ParseFiles()
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			var b bytes.Buffer
+			if err := FormatFiles(&b, files); err != nil {
+				t.Fatalf("unexpected error formatting files: %v", err)
+			}
+
+			reparsed, _, err := newParser(&b).ParseFiles()
+			if err != nil {
+				t.Fatalf("unexpected error parsing formatted patch: %v\n%s", err, b.String())
+			}
+			if len(reparsed) != len(files) {
+				t.Fatalf("incorrect number of files: expected %d, actual %d", len(files), len(reparsed))
+			}
+			for i := range files {
+				expected := *files[i]
+				expected.PatchHeader, expected.Raw = nil, nil
+				if !reflect.DeepEqual(&expected, reparsed[i]) {
+					t.Errorf("file %d does not round trip\nexpected: %+v\n  actual: %+v", i, &expected, reparsed[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package gitdiff
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FuncNamePattern finds the lines used as the comments of fragment headers,
+// like the xfuncname setting of a diff driver in Git configuration.
+type FuncNamePattern struct {
+	rules []funcNameRule
+}
+
+type funcNameRule struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// CompileFuncName parses a pattern in the format of the xfuncname setting in
+// Git configuration. The pattern is a list of regular expressions separated
+// by newlines. Each line is checked against the expressions in order and the
+// first expression that matches decides the result. If the expression starts
+// with "!", the line is not a function name. Otherwise, the function name is
+// the text of the first capturing group of the expression or, if the group
+// did not match, the whole match.
+func CompileFuncName(pattern string) (*FuncNamePattern, error) {
+	var p FuncNamePattern
+	for _, expr := range strings.Split(pattern, "\n") {
+		var rule funcNameRule
+		if strings.HasPrefix(expr, "!") {
+			rule.negate = true
+			expr = expr[1:]
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("gitdiff: invalid function name pattern: %v", err)
+		}
+		rule.re = re
+		p.rules = append(p.rules, rule)
+	}
+	return &p, nil
+}
+
+// MustCompileFuncName is like CompileFuncName but panics if the pattern is
+// invalid.
+func MustCompileFuncName(pattern string) *FuncNamePattern {
+	p, err := CompileFuncName(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Match returns the function name in line and true if the line is a function
+// name. Trailing whitespace is removed from the result.
+func (p *FuncNamePattern) Match(line string) (string, bool) {
+	for _, rule := range p.rules {
+		m := rule.re.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		if rule.negate {
+			return "", false
+		}
+		name := line[m[0]:m[1]]
+		if len(m) > 2 && m[2] >= 0 {
+			name = line[m[2]:m[3]]
+		}
+		return strings.TrimRight(name, " \t"), true
+	}
+	return "", false
+}
+
+// defaultFuncName matches the same lines as the default function name
+// pattern of Git, which is used for files without a diff driver.
+var defaultFuncName = regexp.MustCompile(`^[[:alpha:]_$].*`)
+
+// RegenerateComments sets the comment of each text fragment of f to the
+// function name in the closest line before the fragment in src, the content
+// of the file before the patch. Like Git, it scans backwards from the line
+// before the fragment and uses the first line that matches funcRE, or the
+// text of the first capturing group of funcRE if it has one. If funcRE is
+// nil, it uses the default pattern of Git, which matches lines that start
+// with a letter, an underscore, or a dollar sign. If no line matches, the
+// comment is cleared.
+//
+// Use RegenerateComments to correct the comments of fragments after changing
+// their positions, such as when transforming a patch.
+func RegenerateComments(f *File, src LineReaderAt, funcRE *regexp.Regexp) error {
+	if funcRE == nil {
+		funcRE = defaultFuncName
+	}
+
+	var end int64
+	for _, frag := range f.TextFragments {
+		if frag.OldPosition > end {
+			end = frag.OldPosition
+		}
+	}
+
+	lines := make([][]byte, end)
+	n, err := src.ReadLinesAt(lines, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	old := make([]string, n)
+	for i, line := range lines[:n] {
+		old[i] = string(line)
+	}
+	for _, frag := range f.TextFragments {
+		frag.Comment = ""
+	}
+	setFuncNames(f.TextFragments, old, funcNameFromRegexp(funcRE))
+	return nil
+}
+
+func funcNameFromRegexp(re *regexp.Regexp) *FuncNamePattern {
+	return &FuncNamePattern{rules: []funcNameRule{{re: re}}}
+}
+
+// Language describes how to find function names in files written in a
+// programming or markup language.
+type Language struct {
+	// Name is the name of the language. Files select a language by setting
+	// the diff attribute to this name, like the built-in diff drivers of Git.
+	Name string
+
+	// Extensions lists the file name extensions, including the leading dot,
+	// of files written in the language.
+	Extensions []string
+
+	// FuncName finds the function names used as fragment comments.
+	FuncName *FuncNamePattern
+
+	// Symbols, if set, finds the top-level declarations in a file. It is
+	// used by ChangedSymbols.
+	Symbols SymbolExtractor
+}
+
+var languages = struct {
+	sync.RWMutex
+	byName map[string]Language
+}{byName: make(map[string]Language)}
+
+// RegisterLanguage adds a language to the registry used when generating and
+// classifying fragment comments, replacing any language with the same name.
+// The registry includes the built-in languages of Git with the same names,
+// such as "cpp", "golang", "java", "markdown", and "python".
+func RegisterLanguage(lang Language) {
+	languages.Lock()
+	defer languages.Unlock()
+	languages.byName[lang.Name] = lang
+}
+
+// LookupLanguage returns the registered language with a name.
+func LookupLanguage(name string) (Language, bool) {
+	languages.RLock()
+	defer languages.RUnlock()
+	lang, ok := languages.byName[name]
+	return lang, ok
+}
+
+// LanguageForPath returns the registered language for a file based on the
+// extension of its name. If more than one language uses the extension, the
+// language with the first name in lexical order is returned.
+func LanguageForPath(name string) (Language, bool) {
+	ext := path.Ext(name)
+	if ext == "" {
+		return Language{}, false
+	}
+
+	languages.RLock()
+	defer languages.RUnlock()
+
+	names := make([]string, 0, len(languages.byName))
+	for name := range languages.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		lang := languages.byName[name]
+		for _, e := range lang.Extensions {
+			if strings.EqualFold(e, ext) {
+				return lang, true
+			}
+		}
+	}
+	return Language{}, false
+}
+
+// ClassifyComment returns the name of the language of the file at name and
+// the function name in comment, a comment from the header of a fragment in
+// the file. It returns false if no registered language uses the extension of
+// the file or if the comment is not a function name in the language.
+func ClassifyComment(name, comment string) (string, string, bool) {
+	lang, ok := LanguageForPath(name)
+	if !ok || lang.FuncName == nil {
+		return "", "", false
+	}
+	funcName, ok := lang.FuncName.Match(comment)
+	if !ok {
+		return "", "", false
+	}
+	return lang.Name, funcName, true
+}
+
+func init() {
+	// patterns are adapted from the built-in diff drivers in Git's userdiff.c
+	builtin := []struct {
+		name       string
+		extensions []string
+		pattern    string
+	}{
+		{
+			"bash",
+			[]string{".sh", ".bash"},
+			`^[ \t]*((([a-zA-Z_][a-zA-Z0-9_]*[ \t]*\([ \t]*\))|(function[ \t]+[a-zA-Z_][a-zA-Z0-9_]*(([ \t]*\([ \t]*\))|([ \t]+))))[ \t]*(\{|\(\(?|\[\[)).*$`,
+		},
+		{
+			"cpp",
+			[]string{".c", ".h", ".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx"},
+			"!^[ \t]*[A-Za-z_][A-Za-z_0-9]*:[[:space:]]*($|/[/*])\n" +
+				`^((::[[:space:]]*)?[A-Za-z_].*)$`,
+		},
+		{
+			"csharp",
+			[]string{".cs"},
+			"!^[ \t]*(do|while|for|if|else|instanceof|new|return|switch|case|throw|catch|using)\n" +
+				`^[ \t]*(((static|public|internal|private|protected|new|virtual|sealed|override|unsafe|async)[ \t]+)*[][<>@.~_[:alnum:]]+[ \t]+[<>@._[:alnum:]]+[ \t]*\(.*\))[ \t]*$` + "\n" +
+				`^[ \t]*(((static|public|internal|private|protected|new|virtual|sealed|override|unsafe)[ \t]+)*[][<>@.~_[:alnum:]]+[ \t]+[@._[:alnum:]]+)[ \t]*$` + "\n" +
+				`^[ \t]*(((static|public|internal|private|protected|new|unsafe|sealed|abstract|partial)[ \t]+)*(class|enum|interface|struct|record)[ \t]+.*)$` + "\n" +
+				`^[ \t]*(namespace[ \t]+.*)$`,
+		},
+		{
+			"css",
+			[]string{".css"},
+			"![:;][[:space:]]*$\n" +
+				`(?i)^[:\[@.#]?[_a-z0-9].*$`,
+		},
+		{
+			"golang",
+			[]string{".go"},
+			`^[ \t]*(func[ \t]*.*(\{[ \t]*)?)` + "\n" +
+				`^[ \t]*(type[ \t].*(struct|interface)[ \t]*(\{[ \t]*)?)`,
+		},
+		{
+			"html",
+			[]string{".html", ".htm"},
+			`^[ \t]*(<[Hh][1-6]([ \t].*)?>.*)$`,
+		},
+		{
+			"java",
+			[]string{".java"},
+			"!^[ \t]*(catch|do|for|if|instanceof|new|return|switch|throw|while)\n" +
+				`^[ \t]*(([a-z-]+[ \t]+)*(class|enum|interface|record)[ \t]+.*)$` + "\n" +
+				`^[ \t]*(([A-Za-z_<>&][][?&<>.,A-Za-z_0-9]*[ \t]+)+[A-Za-z_][A-Za-z_0-9]*[ \t]*\([^;]*)$`,
+		},
+		{
+			"markdown",
+			[]string{".md", ".markdown"},
+			`^ {0,3}#{1,6}[ \t].*`,
+		},
+		{
+			"php",
+			[]string{".php"},
+			`^[\t ]*(((public|protected|private|static|abstract|final)[\t ]+)*function.*)$` + "\n" +
+				`^[\t ]*((((final|abstract)[\t ]+)?class|enum|interface|trait).*)$`,
+		},
+		{
+			"python",
+			[]string{".py"},
+			`^[ \t]*((class|(async[ \t]+)?def)[ \t].*)$`,
+		},
+		{
+			"ruby",
+			[]string{".rb"},
+			`^[ \t]*((class|module|def)[ \t].*)$`,
+		},
+		{
+			"rust",
+			[]string{".rs"},
+			`^[\t ]*((pub(\([^\)]+\))?[\t ]+)?((async|const|unsafe|extern([\t ]+"[^"]+"))[\t ]+)?(struct|enum|union|mod|trait|fn|impl|macro_rules!)[< \t]+[^;]*)$`,
+		},
+		{
+			"tex",
+			[]string{".tex"},
+			`^(\\((sub)*section|chapter|part)\*{0,1}\{.*)$`,
+		},
+	}
+
+	for _, b := range builtin {
+		lang := Language{
+			Name:       b.name,
+			Extensions: b.extensions,
+			FuncName:   MustCompileFuncName(b.pattern),
+		}
+		if b.name == "golang" {
+			lang.Symbols = GoSymbols
+		}
+		RegisterLanguage(lang)
+	}
+}
@@ -0,0 +1,278 @@
+package gitdiff
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLanguageFuncName(t *testing.T) {
+	tests := map[string]struct {
+		Language string
+		Line     string
+		FuncName string
+		NoMatch  bool
+	}{
+		"cppFunction": {
+			Language: "cpp",
+			Line:     "static int parse(const char *s)",
+			FuncName: "static int parse(const char *s)",
+		},
+		"cppLabel": {
+			Language: "cpp",
+			Line:     "public:",
+			NoMatch:  true,
+		},
+		"cppIndented": {
+			Language: "cpp",
+			Line:     "\treturn 0;",
+			NoMatch:  true,
+		},
+		"golangFunc": {
+			Language: "golang",
+			Line:     "func (p *parser) Next() error {",
+			FuncName: "func (p *parser) Next() error {",
+		},
+		"golangType": {
+			Language: "golang",
+			Line:     "type File struct {",
+			FuncName: "type File struct {",
+		},
+		"golangStatement": {
+			Language: "golang",
+			Line:     "\tif err != nil {",
+			NoMatch:  true,
+		},
+		"pythonDef": {
+			Language: "python",
+			Line:     "    async def fetch(self, url):",
+			FuncName: "async def fetch(self, url):",
+		},
+		"javaMethod": {
+			Language: "java",
+			Line:     "    public static void main(String[] args) {",
+			FuncName: "public static void main(String[] args) {",
+		},
+		"javaKeyword": {
+			Language: "java",
+			Line:     "    return value(x);",
+			NoMatch:  true,
+		},
+		"markdownHeading": {
+			Language: "markdown",
+			Line:     "## Development Status",
+			FuncName: "## Development Status",
+		},
+		"markdownText": {
+			Language: "markdown",
+			Line:     "Mostly complete.",
+			NoMatch:  true,
+		},
+		"rustFn": {
+			Language: "rust",
+			Line:     "pub(crate) async fn run() -> Result<()> {",
+			FuncName: "pub(crate) async fn run() -> Result<()> {",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lang, ok := LookupLanguage(test.Language)
+			if !ok {
+				t.Fatalf("language %q is not registered", test.Language)
+			}
+
+			funcName, ok := lang.FuncName.Match(test.Line)
+			if test.NoMatch {
+				if ok {
+					t.Fatalf("expected no match, but got %q", funcName)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected match, but line did not match")
+			}
+			if funcName != test.FuncName {
+				t.Errorf("incorrect function name: expected %q, actual %q", test.FuncName, funcName)
+			}
+		})
+	}
+}
+
+func TestCompileFuncName(t *testing.T) {
+	p, err := CompileFuncName("!^skip\n^def (\\w+)\n^(#)?section.*")
+	if err != nil {
+		t.Fatalf("unexpected error compiling pattern: %v", err)
+	}
+
+	tests := map[string]struct {
+		FuncName string
+		OK       bool
+	}{
+		"def parse(x)":  {"parse", true},
+		"skip def x":    {"", false},
+		"section one  ": {"section one", true},
+		"#section two":  {"#", true},
+		"other":         {"", false},
+	}
+	for line, test := range tests {
+		funcName, ok := p.Match(line)
+		if ok != test.OK || funcName != test.FuncName {
+			t.Errorf("incorrect match for %q: expected (%q, %t), actual (%q, %t)", line, test.FuncName, test.OK, funcName, ok)
+		}
+	}
+
+	if _, err := CompileFuncName("^(unclosed"); err == nil {
+		t.Errorf("expected error compiling invalid pattern, but got nil")
+	}
+}
+
+func TestRegenerateComments(t *testing.T) {
+	src := strings.Join([]string{
+		"package main",
+		"",
+		"func one() {",
+		"\tx := 1",
+		"\ty := 2",
+		"}",
+		"",
+		"func two() {",
+		"\tz := 3",
+		"}",
+	}, "\n") + "\n"
+
+	tests := map[string]struct {
+		FuncName  *regexp.Regexp
+		Positions []int64
+		Comments  []string
+	}{
+		"default": {
+			Positions: []int64{1, 4, 9},
+			Comments:  []string{"", "func one() {", "func two() {"},
+		},
+		"group": {
+			FuncName:  regexp.MustCompile(`^func (\w+)`),
+			Positions: []int64{4, 8, 9},
+			Comments:  []string{"one", "one", "two"},
+		},
+		"noMatch": {
+			FuncName:  regexp.MustCompile(`^type `),
+			Positions: []int64{9},
+			Comments:  []string{""},
+		},
+		"pastEnd": {
+			FuncName:  regexp.MustCompile(`^func (\w+)`),
+			Positions: []int64{20},
+			Comments:  []string{"two"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := &File{}
+			for _, pos := range test.Positions {
+				f.TextFragments = append(f.TextFragments, &TextFragment{
+					Comment:     "stale",
+					OldPosition: pos,
+					OldLines:    1,
+				})
+			}
+
+			if err := RegenerateComments(f, &lineReaderAt{r: strings.NewReader(src)}, test.FuncName); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, frag := range f.TextFragments {
+				if frag.Comment != test.Comments[i] {
+					t.Errorf("incorrect comment for fragment %d: expected %q, actual %q", i, test.Comments[i], frag.Comment)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyComment(t *testing.T) {
+	tests := map[string]struct {
+		Name     string
+		Comment  string
+		Language string
+		FuncName string
+		OK       bool
+	}{
+		"golang": {
+			Name:     "gitdiff/parser.go",
+			Comment:  "func (p *parser) Next() error {",
+			Language: "golang",
+			FuncName: "func (p *parser) Next() error {",
+			OK:       true,
+		},
+		"upperCaseExtension": {
+			Name:     "README.MD",
+			Comment:  "# Title",
+			Language: "markdown",
+			FuncName: "# Title",
+			OK:       true,
+		},
+		"notFuncName": {
+			Name:    "main.py",
+			Comment: "import os",
+		},
+		"unknownExtension": {
+			Name:    "data.unknown",
+			Comment: "func main() {",
+		},
+		"noExtension": {
+			Name:    "Makefile",
+			Comment: "all: build",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lang, funcName, ok := ClassifyComment(test.Name, test.Comment)
+			if ok != test.OK || lang != test.Language || funcName != test.FuncName {
+				t.Errorf("incorrect result: expected (%q, %q, %t), actual (%q, %q, %t)",
+					test.Language, test.FuncName, test.OK, lang, funcName, ok)
+			}
+		})
+	}
+}
+
+func TestRegisterLanguage(t *testing.T) {
+	RegisterLanguage(Language{
+		Name:       "testlang",
+		Extensions: []string{".tl"},
+		FuncName:   MustCompileFuncName(`^proc ([a-z]+)`),
+	})
+
+	if lang, funcName, ok := ClassifyComment("x.tl", "proc hello()"); !ok || lang != "testlang" || funcName != "hello" {
+		t.Errorf("incorrect classification: (%q, %q, %t)", lang, funcName, ok)
+	}
+
+	attrs, err := ParseAttributes(strings.NewReader("*.tl diff=testlang\n"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing attributes: %v", err)
+	}
+	oldFS := fstest.MapFS{
+		"x.tl":   {Data: []byte("proc hello()\n  a\n  b\n  c\n  d\n")},
+		"bin.tl": {Data: []byte("proc \x00\n")},
+	}
+	newFS := fstest.MapFS{
+		"x.tl":   {Data: []byte("proc hello()\n  a\n  b\n  c\n  e\n")},
+		"bin.tl": {Data: []byte("proc \x01\x00\n")},
+	}
+
+	files, err := DiffTrees(oldFS, newFS, DiffOptions{Context: 1, Attributes: attrs})
+	if err != nil {
+		t.Fatalf("unexpected error diffing trees: %v", err)
+	}
+	if len(files) != 2 || len(files[1].TextFragments) != 1 {
+		t.Fatalf("incorrect diff: %+v", files)
+	}
+	if !files[0].IsBinary {
+		t.Errorf("binary file with a language was compared as text: %+v", files[0])
+	}
+	if comment := files[1].TextFragments[0].Comment; comment != "hello" {
+		t.Errorf("incorrect comment: expected %q, actual %q", "hello", comment)
+	}
+}
@@ -0,0 +1,76 @@
+package gitdiff
+
+import (
+	"sort"
+	"strings"
+)
+
+// FunctionStat describes the changes a patch makes in the fragments of a file
+// that share a function context.
+type FunctionStat struct {
+	// File is the new name of the file, or the old name if it is deleted.
+	File string
+
+	// Function is the comment of the fragments, which Git fills with the
+	// line of the enclosing function or section, without surrounding
+	// whitespace. It is empty for fragments without a comment.
+	Function string
+
+	Fragments    int
+	LinesAdded   int64
+	LinesDeleted int64
+}
+
+// Churn returns the number of lines added and deleted in the function.
+func (s FunctionStat) Churn() int64 {
+	return s.LinesAdded + s.LinesDeleted
+}
+
+// StatByFunction groups the text fragments of files by file and fragment
+// comment and counts the lines each group adds and deletes, so reviewers can
+// see which functions a patch changes the most. Stats are sorted by
+// decreasing churn, then by file and function. Fragments are grouped by their
+// recorded comments; use RegenerateComments first for patches generated
+// without function context.
+func StatByFunction(files []*File) []FunctionStat {
+	type key struct{ file, function string }
+
+	groups := make(map[key]*FunctionStat)
+	var stats []*FunctionStat
+	for _, f := range files {
+		name := f.NewName
+		if f.IsDelete {
+			name = f.OldName
+		}
+
+		for _, frag := range f.TextFragments {
+			k := key{name, strings.TrimSpace(frag.Comment)}
+			s, ok := groups[k]
+			if !ok {
+				s = &FunctionStat{File: k.file, Function: k.function}
+				groups[k] = s
+				stats = append(stats, s)
+			}
+			s.Fragments++
+			s.LinesAdded += frag.LinesAdded
+			s.LinesDeleted += frag.LinesDeleted
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.Churn() != b.Churn() {
+			return a.Churn() > b.Churn()
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Function < b.Function
+	})
+
+	result := make([]FunctionStat, len(stats))
+	for i, s := range stats {
+		result[i] = *s
+	}
+	return result
+}
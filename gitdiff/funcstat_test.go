@@ -0,0 +1,60 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatByFunction(t *testing.T) {
+	patch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3,3 +3,4 @@ func main() {
+ 	a := 1
+-	b := 2
++	b := 3
++	c := 4
+ 	fmt.Println(a, b)
+@@ -20,3 +21,3 @@ func helper() {
+ 	x := 1
+-	y := 2
++	y := 3
+ 	return x
+@@ -40,3 +41,2 @@ func main() {
+ 	done()
+-	cleanup()
+ }
+@@ -60,3 +60,3 @@
+ var (
+-	v = 1
++	v = 2
+ )
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package main
+-func old() {}
+`
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	expected := []FunctionStat{
+		{File: "main.go", Function: "func main() {", Fragments: 2, LinesAdded: 2, LinesDeleted: 2},
+		{File: "main.go", Function: "", Fragments: 1, LinesAdded: 1, LinesDeleted: 1},
+		{File: "main.go", Function: "func helper() {", Fragments: 1, LinesAdded: 1, LinesDeleted: 1},
+		{File: "old.go", Function: "", Fragments: 1, LinesAdded: 0, LinesDeleted: 2},
+	}
+
+	stats := StatByFunction(files)
+	if !reflect.DeepEqual(expected, stats) {
+		t.Errorf("incorrect stats\nexpected: %+v\n  actual: %+v", expected, stats)
+	}
+	if stats[0].Churn() != 4 {
+		t.Errorf("incorrect churn: expected 4, actual %d", stats[0].Churn())
+	}
+}
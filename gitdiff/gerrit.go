@@ -0,0 +1,169 @@
+package gitdiff
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// GerritEditOp is the kind of a GerritEdit.
+type GerritEditOp int
+
+const (
+	// GerritEditReplace sets the content of a file, creating it if needed.
+	GerritEditReplace GerritEditOp = iota
+	// GerritEditRename moves a file without changing its content.
+	GerritEditRename
+	// GerritEditDelete removes a file.
+	GerritEditDelete
+)
+
+func (op GerritEditOp) String() string {
+	switch op {
+	case GerritEditReplace:
+		return "replace"
+	case GerritEditRename:
+		return "rename"
+	case GerritEditDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("GerritEditOp(%d)", int(op))
+}
+
+// GerritEdit is one call to the change edit endpoints of the Gerrit REST API.
+// Sending the edits returned by GerritEdits in order to the edit of a change
+// makes the same changes as the patch.
+type GerritEdit struct {
+	Op GerritEditOp
+
+	// Path is the name of the file the edit changes. For renames, OldPath is
+	// the name of the file before the rename.
+	Path    string
+	OldPath string
+
+	// Content is the new content of the file for replacements. Mode is the
+	// new mode of the file for replacements, or zero to keep the current mode.
+	Content []byte
+	Mode    os.FileMode
+}
+
+// Method returns the HTTP method of the edit.
+func (e GerritEdit) Method() string {
+	switch e.Op {
+	case GerritEditRename:
+		return http.MethodPost
+	case GerritEditDelete:
+		return http.MethodDelete
+	}
+	return http.MethodPut
+}
+
+// URLPath returns the path of the endpoint for the edit relative to the REST
+// API root, for the change with the given ID.
+func (e GerritEdit) URLPath(changeID string) string {
+	p := "/changes/" + url.PathEscape(changeID) + "/edit"
+	if e.Op == GerritEditRename {
+		return p
+	}
+	return p + "/" + url.PathEscape(e.Path)
+}
+
+// Body returns the JSON request body of the edit, or nil if the edit has no
+// body. Replacements send the content as base64 data so that binary files are
+// preserved.
+func (e GerritEdit) Body() ([]byte, error) {
+	switch e.Op {
+	case GerritEditRename:
+		return json.Marshal(struct {
+			OldPath string `json:"old_path"`
+			NewPath string `json:"new_path"`
+		}{e.OldPath, e.Path})
+	case GerritEditDelete:
+		return nil, nil
+	}
+
+	body := struct {
+		BinaryContent string `json:"binary_content"`
+		FileMode      int    `json:"file_mode,omitempty"`
+	}{
+		BinaryContent: "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(e.Content),
+	}
+	if e.Mode != 0 {
+		// Gerrit expects the octal digits of the mode as a decimal number
+		body.FileMode = gerritFileMode(e.Mode)
+	}
+	return json.Marshal(body)
+}
+
+func gerritFileMode(mode os.FileMode) int {
+	n := 0
+	for m, scale := uint32(mode), 1; m > 0; m, scale = m/8, scale*10 {
+		n += int(m%8) * scale
+	}
+	return n
+}
+
+// GerritEdits applies files to the files in src and returns the edits that
+// make the same changes in a Gerrit change edit, for integrations that cannot
+// push commits with a Git client. Like ApplyTree, later files in the patch
+// see the results of earlier ones. It returns an error if any file does not
+// apply.
+func GerritEdits(src fs.FS, files []*File, opts ApplyOptions) ([]GerritEdit, error) {
+	files, err := OrderFiles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string][]byte)
+	deleted := make(map[string]bool)
+
+	var edits []GerritEdit
+	for _, f := range files {
+		var data []byte
+		if !f.IsNew {
+			if deleted[f.OldName] {
+				return nil, fmt.Errorf("gitdiff: %s: file was deleted by an earlier change", f.OldName)
+			}
+			var ok bool
+			if data, ok = pending[f.OldName]; !ok {
+				if data, err = fs.ReadFile(src, f.OldName); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		var dst bytes.Buffer
+		if err := NewApplierWithOptions(bytes.NewReader(data), opts).ApplyFile(&dst, f); err != nil {
+			return nil, fmt.Errorf("gitdiff: %s: %w", fileName(f), err)
+		}
+
+		switch {
+		case f.IsDelete:
+			edits = append(edits, GerritEdit{Op: GerritEditDelete, Path: f.OldName})
+			delete(pending, f.OldName)
+			deleted[f.OldName] = true
+			continue
+		case f.IsRename:
+			edits = append(edits, GerritEdit{Op: GerritEditRename, Path: f.NewName, OldPath: f.OldName})
+			delete(pending, f.OldName)
+			deleted[f.OldName] = true
+		}
+
+		changed := f.IsNew || f.IsCopy || !bytes.Equal(data, dst.Bytes())
+		if changed || isModeChange(f) {
+			edit := GerritEdit{Op: GerritEditReplace, Path: f.NewName, Content: dst.Bytes()}
+			if f.IsNew || isModeChange(f) {
+				edit.Mode = f.NewMode
+			}
+			edits = append(edits, edit)
+		}
+		pending[f.NewName] = dst.Bytes()
+		delete(deleted, f.NewName)
+	}
+	return edits, nil
+}
@@ -0,0 +1,188 @@
+package gitdiff
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGerritEdits(t *testing.T) {
+	src := fstest.MapFS{
+		"file.txt":   {Data: []byte("line 1\nline 2\n")},
+		"old.txt":    {Data: []byte("moved\n")},
+		"gone.txt":   {Data: []byte("gone\n")},
+		"script.sh":  {Data: []byte("echo\n")},
+		"stale.txt":  {Data: []byte("different\n")},
+		"rename.txt": {Data: []byte("a\nb\n")},
+	}
+
+	tests := map[string]struct {
+		Patch string
+		Edits []GerritEdit
+		Err   interface{}
+	}{
+		"modify": {
+			Patch: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@
+ line 1
+-line 2
++line two
+`,
+			Edits: []GerritEdit{
+				{Op: GerritEditReplace, Path: "file.txt", Content: []byte("line 1\nline two\n")},
+			},
+		},
+		"newAndDelete": {
+			Patch: `diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/dir/new.txt b/dir/new.txt
+new file mode 100755
+--- /dev/null
++++ b/dir/new.txt
+@@ -0,0 +1 @@
++new
+`,
+			Edits: []GerritEdit{
+				{Op: GerritEditDelete, Path: "gone.txt"},
+				{Op: GerritEditReplace, Path: "dir/new.txt", Content: []byte("new\n"), Mode: 0100755},
+			},
+		},
+		"pureRename": {
+			Patch: `diff --git a/old.txt b/new.txt
+similarity index 100%
+rename from old.txt
+rename to new.txt
+`,
+			Edits: []GerritEdit{
+				{Op: GerritEditRename, Path: "new.txt", OldPath: "old.txt"},
+			},
+		},
+		"renameWithChanges": {
+			Patch: `diff --git a/rename.txt b/renamed.txt
+similarity index 50%
+rename from rename.txt
+rename to renamed.txt
+--- a/rename.txt
++++ b/renamed.txt
+@@ -1,2 +1,2 @@
+ a
+-b
++c
+`,
+			Edits: []GerritEdit{
+				{Op: GerritEditRename, Path: "renamed.txt", OldPath: "rename.txt"},
+				{Op: GerritEditReplace, Path: "renamed.txt", Content: []byte("a\nc\n")},
+			},
+		},
+		"modeOnly": {
+			Patch: `diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+`,
+			Edits: []GerritEdit{
+				{Op: GerritEditReplace, Path: "script.sh", Content: []byte("echo\n"), Mode: 0100755},
+			},
+		},
+		"conflict": {
+			Patch: `diff --git a/stale.txt b/stale.txt
+--- a/stale.txt
++++ b/stale.txt
+@@ -1 +1 @@
+-original
++changed
+`,
+			Err: "stale.txt",
+		},
+		"missingFile": {
+			Patch: `diff --git a/missing.txt b/missing.txt
+--- a/missing.txt
++++ b/missing.txt
+@@ -1 +1 @@
+-a
++b
+`,
+			Err: "missing.txt",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			edits, err := GerritEdits(src, files, ApplyOptions{})
+			if test.Err != nil {
+				assertError(t, test.Err, err, "creating Gerrit edits")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error creating Gerrit edits: %v", err)
+			}
+			if !reflect.DeepEqual(test.Edits, edits) {
+				t.Errorf("incorrect edits\nexpected: %+v\n  actual: %+v", test.Edits, edits)
+			}
+		})
+	}
+}
+
+func TestGerritEditRequest(t *testing.T) {
+	tests := map[string]struct {
+		Edit   GerritEdit
+		Method string
+		Path   string
+		Body   string
+	}{
+		"replace": {
+			Edit:   GerritEdit{Op: GerritEditReplace, Path: "dir/file.txt", Content: []byte("hi\n")},
+			Method: http.MethodPut,
+			Path:   "/changes/project~123/edit/dir%2Ffile.txt",
+			Body:   `{"binary_content":"data:application/octet-stream;base64,aGkK"}`,
+		},
+		"replaceMode": {
+			Edit:   GerritEdit{Op: GerritEditReplace, Path: "run.sh", Content: []byte{}, Mode: 0100755},
+			Method: http.MethodPut,
+			Path:   "/changes/project~123/edit/run.sh",
+			Body:   `{"binary_content":"data:application/octet-stream;base64,","file_mode":100755}`,
+		},
+		"rename": {
+			Edit:   GerritEdit{Op: GerritEditRename, Path: "new.txt", OldPath: "old.txt"},
+			Method: http.MethodPost,
+			Path:   "/changes/project~123/edit",
+			Body:   `{"old_path":"old.txt","new_path":"new.txt"}`,
+		},
+		"delete": {
+			Edit:   GerritEdit{Op: GerritEditDelete, Path: "a b.txt"},
+			Method: http.MethodDelete,
+			Path:   "/changes/project~123/edit/a%20b.txt",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if m := test.Edit.Method(); m != test.Method {
+				t.Errorf("incorrect method: expected %s, actual %s", test.Method, m)
+			}
+			if p := test.Edit.URLPath("project~123"); p != test.Path {
+				t.Errorf("incorrect path: expected %s, actual %s", test.Path, p)
+			}
+
+			body, err := test.Edit.Body()
+			if err != nil {
+				t.Fatalf("unexpected error creating body: %v", err)
+			}
+			if string(body) != test.Body {
+				t.Errorf("incorrect body\nexpected: %s\n  actual: %s", test.Body, body)
+			}
+		})
+	}
+}
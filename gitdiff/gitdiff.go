@@ -25,12 +25,37 @@ type File struct {
 	NewOIDPrefix string
 	Score        int
 
+	// ExtendedHeaders contains the lines of the Git file header that are not
+	// interpreted, without trailing newlines: lines with a Git keyword the
+	// parser does not use, like the mode of a combined diff, and lines that
+	// match a parser added with RegisterHeaderParser. Like Git, a line with
+	// any other keyword ends the header. The lines are kept in order and are
+	// written after the index line when the file is formatted.
+	ExtendedHeaders []string
+
+	// Extensions contains data parsed from extended header lines by parsers
+	// added with RegisterHeaderParser. Keys are chosen by the parsers.
+	Extensions map[string]interface{}
+
 	PatchHeader *PatchHeader
 
+	// Raw is the entry describing the file in raw format, if the patch was
+	// generated with the --raw option (e.g. git show --raw --patch).
+	Raw *RawEntry
+
 	// TextFragments contains the fragments describing changes to a text file. It
 	// may be empty if the file is empty or if only the mode changes.
 	TextFragments []*TextFragment
 
+	// ParentCount is the number of parents of a file from a combined diff, as
+	// generated by git for merge commits with the --cc or -c options. It is
+	// zero for other files. Each combined fragment adds one fragment per parent
+	// to TextFragments, describing the changes between that parent and the
+	// result, and CombinedFragments groups these fragments by combined
+	// fragment, in the order of the parents.
+	ParentCount       int
+	CombinedFragments [][]*TextFragment
+
 	// IsBinary is true if the file is a binary file. If the patch includes
 	// binary data, BinaryFragment will be non-nil and describe the changes to
 	// the data. If the patch is reversible, ReverseBinaryFragment will also be
@@ -39,6 +64,24 @@ type File struct {
 	IsBinary              bool
 	BinaryFragment        *BinaryFragment
 	ReverseBinaryFragment *BinaryFragment
+
+	// ContentOmitted is true if the patch does not include the changes to the
+	// content of the file. For deleted files, as in patches generated with the
+	// --irreversible-delete option of git diff or binary patches without
+	// data, the old content cannot be verified when applying. Other files,
+	// such as the files of API responses that leave out large diffs, cannot
+	// be applied at all.
+	ContentOmitted bool
+
+	// Annotations contains notes attached to the file by tools.
+	Annotations []Annotation
+
+	// Meta holds data that an application attaches to the file, such as a
+	// ticket ID, a review ID, or the owner of the file. The parser never
+	// sets it. Like annotations, it is kept by the functions in this package
+	// that copy, filter, split, or merge files, but it is not formatted,
+	// encoded, or compared by Equal. Copies of a file share its map.
+	Meta map[string]interface{}
 }
 
 // TextFragment describes changed lines starting at a specific line in a text file.
@@ -58,6 +101,10 @@ type TextFragment struct {
 	TrailingContext int64
 
 	Lines []Line
+
+	// Annotations contains notes attached to the fragment or to its lines by
+	// tools.
+	Annotations []Annotation
 }
 
 func (f *TextFragment) Raw(op LineOp) string {
@@ -76,20 +123,35 @@ func (f *TextFragment) Header() string {
 }
 
 // Validate checks that the fragment is self-consistent and appliable. Validate
-// returns an error if and only if the fragment is invalid.
+// returns an error if and only if the fragment is invalid. It applies the
+// same rules as the parser, so code that creates or modifies fragments can
+// use it to check that the result could have been parsed from a patch.
 func (f *TextFragment) Validate() error {
 	if f == nil {
 		return errors.New("nil fragment")
 	}
+	if f.OldPosition < 0 || f.NewPosition < 0 {
+		return errors.New("fragment has a negative position")
+	}
 
 	var (
 		oldLines, newLines                     int64
 		leadingContext, trailingContext        int64
 		contextLines, addedLines, deletedLines int64
+		oldNoEOL, newNoEOL                     bool
 	)
 
 	// count the types of lines in the fragment content
 	for i, line := range f.Lines {
+		// only the last line of each version can be missing a newline
+		if (line.Old() && oldNoEOL) || (line.New() && newNoEOL) {
+			return fmt.Errorf("line %d follows a line without a trailing newline", i+1)
+		}
+		if line.NoEOL() {
+			oldNoEOL = oldNoEOL || line.Old()
+			newNoEOL = newNoEOL || line.New()
+		}
+
 		switch line.Op {
 		case OpContext:
 			oldLines++
@@ -137,6 +199,10 @@ func (f *TextFragment) Validate() error {
 	if f.OldPosition == 0 && f.OldLines != 0 {
 		return errors.New("file creation fragment contains context or deletion lines")
 	}
+	// if a file is being deleted, it can only contain deletions
+	if f.NewPosition == 0 && f.NewLines != 0 {
+		return errors.New("file deletion fragment contains context or addition lines")
+	}
 
 	return nil
 }
@@ -182,6 +248,8 @@ const (
 	OpAdd
 )
 
+// String returns the character that starts lines with the operation in a
+// patch, or "?" if the operation is unknown.
 func (op LineOp) String() string {
 	switch op {
 	case OpContext:
@@ -194,6 +262,20 @@ func (op LineOp) String() string {
 	return "?"
 }
 
+// ParseLineOp returns the operation for a line in a patch that starts with
+// the character c. It returns an error if c is not ' ', '-', or '+'.
+func ParseLineOp(c byte) (LineOp, error) {
+	switch c {
+	case ' ':
+		return OpContext, nil
+	case '-':
+		return OpDelete, nil
+	case '+':
+		return OpAdd, nil
+	}
+	return 0, fmt.Errorf("gitdiff: invalid line operation: %q", c)
+}
+
 // BinaryFragment describes changes to a binary file.
 type BinaryFragment struct {
 	Method BinaryPatchMethod
@@ -142,6 +142,68 @@ func TestTextFragmentValidate(t *testing.T) {
 			},
 			Err: "creation fragment",
 		},
+		"fileDeletion": {
+			Fragment: TextFragment{
+				OldPosition:  1,
+				OldLines:     1,
+				NewPosition:  0,
+				NewLines:     1,
+				LinesAdded:   1,
+				LinesDeleted: 1,
+				Lines: []Line{
+					{Op: OpDelete, Line: "old line\n"},
+					{Op: OpAdd, Line: "new line\n"},
+				},
+			},
+			Err: "deletion fragment",
+		},
+		"negativePosition": {
+			Fragment: TextFragment{
+				OldPosition: -1,
+				NewPosition: 1,
+				NewLines:    1,
+				LinesAdded:  1,
+				Lines: []Line{
+					{Op: OpAdd, Line: "new line\n"},
+				},
+			},
+			Err: "negative position",
+		},
+		"missingNewline": {
+			Fragment: TextFragment{
+				OldPosition:     1,
+				OldLines:        2,
+				NewPosition:     1,
+				NewLines:        2,
+				LeadingContext:  1,
+				TrailingContext: 1,
+				LinesAdded:      1,
+				LinesDeleted:    1,
+				Lines: []Line{
+					{Op: OpContext, Line: "line 1\n"},
+					{Op: OpDelete, Line: "old line 2"},
+					{Op: OpAdd, Line: "new line 2\n"},
+					{Op: OpContext, Line: "line 3\n"},
+				},
+			},
+			Err: "line 4 follows a line without a trailing newline",
+		},
+		"missingNewlineAtEnd": {
+			Fragment: TextFragment{
+				OldPosition:    1,
+				OldLines:       2,
+				NewPosition:    1,
+				NewLines:       2,
+				LeadingContext: 1,
+				LinesAdded:     1,
+				LinesDeleted:   1,
+				Lines: []Line{
+					{Op: OpContext, Line: "line 1\n"},
+					{Op: OpDelete, Line: "old line 2"},
+					{Op: OpAdd, Line: "new line 2"},
+				},
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -150,7 +212,10 @@ func TestTextFragmentValidate(t *testing.T) {
 			if test.Err == "" && err != nil {
 				t.Fatalf("unexpected validation error: %v", err)
 			}
-			if test.Err != "" && err == nil {
+			if test.Err == "" {
+				return
+			}
+			if err == nil {
 				t.Fatal("expected validation error, but got nil")
 			}
 			if !strings.Contains(err.Error(), test.Err) {
@@ -159,3 +224,43 @@ func TestTextFragmentValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestLineOp(t *testing.T) {
+	tests := map[string]struct {
+		Char     byte
+		Op       LineOp
+		Old, New bool
+		Err      bool
+	}{
+		"context": {Char: ' ', Op: OpContext, Old: true, New: true},
+		"delete":  {Char: '-', Op: OpDelete, Old: true},
+		"add":     {Char: '+', Op: OpAdd, New: true},
+		"invalid": {Char: '*', Err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			op, err := ParseLineOp(test.Char)
+			if test.Err {
+				if err == nil {
+					t.Fatalf("expected error parsing %q, but got nil", test.Char)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", test.Char, err)
+			}
+			if op != test.Op {
+				t.Errorf("incorrect op: expected %v, actual %v", test.Op, op)
+			}
+			if op.String() != string(test.Char) {
+				t.Errorf("incorrect string: expected %q, actual %q", test.Char, op.String())
+			}
+
+			line := Line{Op: op, Line: "line\n"}
+			if line.Old() != test.Old || line.New() != test.New {
+				t.Errorf("incorrect versions: expected old=%t new=%t, actual old=%t new=%t", test.Old, test.New, line.Old(), line.New())
+			}
+		})
+	}
+}
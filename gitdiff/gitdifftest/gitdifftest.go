@@ -0,0 +1,104 @@
+// Package gitdifftest provides utilities for testing code that uses the
+// gitdiff package, along with a corpus of patches generated by Git.
+package gitdifftest
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"io/fs"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+// Corpus contains patches generated by Git. The "patches" directory contains
+// patches that can be parsed and formatted. The "apply" directory contains
+// test cases for AssertApplies: each case has a patch with a ".patch"
+// extension, the source file it applies to with a ".src" extension, and the
+// expected result with a ".out" extension.
+var Corpus fs.FS
+
+func init() {
+	var err error
+	if Corpus, err = fs.Sub(testdata, "testdata"); err != nil {
+		panic(err)
+	}
+}
+
+// AssertRoundTrip checks that patch parses without errors and that
+// formatting the parsed files produces a patch that parses to the same files.
+// The patch header of each file and any raw entries are not compared because
+// they are not included in the formatted patch.
+func AssertRoundTrip(t testing.TB, patch []byte) {
+	t.Helper()
+
+	files, _, err := gitdiff.ParseAll(bytes.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := gitdiff.FormatFiles(&b, files); err != nil {
+		t.Fatalf("unexpected error formatting files: %v", err)
+	}
+
+	reparsed, _, err := gitdiff.ParseAll(&b)
+	if err != nil {
+		t.Fatalf("unexpected error parsing formatted patch: %v\n%s", err, b.String())
+	}
+	if len(reparsed) != len(files) {
+		t.Fatalf("incorrect number of files: expected %d, actual %d", len(files), len(reparsed))
+	}
+	for i := range files {
+		expected, actual := *files[i], *reparsed[i]
+		expected.PatchHeader, expected.Raw = nil, nil
+		actual.PatchHeader, actual.Raw = nil, nil
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("file %d does not round trip\nexpected: %+v\n  actual: %+v", i, expected, actual)
+		}
+	}
+}
+
+// AssertApplies checks that the patch in the file name+".patch" in fsys
+// contains exactly one file and that applying it to the content of
+// name+".src" produces the content of name+".out". A missing source file is
+// treated as empty.
+func AssertApplies(t testing.TB, fsys fs.FS, name string) {
+	t.Helper()
+
+	patch := readFile(t, fsys, name+".patch")
+	src := readFile(t, fsys, name+".src")
+	out := readFile(t, fsys, name+".out")
+
+	files, _, err := gitdiff.ParseAll(bytes.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("patch should contain exactly one file, but it has %d", len(files))
+	}
+
+	var dst bytes.Buffer
+	if err := gitdiff.NewApplier(bytes.NewReader(src)).ApplyFile(&dst, files[0]); err != nil {
+		t.Fatalf("unexpected error applying patch: %v", err)
+	}
+	if !bytes.Equal(out, dst.Bytes()) {
+		t.Errorf("incorrect result after apply\nexpected:\n%q\nactual:\n%q", out, dst.Bytes())
+	}
+}
+
+func readFile(t testing.TB, fsys fs.FS, name string) []byte {
+	t.Helper()
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil && !(errors.Is(err, fs.ErrNotExist) && path.Ext(name) == ".src") {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return data
+}
@@ -0,0 +1,116 @@
+package gitdifftest
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+func TestCorpusRoundTrip(t *testing.T) {
+	patches, err := fs.Glob(Corpus, "patches/*.patch")
+	if err != nil {
+		t.Fatalf("unexpected error listing patches: %v", err)
+	}
+	if len(patches) == 0 {
+		t.Fatal("corpus does not contain any patches")
+	}
+
+	for _, name := range patches {
+		t.Run(path.Base(name), func(t *testing.T) {
+			patch, err := fs.ReadFile(Corpus, name)
+			if err != nil {
+				t.Fatalf("unexpected error reading patch: %v", err)
+			}
+			AssertRoundTrip(t, patch)
+		})
+	}
+}
+
+func TestCorpusApplies(t *testing.T) {
+	patches, err := fs.Glob(Corpus, "apply/*.patch")
+	if err != nil {
+		t.Fatalf("unexpected error listing patches: %v", err)
+	}
+	if len(patches) == 0 {
+		t.Fatal("corpus does not contain any patches")
+	}
+
+	apply, err := fs.Sub(Corpus, "apply")
+	if err != nil {
+		t.Fatalf("unexpected error opening directory: %v", err)
+	}
+	for _, name := range patches {
+		name = strings.TrimSuffix(path.Base(name), ".patch")
+		t.Run(name, func(t *testing.T) {
+			AssertApplies(t, apply, name)
+		})
+	}
+}
+
+func TestGenerateSynthetic(t *testing.T) {
+	s, err := GenerateSynthetic(SyntheticOptions{Files: 20, Hunks: 3, HunkLines: 5, BinaryRatio: 0.25, Seed: 1})
+	if err != nil {
+		t.Fatalf("unexpected error generating patch: %v", err)
+	}
+	if len(s.Files) != 20 {
+		t.Fatalf("incorrect number of files: expected 20, actual %d", len(s.Files))
+	}
+
+	var binary int
+	for _, f := range s.Files {
+		if f.IsBinary {
+			binary++
+			continue
+		}
+		if len(f.TextFragments) != 3 {
+			t.Errorf("%s: incorrect number of fragments: expected 3, actual %d", f.NewName, len(f.TextFragments))
+		}
+		for _, frag := range f.TextFragments {
+			if frag.LinesDeleted+frag.LinesAdded != 5 {
+				t.Errorf("%s: incorrect number of changed lines: %s", f.NewName, frag.Header())
+			}
+		}
+	}
+	if binary != 5 {
+		t.Errorf("incorrect number of binary files: expected 5, actual %d", binary)
+	}
+
+	AssertRoundTrip(t, s.Patch)
+	for _, f := range s.Files {
+		var dst bytes.Buffer
+		if err := gitdiff.NewApplier(bytes.NewReader(s.Old[f.OldName].Data)).ApplyFile(&dst, f); err != nil {
+			t.Fatalf("%s: unexpected error applying patch: %v", f.NewName, err)
+		}
+		if !bytes.Equal(s.New[f.NewName].Data, dst.Bytes()) {
+			t.Errorf("%s: incorrect result after apply", f.NewName)
+		}
+	}
+
+	again, err := GenerateSynthetic(SyntheticOptions{Files: 20, Hunks: 3, HunkLines: 5, BinaryRatio: 0.25, Seed: 1})
+	if err != nil {
+		t.Fatalf("unexpected error generating patch: %v", err)
+	}
+	if !bytes.Equal(s.Patch, again.Patch) {
+		t.Errorf("patches generated with the same options are not equal")
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	s, err := GenerateSynthetic(SyntheticOptions{BinaryRatio: 0.1})
+	if err != nil {
+		b.Fatalf("unexpected error generating patch: %v", err)
+	}
+	BenchParse(b, s.Patch)
+}
+
+func BenchmarkApply(b *testing.B) {
+	s, err := GenerateSynthetic(SyntheticOptions{BinaryRatio: 0.1})
+	if err != nil {
+		b.Fatalf("unexpected error generating patch: %v", err)
+	}
+	BenchApply(b, s)
+}
@@ -0,0 +1,178 @@
+package gitdifftest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+// lines of unchanged content between the hunks of synthetic files, which
+// keeps the changes in separate fragments with the default context
+const syntheticGap = 10
+
+// SyntheticOptions configures GenerateSynthetic.
+type SyntheticOptions struct {
+	// Files is the number of files in the patch. If zero, 100 is used.
+	Files int
+
+	// Hunks is the number of hunks in each text file. If zero, 4 is used.
+	Hunks int
+
+	// HunkLines is the number of deleted and added lines in each hunk. If
+	// zero, 6 is used.
+	HunkLines int
+
+	// BinaryRatio is the fraction of files that are binary, between 0 and 1.
+	// Binary files have literal or delta binary fragments.
+	BinaryRatio float64
+
+	// Seed seeds the random content of the files. The same options always
+	// generate the same patch.
+	Seed int64
+}
+
+// Synthetic is a generated patch along with the files it applies to.
+type Synthetic struct {
+	// Patch is the formatted patch and Files are its parsed files.
+	Patch []byte
+	Files []*gitdiff.File
+
+	// Old contains the files before applying the patch and New contains the
+	// files after applying it.
+	Old fstest.MapFS
+	New fstest.MapFS
+}
+
+// GenerateSynthetic generates a patch with the shape described by opts, for
+// load tests and benchmarks that need larger or differently shaped inputs
+// than the patches in Corpus.
+func GenerateSynthetic(opts SyntheticOptions) (*Synthetic, error) {
+	if opts.Files == 0 {
+		opts.Files = 100
+	}
+	if opts.Hunks == 0 {
+		opts.Hunks = 4
+	}
+	if opts.HunkLines == 0 {
+		opts.HunkLines = 6
+	}
+	if opts.Files < 0 || opts.Hunks < 0 || opts.HunkLines < 0 {
+		return nil, fmt.Errorf("gitdifftest: invalid synthetic options: %+v", opts)
+	}
+	if opts.BinaryRatio < 0 || opts.BinaryRatio > 1 {
+		return nil, fmt.Errorf("gitdifftest: invalid binary ratio: %v", opts.BinaryRatio)
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	s := &Synthetic{Old: fstest.MapFS{}, New: fstest.MapFS{}}
+
+	for i := 0; i < opts.Files; i++ {
+		// spread binary files evenly through the patch
+		binary := int(float64(i+1)*opts.BinaryRatio) > int(float64(i)*opts.BinaryRatio)
+
+		var name string
+		var old, new []byte
+		if binary {
+			name = fmt.Sprintf("dir%d/file%d.bin", i%10, i)
+			old, new = syntheticBinary(rng)
+		} else {
+			name = fmt.Sprintf("dir%d/file%d.txt", i%10, i)
+			old, new = syntheticText(rng, i, opts.Hunks, opts.HunkLines)
+		}
+		s.Old[name] = &fstest.MapFile{Data: old, Mode: 0644}
+		s.New[name] = &fstest.MapFile{Data: new, Mode: 0644}
+	}
+
+	files, err := gitdiff.DiffTrees(s.Old, s.New, gitdiff.DiffOptions{Binary: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := gitdiff.FormatFiles(&b, files); err != nil {
+		return nil, err
+	}
+	s.Patch = b.Bytes()
+
+	if s.Files, _, err = gitdiff.ParseAll(bytes.NewReader(s.Patch)); err != nil {
+		return nil, fmt.Errorf("gitdifftest: synthetic patch does not parse: %v", err)
+	}
+	return s, nil
+}
+
+func syntheticText(rng *rand.Rand, file, hunks, hunkLines int) (old, new []byte) {
+	var ob, nb bytes.Buffer
+	line := 0
+	writeBoth := func(n int) {
+		for j := 0; j < n; j++ {
+			s := fmt.Sprintf("file %d line %d: %x\n", file, line, rng.Uint32())
+			ob.WriteString(s)
+			nb.WriteString(s)
+			line++
+		}
+	}
+
+	writeBoth(syntheticGap)
+	for h := 0; h < hunks; h++ {
+		deleted := hunkLines / 2
+		for j := 0; j < deleted; j++ {
+			fmt.Fprintf(&ob, "file %d old line %d: %x\n", file, line+j, rng.Uint32())
+		}
+		for j := 0; j < hunkLines-deleted; j++ {
+			fmt.Fprintf(&nb, "file %d new line %d: %x\n", file, line+j, rng.Uint32())
+		}
+		line += hunkLines
+		writeBoth(syntheticGap)
+	}
+	return ob.Bytes(), nb.Bytes()
+}
+
+func syntheticBinary(rng *rand.Rand) (old, new []byte) {
+	old = make([]byte, 512+rng.Intn(512))
+	rng.Read(old)
+	old[0] = 0
+
+	new = append([]byte{}, old...)
+	for j := 0; j < 8; j++ {
+		new[1+rng.Intn(len(new)-1)] = byte(rng.Intn(256))
+	}
+	return old, new
+}
+
+// BenchParse runs a benchmark that parses patch with ParseAll.
+func BenchParse(b *testing.B, patch []byte) {
+	b.Helper()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(patch)))
+
+	for i := 0; i < b.N; i++ {
+		if _, _, err := gitdiff.ParseAll(bytes.NewReader(patch)); err != nil {
+			b.Fatalf("unexpected error parsing patch: %v", err)
+		}
+	}
+}
+
+// BenchApply runs a benchmark that applies the files of s to their old
+// content.
+func BenchApply(b *testing.B, s *Synthetic) {
+	b.Helper()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s.Patch)))
+
+	for i := 0; i < b.N; i++ {
+		for _, f := range s.Files {
+			var src []byte
+			if old, ok := s.Old[f.OldName]; ok {
+				src = old.Data
+			}
+			if err := gitdiff.NewApplier(bytes.NewReader(src)).ApplyFile(io.Discard, f); err != nil {
+				b.Fatalf("unexpected error applying %s: %v", f.NewName, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package gitdiff
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+var headerParsers = struct {
+	sync.RWMutex
+	byPrefix map[string]func(*File, string) error
+	prefixes []string
+}{byPrefix: make(map[string]func(*File, string) error)}
+
+// RegisterHeaderParser adds a parser for extended header lines of Git file
+// headers that start with prefix, such as headers added by a code review
+// system. When the parser finds a matching line, it calls fn with the file
+// and the rest of the line after prefix, without the trailing newline. fn
+// usually stores what it parses with SetExtension; if it returns an error,
+// parsing fails.
+//
+// Lines that match a registered prefix are also kept in the ExtendedHeaders
+// of the file, so they are written back out when the file is formatted, but
+// they are not rejected by the RejectUnknownHeaders option. Registered
+// parsers never replace the built-in headers, like "index" or "rename from".
+// If more than one prefix matches a line, the longest is used. A nil fn
+// removes the parser for prefix.
+func RegisterHeaderParser(prefix string, fn func(*File, string) error) {
+	headerParsers.Lock()
+	defer headerParsers.Unlock()
+
+	if fn == nil {
+		delete(headerParsers.byPrefix, prefix)
+	} else {
+		headerParsers.byPrefix[prefix] = fn
+	}
+
+	prefixes := make([]string, 0, len(headerParsers.byPrefix))
+	for p := range headerParsers.byPrefix {
+		prefixes = append(prefixes, p)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if len(prefixes[i]) != len(prefixes[j]) {
+			return len(prefixes[i]) > len(prefixes[j])
+		}
+		return prefixes[i] < prefixes[j]
+	})
+	headerParsers.prefixes = prefixes
+}
+
+// lookupHeaderParser returns the registered parser for line and the length
+// of its prefix, or nil if no registered prefix matches line.
+func lookupHeaderParser(line string) (func(*File, string) error, int) {
+	headerParsers.RLock()
+	defer headerParsers.RUnlock()
+	for _, p := range headerParsers.prefixes {
+		if p != "" && strings.HasPrefix(line, p) {
+			return headerParsers.byPrefix[p], len(p)
+		}
+	}
+	return nil, 0
+}
+
+// SetExtension sets the value of key in the Extensions of the file, creating
+// the map if needed.
+func (f *File) SetExtension(key string, value interface{}) {
+	if f.Extensions == nil {
+		f.Extensions = make(map[string]interface{})
+	}
+	f.Extensions[key] = value
+}
@@ -0,0 +1,96 @@
+package gitdiff
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisterHeaderParser(t *testing.T) {
+	RegisterHeaderParser("Review-Id: ", func(f *File, value string) error {
+		if value == "" {
+			return errors.New("empty review ID")
+		}
+		f.SetExtension("review-id", value)
+		return nil
+	})
+	RegisterHeaderParser("Review-Id: X", func(f *File, value string) error {
+		f.SetExtension("review-x", value)
+		return nil
+	})
+	defer RegisterHeaderParser("Review-Id: ", nil)
+	defer RegisterHeaderParser("Review-Id: X", nil)
+
+	tests := map[string]struct {
+		Input      string
+		Options    ParseOptions
+		Extensions map[string]interface{}
+		Headers    []string
+		Err        interface{}
+	}{
+		"registered": {
+			Input: `diff --git a/file.txt b/file.txt
+Review-Id: 1234
+old mode 100644
+new mode 100755
+`,
+			Extensions: map[string]interface{}{"review-id": "1234"},
+			Headers:    []string{"Review-Id: 1234"},
+		},
+		"longestPrefix": {
+			Input: `diff --git a/file.txt b/file.txt
+old mode 100644
+new mode 100755
+Review-Id: X99
+`,
+			Extensions: map[string]interface{}{"review-x": "99"},
+			Headers:    []string{"Review-Id: X99"},
+		},
+		"rejectUnknown": {
+			Input: `diff --git a/file.txt b/file.txt
+Review-Id: 1234
+old mode 100644
+new mode 100755
+`,
+			Options:    ParseOptions{RejectUnknownHeaders: true},
+			Extensions: map[string]interface{}{"review-id": "1234"},
+			Headers:    []string{"Review-Id: 1234"},
+		},
+		"parserError": {
+			Input: `diff --git a/file.txt b/file.txt
+Review-Id: 
+old mode 100644
+new mode 100755
+`,
+			Err: "line 2: git file header: empty review ID",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAllWithOptions(strings.NewReader(test.Input), test.Options)
+			if test.Err != nil {
+				assertError(t, test.Err, err, "parsing patch")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("expected 1 file, but got %d", len(files))
+			}
+
+			f := files[0]
+			if !reflect.DeepEqual(test.Extensions, f.Extensions) {
+				t.Errorf("incorrect extensions\nexpected: %+v\n  actual: %+v", test.Extensions, f.Extensions)
+			}
+			if !reflect.DeepEqual(test.Headers, f.ExtendedHeaders) {
+				t.Errorf("incorrect extended headers\nexpected: %q\n  actual: %q", test.Headers, f.ExtendedHeaders)
+			}
+			if f.OldMode != 0100644 || f.NewMode != 0100755 {
+				t.Errorf("incorrect modes: %o, %o", f.OldMode, f.NewMode)
+			}
+		})
+	}
+}
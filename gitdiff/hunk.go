@@ -0,0 +1,62 @@
+package gitdiff
+
+import (
+	"strings"
+)
+
+// Hunk is a fragment as it appears in a patch, with a single header. In a
+// combined diff, a hunk has one TextFragment for each parent of the file,
+// describing the changes between that parent and the result. In other
+// diffs, a hunk has exactly one TextFragment.
+type Hunk struct {
+	// Header is the header line of the hunk, without the trailing newline,
+	// as written by Git. For example, "@@@ -1,3 -1,3 +1,4 @@@ func()".
+	Header string
+
+	// Comment is the text after the ranges in the header, which is shared by
+	// all of the fragments in the hunk.
+	Comment string
+
+	Fragments []*TextFragment
+}
+
+// Hunks groups the text fragments of f by the hunks they appear in.
+func (f *File) Hunks() []*Hunk {
+	var groups [][]*TextFragment
+	if f.ParentCount > 0 {
+		groups = f.CombinedFragments
+	} else {
+		for _, frag := range f.TextFragments {
+			groups = append(groups, []*TextFragment{frag})
+		}
+	}
+
+	hunks := make([]*Hunk, 0, len(groups))
+	for _, frags := range groups {
+		if len(frags) == 0 {
+			continue
+		}
+
+		var b strings.Builder
+		newFormatter(&b).writeFragmentHeader(frags)
+		hunks = append(hunks, &Hunk{
+			Header:    b.String(),
+			Comment:   frags[0].Comment,
+			Fragments: frags,
+		})
+	}
+	return hunks
+}
+
+// String returns a git diff representation of the hunk, starting with its
+// header.
+func (h *Hunk) String() string {
+	var b strings.Builder
+	fm := newFormatter(&b)
+	if len(h.Fragments) == 1 {
+		fm.FormatTextFragment(h.Fragments[0])
+	} else {
+		fm.FormatCombinedFragment(h.Fragments)
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileHunks(t *testing.T) {
+	tests := map[string]struct {
+		Input   string
+		Headers []string
+		Parents int
+	}{
+		"text": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@ first
+ line 1
+-line 2
++line two
+@@ -10 +10 @@
+-line 10
++line ten
+`,
+			Headers: []string{"@@ -1,2 +1,2 @@ first", "@@ -10 +10 @@"},
+			Parents: 1,
+		},
+		"combined": {
+			Input: `diff --cc file.txt
+index 1111111,2222222..3333333
+--- a/file.txt
++++ b/file.txt
+@@@ -1,3 -1,3 +1,3 @@@ comment
+  line 1
+- parent one
+ -parent two
+++merged
+  line 3
+`,
+			Headers: []string{"@@@ -1,3 -1,3 +1,3 @@@ comment"},
+			Parents: 2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Input))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			hunks := files[0].Hunks()
+			if len(hunks) != len(test.Headers) {
+				t.Fatalf("incorrect number of hunks: expected %d, actual %d", len(test.Headers), len(hunks))
+			}
+
+			var out strings.Builder
+			for i, h := range hunks {
+				if h.Header != test.Headers[i] {
+					t.Errorf("hunk %d: incorrect header: expected %q, actual %q", i, test.Headers[i], h.Header)
+				}
+				if len(h.Fragments) != test.Parents {
+					t.Errorf("hunk %d: incorrect number of fragments: expected %d, actual %d", i, test.Parents, len(h.Fragments))
+				}
+				if h.Comment != h.Fragments[0].Comment {
+					t.Errorf("hunk %d: incorrect comment: %q", i, h.Comment)
+				}
+				out.WriteString(h.String())
+			}
+
+			// the hunks of these patches contain all of the lines after the header
+			body := test.Input[strings.Index(test.Input, "\n@@")+1:]
+			if out.String() != body {
+				t.Errorf("incorrect hunk content\nexpected: %q\n  actual: %q", body, out.String())
+			}
+		})
+	}
+}
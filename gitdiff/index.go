@@ -0,0 +1,109 @@
+package gitdiff
+
+import (
+	"hash/fnv"
+	"io"
+	"sort"
+)
+
+// LineIndex maps the checksum of each line in a source to the line numbers
+// where that line appears. It allows the Applier to find candidate positions
+// for a fragment in constant average time, even if the position recorded in
+// the fragment is far from the actual position.
+type LineIndex struct {
+	lines map[uint64][]int64
+	count int64
+}
+
+// NewLineIndex reads all lines in src and builds an index of their content.
+func NewLineIndex(src LineReaderAt) (*LineIndex, error) {
+	idx := &LineIndex{lines: make(map[uint64][]int64)}
+
+	buf := make([][]byte, indexBufferSize)
+	for {
+		n, err := src.ReadLinesAt(buf, idx.count)
+		for _, line := range buf[:n] {
+			h := lineHash(line)
+			idx.lines[h] = append(idx.lines[h], idx.count)
+			idx.count++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// Len returns the number of lines in the indexed source.
+func (idx *LineIndex) Len() int64 {
+	return idx.count
+}
+
+// Candidates returns the zero-indexed source lines where f may start, in
+// increasing order. It looks up the old line of the fragment that appears
+// the fewest times in the source, so every position where the fragment
+// matches exactly is included. Fragments with no old lines may start at any
+// position, so Candidates returns nil for them.
+func (idx *LineIndex) Candidates(f *TextFragment) []int64 {
+	var best []int64
+	var bestOffset int64
+	found := false
+
+	var offset int64
+	for _, line := range f.Lines {
+		if !line.Old() {
+			continue
+		}
+		positions := idx.lines[lineHash([]byte(line.Line))]
+		if !found || len(positions) < len(best) {
+			best, bestOffset, found = positions, offset, true
+		}
+		if len(best) == 0 {
+			return []int64{}
+		}
+		offset++
+	}
+	if !found {
+		return nil
+	}
+
+	candidates := make([]int64, 0, len(best))
+	for _, pos := range best {
+		start := pos - bestOffset
+		if start >= 0 && start+f.OldLines <= idx.count {
+			candidates = append(candidates, start)
+		}
+	}
+	return candidates
+}
+
+// nearest sorts positions by their distance from hint, preferring later
+// positions when the distance is equal.
+func nearest(positions []int64, hint int64) []int64 {
+	sorted := make([]int64, len(positions))
+	copy(sorted, positions)
+
+	dist := func(p int64) int64 {
+		if p < hint {
+			return hint - p
+		}
+		return p - hint
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		di, dj := dist(sorted[i]), dist(sorted[j])
+		if di != dj {
+			return di < dj
+		}
+		return sorted[i] > sorted[j]
+	})
+	return sorted
+}
+
+func lineHash(line []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(line)
+	return h.Sum64()
+}
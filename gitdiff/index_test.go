@@ -0,0 +1,97 @@
+package gitdiff
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineIndexCandidates(t *testing.T) {
+	src := "a\nb\nc\na\nb\nd\n"
+	idx, err := NewLineIndex(&lineReaderAt{r: strings.NewReader(src)})
+	if err != nil {
+		t.Fatalf("unexpected error building index: %v", err)
+	}
+	if idx.Len() != 6 {
+		t.Fatalf("incorrect line count: expected 6, actual %d", idx.Len())
+	}
+
+	frag := func(lines ...Line) *TextFragment {
+		f := &TextFragment{Lines: lines}
+		for _, l := range lines {
+			if l.Old() {
+				f.OldLines++
+			}
+		}
+		return f
+	}
+
+	tests := map[string]struct {
+		Fragment   *TextFragment
+		Candidates []int64
+	}{
+		"repeated": {
+			Fragment:   frag(Line{OpContext, "a\n"}, Line{OpDelete, "b\n"}),
+			Candidates: []int64{0, 3},
+		},
+		"unique": {
+			Fragment:   frag(Line{OpContext, "a\n"}, Line{OpContext, "b\n"}, Line{OpDelete, "d\n"}),
+			Candidates: []int64{3},
+		},
+		"missing": {
+			Fragment:   frag(Line{OpContext, "a\n"}, Line{OpDelete, "z\n"}),
+			Candidates: []int64{},
+		},
+		"noOldLines": {
+			Fragment:   frag(Line{OpAdd, "new\n"}),
+			Candidates: nil,
+		},
+		"pastEnd": {
+			Fragment:   frag(Line{OpContext, "d\n"}, Line{OpDelete, "e\n"}),
+			Candidates: []int64{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			candidates := idx.Candidates(test.Fragment)
+			if !reflect.DeepEqual(test.Candidates, candidates) {
+				t.Errorf("incorrect candidates: expected %v, actual %v", test.Candidates, candidates)
+			}
+		})
+	}
+}
+
+func TestApplyIndexed(t *testing.T) {
+	var src, out strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&src, "line %d\n", i)
+		if i == 9000 {
+			out.WriteString("changed\n")
+		} else {
+			fmt.Fprintf(&out, "line %d\n", i)
+		}
+	}
+
+	// the fragment position is wrong by 8900 lines
+	patch := "@@ -100,3 +100,3 @@\n line 8999\n-line 9000\n+changed\n line 9001\n"
+	p := newTestParser(patch, true)
+	file := &File{}
+	if _, err := p.ParseTextFragments(file); err != nil {
+		t.Fatalf("unexpected error parsing fragments: %v", err)
+	}
+
+	var dst bytes.Buffer
+	a := NewApplierWithOptions(strings.NewReader(src.String()), ApplyOptions{MaxOffset: -1, IndexSource: true})
+	if err := a.ApplyFile(&dst, file); err != nil {
+		t.Fatalf("unexpected error applying file: %v", err)
+	}
+	if dst.String() != out.String() {
+		t.Errorf("incorrect result after apply")
+	}
+	if a.index == nil {
+		t.Errorf("applier did not build an index")
+	}
+}
@@ -0,0 +1,130 @@
+package gitdiff
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// InPlaceFile is a file that can be modified in place, such as an *os.File
+// opened for reading and writing.
+type InPlaceFile interface {
+	io.ReaderAt
+	io.WriterAt
+	Truncate(size int64) error
+}
+
+// ApplyInPlace applies the changes in f directly to file, rewriting only the
+// data that changes. Content before the first text fragment is never read
+// into memory or rewritten and content after it is only rewritten starting at
+// the first byte that differs from the result; the file is then truncated to
+// its new size. This makes small changes near the end of very large files
+// cheap. The result after the first modified line is computed in memory
+// before anything is written, so a failed apply leaves file unchanged.
+//
+// ApplyInPlace returns the offset of the first modified byte, which is the
+// size of the file if nothing changed.
+func ApplyInPlace(file InPlaceFile, f *File) (int64, error) {
+	if f.IsBinary || len(f.TextFragments) == 0 {
+		var buf bytes.Buffer
+		if err := Apply(&buf, file, f); err != nil {
+			return 0, err
+		}
+		return writeChanged(file, 0, buf.Bytes())
+	}
+
+	frags := make([]*TextFragment, len(f.TextFragments))
+	copy(frags, f.TextFragments)
+	sort.Slice(frags, func(i, j int) bool {
+		return frags[i].OldPosition < frags[j].OldPosition
+	})
+
+	// lines are 0-indexed, positions are 1-indexed (but new files have position = 0)
+	startLine := frags[0].OldPosition - 1
+	if startLine < 0 {
+		startLine = 0
+	}
+
+	offset, err := lineOffset(file, startLine)
+	if err != nil {
+		return 0, applyError(err, lineNum(startLine))
+	}
+
+	shifted := *f
+	shifted.TextFragments = make([]*TextFragment, len(frags))
+	for i, frag := range frags {
+		s := *frag
+		if s.OldPosition > 0 {
+			s.OldPosition -= startLine
+		}
+		shifted.TextFragments[i] = &s
+	}
+
+	var tail bytes.Buffer
+	src := io.NewSectionReader(file, offset, 1<<62)
+	if err := Apply(&tail, src, &shifted); err != nil {
+		return 0, err
+	}
+	return writeChanged(file, offset, tail.Bytes())
+}
+
+// lineOffset returns the byte offset of the start of line in r.
+func lineOffset(r io.ReaderAt, line int64) (int64, error) {
+	if line == 0 {
+		return 0, nil
+	}
+
+	lr := &lineReaderAt{r: r}
+	if err := lr.indexTo(line); err != nil {
+		return 0, err
+	}
+	if int64(len(lr.index)) < line {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return lr.index[line-1], nil
+}
+
+// writeChanged writes data to file starting at offset, skipping any leading
+// bytes that are already equal to the existing content, and truncates file
+// to end with data. It returns the offset of the first modified byte.
+func writeChanged(file InPlaceFile, offset int64, data []byte) (int64, error) {
+	buf := make([]byte, byteBufferSize)
+
+	same := 0
+	for same < len(data) {
+		n, err := file.ReadAt(buf[:minInt(len(buf), len(data)-same)], offset+int64(same))
+		i := 0
+		for i < n && buf[i] == data[same+i] {
+			i++
+		}
+		same += i
+		if i < n {
+			break
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, err
+		}
+	}
+
+	first := offset + int64(same)
+	if same < len(data) {
+		if _, err := file.WriteAt(data[same:], first); err != nil {
+			return 0, err
+		}
+	}
+
+	// only truncate if the file has content after the new end
+	end := offset + int64(len(data))
+	var b [1]byte
+	if n, err := file.ReadAt(b[:], end); n > 0 {
+		if err := file.Truncate(end); err != nil {
+			return 0, err
+		}
+	} else if err != nil && err != io.EOF {
+		return 0, err
+	}
+	return first, nil
+}
@@ -0,0 +1,165 @@
+package gitdiff
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// recordingFile is an in-memory InPlaceFile that records the lowest offset
+// written or truncated.
+type recordingFile struct {
+	data    []byte
+	lowest  int64
+	written int
+}
+
+func (f *recordingFile) ReadAt(b []byte, off int64) (int, error) {
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.data[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *recordingFile) WriteAt(b []byte, off int64) (int, error) {
+	if end := off + int64(len(b)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	copy(f.data[off:], b)
+	f.record(off)
+	f.written += len(b)
+	return len(b), nil
+}
+
+func (f *recordingFile) Truncate(size int64) error {
+	f.data = f.data[:size]
+	f.record(size)
+	return nil
+}
+
+func (f *recordingFile) record(off int64) {
+	if off < f.lowest {
+		f.lowest = off
+	}
+}
+
+func TestApplyInPlace(t *testing.T) {
+	var src strings.Builder
+	for i := 1; i <= 1000; i++ {
+		src.WriteString("unchanged line\n")
+	}
+	base := src.String()
+
+	tests := map[string]struct {
+		Patch  string
+		Out    string
+		Offset int64
+		Err    bool
+	}{
+		"appendTail": {
+			Patch: `@@ -999,2 +999,3 @@
+ unchanged line
+ unchanged line
++new line
+`,
+			Out:    base + "new line\n",
+			Offset: int64(len(base)),
+		},
+		"truncateTail": {
+			Patch: `@@ -998,3 +998,1 @@
+ unchanged line
+-unchanged line
+-unchanged line
+`,
+			Out:    base[:998*15],
+			Offset: 998 * 15,
+		},
+		"changeMiddle": {
+			Patch: `@@ -500,1 +500,1 @@
+-unchanged line
++changed line
+`,
+			Out:    base[:499*15] + "changed line\n" + base[500*15:],
+			Offset: 499 * 15,
+		},
+		"conflict": {
+			Patch: `@@ -500,1 +500,1 @@
+-other line
++changed line
+`,
+			Err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newTestParser(test.Patch, true)
+			file := &File{OldName: "file.txt", NewName: "file.txt"}
+			if _, err := p.ParseTextFragments(file); err != nil {
+				t.Fatalf("unexpected error parsing fragments: %v", err)
+			}
+
+			f := &recordingFile{data: []byte(base), lowest: int64(len(base)) + 1}
+			offset, err := ApplyInPlace(f, file)
+			if test.Err {
+				if err == nil {
+					t.Fatalf("expected error applying in place, but got nil")
+				}
+				if string(f.data) != base {
+					t.Errorf("file was modified after failed apply")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error applying in place: %v", err)
+			}
+
+			if offset != test.Offset {
+				t.Errorf("incorrect first modified offset: expected %d, actual %d", test.Offset, offset)
+			}
+			if f.lowest < test.Offset {
+				t.Errorf("file was modified before offset %d at offset %d", test.Offset, f.lowest)
+			}
+			if string(f.data) != test.Out {
+				t.Errorf("incorrect result after apply")
+			}
+		})
+	}
+}
+
+func TestApplyInPlaceOSFile(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "gitdiff-inplace")
+	if err != nil {
+		t.Fatalf("unexpected error creating temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.WriteString("line 1\nline 2\nline 3\n"); err != nil {
+		t.Fatalf("unexpected error writing temp file: %v", err)
+	}
+
+	p := newTestParser("@@ -2,2 +2 @@\n line 2\n-line 3\n", true)
+	file := &File{}
+	if _, err := p.ParseTextFragments(file); err != nil {
+		t.Fatalf("unexpected error parsing fragments: %v", err)
+	}
+
+	if _, err := ApplyInPlace(tmp, file); err != nil {
+		t.Fatalf("unexpected error applying in place: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatalf("unexpected error reading temp file: %v", err)
+	}
+	if string(data) != "line 1\nline 2\n" {
+		t.Errorf("incorrect result after apply: %q", data)
+	}
+}
@@ -0,0 +1,73 @@
+package gitdiff
+
+import (
+	"sync"
+)
+
+// Interner deduplicates strings, so that many parsed patches can share the
+// memory of file names, object IDs, and fragment comments that repeat across
+// them. Set the Interner field of ParseOptions to intern strings as files are
+// parsed. The zero value is ready to use and an Interner is safe for
+// concurrent use. Interned strings are kept until the Interner is no longer
+// referenced.
+type Interner struct {
+	mu      sync.Mutex
+	strings map[string]string
+}
+
+// Intern returns a string equal to s, reusing a previously interned copy if
+// one exists.
+func (in *Interner) Intern(s string) string {
+	if s == "" {
+		return s
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if is, ok := in.strings[s]; ok {
+		return is
+	}
+	if in.strings == nil {
+		in.strings = make(map[string]string)
+	}
+	in.strings[s] = s
+	return s
+}
+
+// Len returns the number of distinct strings in the Interner.
+func (in *Interner) Len() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.strings)
+}
+
+// InternFile replaces the names, object IDs, extended headers, and fragment
+// comments of f with interned copies. It does not change the lines of text
+// fragments, which rarely repeat.
+func (in *Interner) InternFile(f *File) {
+	f.OldName = in.Intern(f.OldName)
+	f.NewName = in.Intern(f.NewName)
+	f.OldOIDPrefix = in.Intern(f.OldOIDPrefix)
+	f.NewOIDPrefix = in.Intern(f.NewOIDPrefix)
+	for i, hdr := range f.ExtendedHeaders {
+		f.ExtendedHeaders[i] = in.Intern(hdr)
+	}
+	for _, frag := range f.TextFragments {
+		frag.Comment = in.Intern(frag.Comment)
+	}
+	if r := f.Raw; r != nil {
+		r.OldName = in.Intern(r.OldName)
+		r.NewName = in.Intern(r.NewName)
+		r.OldOID = in.Intern(r.OldOID)
+		r.NewOID = in.Intern(r.NewOID)
+	}
+	if h := f.PatchHeader; h != nil {
+		for _, id := range []*PatchIdentity{h.Author, h.Committer} {
+			if id != nil {
+				id.Name = in.Intern(id.Name)
+				id.Email = in.Intern(id.Email)
+			}
+		}
+	}
+}
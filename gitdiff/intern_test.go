@@ -0,0 +1,73 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func TestInterner(t *testing.T) {
+	var in Interner
+
+	a := in.Intern(strings.Repeat("dir/", 4))
+	b := in.Intern(strings.Repeat("dir/", 4))
+	if a != b || stringData(a) != stringData(b) {
+		t.Errorf("interned strings do not share memory")
+	}
+	if in.Intern("") != "" {
+		t.Errorf("empty string was not returned unchanged")
+	}
+	if n := in.Len(); n != 1 {
+		t.Errorf("incorrect length: expected 1, actual %d", n)
+	}
+}
+
+func TestParseInterner(t *testing.T) {
+	patch := `diff --git a/dir/file.txt b/dir/file.txt
+index 1c23fcc..40a1b33 100644
+--- a/dir/file.txt
++++ b/dir/file.txt
+@@ -1,1 +1,1 @@ func main()
+-old
++new
+`
+
+	var in Interner
+	var files []*File
+	for i := 0; i < 2; i++ {
+		parsed, _, err := ParseAllWithOptions(strings.NewReader(patch), ParseOptions{Interner: &in})
+		if err != nil {
+			t.Fatalf("unexpected error parsing patch: %v", err)
+		}
+		files = append(files, parsed...)
+	}
+
+	expected, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	if !reflect.DeepEqual(expected[0], files[0]) {
+		t.Errorf("interned file does not match original\nexpected: %+v\n  actual: %+v", expected[0], files[0])
+	}
+
+	f0, f1 := files[0], files[1]
+	shared := map[string][2]string{
+		"OldName":      {f0.OldName, f1.OldName},
+		"NewName":      {f0.NewName, f1.NewName},
+		"OldOIDPrefix": {f0.OldOIDPrefix, f1.OldOIDPrefix},
+		"Comment":      {f0.TextFragments[0].Comment, f1.TextFragments[0].Comment},
+	}
+	for name, s := range shared {
+		if stringData(s[0]) != stringData(s[1]) {
+			t.Errorf("%s: strings do not share memory", name)
+		}
+	}
+	if stringData(f0.OldName) != stringData(f0.NewName) {
+		t.Errorf("OldName and NewName do not share memory")
+	}
+}
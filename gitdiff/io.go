@@ -1,6 +1,7 @@
 package gitdiff
 
 import (
+	"bytes"
 	"errors"
 	"io"
 )
@@ -138,6 +139,90 @@ func (r *lineReaderAt) readBytes(line, n int64) (b []byte, offset int64, err err
 	return b, offset, nil
 }
 
+// LineReader is a LineReaderAt and io.ReaderAt for data that is already in
+// memory. Lines returned by ReadLinesAt share memory with the data instead of
+// being copied and the line index is built only as far as the lines that are
+// requested. Create one with NewLineReaderFromBytes or NewLineReaderFromString.
+// A LineReader is not safe for concurrent use.
+type LineReader struct {
+	data []byte
+
+	// index contains the offset of the end of each indexed line and next is
+	// the offset where indexing continues
+	index []int64
+	next  int64
+}
+
+// NewLineReaderFromBytes returns a LineReader for b. The caller must not
+// modify b while the reader is in use.
+func NewLineReaderFromBytes(b []byte) *LineReader {
+	return &LineReader{data: b}
+}
+
+// NewLineReaderFromString returns a LineReader for s. The string is copied
+// once, because lines are returned as byte slices.
+func NewLineReaderFromString(s string) *LineReader {
+	return &LineReader{data: []byte(s)}
+}
+
+// ReadLinesAt implements LineReaderAt. The returned lines must not be
+// modified.
+func (r *LineReader) ReadLinesAt(lines [][]byte, offset int64) (n int, err error) {
+	if offset < 0 {
+		return 0, errors.New("ReadLinesAt: negative offset")
+	}
+	if len(lines) == 0 {
+		return 0, nil
+	}
+
+	r.indexTo(offset + int64(len(lines)))
+	for n = 0; n < len(lines) && offset+int64(n) < int64(len(r.index)); n++ {
+		lineno := offset + int64(n)
+		start := int64(0)
+		if lineno > 0 {
+			start = r.index[lineno-1]
+		}
+		lines[n] = r.data[start:r.index[lineno]:r.index[lineno]]
+	}
+
+	if n < len(lines) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *LineReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("ReadAt: negative offset")
+	}
+	if off >= int64(len(r.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Len returns the length of the data in bytes.
+func (r *LineReader) Len() int64 {
+	return int64(len(r.data))
+}
+
+func (r *LineReader) indexTo(line int64) {
+	for int64(len(r.index)) < line && r.next < int64(len(r.data)) {
+		i := bytes.IndexByte(r.data[r.next:], '\n')
+		if i < 0 {
+			r.next = int64(len(r.data))
+		} else {
+			r.next += int64(i) + 1
+		}
+		r.index = append(r.index, r.next)
+	}
+}
+
 func isLen(r io.ReaderAt, n int64) (bool, error) {
 	off := n - 1
 	if off < 0 {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -168,6 +169,108 @@ func TestLineReaderAt(t *testing.T) {
 	}
 }
 
+func TestNewLineReader(t *testing.T) {
+	const input = "line 1\nline 2\nline 3\nlast"
+
+	tests := map[string]struct {
+		Offset int64
+		Count  int
+		Lines  []string
+		Err    error
+	}{
+		"readLines": {
+			Offset: 0,
+			Count:  2,
+			Lines:  []string{"line 1\n", "line 2\n"},
+		},
+		"readLastLine": {
+			Offset: 3,
+			Count:  1,
+			Lines:  []string{"last"},
+		},
+		"readThroughEOF": {
+			Offset: 2,
+			Count:  4,
+			Lines:  []string{"line 3\n", "last"},
+			Err:    io.EOF,
+		},
+		"offsetAfterEOF": {
+			Offset: 6,
+			Count:  1,
+			Err:    io.EOF,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			readers := map[string]LineReaderAt{
+				"bytes":  NewLineReaderFromBytes([]byte(input)),
+				"string": NewLineReaderFromString(input),
+			}
+			for rname, r := range readers {
+				lines := make([][]byte, test.Count)
+				n, err := r.ReadLinesAt(lines, test.Offset)
+				if err != test.Err {
+					t.Fatalf("%s: incorrect error: expected %v, actual %v", rname, test.Err, err)
+				}
+				if n != len(test.Lines) {
+					t.Fatalf("%s: incorrect number of lines read: expected %d, actual %d", rname, len(test.Lines), n)
+				}
+				for i, line := range test.Lines {
+					if string(lines[i]) != line {
+						t.Errorf("%s: incorrect content in line %d: expected %q, actual %q", rname, i, line, lines[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	r := NewLineReaderFromString("line 1\nline 2\nlast")
+
+	lines := make([][]byte, 2)
+	if n, err := r.ReadLinesAt(lines, 1); err != nil || n != 2 {
+		t.Fatalf("incorrect read: %d lines, %v", n, err)
+	}
+	if string(lines[0]) != "line 2\n" || string(lines[1]) != "last" {
+		t.Errorf("incorrect lines: %q", lines)
+	}
+	if len(r.index) != 3 {
+		t.Errorf("incorrect index size: expected 3, actual %d", len(r.index))
+	}
+
+	// lines must not allow appending into the data of the next line
+	if cap(lines[0]) != len(lines[0]) {
+		t.Errorf("line has extra capacity: %d > %d", cap(lines[0]), len(lines[0]))
+	}
+
+	p := make([]byte, 6)
+	if n, err := r.ReadAt(p, 14); err != io.EOF || string(p[:n]) != "last" {
+		t.Errorf("incorrect ReadAt: %q, %v", p[:n], err)
+	}
+
+	lazy := NewLineReaderFromBytes([]byte("a\nb\nc\nd\n"))
+	if _, err := lazy.ReadLinesAt(make([][]byte, 1), 0); err != nil {
+		t.Fatalf("unexpected error reading lines: %v", err)
+	}
+	if len(lazy.index) != 1 {
+		t.Errorf("index built past the requested lines: %d lines", len(lazy.index))
+	}
+
+	files, _, err := ParseAll(strings.NewReader("--- a/f\n+++ b/f\n@@ -2 +2 @@\n-b\n+B\n"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	var dst bytes.Buffer
+	if err := Apply(&dst, lazy, files[0]); err != nil {
+		t.Fatalf("unexpected error applying patch: %v", err)
+	}
+	if dst.String() != "a\nB\nc\nd\n" {
+		t.Errorf("incorrect result: %q", dst.String())
+	}
+}
+
 func TestCopyFrom(t *testing.T) {
 	tests := map[string]struct {
 		Bytes  int64
@@ -0,0 +1,40 @@
+package gitdiff
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ParseResult is the JSON document returned by ParseToJSON.
+type ParseResult struct {
+	Preamble string  `json:"preamble"`
+	Files    []*File `json:"files"`
+
+	// Error is the message of the error that stopped parsing, or empty if the
+	// whole patch was parsed. Files contains the files parsed before the error.
+	Error string `json:"error,omitempty"`
+}
+
+// ParseToJSON parses patch with ParseAll and returns the result as a JSON
+// encoded ParseResult. It never fails, which makes it suitable for callers
+// that can only exchange strings, such as JavaScript code calling into a
+// js/wasm build of this package. Files use the default JSON encoding of the
+// File type: modes are numbers and line operations are the numeric values of
+// OpContext, OpDelete, and OpAdd.
+func ParseToJSON(patch string) string {
+	files, preamble, err := ParseAll(strings.NewReader(patch))
+
+	res := ParseResult{Preamble: preamble, Files: files}
+	if res.Files == nil {
+		res.Files = []*File{}
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		b, _ = json.Marshal(ParseResult{Files: []*File{}, Error: err.Error()})
+	}
+	return string(b)
+}
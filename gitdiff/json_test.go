@@ -0,0 +1,65 @@
+package gitdiff
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseToJSON(t *testing.T) {
+	tests := map[string]struct {
+		Input    string
+		Files    int
+		Preamble string
+		Err      bool
+	}{
+		"empty": {
+			Input: "",
+		},
+		"twoFiles": {
+			Input: "From: Morton Haypenny <mhaypenny@example.com>\n\n" +
+				"diff --git a/a.txt b/a.txt\n" +
+				"deleted file mode 100644\n" +
+				"diff --git a/b.txt b/b.txt\n" +
+				"--- a/b.txt\n" +
+				"+++ b/b.txt\n" +
+				"@@ -1 +1 @@\n" +
+				"-old\n" +
+				"+new\n",
+			Files:    2,
+			Preamble: "From: Morton Haypenny <mhaypenny@example.com>\n\n",
+		},
+		"invalid": {
+			Input: "diff --git a/a.txt b/a.txt\n" +
+				"deleted file mode 100644\n" +
+				"diff --git a/b.txt b/b.txt\n" +
+				"--- a/b.txt\n" +
+				"+++ b/b.txt\n" +
+				"@@ -1,2 +1 @@\n" +
+				"-old\n",
+			Files: 1,
+			Err:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var res ParseResult
+			if err := json.Unmarshal([]byte(ParseToJSON(test.Input)), &res); err != nil {
+				t.Fatalf("unexpected error decoding result: %v", err)
+			}
+
+			if test.Err != (res.Error != "") {
+				t.Errorf("incorrect error: expected error %t, actual %q", test.Err, res.Error)
+			}
+			if res.Files == nil {
+				t.Errorf("files are null instead of an empty list")
+			}
+			if len(res.Files) != test.Files {
+				t.Fatalf("incorrect number of files: expected %d, actual %d", test.Files, len(res.Files))
+			}
+			if res.Preamble != test.Preamble {
+				t.Errorf("incorrect preamble\nexpected: %q\n  actual: %q", test.Preamble, res.Preamble)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package gitdiff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const defaultTabWidth = 8
+
+// MarkKind identifies the kind of text in a line marked by LayoutLine.
+type MarkKind int
+
+const (
+	// MarkTab marks a tab character.
+	MarkTab MarkKind = iota
+	// MarkTrailingSpace marks the whitespace at the end of a line.
+	MarkTrailingSpace
+	// MarkNonPrintable marks a control character, a space character other
+	// than U+0020, or a byte that is not valid UTF-8.
+	MarkNonPrintable
+)
+
+func (k MarkKind) String() string {
+	switch k {
+	case MarkTab:
+		return "tab"
+	case MarkTrailingSpace:
+		return "trailing space"
+	case MarkNonPrintable:
+		return "non-printable"
+	}
+	return "unknown"
+}
+
+// Mark is a part of a line that renderers may want to show differently, such
+// as with a visible symbol for whitespace.
+type Mark struct {
+	Kind MarkKind
+
+	// Start and End are the byte offsets of the marked text in the line.
+	Start int
+	End   int
+
+	// Column is the zero-indexed display column where the marked text starts
+	// and Width is the number of columns it occupies after expanding tabs.
+	Column int
+	Width  int
+}
+
+// LineLayout describes how a line is displayed.
+type LineLayout struct {
+	// Width is the number of columns the line occupies after expanding tabs,
+	// not including its line ending. Every character other than a tab takes
+	// one column.
+	Width int
+
+	// Marks contains the tabs and non-printable characters of the line in
+	// order, followed by the trailing whitespace of the line, if any. A
+	// trailing whitespace mark may contain tab and non-printable marks.
+	Marks []Mark
+}
+
+// LayoutOptions configures LayoutLine.
+type LayoutOptions struct {
+	// TabWidth is the distance between tab stops. If zero, 8 is used.
+	TabWidth int
+}
+
+func (opts LayoutOptions) tabWidth() int {
+	if opts.TabWidth <= 0 {
+		return defaultTabWidth
+	}
+	return opts.TabWidth
+}
+
+// LayoutLine computes the display layout of line, so that renderers can show
+// whitespace and special characters precisely without scanning the content
+// again. The trailing newline of the line, if present, is ignored.
+func LayoutLine(line string, opts LayoutOptions) LineLayout {
+	line = strings.TrimSuffix(line, "\n")
+	tabWidth := opts.tabWidth()
+
+	var layout LineLayout
+	col := 0
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+
+		width := 1
+		if r == '\t' {
+			width = tabWidth - col%tabWidth
+			layout.Marks = append(layout.Marks, Mark{MarkTab, i, i + size, col, width})
+		} else if !isPrintable(r, size) {
+			layout.Marks = append(layout.Marks, Mark{MarkNonPrintable, i, i + size, col, width})
+		}
+
+		col += width
+		i += size
+	}
+	layout.Width = col
+
+	if trimmed := strings.TrimRightFunc(line, unicode.IsSpace); len(trimmed) < len(line) {
+		start := displayWidth(trimmed, tabWidth)
+		layout.Marks = append(layout.Marks, Mark{MarkTrailingSpace, len(trimmed), len(line), start, col - start})
+	}
+	return layout
+}
+
+// Layout computes the display layout of each line of the fragment.
+func (f *TextFragment) Layout(opts LayoutOptions) []LineLayout {
+	layouts := make([]LineLayout, len(f.Lines))
+	for i, line := range f.Lines {
+		layouts[i] = LayoutLine(line.Line, opts)
+	}
+	return layouts
+}
+
+func isPrintable(r rune, size int) bool {
+	if r == utf8.RuneError && size == 1 {
+		return false
+	}
+	return r == ' ' || unicode.IsPrint(r)
+}
+
+func displayWidth(s string, tabWidth int) int {
+	col := 0
+	for _, r := range s {
+		if r == '\t' {
+			col += tabWidth - col%tabWidth
+		} else {
+			col++
+		}
+	}
+	return col
+}
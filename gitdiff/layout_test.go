@@ -0,0 +1,103 @@
+package gitdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayoutLine(t *testing.T) {
+	tests := map[string]struct {
+		Line     string
+		TabWidth int
+		Layout   LineLayout
+	}{
+		"plain": {
+			Line:   "hello, world\n",
+			Layout: LineLayout{Width: 12},
+		},
+		"empty": {
+			Line:   "\n",
+			Layout: LineLayout{},
+		},
+		"tabs": {
+			Line: "\tif x {\tfoo\n",
+			Layout: LineLayout{
+				Width: 19,
+				Marks: []Mark{
+					{Kind: MarkTab, Start: 0, End: 1, Column: 0, Width: 8},
+					{Kind: MarkTab, Start: 7, End: 8, Column: 14, Width: 2},
+				},
+			},
+		},
+		"tabWidth": {
+			Line:     "a\tb\n",
+			TabWidth: 4,
+			Layout: LineLayout{
+				Width: 5,
+				Marks: []Mark{
+					{Kind: MarkTab, Start: 1, End: 2, Column: 1, Width: 3},
+				},
+			},
+		},
+		"trailingSpace": {
+			Line: "text \t\n",
+			Layout: LineLayout{
+				Width: 8,
+				Marks: []Mark{
+					{Kind: MarkTab, Start: 5, End: 6, Column: 5, Width: 3},
+					{Kind: MarkTrailingSpace, Start: 4, End: 6, Column: 4, Width: 4},
+				},
+			},
+		},
+		"carriageReturn": {
+			Line: "text\r\n",
+			Layout: LineLayout{
+				Width: 5,
+				Marks: []Mark{
+					{Kind: MarkNonPrintable, Start: 4, End: 5, Column: 4, Width: 1},
+					{Kind: MarkTrailingSpace, Start: 4, End: 5, Column: 4, Width: 1},
+				},
+			},
+		},
+		"nonPrintable": {
+			Line: "a\x00\u00e9\u00a0b\xffc",
+			Layout: LineLayout{
+				Width: 7,
+				Marks: []Mark{
+					{Kind: MarkNonPrintable, Start: 1, End: 2, Column: 1, Width: 1},
+					{Kind: MarkNonPrintable, Start: 4, End: 6, Column: 3, Width: 1},
+					{Kind: MarkNonPrintable, Start: 7, End: 8, Column: 5, Width: 1},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			layout := LayoutLine(test.Line, LayoutOptions{TabWidth: test.TabWidth})
+			if !reflect.DeepEqual(test.Layout, layout) {
+				t.Errorf("incorrect layout\nexpected: %+v\n  actual: %+v", test.Layout, layout)
+			}
+		})
+	}
+}
+
+func TestTextFragmentLayout(t *testing.T) {
+	frag := &TextFragment{
+		Lines: []Line{
+			{OpContext, "\tcontext\n"},
+			{OpAdd, "added \n"},
+		},
+	}
+
+	layouts := frag.Layout(LayoutOptions{})
+	if len(layouts) != 2 {
+		t.Fatalf("incorrect number of layouts: expected 2, actual %d", len(layouts))
+	}
+	if layouts[0].Width != 15 || layouts[1].Width != 6 {
+		t.Errorf("incorrect widths: %d, %d", layouts[0].Width, layouts[1].Width)
+	}
+	if len(layouts[1].Marks) != 1 || layouts[1].Marks[0].Kind != MarkTrailingSpace {
+		t.Errorf("incorrect marks: %+v", layouts[1].Marks)
+	}
+}
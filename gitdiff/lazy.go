@@ -0,0 +1,87 @@
+package gitdiff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LazyFile is a file from a patch indexed by IndexPatch. The embedded File
+// has all of the metadata of the file, and its text fragments have their
+// positions and line counts, but not their lines. Binary fragments are parsed
+// completely.
+type LazyFile struct {
+	*File
+
+	// Hunks contains the location of each hunk of the file in the patch.
+	Hunks []*LazyHunk
+}
+
+// LazyHunk is the location of a hunk in a patch. Use Load to parse its lines.
+type LazyHunk struct {
+	// Fragments contains the fragments of the hunk, without their lines. It
+	// has one fragment per parent for combined diffs and one fragment
+	// otherwise.
+	Fragments []*TextFragment
+
+	// Offset is the byte offset of the hunk header in the patch and Size is
+	// the length in bytes of the hunk, including the header.
+	Offset int64
+	Size   int64
+}
+
+// IndexPatch reads a patch and returns its files without the lines of their
+// text fragments, along with the content before the first file. This uses
+// much less memory than ParseAll for large patches. Viewers can then parse
+// individual hunks on demand with LazyHunk.Load. Like ParseAll, IndexPatch
+// stops at the first error and returns the files indexed before it.
+func IndexPatch(r io.Reader) ([]*LazyFile, string, error) {
+	hunks := make(map[*TextFragment]*LazyHunk)
+
+	p := newParser(r)
+	p.discardLines = true
+	p.onFragment = func(frags []*TextFragment, start, end int64) {
+		hunks[frags[0]] = &LazyHunk{Fragments: frags, Offset: start, Size: end - start}
+	}
+
+	files, preamble, err := p.ParseFiles()
+	setPreambleInfo(files, preamble)
+
+	lazy := make([]*LazyFile, len(files))
+	for i, f := range files {
+		lf := &LazyFile{File: f}
+		for _, frag := range f.TextFragments {
+			if h, ok := hunks[frag]; ok {
+				lf.Hunks = append(lf.Hunks, h)
+			}
+		}
+		lazy[i] = lf
+	}
+	return lazy, preamble, err
+}
+
+// Load parses the hunk from the patch in r, which must contain the same data
+// that was passed to IndexPatch.
+func (h *LazyHunk) Load(r io.ReaderAt) (*Hunk, error) {
+	p := newParser(io.NewSectionReader(r, h.Offset, h.Size))
+	if err := p.Next(); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	f := &File{}
+	if strings.HasPrefix(p.Line(0), "@@@") {
+		if _, err := p.ParseCombinedTextFragments(f); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := p.ParseTextFragments(f); err != nil {
+			return nil, err
+		}
+	}
+
+	hunks := f.Hunks()
+	if len(hunks) != 1 {
+		return nil, fmt.Errorf("gitdiff: no hunk at offset %d", h.Offset)
+	}
+	return hunks[0], nil
+}
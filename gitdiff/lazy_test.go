@@ -0,0 +1,79 @@
+package gitdiff
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndexPatch(t *testing.T) {
+	patches, err := filepath.Glob("testdata/*.patch")
+	if err != nil {
+		t.Fatalf("unexpected error listing patches: %v", err)
+	}
+
+	for _, patch := range patches {
+		t.Run(filepath.Base(patch), func(t *testing.T) {
+			data, err := ioutil.ReadFile(patch)
+			if err != nil {
+				t.Fatalf("unexpected error reading patch: %v", err)
+			}
+
+			files, preamble, err := ParseAll(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			lazy, lazyPreamble, err := IndexPatch(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("unexpected error indexing patch: %v", err)
+			}
+			if preamble != lazyPreamble {
+				t.Errorf("incorrect preamble\nexpected: %q\n  actual: %q", preamble, lazyPreamble)
+			}
+			if len(lazy) != len(files) {
+				t.Fatalf("incorrect number of files: expected %d, actual %d", len(files), len(lazy))
+			}
+
+			for i, f := range files {
+				lf := lazy[i]
+				if lf.NewName != f.NewName || !reflect.DeepEqual(lf.PatchHeader, f.PatchHeader) {
+					t.Errorf("file %d: incorrect metadata: %+v", i, lf.File)
+				}
+
+				hunks := f.Hunks()
+				if len(lf.Hunks) != len(hunks) {
+					t.Fatalf("file %d: incorrect number of hunks: expected %d, actual %d", i, len(hunks), len(lf.Hunks))
+				}
+				for j, h := range lf.Hunks {
+					for _, frag := range h.Fragments {
+						if frag.Lines != nil {
+							t.Errorf("file %d, hunk %d: indexed fragment has lines", i, j)
+						}
+					}
+
+					loaded, err := h.Load(bytes.NewReader(data))
+					if err != nil {
+						t.Fatalf("file %d, hunk %d: unexpected error loading hunk: %v", i, j, err)
+					}
+					if !reflect.DeepEqual(hunks[j], loaded) {
+						t.Errorf("file %d, hunk %d: incorrect hunk\nexpected: %+v\n  actual: %+v", i, j, hunks[j], loaded)
+					}
+					if raw := string(data[h.Offset : h.Offset+h.Size]); raw != hunks[j].String() {
+						t.Errorf("file %d, hunk %d: incorrect location\nexpected: %q\n  actual: %q", i, j, hunks[j].String(), raw)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLazyHunkLoadInvalid(t *testing.T) {
+	data := strings.NewReader("diff --git a/file.txt b/file.txt\n")
+	if _, err := (&LazyHunk{Offset: 0, Size: 10}).Load(data); err == nil {
+		t.Fatal("expected error loading hunk, but got nil")
+	}
+}
@@ -0,0 +1,262 @@
+package gitdiff
+
+import (
+	"io"
+	"strings"
+)
+
+// TokenKind identifies the kind of line in a patch returned by a Lexer.
+type TokenKind int
+
+const (
+	// TokenText is a line outside of any file, such as a commit message or
+	// an email header.
+	TokenText TokenKind = iota
+	// TokenFileHeader is a line that starts a file or names its old or new
+	// version, like "diff --git", "---", and "+++" lines.
+	TokenFileHeader
+	// TokenExtendedHeader is any other line in a file header, such as an
+	// index, mode, or rename line.
+	TokenExtendedHeader
+	// TokenHunkHeader is the "@@" line that starts a text fragment.
+	TokenHunkHeader
+	// TokenContextLine is an unchanged line in a text fragment.
+	TokenContextLine
+	// TokenAddLine is an added line in a text fragment.
+	TokenAddLine
+	// TokenDeleteLine is a deleted line in a text fragment.
+	TokenDeleteLine
+	// TokenNoEOF is a "\ No newline at end of file" marker.
+	TokenNoEOF
+	// TokenBinaryData is a line of a binary patch, including the line that
+	// starts the patch and the "Binary files differ" message.
+	TokenBinaryData
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenText:
+		return "text"
+	case TokenFileHeader:
+		return "file header"
+	case TokenExtendedHeader:
+		return "extended header"
+	case TokenHunkHeader:
+		return "hunk header"
+	case TokenContextLine:
+		return "context line"
+	case TokenAddLine:
+		return "add line"
+	case TokenDeleteLine:
+		return "delete line"
+	case TokenNoEOF:
+		return "no newline"
+	case TokenBinaryData:
+		return "binary data"
+	}
+	return "unknown"
+}
+
+// Token is a line of a patch classified by a Lexer.
+type Token struct {
+	Kind TokenKind
+
+	// Text is the content of the line, including the trailing newline if
+	// present.
+	Text string
+
+	// Line is the one-indexed line number of the token in the input.
+	Line int64
+}
+
+const (
+	lexText = iota
+	lexHeader
+	lexHunk
+	lexBinary
+)
+
+// Lexer splits a patch into a stream of classified lines, for consumers like
+// syntax highlighters that want tokens instead of parsed files. Every line of
+// the input is returned as exactly one token, so concatenating the text of
+// the tokens reproduces the input. Unlike the parser, a Lexer never fails on
+// invalid content: lines that do not fit the structure of a patch are
+// returned as text.
+type Lexer struct {
+	p       *parser
+	started bool
+	err     error
+
+	state int
+
+	// oldLines has the number of lines remaining from each parent in the
+	// current hunk and newLines the number of lines remaining in the result
+	oldLines []int64
+	newLines int64
+}
+
+// NewLexer returns a Lexer that reads a patch from r.
+func NewLexer(r io.Reader) *Lexer {
+	return &Lexer{p: newParser(r)}
+}
+
+// NextToken returns the next line of the patch. It returns io.EOF at the end
+// of the input and any error from reading the input.
+func (l *Lexer) NextToken() (Token, error) {
+	if l.err != nil {
+		return Token{}, l.err
+	}
+	if !l.started {
+		l.started = true
+		if err := l.p.Next(); err != nil {
+			l.err = err
+			return Token{}, err
+		}
+	}
+
+	line := l.p.Line(0)
+	tok := Token{Kind: l.classify(line, l.p.Line(1)), Text: line, Line: l.p.lineno}
+
+	// report errors, including the end of the input, on the next call
+	if err := l.p.Next(); err != nil {
+		l.err = err
+	}
+	return tok, nil
+}
+
+func (l *Lexer) classify(line, next string) TokenKind {
+	switch l.state {
+	case lexHunk:
+		if kind, ok := l.hunkLine(line); ok {
+			return kind
+		}
+	case lexBinary:
+		if isBinaryDataLine(line) {
+			return TokenBinaryData
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(line, "diff "):
+		l.state = lexHeader
+		return TokenFileHeader
+	case strings.HasPrefix(line, "--- ") && strings.HasPrefix(next, "+++ "):
+		l.state = lexHeader
+		return TokenFileHeader
+	case l.state == lexText:
+		// other headers only appear in files
+	case strings.HasPrefix(line, "@@"):
+		if l.startHunk(line) {
+			return TokenHunkHeader
+		}
+	case l.state != lexHeader:
+		// the remaining lines only appear before the first hunk
+	case strings.HasPrefix(line, "+++ "):
+		return TokenFileHeader
+	case strings.HasPrefix(line, "GIT binary patch") || strings.HasPrefix(line, "Binary files "):
+		l.state = lexBinary
+		return TokenBinaryData
+	default:
+		return TokenExtendedHeader
+	}
+
+	l.state = lexText
+	return TokenText
+}
+
+// startHunk parses the line counts of a text or combined fragment header and
+// returns false if the header is invalid.
+func (l *Lexer) startHunk(line string) bool {
+	marks := strings.IndexFunc(line, func(c rune) bool { return c != '@' })
+	if marks < 2 {
+		return false
+	}
+	endMark := " " + line[:marks]
+
+	end := strings.Index(line[marks:], endMark)
+	if end < 0 {
+		return false
+	}
+	ranges := strings.Fields(line[marks : marks+end])
+	if len(ranges) != marks {
+		return false
+	}
+
+	l.oldLines = make([]int64, marks-1)
+	for i, r := range ranges {
+		prefix := "-"
+		if i == len(ranges)-1 {
+			prefix = "+"
+		}
+		if !strings.HasPrefix(r, prefix) {
+			return false
+		}
+		_, lines, err := parseRange(r[1:])
+		if err != nil {
+			return false
+		}
+		if i < len(l.oldLines) {
+			l.oldLines[i] = lines
+		} else {
+			l.newLines = lines
+		}
+	}
+	l.state = lexHunk
+	return true
+}
+
+// hunkLine classifies a line in a hunk and returns false if the hunk has
+// ended.
+func (l *Lexer) hunkLine(line string) (TokenKind, bool) {
+	if strings.HasPrefix(line, "\\") {
+		return TokenNoEOF, true
+	}
+
+	remaining := l.newLines > 0
+	for _, n := range l.oldLines {
+		remaining = remaining || n > 0
+	}
+	if !remaining || len(line) < len(l.oldLines) {
+		return 0, false
+	}
+
+	ops := line[:len(l.oldLines)]
+	if strings.Trim(ops, " +-") != "" {
+		return 0, false
+	}
+	// a deleted line only appears in the parents with a '-'; other lines
+	// appear in the result and in the parents with a ' '
+	deleted := strings.Contains(ops, "-")
+	for i, op := range ops {
+		if op == '-' || (op == ' ' && !deleted) {
+			l.oldLines[i]--
+		}
+	}
+
+	switch {
+	case deleted:
+		return TokenDeleteLine, true
+	case strings.Contains(ops, "+"):
+		l.newLines--
+		return TokenAddLine, true
+	}
+	l.newLines--
+	return TokenContextLine, true
+}
+
+// isBinaryDataLine returns true if line could be part of the data of a binary
+// patch.
+func isBinaryDataLine(line string) bool {
+	if line == "\n" || strings.HasPrefix(line, "literal ") || strings.HasPrefix(line, "delta ") {
+		return true
+	}
+	if len(line) < 2 || !(('A' <= line[0] && line[0] <= 'Z') || ('a' <= line[0] && line[0] <= 'z')) {
+		return false
+	}
+	for _, c := range []byte(strings.TrimSuffix(line[1:], "\n")) {
+		if _, ok := b85Table[c]; !ok {
+			return false
+		}
+	}
+	return true
+}
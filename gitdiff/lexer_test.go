@@ -0,0 +1,171 @@
+package gitdiff
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	tests := map[string]struct {
+		Input string
+		Kinds []TokenKind
+	}{
+		"gitPatch": {
+			Input: `From 61f5cd90bed4d204ee3feb3aa41ee91d4734855b Mon Sep 17 00:00:00 2001
+Subject: [PATCH] A change
+
+---
+diff --git a/file.txt b/file.txt
+index 1c23fcc..40a1b33 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@ func main()
+ a
+--b
++-c
+ d
+\ No newline at end of file
+-- 
+2.20.1
+`,
+			Kinds: []TokenKind{
+				TokenText, TokenText, TokenText, TokenText,
+				TokenFileHeader, TokenExtendedHeader, TokenFileHeader, TokenFileHeader,
+				TokenHunkHeader, TokenContextLine, TokenDeleteLine, TokenAddLine, TokenContextLine, TokenNoEOF,
+				TokenText, TokenText,
+			},
+		},
+		"unifiedDiff": {
+			Input: `--- a.txt
++++ b.txt
+@@ -1 +1 @@
+-a
++b
+--- c.txt
++++ d.txt
+@@ -1 +1,2 @@
+ c
++d
+`,
+			Kinds: []TokenKind{
+				TokenFileHeader, TokenFileHeader, TokenHunkHeader, TokenDeleteLine, TokenAddLine,
+				TokenFileHeader, TokenFileHeader, TokenHunkHeader, TokenContextLine, TokenAddLine,
+			},
+		},
+		"combined": {
+			Input: `diff --cc file.txt
+index 1111111,2222222..3333333
+--- a/file.txt
++++ b/file.txt
+@@@ -1,2 -1,2 +1,2 @@@
+  a
+- b
+ -c
+++d
+`,
+			Kinds: []TokenKind{
+				TokenFileHeader, TokenExtendedHeader, TokenFileHeader, TokenFileHeader,
+				TokenHunkHeader, TokenContextLine, TokenDeleteLine, TokenDeleteLine, TokenAddLine,
+			},
+		},
+		"binary": {
+			Input: `diff --git a/file.bin b/file.bin
+new file mode 100644
+index 0000000..a8a7d4b
+GIT binary patch
+literal 5
+Mc${NkU}WL~000F500RI3
+
+literal 0
+HcmV?d00001
+
+diff --git a/other.bin b/other.bin
+index 1111111..2222222 100644
+Binary files a/other.bin and b/other.bin differ
+`,
+			Kinds: []TokenKind{
+				TokenFileHeader, TokenExtendedHeader, TokenExtendedHeader,
+				TokenBinaryData, TokenBinaryData, TokenBinaryData, TokenBinaryData, TokenBinaryData, TokenBinaryData, TokenBinaryData,
+				TokenFileHeader, TokenExtendedHeader, TokenBinaryData,
+			},
+		},
+		"invalidHunk": {
+			Input: `diff --git a/file.txt b/file.txt
+@@ -1,x +1 @@
+ a
+`,
+			Kinds: []TokenKind{TokenFileHeader, TokenText, TokenText},
+		},
+		"shortHunk": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ a
+commit 5d9790fec7d95aa223f3d20936340bf55ff3dcbe
+`,
+			Kinds: []TokenKind{
+				TokenFileHeader, TokenFileHeader, TokenFileHeader, TokenHunkHeader, TokenContextLine, TokenText,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tokens := lexAll(t, test.Input)
+
+			kinds := make([]TokenKind, len(tokens))
+			for i, tok := range tokens {
+				kinds[i] = tok.Kind
+				if tok.Line != int64(i+1) {
+					t.Errorf("token %d: incorrect line: expected %d, actual %d", i, i+1, tok.Line)
+				}
+			}
+			if !reflect.DeepEqual(test.Kinds, kinds) {
+				t.Errorf("incorrect token kinds\nexpected: %v\n  actual: %v", test.Kinds, kinds)
+			}
+		})
+	}
+}
+
+func TestLexerReproducesInput(t *testing.T) {
+	patches, err := filepath.Glob("testdata/*.patch")
+	if err != nil {
+		t.Fatalf("unexpected error listing patches: %v", err)
+	}
+
+	for _, patch := range patches {
+		data, err := ioutil.ReadFile(patch)
+		if err != nil {
+			t.Fatalf("unexpected error reading patch: %v", err)
+		}
+
+		var b bytes.Buffer
+		for _, tok := range lexAll(t, string(data)) {
+			b.WriteString(tok.Text)
+		}
+		if !bytes.Equal(data, b.Bytes()) {
+			t.Errorf("%s: tokens do not reproduce the input", patch)
+		}
+	}
+}
+
+func lexAll(t *testing.T, input string) []Token {
+	var tokens []Token
+	l := NewLexer(strings.NewReader(input))
+	for {
+		tok, err := l.NextToken()
+		if err == io.EOF {
+			return tokens
+		}
+		if err != nil {
+			t.Fatalf("unexpected error reading token: %v", err)
+		}
+		tokens = append(tokens, tok)
+	}
+}
@@ -0,0 +1,152 @@
+package gitdiff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultHeaderLines is the number of lines LicensePolicy searches for a
+// required header if HeaderLines is not set.
+const defaultHeaderLines = 20
+
+// LicensePolicy describes the license text required in or forbidden from new
+// files that match a path pattern.
+type LicensePolicy struct {
+	// Pattern selects the files the policy applies to, using the same syntax
+	// as patterns in a .gitattributes file. An empty pattern matches all
+	// files.
+	Pattern string
+
+	// Required, if set, must match one of the first HeaderLines lines of
+	// each new file.
+	Required *regexp.Regexp
+
+	// HeaderLines is the number of lines searched for the required header.
+	// If zero, the first 20 lines are searched.
+	HeaderLines int
+
+	// Forbidden patterns must not match any line of a new file.
+	Forbidden []*regexp.Regexp
+}
+
+// LicenseViolationKind identifies the type of a LicenseViolation.
+type LicenseViolationKind int
+
+const (
+	// LicenseMissingHeader means a new file did not contain a required header.
+	LicenseMissingHeader LicenseViolationKind = iota
+
+	// LicenseForbiddenText means a new file contained forbidden text.
+	LicenseForbiddenText
+)
+
+func (k LicenseViolationKind) String() string {
+	switch k {
+	case LicenseMissingHeader:
+		return "missing-header"
+	case LicenseForbiddenText:
+		return "forbidden-text"
+	}
+	return fmt.Sprintf("LicenseViolationKind(%d)", int(k))
+}
+
+// LicenseViolation is a new file that does not follow a LicensePolicy.
+type LicenseViolation struct {
+	Kind LicenseViolationKind
+
+	// File is the name of the new file.
+	File string
+
+	// Line is the line of the forbidden text, or zero for missing headers.
+	Line int64
+
+	// Pattern is the Pattern of the policy that was violated.
+	Pattern string
+
+	// Text is the expression that was required or forbidden.
+	Text string
+}
+
+func (v LicenseViolation) String() string {
+	if v.Kind == LicenseMissingHeader {
+		return fmt.Sprintf("%s: missing required header %q", v.File, v.Text)
+	}
+	return fmt.Sprintf("%s:%d: contains forbidden text %q", v.File, v.Line, v.Text)
+}
+
+// CheckLicenses checks the content added by new text files against all of
+// the policies that match their names. Other files are ignored. It returns an
+// error if a policy has an invalid pattern.
+func CheckLicenses(files []*File, policies []LicensePolicy) ([]LicenseViolation, error) {
+	patterns := make([]*regexp.Regexp, len(policies))
+	for i, policy := range policies {
+		if policy.Pattern == "" {
+			continue
+		}
+		re, err := attrPatternRegexp(policy.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("gitdiff: invalid license pattern: %s: %v", policy.Pattern, err)
+		}
+		patterns[i] = re
+	}
+
+	var violations []LicenseViolation
+	for _, f := range files {
+		if !f.IsNew || f.IsBinary {
+			continue
+		}
+		for i, policy := range policies {
+			if patterns[i] != nil && !patterns[i].MatchString(f.NewName) {
+				continue
+			}
+			violations = append(violations, policy.check(f)...)
+		}
+	}
+	return violations, nil
+}
+
+func (policy LicensePolicy) check(f *File) []LicenseViolation {
+	headerLines := policy.HeaderLines
+	if headerLines <= 0 {
+		headerLines = defaultHeaderLines
+	}
+
+	var violations []LicenseViolation
+	found := policy.Required == nil
+	for _, frag := range f.TextFragments {
+		lineno := frag.NewPosition
+		for _, line := range frag.Lines {
+			if line.Op != OpAdd {
+				continue
+			}
+			text := strings.TrimSuffix(line.Line, "\n")
+
+			if !found && lineno <= int64(headerLines) && policy.Required.MatchString(text) {
+				found = true
+			}
+			for _, re := range policy.Forbidden {
+				if re.MatchString(text) {
+					violations = append(violations, LicenseViolation{
+						Kind:    LicenseForbiddenText,
+						File:    f.NewName,
+						Line:    lineno,
+						Pattern: policy.Pattern,
+						Text:    re.String(),
+					})
+				}
+			}
+			lineno++
+		}
+	}
+
+	if !found {
+		violations = append([]LicenseViolation{{
+			Kind:    LicenseMissingHeader,
+			File:    f.NewName,
+			Pattern: policy.Pattern,
+			Text:    policy.Required.String(),
+		}}, violations...)
+	}
+	return violations
+}
@@ -0,0 +1,93 @@
+package gitdiff
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCheckLicenses(t *testing.T) {
+	newFile := func(name string, lines ...string) *File {
+		frag := &TextFragment{NewPosition: 1, NewLines: int64(len(lines))}
+		for _, line := range lines {
+			frag.Lines = append(frag.Lines, Line{OpAdd, line + "\n"})
+		}
+		return &File{NewName: name, IsNew: true, TextFragments: []*TextFragment{frag}}
+	}
+
+	spdx := regexp.MustCompile(`SPDX-License-Identifier: (MIT|Apache-2\.0)`)
+	gpl := regexp.MustCompile(`GNU General Public License`)
+
+	files := []*File{
+		newFile("main.go", "// SPDX-License-Identifier: MIT", "package main"),
+		newFile("lib/util.go", "package lib", "// GNU General Public License"),
+		newFile("docs/README.md", "# Docs", "GNU General Public License"),
+		{OldName: "old.go", NewName: "old.go", TextFragments: []*TextFragment{
+			{NewPosition: 1, NewLines: 1, Lines: []Line{{OpAdd, "package old\n"}}},
+		}},
+		{NewName: "logo.go", IsNew: true, IsBinary: true},
+	}
+
+	tests := map[string]struct {
+		Policies   []LicensePolicy
+		Violations []string
+		Err        bool
+	}{
+		"noPolicies": {},
+		"required": {
+			Policies: []LicensePolicy{{Pattern: "*.go", Required: spdx}},
+			Violations: []string{
+				`lib/util.go: missing required header "SPDX-License-Identifier: (MIT|Apache-2\\.0)"`,
+			},
+		},
+		"headerLines": {
+			Policies: []LicensePolicy{{Pattern: "*.go", Required: regexp.MustCompile(`^package`), HeaderLines: 1}},
+			Violations: []string{
+				`main.go: missing required header "^package"`,
+			},
+		},
+		"forbidden": {
+			Policies: []LicensePolicy{{Forbidden: []*regexp.Regexp{gpl}}},
+			Violations: []string{
+				`lib/util.go:2: contains forbidden text "GNU General Public License"`,
+				`docs/README.md:2: contains forbidden text "GNU General Public License"`,
+			},
+		},
+		"directoryPattern": {
+			Policies: []LicensePolicy{{Pattern: "docs/**", Required: spdx, Forbidden: []*regexp.Regexp{gpl}}},
+			Violations: []string{
+				`docs/README.md: missing required header "SPDX-License-Identifier: (MIT|Apache-2\\.0)"`,
+				`docs/README.md:2: contains forbidden text "GNU General Public License"`,
+			},
+		},
+		"invalidPattern": {
+			Policies: []LicensePolicy{{Pattern: "[z-a]"}},
+			Err:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			violations, err := CheckLicenses(files, test.Policies)
+			if test.Err {
+				if err == nil {
+					t.Fatal("expected error checking licenses, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error checking licenses: %v", err)
+			}
+
+			var actual []string
+			for _, v := range violations {
+				actual = append(actual, v.String())
+			}
+			if !reflect.DeepEqual(test.Violations, actual) {
+				t.Errorf("incorrect violations\nexpected: %s\n  actual: %s",
+					strings.Join(test.Violations, "; "), strings.Join(actual, "; "))
+			}
+		})
+	}
+}
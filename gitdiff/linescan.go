@@ -0,0 +1,127 @@
+package gitdiff
+
+import (
+	"bufio"
+	"io"
+)
+
+// LineScanner reads input one line at a time with a fixed window of lines
+// after the current line, like the parser in this package. It is meant for
+// parsing extensions to the patch format, such as custom headers, outside of
+// this package: a parsing function can look ahead at the next lines to decide
+// if it recognizes them before consuming them with Next.
+//
+// Lines include their trailing newline, if present, so only lines after the
+// end of the input are empty. Like the parser, if the first line ends in
+// CRLF, the carriage return is removed from every line that ends in CRLF, so
+// lines end in LF, and input with CR line terminators is an error. Call Next
+// to read the first line before using the other methods.
+type LineScanner struct {
+	r      stringReader
+	lines  []string
+	cr     []bool
+	crlf   bool
+	lineno int64
+	offset int64
+	eof    bool
+	err    error
+}
+
+// NewLineScanner returns a LineScanner that reads from r and can look ahead
+// up to lookahead lines after the current line. A lookahead of less than one
+// is treated as one.
+func NewLineScanner(r io.Reader, lookahead int) *LineScanner {
+	if lookahead < 1 {
+		lookahead = 1
+	}
+	sr, ok := r.(stringReader)
+	if !ok {
+		sr = bufio.NewReader(r)
+	}
+	return &LineScanner{r: sr, lines: make([]string, lookahead+1), cr: make([]bool, lookahead+1)}
+}
+
+// Next advances the scanner by one line. It returns io.EOF when there are no
+// more lines, or any other error encountered while reading.
+func (s *LineScanner) Next() error {
+	if s.eof {
+		return io.EOF
+	}
+
+	if s.lineno == 0 {
+		// on the first call, fill the lookahead window
+		for i := 1; i < len(s.lines); i++ {
+			if err := s.shift(); err != nil {
+				return err
+			}
+		}
+	} else {
+		s.offset += int64(len(s.lines[0]))
+		if s.cr[0] {
+			s.offset++
+		}
+	}
+
+	if err := s.shift(); err != nil {
+		return err
+	}
+	s.lineno++
+	if s.lines[0] == "" {
+		s.eof = true
+		return io.EOF
+	}
+	return nil
+}
+
+func (s *LineScanner) shift() error {
+	copy(s.lines, s.lines[1:])
+	copy(s.cr, s.cr[1:])
+	s.lines[len(s.lines)-1], s.cr[len(s.cr)-1] = "", false
+
+	if s.err != nil {
+		if s.err == io.EOF {
+			return nil
+		}
+		return s.err
+	}
+
+	line, err := s.r.ReadString('\n')
+	if s.lineno == 0 && s.lines[len(s.lines)-2] == "" {
+		// this is the first line of the input
+		crlf, cerr := detectCRLF(line)
+		if cerr != nil {
+			s.err = cerr
+			return cerr
+		}
+		s.crlf = crlf
+	}
+
+	s.lines[len(s.lines)-1], s.cr[len(s.cr)-1] = trimCR(line, s.crlf)
+	s.err = err
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Line returns a line without advancing the scanner. A delta of 0 returns the
+// current line and higher deltas return lines after it. It returns an empty
+// string if delta is larger than the lookahead of the scanner or if the line
+// is after the end of the input.
+func (s *LineScanner) Line(delta int) string {
+	if delta < 0 || delta >= len(s.lines) {
+		return ""
+	}
+	return s.lines[delta]
+}
+
+// Lineno returns the one-indexed number of the current line.
+func (s *LineScanner) Lineno() int64 {
+	return s.lineno
+}
+
+// Offset returns the byte offset of the current line in the input, counting
+// any carriage returns removed from earlier lines.
+func (s *LineScanner) Offset() int64 {
+	return s.offset
+}
@@ -0,0 +1,100 @@
+package gitdiff
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineScanner(t *testing.T) {
+	s := NewLineScanner(strings.NewReader("line 1\nline 2\nline 3\nline 4"), 2)
+
+	type state struct {
+		Lines  [4]string
+		Lineno int64
+		Offset int64
+	}
+	expected := []state{
+		{[4]string{"line 1\n", "line 2\n", "line 3\n", ""}, 1, 0},
+		{[4]string{"line 2\n", "line 3\n", "line 4", ""}, 2, 7},
+		{[4]string{"line 3\n", "line 4", "", ""}, 3, 14},
+		{[4]string{"line 4", "", "", ""}, 4, 21},
+	}
+
+	for i, exp := range expected {
+		if err := s.Next(); err != nil {
+			t.Fatalf("line %d: unexpected error: %v", i+1, err)
+		}
+		var actual state
+		for d := range actual.Lines {
+			actual.Lines[d] = s.Line(d)
+		}
+		actual.Lineno, actual.Offset = s.Lineno(), s.Offset()
+		if actual != exp {
+			t.Errorf("line %d: incorrect state\nexpected: %+v\n  actual: %+v", i+1, exp, actual)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Next(); err != io.EOF {
+			t.Fatalf("expected io.EOF at end of input, but got %v", err)
+		}
+	}
+	if s.Line(0) != "" {
+		t.Errorf("expected empty line at end of input, but got %q", s.Line(0))
+	}
+}
+
+func TestLineScannerCRLF(t *testing.T) {
+	s := NewLineScanner(strings.NewReader("line 1\r\nline 2\r\nline\r3\nline 4\r\n"), 2)
+
+	type state struct {
+		Lines  [3]string
+		Offset int64
+	}
+	expected := []state{
+		{[3]string{"line 1\n", "line 2\n", "line\r3\n"}, 0},
+		{[3]string{"line 2\n", "line\r3\n", "line 4\n"}, 8},
+		{[3]string{"line\r3\n", "line 4\n", ""}, 16},
+		{[3]string{"line 4\n", "", ""}, 23},
+	}
+
+	for i, exp := range expected {
+		if err := s.Next(); err != nil {
+			t.Fatalf("line %d: unexpected error: %v", i+1, err)
+		}
+		var actual state
+		for d := range actual.Lines {
+			actual.Lines[d] = s.Line(d)
+		}
+		actual.Offset = s.Offset()
+		if actual != exp {
+			t.Errorf("line %d: incorrect state\nexpected: %+v\n  actual: %+v", i+1, exp, actual)
+		}
+	}
+
+	s = NewLineScanner(strings.NewReader("line 1\rline 2\r"), 1)
+	if err := s.Next(); err == nil || !strings.Contains(err.Error(), "CR line terminators") {
+		t.Errorf("expected error for CR line terminators, but got %v", err)
+	}
+}
+
+func TestLineScannerEmpty(t *testing.T) {
+	s := NewLineScanner(strings.NewReader(""), 0)
+	if err := s.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF for empty input, but got %v", err)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read(p []byte) (int, error) { return 0, r.err }
+
+func TestLineScannerError(t *testing.T) {
+	errRead := errors.New("read failed")
+	s := NewLineScanner(io.MultiReader(strings.NewReader("line 1\n"), errReader{errRead}), 1)
+	if err := s.Next(); !errors.Is(err, errRead) {
+		t.Fatalf("expected read error, but got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package gitdiff
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var logCommitRegexp = regexp.MustCompile(`^commit [0-9a-f]{7,}\b`)
+
+// CommitPatch is a single commit from the output of git log -p.
+type CommitPatch struct {
+	// Header is the parsed commit header. It is nil if the header could not be
+	// parsed.
+	Header *PatchHeader
+
+	// Preamble is the raw content of the commit before the first file.
+	Preamble string
+
+	// Raw contains the entries in raw format that preceded the diff, if the
+	// log was generated with the --raw option.
+	Raw []*RawEntry
+
+	// Files contains the changes made by the commit. It is empty for commits
+	// without a diff, such as merge commits when git log does not use the -c
+	// or --cc options.
+	Files []*File
+}
+
+// ParseLog parses the output of git log -p (or git show for several commits)
+// into a sequence of commits. The stream is split into commits at each line
+// that starts with "commit <sha>" and the content of each commit is parsed
+// like a single patch, including combined diffs for merge commits.
+//
+// If an error occurs, ParseLog returns all commits parsed before the error.
+func ParseLog(r io.Reader) ([]*CommitPatch, error) {
+	var commits []*CommitPatch
+	var block strings.Builder
+
+	flush := func() error {
+		if block.Len() == 0 {
+			return nil
+		}
+		defer block.Reset()
+
+		files, preamble, err := newParser(strings.NewReader(block.String())).ParseFiles()
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			preamble = block.String()
+		}
+
+		c := &CommitPatch{Preamble: preamble, Files: files}
+
+		header, raw := splitRawEntries(preamble)
+		if h, err := ParsePatchHeader(header); err == nil {
+			c.Header = h
+		}
+		c.Raw = raw
+
+		for _, f := range files {
+			f.PatchHeader = c.Header
+			f.Raw = findRawEntry(raw, f)
+		}
+		commits = append(commits, c)
+		return nil
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return commits, err
+		}
+
+		if logCommitRegexp.MatchString(line) {
+			if ferr := flush(); ferr != nil {
+				return commits, ferr
+			}
+		}
+		block.WriteString(line)
+
+		if err == io.EOF {
+			break
+		}
+	}
+	return commits, flush()
+}
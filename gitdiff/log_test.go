@@ -0,0 +1,57 @@
+package gitdiff
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseLog(t *testing.T) {
+	f, err := os.Open("testdata/log.patch")
+	if err != nil {
+		t.Fatalf("unexpected error opening input file: %v", err)
+	}
+	defer f.Close()
+
+	commits, err := ParseLog(f)
+	if err != nil {
+		t.Fatalf("unexpected error parsing log: %v", err)
+	}
+	if len(commits) != 3 {
+		t.Fatalf("incorrect number of commits: expected 3, actual %d", len(commits))
+	}
+
+	expected := []struct {
+		SHA   string
+		Title string
+		Files int
+		Frags int
+	}{
+		{"8b57e9e4c5c0cc23b6a3b0d62e6e1f44a0c70a11", "Merge branch 'feature'", 1, 2},
+		{"7d5e2a9d7b6cbd9df0d8a2c1b43a6fd0b5f7a5e3", "An empty commit.", 0, 0},
+		{"1c0f2b3a34b8f7f2f22cf7df1e1bbf18c36d3e2b", "Change a file.", 1, 1},
+	}
+
+	for i, exp := range expected {
+		c := commits[i]
+		if c.Header == nil {
+			t.Fatalf("commit %d: missing header", i)
+		}
+		if c.Header.SHA != exp.SHA {
+			t.Errorf("commit %d: incorrect SHA: expected %s, actual %s", i, exp.SHA, c.Header.SHA)
+		}
+		if c.Header.Title != exp.Title {
+			t.Errorf("commit %d: incorrect title: expected %q, actual %q", i, exp.Title, c.Header.Title)
+		}
+		if len(c.Files) != exp.Files {
+			t.Fatalf("commit %d: incorrect number of files: expected %d, actual %d", i, exp.Files, len(c.Files))
+		}
+		for _, file := range c.Files {
+			if file.PatchHeader != c.Header {
+				t.Errorf("commit %d: file does not reference commit header", i)
+			}
+			if len(file.TextFragments) != exp.Frags {
+				t.Errorf("commit %d: incorrect number of fragments: expected %d, actual %d", i, exp.Frags, len(file.TextFragments))
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package gitdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WorkspaceEdit is a set of changes to the files of a workspace, like a
+// WorkspaceEdit of the Language Server Protocol. Marshalled to JSON, it can
+// be sent to an editor in a workspace/applyEdit request or shown as a
+// preview of a patch.
+type WorkspaceEdit struct {
+	// DocumentChanges contains *TextDocumentEdit, *CreateFile, *RenameFile,
+	// and *DeleteFile values, which must be applied in order.
+	DocumentChanges []DocumentChange `json:"documentChanges"`
+}
+
+// DocumentChange is an operation in a WorkspaceEdit.
+type DocumentChange interface {
+	isDocumentChange()
+}
+
+// TextEdit is a replacement of the text in a range of a document.
+type TextEdit struct {
+	Range   TextRange `json:"range"`
+	NewText string    `json:"newText"`
+}
+
+// TextDocumentIdentifier identifies a document and, optionally, the version
+// of the document that the edits apply to. A nil Version means any version.
+type TextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+// TextDocumentEdit changes the content of an existing document.
+type TextDocumentEdit struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit             `json:"edits"`
+}
+
+// CreateFile creates an empty document.
+type CreateFile struct {
+	URI string `json:"uri"`
+}
+
+// RenameFile moves a document to a new URI.
+type RenameFile struct {
+	OldURI string `json:"oldUri"`
+	NewURI string `json:"newUri"`
+}
+
+// DeleteFile deletes a document.
+type DeleteFile struct {
+	URI string `json:"uri"`
+}
+
+func (*TextDocumentEdit) isDocumentChange() {}
+func (*CreateFile) isDocumentChange()       {}
+func (*RenameFile) isDocumentChange()       {}
+func (*DeleteFile) isDocumentChange()       {}
+
+// MarshalJSON implements json.Marshaler, adding the "kind" field.
+func (c *CreateFile) MarshalJSON() ([]byte, error) {
+	type createFile CreateFile
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		*createFile
+	}{"create", (*createFile)(c)})
+}
+
+// MarshalJSON implements json.Marshaler, adding the "kind" field.
+func (r *RenameFile) MarshalJSON() ([]byte, error) {
+	type renameFile RenameFile
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		*renameFile
+	}{"rename", (*renameFile)(r)})
+}
+
+// MarshalJSON implements json.Marshaler, adding the "kind" field.
+func (d *DeleteFile) MarshalJSON() ([]byte, error) {
+	type deleteFile DeleteFile
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		*deleteFile
+	}{"delete", (*deleteFile)(d)})
+}
+
+// WorkspaceEditOptions configures NewWorkspaceEdit.
+type WorkspaceEditOptions struct {
+	// Root is the directory that file names in the patch are relative to.
+	// If empty, the current directory is used.
+	Root string
+
+	// Encoding is the unit of character offsets in text edits. If empty,
+	// PositionUTF16 is used.
+	Encoding PositionEncoding
+}
+
+// NewWorkspaceEdit converts files to a WorkspaceEdit that makes the same
+// changes, so editors can preview and apply a patch with their own edit
+// machinery. New files become a create operation followed by a text edit,
+// renamed files a rename followed by edits to the new document, and deleted
+// files a delete operation. Mode changes are ignored.
+//
+// NewWorkspaceEdit returns an error for binary changes, copies, and combined
+// diffs, which have no representation as text edits of existing documents,
+// and an *UnsafePathError for names rejected by ValidatePath, such as names
+// that resolve outside of opts.Root.
+func NewWorkspaceEdit(files []*File, opts WorkspaceEditOptions) (*WorkspaceEdit, error) {
+	files, err := OrderFiles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	uri := func(name string) string {
+		p := filepath.ToSlash(root)
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		u := url.URL{Scheme: "file", Path: path.Join(p, name)}
+		return u.String()
+	}
+
+	ws := &WorkspaceEdit{DocumentChanges: []DocumentChange{}}
+	for _, f := range files {
+		for _, name := range []string{f.OldName, f.NewName} {
+			if name == "" {
+				continue
+			}
+			if err := ValidatePath(name); err != nil {
+				return nil, err
+			}
+		}
+
+		switch {
+		case f.ParentCount > 0:
+			return nil, fmt.Errorf("gitdiff: %s: cannot convert a combined diff to a workspace edit", fileName(f))
+		case f.IsCopy:
+			return nil, fmt.Errorf("gitdiff: %s: cannot convert a copy to a workspace edit", fileName(f))
+		case f.IsDelete:
+			ws.DocumentChanges = append(ws.DocumentChanges, &DeleteFile{URI: uri(f.OldName)})
+			continue
+		case f.IsBinary:
+			return nil, fmt.Errorf("gitdiff: %s: cannot convert a binary change to a workspace edit", fileName(f))
+		case f.IsNew:
+			ws.DocumentChanges = append(ws.DocumentChanges, &CreateFile{URI: uri(f.NewName)})
+		case f.IsRename:
+			ws.DocumentChanges = append(ws.DocumentChanges, &RenameFile{OldURI: uri(f.OldName), NewURI: uri(f.NewName)})
+		}
+
+		edits := f.EditsWithOptions(EditOptions{Encoding: opts.Encoding})
+		if len(edits) == 0 {
+			continue
+		}
+
+		doc := &TextDocumentEdit{TextDocument: TextDocumentIdentifier{URI: uri(f.NewName)}}
+		for _, e := range edits {
+			doc.Edits = append(doc.Edits, TextEdit{Range: e.OldRange, NewText: e.Text})
+		}
+		ws.DocumentChanges = append(ws.DocumentChanges, doc)
+	}
+	return ws, nil
+}
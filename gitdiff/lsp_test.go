@@ -0,0 +1,119 @@
+package gitdiff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewWorkspaceEdit(t *testing.T) {
+	tests := map[string]struct {
+		Patch  string
+		Opts   WorkspaceEditOptions
+		Output string
+		Err    interface{}
+	}{
+		"modify": {
+			Patch: "diff --git a/dir/f.txt b/dir/f.txt\n--- a/dir/f.txt\n+++ b/dir/f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+B\n",
+			Opts:  WorkspaceEditOptions{Root: "/work"},
+			Output: `{"documentChanges":[` +
+				`{"textDocument":{"uri":"file:///work/dir/f.txt","version":null},"edits":[` +
+				`{"range":{"start":{"line":1,"character":0},"end":{"line":2,"character":0}},"newText":"B\n"}]}]}`,
+		},
+		"create": {
+			Patch: "diff --git a/new b/new\nnew file mode 100644\n--- /dev/null\n+++ b/new\n@@ -0,0 +1 @@\n+x\n",
+			Opts:  WorkspaceEditOptions{Root: "/work"},
+			Output: `{"documentChanges":[` +
+				`{"kind":"create","uri":"file:///work/new"},` +
+				`{"textDocument":{"uri":"file:///work/new","version":null},"edits":[` +
+				`{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"newText":"x\n"}]}]}`,
+		},
+		"createEmpty": {
+			Patch:  "diff --git a/empty b/empty\nnew file mode 100644\nindex 0000000..e69de29\n",
+			Opts:   WorkspaceEditOptions{Root: "/work"},
+			Output: `{"documentChanges":[{"kind":"create","uri":"file:///work/empty"}]}`,
+		},
+		"delete": {
+			Patch:  "diff --git a/old b/old\ndeleted file mode 100644\n--- a/old\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n",
+			Opts:   WorkspaceEditOptions{Root: "/work"},
+			Output: `{"documentChanges":[{"kind":"delete","uri":"file:///work/old"}]}`,
+		},
+		"rename": {
+			Patch: "diff --git a/a b/b c\nsimilarity index 50%\nrename from a\nrename to b c\n--- a/a\n+++ b/b c\n@@ -1,2 +1,2 @@\n x\n-y\n+z\n",
+			Opts:  WorkspaceEditOptions{Root: "/work"},
+			Output: `{"documentChanges":[` +
+				`{"kind":"rename","oldUri":"file:///work/a","newUri":"file:///work/b%20c"},` +
+				`{"textDocument":{"uri":"file:///work/b%20c","version":null},"edits":[` +
+				`{"range":{"start":{"line":1,"character":0},"end":{"line":2,"character":0}},"newText":"z\n"}]}]}`,
+		},
+		"modeOnly": {
+			Patch:  "diff --git a/f b/f\nold mode 100644\nnew mode 100755\n",
+			Opts:   WorkspaceEditOptions{Root: "/work"},
+			Output: `{"documentChanges":[]}`,
+		},
+		"binary": {
+			Patch: "diff --git a/f b/f\nindex 1234567..89abcde 100644\nBinary files a/f and b/f differ\n",
+			Err:   "binary change",
+		},
+		"unsafeNew": {
+			Patch: "--- /dev/null\n+++ ../../../etc/cron.d/x\n@@ -0,0 +1 @@\n+x\n",
+			Opts:  WorkspaceEditOptions{Root: "/home/u/proj"},
+			Err:   `unsafe path "../../../etc/cron.d/x"`,
+		},
+		"unsafeRename": {
+			Patch: "diff --git a/a b/b\nsimilarity index 100%\nrename from a\nrename to ../b\n",
+			Opts:  WorkspaceEditOptions{Root: "/home/u/proj"},
+			Err:   "unsafe path",
+		},
+		"copy": {
+			Patch: "diff --git a/a b/b\nsimilarity index 100%\ncopy from a\ncopy to b\n",
+			Err:   "copy",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			ws, err := NewWorkspaceEdit(files, test.Opts)
+			if test.Err != nil {
+				assertError(t, test.Err, err, "creating workspace edit")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error creating workspace edit: %v", err)
+			}
+
+			out, err := json.Marshal(ws)
+			if err != nil {
+				t.Fatalf("unexpected error marshalling workspace edit: %v", err)
+			}
+			if string(out) != test.Output {
+				t.Errorf("incorrect JSON\nexpected: %s\n  actual: %s", test.Output, out)
+			}
+		})
+	}
+}
+
+func TestNewWorkspaceEditOrder(t *testing.T) {
+	patch := "diff --git a/f b/f\ndeleted file mode 100644\n--- a/f\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n" +
+		"diff --git a/g b/f\nsimilarity index 100%\nrename from g\nrename to f\n"
+
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	ws, err := NewWorkspaceEdit(files, WorkspaceEditOptions{Root: "/work"})
+	if err != nil {
+		t.Fatalf("unexpected error creating workspace edit: %v", err)
+	}
+	if len(ws.DocumentChanges) != 2 {
+		t.Fatalf("expected 2 document changes, got %d", len(ws.DocumentChanges))
+	}
+	if _, ok := ws.DocumentChanges[0].(*DeleteFile); !ok {
+		t.Errorf("expected delete before rename, got %T first", ws.DocumentChanges[0])
+	}
+}
@@ -0,0 +1,121 @@
+package gitdiff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ErrManifestMismatch matches errors returned by VerifyManifest when the
+// files do not match the manifest. Use errors.Is to test for it.
+var ErrManifestMismatch = errors.New("gitdiff: files do not match manifest")
+
+// PatchManifest contains SHA-256 hashes of the files and hunks of a patch.
+// Systems that apply the same patch on many machines can distribute the small
+// manifest through a trusted channel and use VerifyManifest to check the
+// patch each machine receives.
+type PatchManifest struct {
+	// Digest is the hash of the digests of all files, in order.
+	Digest string          `json:"digest"`
+	Files  []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry contains the hashes of one file in a PatchManifest.
+type ManifestEntry struct {
+	Name string `json:"name"`
+
+	// Digest is the hash of the metadata of the file, the digests of its
+	// hunks, and its binary data.
+	Digest string `json:"digest"`
+
+	// Hunks contains the hash of each hunk of the file, in order.
+	Hunks []string `json:"hunks,omitempty"`
+}
+
+// Manifest returns the manifest of files. The hashes depend only on the
+// content of the files, not on how the patch was formatted, so binary data
+// is hashed after it is decoded.
+func Manifest(files []*File) PatchManifest {
+	m := PatchManifest{Files: make([]ManifestEntry, len(files))}
+
+	h := sha256.New()
+	for i, f := range files {
+		m.Files[i] = manifestEntry(f)
+		writeHashField(h, m.Files[i].Name)
+		writeHashField(h, m.Files[i].Digest)
+	}
+	m.Digest = hex.EncodeToString(h.Sum(nil))
+	return m
+}
+
+func manifestEntry(f *File) ManifestEntry {
+	e := ManifestEntry{Name: fileName(f)}
+
+	h := sha256.New()
+	writeHashField(h, f.OldName)
+	writeHashField(h, f.NewName)
+	writeHashField(h, fmt.Sprintf("%t %t %t %t %t %o %o", f.IsNew, f.IsDelete, f.IsCopy, f.IsRename, f.IsBinary, f.OldMode, f.NewMode))
+	writeHashField(h, f.OldOIDPrefix)
+	writeHashField(h, f.NewOIDPrefix)
+
+	for _, hunk := range f.Hunks() {
+		sum := sha256.Sum256([]byte(hunk.String()))
+		digest := hex.EncodeToString(sum[:])
+		e.Hunks = append(e.Hunks, digest)
+		writeHashField(h, digest)
+	}
+
+	for _, frag := range []*BinaryFragment{f.BinaryFragment, f.ReverseBinaryFragment} {
+		if frag == nil {
+			writeHashField(h, "")
+			continue
+		}
+		writeHashField(h, fmt.Sprintf("%d %d", frag.Method, len(frag.Data)))
+		_, _ = h.Write(frag.Data)
+	}
+
+	e.Digest = hex.EncodeToString(h.Sum(nil))
+	return e
+}
+
+// writeHashField writes a length-prefixed string so that the boundaries of
+// fields do not depend on their content.
+func writeHashField(h hash.Hash, s string) {
+	_, _ = io.WriteString(h, fmt.Sprintf("%d:%s", len(s), s))
+}
+
+// VerifyManifest checks that files match the manifest m. If they do not, it
+// returns an error matching ErrManifestMismatch that names the first file or
+// hunk that differs.
+func VerifyManifest(files []*File, m PatchManifest) error {
+	actual := Manifest(files)
+	if actual.Digest == m.Digest {
+		return nil
+	}
+
+	if len(actual.Files) != len(m.Files) {
+		return fmt.Errorf("%w: manifest has %d files, patch has %d", ErrManifestMismatch, len(m.Files), len(actual.Files))
+	}
+	for i, e := range m.Files {
+		a := actual.Files[i]
+		if a.Name != e.Name {
+			return fmt.Errorf("%w: file %d: expected %s, found %s", ErrManifestMismatch, i+1, e.Name, a.Name)
+		}
+		if a.Digest == e.Digest {
+			continue
+		}
+		if len(a.Hunks) != len(e.Hunks) {
+			return fmt.Errorf("%w: %s: manifest has %d hunks, patch has %d", ErrManifestMismatch, e.Name, len(e.Hunks), len(a.Hunks))
+		}
+		for j := range e.Hunks {
+			if a.Hunks[j] != e.Hunks[j] {
+				return fmt.Errorf("%w: %s: hunk %d differs", ErrManifestMismatch, e.Name, j+1)
+			}
+		}
+		return fmt.Errorf("%w: %s: file differs", ErrManifestMismatch, e.Name)
+	}
+	return fmt.Errorf("%w: digest differs", ErrManifestMismatch)
+}
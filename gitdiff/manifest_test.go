@@ -0,0 +1,99 @@
+package gitdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/two_files.patch")
+	if err != nil {
+		t.Fatalf("unexpected error reading patch: %v", err)
+	}
+	parse := func(t *testing.T, patch string) []*File {
+		files, _, err := ParseAll(strings.NewReader(patch))
+		if err != nil {
+			t.Fatalf("unexpected error parsing patch: %v", err)
+		}
+		return files
+	}
+
+	files := parse(t, string(data))
+	m := Manifest(files)
+	if len(m.Files) != len(files) {
+		t.Fatalf("incorrect number of manifest files: expected %d, actual %d", len(files), len(m.Files))
+	}
+	for i, f := range files {
+		if len(m.Files[i].Hunks) != len(f.Hunks()) {
+			t.Errorf("file %d: incorrect number of hunks: expected %d, actual %d", i, len(f.Hunks()), len(m.Files[i].Hunks))
+		}
+	}
+
+	// the manifest survives encoding and formatting the files again
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("unexpected error encoding manifest: %v", err)
+	}
+	var decoded PatchManifest
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("unexpected error decoding manifest: %v", err)
+	}
+
+	var formatted bytes.Buffer
+	if err := FormatFiles(&formatted, files); err != nil {
+		t.Fatalf("unexpected error formatting files: %v", err)
+	}
+	if err := VerifyManifest(parse(t, formatted.String()), decoded); err != nil {
+		t.Fatalf("unexpected error verifying formatted files: %v", err)
+	}
+
+	tests := map[string]struct {
+		Patch    func(string) string
+		Manifest func(PatchManifest) PatchManifest
+		Err      string
+	}{
+		"changedLine": {
+			Patch: func(s string) string { return strings.Replace(s, "+new line 2", "+new line 9", 1) },
+			Err:   "hunk 1 differs",
+		},
+		"changedMode": {
+			Patch: func(s string) string {
+				return strings.Replace(s, "index ebe9fa54..fe103e1d 100644", "index ebe9fa54..fe103e1d 100755", 1)
+			},
+			Err: "file differs",
+		},
+		"missingFile": {
+			Patch: func(s string) string { return s[:strings.LastIndex(s, "diff --git")] },
+			Err:   "manifest has 2 files, patch has 1",
+		},
+		"changedDigest": {
+			Manifest: func(m PatchManifest) PatchManifest {
+				m.Digest = strings.Repeat("0", 64)
+				return m
+			},
+			Err: "digest differs",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			patch := string(data)
+			if test.Patch != nil {
+				patch = test.Patch(patch)
+				if patch == string(data) {
+					t.Fatal("test did not change the patch")
+				}
+			}
+			manifest := m
+			if test.Manifest != nil {
+				manifest = test.Manifest(manifest)
+			}
+			err := VerifyManifest(parse(t, patch), manifest)
+			assertError(t, ErrManifestMismatch, err, "verifying manifest")
+			assertError(t, test.Err, err, "verifying manifest")
+		})
+	}
+}
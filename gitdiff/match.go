@@ -0,0 +1,127 @@
+package gitdiff
+
+import (
+	"bytes"
+	"unicode"
+)
+
+// Matcher decides if a text fragment applies to a sequence of source lines.
+// The Applier uses a Matcher to test the position recorded in the fragment
+// and, if ApplyOptions allow searching, other nearby positions.
+//
+// Match is called with exactly f.OldLines lines from the source, each
+// including its newline character if present. When a Matcher accepts lines
+// that are not identical to the old lines of the fragment, the Applier keeps
+// the source version of context lines in its output.
+type Matcher interface {
+	Match(f *TextFragment, lines [][]byte) bool
+}
+
+// MatcherFunc is an adapter to allow the use of ordinary functions as
+// Matchers.
+type MatcherFunc func(f *TextFragment, lines [][]byte) bool
+
+// Match calls fn(f, lines).
+func (fn MatcherFunc) Match(f *TextFragment, lines [][]byte) bool {
+	return fn(f, lines)
+}
+
+// LineMatcher returns a Matcher that compares each old line of a fragment to
+// the corresponding source line after transforming both with normalize.
+func LineMatcher(normalize func([]byte) []byte) Matcher {
+	return MatcherFunc(func(f *TextFragment, lines [][]byte) bool {
+		i := 0
+		for _, line := range f.Lines {
+			if !line.Old() {
+				continue
+			}
+			if i >= len(lines) || !bytes.Equal(normalize([]byte(line.Line)), normalize(lines[i])) {
+				return false
+			}
+			i++
+		}
+		return i == len(lines)
+	})
+}
+
+var (
+	// ExactMatcher requires source lines to exactly match the old lines of
+	// the fragment. This is the default.
+	ExactMatcher Matcher = LineMatcher(func(b []byte) []byte { return b })
+
+	// WhitespaceMatcher ignores changes in the amount of whitespace, like the
+	// --ignore-space-change option of git apply: runs of whitespace compare
+	// equal to a single space and trailing whitespace is ignored.
+	WhitespaceMatcher Matcher = LineMatcher(collapseSpace)
+
+	// TokenMatcher ignores all whitespace between tokens, so lines match if
+	// they contain the same sequence of non-whitespace characters.
+	TokenMatcher Matcher = LineMatcher(removeSpace)
+
+	// AnchorMatcher only compares the leading and trailing context lines of
+	// the fragment, which anchor it in the source. Deleted lines and context
+	// lines between changes are not compared. Fragments without context are
+	// compared exactly.
+	AnchorMatcher Matcher = MatcherFunc(matchAnchors)
+
+	// ForceMatcher accepts any source lines, so fragments apply purely by
+	// position, replacing the lines at their recorded positions even if the
+	// context or deleted lines differ, like the --force option of patch. Use
+	// it to recover when a patch is known to be correct but the source has
+	// cosmetic differences. Fragments only fail to apply if the source does
+	// not have enough lines.
+	ForceMatcher Matcher = MatcherFunc(func(f *TextFragment, lines [][]byte) bool { return true })
+)
+
+func collapseSpace(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	space := false
+	for _, r := range string(b) {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space && len(out) > 0 {
+			out = append(out, ' ')
+		}
+		space = false
+		out = append(out, string(r)...)
+	}
+	return out
+}
+
+func removeSpace(b []byte) []byte {
+	return bytes.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, b)
+}
+
+func matchAnchors(f *TextFragment, lines [][]byte) bool {
+	if f.LeadingContext == 0 && f.TrailingContext == 0 {
+		return ExactMatcher.Match(f, lines)
+	}
+	if int64(len(lines)) != f.OldLines {
+		return false
+	}
+
+	var old []string
+	for _, line := range f.Lines {
+		if line.Old() {
+			old = append(old, line.Line)
+		}
+	}
+	for i := int64(0); i < f.LeadingContext; i++ {
+		if old[i] != string(lines[i]) {
+			return false
+		}
+	}
+	for i := int64(len(old)) - f.TrailingContext; i < int64(len(old)); i++ {
+		if old[i] != string(lines[i]) {
+			return false
+		}
+	}
+	return true
+}
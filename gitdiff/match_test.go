@@ -0,0 +1,167 @@
+package gitdiff
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+	frag := &TextFragment{
+		OldPosition:     1,
+		OldLines:        3,
+		NewPosition:     1,
+		NewLines:        3,
+		LinesAdded:      1,
+		LinesDeleted:    1,
+		LeadingContext:  1,
+		TrailingContext: 1,
+		Lines: []Line{
+			{OpContext, "func main() {\n"},
+			{OpDelete, "\tx := a + b\n"},
+			{OpAdd, "\tx := a - b\n"},
+			{OpContext, "}\n"},
+		},
+	}
+
+	lines := func(s ...string) [][]byte {
+		var b [][]byte
+		for _, l := range s {
+			b = append(b, []byte(l))
+		}
+		return b
+	}
+
+	tests := map[string]struct {
+		Lines   [][]byte
+		Matches map[string]bool
+	}{
+		"exact": {
+			Lines: lines("func main() {\n", "\tx := a + b\n", "}\n"),
+			Matches: map[string]bool{
+				"exact": true, "whitespace": true, "token": true, "anchor": true, "force": true,
+			},
+		},
+		"changedIndent": {
+			Lines: lines("func main() {  \n", "    x  :=  a + b\n", "}\n"),
+			Matches: map[string]bool{
+				"exact": false, "whitespace": true, "token": true, "anchor": false, "force": true,
+			},
+		},
+		"removedSpace": {
+			Lines: lines("func main(){\n", "\tx:=a+b\n", "}\n"),
+			Matches: map[string]bool{
+				"exact": false, "whitespace": false, "token": true, "anchor": false, "force": true,
+			},
+		},
+		"changedMiddle": {
+			Lines: lines("func main() {\n", "\ty := c\n", "}\n"),
+			Matches: map[string]bool{
+				"exact": false, "whitespace": false, "token": false, "anchor": true, "force": true,
+			},
+		},
+	}
+
+	matchers := map[string]Matcher{
+		"exact":      ExactMatcher,
+		"whitespace": WhitespaceMatcher,
+		"token":      TokenMatcher,
+		"anchor":     AnchorMatcher,
+		"force":      ForceMatcher,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			for mname, expected := range test.Matches {
+				if actual := matchers[mname].Match(frag, test.Lines); actual != expected {
+					t.Errorf("incorrect result for %s matcher: expected %t, actual %t", mname, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyWithOptions(t *testing.T) {
+	patch := `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -2,3 +2,3 @@
+ line 2
+-line 3
++line three
+ line 4
+@@ -8,3 +8,3 @@
+ line 8
+-line 9
++line nine
+ line 10
+`
+	tests := map[string]struct {
+		Src  string
+		Opts ApplyOptions
+		Out  string
+		Err  interface{}
+	}{
+		"strict": {
+			Src: "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\n",
+			Out: "line 1\nline 2\nline three\nline 4\nline 5\nline 6\nline 7\nline 8\nline nine\nline 10\n",
+		},
+		"strictOffset": {
+			Src: "line 0\nline 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\n",
+			Err: &Conflict{},
+		},
+		"searchOffset": {
+			Src:  "line 0\nline 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\n",
+			Opts: ApplyOptions{MaxOffset: 2},
+			Out:  "line 0\nline 1\nline 2\nline three\nline 4\nline 5\nline 6\nline 7\nline 8\nline nine\nline 10\n",
+		},
+		"searchBackward": {
+			Src:  "line 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\n",
+			Opts: ApplyOptions{MaxOffset: -1},
+			Out:  "line 2\nline three\nline 4\nline 5\nline 6\nline 7\nline 8\nline nine\nline 10\n",
+		},
+		"searchTooFar": {
+			Src:  "new\nnew\nnew\nline 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\n",
+			Opts: ApplyOptions{MaxOffset: 2},
+			Err:  &Conflict{},
+		},
+		"whitespace": {
+			Src:  "line 1\nline  2\nline 3\nline 4 \nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\n",
+			Opts: ApplyOptions{Matcher: WhitespaceMatcher},
+			Out:  "line 1\nline  2\nline three\nline 4 \nline 5\nline 6\nline 7\nline 8\nline nine\nline 10\n",
+		},
+		"force": {
+			Src:  "line 1\nline B\nline C\nline D\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\n",
+			Opts: ApplyOptions{Matcher: ForceMatcher},
+			Out:  "line 1\nline B\nline three\nline D\nline 5\nline 6\nline 7\nline 8\nline nine\nline 10\n",
+		},
+		"forceShort": {
+			Src:  "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\n",
+			Opts: ApplyOptions{Matcher: ForceMatcher},
+			Err:  io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := newParser(strings.NewReader(patch)).ParseFiles()
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			var dst bytes.Buffer
+			err = NewApplierWithOptions(strings.NewReader(test.Src), test.Opts).ApplyFile(&dst, files[0])
+			if test.Err != nil {
+				assertError(t, test.Err, err, "applying file")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error applying file: %v", err)
+			}
+			if dst.String() != test.Out {
+				t.Errorf("incorrect result after apply\nexpected:\n%q\nactual:\n%q", test.Out, dst.String())
+			}
+		})
+	}
+}
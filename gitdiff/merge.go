@@ -0,0 +1,258 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ConflictStyle selects how ApplyThreeWay writes conflicting changes, like
+// the merge.conflictStyle setting of Git.
+type ConflictStyle int
+
+const (
+	// ConflictMerge writes our lines and their lines between "<<<<<<<",
+	// "=======", and ">>>>>>>" markers.
+	ConflictMerge ConflictStyle = iota
+
+	// ConflictDiff3 also writes the base lines, after a "|||||||" marker
+	// that follows our lines.
+	ConflictDiff3
+)
+
+// Markers written around conflicting changes.
+const (
+	conflictOurs   = "<<<<<<< ours\n"
+	conflictBase   = "||||||| base\n"
+	conflictSep    = "=======\n"
+	conflictTheirs = ">>>>>>> theirs\n"
+)
+
+// LineRange is a range of lines in a file. Position is the one-indexed
+// number of the first line.
+type LineRange struct {
+	Position int64
+	Lines    int64
+}
+
+// ApplyThreeWay applies f to ours, the current content of a file, using
+// base, the content the patch was created from, like git apply --3way. It
+// applies f to base and merges the changes from base to the result with the
+// changes from base to ours, writing the merged content to dst. Changes that
+// do not overlap are combined. Overlapping changes that differ are written
+// between conflict markers in the style selected by opts.ConflictStyle and
+// returned as conflicts; the error is nil if the only problems are conflicts.
+// The other options are used to apply f to base.
+//
+// If opts.ConflictResolver is set, it is called for each conflict and the
+// conflicts it resolves are written without markers. These conflicts are
+// still returned, with Resolved set, so callers can audit the changes made
+// automatically.
+//
+// Lines in a conflict that do not end with a newline have one added so that
+// the markers are on separate lines. Binary files are not supported.
+func ApplyThreeWay(dst io.Writer, base, ours []byte, f *File, opts ApplyOptions) ([]*Conflict, error) {
+	if f.IsBinary {
+		return nil, applyError(errors.New("three-way apply of binary files is not supported"))
+	}
+
+	var theirs bytes.Buffer
+	if err := NewApplierWithOptions(bytes.NewReader(base), opts).ApplyFile(&theirs, f); err != nil {
+		return nil, err
+	}
+
+	name := f.NewName
+	if f.IsDelete {
+		name = f.OldName
+	}
+
+	baseLines := splitLines(base)
+	m := merger{
+		name:     name,
+		style:    opts.ConflictStyle,
+		resolver: opts.ConflictResolver,
+		base:     baseLines,
+		ours:     changedRanges(diffLines(baseLines, splitLines(ours))),
+		theirs:   changedRanges(diffLines(baseLines, splitLines(theirs.Bytes()))),
+	}
+	m.merge()
+
+	if _, err := dst.Write(m.out.Bytes()); err != nil {
+		return nil, applyError(err)
+	}
+	return m.conflicts, nil
+}
+
+// changedRange replaces the base lines from start to end with lines.
+type changedRange struct {
+	start, end int
+	lines      []string
+}
+
+// changedRanges groups the deleted and added lines of an edit script into
+// replacements of ranges of the old lines.
+func changedRanges(script []Line) []changedRange {
+	var ranges []changedRange
+	var r *changedRange
+
+	pos := 0
+	for _, line := range script {
+		if line.Op == OpContext {
+			r = nil
+			pos++
+			continue
+		}
+		if r == nil {
+			ranges = append(ranges, changedRange{start: pos, end: pos})
+			r = &ranges[len(ranges)-1]
+		}
+		if line.Op == OpDelete {
+			pos++
+			r.end = pos
+		} else {
+			r.lines = append(r.lines, line.Line)
+		}
+	}
+	return ranges
+}
+
+type merger struct {
+	name     string
+	style    ConflictStyle
+	resolver ConflictResolver
+	base     []string
+	ours     []changedRange
+	theirs   []changedRange
+
+	out       bytes.Buffer
+	outLines  int64
+	conflicts []*Conflict
+}
+
+func (m *merger) merge() {
+	pos := 0
+	for len(m.ours) > 0 || len(m.theirs) > 0 {
+		// start a group with the first change on either side and extend it
+		// with all of the changes that overlap or touch it
+		var start int
+		switch {
+		case len(m.theirs) == 0:
+			start = m.ours[0].start
+		case len(m.ours) == 0:
+			start = m.theirs[0].start
+		default:
+			start = minInt(m.ours[0].start, m.theirs[0].start)
+		}
+
+		end := start
+		var ours, theirs []changedRange
+		for {
+			if len(m.ours) > 0 && m.ours[0].start <= end {
+				end = maxInt(end, m.ours[0].end)
+				ours, m.ours = append(ours, m.ours[0]), m.ours[1:]
+			} else if len(m.theirs) > 0 && m.theirs[0].start <= end {
+				end = maxInt(end, m.theirs[0].end)
+				theirs, m.theirs = append(theirs, m.theirs[0]), m.theirs[1:]
+			} else {
+				break
+			}
+		}
+
+		m.write(m.base[pos:start]...)
+		pos = end
+
+		oursLines := m.replace(start, end, ours)
+		theirsLines := m.replace(start, end, theirs)
+		switch {
+		case len(theirs) == 0:
+			m.write(oursLines...)
+		case len(ours) == 0 || equalLines(oursLines, theirsLines):
+			m.write(theirsLines...)
+		default:
+			m.conflict(oursLines, theirsLines, m.base[start:end])
+		}
+	}
+	m.write(m.base[pos:]...)
+}
+
+// replace returns the base lines from start to end with the changes applied.
+func (m *merger) replace(start, end int, changes []changedRange) []string {
+	var lines []string
+	pos := start
+	for _, c := range changes {
+		lines = append(lines, m.base[pos:c.start]...)
+		lines = append(lines, c.lines...)
+		pos = c.end
+	}
+	return append(lines, m.base[pos:end]...)
+}
+
+func (m *merger) conflict(ours, theirs, base []string) {
+	c := &Conflict{
+		Path:   m.name,
+		Ours:   ours,
+		Theirs: theirs,
+		Base:   append([]string(nil), base...),
+	}
+	c.NewRange.Position = m.outLines + 1
+	c.msg = fmt.Sprintf("%s: overlapping changes at line %d", m.name, c.NewRange.Position)
+	m.conflicts = append(m.conflicts, c)
+
+	if m.resolver != nil {
+		if lines, ok := m.resolver.Resolve(c); ok {
+			m.write(lines...)
+			c.NewRange.Lines = m.outLines + 1 - c.NewRange.Position
+			c.Resolved = true
+			return
+		}
+	}
+
+	m.write(conflictOurs)
+	m.writeTerminated(ours)
+	if m.style == ConflictDiff3 {
+		m.write(conflictBase)
+		m.writeTerminated(base)
+	}
+	m.write(conflictSep)
+	m.writeTerminated(theirs)
+	m.write(conflictTheirs)
+
+	c.NewRange.Lines = m.outLines + 1 - c.NewRange.Position
+}
+
+func (m *merger) write(lines ...string) {
+	for _, line := range lines {
+		m.out.WriteString(line)
+	}
+	m.outLines += int64(len(lines))
+}
+
+func (m *merger) writeTerminated(lines []string) {
+	for _, line := range lines {
+		m.write(line)
+		if !strings.HasSuffix(line, "\n") {
+			m.out.WriteByte('\n')
+		}
+	}
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,148 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyThreeWay(t *testing.T) {
+	base := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+
+	tests := map[string]struct {
+		Ours   string
+		Theirs string
+		Style  ConflictStyle
+
+		Output    string
+		Conflicts []*Conflict
+	}{
+		"unchanged": {
+			Ours:   base,
+			Theirs: "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n",
+			Output: "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n",
+		},
+		"separateChanges": {
+			Ours:   "1\n2\n3\n4\n5\n6\n7\n8\nnine\n10\n",
+			Theirs: "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			Output: "one\n2\n3\n4\n5\n6\n7\n8\nnine\n10\n",
+		},
+		"sameChange": {
+			Ours:   "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n",
+			Theirs: "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n",
+			Output: "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n",
+		},
+		"conflict": {
+			Ours:   "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n",
+			Theirs: "1\n2\n3\nFOUR\n5\n6\n7\n8\nnine\n10\n",
+			Output: "1\n2\n3\n<<<<<<< ours\nfour\n=======\nFOUR\n>>>>>>> theirs\n5\n6\n7\n8\nnine\n10\n",
+			Conflicts: []*Conflict{
+				{
+					Path:     "file.txt",
+					Ours:     []string{"four\n"},
+					Theirs:   []string{"FOUR\n"},
+					Base:     []string{"4\n"},
+					NewRange: LineRange{Position: 4, Lines: 5},
+				},
+			},
+		},
+		"conflictDiff3": {
+			Ours:   "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n",
+			Theirs: "1\n2\n3\nFOUR\nFIVE\n6\n7\n8\n9\n10\n",
+			Style:  ConflictDiff3,
+			Output: "1\n2\n3\n<<<<<<< ours\nfour\n5\n||||||| base\n4\n5\n=======\nFOUR\nFIVE\n>>>>>>> theirs\n6\n7\n8\n9\n10\n",
+			Conflicts: []*Conflict{
+				{
+					Path:     "file.txt",
+					Ours:     []string{"four\n", "5\n"},
+					Theirs:   []string{"FOUR\n", "FIVE\n"},
+					Base:     []string{"4\n", "5\n"},
+					NewRange: LineRange{Position: 4, Lines: 10},
+				},
+			},
+		},
+		"insertSamePosition": {
+			Ours:   "1\n2\n3\n4\n5\nours\n6\n7\n8\n9\n10\n",
+			Theirs: "1\n2\n3\n4\n5\ntheirs\n6\n7\n8\n9\n10\n",
+			Output: "1\n2\n3\n4\n5\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\n6\n7\n8\n9\n10\n",
+			Conflicts: []*Conflict{
+				{
+					Path:     "file.txt",
+					Ours:     []string{"ours\n"},
+					Theirs:   []string{"theirs\n"},
+					NewRange: LineRange{Position: 6, Lines: 5},
+				},
+			},
+		},
+		"missingNewline": {
+			Ours:   "1\n2\n3\n4\n5\n6\n7\n8\n9\nours",
+			Theirs: "1\n2\n3\n4\n5\n6\n7\n8\n9\ntheirs",
+			Output: "1\n2\n3\n4\n5\n6\n7\n8\n9\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\n",
+			Conflicts: []*Conflict{
+				{
+					Path:     "file.txt",
+					Ours:     []string{"ours"},
+					Theirs:   []string{"theirs"},
+					Base:     []string{"10\n"},
+					NewRange: LineRange{Position: 10, Lines: 5},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := &File{
+				OldName:       "file.txt",
+				NewName:       "file.txt",
+				TextFragments: DiffText([]byte(base), []byte(test.Theirs), DiffOptions{}),
+			}
+
+			var out bytes.Buffer
+			conflicts, err := ApplyThreeWay(&out, []byte(base), []byte(test.Ours), f, ApplyOptions{ConflictStyle: test.Style})
+			if err != nil {
+				t.Fatalf("unexpected error applying patch: %v", err)
+			}
+			if out.String() != test.Output {
+				t.Errorf("incorrect output\nexpected: %q\n  actual: %q", test.Output, out.String())
+			}
+
+			if len(conflicts) != len(test.Conflicts) {
+				t.Fatalf("incorrect number of conflicts: expected %d, actual %d", len(test.Conflicts), len(conflicts))
+			}
+			for i, c := range conflicts {
+				if !errors.Is(c, &Conflict{}) {
+					t.Errorf("conflict %d does not match an empty Conflict", i)
+				}
+				expected := test.Conflicts[i]
+				expected.msg = c.msg
+				if !reflect.DeepEqual(expected, c) {
+					t.Errorf("incorrect conflict %d\nexpected: %+v\n  actual: %+v", i, expected, c)
+				}
+
+				lines := strings.SplitAfter(out.String(), "\n")
+				first := lines[c.NewRange.Position-1]
+				last := lines[c.NewRange.Position+c.NewRange.Lines-2]
+				if first != conflictOurs || last != conflictTheirs {
+					t.Errorf("conflict %d: range does not cover markers: %q ... %q", i, first, last)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyThreeWayPatchConflict(t *testing.T) {
+	f := &File{
+		OldName:       "file.txt",
+		NewName:       "file.txt",
+		TextFragments: DiffText([]byte("a\nb\n"), []byte("a\nc\n"), DiffOptions{}),
+	}
+
+	var out bytes.Buffer
+	_, err := ApplyThreeWay(&out, []byte("x\ny\n"), []byte("x\ny\n"), f, ApplyOptions{})
+	if !errors.Is(err, &Conflict{}) {
+		t.Fatalf("expected conflict applying patch to base, but got %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package gitdiff
+
+// SetMeta sets the value of key in the Meta of the file, creating the map if
+// needed. Because copies of a file share its map, SetMeta on a copy also
+// changes the original file.
+func (f *File) SetMeta(key string, value interface{}) {
+	if f.Meta == nil {
+		f.Meta = make(map[string]interface{})
+	}
+	f.Meta[key] = value
+}
+
+// joinMeta returns the entries of a and b in a new map. If both have a key,
+// the value from b is used.
+func joinMeta(a, b map[string]interface{}) map[string]interface{} {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+	joined := make(map[string]interface{}, len(a)+len(b))
+	for k, v := range a {
+		joined[k] = v
+	}
+	for k, v := range b {
+		joined[k] = v
+	}
+	return joined
+}
@@ -0,0 +1,91 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileMeta(t *testing.T) {
+	const patch = `diff --git a/file.txt b/file.txt
+index 1c23fcc..40a1b33 100644
+--- a/file.txt
++++ b/file.txt
+@@ -2,3 +2,3 @@
+ b
+-c
++C
+ d
+diff --git a/file.txt b/file.txt
+index 1c23fcc..40a1b33 100644
+--- a/file.txt
++++ b/file.txt
+@@ -10,3 +10,3 @@
+ j
+-k
++K
+ l
+`
+
+	parse := func(t *testing.T) []*File {
+		files, _, err := ParseAll(strings.NewReader(patch))
+		if err != nil {
+			t.Fatalf("unexpected error parsing patch: %v", err)
+		}
+		for _, f := range files {
+			if f.Meta != nil {
+				t.Fatalf("parser set meta: %+v", f.Meta)
+			}
+		}
+		files[0].SetMeta("ticket", "ABC-1")
+		files[1].SetMeta("review", 42)
+		return files
+	}
+
+	tests := map[string]struct {
+		Transform func([]*File) ([]*File, error)
+		Meta      []map[string]interface{}
+	}{
+		"normalize": {
+			Transform: func(files []*File) ([]*File, error) {
+				return Normalize(files, NormalizeOptions{}), nil
+			},
+			Meta: []map[string]interface{}{{"ticket": "ABC-1"}, {"review": 42}},
+		},
+		"anonymize": {
+			Transform: func(files []*File) ([]*File, error) {
+				return AnonymizePaths(files, AnonymizeOptions{}), nil
+			},
+			Meta: []map[string]interface{}{{"ticket": "ABC-1"}, {"review": 42}},
+		},
+		"mergeDuplicates": {
+			Transform: func(files []*File) ([]*File, error) {
+				return MergeDuplicatePaths(files, DuplicatePathOptions{})
+			},
+			Meta: []map[string]interface{}{{"ticket": "ABC-1", "review": 42}},
+		},
+		"splitByOwner": {
+			Transform: func(files []*File) ([]*File, error) {
+				return SplitByOwner(files, func(string) string { return "owner" })["owner"], nil
+			},
+			Meta: []map[string]interface{}{{"ticket": "ABC-1"}, {"review": 42}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := test.Transform(parse(t))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var meta []map[string]interface{}
+			for _, f := range files {
+				meta = append(meta, f.Meta)
+			}
+			if !reflect.DeepEqual(test.Meta, meta) {
+				t.Errorf("incorrect meta\nexpected: %+v\n  actual: %+v", test.Meta, meta)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package gitdiff
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+)
+
+// FileMetrics describes the size of the changes to a file in a patch.
+type FileMetrics struct {
+	// Name is the new name of the file, or the old name if it is deleted.
+	Name string
+
+	Fragments        int
+	MaxFragmentLines int64 // the most lines added and deleted by one fragment
+	LinesAdded       int64
+	LinesDeleted     int64
+
+	// BinarySize is the size of the data in the binary fragment of the file
+	// and BinaryEntropy is the Shannon entropy of that data in bits per byte,
+	// between 0 and 8. Data with high entropy, such as compressed or
+	// encrypted content, cannot be reviewed. Both are zero for text files.
+	BinarySize    int64
+	BinaryEntropy float64
+}
+
+// Churn returns the number of lines added and deleted in the file.
+func (m FileMetrics) Churn() int64 {
+	return m.LinesAdded + m.LinesDeleted
+}
+
+// PatchMetrics describes the size of the changes in a patch.
+type PatchMetrics struct {
+	Files []FileMetrics
+
+	Fragments        int
+	MaxFragmentLines int64
+	LinesAdded       int64
+	LinesDeleted     int64
+	BinarySize       int64
+
+	// MaxBinaryEntropy is the highest entropy of any binary file.
+	MaxBinaryEntropy float64
+
+	// Directories lists the directories that contain the old or new versions
+	// of the changed files, in sorted order. The root directory is ".".
+	Directories []string
+}
+
+// Churn returns the number of lines added and deleted in the patch.
+func (m PatchMetrics) Churn() int64 {
+	return m.LinesAdded + m.LinesDeleted
+}
+
+// Measure computes metrics for the changes in files.
+func Measure(files []*File) PatchMetrics {
+	var m PatchMetrics
+	dirs := make(map[string]bool)
+
+	for _, f := range files {
+		fm := measureFile(f)
+		m.Files = append(m.Files, fm)
+
+		m.Fragments += fm.Fragments
+		m.LinesAdded += fm.LinesAdded
+		m.LinesDeleted += fm.LinesDeleted
+		m.BinarySize += fm.BinarySize
+		if fm.MaxFragmentLines > m.MaxFragmentLines {
+			m.MaxFragmentLines = fm.MaxFragmentLines
+		}
+		if fm.BinaryEntropy > m.MaxBinaryEntropy {
+			m.MaxBinaryEntropy = fm.BinaryEntropy
+		}
+
+		if !f.IsNew && f.OldName != "" {
+			dirs[path.Dir(f.OldName)] = true
+		}
+		if !f.IsDelete && f.NewName != "" {
+			dirs[path.Dir(f.NewName)] = true
+		}
+	}
+
+	for dir := range dirs {
+		m.Directories = append(m.Directories, dir)
+	}
+	sort.Strings(m.Directories)
+	return m
+}
+
+func measureFile(f *File) FileMetrics {
+	fm := FileMetrics{Name: f.NewName, Fragments: len(f.TextFragments)}
+	if f.IsDelete {
+		fm.Name = f.OldName
+	}
+
+	for _, frag := range f.TextFragments {
+		fm.LinesAdded += frag.LinesAdded
+		fm.LinesDeleted += frag.LinesDeleted
+		if n := frag.LinesAdded + frag.LinesDeleted; n > fm.MaxFragmentLines {
+			fm.MaxFragmentLines = n
+		}
+	}
+
+	if f.BinaryFragment != nil {
+		fm.Fragments = 1
+		fm.BinarySize = int64(len(f.BinaryFragment.Data))
+		fm.BinaryEntropy = entropy(f.BinaryFragment.Data)
+	}
+	return fm
+}
+
+// entropy returns the Shannon entropy of data in bits per byte.
+func entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	var e float64
+	n := float64(len(data))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			e -= p * math.Log2(p)
+		}
+	}
+	return e
+}
+
+// Budget sets limits on the size of a patch, for example to flag patches
+// that are too large to review. Limits that are zero are not checked.
+type Budget struct {
+	MaxFiles         int
+	MaxFragments     int
+	MaxFragmentLines int64
+	MaxChurn         int64
+	MaxDirectories   int
+	MaxBinarySize    int64
+	MaxBinaryEntropy float64
+}
+
+// BudgetViolation describes a metric of a patch that exceeds its limit in a
+// Budget.
+type BudgetViolation struct {
+	Metric string
+	Value  float64
+	Limit  float64
+}
+
+func (v BudgetViolation) String() string {
+	return fmt.Sprintf("%s %g exceeds limit of %g", v.Metric, v.Value, v.Limit)
+}
+
+// Check returns the metrics in m that exceed the limits in b. The result is
+// empty if the patch is within the budget.
+func (b Budget) Check(m PatchMetrics) []BudgetViolation {
+	var violations []BudgetViolation
+	check := func(metric string, value, limit float64) {
+		if limit > 0 && value > limit {
+			violations = append(violations, BudgetViolation{metric, value, limit})
+		}
+	}
+
+	check("files", float64(len(m.Files)), float64(b.MaxFiles))
+	check("fragments", float64(m.Fragments), float64(b.MaxFragments))
+	check("fragment lines", float64(m.MaxFragmentLines), float64(b.MaxFragmentLines))
+	check("churn", float64(m.Churn()), float64(b.MaxChurn))
+	check("directories", float64(len(m.Directories)), float64(b.MaxDirectories))
+	check("binary size", float64(m.BinarySize), float64(b.MaxBinarySize))
+	check("binary entropy", m.MaxBinaryEntropy, b.MaxBinaryEntropy)
+	return violations
+}
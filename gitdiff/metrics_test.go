@@ -0,0 +1,138 @@
+package gitdiff
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	files := []*File{
+		{
+			OldName: "src/main.go",
+			NewName: "src/main.go",
+			TextFragments: []*TextFragment{
+				{LinesAdded: 3, LinesDeleted: 1},
+				{LinesAdded: 1, LinesDeleted: 5},
+			},
+		},
+		{
+			OldName:  "docs/old.txt",
+			IsDelete: true,
+			TextFragments: []*TextFragment{
+				{LinesDeleted: 2},
+			},
+		},
+		{
+			NewName:        "logo.png",
+			IsNew:          true,
+			IsBinary:       true,
+			BinaryFragment: &BinaryFragment{Method: BinaryPatchLiteral, Data: []byte{0, 1, 2, 3}},
+		},
+		{
+			OldName:  "a/file.txt",
+			NewName:  "b/file.txt",
+			IsRename: true,
+		},
+	}
+
+	m := Measure(files)
+
+	expectedFiles := []FileMetrics{
+		{Name: "src/main.go", Fragments: 2, MaxFragmentLines: 6, LinesAdded: 4, LinesDeleted: 6},
+		{Name: "docs/old.txt", Fragments: 1, MaxFragmentLines: 2, LinesDeleted: 2},
+		{Name: "logo.png", Fragments: 1, BinarySize: 4, BinaryEntropy: 2},
+		{Name: "b/file.txt"},
+	}
+	if !reflect.DeepEqual(expectedFiles, m.Files) {
+		t.Errorf("incorrect file metrics\nexpected: %+v\n  actual: %+v", expectedFiles, m.Files)
+	}
+
+	if m.Fragments != 4 || m.MaxFragmentLines != 6 || m.LinesAdded != 4 || m.LinesDeleted != 8 || m.Churn() != 12 {
+		t.Errorf("incorrect line metrics: %+v", m)
+	}
+	if m.BinarySize != 4 || m.MaxBinaryEntropy != 2 {
+		t.Errorf("incorrect binary metrics: size %d, entropy %g", m.BinarySize, m.MaxBinaryEntropy)
+	}
+
+	expectedDirs := []string{".", "a", "b", "docs", "src"}
+	if !reflect.DeepEqual(expectedDirs, m.Directories) {
+		t.Errorf("incorrect directories: expected %q, actual %q", expectedDirs, m.Directories)
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	tests := map[string]struct {
+		Data    []byte
+		Entropy float64
+	}{
+		"empty":   {nil, 0},
+		"uniform": {[]byte("aaaa"), 0},
+		"twoBits": {[]byte("abcd"), 2},
+		"allBytes": {
+			Data: func() []byte {
+				b := make([]byte, 256)
+				for i := range b {
+					b[i] = byte(i)
+				}
+				return b
+			}(),
+			Entropy: 8,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if e := entropy(test.Data); math.Abs(e-test.Entropy) > 1e-9 {
+				t.Errorf("incorrect entropy: expected %g, actual %g", test.Entropy, e)
+			}
+		})
+	}
+}
+
+func TestBudgetCheck(t *testing.T) {
+	m := PatchMetrics{
+		Files:            make([]FileMetrics, 3),
+		Fragments:        10,
+		MaxFragmentLines: 120,
+		LinesAdded:       400,
+		LinesDeleted:     200,
+		Directories:      []string{"a", "b"},
+		MaxBinaryEntropy: 7.9,
+	}
+
+	tests := map[string]struct {
+		Budget     Budget
+		Violations []string
+	}{
+		"unlimited": {
+			Budget: Budget{},
+		},
+		"withinBudget": {
+			Budget: Budget{MaxFiles: 3, MaxChurn: 600, MaxDirectories: 2},
+		},
+		"tooLarge": {
+			Budget: Budget{MaxFiles: 2, MaxFragmentLines: 100, MaxChurn: 500, MaxBinaryEntropy: 7.5},
+			Violations: []string{
+				"files 3 exceeds limit of 2",
+				"fragment lines 120 exceeds limit of 100",
+				"churn 600 exceeds limit of 500",
+				"binary entropy 7.9 exceeds limit of 7.5",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var violations []string
+			for _, v := range test.Budget.Check(m) {
+				violations = append(violations, v.String())
+			}
+			if !reflect.DeepEqual(test.Violations, violations) {
+				t.Errorf("incorrect violations\nexpected: %s\n  actual: %s",
+					strings.Join(test.Violations, "; "), strings.Join(violations, "; "))
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package gitdiff
+
+import (
+	"unicode"
+)
+
+// minMovedAlnum is the minimum number of alphanumeric characters in a moved
+// block, like the default for the --color-moved option of git diff.
+const minMovedAlnum = 20
+
+// MovedBlock describes consecutive lines that are deleted in one place in a
+// patch and added, with the same content, in another place.
+type MovedBlock struct {
+	// OldName is the name of the file the lines are deleted from and
+	// OldPosition is the number of the first deleted line in the old version
+	// of the file.
+	OldName     string
+	OldPosition int64
+
+	// NewName is the name of the file the lines are added to and NewPosition
+	// is the number of the first added line in the new version of the file.
+	NewName     string
+	NewPosition int64
+
+	// Lines is the number of lines in the block.
+	Lines int64
+}
+
+// movedLine is a deleted or added line in a patch.
+type movedLine struct {
+	name     string
+	position int64
+	run      int // lines in the same run are consecutive in the patch
+	line     string
+}
+
+// DetectMoves finds blocks of deleted lines in files that are added
+// elsewhere in the same patch, as with the --color-moved option of git diff.
+// A block ends at the first line that is not deleted and at the first line
+// that is not added, so lines separated by context in either place form
+// different blocks. Blocks must contain at least 20 alphanumeric characters
+// and each added line is part of at most one block. Blocks are returned in
+// the order of their deleted lines in the patch.
+func DetectMoves(files []*File) []MovedBlock {
+	var deleted, added []movedLine
+	run := 0
+	for _, f := range files {
+		for _, frag := range f.TextFragments {
+			oldLine, newLine := frag.OldPosition, frag.NewPosition
+			prev := OpContext
+			for _, line := range frag.Lines {
+				if line.Op != prev {
+					run++
+					prev = line.Op
+				}
+				switch line.Op {
+				case OpDelete:
+					deleted = append(deleted, movedLine{f.OldName, oldLine, run, line.Line})
+				case OpAdd:
+					added = append(added, movedLine{f.NewName, newLine, run, line.Line})
+				}
+				if line.Old() {
+					oldLine++
+				}
+				if line.New() {
+					newLine++
+				}
+			}
+		}
+	}
+
+	index := make(map[string][]int)
+	for i, line := range added {
+		index[line.line] = append(index[line.line], i)
+	}
+	used := make([]bool, len(added))
+
+	var blocks []MovedBlock
+	for i := 0; i < len(deleted); {
+		bestStart, bestLen := 0, 0
+		for _, j := range index[deleted[i].line] {
+			if used[j] {
+				continue
+			}
+			n := 1
+			for i+n < len(deleted) && j+n < len(added) &&
+				deleted[i+n].run == deleted[i].run && added[j+n].run == added[j].run &&
+				!used[j+n] && deleted[i+n].line == added[j+n].line {
+				n++
+			}
+			if n > bestLen {
+				bestStart, bestLen = j, n
+			}
+		}
+
+		if bestLen == 0 || countAlnum(deleted[i:i+bestLen]) < minMovedAlnum {
+			i++
+			continue
+		}
+
+		for k := 0; k < bestLen; k++ {
+			used[bestStart+k] = true
+		}
+		del, add := deleted[i], added[bestStart]
+		blocks = append(blocks, MovedBlock{
+			OldName:     del.name,
+			OldPosition: del.position,
+			NewName:     add.name,
+			NewPosition: add.position,
+			Lines:       int64(bestLen),
+		})
+		i += bestLen
+	}
+	return blocks
+}
+
+func countAlnum(lines []movedLine) int {
+	n := 0
+	for _, line := range lines {
+		for _, r := range line.line {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,116 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectMoves(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output []MovedBlock
+	}{
+		"betweenFiles": {
+			Input: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,6 +1,2 @@
+ package main
+-
+-func helper() int {
+-	return computeSomething()
+-}
+ // end
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1,2 +1,6 @@
+ package main
+ // start
++
++func helper() int {
++	return computeSomething()
++}
+`,
+			Output: []MovedBlock{
+				{OldName: "a.go", OldPosition: 2, NewName: "b.go", NewPosition: 3, Lines: 4},
+			},
+		},
+		"withinFile": {
+			Input: `diff --git a/list.txt b/list.txt
+--- a/list.txt
++++ b/list.txt
+@@ -1,5 +1,5 @@
+-the first line of the list
+-the second line of the list
+ middle
+ other
+ last
++the first line of the list
++the second line of the list
+`,
+			Output: []MovedBlock{
+				{OldName: "list.txt", OldPosition: 1, NewName: "list.txt", NewPosition: 4, Lines: 2},
+			},
+		},
+		"tooShort": {
+			Input: `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,3 @@
+-}
+ x
+ y
++}
+`,
+		},
+		"splitByChange": {
+			Input: `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,4 +1,5 @@
+-alpha alpha alpha alpha alpha
+-bravo bravo bravo bravo bravo
+ context
+ context
++alpha alpha alpha alpha alpha
++changed
++bravo bravo bravo bravo bravo
+`,
+			Output: []MovedBlock{
+				{OldName: "a.txt", OldPosition: 1, NewName: "a.txt", NewPosition: 3, Lines: 1},
+				{OldName: "a.txt", OldPosition: 2, NewName: "a.txt", NewPosition: 5, Lines: 1},
+			},
+		},
+		"addedOnce": {
+			Input: `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,4 +1,3 @@
+-this line is duplicated
+ context
+-this line is duplicated
+ context
++this line is duplicated
+`,
+			Output: []MovedBlock{
+				{OldName: "a.txt", OldPosition: 1, NewName: "a.txt", NewPosition: 3, Lines: 1},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Input))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			blocks := DetectMoves(files)
+			if !reflect.DeepEqual(test.Output, blocks) {
+				t.Errorf("incorrect moved blocks\nexpected: %+v\n  actual: %+v", test.Output, blocks)
+			}
+		})
+	}
+}
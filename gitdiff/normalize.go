@@ -0,0 +1,187 @@
+package gitdiff
+
+import (
+	"bytes"
+	"time"
+)
+
+// NormalizeOptions configures Normalize.
+type NormalizeOptions struct {
+	// Positions removes the positions of text fragments, so that patches
+	// making the same changes at different lines of a file are equal.
+	Positions bool
+
+	// Comments removes the comments of text fragments, which depend on the
+	// tool and configuration that generated the patch.
+	Comments bool
+
+	// Headers removes the patch headers of files. Otherwise, only the commit
+	// hashes and dates in the headers are removed.
+	Headers bool
+}
+
+// Normalize returns copies of files without data that changes when the same
+// patch is generated again, such as object IDs, similarity scores, raw
+// entries, and commit hashes and dates, so that patches can be compared with Equal.
+// Options remove more data. Normalized files are meant for comparison and may
+// not format or apply like the original files. The files are not modified.
+func Normalize(files []*File, opts NormalizeOptions) []*File {
+	normalized := make([]*File, len(files))
+	headers := make(map[*PatchHeader]*PatchHeader)
+
+	for i, f := range files {
+		n := *f
+		n.OldOIDPrefix, n.NewOIDPrefix = "", ""
+		n.Score = 0
+		n.Raw = nil
+
+		if h := f.PatchHeader; h != nil && !opts.Headers {
+			if _, ok := headers[h]; !ok {
+				nh := *h
+				nh.SHA = ""
+				nh.AuthorDate, nh.CommitterDate = time.Time{}, time.Time{}
+				headers[h] = &nh
+			}
+			n.PatchHeader = headers[h]
+		} else {
+			n.PatchHeader = nil
+		}
+
+		frags := make(map[*TextFragment]*TextFragment)
+		n.TextFragments = make([]*TextFragment, len(f.TextFragments))
+		for j, frag := range f.TextFragments {
+			nf := *frag
+			if opts.Positions {
+				nf.OldPosition, nf.NewPosition = 0, 0
+			}
+			if opts.Comments {
+				nf.Comment = ""
+			}
+			n.TextFragments[j] = &nf
+			frags[frag] = &nf
+		}
+		if f.CombinedFragments != nil {
+			n.CombinedFragments = make([][]*TextFragment, len(f.CombinedFragments))
+			for j, group := range f.CombinedFragments {
+				n.CombinedFragments[j] = make([]*TextFragment, len(group))
+				for k, frag := range group {
+					n.CombinedFragments[j][k] = frags[frag]
+				}
+			}
+		}
+
+		normalized[i] = &n
+	}
+	return normalized
+}
+
+// Equal returns true if a and b contain the same files in the same order.
+// Files are equal if all of their fields other than annotations are equal,
+// comparing patch headers, raw entries, and fragments by value and treating
+// nil and empty slices as equal. Use Normalize first to ignore data that is not part of the changes.
+func Equal(a, b []*File) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !fileEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func fileEqual(a, b *File) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.OldName == b.OldName && a.NewName == b.NewName &&
+		a.IsNew == b.IsNew && a.IsDelete == b.IsDelete && a.IsCopy == b.IsCopy && a.IsRename == b.IsRename &&
+		a.OldMode == b.OldMode && a.NewMode == b.NewMode &&
+		a.OldOIDPrefix == b.OldOIDPrefix && a.NewOIDPrefix == b.NewOIDPrefix && a.Score == b.Score &&
+		equalLines(a.ExtendedHeaders, b.ExtendedHeaders) &&
+		patchHeaderEqual(a.PatchHeader, b.PatchHeader) &&
+		rawEntryEqual(a.Raw, b.Raw) &&
+		textFragmentsEqual(a.TextFragments, b.TextFragments) &&
+		a.ParentCount == b.ParentCount && combinedFragmentsEqual(a.CombinedFragments, b.CombinedFragments) &&
+		a.IsBinary == b.IsBinary &&
+		binaryFragmentEqual(a.BinaryFragment, b.BinaryFragment) &&
+		binaryFragmentEqual(a.ReverseBinaryFragment, b.ReverseBinaryFragment) &&
+		a.ContentOmitted == b.ContentOmitted
+}
+
+func patchHeaderEqual(a, b *PatchHeader) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.SHA == b.SHA &&
+		identityEqual(a.Author, b.Author) && a.AuthorDate.Equal(b.AuthorDate) &&
+		identityEqual(a.Committer, b.Committer) && a.CommitterDate.Equal(b.CommitterDate) &&
+		a.Title == b.Title && a.Body == b.Body &&
+		a.SubjectPrefix == b.SubjectPrefix && a.BodyAppendix == b.BodyAppendix
+}
+
+func identityEqual(a, b *PatchIdentity) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func rawEntryEqual(a, b *RawEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func textFragmentsEqual(a, b []*TextFragment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !textFragmentEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func textFragmentEqual(a, b *TextFragment) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Comment != b.Comment ||
+		a.OldPosition != b.OldPosition || a.OldLines != b.OldLines ||
+		a.NewPosition != b.NewPosition || a.NewLines != b.NewLines ||
+		a.LinesAdded != b.LinesAdded || a.LinesDeleted != b.LinesDeleted ||
+		a.LeadingContext != b.LeadingContext || a.TrailingContext != b.TrailingContext ||
+		len(a.Lines) != len(b.Lines) {
+		return false
+	}
+	for i := range a.Lines {
+		if a.Lines[i] != b.Lines[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func combinedFragmentsEqual(a, b [][]*TextFragment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !textFragmentsEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func binaryFragmentEqual(a, b *BinaryFragment) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Method == b.Method && a.Size == b.Size && bytes.Equal(a.Data, b.Data)
+}
@@ -0,0 +1,140 @@
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	const patch = `commit 5d9790fec7d95aa223f3d20936340bf55ff3dcbe
+Author: Morton Haypenny <mhaypenny@example.com>
+Date:   Tue Apr 2 22:55:40 2019 -0700
+
+    A single change.
+
+diff --git a/file.txt b/file.txt
+index 1c23fcc..40a1b33 100644
+--- a/file.txt
++++ b/file.txt
+@@ -3,3 +3,3 @@ func main()
+ b
+-c
++C
+ d
+`
+	regenerated := strings.NewReplacer(
+		"5d9790fec7d95aa223f3d20936340bf55ff3dcbe", "a3f5d9e6c2b1f0e9d8c7b6a5f4e3d2c1b0a9f8e7",
+		"Tue Apr 2 22:55:40 2019", "Wed Apr 3 09:12:00 2019",
+		"index 1c23fcc..40a1b33", "index 1c23fccd..40a1b33e",
+	).Replace(patch)
+
+	tests := map[string]struct {
+		Patch   string
+		Options NormalizeOptions
+		Equal   bool
+	}{
+		"regenerated": {
+			Patch: regenerated,
+			Equal: true,
+		},
+		"differentComment": {
+			Patch: strings.Replace(patch, "func main()", "func init()", 1),
+			Equal: false,
+		},
+		"ignoreComments": {
+			Patch:   strings.Replace(patch, "func main()", "func init()", 1),
+			Options: NormalizeOptions{Comments: true},
+			Equal:   true,
+		},
+		"differentPosition": {
+			Patch: strings.Replace(patch, "@@ -3,3 +3,3 @@", "@@ -8,3 +8,3 @@", 1),
+			Equal: false,
+		},
+		"ignorePositions": {
+			Patch:   strings.Replace(patch, "@@ -3,3 +3,3 @@", "@@ -8,3 +8,3 @@", 1),
+			Options: NormalizeOptions{Positions: true},
+			Equal:   true,
+		},
+		"differentTitle": {
+			Patch: strings.Replace(patch, "A single change.", "One change.", 1),
+			Equal: false,
+		},
+		"ignoreHeaders": {
+			Patch:   strings.Replace(patch, "A single change.", "One change.", 1),
+			Options: NormalizeOptions{Headers: true},
+			Equal:   true,
+		},
+		"differentContent": {
+			Patch: strings.Replace(patch, "+C", "+X", 1),
+			Equal: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, _, err := ParseAll(strings.NewReader(patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			b, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			if test.Equal && Equal(a, b) {
+				t.Fatalf("patches are equal before normalization")
+			}
+
+			na, nb := Normalize(a, test.Options), Normalize(b, test.Options)
+			if eq := Equal(na, nb); eq != test.Equal {
+				t.Errorf("incorrect result: expected %t, actual %t", test.Equal, eq)
+			}
+			if a[0].OldOIDPrefix == "" || a[0].PatchHeader.AuthorDate.IsZero() {
+				t.Errorf("input files were modified")
+			}
+		})
+	}
+}
+
+func TestNormalizeCombined(t *testing.T) {
+	const patch = `diff --cc file.txt
+index 1111111,2222222..3333333
+--- a/file.txt
++++ b/file.txt
+@@@ -1,2 -1,2 +1,3 @@@
+  a
+ +b
++ c
+`
+	files, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	n := Normalize(files, NormalizeOptions{Positions: true})[0]
+	for i, group := range n.CombinedFragments {
+		for j, frag := range group {
+			if frag != n.TextFragments[i*len(group)+j] {
+				t.Errorf("combined fragment %d, %d is not a normalized text fragment", i, j)
+			}
+		}
+	}
+	if files[0].TextFragments[0].OldPosition != 1 {
+		t.Errorf("input fragments were modified")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := []*File{{NewName: "file.txt", TextFragments: []*TextFragment{}}}
+	b := []*File{{NewName: "file.txt"}}
+	if !Equal(a, b) {
+		t.Errorf("files with nil and empty fragments are not equal")
+	}
+	if Equal(a, nil) {
+		t.Errorf("patches with different numbers of files are equal")
+	}
+
+	b[0].BinaryFragment = &BinaryFragment{Method: BinaryPatchLiteral}
+	if Equal(a, b) {
+		t.Errorf("files with different binary fragments are equal")
+	}
+}
@@ -0,0 +1,116 @@
+package gitdiff
+
+import (
+	"strings"
+)
+
+// DefaultBinaryNotices are the formats of the notices for binary files that
+// are recognized when the BinaryNotices option is not set. They include the
+// notices of Git and GNU diff in English and the notices of GNU diff in some
+// other locales. The notices of diff --brief, like "Files %s and %s differ",
+// are not included because diff --brief prints them for text files too, so
+// they do not show that a file is binary.
+var DefaultBinaryNotices = []string{
+	"Binary files %s and %s differ",
+	"Binärdateien %s und %s sind verschieden.",
+	"Les fichiers binaires %s et %s sont différents",
+	"Los ficheros binarios %s y %s son distintos",
+	"Los archivos binarios %s y %s son distintos",
+	"I file binari %s e %s sono diversi",
+	"Os arquivos binários %s e %s diferem",
+	"Двоичные файлы %s и %s различаются",
+	"バイナリーファイル %s と %s は異なります",
+	"二进制文件 %s 和 %s 不同",
+}
+
+// binaryNotice is a notice format split at its verbs.
+type binaryNotice struct {
+	prefix, sep, suffix string
+}
+
+// compileBinaryNotices splits the formats of notices, skipping formats that
+// do not have exactly two %s verbs.
+func compileBinaryNotices(formats []string) []binaryNotice {
+	notices := make([]binaryNotice, 0, len(formats))
+	for _, format := range formats {
+		parts := strings.Split(format, "%s")
+		if len(parts) != 3 || parts[1] == "" {
+			continue
+		}
+		notices = append(notices, binaryNotice{parts[0], parts[1], parts[2]})
+	}
+	return notices
+}
+
+var defaultBinaryNotices = compileBinaryNotices(DefaultBinaryNotices)
+
+// parseBinaryNotice returns a binary file for a line that matches one of the
+// notices, like "Binary files x and y differ", or nil if line is not a notice.
+// If notices is nil, the default notices are used.
+func parseBinaryNotice(line string, notices []binaryNotice) *File {
+	if notices == nil {
+		notices = defaultBinaryNotices
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+	for _, notice := range notices {
+		if len(line) < len(notice.prefix)+len(notice.suffix) ||
+			!strings.HasPrefix(line, notice.prefix) ||
+			!strings.HasSuffix(line, notice.suffix) {
+			continue
+		}
+
+		names := line[len(notice.prefix) : len(line)-len(notice.suffix)]
+		if oldName, newName, ok := splitNoticeNames(names, notice.sep); ok {
+			return binaryNoticeFile(oldName, newName)
+		}
+	}
+	return nil
+}
+
+func binaryNoticeFile(oldName, newName string) *File {
+	// notices printed by git without a file header use its default prefixes
+	hasPrefix := func(name, prefix string) bool {
+		return name == devNull || strings.HasPrefix(name, prefix)
+	}
+	if hasPrefix(oldName, "a/") && hasPrefix(newName, "b/") {
+		if oldName != devNull {
+			oldName = oldName[2:]
+		}
+		if newName != devNull {
+			newName = newName[2:]
+		}
+	}
+
+	f := traditionalFile(oldName, newName, false, false)
+	f.IsBinary = true
+	return f
+}
+
+// splitNoticeNames splits the names in a notice like "x and y" at sep. If the
+// names contain sep, the split where the names are the same after the first
+// directory, or where one name is /dev/null, is preferred.
+func splitNoticeNames(s, sep string) (oldName, newName string, ok bool) {
+	for i := 0; i+len(sep) <= len(s); i++ {
+		if s[i:i+len(sep)] != sep {
+			continue
+		}
+
+		o, n, err := parseName(s[:i], 0, 0)
+		if err != nil || n != i {
+			continue
+		}
+		nn, n, err := parseName(s[i+len(sep):], 0, 0)
+		if err != nil || n != len(s)-i-len(sep) {
+			continue
+		}
+
+		if !ok {
+			oldName, newName, ok = o, nn, true
+		}
+		if o == devNull || nn == devNull || trimTreePrefix(o, 1) == trimTreePrefix(nn, 1) {
+			return o, nn, true
+		}
+	}
+	return oldName, newName, ok
+}
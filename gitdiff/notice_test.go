@@ -0,0 +1,82 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryNotices(t *testing.T) {
+	tests := map[string]struct {
+		Input   string
+		Notices []string
+		Output  []*File
+	}{
+		"briefNotIncluded": {
+			Input: "Files a/file.txt and b/file.txt differ\n",
+		},
+		"briefNotices": {
+			Input:   "Files a/image.png and b/image.png differ\n",
+			Notices: []string{"Files %s and %s differ"},
+			Output: []*File{
+				{OldName: "image.png", NewName: "image.png", IsBinary: true},
+			},
+		},
+		"german": {
+			Input: "Binärdateien alt/bild.png und neu/bild.png sind verschieden.\n",
+			Output: []*File{
+				{OldName: "neu/bild.png", NewName: "neu/bild.png", IsBinary: true},
+			},
+		},
+		"french": {
+			Input: "Les fichiers binaires /dev/null et b/image.png sont différents\n",
+			Output: []*File{
+				{NewName: "image.png", IsNew: true, IsBinary: true},
+			},
+		},
+		"multiple": {
+			Input: `Binary files a/one.png and b/one.png differ
+Binary files a/two.png and /dev/null differ
+`,
+			Output: []*File{
+				{OldName: "one.png", NewName: "one.png", IsBinary: true},
+				{OldName: "two.png", IsDelete: true, IsBinary: true},
+			},
+		},
+		"gitHeader": {
+			Input: `diff --git a/image.png b/image.png
+index 1c23282..a2baf3a 100644
+Binärdateien a/image.png und b/image.png sind verschieden.
+`,
+			Output: []*File{
+				{
+					OldName:      "image.png",
+					NewName:      "image.png",
+					OldOIDPrefix: "1c23282",
+					NewOIDPrefix: "a2baf3a",
+					OldMode:      0100644,
+					IsBinary:     true,
+				},
+			},
+		},
+		"customNotices": {
+			Input:   "Bestanden a/image.png en b/image.png zijn verschillend\nBinary files a/x and b/x differ\n",
+			Notices: []string{"Bestanden %s en %s zijn verschillend", "invalid %s"},
+			Output: []*File{
+				{OldName: "image.png", NewName: "image.png", IsBinary: true},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAllWithOptions(strings.NewReader(test.Input), ParseOptions{BinaryNotices: test.Notices})
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			if !reflect.DeepEqual(test.Output, files) {
+				t.Errorf("incorrect files\nexpected: %+v\n  actual: %+v", test.Output, files)
+			}
+		})
+	}
+}
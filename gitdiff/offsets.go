@@ -0,0 +1,52 @@
+package gitdiff
+
+// Span is a range of bytes in the input of a parser, from Start up to but not
+// including End.
+type Span struct {
+	Start int64
+	End   int64
+}
+
+// Len returns the number of bytes in the span.
+func (s Span) Len() int64 {
+	return s.End - s.Start
+}
+
+// Offsets records where files and fragments start and end in a parsed patch,
+// so callers can slice the original input to display or transmit part of a
+// patch without formatting it again. Set the Offsets field of ParseOptions to
+// record offsets while parsing. The zero value is ready to use.
+type Offsets struct {
+	files map[*File]Span
+	frags map[*TextFragment]Span
+}
+
+// File returns the location of f, from the start of its header to the end of
+// its last fragment, and true if f was parsed while recording offsets.
+func (o *Offsets) File(f *File) (Span, bool) {
+	s, ok := o.files[f]
+	return s, ok
+}
+
+// Fragment returns the location of frag, from the start of its header to the
+// end of its last line, and true if frag was parsed while recording offsets.
+// The fragments of a combined diff that belong to the same hunk share the
+// same location.
+func (o *Offsets) Fragment(frag *TextFragment) (Span, bool) {
+	s, ok := o.frags[frag]
+	return s, ok
+}
+
+func (o *Offsets) setFile(f *File, s Span) {
+	if o.files == nil {
+		o.files = make(map[*File]Span)
+	}
+	o.files[f] = s
+}
+
+func (o *Offsets) setFragment(frag *TextFragment, s Span) {
+	if o.frags == nil {
+		o.frags = make(map[*TextFragment]Span)
+	}
+	o.frags[frag] = s
+}
@@ -0,0 +1,73 @@
+package gitdiff
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOffsets(t *testing.T) {
+	patches, err := filepath.Glob("testdata/*.patch")
+	if err != nil {
+		t.Fatalf("unexpected error listing patches: %v", err)
+	}
+
+	for _, patch := range patches {
+		t.Run(filepath.Base(patch), func(t *testing.T) {
+			data, err := ioutil.ReadFile(patch)
+			if err != nil {
+				t.Fatalf("unexpected error reading patch: %v", err)
+			}
+
+			var offsets Offsets
+			files, _, err := ParseAllWithOptions(bytes.NewReader(data), ParseOptions{Offsets: &offsets})
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			for i, f := range files {
+				span, ok := offsets.File(f)
+				if !ok {
+					t.Fatalf("file %d: no offsets recorded", i)
+				}
+
+				// each file parses the same way on its own
+				reparsed, _, err := ParseAll(bytes.NewReader(data[span.Start:span.End]))
+				if err != nil {
+					t.Fatalf("file %d: unexpected error parsing span: %v", i, err)
+				}
+				expected := *f
+				expected.PatchHeader, expected.Raw = nil, nil
+				if len(reparsed) != 1 || !reflect.DeepEqual(&expected, reparsed[0]) {
+					t.Errorf("file %d: span does not contain the file\n%s", i, data[span.Start:span.End])
+				}
+
+				for j, frag := range f.TextFragments {
+					fspan, ok := offsets.Fragment(frag)
+					if !ok {
+						t.Fatalf("file %d, fragment %d: no offsets recorded", i, j)
+					}
+					if fspan.Start < span.Start || fspan.End > span.End || fspan.Len() <= 0 {
+						t.Errorf("file %d, fragment %d: span %+v is not inside file span %+v", i, j, fspan, span)
+					}
+					if raw := string(data[fspan.Start:fspan.End]); !strings.HasPrefix(raw, "@@") {
+						t.Errorf("file %d, fragment %d: span does not start with a header: %q", i, j, raw)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOffsetsNotRecorded(t *testing.T) {
+	var offsets Offsets
+	if _, ok := offsets.File(&File{}); ok {
+		t.Error("expected no offsets for unknown file")
+	}
+	if _, ok := offsets.Fragment(&TextFragment{}); ok {
+		t.Error("expected no offsets for unknown fragment")
+	}
+}
@@ -0,0 +1,138 @@
+package gitdiff
+
+import (
+	"io"
+	"path"
+	"strings"
+)
+
+// OnlyIn is an "Only in dir: name" notice, printed by diff -r for a file or
+// directory that exists in only one of the compared directories. The notice
+// is the same for files and directories, so Name may be a directory, whose
+// content is not listed.
+type OnlyIn struct {
+	Dir  string
+	Name string
+
+	// IsNew is true if Dir is in the new directory of the comparison and
+	// IsDelete is true if it is in the old directory. If the parser could
+	// not find the compared directories, both are false.
+	IsNew    bool
+	IsDelete bool
+}
+
+// Path returns the path of the file in the notice.
+func (o OnlyIn) Path() string {
+	return path.Join(o.Dir, o.Name)
+}
+
+// ParseOnlyInHeader parses an "Only in" notice. If the OnlyInFiles option is
+// set and the side of the notice is known, it returns a new or deleted file
+// for the notice with ContentOmitted set. Otherwise, it reports the notice
+// to the OnlyIn option, if set, and returns nil without advancing, so the
+// notice is part of the text between files.
+func (p *parser) ParseOnlyInHeader() (*File, error) {
+	const prefix = "Only in "
+
+	line := strings.TrimRight(p.Line(0), "\r\n")
+	if !strings.HasPrefix(line, prefix) || (p.onOnlyIn == nil && !p.onlyInFiles) {
+		return nil, nil
+	}
+	i := strings.Index(line, ": ")
+	if i < len(prefix) || i+2 == len(line) {
+		return nil, nil
+	}
+
+	o := OnlyIn{Dir: line[len(prefix):i], Name: line[i+2:]}
+	switch {
+	case p.newRoot != "" && inRoot(o.Dir, p.newRoot):
+		o.IsNew = true
+	case p.oldRoot != "" && inRoot(o.Dir, p.oldRoot):
+		o.IsDelete = true
+	}
+	if p.onOnlyIn != nil {
+		p.onOnlyIn(o)
+	}
+	if !p.onlyInFiles || (!o.IsNew && !o.IsDelete) {
+		return nil, nil
+	}
+
+	if err := p.Next(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	p.fragmentsExpected = false
+
+	f := &File{IsNew: o.IsNew, IsDelete: o.IsDelete, ContentOmitted: true}
+	if o.IsNew {
+		f.NewName = o.Path()
+	} else {
+		f.OldName = o.Path()
+	}
+	return f, nil
+}
+
+// setRoots records the compared directories from the old and new names of a
+// traditional file header or of a diff command line, like "old/file.txt" and
+// "new/file.txt". The directories are what remains of the names after
+// removing the path they have in common at the end, so absolute names like
+// "/tmp/a/file.txt" and "/tmp/b/file.txt" also work. If one name is
+// /dev/null, the directory of the other is its first element. Names that do
+// not share a path or that are not in a directory do not change the recorded
+// directories.
+func (p *parser) setRoots(oldName, newName string) {
+	if oldName == devNull || newName == devNull {
+		if root := rootDir(oldName); root != "" {
+			p.oldRoot = root
+		}
+		if root := rootDir(newName); root != "" {
+			p.newRoot = root
+		}
+		return
+	}
+
+	oldElems := strings.Split(strings.TrimPrefix(oldName, "./"), "/")
+	newElems := strings.Split(strings.TrimPrefix(newName, "./"), "/")
+	common := 0
+	for common < len(oldElems)-1 && common < len(newElems)-1 &&
+		oldElems[len(oldElems)-1-common] == newElems[len(newElems)-1-common] {
+		common++
+	}
+	if common == 0 {
+		return
+	}
+
+	oldRoot := strings.Join(oldElems[:len(oldElems)-common], "/")
+	newRoot := strings.Join(newElems[:len(newElems)-common], "/")
+	if oldRoot != "" && newRoot != "" && oldRoot != newRoot {
+		p.oldRoot, p.newRoot = oldRoot, newRoot
+	}
+}
+
+// setRootsFromCommand records the compared directories from a diff command
+// line printed by diff -r, like "diff -ru old/file.txt new/file.txt".
+func (p *parser) setRootsFromCommand(line string) {
+	if !strings.HasPrefix(line, "diff ") || strings.HasPrefix(line, "diff --git ") {
+		return
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 3 || strings.HasPrefix(fields[len(fields)-2], "-") {
+		return
+	}
+	p.setRoots(fields[len(fields)-2], fields[len(fields)-1])
+}
+
+func rootDir(name string) string {
+	if name == devNull {
+		return ""
+	}
+	name = strings.TrimPrefix(name, "./")
+	if i := strings.IndexByte(name, '/'); i > 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+func inRoot(dir, root string) bool {
+	dir = strings.TrimPrefix(dir, "./")
+	return dir == root || strings.HasPrefix(dir, root+"/")
+}
@@ -0,0 +1,130 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOnlyIn(t *testing.T) {
+	const input = `Only in old: early.txt
+diff -ru old/file.txt new/file.txt
+--- old/file.txt	2019-03-21 23:00:00.0 -0700
++++ new/file.txt	2019-03-21 23:30:00.0 -0700
+@@ -1 +1 @@
+-line 1
++line one
+Only in new/dir: added.txt
+Only in old: removed.txt
+Only in other: unknown.txt
+`
+
+	tests := map[string]struct {
+		Files   bool
+		Output  []*File
+		Notices []OnlyIn
+	}{
+		"default": {
+			Output: []*File{
+				{OldName: "new/file.txt", NewName: "new/file.txt"},
+			},
+		},
+		"collect": {
+			Output: []*File{
+				{OldName: "new/file.txt", NewName: "new/file.txt"},
+			},
+			Notices: []OnlyIn{
+				{Dir: "old", Name: "early.txt"},
+				{Dir: "new/dir", Name: "added.txt", IsNew: true},
+				{Dir: "old", Name: "removed.txt", IsDelete: true},
+				{Dir: "other", Name: "unknown.txt"},
+			},
+		},
+		"files": {
+			Files: true,
+			Output: []*File{
+				{OldName: "new/file.txt", NewName: "new/file.txt"},
+				{NewName: "new/dir/added.txt", IsNew: true, ContentOmitted: true},
+				{OldName: "old/removed.txt", IsDelete: true, ContentOmitted: true},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var notices []OnlyIn
+			opts := ParseOptions{OnlyInFiles: test.Files}
+			if test.Notices != nil {
+				opts.OnlyIn = func(o OnlyIn) { notices = append(notices, o) }
+			}
+
+			files, _, err := ParseAllWithOptions(strings.NewReader(input), opts)
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			for _, f := range files {
+				f.TextFragments = nil
+			}
+			if !reflect.DeepEqual(test.Output, files) {
+				t.Errorf("incorrect files\nexpected: %+v\n  actual: %+v", test.Output, files)
+			}
+			if !reflect.DeepEqual(test.Notices, notices) {
+				t.Errorf("incorrect notices\nexpected: %+v\n  actual: %+v", test.Notices, notices)
+			}
+		})
+	}
+}
+
+func TestOnlyInRoots(t *testing.T) {
+	tests := map[string]struct {
+		Input   string
+		Notices []OnlyIn
+	}{
+		"absolute": {
+			Input: `diff -ru /tmp/a/src/file.txt /tmp/b/src/file.txt
+Only in /tmp/b/src: added.txt
+Only in /tmp/a: removed
+`,
+			Notices: []OnlyIn{
+				{Dir: "/tmp/b/src", Name: "added.txt", IsNew: true},
+				{Dir: "/tmp/a", Name: "removed", IsDelete: true},
+			},
+		},
+		"nested": {
+			Input: `diff -ru ./old/src/file.txt ./new/src/file.txt
+Only in ./new: added.txt
+`,
+			Notices: []OnlyIn{
+				{Dir: "./new", Name: "added.txt", IsNew: true},
+			},
+		},
+		"sameDirectory": {
+			Input: `diff -u src/file.txt.orig src/file.txt
+Only in src: added.txt
+`,
+			Notices: []OnlyIn{
+				{Dir: "src", Name: "added.txt"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var notices []OnlyIn
+			opts := ParseOptions{OnlyIn: func(o OnlyIn) { notices = append(notices, o) }}
+			if _, _, err := ParseAllWithOptions(strings.NewReader(test.Input), opts); err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			if !reflect.DeepEqual(test.Notices, notices) {
+				t.Errorf("incorrect notices\nexpected: %+v\n  actual: %+v", test.Notices, notices)
+			}
+		})
+	}
+}
+
+func TestOnlyInPath(t *testing.T) {
+	if p := (OnlyIn{Dir: "new/dir", Name: "file.txt"}).Path(); p != "new/dir/file.txt" {
+		t.Errorf("incorrect path: %q", p)
+	}
+}
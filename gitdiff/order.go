@@ -0,0 +1,192 @@
+package gitdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DependencyReason describes why one file must be applied before another.
+type DependencyReason int
+
+const (
+	// DependsOnSource means the first file reads the old content of a path
+	// (as the source of a rename or copy, or as a deletion) that the second
+	// file creates
+	DependsOnSource DependencyReason = iota
+	// DependsOnCreate means the first file creates a path that the second
+	// file modifies
+	DependsOnCreate
+	// DependsOnOrder means both files change the same path in a way that
+	// has no natural order, so they are applied in their original order
+	DependsOnOrder
+)
+
+func (r DependencyReason) String() string {
+	switch r {
+	case DependsOnSource:
+		return "source"
+	case DependsOnCreate:
+		return "create"
+	case DependsOnOrder:
+		return "order"
+	}
+	return "unknown"
+}
+
+// Dependency records that the file at index Before must be applied before
+// the file at index After because both involve Path.
+type Dependency struct {
+	Before int
+	After  int
+	Path   string
+	Reason DependencyReason
+}
+
+// CycleError is returned by OrderFiles when the dependencies between files
+// form a cycle, such as when two files swap names.
+type CycleError struct {
+	// Files contains the indices of the files that are part of the cycle or
+	// depend on a file in the cycle
+	Files []int
+	Names []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("gitdiff: dependency cycle between files: %s", strings.Join(e.Names, ", "))
+}
+
+// AnalyzeDependencies finds the dependencies between the files in a patch
+// that matter when applying the files one at a time. A file that reads the
+// old content of a path must be applied before any file that creates the
+// path, a file that creates a path must be applied before any file that
+// modifies it in place, and other files that involve the same path keep
+// their original order.
+func AnalyzeDependencies(files []*File) []Dependency {
+	var deps []Dependency
+	for i, a := range files {
+		for j, b := range files {
+			if i == j {
+				continue
+			}
+			if p, ok := readsBeforeCreate(a, b); ok {
+				deps = append(deps, Dependency{i, j, p, DependsOnSource})
+				continue
+			}
+			if p, ok := createsBeforeModify(a, b); ok {
+				deps = append(deps, Dependency{i, j, p, DependsOnCreate})
+				continue
+			}
+			if i > j {
+				continue
+			}
+			if p, ok := sharedPath(a, b); ok {
+				_, ba := readsBeforeCreate(b, a)
+				_, bc := createsBeforeModify(b, a)
+				if !ba && !bc {
+					deps = append(deps, Dependency{i, j, p, DependsOnOrder})
+				}
+			}
+		}
+	}
+	return deps
+}
+
+// OrderFiles returns the files in an order that satisfies the dependencies
+// found by AnalyzeDependencies, keeping the original order where possible. It
+// returns a *CycleError if the dependencies form a cycle.
+func OrderFiles(files []*File) ([]*File, error) {
+	deps := AnalyzeDependencies(files)
+
+	after := make([][]int, len(files))
+	pending := make([]int, len(files))
+	for _, d := range deps {
+		after[d.Before] = append(after[d.Before], d.After)
+		pending[d.After]++
+	}
+
+	ordered := make([]*File, 0, len(files))
+	done := make([]bool, len(files))
+	for len(ordered) < len(files) {
+		next := -1
+		for i := range files {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			err := &CycleError{}
+			for i, f := range files {
+				if !done[i] {
+					err.Files = append(err.Files, i)
+					err.Names = append(err.Names, fileName(f))
+				}
+			}
+			return nil, err
+		}
+
+		done[next] = true
+		ordered = append(ordered, files[next])
+		for _, j := range after[next] {
+			pending[j]--
+		}
+	}
+	return ordered, nil
+}
+
+// readsBeforeCreate returns true if a reads the old content of a path that b
+// creates.
+func readsBeforeCreate(a, b *File) (string, bool) {
+	if !(a.IsRename || a.IsCopy || a.IsDelete) {
+		return "", false
+	}
+	if !(b.IsNew || b.IsRename || b.IsCopy) {
+		return "", false
+	}
+	return a.OldName, a.OldName == b.NewName
+}
+
+// createsBeforeModify returns true if a creates a path that b modifies.
+func createsBeforeModify(a, b *File) (string, bool) {
+	if !(a.IsNew || a.IsRename || a.IsCopy) {
+		return "", false
+	}
+	if b.IsNew || b.IsDelete || b.IsRename || b.IsCopy {
+		return "", false
+	}
+	return a.NewName, a.NewName == b.OldName
+}
+
+// sharedPath returns a path that both a and b involve.
+func sharedPath(a, b *File) (string, bool) {
+	for _, p := range filePaths(a) {
+		for _, q := range filePaths(b) {
+			if p == q {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+func filePaths(f *File) []string {
+	var paths []string
+	if !f.IsNew && f.OldName != "" {
+		paths = append(paths, f.OldName)
+	}
+	if !f.IsDelete && f.NewName != "" && f.NewName != f.OldName {
+		paths = append(paths, f.NewName)
+	}
+	return paths
+}
+
+// fileName returns the name of a file for use in messages.
+func fileName(f *File) string {
+	switch {
+	case f.IsDelete:
+		return f.OldName
+	case f.IsRename || f.IsCopy:
+		return f.OldName + " => " + f.NewName
+	}
+	return f.NewName
+}
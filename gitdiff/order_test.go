@@ -0,0 +1,109 @@
+package gitdiff
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrderFiles(t *testing.T) {
+	modify := func(name string) *File {
+		return &File{OldName: name, NewName: name}
+	}
+	create := func(name string) *File {
+		return &File{NewName: name, IsNew: true}
+	}
+	remove := func(name string) *File {
+		return &File{OldName: name, IsDelete: true}
+	}
+	rename := func(from, to string) *File {
+		return &File{OldName: from, NewName: to, IsRename: true}
+	}
+
+	tests := map[string]struct {
+		Files []*File
+		Order []int
+		Err   bool
+	}{
+		"independent": {
+			Files: []*File{modify("a"), modify("b"), create("c")},
+			Order: []int{0, 1, 2},
+		},
+		"renameThenReplace": {
+			Files: []*File{create("a"), rename("a", "b")},
+			Order: []int{1, 0},
+		},
+		"deleteThenCreate": {
+			Files: []*File{create("a"), remove("a")},
+			Order: []int{1, 0},
+		},
+		"renameThenModify": {
+			Files: []*File{modify("b"), rename("a", "b")},
+			Order: []int{1, 0},
+		},
+		"duplicatePaths": {
+			Files: []*File{modify("a"), modify("b"), modify("a")},
+			Order: []int{0, 1, 2},
+		},
+		"chain": {
+			Files: []*File{rename("b", "c"), rename("a", "b")},
+			Order: []int{0, 1},
+		},
+		"chainReversed": {
+			Files: []*File{rename("a", "b"), rename("b", "c")},
+			Order: []int{1, 0},
+		},
+		"swap": {
+			Files: []*File{rename("a", "b"), rename("b", "a"), modify("c")},
+			Err:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ordered, err := OrderFiles(test.Files)
+			if test.Err {
+				var cerr *CycleError
+				if !errors.As(err, &cerr) {
+					t.Fatalf("expected cycle error, but got %v", err)
+				}
+				if len(cerr.Files) != 2 {
+					t.Errorf("incorrect files in cycle: %v", cerr.Files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error ordering files: %v", err)
+			}
+			if len(ordered) != len(test.Order) {
+				t.Fatalf("incorrect number of files: expected %d, actual %d", len(test.Order), len(ordered))
+			}
+			for i, idx := range test.Order {
+				if ordered[i] != test.Files[idx] {
+					t.Errorf("incorrect file at position %d: expected %s, actual %s", i, fileName(test.Files[idx]), fileName(ordered[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeDependencies(t *testing.T) {
+	files := []*File{
+		{NewName: "a", IsNew: true},
+		{OldName: "a", NewName: "b", IsRename: true},
+		{OldName: "b", NewName: "b"},
+	}
+
+	deps := AnalyzeDependencies(files)
+	expected := []Dependency{
+		{Before: 1, After: 0, Path: "a", Reason: DependsOnSource},
+		{Before: 1, After: 2, Path: "b", Reason: DependsOnCreate},
+	}
+	if len(deps) != len(expected) {
+		t.Fatalf("incorrect dependencies: expected %+v, actual %+v", expected, deps)
+	}
+	for i := range expected {
+		if deps[i] != expected[i] {
+			t.Errorf("incorrect dependency %d: expected %+v, actual %+v", i, expected[i], deps[i])
+		}
+	}
+}
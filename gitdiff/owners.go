@@ -0,0 +1,149 @@
+package gitdiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// SplitByOwner divides files into groups by the owner of each file, as
+// returned by ownerOf. The owner of a file is the owner of its new name, or
+// of its old name if the file is deleted. Files keep their relative order in
+// each group. Files without an owner are in the group with the empty key.
+func SplitByOwner(files []*File, ownerOf func(path string) string) map[string][]*File {
+	groups := make(map[string][]*File)
+	for _, f := range files {
+		name := f.NewName
+		if f.IsDelete {
+			name = f.OldName
+		}
+		owner := ownerOf(name)
+		groups[owner] = append(groups[owner], f)
+	}
+	return groups
+}
+
+// CodeOwners holds the rules from a CODEOWNERS file, as used by GitHub and
+// GitLab to assign owners to the files in a repository.
+//
+// Each rule is a pattern followed by a list of owners. Patterns follow the
+// rules for .gitignore files: patterns without a slash match files and
+// directories in any directory, other patterns match paths relative to the
+// root of the repository, and patterns that match a directory match all of
+// the files in it. Patterns that end with "/*" only match the files directly
+// in a directory, not the files in its subdirectories. The last rule that
+// matches a file determines its owners. Section headers, such as
+// "[Documentation]", are ignored.
+type CodeOwners struct {
+	rules []codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern   *regexp.Regexp
+	dirOnly   bool
+	filesOnly bool
+	owners    []string
+}
+
+// ParseCodeOwners parses the content of a CODEOWNERS file.
+func ParseCodeOwners(r io.Reader) (*CodeOwners, error) {
+	var c CodeOwners
+
+	br := bufio.NewReader(r)
+	for lineno := 1; ; lineno++ {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if perr := c.parseLine(line); perr != nil {
+			return nil, fmt.Errorf("gitdiff: CODEOWNERS:%d: %v", lineno, perr)
+		}
+		if err == io.EOF {
+			return &c, nil
+		}
+	}
+}
+
+func (c *CodeOwners) parseLine(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' || line[0] == '[' || strings.HasPrefix(line, "^[") {
+		return nil
+	}
+
+	// a backslash escapes spaces and leading hashes in patterns
+	var pattern strings.Builder
+	i := 0
+	for ; i < len(line) && line[i] != ' ' && line[i] != '\t'; i++ {
+		if line[i] == '\\' && i+1 < len(line) && (line[i+1] == ' ' || line[i+1] == '#') {
+			i++
+		}
+		pattern.WriteByte(line[i])
+	}
+
+	var owners []string
+	for _, field := range strings.Fields(line[i:]) {
+		if strings.HasPrefix(field, "#") {
+			break
+		}
+		owners = append(owners, field)
+	}
+
+	p := pattern.String()
+	rule := codeOwnersRule{owners: owners}
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimSuffix(p, "/")
+	}
+	if p == "" {
+		return fmt.Errorf("invalid pattern: %s", pattern.String())
+	}
+	rule.filesOnly = strings.HasSuffix(p, "/*")
+
+	re, err := attrPatternRegexp(p)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %s: %v", pattern.String(), err)
+	}
+	rule.pattern = re
+	c.rules = append(c.rules, rule)
+	return nil
+}
+
+// Owners returns the owners of the file at name, a slash-separated path
+// relative to the root of the repository. It returns nil if no rule matches
+// the file or if the last matching rule has no owners. Owners may be called
+// on a nil *CodeOwners, in which case it returns nil.
+func (c *CodeOwners) Owners(name string) []string {
+	if c == nil {
+		return nil
+	}
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].matches(name) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// OwnerOf returns the owners of the file at name separated by spaces. It can
+// be used with SplitByOwner to group files by their owners.
+func (c *CodeOwners) OwnerOf(name string) string {
+	return strings.Join(c.Owners(name), " ")
+}
+
+func (r codeOwnersRule) matches(name string) bool {
+	if !r.dirOnly && r.pattern.MatchString(name) {
+		return true
+	}
+	if r.filesOnly {
+		return false
+	}
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if r.pattern.MatchString(dir) {
+			return true
+		}
+	}
+	return false
+}
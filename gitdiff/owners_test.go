@@ -0,0 +1,93 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodeOwners(t *testing.T) {
+	owners, err := ParseCodeOwners(strings.NewReader(`# default owners
+*       @org/core
+
+[Documentation]
+/docs/  @org/docs @alice   # docs team
+*.md    @org/writers
+/build/logs/
+scripts/* @org/ops
+**/testdata/** @org/qa
+with\ space.txt @bob
+`))
+	if err != nil {
+		t.Fatalf("unexpected error parsing CODEOWNERS: %v", err)
+	}
+
+	tests := map[string][]string{
+		"main.go":                    {"@org/core"},
+		"docs/guide/intro.txt":       {"@org/docs", "@alice"},
+		"docs/README.md":             {"@org/writers"},
+		"src/docs/intro.txt":         {"@org/core"},
+		"build/logs/out.log":         nil,
+		"build/main.o":               {"@org/core"},
+		"scripts/deploy.sh":          {"@org/ops"},
+		"scripts/lib/util.sh":        {"@org/core"},
+		"gitdiff/testdata/a.patch":   {"@org/qa"},
+		"with space.txt":             {"@bob"},
+		"dir/with space.txt":         {"@bob"},
+		"gitdiff/testdata/x/y.patch": {"@org/qa"},
+	}
+	for name, expected := range tests {
+		if actual := owners.Owners(name); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("incorrect owners for %s: expected %q, actual %q", name, expected, actual)
+		}
+	}
+
+	if owner := owners.OwnerOf("docs/a.txt"); owner != "@org/docs @alice" {
+		t.Errorf("incorrect owner: %q", owner)
+	}
+
+	var nilOwners *CodeOwners
+	if actual := nilOwners.Owners("main.go"); actual != nil {
+		t.Errorf("expected no owners from nil CodeOwners, but got %q", actual)
+	}
+}
+
+func TestParseCodeOwnersError(t *testing.T) {
+	_, err := ParseCodeOwners(strings.NewReader("*.go @a\n/ @b\n"))
+	if err == nil {
+		t.Fatal("expected error parsing invalid pattern, but got nil")
+	}
+	if !strings.Contains(err.Error(), "CODEOWNERS:2") {
+		t.Errorf("error does not include line number: %v", err)
+	}
+}
+
+func TestSplitByOwner(t *testing.T) {
+	files := []*File{
+		{OldName: "docs/a.md", NewName: "docs/a.md"},
+		{OldName: "main.go", NewName: "main.go"},
+		{OldName: "docs/old.md", IsDelete: true},
+		{NewName: "README", IsNew: true},
+		{OldName: "docs/b.md", NewName: "src/b.go", IsRename: true},
+	}
+
+	ownerOf := func(path string) string {
+		switch {
+		case strings.HasPrefix(path, "docs/"):
+			return "docs"
+		case strings.HasSuffix(path, ".go"):
+			return "go"
+		}
+		return ""
+	}
+
+	groups := SplitByOwner(files, ownerOf)
+	expected := map[string][]*File{
+		"docs": {files[0], files[2]},
+		"go":   {files[1], files[4]},
+		"":     {files[3]},
+	}
+	if !reflect.DeepEqual(expected, groups) {
+		t.Errorf("incorrect groups\nexpected: %v\n  actual: %v", expected, groups)
+	}
+}
@@ -5,6 +5,7 @@ package gitdiff
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -14,9 +15,12 @@ const commitPrefix = "commit"
 
 // Parse parses a patch with changes to one or more files. Any content before
 // the first file is returned as the second value. If an error occurs while
-// parsing, it returns all files parsed before the error.
+// parsing, it returns all files parsed before the error. Like ParseAll,
+// compressed input is decompressed with the decompressors added by
+// RegisterDecompressor and input with a byte order mark or an encoded mail
+// body is decoded.
 func Parse(r io.Reader) (<-chan *File, error) {
-	p := newParser(r)
+	p := &NewParser(r, ParseOptions{}).p
 	out := make(chan *File)
 
 	if err := p.Next(); err != nil {
@@ -31,6 +35,7 @@ func Parse(r io.Reader) (<-chan *File, error) {
 		defer close(out)
 
 		ph := &PatchHeader{}
+		var raw []*RawEntry
 		for {
 			file, pre, err := p.ParseNextFileHeader()
 			if err != nil {
@@ -41,28 +46,24 @@ func Parse(r io.Reader) (<-chan *File, error) {
 				continue
 			}
 
+			pre, entries := splitRawEntries(pre)
 			if strings.Contains(pre, commitPrefix) {
 				ph, _ = ParsePatchHeader(pre)
+				raw = entries
+			} else if len(entries) > 0 {
+				raw = entries
 			}
 
 			if file == nil {
 				break
 			}
 
-			for _, fn := range []func(*File) (int, error){
-				p.ParseTextFragments,
-				p.ParseBinaryFragments,
-			} {
-				n, err := fn(file)
-				if err != nil {
-					return
-				}
-				if n > 0 {
-					break
-				}
+			if err := p.ParseFragments(file); err != nil {
+				return
 			}
 
 			file.PatchHeader = ph
+			file.Raw = findRawEntry(raw, file)
 			out <- file
 		}
 	}(out, r)
@@ -70,7 +71,312 @@ func Parse(r io.Reader) (<-chan *File, error) {
 	return out, nil
 }
 
-// TODO(bkeyes): consider exporting the parser type with configuration
+// ParseAll parses a patch like Parse, but returns all of the files at once
+// along with the content before the first file. Unlike Parse, it stops and
+// returns an error if any part of the patch is invalid, along with the files
+// parsed before the error.
+func ParseAll(r io.Reader) ([]*File, string, error) {
+	return ParseAllWithOptions(r, ParseOptions{})
+}
+
+// ParseOptions configures parsing.
+type ParseOptions struct {
+	// RejectUnknownHeaders makes parsing fail if a Git file header contains
+	// an extended header line that is not recognized. By default, these
+	// lines are kept in the ExtendedHeaders field of the file.
+	RejectUnknownHeaders bool
+
+	// ErrorContext is the number of lines before and after the line of a
+	// parse error that are included in the error message as an excerpt of
+	// the input. If zero, errors do not include an excerpt.
+	ErrorContext int
+
+	// Interner, if set, interns the names and other repeated strings of the
+	// parsed files. Share an Interner between calls to reduce the memory
+	// used by many parsed patches.
+	Interner *Interner
+
+	// Offsets, if set, records the location of each parsed file and fragment
+	// in the input.
+	Offsets *Offsets
+
+	// KeepEncoding disables the detection of byte order marks at the start
+	// of the input and of encoded email bodies. By default, a UTF-8 byte
+	// order mark is skipped and input that starts with a UTF-16 byte order
+	// mark, as written by some Windows editors, is transcoded to UTF-8
+	// before parsing. If the input is an email message with a body in the
+	// quoted-printable or base64 content transfer encoding, the body is
+	// decoded before parsing. The offsets of transcoded or decoded input
+	// refer to the decoded text.
+	KeepEncoding bool
+
+	// KeepCompressed disables the detection of compressed input. By default,
+	// input that starts with the magic bytes of a decompressor added with
+	// RegisterDecompressor, such as gzip or bzip2, is decompressed before
+	// parsing. The offsets of decompressed input refer to the decompressed
+	// text.
+	KeepCompressed bool
+
+	// MaxDecompressedBytes is the maximum size of decompressed input. If the
+	// input decompresses to more bytes, parsing stops with an error matching
+	// ErrPatchTooLarge, which protects services that limit the size of their
+	// input from small compressed inputs that expand to very large patches.
+	// If zero, DefaultMaxDecompressedBytes is used. If negative, the size is
+	// not limited.
+	MaxDecompressedBytes int64
+
+	// PreserveCR keeps the carriage returns at the end of the content lines
+	// of fragments in patches with CRLF line endings. If the first line of
+	// the input ends in CRLF, as when an email client converts the line
+	// endings of a patch, the parser removes the carriage return from every
+	// line that ends in CRLF, so the headers parse and the content lines end
+	// in LF, as in the original patch. Set PreserveCR if the files the patch
+	// applies to also use CRLF line endings.
+	PreserveCR bool
+
+	// Recount ignores the line counts in the headers of text fragments and
+	// counts the lines of each fragment instead, like the --recount option
+	// of git apply. A fragment ends at the first line that is not a content
+	// line or that starts the next fragment or file. Use it for patches
+	// that were edited by hand without updating the fragment headers.
+	Recount bool
+
+	// RejectEmptyContextLines makes parsing fail if a fragment contains an
+	// empty line instead of a context line with a single space, as written
+	// by newer versions of GNU diff and by some editors that remove trailing
+	// whitespace. By default, these lines are empty context lines.
+	RejectEmptyContextLines bool
+
+	// LenientFragmentHeaders accepts text fragment headers with extra
+	// whitespace between their fields, like "@@  -1,3  +1,4  @@".
+	LenientFragmentHeaders bool
+
+	// BinaryNotices are the formats of lines that say two binary files
+	// differ without including their data, like "Binary files %s and %s
+	// differ". Each format has two %s verbs for the old and new names of the
+	// file; formats without exactly two verbs are ignored. Lines that match
+	// a format produce binary files, either in a Git file header or on their
+	// own, as printed by diff -r. If nil, DefaultBinaryNotices is used.
+	BinaryNotices []string
+
+	// OnlyIn, if set, is called with each "Only in dir: name" notice, as
+	// printed by diff -r for files that exist in only one of the compared
+	// directories. Use it to collect the notices in a separate list.
+	OnlyIn func(OnlyIn)
+
+	// OnlyInFiles adds a file for each "Only in" notice: a new file if the
+	// notice is for the new directory of the comparison or a deleted file if
+	// it is for the old directory. The files have no fragments and have
+	// ContentOmitted set, so new files never apply and deleted files only
+	// apply with the AllowContentOmitted option. Because diff -r prints the
+	// same notice for directories, a file may be a directory; callers that
+	// apply these files must be prepared for that. The directories of the
+	// comparison are found from the names in the traditional file headers
+	// and diff command lines before the notice; notices before the first of
+	// these do not add files. By default, the notices are ignored like other
+	// text between files.
+	OnlyInFiles bool
+}
+
+// ParseAllWithOptions is like ParseAll, but uses the given options.
+func ParseAllWithOptions(r io.Reader, opts ParseOptions) ([]*File, string, error) {
+	return NewParser(r, opts).ParseAll()
+}
+
+// Parser parses patches with a fixed set of options. Unlike
+// ParseAllWithOptions, a Parser can be reset to parse another input while
+// reusing its buffers, so services that parse many patches can keep parsers
+// in a sync.Pool instead of allocating new ones for each patch.
+type Parser struct {
+	p    parser
+	br   *bufio.Reader
+	opts ParseOptions
+
+	// state for parsing one file at a time with nextFile
+	started bool
+	header  *PatchHeader
+	raw     []*RawEntry
+}
+
+// NewParser returns a Parser that reads a patch from r.
+func NewParser(r io.Reader, opts ParseOptions) *Parser {
+	pp := &Parser{opts: opts}
+	pp.Reset(r)
+	return pp
+}
+
+// Reset discards the state of the Parser and makes it read a new patch from
+// r, keeping its options and reusing its buffers.
+func (pp *Parser) Reset(r io.Reader) {
+	pp.p = parser{history: pp.p.history[:0]}
+	pp.started, pp.header, pp.raw = false, nil, nil
+
+	p := &pp.p
+	if sr, ok := r.(stringReader); ok && pp.opts.KeepEncoding && pp.opts.KeepCompressed {
+		p.r = sr
+	} else {
+		br, ok := r.(*bufio.Reader)
+		if !ok {
+			if pp.br == nil {
+				pp.br = bufio.NewReader(r)
+			} else {
+				pp.br.Reset(r)
+			}
+			br = pp.br
+		}
+
+		if !pp.opts.KeepCompressed {
+			max := pp.opts.MaxDecompressedBytes
+			if max == 0 {
+				max = DefaultMaxDecompressedBytes
+			}
+			br = decompressInput(br, max)
+		}
+
+		p.r = br
+		if !pp.opts.KeepEncoding {
+			p.r, p.offset = decodeInput(br)
+		}
+	}
+
+	p.rejectUnknownHeaders = pp.opts.RejectUnknownHeaders
+	p.preserveCR = pp.opts.PreserveCR
+	p.recount = pp.opts.Recount
+	p.rejectEmptyContext = pp.opts.RejectEmptyContextLines
+	p.lenientFragmentHeaders = pp.opts.LenientFragmentHeaders
+	p.onOnlyIn = pp.opts.OnlyIn
+	p.onlyInFiles = pp.opts.OnlyInFiles
+	if pp.opts.BinaryNotices != nil {
+		p.binaryNotices = compileBinaryNotices(pp.opts.BinaryNotices)
+	}
+	p.errorContext = pp.opts.ErrorContext
+	if o := pp.opts.Offsets; o != nil {
+		p.onFile = func(f *File, start, end int64) { o.setFile(f, Span{start, end}) }
+		p.onFragment = func(frags []*TextFragment, start, end int64) {
+			for _, frag := range frags {
+				o.setFragment(frag, Span{start, end})
+			}
+		}
+	}
+}
+
+// ParseAll parses the patch like ParseAll, returning all of the files and
+// the content before the first file. Call Reset before parsing another patch.
+func (pp *Parser) ParseAll() ([]*File, string, error) {
+	files, preamble, err := pp.p.ParseFiles()
+	setPreambleInfo(files, preamble)
+	if pp.opts.Interner != nil {
+		for _, f := range files {
+			pp.opts.Interner.InternFile(f)
+		}
+	}
+	return files, preamble, err
+}
+
+// setPreambleInfo sets the patch header and raw entries of files from the
+// content before the first file.
+func setPreambleInfo(files []*File, preamble string) {
+	header, raw := splitRawEntries(preamble)
+	var ph *PatchHeader
+	if strings.Contains(header, commitPrefix) {
+		ph, _ = ParsePatchHeader(header)
+	}
+	for _, f := range files {
+		f.PatchHeader = ph
+		f.Raw = findRawEntry(raw, f)
+	}
+}
+
+// ParseFragments parses the text, combined, or binary fragments that follow a
+// file header and attaches them to f.
+func (p *parser) ParseFragments(f *File) error {
+	if f.IsBinary {
+		// files from binary notices are complete without fragments, and
+		// the next line may be the notice for another file
+		return p.finishFragments(f)
+	}
+
+	for _, fn := range []func(*File) (int, error){
+		p.ParseTextFragments,
+		p.ParseCombinedTextFragments,
+		p.ParseBinaryFragments,
+	} {
+		n, err := fn(f)
+		if err != nil {
+			var terr *TruncatedPatchError
+			if errors.As(err, &terr) && terr.File == "" {
+				terr.File = fileName(f)
+			}
+			return err
+		}
+		if n > 0 {
+			return nil
+		}
+	}
+	if p.fragmentsExpected {
+		terr := p.Truncated("missing fragments after file header")
+		terr.File = fileName(f)
+		return terr
+	}
+	return p.finishFragments(f)
+}
+
+// finishFragments marks deleted files without fragments as omitting their
+// content.
+func (p *parser) finishFragments(f *File) error {
+	if f.IsDelete && f.BinaryFragment == nil && !isEmptyBlobOID(f.OldOIDPrefix) {
+		f.ContentOmitted = true
+	}
+	return nil
+}
+
+// isEmptyBlobOID returns true if oid is a prefix of the SHA1 or SHA256 hash
+// of an empty blob. An empty oid is assumed to be empty.
+func isEmptyBlobOID(oid string) bool {
+	const (
+		emptySHA1   = "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"
+		emptySHA256 = "473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813"
+	)
+	return strings.HasPrefix(emptySHA1, oid) || strings.HasPrefix(emptySHA256, oid)
+}
+
+// ParseFiles parses all of the files in the stream, returning the files and
+// the content before the first file. Unlike Parse, it stops and returns an
+// error if any part of the stream is invalid.
+func (p *parser) ParseFiles() ([]*File, string, error) {
+	if err := p.Next(); err != nil {
+		if err == io.EOF {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	var files []*File
+	var preamble string
+	for {
+		file, pre, err := p.ParseNextFileHeader()
+		if err != nil {
+			return files, preamble, err
+		}
+		if len(files) == 0 {
+			preamble = pre
+		}
+		if file == nil {
+			break
+		}
+
+		if err := p.ParseFragments(file); err != nil {
+			return files, preamble, err
+		}
+		if p.onFile != nil {
+			p.onFile(file, p.fileStart, p.offset)
+		}
+		files = append(files, file)
+	}
+	return files, preamble, nil
+}
+
+// TODO(bkeyes): consider exposing more configuration in Parser
 // this would enable OID validation, p-value guessing, and prefix stripping
 // by allowing users to set or override defaults
 
@@ -92,6 +398,69 @@ type parser struct {
 	eof    bool
 	lineno int64
 	lines  [3]string
+
+	// offset is the byte offset of the current line in the input and
+	// fileStart is the offset of the header of the last file
+	offset    int64
+	fileStart int64
+
+	// fragmentsExpected is true if the input ended after the names in the
+	// last file header, which are only present if fragments follow
+	fragmentsExpected bool
+
+	// onAdd, if set, is called with the new line number and content of each
+	// added line as fragments are parsed
+	onAdd func(lineno int64, line string)
+
+	// rejectUnknownHeaders is true if unrecognized extended header lines are
+	// an error instead of being added to the file
+	rejectUnknownHeaders bool
+
+	// discardLines is true if the lines of parsed fragments are not needed
+	// and should not be kept in memory
+	discardLines bool
+
+	// onFragment, if set, is called after parsing each text or combined
+	// fragment with the fragment and the byte offsets of its first line and
+	// of the line after it
+	onFragment func(frags []*TextFragment, start, end int64)
+
+	// errorContext is the number of lines around an error to include in the
+	// message and history holds up to that many lines before the current line
+	errorContext int
+	history      []string
+
+	// onFile, if set, is called by ParseFiles after parsing each file with
+	// the byte offsets of its header and of the line after its last fragment
+	onFile func(f *File, start, end int64)
+
+	// crlf is true if the first line of the input ends in CRLF, as when an
+	// email client converts the line endings of a patch. The carriage
+	// return is then removed from the end of each line and cr records the
+	// lines that had one.
+	crlf bool
+	cr   [3]bool
+
+	// preserveCR is true if the carriage returns removed from the content
+	// lines of fragments are restored
+	preserveCR bool
+
+	// recount, rejectEmptyContext, and lenientFragmentHeaders are set from
+	// the options of the same names
+	recount                bool
+	rejectEmptyContext     bool
+	lenientFragmentHeaders bool
+
+	// binaryNotices are the formats of binary notices, or nil to use the
+	// default formats
+	binaryNotices []binaryNotice
+
+	// onOnlyIn and onlyInFiles are set from the OnlyIn and OnlyInFiles
+	// options, and oldRoot and newRoot are the compared directories of a
+	// recursive diff, if known
+	onOnlyIn         func(OnlyIn)
+	onlyInFiles      bool
+	oldRoot, newRoot string
 }
 
 func newParser(r io.Reader) *parser {
@@ -115,6 +484,17 @@ func (p *parser) Next() error {
 				return err
 			}
 		}
+	} else {
+		p.offset += int64(len(p.lines[0]))
+		if p.cr[0] {
+			p.offset++
+		}
+		if p.errorContext > 0 {
+			if len(p.history) == p.errorContext {
+				p.history = p.history[1:]
+			}
+			p.history = append(p.history, p.lines[0])
+		}
 	}
 
 	err := p.shiftLines()
@@ -130,12 +510,53 @@ func (p *parser) Next() error {
 	return nil
 }
 
-func (p *parser) shiftLines() (err error) {
+func (p *parser) shiftLines() error {
 	for i := 0; i < len(p.lines)-1; i++ {
 		p.lines[i] = p.lines[i+1]
+		p.cr[i] = p.cr[i+1]
+	}
+
+	line, err := p.r.ReadString('\n')
+	if p.lineno == 0 && p.lines[len(p.lines)-1] == "" {
+		crlf, cerr := detectCRLF(line)
+		if cerr != nil {
+			return cerr
+		}
+		p.crlf = crlf
+	}
+
+	line, cr := trimCR(line, p.crlf)
+	p.lines[len(p.lines)-1], p.cr[len(p.lines)-1] = line, cr
+	return err
+}
+
+// detectCRLF returns true if the first line of the input ends in CRLF. It
+// returns an error if the line uses CR line terminators, which would make
+// the whole input a single line.
+func detectCRLF(first string) (bool, error) {
+	if strings.Contains(strings.ReplaceAll(first, "\r\n", ""), "\r") {
+		return false, errors.New("gitdiff: line 1: input uses CR line terminators, which must be converted to LF or CRLF")
+	}
+	return strings.HasSuffix(first, "\r\n"), nil
+}
+
+// trimCR replaces the CRLF at the end of line with LF if crlf is true. It
+// returns the line and true if it removed a carriage return.
+func trimCR(line string, crlf bool) (string, bool) {
+	if crlf && strings.HasSuffix(line, "\r\n") {
+		return line[:len(line)-2] + "\n", true
+	}
+	return line, false
+}
+
+// content returns data, the content of the current line of a fragment,
+// restoring the carriage return removed from the line if the parser
+// preserves them.
+func (p *parser) content(data string) string {
+	if p.preserveCR && p.cr[0] && strings.HasSuffix(data, "\n") {
+		return data[:len(data)-1] + "\r\n"
 	}
-	p.lines[len(p.lines)-1], err = p.r.ReadString('\n')
-	return
+	return data
 }
 
 // Line returns a line from the parser without advancing it. A delta of 0
@@ -147,7 +568,87 @@ func (p *parser) Line(delta uint) string {
 	return p.lines[delta]
 }
 
-// Errorf generates an error and appends the current line information.
+// Errorf generates an error and appends the current line information. If
+// the parser has an error context, the error includes an excerpt of the input.
 func (p *parser) Errorf(delta int64, msg string, args ...interface{}) error {
-	return fmt.Errorf("gitdiff: line %d: %s", p.lineno+delta, fmt.Sprintf(msg, args...))
+	err := fmt.Sprintf("gitdiff: line %d: %s", p.lineno+delta, fmt.Sprintf(msg, args...))
+	if p.errorContext > 0 {
+		lines := append([]string{}, p.history...)
+		for _, line := range p.lines {
+			if line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+		if ex := formatExcerpt(lines, p.lineno-int64(len(p.history)), p.lineno+delta, p.errorContext); ex != "" {
+			err += "\n" + ex
+		}
+	}
+	return errors.New(err)
+}
+
+// ErrTruncatedPatch matches errors returned when the input ends in the middle
+// of a file header or fragment, such as when a download is interrupted. Use
+// errors.Is to test for it and errors.As with a *TruncatedPatchError to get
+// details.
+var ErrTruncatedPatch = errors.New("truncated patch")
+
+// TruncatedPatchError describes where a truncated patch ended.
+type TruncatedPatchError struct {
+	// Line is the number of the last line of the input.
+	Line int64
+
+	// File is the name of the incomplete file, if known. Fragment is the
+	// header of the incomplete fragment or empty if the input ended in a file
+	// header or binary fragment.
+	File     string
+	Fragment string
+
+	// ExpectedOld and ExpectedNew are the number of old and new lines in the
+	// incomplete text fragment according to its header. FoundOld and FoundNew
+	// are the number of lines present before the end of the input.
+	ExpectedOld, ExpectedNew int64
+	FoundOld, FoundNew       int64
+
+	msg string
+}
+
+func (e *TruncatedPatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "gitdiff: line %d: %v", e.Line, ErrTruncatedPatch)
+	if e.File != "" {
+		fmt.Fprintf(&b, ": %s", e.File)
+	}
+	if e.Fragment != "" {
+		fmt.Fprintf(&b, ": %s: expected %d old and %d new lines, found %d and %d",
+			strings.TrimSpace(e.Fragment), e.ExpectedOld, e.ExpectedNew, e.FoundOld, e.FoundNew)
+	}
+	if e.msg != "" {
+		fmt.Fprintf(&b, ": %s", e.msg)
+	}
+	return b.String()
+}
+
+// Is returns true if target is ErrTruncatedPatch.
+func (e *TruncatedPatchError) Is(target error) bool {
+	return target == ErrTruncatedPatch
+}
+
+// Truncated generates a *TruncatedPatchError for the end of the input.
+func (p *parser) Truncated(msg string, args ...interface{}) *TruncatedPatchError {
+	line := p.lineno
+	if p.eof {
+		line--
+	}
+	return &TruncatedPatchError{Line: line, msg: fmt.Sprintf(msg, args...)}
+}
+
+// truncatedFragment generates a *TruncatedPatchError for a text fragment that
+// is missing lines.
+func (p *parser) truncatedFragment(frag *TextFragment, oldLines, newLines int64) *TruncatedPatchError {
+	err := p.Truncated("")
+	err.Fragment = frag.Header()
+	err.ExpectedOld, err.ExpectedNew = frag.OldLines, frag.NewLines
+	err.FoundOld, err.FoundNew = frag.OldLines-oldLines, frag.NewLines-newLines
+	return err
 }
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -531,6 +535,74 @@ Date:   Tue Apr 2 22:55:40 2019 -0700
 	}
 }
 
+func TestParseAll(t *testing.T) {
+	tests := map[string]struct {
+		Input    string
+		Files    int
+		Preamble string
+		Err      bool
+	}{
+		"twoFiles": {
+			Input: "testdata/two_files.patch",
+			Files: 2,
+			Preamble: `commit 5d9790fec7d95aa223f3d20936340bf55ff3dcbe
+Author: Morton Haypenny <mhaypenny@example.com>
+Date:   Tue Apr 2 22:55:40 2019 -0700
+
+    A file with multiple fragments.
+
+    The content is arbitrary.
+
+`,
+		},
+		"invalidFragment": {
+			Input: "diff --git a/file.txt b/file.txt\n" +
+				"--- a/file.txt\n" +
+				"+++ b/file.txt\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"-old line\n" +
+				"+new line\n",
+			Err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var r io.Reader = strings.NewReader(test.Input)
+			if strings.HasPrefix(test.Input, "testdata/") {
+				f, err := os.Open(test.Input)
+				if err != nil {
+					t.Fatalf("unexpected error opening input file: %v", err)
+				}
+				defer f.Close()
+				r = f
+			}
+
+			files, preamble, err := ParseAll(r)
+			if test.Err {
+				if err == nil {
+					t.Fatalf("expected error parsing patch, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			if len(files) != test.Files {
+				t.Fatalf("incorrect number of parsed files: expected %d, actual %d", test.Files, len(files))
+			}
+			if preamble != test.Preamble {
+				t.Errorf("incorrect preamble\nexpected: %q\n  actual: %q", test.Preamble, preamble)
+			}
+			for _, f := range files {
+				if f.PatchHeader == nil || f.PatchHeader.Title != "A file with multiple fragments." {
+					t.Errorf("file %s: incorrect patch header: %+v", f.NewName, f.PatchHeader)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkParse(b *testing.B) {
 	var inputDiff string
 	{
@@ -601,3 +673,312 @@ func asTime(s string) time.Time {
 	}
 	return t
 }
+
+func TestParseTruncated(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output *TruncatedPatchError
+	}{
+		"midFragment": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,4 +1,4 @@
+ line 1
+-line 2
++line two
+`,
+			Output: &TruncatedPatchError{
+				Line:        7,
+				File:        "file.txt",
+				Fragment:    "@@ -1,4 +1,4 @@ ",
+				ExpectedOld: 4, ExpectedNew: 4,
+				FoundOld: 2, FoundNew: 2,
+			},
+		},
+		"afterFragmentHeader": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@
+`,
+			Output: &TruncatedPatchError{
+				Line:        4,
+				File:        "file.txt",
+				Fragment:    "@@ -1,2 +1,2 @@ ",
+				ExpectedOld: 2, ExpectedNew: 2,
+			},
+		},
+		"afterNames": {
+			Input: `diff --git a/file.txt b/file.txt
+index 1111111..2222222 100644
+--- a/file.txt
++++ b/file.txt
+`,
+			Output: &TruncatedPatchError{
+				Line: 4,
+				File: "file.txt",
+				msg:  "missing fragments after file header",
+			},
+		},
+		"midBinary": {
+			Input: `diff --git a/ten.bin b/ten.bin
+new file mode 100644
+index 0000000..77b068b
+GIT binary patch
+literal 40
+gcmZQzU|?i` + "`" + `U?w2V48*KJ%mKu_Kr9NxN<eH500b)lkN^Mx
+`,
+			Output: &TruncatedPatchError{
+				Line: 6,
+				File: "ten.bin",
+				msg:  "binary patch: unexpected EOF",
+			},
+		},
+		"combined": {
+			Input: `diff --cc file.txt
+index 1111111,2222222..3333333
+--- a/file.txt
++++ b/file.txt
+@@@ -1,2 -1,2 +1,3 @@@
+  line 1
++ line from first
+`,
+			Output: &TruncatedPatchError{
+				Line:        7,
+				File:        "file.txt",
+				Fragment:    "@@ -1,2 +1,3 @@ ",
+				ExpectedOld: 2, ExpectedNew: 3,
+				FoundOld: 1, FoundNew: 2,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := ParseAll(strings.NewReader(test.Input))
+			if !errors.Is(err, ErrTruncatedPatch) {
+				t.Fatalf("expected truncated patch error, but got %v", err)
+			}
+
+			var terr *TruncatedPatchError
+			if !errors.As(err, &terr) {
+				t.Fatalf("error is not a *TruncatedPatchError: %T", err)
+			}
+			if !reflect.DeepEqual(test.Output, terr) {
+				t.Errorf("incorrect error\nexpected: %+v\n  actual: %+v", test.Output, terr)
+			}
+		})
+	}
+
+	t.Run("miscountIsNotTruncated", func(t *testing.T) {
+		_, _, err := ParseAll(strings.NewReader(`diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ line 1
+-line 2
++line two
+diff --git a/other.txt b/other.txt
+`))
+		if err == nil || errors.Is(err, ErrTruncatedPatch) {
+			t.Fatalf("expected miscount error, but got %v", err)
+		}
+	})
+}
+
+func TestParseContentOmitted(t *testing.T) {
+	tests := map[string]struct {
+		Input   string
+		Omitted bool
+	}{
+		"irreversibleDelete": {
+			Input: `diff --git a/file.txt b/file.txt
+deleted file mode 100644
+index 1234567..0000000
+`,
+			Omitted: true,
+		},
+		"deleteEmptyFile": {
+			Input: `diff --git a/file.txt b/file.txt
+deleted file mode 100644
+index e69de29..0000000
+`,
+			Omitted: false,
+		},
+		"deleteWithContent": {
+			Input: `diff --git a/file.txt b/file.txt
+deleted file mode 100644
+index 1234567..0000000
+--- a/file.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-line 1
+`,
+			Omitted: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Input))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("expected 1 file, but got %d", len(files))
+			}
+			if files[0].ContentOmitted != test.Omitted {
+				t.Errorf("incorrect ContentOmitted: expected %t, actual %t", test.Omitted, files[0].ContentOmitted)
+			}
+		})
+	}
+}
+
+func TestParseRejectUnknownHeaders(t *testing.T) {
+	input := `diff --git a/file.txt b/file.txt
+old mode 100644
+new mode 100755
+mode 100644,100644..100644
+`
+
+	files, _, err := ParseAll(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	if len(files) != 1 || !reflect.DeepEqual(files[0].ExtendedHeaders, []string{"mode 100644,100644..100644"}) {
+		t.Fatalf("incorrect extended headers: %+v", files)
+	}
+
+	_, _, err = ParseAllWithOptions(strings.NewReader(input), ParseOptions{RejectUnknownHeaders: true})
+	if err == nil {
+		t.Fatal("expected error parsing patch, but got nil")
+	}
+	if !strings.Contains(err.Error(), "line 4: git file header: unknown extended header") {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
+
+func TestParserReset(t *testing.T) {
+	patches, err := filepath.Glob("testdata/*.patch")
+	if err != nil {
+		t.Fatalf("unexpected error listing patches: %v", err)
+	}
+
+	var p *Parser
+	for i := 0; i < 2; i++ {
+		for _, patch := range patches {
+			data, err := ioutil.ReadFile(patch)
+			if err != nil {
+				t.Fatalf("unexpected error reading patch: %v", err)
+			}
+
+			expected, expectedPreamble, err := ParseAll(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s: unexpected error parsing patch: %v", patch, err)
+			}
+
+			// hide the ReadString method to use the parser's own reader
+			r := iotest.HalfReader(bytes.NewReader(data))
+			if p == nil {
+				p = NewParser(r, ParseOptions{ErrorContext: 2})
+			} else {
+				p.Reset(r)
+			}
+
+			files, preamble, err := p.ParseAll()
+			if err != nil {
+				t.Fatalf("%s: unexpected error parsing patch after reset: %v", patch, err)
+			}
+			if preamble != expectedPreamble {
+				t.Errorf("%s: incorrect preamble\nexpected: %q\n  actual: %q", patch, expectedPreamble, preamble)
+			}
+			if !reflect.DeepEqual(expected, files) {
+				t.Errorf("%s: incorrect files after reset", patch)
+			}
+		}
+	}
+}
+
+func TestParseLineEndings(t *testing.T) {
+	const patch = `diff --git a/file.txt b/file.txt
+index 1111111..2222222 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ line 1
+
+-line 3
++line three
+`
+	crlf := strings.ReplaceAll(patch, "\n", "\r\n")
+
+	expected, _, err := ParseAll(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	lines := func(files []*File) []string {
+		var lines []string
+		for _, line := range files[0].TextFragments[0].Lines {
+			lines = append(lines, line.Line)
+		}
+		return lines
+	}
+
+	tests := map[string]struct {
+		Input string
+		Opts  ParseOptions
+		Lines []string
+		Err   string
+	}{
+		"crlf": {
+			Input: crlf,
+			Lines: lines(expected),
+		},
+		"crlfPreserve": {
+			Input: crlf,
+			Opts:  ParseOptions{PreserveCR: true},
+			Lines: []string{"line 1\r\n", "\r\n", "line 3\r\n", "line three\r\n"},
+		},
+		"mixed": {
+			Input: strings.Replace(crlf, "+line three\r\n", "+line three\n", 1),
+			Opts:  ParseOptions{PreserveCR: true},
+			Lines: []string{"line 1\r\n", "\r\n", "line 3\r\n", "line three\n"},
+		},
+		"lfWithCRContent": {
+			Input: strings.Replace(patch, "+line three\n", "+line three\r\n", 1),
+			Lines: []string{"line 1\n", "\n", "line 3\n", "line three\r\n"},
+		},
+		"cr": {
+			Input: strings.ReplaceAll(patch, "\n", "\r"),
+			Err:   "CR line terminators",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var offsets Offsets
+			test.Opts.Offsets = &offsets
+
+			files, _, err := ParseAllWithOptions(strings.NewReader(test.Input), test.Opts)
+			if test.Err != "" {
+				assertError(t, test.Err, err, "parsing patch")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			if files[0].OldName != "file.txt" || files[0].NewName != "file.txt" || files[0].OldOIDPrefix != "1111111" {
+				t.Errorf("incorrect file header: %+v", files[0])
+			}
+			if actual := lines(files); !reflect.DeepEqual(test.Lines, actual) {
+				t.Errorf("incorrect lines\nexpected: %q\n  actual: %q", test.Lines, actual)
+			}
+			if span, _ := offsets.File(files[0]); span.End != int64(len(test.Input)) {
+				t.Errorf("incorrect file end: expected %d, actual %d", len(test.Input), span.End)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ApplyState records the progress of ApplyPartial, so that an apply stopped
+// by a conflict can be resumed after the conflict is fixed. It can be
+// serialized as text with MarshalText to hand it to another process.
+type ApplyState struct {
+	// Fragment is the index in the text fragments of the file of the next
+	// fragment to apply.
+	Fragment int
+
+	// Line is the zero-indexed line of the source where applying continues.
+	// The output written so far is the result for the lines before it.
+	Line int64
+
+	// Offset is the difference between the recorded and actual positions of
+	// the last applied fragment, used to locate the next one.
+	Offset int64
+}
+
+// ApplyPartial applies the text fragments of f to src, starting from state,
+// and writes the result to dst. Use the zero ApplyState to start at the
+// beginning of the file. The options are used as with an Applier, but
+// Filters and Attributes are ignored.
+//
+// If a fragment does not apply, ApplyPartial writes the result of the
+// fragments before it and the source lines up to the position where the
+// fragment should apply and returns the state at that point along with the
+// error. Callers can then resolve the conflict, either by changing the
+// source and calling ApplyPartial again with the returned state, or by
+// writing their own result for the fragment to dst and continuing with the
+// state returned by Skip. If all fragments apply, the rest of the source is
+// written to dst and the error is nil.
+func ApplyPartial(dst io.Writer, src io.ReaderAt, f *File, state ApplyState, opts ApplyOptions) (ApplyState, error) {
+	if f.IsBinary {
+		return state, applyError(errors.New("partial apply of binary files is not supported"))
+	}
+	if state.Fragment < 0 || state.Fragment > len(f.TextFragments) || state.Line < 0 {
+		return state, applyError(fmt.Errorf("invalid apply state: %s", state))
+	}
+
+	// the state needs the line after each fragment, which is not known
+	// for fragments applied from the end of the source
+	opts.AnchorTail = false
+
+	a := NewApplierWithOptions(src, opts)
+	a.nextLine, a.offset = state.Line, state.Offset
+	a.applyType = applyText
+
+	var b bytes.Buffer
+	for i := state.Fragment; i < len(f.TextFragments); i++ {
+		frag := f.TextFragments[i]
+
+		b.Reset()
+		if err := a.ApplyTextFragment(&b, frag); err != nil {
+			a.nextLine, a.offset = state.Line, state.Offset
+			n, werr := a.copyToFragment(dst, frag)
+			state.Line += n
+			if werr != nil {
+				return state, werr
+			}
+			return state, applyError(err, fragNum(i))
+		}
+		if _, err := dst.Write(b.Bytes()); err != nil {
+			return state, applyError(err)
+		}
+		state = ApplyState{Fragment: i + 1, Line: a.nextLine, Offset: a.offset}
+	}
+	return state, applyError(a.Flush(dst))
+}
+
+// copyToFragment writes the source lines from the next line up to the
+// position where f should apply given the current offset. It returns the
+// number of lines written.
+func (a *Applier) copyToFragment(dst io.Writer, f *TextFragment) (int64, error) {
+	fragStart := fragmentOldStart(f) + a.offset
+	if fragStart <= a.nextLine {
+		return 0, nil
+	}
+
+	lines := make([][]byte, fragStart-a.nextLine)
+	n, err := a.lineSrc.ReadLinesAt(lines, a.nextLine)
+	if err != nil && err != io.EOF {
+		return 0, applyError(err, lineNum(a.nextLine+int64(n)))
+	}
+	for i, line := range lines[:n] {
+		if _, err := dst.Write(line); err != nil {
+			return int64(i), applyError(err)
+		}
+	}
+	return int64(n), nil
+}
+
+// Skip returns the state after the fragment of f that failed to apply at
+// s, as if it applied at the current line. Use it after writing a manual
+// resolution of the fragment to continue with the next fragment.
+func (s ApplyState) Skip(f *File) ApplyState {
+	if s.Fragment < 0 || s.Fragment >= len(f.TextFragments) {
+		return s
+	}
+	return ApplyState{
+		Fragment: s.Fragment + 1,
+		Line:     s.Line + f.TextFragments[s.Fragment].OldLines,
+		Offset:   s.Offset,
+	}
+}
+
+func (s ApplyState) String() string {
+	return fmt.Sprintf("%d:%d:%d", s.Fragment, s.Line, s.Offset)
+}
+
+// MarshalText encodes the state as a short token.
+func (s ApplyState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state encoded by MarshalText.
+func (s *ApplyState) UnmarshalText(text []byte) error {
+	var state ApplyState
+	var extra string
+	n, _ := fmt.Sscanf(string(text), "%d:%d:%d%s", &state.Fragment, &state.Line, &state.Offset, &extra)
+	if n != 3 {
+		return fmt.Errorf("gitdiff: invalid apply state: %q", text)
+	}
+	*s = state
+	return nil
+}
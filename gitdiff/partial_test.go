@@ -0,0 +1,106 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApplyPartial(t *testing.T) {
+	src := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n"
+	dst := strings.Replace(strings.Replace(src, "3\n", "three\n", 1), "17\n", "seventeen\n", 1)
+	f := &File{TextFragments: DiffText([]byte(src), []byte(dst), DiffOptions{Context: 1})}
+	if len(f.TextFragments) != 2 {
+		t.Fatalf("patch should have 2 fragments, but it has %d", len(f.TextFragments))
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		var out bytes.Buffer
+		state, err := ApplyPartial(&out, strings.NewReader(src), f, ApplyState{}, ApplyOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error applying patch: %v", err)
+		}
+		if out.String() != dst {
+			t.Errorf("incorrect output\nexpected: %q\n  actual: %q", dst, out.String())
+		}
+		if state.Fragment != 2 {
+			t.Errorf("incorrect state: %s", state)
+		}
+	})
+
+	conflicted := strings.Replace(src, "18\n", "eighteen\n", 1)
+	partial := strings.Replace(conflicted, "3\n", "three\n", 1)
+	partial = partial[:strings.Index(partial, "16\n")]
+
+	apply := func(t *testing.T) (*bytes.Buffer, ApplyState) {
+		var out bytes.Buffer
+		state, err := ApplyPartial(&out, strings.NewReader(conflicted), f, ApplyState{}, ApplyOptions{})
+		if !errors.Is(err, &Conflict{}) {
+			t.Fatalf("expected conflict applying patch, but got %v", err)
+		}
+		var aerr *ApplyError
+		if !errors.As(err, &aerr) || aerr.Fragment != 2 {
+			t.Errorf("incorrect error: %#v", err)
+		}
+		if out.String() != partial {
+			t.Errorf("incorrect partial output\nexpected: %q\n  actual: %q", partial, out.String())
+		}
+		if expected := (ApplyState{Fragment: 1, Line: 15}); state != expected {
+			t.Errorf("incorrect state: expected %s, actual %s", expected, state)
+		}
+		return &out, state
+	}
+
+	t.Run("resumeFixedSource", func(t *testing.T) {
+		out, state := apply(t)
+
+		fixed := strings.Replace(conflicted, "eighteen\n", "18\n", 1)
+		state, err := ApplyPartial(out, strings.NewReader(fixed), f, state, ApplyOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error resuming apply: %v", err)
+		}
+		if out.String() != dst {
+			t.Errorf("incorrect output\nexpected: %q\n  actual: %q", dst, out.String())
+		}
+		if state.Fragment != 2 {
+			t.Errorf("incorrect state: %s", state)
+		}
+	})
+
+	t.Run("resumeSkip", func(t *testing.T) {
+		out, state := apply(t)
+
+		out.WriteString("16\nseventeen\neighteen\n")
+		if _, err := ApplyPartial(out, strings.NewReader(conflicted), f, state.Skip(f), ApplyOptions{}); err != nil {
+			t.Fatalf("unexpected error resuming apply: %v", err)
+		}
+		expected := strings.Replace(dst, "18\n", "eighteen\n", 1)
+		if out.String() != expected {
+			t.Errorf("incorrect output\nexpected: %q\n  actual: %q", expected, out.String())
+		}
+	})
+}
+
+func TestApplyStateText(t *testing.T) {
+	state := ApplyState{Fragment: 3, Line: 120, Offset: -4}
+
+	text, err := state.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error marshaling state: %v", err)
+	}
+
+	var decoded ApplyState
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatalf("unexpected error unmarshaling state: %v", err)
+	}
+	if decoded != state {
+		t.Errorf("incorrect state: expected %s, actual %s", state, decoded)
+	}
+
+	for _, invalid := range []string{"", "1:2", "1:2:3:4", "a:b:c"} {
+		if err := decoded.UnmarshalText([]byte(invalid)); err == nil {
+			t.Errorf("expected error unmarshaling %q, but got nil", invalid)
+		}
+	}
+}
@@ -187,6 +187,22 @@ func ParsePatchDate(s string) (time.Time, error) {
 // + PatchHeader.Title + "\n" + PatchHeader.Body + "\n" +
 // PatchHeader.BodyAppendix`.
 func ParsePatchHeader(s string) (*PatchHeader, error) {
+	return ParsePatchHeaderWithOptions(s, PatchHeaderOptions{})
+}
+
+// PatchHeaderOptions configures ParsePatchHeaderWithOptions.
+type PatchHeaderOptions struct {
+	// Scissors removes the content of the body of an email before the last
+	// scissors line, such as "-- >8 --", like the --scissors option of git
+	// am. Headers for the subject, author, and date at the start of the
+	// content after the line replace those of the email. This lets patches
+	// be sent as part of a discussion, with the discussion above the line.
+	Scissors bool
+}
+
+// ParsePatchHeaderWithOptions is like ParsePatchHeader, but uses the given
+// options.
+func ParsePatchHeaderWithOptions(s string, opts PatchHeaderOptions) (*PatchHeader, error) {
 	r := bufio.NewReader(strings.NewReader(s))
 
 	var line string
@@ -208,10 +224,10 @@ func ParsePatchHeader(s string) (*PatchHeader, error) {
 
 	switch {
 	case strings.HasPrefix(line, mailHeaderPrefix):
-		return parseHeaderMail(line, r)
+		return parseHeaderMail(line, r, opts)
 	case strings.HasPrefix(line, mailMinimumHeaderPrefix):
 		r = bufio.NewReader(strings.NewReader(s))
-		return parseHeaderMail("", r)
+		return parseHeaderMail("", r, opts)
 	case strings.HasPrefix(line, prettyHeaderPrefix):
 		return parseHeaderPretty(line, r)
 	}
@@ -362,7 +378,7 @@ func scanMessageBody(s *bufio.Scanner, indent string, separateAppendix bool) (st
 	return body.String(), appendix.String()
 }
 
-func parseHeaderMail(mailLine string, r io.Reader) (*PatchHeader, error) {
+func parseHeaderMail(mailLine string, r io.Reader, opts PatchHeaderOptions) (*PatchHeader, error) {
 	msg, err := mail.ReadMessage(r)
 	if err != nil {
 		return nil, err
@@ -401,7 +417,26 @@ func parseHeaderMail(mailLine string, r io.Reader) (*PatchHeader, error) {
 	subject := msg.Header.Get("Subject")
 	h.SubjectPrefix, h.Title = parseSubject(subject)
 
-	s := bufio.NewScanner(msg.Body)
+	body := msg.Body
+	if r := decodeTransferEncoding(msg.Header.Get("Content-Transfer-Encoding"), body); r != nil {
+		body = r
+	}
+
+	if opts.Scissors {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		rest, ok := cutScissors(string(data))
+		if ok {
+			if rest, err = parseInBodyHeaders(h, rest); err != nil {
+				return nil, err
+			}
+		}
+		body = strings.NewReader(rest)
+	}
+
+	s := bufio.NewScanner(body)
 	h.Body, h.BodyAppendix = scanMessageBody(s, "", true)
 	if s.Err() != nil {
 		return nil, s.Err()
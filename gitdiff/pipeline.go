@@ -0,0 +1,196 @@
+package gitdiff
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Pipeline processes the files of a patch in stages as they are parsed, so
+// large patches can be filtered, checked, and written or applied without
+// keeping all of their files in memory. Each file passes through the stages
+// in order: the filters select files, the transforms modify them, the
+// analyzers scan their added lines, and the sink receives the result. All
+// stages are optional.
+type Pipeline struct {
+	// ParseOptions configures how the patch is parsed. The Offsets option is
+	// not supported.
+	ParseOptions ParseOptions
+
+	// Filters select the files to process. Files are skipped unless every
+	// filter returns true.
+	Filters []func(f *File) bool
+
+	// Transforms are called in order on each selected file and return the
+	// file to pass to the next stage, which may be f itself after modifying
+	// it. If a transform returns nil, the file is skipped.
+	Transforms []func(f *File) (*File, error)
+
+	// Analyzers scan the lines added by each transformed file. Run returns
+	// the findings of all analyzers.
+	Analyzers []Scanner
+
+	// Sink, if set, is called with each file after the other stages, for
+	// example to write it with FormatSink or apply it with TreeSink.
+	Sink func(f *File) error
+}
+
+// Run reads a patch from r and processes each file with the stages of the
+// pipeline as soon as it is parsed. It returns the findings of the analyzers.
+// Run stops at the first error from parsing or from a stage, returning the
+// findings reported before the error.
+func (pl Pipeline) Run(r io.Reader) ([]Finding, error) {
+	opts := pl.ParseOptions
+	opts.Offsets = nil
+	pp := NewParser(r, opts)
+
+	var findings []Finding
+	for {
+		f, err := pp.nextFile()
+		if err == io.EOF {
+			return findings, nil
+		}
+		if err != nil {
+			return findings, err
+		}
+
+		if !pl.selects(f) {
+			continue
+		}
+		for _, t := range pl.Transforms {
+			next, err := t(f)
+			if err != nil {
+				return findings, fmt.Errorf("gitdiff: %s: %w", fileName(f), err)
+			}
+			if f = next; f == nil {
+				break
+			}
+		}
+		if f == nil {
+			continue
+		}
+
+		for _, a := range pl.Analyzers {
+			findings = append(findings, scanFile(f, a)...)
+		}
+		if pl.Sink != nil {
+			if err := pl.Sink(f); err != nil {
+				return findings, err
+			}
+		}
+	}
+}
+
+// selects returns true if every filter of the pipeline selects f.
+func (pl Pipeline) selects(f *File) bool {
+	for _, filter := range pl.Filters {
+		if !filter(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanFile calls s for each line added by the text fragments of f.
+func scanFile(f *File, s Scanner) []Finding {
+	name := f.NewName
+	if f.IsDelete {
+		name = f.OldName
+	}
+
+	var findings []Finding
+	for _, frag := range f.TextFragments {
+		lineno := frag.NewPosition
+		for _, line := range frag.Lines {
+			if line.Op == OpAdd {
+				findings = append(findings, s.ScanLine(name, lineno, line.Line)...)
+			}
+			if line.New() {
+				lineno++
+			}
+		}
+	}
+	return findings
+}
+
+// nextFile parses and returns the next file of the patch, or io.EOF if there
+// are no more files. Like Parse, it sets the patch header of each file from
+// the last commit header that precedes it, so logs with many commits can be
+// processed one file at a time.
+func (pp *Parser) nextFile() (*File, error) {
+	p := &pp.p
+	if !pp.started {
+		pp.started = true
+		if err := p.Next(); err != nil {
+			return nil, err
+		}
+	}
+
+	f, pre, err := p.ParseNextFileHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	pre, entries := splitRawEntries(pre)
+	if strings.Contains(pre, commitPrefix) {
+		pp.header, _ = ParsePatchHeader(pre)
+		pp.raw = entries
+	} else if len(entries) > 0 {
+		pp.raw = entries
+	}
+
+	if f == nil {
+		return nil, io.EOF
+	}
+	if err := p.ParseFragments(f); err != nil {
+		return nil, err
+	}
+
+	f.PatchHeader = pp.header
+	f.Raw = findRawEntry(pp.raw, f)
+	if pp.opts.Interner != nil {
+		pp.opts.Interner.InternFile(f)
+	}
+	return f, nil
+}
+
+// PathFilter returns a pipeline filter that selects files with an old or new
+// name that matches one of the patterns, using the syntax of path.Match.
+func PathFilter(patterns ...string) func(f *File) bool {
+	return func(f *File) bool {
+		for _, pattern := range patterns {
+			for _, name := range []string{f.OldName, f.NewName} {
+				if ok, _ := path.Match(pattern, name); ok && name != "" {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// NormalizeTransform returns a pipeline transform that normalizes each file
+// with Normalize and opts.
+func NormalizeTransform(opts NormalizeOptions) func(f *File) (*File, error) {
+	return func(f *File) (*File, error) {
+		return Normalize([]*File{f}, opts)[0], nil
+	}
+}
+
+// FormatSink returns a pipeline sink that writes each file to w in the git
+// diff format.
+func FormatSink(w io.Writer) func(f *File) error {
+	return func(f *File) error {
+		return FormatFiles(w, []*File{f})
+	}
+}
+
+// TreeSink returns a pipeline sink that applies each file to tree with
+// BuildTree. Files are applied one at a time, so unlike BuildTree with all of
+// the files, an error leaves the changes of earlier files in the tree.
+func TreeSink(tree TreeWriter) func(f *File) error {
+	return func(f *File) error {
+		return BuildTree([]*File{f}, tree)
+	}
+}
@@ -0,0 +1,170 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pipelinePatch = `commit 5d9790fec7d95aa223f3d20936340bf55ff3dcbe
+Author: Morton Haypenny <mhaypenny@example.com>
+Date:   Tue Apr 2 22:55:40 2019 -0700
+
+    A commit with several files.
+
+diff --git a/docs/readme.txt b/docs/readme.txt
+index 1111111..2222222 100644
+--- a/docs/readme.txt
++++ b/docs/readme.txt
+@@ -1,2 +1,2 @@
+ line 1
+-line 2
++line two
+diff --git a/src/main.go b/src/main.go
+index 3333333..4444444 100644
+--- a/src/main.go
++++ b/src/main.go
+@@ -1 +1,2 @@
+ package main
++// TODO: remove
+diff --git a/src/skip.go b/src/skip.go
+index 5555555..6666666 100644
+--- a/src/skip.go
++++ b/src/skip.go
+@@ -1 +1 @@
+-package old
++package skip
+`
+
+func TestPipeline(t *testing.T) {
+	todo := ScannerFunc(func(file string, lineno int64, line string) []Finding {
+		if strings.Contains(line, "TODO") {
+			return []Finding{{File: file, Line: lineno, Rule: "todo"}}
+		}
+		return nil
+	})
+
+	var names []string
+	var out bytes.Buffer
+	sink := FormatSink(&out)
+
+	pl := Pipeline{
+		Filters: []func(*File) bool{PathFilter("src/*", "docs/*")},
+		Transforms: []func(*File) (*File, error){
+			func(f *File) (*File, error) {
+				if f.NewName == "src/skip.go" {
+					return nil, nil
+				}
+				return f, nil
+			},
+			NormalizeTransform(NormalizeOptions{Headers: true}),
+		},
+		Analyzers: []Scanner{todo},
+		Sink: func(f *File) error {
+			names = append(names, f.NewName)
+			if f.PatchHeader != nil {
+				t.Errorf("%s: expected normalized file without a patch header", f.NewName)
+			}
+			return sink(f)
+		},
+	}
+
+	findings, err := pl.Run(strings.NewReader(pipelinePatch))
+	if err != nil {
+		t.Fatalf("unexpected error running pipeline: %v", err)
+	}
+
+	expectedFindings := []Finding{{File: "src/main.go", Line: 2, Rule: "todo"}}
+	if !reflect.DeepEqual(expectedFindings, findings) {
+		t.Errorf("incorrect findings\nexpected: %+v\n  actual: %+v", expectedFindings, findings)
+	}
+
+	expectedNames := []string{"docs/readme.txt", "src/main.go"}
+	if !reflect.DeepEqual(expectedNames, names) {
+		t.Errorf("incorrect files\nexpected: %q\n  actual: %q", expectedNames, names)
+	}
+
+	if !strings.Contains(out.String(), "diff --git a/src/main.go b/src/main.go\n") || strings.Contains(out.String(), "skip.go") {
+		t.Errorf("incorrect formatted output:\n%s", out.String())
+	}
+}
+
+func TestPipelinePatchHeader(t *testing.T) {
+	var titles []string
+	pl := Pipeline{
+		Sink: func(f *File) error {
+			if f.PatchHeader == nil {
+				t.Fatalf("%s: missing patch header", f.NewName)
+			}
+			titles = append(titles, f.PatchHeader.Title)
+			return nil
+		},
+	}
+	if _, err := pl.Run(strings.NewReader(pipelinePatch)); err != nil {
+		t.Fatalf("unexpected error running pipeline: %v", err)
+	}
+
+	expected := []string{"A commit with several files.", "A commit with several files.", "A commit with several files."}
+	if !reflect.DeepEqual(expected, titles) {
+		t.Errorf("incorrect titles\nexpected: %q\n  actual: %q", expected, titles)
+	}
+}
+
+func TestPipelineTreeSink(t *testing.T) {
+	tree := &memTree{
+		blobs: map[string]string{"docs/readme.txt": "line 1\nline 2\n"},
+		modes: map[string]os.FileMode{},
+	}
+
+	pl := Pipeline{
+		Filters: []func(*File) bool{PathFilter("docs/*")},
+		Sink:    TreeSink(tree),
+	}
+	if _, err := pl.Run(strings.NewReader(pipelinePatch)); err != nil {
+		t.Fatalf("unexpected error running pipeline: %v", err)
+	}
+	if data := tree.blobs["docs/readme.txt"]; data != "line 1\nline two\n" {
+		t.Errorf("incorrect content: %q", data)
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	errTransform := errors.New("transform failed")
+
+	tests := map[string]struct {
+		Input    string
+		Pipeline Pipeline
+		Err      interface{}
+	}{
+		"transform": {
+			Input: pipelinePatch,
+			Pipeline: Pipeline{
+				Transforms: []func(*File) (*File, error){
+					func(f *File) (*File, error) { return nil, errTransform },
+				},
+			},
+			Err: errTransform,
+		},
+		"sink": {
+			Input: pipelinePatch,
+			Pipeline: Pipeline{
+				Sink: func(f *File) error { return errTransform },
+			},
+			Err: errTransform,
+		},
+		"parse": {
+			Input: "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n-a\n+b\n",
+			Err:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := test.Pipeline.Run(strings.NewReader(test.Input))
+			assertError(t, test.Err, err, "running pipeline")
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package gitdiff
+
+import (
+	"bytes"
+	"sort"
+)
+
+// PredictedConflict is a conflict between two patches that change the same
+// part of a file, as reported by PredictConflicts.
+type PredictedConflict struct {
+	// A and B are the indexes of the patches, with A < B.
+	A, B int
+
+	// Path is the name of the file in the base version, or the new name if
+	// both patches create or copy the file.
+	Path string
+
+	// Reason describes the conflict, such as "overlapping changes" or
+	// "deleted in one patch and modified in the other".
+	Reason string
+
+	// Lines is the range of lines of the base version covered by the
+	// overlapping changes, or zero if the whole file conflicts. A range
+	// with no lines is a position where both patches insert lines.
+	Lines LineRange
+}
+
+// baseChange replaces the zero-indexed base lines from start to end with new
+// lines.
+type baseChange struct {
+	start, end int64
+	lines      []string
+}
+
+// PredictConflicts reports the pairs of patches that would conflict if they
+// were merged, assuming they were all created from the same base version of
+// the files, such as pending pull requests for the same branch. Like a
+// three-way merge, changes to the same or adjacent lines conflict unless they
+// are identical, as do different changes to the same binary file, deleting a
+// file that another patch modifies, creating the same file with different
+// content, renaming a file to different names, and changing a mode to
+// different values. Conflicts are sorted by patch indexes, path, and line.
+func PredictConflicts(patches [][]*File) []PredictedConflict {
+	byPath := make([]map[string]*File, len(patches))
+	for i, files := range patches {
+		byPath[i] = make(map[string]*File)
+		for _, f := range files {
+			byPath[i][predictPath(f)] = f
+		}
+	}
+
+	var conflicts []PredictedConflict
+	for a := range patches {
+		for b := a + 1; b < len(patches); b++ {
+			for path, fa := range byPath[a] {
+				fb, ok := byPath[b][path]
+				if !ok {
+					continue
+				}
+				for _, c := range predictFileConflicts(fa, fb) {
+					c.A, c.B, c.Path = a, b, path
+					conflicts = append(conflicts, c)
+				}
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		ci, cj := conflicts[i], conflicts[j]
+		switch {
+		case ci.A != cj.A:
+			return ci.A < cj.A
+		case ci.B != cj.B:
+			return ci.B < cj.B
+		case ci.Path != cj.Path:
+			return ci.Path < cj.Path
+		}
+		return ci.Lines.Position < cj.Lines.Position
+	})
+	return conflicts
+}
+
+// predictPath returns the name that identifies f in the base version. A copy
+// does not change its source, so it is identified by its new name.
+func predictPath(f *File) string {
+	if f.IsNew || f.IsCopy {
+		return f.NewName
+	}
+	return f.OldName
+}
+
+func predictFileConflicts(a, b *File) []PredictedConflict {
+	whole := func(reason string) []PredictedConflict {
+		return []PredictedConflict{{Reason: reason}}
+	}
+
+	switch {
+	case a.IsNew && b.IsNew:
+		if !sameChanges(a, b) {
+			return whole("created with different content")
+		}
+		return nil
+	case a.IsCopy || b.IsCopy:
+		if !a.IsCopy || !b.IsCopy || a.OldName != b.OldName || !sameChanges(a, b) {
+			return whole("created with different content")
+		}
+		return nil
+	case a.IsDelete && b.IsDelete:
+		return nil
+	case a.IsDelete || b.IsDelete:
+		return whole("deleted in one patch and modified in the other")
+	case a.IsRename && b.IsRename && a.NewName != b.NewName:
+		return whole("renamed to different names")
+	case a.NewMode != 0 && b.NewMode != 0 && a.NewMode != b.NewMode:
+		return whole("mode changed to different values")
+	case a.IsBinary || b.IsBinary:
+		if !sameChanges(a, b) {
+			return whole("different binary changes")
+		}
+		return nil
+	}
+
+	var conflicts []PredictedConflict
+	ca, cb := baseChanges(a), baseChanges(b)
+	for _, x := range ca {
+		for _, y := range cb {
+			// like a merge, changes that touch without overlapping still
+			// conflict because their order is ambiguous
+			if x.start > y.end || y.start > x.end || sameBaseChange(x, y) {
+				continue
+			}
+			start, end := minInt64(x.start, y.start), maxInt64(x.end, y.end)
+			conflicts = append(conflicts, PredictedConflict{
+				Reason: "overlapping changes",
+				Lines:  LineRange{Position: start + 1, Lines: end - start},
+			})
+		}
+	}
+	return conflicts
+}
+
+// baseChanges returns the changes of the text fragments of f in the
+// coordinates of the base version.
+func baseChanges(f *File) []baseChange {
+	var changes []baseChange
+	for _, frag := range f.TextFragments {
+		offset := fragmentOldStart(frag)
+		for _, r := range changedRanges(frag.Lines) {
+			changes = append(changes, baseChange{
+				start: offset + int64(r.start),
+				end:   offset + int64(r.end),
+				lines: r.lines,
+			})
+		}
+	}
+	return changes
+}
+
+func sameBaseChange(x, y baseChange) bool {
+	return x.start == y.start && x.end == y.end && equalLines(x.lines, y.lines)
+}
+
+// sameChanges returns true if a and b make the same changes to their content.
+func sameChanges(a, b *File) bool {
+	if a.IsBinary || b.IsBinary {
+		if a.NewOIDPrefix != "" && b.NewOIDPrefix != "" && a.NewOIDPrefix == b.NewOIDPrefix && isFullOID(a.NewOIDPrefix) {
+			return true
+		}
+		fa, fb := a.BinaryFragment, b.BinaryFragment
+		return fa != nil && fb != nil && fa.Method == fb.Method && bytes.Equal(fa.Data, fb.Data)
+	}
+
+	ca, cb := baseChanges(a), baseChanges(b)
+	if len(ca) != len(cb) {
+		return false
+	}
+	for i := range ca {
+		if !sameBaseChange(ca[i], cb[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isFullOID(oid string) bool {
+	return len(oid) == 40 || len(oid) == 64
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,185 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPredictConflicts(t *testing.T) {
+	patches := []string{
+		// 0: changes line 3 of a.txt
+		`diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -2,3 +2,3 @@
+ line 2
+-line 3
++line three
+ line 4
+`,
+		// 1: changes line 3 of a.txt differently and deletes gone.txt
+		`diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -2,3 +2,3 @@
+ line 2
+-line 3
++line 3!
+ line 4
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+`,
+		// 2: same change as 0 and a change to line 8 of a.txt
+		`diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -2,3 +2,3 @@
+ line 2
+-line 3
++line three
+ line 4
+@@ -7,3 +7,3 @@
+ line 7
+-line 8
++line eight
+ line 9
+`,
+		// 3: changes line 9 of a.txt, next to the change in 2, and modifies gone.txt
+		`diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -8,3 +8,3 @@
+ line 8
+-line 9
++line nine
+ line 10
+diff --git a/gone.txt b/gone.txt
+--- a/gone.txt
++++ b/gone.txt
+@@ -1 +1 @@
+-gone
++still here
+`,
+		// 4: changes line 20 of a.txt and creates new.txt
+		`diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -19,3 +19,3 @@
+ line 19
+-line 20
++line twenty
+ line 21
+diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++new
+`,
+		// 5: creates new.txt with different content
+		`diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++other
+`,
+	}
+
+	parsed := make([][]*File, len(patches))
+	for i, patch := range patches {
+		files, _, err := ParseAll(strings.NewReader(patch))
+		if err != nil {
+			t.Fatalf("patch %d: unexpected error parsing patch: %v", i, err)
+		}
+		parsed[i] = files
+	}
+
+	expected := []PredictedConflict{
+		{A: 0, B: 1, Path: "a.txt", Reason: "overlapping changes", Lines: LineRange{Position: 3, Lines: 1}},
+		{A: 1, B: 2, Path: "a.txt", Reason: "overlapping changes", Lines: LineRange{Position: 3, Lines: 1}},
+		{A: 1, B: 3, Path: "gone.txt", Reason: "deleted in one patch and modified in the other"},
+		{A: 2, B: 3, Path: "a.txt", Reason: "overlapping changes", Lines: LineRange{Position: 8, Lines: 2}},
+		{A: 4, B: 5, Path: "new.txt", Reason: "created with different content"},
+	}
+
+	conflicts := PredictConflicts(parsed)
+	if !reflect.DeepEqual(expected, conflicts) {
+		t.Errorf("incorrect conflicts\nexpected: %+v\n  actual: %+v", expected, conflicts)
+	}
+}
+
+func TestPredictConflictsWholeFile(t *testing.T) {
+	tests := map[string]struct {
+		A, B   string
+		Reason string
+	}{
+		"renamedDifferently": {
+			A:      "diff --git a/old.txt b/one.txt\nsimilarity index 100%\nrename from old.txt\nrename to one.txt\n",
+			B:      "diff --git a/old.txt b/two.txt\nsimilarity index 100%\nrename from old.txt\nrename to two.txt\n",
+			Reason: "renamed to different names",
+		},
+		"renamedSame": {
+			A: "diff --git a/old.txt b/one.txt\nsimilarity index 100%\nrename from old.txt\nrename to one.txt\n",
+			B: "diff --git a/old.txt b/one.txt\nsimilarity index 100%\nrename from old.txt\nrename to one.txt\n",
+		},
+		"modes": {
+			A:      "diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n",
+			B:      "diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100600\n",
+			Reason: "mode changed to different values",
+		},
+		"bothDeleted": {
+			A: "diff --git a/a.txt b/a.txt\ndeleted file mode 100644\nindex 1234567..0000000\n",
+			B: "diff --git a/a.txt b/a.txt\ndeleted file mode 100644\nindex 1234567..0000000\n",
+		},
+		"copyAndModifySource": {
+			A: "diff --git a/x.txt b/y.txt\nsimilarity index 75%\ncopy from x.txt\ncopy to y.txt\n--- a/x.txt\n+++ b/y.txt\n@@ -1,3 +1,3 @@\n 1\n-2\n+two\n 3\n",
+			B: "diff --git a/x.txt b/x.txt\n--- a/x.txt\n+++ b/x.txt\n@@ -1,3 +1,3 @@\n 1\n-2\n+TWO\n 3\n",
+		},
+		"copyWithModifiedSource": {
+			A: "diff --git a/x.txt b/x.txt\n--- a/x.txt\n+++ b/x.txt\n@@ -1,3 +1,3 @@\n 1\n-2\n+two\n 3\n" +
+				"diff --git a/x.txt b/y.txt\nsimilarity index 100%\ncopy from x.txt\ncopy to y.txt\n",
+			B:      "diff --git a/x.txt b/x.txt\n--- a/x.txt\n+++ b/x.txt\n@@ -1,3 +1,3 @@\n 1\n-2\n+TWO\n 3\n",
+			Reason: "overlapping changes",
+		},
+		"copiedFromDifferentFiles": {
+			A:      "diff --git a/x.txt b/y.txt\nsimilarity index 100%\ncopy from x.txt\ncopy to y.txt\n",
+			B:      "diff --git a/z.txt b/y.txt\nsimilarity index 100%\ncopy from z.txt\ncopy to y.txt\n",
+			Reason: "created with different content",
+		},
+		"binary": {
+			A:      "diff --git a/a.bin b/a.bin\nindex 1234567..89abcde 100644\nBinary files a/a.bin and b/a.bin differ\n",
+			B:      "diff --git a/a.bin b/a.bin\nindex 1234567..fedcba9 100644\nBinary files a/a.bin and b/a.bin differ\n",
+			Reason: "different binary changes",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, _, err := ParseAll(strings.NewReader(test.A))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch A: %v", err)
+			}
+			b, _, err := ParseAll(strings.NewReader(test.B))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch B: %v", err)
+			}
+
+			conflicts := PredictConflicts([][]*File{a, b})
+			if test.Reason == "" {
+				if len(conflicts) > 0 {
+					t.Fatalf("expected no conflicts, but got %+v", conflicts)
+				}
+				return
+			}
+			if len(conflicts) != 1 || conflicts[0].Reason != test.Reason {
+				t.Fatalf("expected one conflict with reason %q, but got %+v", test.Reason, conflicts)
+			}
+		})
+	}
+}
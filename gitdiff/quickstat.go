@@ -0,0 +1,343 @@
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FileStat is the number of lines changed in a file, as reported by
+// QuickStat.
+type FileStat struct {
+	// Name is the new name of the file, or the old name if it is deleted.
+	Name string
+
+	LinesAdded   int64
+	LinesDeleted int64
+
+	// IsBinary is true if the patch has a binary change for the file.
+	IsBinary bool
+}
+
+// QuickStat reads the patch in r and returns the number of lines added and
+// deleted in each file, like git diff --numstat. It only looks at file
+// headers, fragment headers, and the first characters of fragment lines, so
+// it is several times faster than ParseAll and allocates little memory, but
+// it does not validate the patch as completely. For combined diffs, lines are
+// counted against the first parent.
+//
+// QuickStat stops at the first error and returns the files read before it.
+func QuickStat(r io.Reader) ([]FileStat, error) {
+	qs := quickStat{r: bufio.NewReaderSize(r, 64*1024)}
+	err := qs.run()
+	return qs.files(), err
+}
+
+// quickStatFile is a file in progress during QuickStat.
+type quickStatFile struct {
+	FileStat
+	oldName, newName string
+	isDelete         bool
+}
+
+type quickStat struct {
+	r      *bufio.Reader
+	long   []byte
+	lineno int64
+
+	stats []quickStatFile
+
+	// header is true between a git file header and its first fragment, when
+	// lines starting with "---" and "+++" are part of the header
+	header bool
+
+	// pendingOld is the name from a "---" line that may start a traditional
+	// diff if it is followed by a "+++" line
+	pendingOld string
+	pending    bool
+}
+
+// readLine returns the next line of input, including the trailing newline.
+// The returned slice is only valid until the next call.
+func (qs *quickStat) readLine() ([]byte, error) {
+	line, err := qs.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		qs.long = append(qs.long[:0], line...)
+		for err == bufio.ErrBufferFull {
+			line, err = qs.r.ReadSlice('\n')
+			qs.long = append(qs.long, line...)
+		}
+		line = qs.long
+	}
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err == nil {
+		qs.lineno++
+	}
+	return line, err
+}
+
+func (qs *quickStat) errorf(msg string, args ...interface{}) error {
+	return fmt.Errorf("gitdiff: line %d: %s", qs.lineno, fmt.Sprintf(msg, args...))
+}
+
+func (qs *quickStat) run() error {
+	for {
+		line, err := qs.readLine()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		pending := qs.pending
+		qs.pending = false
+
+		switch {
+		case bytes.HasPrefix(line, []byte("@@")):
+			if len(qs.stats) == 0 {
+				continue
+			}
+			qs.header = false
+			if err := qs.skipFragment(line); err != nil {
+				return err
+			}
+
+		case bytes.HasPrefix(line, []byte("diff --git ")):
+			name, _ := parseGitHeaderName(trimLine(line[len("diff --git "):]))
+			qs.startFile(name, name)
+
+		case bytes.HasPrefix(line, []byte("diff --cc ")) || bytes.HasPrefix(line, []byte("diff --combined ")):
+			header := trimLine(line[bytes.IndexByte(line, ' ')+1:])
+			header = header[strings.IndexByte(header, ' ')+1:]
+			name, _, _ := parseName(header, 0, 0)
+			qs.startFile(name, name)
+
+		case bytes.HasPrefix(line, []byte("--- ")):
+			// git headers only need these names if the default name is empty
+			if qs.header && qs.current().newName != "" {
+				continue
+			}
+			name, _, _ := parseName(trimLine(line[4:]), '\t', qs.nameDrop())
+			if qs.header {
+				qs.current().oldName = name
+			} else {
+				qs.pendingOld, qs.pending = name, true
+			}
+
+		case bytes.HasPrefix(line, []byte("+++ ")):
+			if qs.header && !pending && qs.current().newName != "" {
+				continue
+			}
+			name, _, _ := parseName(trimLine(line[4:]), '\t', qs.nameDrop())
+			if pending {
+				qs.startFile(qs.pendingOld, name)
+				qs.current().isDelete = name == devNull
+			} else if qs.header {
+				qs.current().newName = name
+			}
+
+		case !qs.header && maybeBinaryNotice(line):
+			if f := parseBinaryNotice(string(line), nil); f != nil {
+				qs.startFile(f.OldName, f.NewName)
+				qs.current().isDelete = f.IsDelete
+				qs.current().IsBinary = true
+				qs.header = false
+			}
+
+		case !qs.header:
+			// ignore other lines outside of file headers
+
+		case bytes.HasPrefix(line, []byte("rename to ")):
+			qs.current().newName, _, _ = parseName(trimLine(line[len("rename to "):]), 0, 0)
+
+		case bytes.HasPrefix(line, []byte("copy to ")):
+			qs.current().newName, _, _ = parseName(trimLine(line[len("copy to "):]), 0, 0)
+
+		case bytes.HasPrefix(line, []byte("deleted file mode ")):
+			qs.current().isDelete = true
+
+		case bytes.HasPrefix(line, []byte("GIT binary patch")) || bytes.HasPrefix(line, []byte("Binary files ")):
+			qs.current().IsBinary = true
+			qs.header = false
+		}
+	}
+}
+
+// nameDrop returns the number of directories to drop from the names in "---"
+// and "+++" lines. Git headers use prefixes like "a/", but traditional headers
+// keep their names as they are.
+func (qs *quickStat) nameDrop() int {
+	if qs.header {
+		return 1
+	}
+	return 0
+}
+
+func (qs *quickStat) startFile(oldName, newName string) {
+	qs.stats = append(qs.stats, quickStatFile{oldName: oldName, newName: newName})
+	qs.header = true
+}
+
+func (qs *quickStat) current() *quickStatFile {
+	return &qs.stats[len(qs.stats)-1]
+}
+
+// skipFragment reads the lines of the fragment with the header in line and
+// adds the changed lines to the current file.
+func (qs *quickStat) skipFragment(line []byte) error {
+	marks := bytes.IndexFunc(line, func(c rune) bool { return c != '@' })
+	if marks < 2 {
+		return qs.errorf("invalid fragment header")
+	}
+	parents := marks - 1
+
+	var oldLines [8]int64
+	if parents > len(oldLines) {
+		return qs.errorf("invalid fragment header: too many parents")
+	}
+
+	// parse the ranges without allocating, since there is one header for
+	// every few lines of most patches
+	var newLines int64
+	rest := line[marks:]
+	for i := 0; i <= parents; i++ {
+		op := byte('-')
+		if i == parents {
+			op = '+'
+		}
+		if len(rest) < 2 || rest[0] != ' ' || rest[1] != op {
+			return qs.errorf("invalid fragment header")
+		}
+
+		var n int64
+		var ok bool
+		if n, rest, ok = parseQuickRange(rest[2:]); !ok {
+			return qs.errorf("invalid fragment header")
+		}
+		if i == parents {
+			newLines = n
+		} else {
+			oldLines[i] = n
+		}
+	}
+	if !bytes.HasPrefix(rest, []byte(" ")) || !bytes.HasPrefix(rest[1:], line[:marks]) {
+		return qs.errorf("invalid fragment header")
+	}
+
+	remaining := func() bool {
+		for _, n := range oldLines[:parents] {
+			if n > 0 {
+				return true
+			}
+		}
+		return newLines > 0
+	}
+
+	f := qs.current()
+	for remaining() {
+		line, err := qs.readLine()
+		if err != nil {
+			if err == io.EOF {
+				return qs.errorf("fragment is truncated")
+			}
+			return err
+		}
+
+		if line[0] == '\n' || line[0] == '\r' {
+			// newer GNU diff versions create empty context lines
+			for i := range oldLines[:parents] {
+				oldLines[i]--
+			}
+			newLines--
+			continue
+		}
+		if line[0] == '\\' {
+			continue
+		}
+		if len(line) < parents {
+			return qs.errorf("invalid line operation: %q", line)
+		}
+
+		ops := line[:parents]
+		deleted := bytes.IndexByte(ops, '-') >= 0
+		for i, op := range ops {
+			switch {
+			case op == '-':
+				oldLines[i]--
+			case op == ' ' && !deleted:
+				oldLines[i]--
+			case op == ' ' || op == '+' && !deleted:
+			default:
+				return qs.errorf("invalid line operation: %q", ops)
+			}
+		}
+		if !deleted {
+			newLines--
+		}
+
+		switch ops[0] {
+		case '-':
+			f.LinesDeleted++
+		case '+':
+			f.LinesAdded++
+		}
+	}
+	return nil
+}
+
+// maybeBinaryNotice returns true if line starts like one of the default
+// binary notices, so most lines are not converted to strings.
+func maybeBinaryNotice(line []byte) bool {
+	for _, notice := range defaultBinaryNotices {
+		if bytes.HasPrefix(line, []byte(notice.prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (qs *quickStat) files() []FileStat {
+	files := make([]FileStat, len(qs.stats))
+	for i, f := range qs.stats {
+		files[i] = f.FileStat
+		files[i].Name = f.newName
+		if f.isDelete || f.newName == "" || f.newName == devNull {
+			files[i].Name = f.oldName
+		}
+	}
+	return files
+}
+
+// parseQuickRange parses a range in a fragment header, like "12,3", at the
+// start of b and returns the number of lines in the range and the rest of b.
+func parseQuickRange(b []byte) (lines int64, rest []byte, ok bool) {
+	if _, rest = parseQuickNumber(b); len(rest) == len(b) {
+		return 0, nil, false
+	}
+	if len(rest) == 0 || rest[0] != ',' {
+		return 1, rest, true
+	}
+
+	lines, after := parseQuickNumber(rest[1:])
+	if len(after) == len(rest)-1 {
+		return 0, nil, false
+	}
+	return lines, after, true
+}
+
+func parseQuickNumber(b []byte) (n int64, rest []byte) {
+	i := 0
+	for ; i < len(b) && '0' <= b[i] && b[i] <= '9' && n < 1<<52; i++ {
+		n = n*10 + int64(b[i]-'0')
+	}
+	return n, b[i:]
+}
+
+func trimLine(line []byte) string {
+	return strings.TrimRight(string(line), "\r\n")
+}
@@ -0,0 +1,226 @@
+package gitdiff
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuickStat(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output []FileStat
+		Err    interface{}
+	}{
+		"gitFiles": {
+			Input: `diff --git a/dir/file.txt b/dir/file.txt
+index ebe9fa54..fe103e1d 100644
+--- a/dir/file.txt
++++ b/dir/file.txt
+@@ -1,3 +1,4 @@
+ line 1
+---- line 2
+++++ line two
++++ line three
+ line 4
+@@ -10,2 +11,1 @@ section
+-line 10
+ line 11
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 1c23fcc..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-line 1
+-line 2
+diff --git a/a.txt b/b.txt
+similarity index 100%
+rename from a.txt
+rename to b.txt
+diff --git a/image.png b/image.png
+new file mode 100644
+index 0000000..fe103e1
+Binary files /dev/null and b/image.png differ
+`,
+			Output: []FileStat{
+				{Name: "dir/file.txt", LinesAdded: 2, LinesDeleted: 2},
+				{Name: "old.txt", LinesDeleted: 2},
+				{Name: "b.txt"},
+				{Name: "image.png", IsBinary: true},
+			},
+		},
+		"traditionalDirectories": {
+			Input: `--- old/dir/file.txt	2019-04-02 22:55:40.000000000 -0700
++++ new/dir/file.txt	2019-04-02 22:56:40.000000000 -0700
+@@ -1 +1 @@
+-line 1
++line one
+`,
+			Output: []FileStat{
+				{Name: "new/dir/file.txt", LinesAdded: 1, LinesDeleted: 1},
+			},
+		},
+		"traditional": {
+			Input: `--- file.txt.orig	2019-04-02 22:55:40.000000000 -0700
++++ file.txt	2019-04-02 22:56:40.000000000 -0700
+@@ -1,2 +1,2 @@
+-line 1
++line one
+
+ line 3
+--- other.txt.orig
++++ other.txt
+@@ -0,0 +1 @@
++new line
+\ No newline at end of file
+`,
+			Output: []FileStat{
+				{Name: "file.txt", LinesAdded: 1, LinesDeleted: 1},
+				{Name: "other.txt", LinesAdded: 1},
+			},
+		},
+		"combined": {
+			Input: `diff --cc file.txt
+index 1c23fcc,40a1f4a..8f1c1b8
+--- a/file.txt
++++ b/file.txt
+@@@ -1,3 -1,3 +1,3 @@@
+  line 1
+- line 2
+ -line two
+++line 2 merged
+  line 3
+`,
+			Output: []FileStat{
+				{Name: "file.txt", LinesAdded: 1, LinesDeleted: 1},
+			},
+		},
+		"truncatedFragment": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ line 1
+-line 2
+`,
+			Output: []FileStat{
+				{Name: "file.txt", LinesDeleted: 1},
+			},
+			Err: "line 6",
+		},
+		"invalidFragmentHeader": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,a +1 @@
+`,
+			Output: []FileStat{
+				{Name: "file.txt"},
+			},
+			Err: "invalid fragment header",
+		},
+		"invalidLine": {
+			Input: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@
+ line 1
+*line 2
+`,
+			Output: []FileStat{
+				{Name: "file.txt"},
+			},
+			Err: "invalid line operation",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := QuickStat(strings.NewReader(test.Input))
+			if test.Err != nil {
+				assertError(t, test.Err, err, "computing stats")
+			} else if err != nil {
+				t.Fatalf("unexpected error computing stats: %v", err)
+			}
+
+			if !reflect.DeepEqual(test.Output, files) {
+				t.Errorf("incorrect stats\nexpected: %+v\n  actual: %+v", test.Output, files)
+			}
+		})
+	}
+}
+
+func TestQuickStatMatchesParse(t *testing.T) {
+	for _, name := range []string{"one_file.patch", "two_files.patch", "new_binary_file.patch", "extended_headers.patch", "binary_notice.patch"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatalf("unexpected error reading patch: %v", err)
+			}
+
+			parsed, _, err := ParseAll(strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			var expected []FileStat
+			for _, f := range parsed {
+				stat := FileStat{Name: f.NewName, IsBinary: f.IsBinary}
+				if f.IsDelete {
+					stat.Name = f.OldName
+				}
+				for _, frag := range f.TextFragments {
+					stat.LinesAdded += frag.LinesAdded
+					stat.LinesDeleted += frag.LinesDeleted
+				}
+				expected = append(expected, stat)
+			}
+
+			files, err := QuickStat(strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatalf("unexpected error computing stats: %v", err)
+			}
+			if !reflect.DeepEqual(expected, files) {
+				t.Errorf("incorrect stats\nexpected: %+v\n  actual: %+v", expected, files)
+			}
+		})
+	}
+}
+
+func BenchmarkQuickStat(b *testing.B) {
+	var builder strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&builder, `diff --git a/dir/file%[1]d.txt b/dir/file%[1]d.txt
+index ebe9fa54..fe103e1d 100644
+--- a/dir/file%[1]d.txt
++++ b/dir/file%[1]d.txt
+@@ -3,6 +3,8 @@ fragment 1
+ context line
+-old line 1
+-old line 2
+ context line
++new line 1
++new line 2
++new line 3
+ context line
+-old line 3
++new line 4
++new line 5
+@@ -31,2 +33,2 @@ fragment 2
+ context line
+-old line 4
++new line 6
+`, i)
+	}
+	input := builder.String()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := QuickStat(strings.NewReader(input)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
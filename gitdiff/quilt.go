@@ -0,0 +1,201 @@
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// QuiltPatch is an entry in a quilt series file.
+type QuiltPatch struct {
+	// Name is the name of the patch file, relative to the patch directory.
+	Name string
+
+	// Strip is the number of leading directories removed from the names in
+	// the patch, as set by the -p option. It is 1 if the entry has no -p
+	// option.
+	Strip int
+
+	// Reverse is true if the patch is applied in reverse, as set by the -R
+	// option.
+	Reverse bool
+
+	// Options contains the other options of the entry, which are kept when
+	// the series is written but ignored when applying it.
+	Options []string
+}
+
+// QuiltSeries is a quilt series file: a list of patches that are applied in
+// order on top of a source tree, as used to maintain distribution packages.
+type QuiltSeries struct {
+	// Dir is the directory that contains the patch files. If it is relative,
+	// it is relative to the directory the series is applied to. If empty,
+	// the patches are in the "patches" directory, the default of quilt.
+	Dir string
+
+	Patches []QuiltPatch
+}
+
+// ParseQuiltSeries parses a quilt series file. Empty lines and comments,
+// which start with "#" at the start of a line or after whitespace, are
+// ignored.
+func ParseQuiltSeries(r io.Reader) (*QuiltSeries, error) {
+	s := &QuiltSeries{}
+
+	sc := bufio.NewScanner(r)
+	for lineno := 1; sc.Scan(); lineno++ {
+		fields := strings.Fields(sc.Text())
+		for i, f := range fields {
+			if strings.HasPrefix(f, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		p := QuiltPatch{Name: fields[0], Strip: 1}
+		for _, opt := range fields[1:] {
+			switch {
+			case opt == "-R":
+				p.Reverse = true
+			case strings.HasPrefix(opt, "-p") && isQuiltStrip(opt[2:]):
+				p.Strip, _ = strconv.Atoi(opt[2:])
+			default:
+				p.Options = append(p.Options, opt)
+			}
+		}
+		s.Patches = append(s.Patches, p)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func isQuiltStrip(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the entry as a line of a series file, without a newline.
+func (p QuiltPatch) String() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+	if p.Strip != 1 {
+		fmt.Fprintf(&b, " -p%d", p.Strip)
+	}
+	if p.Reverse {
+		b.WriteString(" -R")
+	}
+	for _, opt := range p.Options {
+		b.WriteString(" ")
+		b.WriteString(opt)
+	}
+	return b.String()
+}
+
+// WriteTo writes the series file for the patches in s to w.
+func (s *QuiltSeries) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	for _, p := range s.Patches {
+		b.WriteString(p.String())
+		b.WriteByte('\n')
+	}
+	return b.WriteTo(w)
+}
+
+// ApplySeries applies the patches in series to the files in dir in order,
+// like quilt push -a, using ApplyTree for each patch. Each patch sees the
+// results of the patches before it. Patch files may be compressed, as with
+// Parse. If a patch does not apply, ApplySeries stops and returns an error
+// that names the patch; the patches before it remain applied.
+//
+// Because each patch depends on the results of the previous ones, opts.Check
+// is not supported.
+func ApplySeries(dir string, series *QuiltSeries, opts ApplyTreeOptions) error {
+	if opts.Check {
+		return errors.New("gitdiff: cannot check a series without applying it")
+	}
+
+	patchDir := series.Dir
+	if patchDir == "" {
+		patchDir = "patches"
+	}
+	if !filepath.IsAbs(patchDir) {
+		patchDir = filepath.Join(dir, patchDir)
+	}
+
+	for _, p := range series.Patches {
+		if err := applyQuiltPatch(dir, patchDir, p, opts); err != nil {
+			return fmt.Errorf("gitdiff: patch %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyQuiltPatch(dir, patchDir string, p QuiltPatch, opts ApplyTreeOptions) error {
+	data, err := os.ReadFile(filepath.Join(patchDir, filepath.FromSlash(p.Name)))
+	if err != nil {
+		return err
+	}
+
+	files, _, err := ParseAll(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	git := isGitPatch(data)
+	for i, f := range files {
+		f.OldName, f.NewName = namesAtLevel(f, p.Strip, git)
+		if p.Reverse {
+			if files[i], err = reverseFile(f); err != nil {
+				return err
+			}
+		}
+	}
+	return ApplyTree(dir, files, opts)
+}
+
+// reverseFile returns a file that undoes the changes of f.
+func reverseFile(f *File) (*File, error) {
+	if f.ParentCount > 0 {
+		return nil, fmt.Errorf("%s: cannot reverse a combined diff", fileName(f))
+	}
+	if f.ContentOmitted {
+		return nil, fmt.Errorf("%s: cannot reverse a deletion without the deleted content", fileName(f))
+	}
+
+	r := *f
+	r.OldName, r.NewName = f.NewName, f.OldName
+	r.IsNew, r.IsDelete = f.IsDelete, f.IsNew
+	r.OldMode, r.NewMode = f.NewMode, f.OldMode
+	r.OldOIDPrefix, r.NewOIDPrefix = f.NewOIDPrefix, f.OldOIDPrefix
+
+	if f.IsBinary && f.BinaryFragment != nil {
+		if f.ReverseBinaryFragment == nil {
+			return nil, fmt.Errorf("%s: cannot reverse a binary patch without reverse data", fileName(f))
+		}
+		r.BinaryFragment, r.ReverseBinaryFragment = f.ReverseBinaryFragment, f.BinaryFragment
+	}
+
+	r.TextFragments = make([]*TextFragment, len(f.TextFragments))
+	for i, frag := range f.TextFragments {
+		r.TextFragments[i] = reverseTextFragment(frag)
+	}
+	return &r, nil
+}
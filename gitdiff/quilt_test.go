@@ -0,0 +1,160 @@
+package gitdiff
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQuiltSeries(t *testing.T) {
+	input := `# patches for the 1.2 release
+fix-build.patch
+debian/paths.diff -p0
+revert-api.patch -R -p2
+  upstream.patch -pab   # from upstream
+
+`
+	expected := &QuiltSeries{
+		Patches: []QuiltPatch{
+			{Name: "fix-build.patch", Strip: 1},
+			{Name: "debian/paths.diff", Strip: 0},
+			{Name: "revert-api.patch", Strip: 2, Reverse: true},
+			{Name: "upstream.patch", Strip: 1, Options: []string{"-pab"}},
+		},
+	}
+
+	s, err := ParseQuiltSeries(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error parsing series: %v", err)
+	}
+	if !reflect.DeepEqual(expected, s) {
+		t.Fatalf("incorrect series\nexpected: %+v\n  actual: %+v", expected, s)
+	}
+
+	var b bytes.Buffer
+	if _, err := s.WriteTo(&b); err != nil {
+		t.Fatalf("unexpected error writing series: %v", err)
+	}
+	output := "fix-build.patch\ndebian/paths.diff -p0\nrevert-api.patch -p2 -R\nupstream.patch -pab\n"
+	if b.String() != output {
+		t.Errorf("incorrect output\nexpected: %q\n  actual: %q", output, b.String())
+	}
+}
+
+func TestApplySeries(t *testing.T) {
+	patches := map[string]string{
+		"git.patch": `diff --git a/src/main.c b/src/main.c
+--- a/src/main.c
++++ b/src/main.c
+@@ -1,2 +1,2 @@
+ int main() {
+-	return 1;
++	return 0;
+`,
+		"p0.diff": `--- src/main.c
++++ src/main.c
+@@ -1,2 +1,3 @@
+ int main() {
++	/* exit cleanly */
+ 	return 0;
+`,
+		"p2.diff": `--- old/pkg/src/README
++++ new/pkg/src/README
+@@ -1 +1 @@
+-draft
++final
+`,
+		"reverse.patch": `diff --git a/src/README b/src/README
+--- a/src/README
++++ b/src/README
+@@ -1 +1 @@
+-old
++draft
+`,
+	}
+
+	tests := map[string]struct {
+		Series string
+		Files  map[string]string
+		Err    interface{}
+	}{
+		"inOrder": {
+			Series: "git.patch\np0.diff -p0\np2.diff -p2\n",
+			Files: map[string]string{
+				"src/main.c": "int main() {\n\t/* exit cleanly */\n\treturn 0;\n",
+				"src/README": "final\n",
+			},
+		},
+		"reverse": {
+			Series: "reverse.patch -R\n",
+			Files: map[string]string{
+				"src/main.c": "int main() {\n\treturn 1;\n",
+				"src/README": "old\n",
+			},
+		},
+		"dependsOnOrder": {
+			Series: "p0.diff -p0\ngit.patch\n",
+			Err:    "patch p0.diff",
+		},
+		"missingPatch": {
+			Series: "missing.patch\n",
+			Err:    "patch missing.patch",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gitdiff-quilt")
+			if err != nil {
+				t.Fatalf("unexpected error creating directory: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			files := map[string]string{
+				"src/main.c": "int main() {\n\treturn 1;\n",
+				"src/README": "draft\n",
+			}
+			for name, content := range patches {
+				files["debian/patches/"+name] = content
+			}
+			for name, content := range files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("unexpected error creating directory: %v", err)
+				}
+				if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("unexpected error writing file: %v", err)
+				}
+			}
+
+			series, err := ParseQuiltSeries(strings.NewReader(test.Series))
+			if err != nil {
+				t.Fatalf("unexpected error parsing series: %v", err)
+			}
+			series.Dir = "debian/patches"
+
+			err = ApplySeries(dir, series, ApplyTreeOptions{})
+			if test.Err != nil {
+				assertError(t, test.Err, err, "applying series")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error applying series: %v", err)
+			}
+
+			for name, expected := range test.Files {
+				data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatalf("unexpected error reading %s: %v", name, err)
+				}
+				if string(data) != expected {
+					t.Errorf("incorrect content of %s\nexpected: %q\n  actual: %q", name, expected, data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package gitdiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RawEntry describes a change to a single file in the raw output format used
+// by git diff --raw and git show --raw:
+//
+//	:100644 100644 bcd1234 0123456 M	file0
+//	:100644 100644 abcd123 1234567 R086	file1	file3
+//
+// For combined entries of merge commits, which start with one colon for each
+// parent, the old mode and OID are those of the first parent and Status is the
+// status for the first parent.
+type RawEntry struct {
+	OldMode os.FileMode
+	NewMode os.FileMode
+
+	OldOID string
+	NewOID string
+
+	// Status is the status letter of the change, such as 'A', 'M', 'D', 'R',
+	// or 'C'. Score is the similarity score for renames and copies, the
+	// dissimilarity score for rewrites, or 0 if the status has no score.
+	Status byte
+	Score  int
+
+	// OldName and NewName are the names of the file before and after the
+	// change. They are the same unless the change is a rename or copy.
+	OldName string
+	NewName string
+}
+
+// ParseRawEntry parses a single line in raw format. The line may include a
+// trailing newline.
+func ParseRawEntry(line string) (*RawEntry, error) {
+	line = strings.TrimSuffix(line, "\n")
+
+	tab := strings.IndexByte(line, '\t')
+	if tab < 0 {
+		return nil, fmt.Errorf("invalid raw entry: missing file name")
+	}
+
+	e, err := parseRawMeta(line[:tab])
+	if err != nil {
+		return nil, err
+	}
+	if e.OldName, e.NewName, err = parseRawNames(line[tab+1:], e.hasTwoNames(), '\t'); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseRawMeta parses the modes, OIDs, and status at the start of an entry in
+// raw format.
+func parseRawMeta(meta string) (*RawEntry, error) {
+	parents := 0
+	for parents < len(meta) && meta[parents] == ':' {
+		parents++
+	}
+	if parents == 0 {
+		return nil, fmt.Errorf("invalid raw entry: missing ':'")
+	}
+
+	fields := strings.Fields(meta[parents:])
+	if len(fields) != 2*(parents+1)+1 {
+		return nil, fmt.Errorf("invalid raw entry: expected %d fields, found %d", 2*(parents+1)+1, len(fields))
+	}
+	modes, oids, status := fields[:parents+1], fields[parents+1:2*(parents+1)], fields[len(fields)-1]
+
+	e := &RawEntry{OldOID: oids[0], NewOID: oids[parents]}
+
+	var err error
+	if e.OldMode, err = parseMode(modes[0]); err != nil {
+		return nil, err
+	}
+	if e.NewMode, err = parseMode(modes[parents]); err != nil {
+		return nil, err
+	}
+
+	if parents > 1 {
+		e.Status = status[0]
+		return e, nil
+	}
+	if err := e.parseStatus(status); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseStatus parses a status letter and optional score.
+func (e *RawEntry) parseStatus(status string) error {
+	if status == "" {
+		return fmt.Errorf("invalid raw entry: missing status")
+	}
+	e.Status = status[0]
+	if !strings.ContainsRune("ACDMRTUX", rune(e.Status)) {
+		return fmt.Errorf("invalid raw entry: unknown status: %c", e.Status)
+	}
+	if len(status) > 1 {
+		score, err := strconv.Atoi(status[1:])
+		if err != nil {
+			return fmt.Errorf("invalid raw entry: invalid score: %s", status[1:])
+		}
+		e.Score = score
+	}
+	return nil
+}
+
+// hasTwoNames returns true if the entry has both an old and a new name.
+func (e *RawEntry) hasTwoNames() bool {
+	return e.Status == 'R' || e.Status == 'C'
+}
+
+// parseRawNames parses the one or two tab-separated names at the end of a raw
+// entry.
+func parseRawNames(s string, two bool, sep byte) (oldName, newName string, err error) {
+	name, n, err := parseName(s, sep, 0)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid raw entry: %v", err)
+	}
+	if !two {
+		return name, name, nil
+	}
+
+	if n >= len(s) || s[n] != sep {
+		return "", "", fmt.Errorf("invalid raw entry: missing second file name")
+	}
+	second, _, err := parseName(s[n+1:], sep, 0)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid raw entry: %v", err)
+	}
+	return name, second, nil
+}
+
+// ParseRaw parses the output of git diff --raw, with one entry per line.
+// Empty lines are ignored.
+func ParseRaw(r io.Reader) ([]*RawEntry, error) {
+	return parseEntryLines(r, ParseRawEntry)
+}
+
+// ParseNameStatusEntry parses a single line in the format used by git diff
+// --name-status. The returned entry only has a status, score, and names.
+func ParseNameStatusEntry(line string) (*RawEntry, error) {
+	line = strings.TrimSuffix(line, "\n")
+
+	tab := strings.IndexByte(line, '\t')
+	if tab < 0 {
+		return nil, fmt.Errorf("invalid name-status entry: missing file name")
+	}
+
+	e := &RawEntry{}
+	if err := e.parseStatus(line[:tab]); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if e.OldName, e.NewName, err = parseRawNames(line[tab+1:], e.hasTwoNames(), '\t'); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ParseNameStatus parses the output of git diff --name-status, with one entry
+// per line. Empty lines are ignored.
+func ParseNameStatus(r io.Reader) ([]*RawEntry, error) {
+	return parseEntryLines(r, ParseNameStatusEntry)
+}
+
+func parseEntryLines(r io.Reader, parse func(string) (*RawEntry, error)) ([]*RawEntry, error) {
+	var entries []*RawEntry
+
+	br := bufio.NewReader(r)
+	for lineno := 1; ; lineno++ {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return entries, err
+		}
+		if strings.TrimSpace(line) != "" {
+			e, perr := parse(line)
+			if perr != nil {
+				return entries, fmt.Errorf("gitdiff: line %d: %v", lineno, perr)
+			}
+			entries = append(entries, e)
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+	}
+}
+
+// ParseRawZ parses the output of git diff --raw -z, where fields and names
+// are terminated by NUL characters and names are never quoted.
+func ParseRawZ(r io.Reader) ([]*RawEntry, error) {
+	return parseEntriesZ(r, parseRawMeta)
+}
+
+// ParseNameStatusZ parses the output of git diff --name-status -z, where
+// fields and names are terminated by NUL characters and names are never
+// quoted. The returned entries only have a status, score, and names.
+func ParseNameStatusZ(r io.Reader) ([]*RawEntry, error) {
+	return parseEntriesZ(r, func(status string) (*RawEntry, error) {
+		e := &RawEntry{}
+		return e, e.parseStatus(status)
+	})
+}
+
+func parseEntriesZ(r io.Reader, parseMeta func(string) (*RawEntry, error)) ([]*RawEntry, error) {
+	var entries []*RawEntry
+
+	br := bufio.NewReader(r)
+	next := func() (string, error) {
+		field, err := br.ReadString(0)
+		if err == io.EOF && field != "" {
+			err = nil
+		}
+		return strings.TrimSuffix(field, "\x00"), err
+	}
+
+	for {
+		meta, err := next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		if strings.TrimSpace(meta) == "" {
+			continue
+		}
+
+		e, err := parseMeta(meta)
+		if err != nil {
+			return entries, fmt.Errorf("gitdiff: entry %d: %v", len(entries)+1, err)
+		}
+
+		if e.OldName, err = next(); err != nil || e.OldName == "" {
+			return entries, fmt.Errorf("gitdiff: entry %d: missing file name", len(entries)+1)
+		}
+		e.NewName = e.OldName
+		if e.hasTwoNames() {
+			if e.NewName, err = next(); err != nil || e.NewName == "" {
+				return entries, fmt.Errorf("gitdiff: entry %d: missing second file name", len(entries)+1)
+			}
+		}
+		entries = append(entries, e)
+	}
+}
+
+// splitRawEntries removes lines in raw format from a preamble, returning the
+// remaining content and the parsed entries. Lines that look like raw entries
+// but fail to parse are left in the preamble.
+func splitRawEntries(preamble string) (string, []*RawEntry) {
+	if !strings.Contains(preamble, "\n:") && !strings.HasPrefix(preamble, ":") {
+		return preamble, nil
+	}
+
+	var rest strings.Builder
+	var entries []*RawEntry
+	for _, line := range strings.SplitAfter(preamble, "\n") {
+		if strings.HasPrefix(line, ":") {
+			if e, err := ParseRawEntry(line); err == nil {
+				entries = append(entries, e)
+				continue
+			}
+		}
+		rest.WriteString(line)
+	}
+	return rest.String(), entries
+}
+
+// findRawEntry returns the entry in entries that describes f or nil if there
+// is no matching entry.
+func findRawEntry(entries []*RawEntry, f *File) *RawEntry {
+	for _, e := range entries {
+		switch {
+		case f.IsDelete:
+			if e.Status == 'D' && e.OldName == f.OldName {
+				return e
+			}
+		case f.IsNew:
+			if e.Status == 'A' && e.NewName == f.NewName {
+				return e
+			}
+		default:
+			if e.OldName == f.OldName && e.NewName == f.NewName {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// NewRawEntry returns the raw format entry describing the changes to f. If f
+// was parsed with a raw entry, that entry is returned. Otherwise, the entry is
+// computed from the fields of f.
+func NewRawEntry(f *File) *RawEntry {
+	if f.Raw != nil {
+		return f.Raw
+	}
+
+	e := &RawEntry{
+		OldMode: f.OldMode,
+		NewMode: f.NewMode,
+		OldOID:  f.OldOIDPrefix,
+		NewOID:  f.NewOIDPrefix,
+		OldName: f.OldName,
+		NewName: f.NewName,
+	}
+
+	switch {
+	case f.IsNew:
+		e.Status = 'A'
+		e.OldName = f.NewName
+	case f.IsDelete:
+		e.Status = 'D'
+		e.NewName = f.OldName
+	case f.IsRename:
+		e.Status, e.Score = 'R', f.Score
+	case f.IsCopy:
+		e.Status, e.Score = 'C', f.Score
+	case f.OldMode != 0 && f.NewMode != 0 && f.OldMode&0170000 != f.NewMode&0170000:
+		e.Status = 'T'
+	default:
+		e.Status = 'M'
+	}
+
+	if !f.IsNew && !f.IsDelete && e.NewMode == 0 {
+		e.NewMode = e.OldMode
+	}
+	return e
+}
+
+// String returns the entry in raw format, without a trailing newline.
+func (e *RawEntry) String() string {
+	oid := func(s string) string {
+		if s == "" {
+			return "0000000"
+		}
+		return s
+	}
+	return fmt.Sprintf(":%06o %06o %s %s %s", e.OldMode, e.NewMode, oid(e.OldOID), oid(e.NewOID), e.nameStatus())
+}
+
+// nameStatus returns the entry in name-status format.
+func (e *RawEntry) nameStatus() string {
+	status := string(e.Status)
+	if e.Status == 'R' || e.Status == 'C' {
+		status = fmt.Sprintf("%c%03d", e.Status, e.Score)
+		return status + "\t" + quoteName(e.OldName) + "\t" + quoteName(e.NewName)
+	}
+	if e.Status == 'D' {
+		return status + "\t" + quoteName(e.OldName)
+	}
+	return status + "\t" + quoteName(e.NewName)
+}
+
+// FormatNameOnly returns the names of the changed files in the format used
+// by git diff --name-only, one name per line.
+func FormatNameOnly(files []*File) string {
+	var b strings.Builder
+	for _, f := range files {
+		name := f.NewName
+		if f.IsDelete {
+			name = f.OldName
+		}
+		b.WriteString(quoteName(name))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// FormatNameStatus returns the status and names of the changed files in the
+// format used by git diff --name-status, one file per line.
+func FormatNameStatus(files []*File) string {
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(NewRawEntry(f).nameStatus())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// FormatRaw returns the changed files in the format used by git diff --raw,
+// one file per line.
+func FormatRaw(files []*File) string {
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(NewRawEntry(f).String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// quoteName quotes a file name in the same way as Git if it contains special
+// characters. Otherwise, it returns the name unchanged.
+func quoteName(name string) string {
+	needsQuote := false
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			needsQuote = true
+			break
+		}
+	}
+	if !needsQuote {
+		return name
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; c {
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\v':
+			b.WriteString(`\v`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,313 @@
+package gitdiff
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRawEntry(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Output *RawEntry
+		Err    bool
+	}{
+		"modify": {
+			Input: ":100644 100644 bcd1234 0123456 M\tfile0\n",
+			Output: &RawEntry{
+				OldMode: os.FileMode(0100644),
+				NewMode: os.FileMode(0100644),
+				OldOID:  "bcd1234",
+				NewOID:  "0123456",
+				Status:  'M',
+				OldName: "file0",
+				NewName: "file0",
+			},
+		},
+		"rename": {
+			Input: ":100644 100644 abcd123 1234567 R086\tfile1\tfile3",
+			Output: &RawEntry{
+				OldMode: os.FileMode(0100644),
+				NewMode: os.FileMode(0100644),
+				OldOID:  "abcd123",
+				NewOID:  "1234567",
+				Status:  'R',
+				Score:   86,
+				OldName: "file1",
+				NewName: "file3",
+			},
+		},
+		"create": {
+			Input: ":000000 100755 0000000 1234567 A\t\"dir/sp ace\\tfile\"",
+			Output: &RawEntry{
+				NewMode: os.FileMode(0100755),
+				OldOID:  "0000000",
+				NewOID:  "1234567",
+				Status:  'A',
+				OldName: "dir/sp ace\tfile",
+				NewName: "dir/sp ace\tfile",
+			},
+		},
+		"combined": {
+			Input: "::100644 100644 100644 fabadb8 cc95eb0 4866510 MM\tdesc.c",
+			Output: &RawEntry{
+				OldMode: os.FileMode(0100644),
+				NewMode: os.FileMode(0100644),
+				OldOID:  "fabadb8",
+				NewOID:  "4866510",
+				Status:  'M',
+				OldName: "desc.c",
+				NewName: "desc.c",
+			},
+		},
+		"missingColon": {
+			Input: "100644 100644 bcd1234 0123456 M\tfile0",
+			Err:   true,
+		},
+		"missingName": {
+			Input: ":100644 100644 bcd1234 0123456 M",
+			Err:   true,
+		},
+		"missingSecondName": {
+			Input: ":100644 100644 bcd1234 0123456 R100\tfile0",
+			Err:   true,
+		},
+		"badScore": {
+			Input: ":100644 100644 bcd1234 0123456 R1x\tfile0\tfile1",
+			Err:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, err := ParseRawEntry(test.Input)
+			if test.Err {
+				if err == nil {
+					t.Fatalf("expected error parsing raw entry, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing raw entry: %v", err)
+			}
+			if !reflect.DeepEqual(test.Output, e) {
+				t.Errorf("incorrect entry\nexpected: %+v\n  actual: %+v", test.Output, e)
+			}
+		})
+	}
+}
+
+func TestParseShowRaw(t *testing.T) {
+	f, err := os.Open("testdata/show_raw.patch")
+	if err != nil {
+		t.Fatalf("unexpected error opening input file: %v", err)
+	}
+	defer f.Close()
+
+	files, err := Parse(f)
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	var n int
+	for file := range files {
+		n++
+		if file.Raw == nil {
+			t.Fatalf("file %s: missing raw entry", file.NewName)
+		}
+		if file.Raw.OldName != file.OldName || file.Raw.NewName != file.NewName {
+			t.Errorf("file %s: incorrect raw entry: %+v", file.NewName, file.Raw)
+		}
+		if file.PatchHeader == nil || file.PatchHeader.Body != "" {
+			t.Errorf("file %s: raw entries were included in the patch header: %+v", file.NewName, file.PatchHeader)
+		}
+	}
+	if n != 2 {
+		t.Fatalf("incorrect number of files: expected 2, actual %d", n)
+	}
+}
+
+func TestFormatRawAndNameStatus(t *testing.T) {
+	files := []*File{
+		{
+			OldName:      "file.txt",
+			NewName:      "file.txt",
+			OldMode:      os.FileMode(0100644),
+			OldOIDPrefix: "ebe9fa5",
+			NewOIDPrefix: "fe103e1",
+		},
+		{
+			OldName:      "old.txt",
+			NewName:      "new.txt",
+			IsRename:     true,
+			Score:        100,
+			OldMode:      os.FileMode(0100644),
+			NewMode:      os.FileMode(0100644),
+			OldOIDPrefix: "417ebc7",
+			NewOIDPrefix: "417ebc7",
+		},
+		{
+			OldName:      "a.txt",
+			NewName:      "b.txt",
+			IsCopy:       true,
+			Score:        75,
+			OldMode:      os.FileMode(0100644),
+			NewMode:      os.FileMode(0100644),
+			OldOIDPrefix: "1111111",
+			NewOIDPrefix: "2222222",
+		},
+		{
+			NewName:      "dir/new fileé.sh",
+			IsNew:        true,
+			NewMode:      os.FileMode(0100755),
+			OldOIDPrefix: "0000000",
+			NewOIDPrefix: "3333333",
+		},
+		{
+			OldName:      "gone.txt",
+			IsDelete:     true,
+			OldMode:      os.FileMode(0100644),
+			OldOIDPrefix: "4444444",
+			NewOIDPrefix: "0000000",
+		},
+		{
+			OldName:      "link",
+			NewName:      "link",
+			OldMode:      os.FileMode(0100644),
+			NewMode:      os.FileMode(0120000),
+			OldOIDPrefix: "5555555",
+			NewOIDPrefix: "6666666",
+		},
+		{
+			OldName:      "script.sh",
+			NewName:      "script.sh",
+			OldMode:      os.FileMode(0100644),
+			NewMode:      os.FileMode(0100755),
+			OldOIDPrefix: "7777777",
+			NewOIDPrefix: "7777777",
+		},
+	}
+
+	t.Run("nameOnly", func(t *testing.T) {
+		expected := "file.txt\nnew.txt\nb.txt\n\"dir/new file\\303\\251.sh\"\ngone.txt\nlink\nscript.sh\n"
+		if out := FormatNameOnly(files); out != expected {
+			t.Errorf("incorrect output\nexpected: %q\n  actual: %q", expected, out)
+		}
+	})
+
+	t.Run("nameStatus", func(t *testing.T) {
+		expected := "M\tfile.txt\n" +
+			"R100\told.txt\tnew.txt\n" +
+			"C075\ta.txt\tb.txt\n" +
+			"A\t\"dir/new file\\303\\251.sh\"\n" +
+			"D\tgone.txt\n" +
+			"T\tlink\n" +
+			"M\tscript.sh\n"
+		if out := FormatNameStatus(files); out != expected {
+			t.Errorf("incorrect output\nexpected: %q\n  actual: %q", expected, out)
+		}
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		expected := ":100644 100644 ebe9fa5 fe103e1 M\tfile.txt\n" +
+			":100644 100644 417ebc7 417ebc7 R100\told.txt\tnew.txt\n" +
+			":100644 100644 1111111 2222222 C075\ta.txt\tb.txt\n" +
+			":000000 100755 0000000 3333333 A\t\"dir/new file\\303\\251.sh\"\n" +
+			":100644 000000 4444444 0000000 D\tgone.txt\n" +
+			":100644 120000 5555555 6666666 T\tlink\n" +
+			":100644 100755 7777777 7777777 M\tscript.sh\n"
+		if out := FormatRaw(files); out != expected {
+			t.Errorf("incorrect output\nexpected: %q\n  actual: %q", expected, out)
+		}
+	})
+
+	t.Run("roundTrip", func(t *testing.T) {
+		for _, f := range files {
+			line := NewRawEntry(f).String()
+			e, err := ParseRawEntry(line)
+			if err != nil {
+				t.Fatalf("unexpected error parsing formatted entry %q: %v", line, err)
+			}
+			if e.String() != line {
+				t.Errorf("entry did not round trip\nexpected: %q\n  actual: %q", line, e.String())
+			}
+		}
+	})
+}
+
+func TestParseRawOutput(t *testing.T) {
+	mode := os.FileMode(0100644)
+	full := []*RawEntry{
+		{OldMode: mode, NewMode: mode, OldOID: "ebe9fa5", NewOID: "fe103e1", Status: 'M', OldName: "file.txt", NewName: "file.txt"},
+		{OldMode: mode, NewMode: mode, OldOID: "417ebc7", NewOID: "417ebc7", Status: 'R', Score: 100, OldName: "old.txt", NewName: "new\tname.txt"},
+		{OldMode: mode, NewMode: 0, OldOID: "4444444", NewOID: "0000000", Status: 'D', OldName: "gone.txt", NewName: "gone.txt"},
+	}
+	names := []*RawEntry{
+		{Status: 'M', OldName: "file.txt", NewName: "file.txt"},
+		{Status: 'R', Score: 100, OldName: "old.txt", NewName: "new\tname.txt"},
+		{Status: 'D', OldName: "gone.txt", NewName: "gone.txt"},
+	}
+
+	tests := map[string]struct {
+		Parse  func(r io.Reader) ([]*RawEntry, error)
+		Input  string
+		Output []*RawEntry
+		Err    bool
+	}{
+		"raw": {
+			Parse: ParseRaw,
+			Input: ":100644 100644 ebe9fa5 fe103e1 M\tfile.txt\n" +
+				":100644 100644 417ebc7 417ebc7 R100\told.txt\t\"new\\tname.txt\"\n" +
+				"\n" +
+				":100644 000000 4444444 0000000 D\tgone.txt",
+			Output: full,
+		},
+		"rawZ": {
+			Parse: ParseRawZ,
+			Input: ":100644 100644 ebe9fa5 fe103e1 M\x00file.txt\x00" +
+				":100644 100644 417ebc7 417ebc7 R100\x00old.txt\x00new\tname.txt\x00" +
+				":100644 000000 4444444 0000000 D\x00gone.txt\x00",
+			Output: full,
+		},
+		"rawZMissingName": {
+			Parse: ParseRawZ,
+			Input: ":100644 100644 417ebc7 417ebc7 R100\x00old.txt\x00",
+			Err:   true,
+		},
+		"nameStatus": {
+			Parse:  ParseNameStatus,
+			Input:  "M\tfile.txt\nR100\told.txt\t\"new\\tname.txt\"\nD\tgone.txt\n",
+			Output: names,
+		},
+		"nameStatusZ": {
+			Parse:  ParseNameStatusZ,
+			Input:  "M\x00file.txt\x00R100\x00old.txt\x00new\tname.txt\x00D\x00gone.txt\x00",
+			Output: names,
+		},
+		"nameStatusInvalidStatus": {
+			Parse: ParseNameStatus,
+			Input: "M\tfile.txt\nQ\tother.txt\n",
+			Err:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entries, err := test.Parse(strings.NewReader(test.Input))
+			if test.Err {
+				if err == nil {
+					t.Fatalf("expected error parsing output, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing output: %v", err)
+			}
+			if !reflect.DeepEqual(test.Output, entries) {
+				t.Errorf("incorrect entries\nexpected: %+v\n  actual: %+v", test.Output, entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package gitdiff
+
+import (
+	"strings"
+)
+
+// ConflictResolver resolves conflicts found by ApplyThreeWay.
+type ConflictResolver interface {
+	// Resolve returns the lines to write in place of the conflicting changes
+	// in c and true, or false to write the conflict with markers. Lines
+	// should include their newline characters.
+	Resolve(c *Conflict) ([]string, bool)
+}
+
+// ConflictResolverFunc is an adapter to allow the use of ordinary functions
+// as ConflictResolvers.
+type ConflictResolverFunc func(c *Conflict) ([]string, bool)
+
+// Resolve calls fn(c).
+func (fn ConflictResolverFunc) Resolve(c *Conflict) ([]string, bool) {
+	return fn(c)
+}
+
+var (
+	// ResolveOurs resolves conflicts by keeping our lines, like the ours
+	// option of git merge-file.
+	ResolveOurs ConflictResolver = ConflictResolverFunc(func(c *Conflict) ([]string, bool) {
+		return c.Ours, true
+	})
+
+	// ResolveTheirs resolves conflicts by taking the lines from the patch,
+	// like the theirs option of git merge-file.
+	ResolveTheirs ConflictResolver = ConflictResolverFunc(func(c *Conflict) ([]string, bool) {
+		return c.Theirs, true
+	})
+
+	// ResolveUnion resolves conflicts by keeping our lines followed by the
+	// lines from the patch that are not in ours, like the union option of
+	// git merge-file but without lines that both sides contain. Lines that
+	// repeat within one side are kept.
+	ResolveUnion ConflictResolver = ConflictResolverFunc(resolveUnion)
+)
+
+func resolveUnion(c *Conflict) ([]string, bool) {
+	ours := make(map[string]bool)
+	for _, line := range c.Ours {
+		ours[strings.TrimSuffix(line, "\n")] = true
+	}
+
+	lines := append([]string(nil), c.Ours...)
+	for _, line := range c.Theirs {
+		if ours[strings.TrimSuffix(line, "\n")] {
+			continue
+		}
+
+		// only the last line can be missing a newline
+		if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+			lines[n-1] += "\n"
+		}
+		lines = append(lines, line)
+	}
+	return lines, true
+}
@@ -0,0 +1,132 @@
+package gitdiff
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyThreeWayResolve(t *testing.T) {
+	base := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	ours := "1\nfour\n3\n4\n5\n6\n7\n8\nours\n10\n"
+	theirs := "1\nFOUR\n3\n4\n5\n6\n7\n8\ntheirs"
+
+	tests := map[string]struct {
+		Resolver ConflictResolver
+		Output   string
+		Resolved []bool
+		Ranges   []LineRange
+	}{
+		"ours": {
+			Resolver: ResolveOurs,
+			Output:   ours,
+			Resolved: []bool{true, true},
+			Ranges:   []LineRange{{Position: 2, Lines: 1}, {Position: 9, Lines: 2}},
+		},
+		"theirs": {
+			Resolver: ResolveTheirs,
+			Output:   theirs,
+			Resolved: []bool{true, true},
+			Ranges:   []LineRange{{Position: 2, Lines: 1}, {Position: 9, Lines: 1}},
+		},
+		"union": {
+			Resolver: ResolveUnion,
+			Output:   "1\nfour\nFOUR\n3\n4\n5\n6\n7\n8\nours\n10\ntheirs",
+			Resolved: []bool{true, true},
+			Ranges:   []LineRange{{Position: 2, Lines: 2}, {Position: 10, Lines: 3}},
+		},
+		"callback": {
+			Resolver: ConflictResolverFunc(func(c *Conflict) ([]string, bool) {
+				if strings.Join(c.Base, "") == "2\n" {
+					return []string{"two\n"}, true
+				}
+				return nil, false
+			}),
+			Output:   "1\ntwo\n3\n4\n5\n6\n7\n8\n<<<<<<< ours\nours\n10\n=======\ntheirs\n>>>>>>> theirs\n",
+			Resolved: []bool{true, false},
+			Ranges:   []LineRange{{Position: 2, Lines: 1}, {Position: 9, Lines: 6}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := &File{
+				OldName:       "file.txt",
+				NewName:       "file.txt",
+				TextFragments: DiffText([]byte(base), []byte(theirs), DiffOptions{}),
+			}
+
+			var out bytes.Buffer
+			conflicts, err := ApplyThreeWay(&out, []byte(base), []byte(ours), f, ApplyOptions{ConflictResolver: test.Resolver})
+			if err != nil {
+				t.Fatalf("unexpected error applying patch: %v", err)
+			}
+			if out.String() != test.Output {
+				t.Errorf("incorrect output\nexpected: %q\n  actual: %q", test.Output, out.String())
+			}
+
+			if len(conflicts) != len(test.Resolved) {
+				t.Fatalf("incorrect number of conflicts: expected %d, actual %d", len(test.Resolved), len(conflicts))
+			}
+			for i, c := range conflicts {
+				if c.Resolved != test.Resolved[i] {
+					t.Errorf("conflict %d: incorrect resolved: expected %t, actual %t", i, test.Resolved[i], c.Resolved)
+				}
+				if c.NewRange != test.Ranges[i] {
+					t.Errorf("conflict %d: incorrect range: expected %+v, actual %+v", i, test.Ranges[i], c.NewRange)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveUnion(t *testing.T) {
+	tests := map[string]struct {
+		Ours   []string
+		Theirs []string
+		Output []string
+	}{
+		"disjoint": {
+			Ours:   []string{"a\n"},
+			Theirs: []string{"b\n"},
+			Output: []string{"a\n", "b\n"},
+		},
+		"shared": {
+			Ours:   []string{"a\n", "b\n"},
+			Theirs: []string{"b\n", "c\n"},
+			Output: []string{"a\n", "b\n", "c\n"},
+		},
+		"repeatedOurs": {
+			Ours:   []string{"\t}\n", "}\n", "}\n"},
+			Theirs: []string{"}\n", "x\n"},
+			Output: []string{"\t}\n", "}\n", "}\n", "x\n"},
+		},
+		"repeatedTheirs": {
+			Ours:   []string{"a\n"},
+			Theirs: []string{"}\n", "}\n"},
+			Output: []string{"a\n", "}\n", "}\n"},
+		},
+		"missingNewline": {
+			Ours:   []string{"a"},
+			Theirs: []string{"b"},
+			Output: []string{"a\n", "b"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ours := append([]string(nil), test.Ours...)
+			lines, ok := ResolveUnion.Resolve(&Conflict{Ours: ours, Theirs: test.Theirs})
+			if !ok {
+				t.Fatal("expected conflict to be resolved")
+			}
+			if !reflect.DeepEqual(test.Output, lines) {
+				t.Errorf("incorrect lines\nexpected: %q\n  actual: %q", test.Output, lines)
+			}
+			if !reflect.DeepEqual(test.Ours, ours) {
+				t.Errorf("our lines were modified: %q", ours)
+			}
+		})
+	}
+}
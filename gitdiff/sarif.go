@@ -0,0 +1,152 @@
+package gitdiff
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+)
+
+// SARIFRule describes a rule that reports findings in a SARIF log.
+type SARIFRule struct {
+	ID          string
+	Description string
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	ShortDescription *sarifMessage `json:"shortDescription,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int64         `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// WriteSARIF writes findings to w as a SARIF 2.1.0 log from the tool with
+// the given name, so they can be uploaded to code scanning services. Rules
+// describes the rules of the tool; rules of findings that are not in it are
+// added without a description. Each finding is reported as a warning with
+// its Message, or the description of its rule if it has no message, at the
+// line of the finding in the new version of the file. The name of the file is
+// percent-encoded as a relative URI. Like the default of SARIF, columns are
+// in UTF-16 code units, so the column is only included if ColumnUTF16 is set
+// or the finding is at the start of the line. The Redacted line is included
+// as a snippet.
+func WriteSARIF(w io.Writer, tool string, rules []SARIFRule, findings []Finding) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: tool, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+
+	index := make(map[string]int)
+	descriptions := make(map[string]string)
+	addRule := func(id, description string) int {
+		if i, ok := index[id]; ok {
+			return i
+		}
+		r := sarifRule{ID: id}
+		if description != "" {
+			r.ShortDescription = &sarifMessage{Text: description}
+		}
+		index[id] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, r)
+		return index[id]
+	}
+	for _, r := range rules {
+		addRule(r.ID, r.Description)
+		descriptions[r.ID] = r.Description
+	}
+
+	for _, f := range findings {
+		msg := f.Message
+		if msg == "" {
+			msg = descriptions[f.Rule]
+		}
+		if msg == "" {
+			msg = f.Rule
+		}
+
+		loc := sarifLocation{
+			PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: sarifURI(f.File)}},
+		}
+		if f.Line > 0 {
+			region := &sarifRegion{StartLine: f.Line, StartColumn: f.ColumnUTF16}
+			if f.Column == 1 {
+				region.StartColumn = 1
+			}
+			if f.Redacted != "" {
+				region.Snippet = &sarifMessage{Text: f.Redacted}
+			}
+			loc.PhysicalLocation.Region = region
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    f.Rule,
+			RuleIndex: addRule(f.Rule, ""),
+			Level:     "warning",
+			Message:   sarifMessage{Text: msg},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifURI returns name as a relative URI reference, percent-encoding the
+// characters that are not allowed in URIs.
+func sarifURI(name string) string {
+	u := url.URL{Path: name}
+	return u.String()
+}
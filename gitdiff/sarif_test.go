@@ -0,0 +1,92 @@
+package gitdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	rules := []SARIFRule{
+		{ID: "trailing-whitespace", Description: "Line ends with whitespace"},
+		{ID: "unused", Description: "A rule without findings"},
+	}
+	findings := []Finding{
+		{File: "docs/readme.txt", Line: 2, Rule: "trailing-whitespace"},
+		{File: "src/main.go", Line: 4, Column: 9, ColumnUTF16: 7, Rule: RuleBidiControl, Redacted: "\"é\" := <U+202E>", Message: "bidirectional control character"},
+		{File: "docs/read me #1.txt", Line: 1, Column: 3, Rule: "trailing-whitespace"},
+		{File: "src/main.go", Rule: "invalid-fragment", Message: "fragment 1: fragment contains no changes"},
+	}
+
+	var b bytes.Buffer
+	if err := WriteSARIF(&b, "gogitdiff", rules, findings); err != nil {
+		t.Fatalf("unexpected error writing SARIF: %v", err)
+	}
+
+	var log map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, b.String())
+	}
+
+	var expected map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "gogitdiff", "rules": [
+      {"id": "trailing-whitespace", "shortDescription": {"text": "Line ends with whitespace"}},
+      {"id": "unused", "shortDescription": {"text": "A rule without findings"}},
+      {"id": "bidi-control"},
+      {"id": "invalid-fragment"}
+    ]}},
+    "results": [
+      {
+        "ruleId": "trailing-whitespace", "ruleIndex": 0, "level": "warning",
+        "message": {"text": "Line ends with whitespace"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "docs/readme.txt"}, "region": {"startLine": 2}}}]
+      },
+      {
+        "ruleId": "bidi-control", "ruleIndex": 2, "level": "warning",
+        "message": {"text": "bidirectional control character"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/main.go"}, "region": {"startLine": 4, "startColumn": 7, "snippet": {"text": "\"é\" := <U+202E>"}}}}]
+      },
+      {
+        "ruleId": "trailing-whitespace", "ruleIndex": 0, "level": "warning",
+        "message": {"text": "Line ends with whitespace"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "docs/read%20me%20%231.txt"}, "region": {"startLine": 1}}}]
+      },
+      {
+        "ruleId": "invalid-fragment", "ruleIndex": 3, "level": "warning",
+        "message": {"text": "fragment 1: fragment contains no changes"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/main.go"}}}]
+      }
+    ]
+  }]
+}`), &expected); err != nil {
+		t.Fatalf("invalid expected JSON: %v", err)
+	}
+
+	if !reflect.DeepEqual(expected, log) {
+		t.Errorf("incorrect SARIF log\nexpected: %v\n  actual: %v", expected, log)
+	}
+}
+
+func TestWriteSARIFEmpty(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteSARIF(&b, "gogitdiff", nil, nil); err != nil {
+		t.Fatalf("unexpected error writing SARIF: %v", err)
+	}
+
+	var log struct {
+		Runs []struct {
+			Results []interface{} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(log.Runs) != 1 || log.Runs[0].Results == nil {
+		t.Errorf("expected one run with an empty result list, but got: %s", b.String())
+	}
+}
@@ -0,0 +1,155 @@
+package gitdiff
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Finding is a match reported by a Scanner.
+type Finding struct {
+	// File is the name of the file that contains the match.
+	File string
+
+	// Line is the line number of the match in the new version of the file.
+	Line int64
+
+	// Column is the one-indexed byte position of the match in the line, or
+	// zero if the scanner does not report positions.
+	Column int
+
+	// ColumnUTF16 is the one-indexed position of the match in the line in
+	// UTF-16 code units, as used by SARIF, or zero if it is not known.
+	ColumnUTF16 int
+
+	// Rule is the name of the rule that matched.
+	Rule string
+
+	// Redacted is the matching line, without its trailing newline, with the
+	// sensitive part of the match replaced.
+	Redacted string
+
+	// Message, if set, describes the problem found. Otherwise, reports use
+	// the description of the rule.
+	Message string
+}
+
+// Scanner examines the lines added by a patch.
+type Scanner interface {
+	// ScanLine is called for each added line with the name of the file, the
+	// line number in the new version of the file, and the content of the
+	// line. The content includes the trailing newline, if present.
+	ScanLine(file string, lineno int64, line string) []Finding
+}
+
+// ScannerFunc is an adapter to use an ordinary function as a Scanner.
+type ScannerFunc func(file string, lineno int64, line string) []Finding
+
+// ScanLine calls fn(file, lineno, line).
+func (fn ScannerFunc) ScanLine(file string, lineno int64, line string) []Finding {
+	return fn(file, lineno, line)
+}
+
+// ScanPatch parses the patch in r and calls s for each added line as it is
+// read. Unlike ParseAll, it does not keep the content of parsed files, so it
+// can screen large patches with little memory. It returns all findings and
+// stops at the first parse error, returning the findings reported before the
+// error.
+func ScanPatch(r io.Reader, s Scanner) ([]Finding, error) {
+	var findings []Finding
+	var name string
+
+	p := newParser(r)
+	p.discardLines = true
+	p.onAdd = func(lineno int64, line string) {
+		findings = append(findings, s.ScanLine(name, lineno, line)...)
+	}
+
+	if err := p.Next(); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for {
+		file, _, err := p.ParseNextFileHeader()
+		if err != nil {
+			return findings, err
+		}
+		if file == nil {
+			break
+		}
+
+		name = file.NewName
+		if file.IsDelete {
+			name = file.OldName
+		}
+		if err := p.ParseFragments(file); err != nil {
+			return findings, err
+		}
+	}
+	return findings, nil
+}
+
+// SecretRule is a named pattern that matches a credential or key. If the
+// pattern has a capture group, only the first group is redacted.
+type SecretRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// DefaultSecretRules match common credential formats.
+var DefaultSecretRules = []SecretRule{
+	{"aws-access-key-id", regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{"github-token", regexp.MustCompile(`\b(gh[pousr]_[0-9A-Za-z]{36,})\b`)},
+	{"slack-token", regexp.MustCompile(`\b(xox[abposr]-[0-9A-Za-z-]{10,})\b`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN ((?:[A-Z]+ )*PRIVATE KEY)-----`)},
+	{"generic-secret", regexp.MustCompile(`(?i)(?:password|passwd|secret|api_?key|token)["']?\s*[:=]\s*["']([^"'\s]{8,})["']`)},
+}
+
+// SecretScanner is a Scanner that reports lines matching any of its rules.
+// The zero value uses DefaultSecretRules.
+type SecretScanner struct {
+	Rules []SecretRule
+}
+
+// ScanLine implements Scanner. It reports at most one finding per rule.
+func (s *SecretScanner) ScanLine(file string, lineno int64, line string) []Finding {
+	rules := s.Rules
+	if rules == nil {
+		rules = DefaultSecretRules
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+
+	var findings []Finding
+	for _, rule := range rules {
+		m := rule.Pattern.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		start, end := m[0], m[1]
+		if len(m) > 2 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		findings = append(findings, Finding{
+			File:        file,
+			Line:        lineno,
+			Column:      m[0] + 1,
+			ColumnUTF16: int(textWidth(line[:m[0]], PositionUTF16)) + 1,
+			Rule:        rule.Name,
+			Redacted:    line[:start] + redact(line[start:end]) + line[end:],
+		})
+	}
+	return findings
+}
+
+// redact hides a secret, keeping a short prefix to help identify it. The
+// length of the secret is not preserved.
+func redact(secret string) string {
+	const keep = 4
+	if len(secret) <= 2*keep {
+		return "****"
+	}
+	return secret[:keep] + "****"
+}
@@ -0,0 +1,115 @@
+package gitdiff
+
+import (
+	"bufio"
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+// isScissorsLine returns true if line is a scissors line, using the rules
+// of git mailinfo: the line contains a scissors mark, such as ">8" or "8<",
+// in a perforation of dashes that covers most of the visible line.
+func isScissorsLine(line string) bool {
+	var scissors, perforation, gap int
+	first, last := -1, -1
+	inPerforation := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if unicode.IsSpace(rune(c)) {
+			if inPerforation {
+				perforation++
+				gap++
+			}
+			continue
+		}
+
+		last = i
+		if first < 0 {
+			first = i
+		}
+		if c == '-' {
+			inPerforation = true
+			perforation++
+			continue
+		}
+		if mark := line[i:]; strings.HasPrefix(mark, ">8") || strings.HasPrefix(mark, "8<") ||
+			strings.HasPrefix(mark, ">%") || strings.HasPrefix(mark, "%<") {
+			inPerforation = true
+			perforation += 2
+			scissors += 2
+			i++
+			continue
+		}
+		inPerforation = false
+	}
+
+	// the mark must be at least 8 bytes, like "-- >8 --", and must cover at
+	// least a third of the visible line without too many gaps
+	visible := last - first + 1
+	return scissors > 0 && visible >= 8 && visible < perforation*3 && gap*2 < perforation
+}
+
+// cutScissors returns the content of body after the last scissors line and
+// true, or body and false if it does not have a scissors line.
+func cutScissors(body string) (string, bool) {
+	lines := strings.SplitAfter(body, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if isScissorsLine(lines[i]) {
+			return strings.Join(lines[i+1:], ""), true
+		}
+	}
+	return body, false
+}
+
+// parseInBodyHeaders sets the fields of h from the headers at the start of
+// body, as allowed after a scissors line, and returns the rest of the body.
+func parseInBodyHeaders(h *PatchHeader, body string) (string, error) {
+	body = strings.TrimLeft(body, "\r\n")
+
+	r := bufio.NewReader(strings.NewReader(body))
+	var n int
+	var headers []string
+	for {
+		line, err := r.ReadString('\n')
+		name := strings.ToLower(strings.SplitN(line, ":", 2)[0])
+		if name != "from" && name != "subject" && name != "date" {
+			break
+		}
+		n += len(line)
+		headers = append(headers, line)
+		if err != nil {
+			break
+		}
+	}
+	if len(headers) == 0 {
+		return body, nil
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(strings.Join(headers, "") + "\n"))
+	if err != nil {
+		return "", err
+	}
+	if from := msg.Header.Get("From"); from != "" {
+		addr, err := mail.ParseAddress(from)
+		if err != nil {
+			return "", err
+		}
+		if addr.Name == "" {
+			addr.Name = addr.Address
+		}
+		h.Author = &PatchIdentity{Name: addr.Name, Email: addr.Address}
+	}
+	if date := msg.Header.Get("Date"); date != "" {
+		d, err := ParsePatchDate(date)
+		if err != nil {
+			return "", err
+		}
+		h.AuthorDate = d
+	}
+	if subject := msg.Header.Get("Subject"); subject != "" {
+		h.SubjectPrefix, h.Title = parseSubject(subject)
+	}
+	return body[n:], nil
+}
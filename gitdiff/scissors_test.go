@@ -0,0 +1,73 @@
+package gitdiff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsScissorsLine(t *testing.T) {
+	tests := map[string]bool{
+		"-- >8 --\n":                          true,
+		"-- 8< --\n":                          true,
+		"  -- >8 -- cut here --\n":            true,
+		"-- >8 -- -- -- -- -- cut here\n":     true,
+		"------------ >8 ------------\n":      true,
+		"- - - - >% - - - -\n":                true,
+		"-->8--\n":                            false,
+		">8\n":                                false,
+		"-- 8\n":                              false,
+		"--------\n":                          false,
+		"see the -- >8 -- line below, then\n": false,
+	}
+
+	for line, expected := range tests {
+		if actual := isScissorsLine(line); actual != expected {
+			t.Errorf("incorrect result for %q: expected %t, actual %t", line, expected, actual)
+		}
+	}
+}
+
+func TestParsePatchHeaderScissors(t *testing.T) {
+	const header = `From 61f5cd90bed4d204ee3feb3aa41ee91d4734855b Mon Sep 17 00:00:00 2001
+From: Morton Haypenny <mhaypenny@example.com>
+Date: Sat, 11 Apr 2020 15:21:23 -0700
+Subject: [PATCH] Re: A discussion about the change
+
+I think we should do this instead:
+
+-- >8 --
+From: Another Author <another@example.com>
+Subject: [PATCH] Change the value
+
+The value is now correct.
+`
+
+	h, err := ParsePatchHeaderWithOptions(header, PatchHeaderOptions{Scissors: true})
+	if err != nil {
+		t.Fatalf("unexpected error parsing header: %v", err)
+	}
+
+	expected := &PatchHeader{
+		SHA:           "61f5cd90bed4d204ee3feb3aa41ee91d4734855b",
+		Author:        &PatchIdentity{Name: "Another Author", Email: "another@example.com"},
+		AuthorDate:    time.Date(2020, 4, 11, 15, 21, 23, 0, time.FixedZone("PDT", -7*60*60)),
+		Title:         "Change the value",
+		SubjectPrefix: "[PATCH] ",
+		Body:          "The value is now correct.",
+	}
+	assertPatchIdentity(t, "author", expected.Author, h.Author)
+	if !expected.AuthorDate.Equal(h.AuthorDate) {
+		t.Errorf("incorrect author date: expected %v, actual %v", expected.AuthorDate, h.AuthorDate)
+	}
+	if h.SHA != expected.SHA || h.Title != expected.Title || h.SubjectPrefix != expected.SubjectPrefix || h.Body != expected.Body {
+		t.Errorf("incorrect header\nexpected: %+v\n  actual: %+v", expected, h)
+	}
+
+	h, err = ParsePatchHeader(header)
+	if err != nil {
+		t.Fatalf("unexpected error parsing header: %v", err)
+	}
+	if h.Title != "A discussion about the change" || h.Author.Name != "Morton Haypenny" {
+		t.Errorf("scissors were used without the option: %+v", h)
+	}
+}
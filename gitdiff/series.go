@@ -0,0 +1,258 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	seriesVersionRegexp = regexp.MustCompile(`^[vV]([0-9]+)$`)
+	seriesNumberRegexp  = regexp.MustCompile(`^([0-9]+)/([0-9]+)$`)
+)
+
+// SeriesPrefix is the bracketed prefix of the subject of a patch created by
+// git format-patch, such as "[PATCH v2 3/7]".
+type SeriesPrefix struct {
+	// Tag is the text of the prefix other than the version and numbers, such
+	// as "PATCH" or "RFC PATCH net-next".
+	Tag string
+
+	// Version is the version of the series, or 1 if the prefix does not have
+	// a version.
+	Version int
+
+	// Number is the one-indexed position of the patch in the series, or zero
+	// for a cover letter, and Total is the number of patches in the series,
+	// not including the cover letter. Both are 1 if the prefix does not have
+	// numbers.
+	Number int
+	Total  int
+}
+
+// ParseSeriesPrefix parses the last bracketed group in s, which is usually
+// the SubjectPrefix of a PatchHeader. It returns false if s does not contain
+// a bracketed group.
+func ParseSeriesPrefix(s string) (SeriesPrefix, bool) {
+	end := strings.LastIndexByte(s, ']')
+	if end < 0 {
+		return SeriesPrefix{}, false
+	}
+	start := strings.LastIndexByte(s[:end], '[')
+	if start < 0 {
+		return SeriesPrefix{}, false
+	}
+
+	p := SeriesPrefix{Version: 1, Number: 1, Total: 1}
+	var tag []string
+	for _, word := range strings.Fields(s[start+1 : end]) {
+		if m := seriesVersionRegexp.FindStringSubmatch(word); m != nil {
+			p.Version, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if m := seriesNumberRegexp.FindStringSubmatch(word); m != nil {
+			p.Number, _ = strconv.Atoi(m[1])
+			p.Total, _ = strconv.Atoi(m[2])
+			continue
+		}
+		tag = append(tag, word)
+	}
+	p.Tag = strings.Join(tag, " ")
+	return p, true
+}
+
+// String returns the prefix in the format used by git format-patch. Numbers
+// are padded with zeros to the width of the total and are omitted for a
+// series with a single patch and no cover letter. If the tag is empty,
+// "PATCH" is used.
+func (p SeriesPrefix) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	if p.Tag == "" {
+		b.WriteString("PATCH")
+	} else {
+		b.WriteString(p.Tag)
+	}
+	if p.Version > 1 {
+		fmt.Fprintf(&b, " v%d", p.Version)
+	}
+	if p.Total > 1 || p.Number != p.Total {
+		width := len(strconv.Itoa(p.Total))
+		fmt.Fprintf(&b, " %0*d/%d", width, p.Number, p.Total)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// SeriesPatch is one patch or the cover letter of a series.
+type SeriesPatch struct {
+	Prefix SeriesPrefix
+	Header *PatchHeader
+	Files  []*File
+}
+
+// ParseSeriesPatch parses a single patch created by git format-patch. The
+// patch may be a cover letter without any files. It returns an error if the
+// subject of the patch does not have a prefix.
+func ParseSeriesPatch(r io.Reader) (*SeriesPatch, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	files, preamble, err := ParseAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		// cover letters have no files, so the whole message is the header
+		preamble = string(data)
+	}
+
+	header, err := ParsePatchHeader(preamble)
+	if err != nil {
+		return nil, err
+	}
+	prefix, ok := ParseSeriesPrefix(header.SubjectPrefix)
+	if !ok {
+		return nil, errors.New("gitdiff: patch subject does not have a prefix")
+	}
+	for _, f := range files {
+		f.PatchHeader = header
+	}
+	return &SeriesPatch{Prefix: prefix, Header: header, Files: files}, nil
+}
+
+// Subject returns the subject line of the patch, with its prefix.
+func (p *SeriesPatch) Subject() string {
+	var title string
+	if p.Header != nil {
+		title = p.Header.Title
+	}
+	return p.Prefix.String() + " " + title
+}
+
+// setPrefix changes the prefix of the patch and the subject prefix of its
+// header.
+func (p *SeriesPatch) setPrefix(prefix SeriesPrefix) {
+	p.Prefix = prefix
+	if p.Header != nil {
+		p.Header.SubjectPrefix = prefix.String() + " "
+	}
+}
+
+// Series is a set of patches created by git format-patch, in order.
+type Series struct {
+	// Patches contains the patches in order of their numbers, starting with
+	// the cover letter, if present.
+	Patches []*SeriesPatch
+}
+
+// NewSeries orders patches by their numbers. It returns an error if the
+// patches have different versions or totals, or if two patches have the same
+// number. Use Missing to find patches that are not present.
+func NewSeries(patches []*SeriesPatch) (*Series, error) {
+	sorted := make([]*SeriesPatch, len(patches))
+	copy(sorted, patches)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Prefix.Number < sorted[j].Prefix.Number
+	})
+
+	for i, p := range sorted {
+		if i == 0 {
+			continue
+		}
+		prev := sorted[i-1].Prefix
+		switch {
+		case p.Prefix.Version != prev.Version:
+			return nil, fmt.Errorf("gitdiff: series has patches from versions %d and %d", prev.Version, p.Prefix.Version)
+		case p.Prefix.Total != prev.Total:
+			return nil, fmt.Errorf("gitdiff: series has patches with totals %d and %d", prev.Total, p.Prefix.Total)
+		case p.Prefix.Number == prev.Number:
+			return nil, fmt.Errorf("gitdiff: series has more than one patch %d", p.Prefix.Number)
+		}
+	}
+	return &Series{Patches: sorted}, nil
+}
+
+// CoverLetter returns the cover letter of the series, or nil if it does not
+// have one.
+func (s *Series) CoverLetter() *SeriesPatch {
+	if len(s.Patches) > 0 && s.Patches[0].Prefix.Number == 0 {
+		return s.Patches[0]
+	}
+	return nil
+}
+
+// Missing returns the numbers of the patches in the series that are not
+// present, based on the total in their prefixes.
+func (s *Series) Missing() []int {
+	if len(s.Patches) == 0 {
+		return nil
+	}
+
+	present := make(map[int]bool)
+	for _, p := range s.Patches {
+		present[p.Prefix.Number] = true
+	}
+
+	var missing []int
+	for n := 1; n <= s.Patches[0].Prefix.Total; n++ {
+		if !present[n] {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+// Renumber numbers the patches in the series from 1 in their current order
+// and sets the total to the number of patches, as when a series is sent
+// again after patches are added, removed, or reordered. The cover letter
+// keeps the number 0. The subject prefixes of the patch headers are updated.
+func (s *Series) Renumber() {
+	cover := s.CoverLetter()
+	total := len(s.Patches)
+	if cover != nil {
+		total--
+	}
+
+	n := 1
+	for _, p := range s.Patches {
+		prefix := p.Prefix
+		prefix.Total = total
+		if p != cover {
+			prefix.Number = n
+			n++
+		}
+		p.setPrefix(prefix)
+	}
+}
+
+// Reprefix sets the tag and version of every patch in the series, as with
+// the --subject-prefix and --reroll-count options of git format-patch. The
+// subject prefixes of the patch headers are updated.
+func (s *Series) Reprefix(tag string, version int) {
+	for _, p := range s.Patches {
+		prefix := p.Prefix
+		prefix.Tag, prefix.Version = tag, version
+		p.setPrefix(prefix)
+	}
+}
+
+// IsCoverLetter returns true if the header is from the cover letter of a
+// patch series, the message numbered 0 that git format-patch creates with
+// the --cover-letter option. Cover letters describe the series and do not
+// contain any changes.
+func (h *PatchHeader) IsCoverLetter() bool {
+	if h == nil {
+		return false
+	}
+	prefix, ok := ParseSeriesPrefix(h.SubjectPrefix)
+	return ok && prefix.Number == 0
+}
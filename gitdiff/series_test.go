@@ -0,0 +1,200 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSeriesPrefix(t *testing.T) {
+	tests := map[string]struct {
+		Input  string
+		Prefix SeriesPrefix
+		String string
+		Err    bool
+	}{
+		"single": {
+			Input:  "[PATCH] ",
+			Prefix: SeriesPrefix{Tag: "PATCH", Version: 1, Number: 1, Total: 1},
+			String: "[PATCH]",
+		},
+		"numbered": {
+			Input:  "[PATCH 03/12] ",
+			Prefix: SeriesPrefix{Tag: "PATCH", Version: 1, Number: 3, Total: 12},
+			String: "[PATCH 03/12]",
+		},
+		"version": {
+			Input:  "[PATCH v2 3/7] ",
+			Prefix: SeriesPrefix{Tag: "PATCH", Version: 2, Number: 3, Total: 7},
+			String: "[PATCH v2 3/7]",
+		},
+		"coverLetter": {
+			Input:  "[RFC PATCH net-next v3 0/1] ",
+			Prefix: SeriesPrefix{Tag: "RFC PATCH net-next", Version: 3, Number: 0, Total: 1},
+			String: "[RFC PATCH net-next v3 0/1]",
+		},
+		"reply": {
+			Input:  "Re: [PATCH 2/2] ",
+			Prefix: SeriesPrefix{Tag: "PATCH", Version: 1, Number: 2, Total: 2},
+			String: "[PATCH 2/2]",
+		},
+		"noPrefix": {
+			Input: "Re: ",
+			Err:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			prefix, ok := ParseSeriesPrefix(test.Input)
+			if test.Err {
+				if ok {
+					t.Fatalf("expected failure parsing prefix, but got %+v", prefix)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("failed to parse prefix %q", test.Input)
+			}
+			if prefix != test.Prefix {
+				t.Errorf("incorrect prefix\nexpected: %+v\n  actual: %+v", test.Prefix, prefix)
+			}
+			if s := prefix.String(); s != test.String {
+				t.Errorf("incorrect string: expected %q, actual %q", test.String, s)
+			}
+		})
+	}
+}
+
+func TestParseSeriesPatch(t *testing.T) {
+	const patch = `From 61f5cd90bed4d204ee3feb3aa41ee91d4734855b Mon Sep 17 00:00:00 2001
+From: Morton Haypenny <mhaypenny@example.com>
+Date: Sat, 11 Apr 2020 15:21:23 -0700
+Subject: [PATCH v2 2/3] Change the file
+
+---
+ file.txt | 2 +-
+ 1 file changed, 1 insertion(+), 1 deletion(-)
+
+diff --git a/file.txt b/file.txt
+index 1c23fcc..40a1b33 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1 +1 @@
+-a
++b
+--
+2.26.0
+`
+	p, err := ParseSeriesPatch(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	if expected := (SeriesPrefix{Tag: "PATCH", Version: 2, Number: 2, Total: 3}); p.Prefix != expected {
+		t.Errorf("incorrect prefix: %+v", p.Prefix)
+	}
+	if len(p.Files) != 1 || p.Files[0].PatchHeader != p.Header {
+		t.Errorf("incorrect files: %+v", p.Files)
+	}
+	if s := p.Subject(); s != "[PATCH v2 2/3] Change the file" {
+		t.Errorf("incorrect subject: %q", s)
+	}
+
+	cover := strings.Replace(patch[:strings.Index(patch, "---")], "2/3] Change the file", "0/3] Change things", 1)
+	p, err = ParseSeriesPatch(strings.NewReader(cover))
+	if err != nil {
+		t.Fatalf("unexpected error parsing cover letter: %v", err)
+	}
+	if p.Prefix.Number != 0 || len(p.Files) != 0 {
+		t.Errorf("incorrect cover letter: %+v", p)
+	}
+}
+
+func TestSeries(t *testing.T) {
+	newPatch := func(subject string) *SeriesPatch {
+		prefix, _ := ParseSeriesPrefix(subject)
+		return &SeriesPatch{Prefix: prefix, Header: &PatchHeader{Title: "title", SubjectPrefix: subject + " "}}
+	}
+
+	t.Run("order", func(t *testing.T) {
+		s, err := NewSeries([]*SeriesPatch{
+			newPatch("[PATCH 4/5]"),
+			newPatch("[PATCH 1/5]"),
+			newPatch("[PATCH 0/5]"),
+			newPatch("[PATCH 2/5]"),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error creating series: %v", err)
+		}
+
+		var numbers []int
+		for _, p := range s.Patches {
+			numbers = append(numbers, p.Prefix.Number)
+		}
+		if !reflect.DeepEqual([]int{0, 1, 2, 4}, numbers) {
+			t.Errorf("incorrect order: %v", numbers)
+		}
+		if s.CoverLetter() != s.Patches[0] {
+			t.Errorf("incorrect cover letter")
+		}
+		if missing := s.Missing(); !reflect.DeepEqual([]int{3, 5}, missing) {
+			t.Errorf("incorrect missing patches: %v", missing)
+		}
+
+		s.Renumber()
+		s.Reprefix("PATCH", 2)
+
+		var subjects []string
+		for _, p := range s.Patches {
+			subjects = append(subjects, p.Subject())
+		}
+		expected := []string{
+			"[PATCH v2 0/3] title",
+			"[PATCH v2 1/3] title",
+			"[PATCH v2 2/3] title",
+			"[PATCH v2 3/3] title",
+		}
+		if !reflect.DeepEqual(expected, subjects) {
+			t.Errorf("incorrect subjects\nexpected: %q\n  actual: %q", expected, subjects)
+		}
+		if h := s.Patches[3].Header; h.SubjectPrefix != "[PATCH v2 3/3] " {
+			t.Errorf("incorrect header subject prefix: %q", h.SubjectPrefix)
+		}
+		if s.Missing() != nil {
+			t.Errorf("renumbered series has missing patches: %v", s.Missing())
+		}
+	})
+
+	errTests := map[string][]string{
+		"duplicate": {"[PATCH 1/2]", "[PATCH 1/2]"},
+		"versions":  {"[PATCH 1/2]", "[PATCH v2 2/2]"},
+		"totals":    {"[PATCH 1/2]", "[PATCH 2/3]"},
+	}
+	for name, subjects := range errTests {
+		t.Run(name, func(t *testing.T) {
+			var patches []*SeriesPatch
+			for _, subject := range subjects {
+				patches = append(patches, newPatch(subject))
+			}
+			if _, err := NewSeries(patches); err == nil {
+				t.Fatal("expected error creating series, but got nil")
+			}
+		})
+	}
+}
+
+func TestIsCoverLetter(t *testing.T) {
+	tests := map[string]bool{
+		"[PATCH 0/3] ":    true,
+		"[PATCH v2 0/1] ": true,
+		"[PATCH 1/3] ":    false,
+		"[PATCH] ":        false,
+		"":                false,
+	}
+	for prefix, expected := range tests {
+		h := &PatchHeader{SubjectPrefix: prefix, Title: "title"}
+		if actual := h.IsCoverLetter(); actual != expected {
+			t.Errorf("incorrect result for %q: expected %t, actual %t", prefix, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package gitdiff
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+const (
+	similarityHashBase  = 107927 // from diffcore-delta.c
+	similarityChunkSize = 64
+	binarySniffSize     = 8000 // from xdiff-interface.c
+)
+
+// Similarity returns a score from 0 to 100 describing how similar new is to
+// old, using the same method as Git's rename and copy detection: both inputs
+// are split into chunks that end at a newline or after 64 bytes, and the
+// score is the number of bytes in chunks common to both inputs as a
+// percentage of the size of the larger input. For text content, carriage
+// returns in CRLF line endings are ignored.
+func Similarity(old, new []byte) int {
+	maxSize := len(old)
+	if len(new) > maxSize {
+		maxSize = len(new)
+	}
+	if maxSize == 0 {
+		return 100
+	}
+
+	src := similarityHashes(old)
+	dst := similarityHashes(new)
+
+	var copied int
+	for h, n := range src {
+		if m := dst[h]; m < n {
+			copied += m
+		} else {
+			copied += n
+		}
+	}
+	return copied * 100 / maxSize
+}
+
+// similarityHashes counts the bytes in each chunk of data, keyed by the hash
+// of the chunk.
+func similarityHashes(data []byte) map[uint32]int {
+	isText := !isBinary(data)
+	hashes := make(map[uint32]int)
+
+	var accum1, accum2 uint32
+	var n int
+	for i := 0; i < len(data); i++ {
+		c := uint32(data[i])
+		if isText && c == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			continue
+		}
+
+		old1 := accum1
+		accum1 = (accum1 << 7) ^ (accum2 >> 25)
+		accum2 = (accum2 << 7) ^ (old1 >> 25)
+		accum1 += c
+
+		n++
+		if n < similarityChunkSize && c != '\n' {
+			continue
+		}
+		hashes[(accum1+accum2*0x61)%similarityHashBase] += n
+		n = 0
+		accum1, accum2 = 0, 0
+	}
+	if n > 0 {
+		hashes[(accum1+accum2*0x61)%similarityHashBase] += n
+	}
+	return hashes
+}
+
+// isBinary returns true if data looks like binary content, using the same
+// test as Git: the presence of a NUL byte in the first 8000 bytes.
+func isBinary(data []byte) bool {
+	if len(data) > binarySniffSize {
+		data = data[:binarySniffSize]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// DetectRenames finds pairs of deleted and created files in files with
+// content that is at least threshold percent similar and replaces each pair
+// with a single renamed File that describes the changes between the deleted
+// and the created content. Files that are not part of a pair are returned
+// unchanged in their original order; each renamed file takes the position of
+// its deleted file.
+//
+// The content of deleted and created files is reconstructed from their
+// fragments, so files without full content, such as binary files without
+// patch data, are never paired. Like Git, empty files are never paired
+// either. A renamed file has the annotations of both files of its pair, but
+// its fragments are new and have no annotations.
+func DetectRenames(files []*File, threshold int) []*File {
+	return detectRenames(files, threshold, DiffOptions{})
+}
+
+func detectRenames(files []*File, threshold int, opts DiffOptions) []*File {
+	type candidate struct {
+		del, add int
+		score    int
+	}
+
+	contents := make(map[int][]byte)
+	for i, f := range files {
+		if f.IsNew || f.IsDelete {
+			if data, ok := fileContent(f); ok && len(data) > 0 {
+				contents[i] = data
+			}
+		}
+	}
+
+	var candidates []candidate
+	for i, del := range files {
+		if !del.IsDelete {
+			continue
+		}
+		old, ok := contents[i]
+		if !ok {
+			continue
+		}
+		for j, add := range files {
+			if !add.IsNew || add.IsBinary != del.IsBinary {
+				continue
+			}
+			new, ok := contents[j]
+			if !ok {
+				continue
+			}
+			if score := Similarity(old, new); score >= threshold {
+				candidates = append(candidates, candidate{i, j, score})
+			}
+		}
+	}
+
+	// prefer higher scores, then exact name matches, then earlier files
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if ci.score != cj.score {
+			return ci.score > cj.score
+		}
+		return basename(files[ci.del].OldName) == basename(files[ci.add].NewName) &&
+			basename(files[cj.del].OldName) != basename(files[cj.add].NewName)
+	})
+
+	paired := make(map[int]*File)
+	used := make(map[int]bool)
+	for _, c := range candidates {
+		if used[c.del] || used[c.add] {
+			continue
+		}
+		used[c.del], used[c.add] = true, true
+		paired[c.del] = newRenamedFile(files[c.del], files[c.add], contents[c.del], contents[c.add], c.score, opts)
+	}
+
+	result := make([]*File, 0, len(files)-len(paired))
+	for i, f := range files {
+		switch {
+		case paired[i] != nil:
+			result = append(result, paired[i])
+		case !used[i]:
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func newRenamedFile(del, add *File, old, new []byte, score int, opts DiffOptions) *File {
+	f := &File{
+		OldName:      del.OldName,
+		NewName:      add.NewName,
+		IsRename:     true,
+		OldMode:      del.OldMode,
+		OldOIDPrefix: del.OldOIDPrefix,
+		NewOIDPrefix: add.NewOIDPrefix,
+		Score:        score,
+		PatchHeader:  add.PatchHeader,
+		IsBinary:     del.IsBinary,
+		Annotations:  joinAnnotations(del.Annotations, add.Annotations),
+		Meta:         joinMeta(del.Meta, add.Meta),
+	}
+	if add.NewMode != del.OldMode {
+		f.NewMode = add.NewMode
+	}
+
+	if bytes.Equal(old, new) {
+		return f
+	}
+	if f.IsBinary {
+		f.BinaryFragment, f.ReverseBinaryFragment = DiffBinary(old, new)
+	} else {
+		f.TextFragments = opts.textFragments(old, new)
+	}
+	return f
+}
+
+// fileContent reconstructs the full content of a created or deleted file
+// from its fragments. It returns false if the content is not available.
+func fileContent(f *File) ([]byte, bool) {
+	if f.IsBinary {
+		frag := f.BinaryFragment
+		if f.IsDelete {
+			frag = f.ReverseBinaryFragment
+		}
+		if frag == nil || frag.Method != BinaryPatchLiteral {
+			return nil, false
+		}
+		return frag.Data, true
+	}
+
+	op := OpAdd
+	if f.IsDelete {
+		op = OpDelete
+	}
+
+	var b bytes.Buffer
+	for _, frag := range f.TextFragments {
+		for _, line := range frag.Lines {
+			if line.Op == op {
+				b.WriteString(line.Line)
+			}
+		}
+	}
+	return b.Bytes(), true
+}
+
+func basename(name string) string {
+	return name[strings.LastIndexByte(name, '/')+1:]
+}
@@ -0,0 +1,98 @@
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	lines := func(n int, s string) string {
+		return strings.Repeat(s, n)
+	}
+
+	tests := map[string]struct {
+		Old, New string
+		Score    int
+	}{
+		"identical":  {Old: "a\nb\nc\n", New: "a\nb\nc\n", Score: 100},
+		"empty":      {Old: "", New: "", Score: 100},
+		"disjoint":   {Old: "a\nb\n", New: "c\nd\n", Score: 0},
+		"halfLines":  {Old: "aaaa\nbbbb\n", New: "aaaa\ncccc\n", Score: 50},
+		"crlf":       {Old: "a\r\nb\r\n", New: "a\nb\n", Score: 66},
+		"appended":   {Old: lines(9, "line\n"), New: lines(9, "line\n") + "more\n", Score: 90},
+		"longChunks": {Old: lines(3, strings.Repeat("x", 64)), New: lines(2, strings.Repeat("x", 64)), Score: 66},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			score := Similarity([]byte(test.Old), []byte(test.New))
+			if score != test.Score {
+				t.Errorf("incorrect score: expected %d, actual %d", test.Score, score)
+			}
+		})
+	}
+}
+
+func TestDetectRenames(t *testing.T) {
+	content := "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\n"
+	changed := strings.Replace(content, "line 5\n", "line five\n", 1)
+
+	newFile := func(name, data string) *File {
+		return &File{
+			NewName:       name,
+			IsNew:         true,
+			TextFragments: diffTextFragments(nil, []byte(data), 3),
+		}
+	}
+	deletedFile := func(name, data string) *File {
+		return &File{
+			OldName:       name,
+			IsDelete:      true,
+			TextFragments: diffTextFragments([]byte(data), nil, 3),
+		}
+	}
+
+	files := []*File{
+		deletedFile("old.txt", content),
+		newFile("other.txt", "unrelated\n"),
+		newFile("new.txt", changed),
+		deletedFile("same.txt", "same content\n"),
+		newFile("dir/same.txt", "same content\n"),
+		deletedFile("empty.txt", ""),
+		newFile("dir/empty.txt", ""),
+	}
+
+	result := DetectRenames(files, 50)
+	if len(result) != 5 {
+		t.Fatalf("incorrect number of files: expected 5, actual %d", len(result))
+	}
+
+	rename := result[0]
+	if !rename.IsRename || rename.OldName != "old.txt" || rename.NewName != "new.txt" {
+		t.Fatalf("incorrect rename: %+v", rename)
+	}
+	if rename.Score != 86 {
+		t.Errorf("incorrect score: expected 86, actual %d", rename.Score)
+	}
+	if len(rename.TextFragments) != 1 || rename.TextFragments[0].LinesAdded != 1 || rename.TextFragments[0].LinesDeleted != 1 {
+		t.Errorf("incorrect fragments for rename: %+v", rename.TextFragments)
+	}
+
+	if result[1] != files[1] {
+		t.Errorf("unpaired file was not preserved: %+v", result[1])
+	}
+
+	exact := result[2]
+	if !exact.IsRename || exact.OldName != "same.txt" || exact.NewName != "dir/same.txt" || exact.Score != 100 {
+		t.Errorf("incorrect exact rename: %+v", exact)
+	}
+	if len(exact.TextFragments) != 0 {
+		t.Errorf("exact rename has fragments: %+v", exact.TextFragments)
+	}
+
+	for _, f := range result[3:] {
+		if f.IsRename {
+			t.Errorf("empty files were paired: %+v", f)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package gitdiff
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// StripLevelResult describes how a patch matches a source tree when the
+// names in the patch are stripped like the -p option of patch.
+type StripLevelResult struct {
+	Level int
+
+	// Missing contains the names of files that the patch changes but that
+	// are not in the tree at this level.
+	Missing []string
+
+	// Existing contains the names of files that the patch creates but that
+	// are already in the tree at this level.
+	Existing []string
+}
+
+// OK returns true if every file in the patch matches the tree at the level.
+func (r StripLevelResult) OK() bool {
+	return len(r.Missing) == 0 && len(r.Existing) == 0
+}
+
+// CheckStripLevels parses patch and checks it against the names of the files
+// in a source tree at every strip level from 0 to the depth of the deepest
+// name in the patch, like running patch --dry-run -pN for each level. The
+// tree is a list of slash-separated file names relative to the root of the
+// tree, such as the output of find or tar -t; names of directories are
+// ignored. Only the names are checked, not whether the content applies.
+func CheckStripLevels(patch []byte, tree []string) ([]StripLevelResult, error) {
+	files, _, err := ParseAll(bytes.NewReader(patch))
+	if err != nil {
+		return nil, err
+	}
+	git := isGitPatch(patch)
+
+	exists := make(map[string]bool, len(tree))
+	for _, name := range tree {
+		name = strings.TrimPrefix(path.Clean(strings.TrimPrefix(name, "./")), "./")
+		exists[name] = true
+	}
+
+	maxLevel := 0
+	for _, f := range files {
+		for _, name := range []string{f.OldName, f.NewName} {
+			if depth := strings.Count(name, "/"); depth > maxLevel {
+				maxLevel = depth
+			}
+		}
+	}
+	if git {
+		maxLevel++
+	}
+
+	results := make([]StripLevelResult, 0, maxLevel+1)
+	for level := 0; level <= maxLevel; level++ {
+		r := StripLevelResult{Level: level}
+		for _, f := range files {
+			oldName, newName := namesAtLevel(f, level, git)
+			if (oldName == "" && f.OldName != "") || (newName == "" && f.NewName != "") {
+				// the names do not have enough directories for the level
+				r.Missing = append(r.Missing, fileName(f))
+				continue
+			}
+
+			switch {
+			case f.IsNew:
+				if exists[newName] {
+					r.Existing = append(r.Existing, newName)
+				}
+			case f.IsRename || f.IsCopy || newName == "":
+				if !exists[oldName] {
+					r.Missing = append(r.Missing, oldName)
+				}
+			default:
+				// like patch, use whichever name exists
+				if !exists[oldName] && !exists[newName] {
+					r.Missing = append(r.Missing, oldName)
+				}
+			}
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// FindStripLevel returns the lowest strip level at which every file in patch
+// matches the names in tree, as described by CheckStripLevels. It returns an
+// error if no level matches.
+func FindStripLevel(patch []byte, tree []string) (int, error) {
+	results, err := CheckStripLevels(patch, tree)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range results {
+		if r.OK() {
+			return r.Level, nil
+		}
+	}
+	return 0, fmt.Errorf("gitdiff: patch does not match the tree at any strip level")
+}
+
+// isGitPatch returns true if the patch contains Git file headers.
+func isGitPatch(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("diff --git ")) || bytes.Contains(data, []byte("\ndiff --git "))
+}
+
+// namesAtLevel returns the names of f as patch -p<level> would see them.
+// Names in Git file headers already have their a/ and b/ prefixes removed,
+// while names in traditional headers are kept as they are.
+func namesAtLevel(f *File, level int, git bool) (oldName, newName string) {
+	oldName, newName = f.OldName, f.NewName
+	switch {
+	case git && level == 0:
+		if oldName != "" {
+			oldName = "a/" + oldName
+		}
+		if newName != "" {
+			newName = "b/" + newName
+		}
+	case git:
+		oldName, newName = trimTreePrefix(oldName, level-1), trimTreePrefix(newName, level-1)
+	default:
+		oldName, newName = trimTreePrefix(oldName, level), trimTreePrefix(newName, level)
+	}
+	return oldName, newName
+}
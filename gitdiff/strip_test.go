@@ -0,0 +1,113 @@
+package gitdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckStripLevels(t *testing.T) {
+	tree := []string{
+		"./src/",
+		"./src/main.c",
+		"./src/util.h",
+		"README",
+	}
+
+	tests := map[string]struct {
+		Patch   string
+		Results []StripLevelResult
+		Level   int
+		Err     interface{}
+	}{
+		"gitPatch": {
+			Patch: `diff --git a/src/main.c b/src/main.c
+--- a/src/main.c
++++ b/src/main.c
+@@ -1 +1 @@
+-a
++b
+diff --git a/src/new.c b/src/new.c
+new file mode 100644
+--- /dev/null
++++ b/src/new.c
+@@ -0,0 +1 @@
++new
+`,
+			Results: []StripLevelResult{
+				{Level: 0, Missing: []string{"a/src/main.c"}},
+				{Level: 1},
+				{Level: 2, Missing: []string{"main.c"}},
+			},
+			Level: 1,
+		},
+		"traditionalPatch": {
+			Patch: `--- pkg-1.0.orig/src/util.h
++++ pkg-1.0/src/util.h
+@@ -1 +1 @@
+-a
++b
+--- pkg-1.0.orig/README
++++ pkg-1.0/README
+@@ -1 +1 @@
+-a
++b
+`,
+			Results: []StripLevelResult{
+				{Level: 0, Missing: []string{"pkg-1.0/src/util.h", "pkg-1.0/README"}},
+				{Level: 1},
+				{Level: 2, Missing: []string{"util.h", "pkg-1.0/README"}},
+			},
+			Level: 1,
+		},
+		"existingNewFile": {
+			Patch: `--- /dev/null
++++ README
+@@ -0,0 +1 @@
++new
+`,
+			Results: []StripLevelResult{
+				{Level: 0, Existing: []string{"README"}},
+			},
+			Err: "any strip level",
+		},
+		"noMatch": {
+			Patch: `diff --git a/lib/other.c b/lib/other.c
+--- a/lib/other.c
++++ b/lib/other.c
+@@ -1 +1 @@
+-a
++b
+`,
+			Results: []StripLevelResult{
+				{Level: 0, Missing: []string{"a/lib/other.c"}},
+				{Level: 1, Missing: []string{"lib/other.c"}},
+				{Level: 2, Missing: []string{"other.c"}},
+			},
+			Err: "any strip level",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			results, err := CheckStripLevels([]byte(test.Patch), tree)
+			if err != nil {
+				t.Fatalf("unexpected error checking strip levels: %v", err)
+			}
+			if !reflect.DeepEqual(test.Results, results) {
+				t.Errorf("incorrect results\nexpected: %+v\n  actual: %+v", test.Results, results)
+			}
+
+			level, err := FindStripLevel([]byte(test.Patch), tree)
+			if test.Err != nil {
+				assertError(t, test.Err, err, "finding strip level")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error finding strip level: %v", err)
+			}
+			if level != test.Level {
+				t.Errorf("incorrect level: expected %d, actual %d", test.Level, level)
+			}
+		})
+	}
+}
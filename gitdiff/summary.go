@@ -0,0 +1,115 @@
+package gitdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SummaryVerbosity controls how much detail Summarize includes.
+type SummaryVerbosity int
+
+const (
+	// SummaryShort only reports the number of changed files
+	SummaryShort SummaryVerbosity = iota
+	// SummaryNormal also reports renames, copies, mode changes, creations,
+	// deletions, and binary files
+	SummaryNormal
+	// SummaryVerbose also reports line counts and names created and deleted
+	// files instead of counting them
+	SummaryVerbose
+)
+
+// SummaryOptions configures the output of SummarizeWith.
+type SummaryOptions struct {
+	Verbosity SummaryVerbosity
+
+	// Separator is placed between the parts of the summary. If empty, "; " is
+	// used.
+	Separator string
+}
+
+// Summarize returns a short, human-readable description of the changes in
+// files, suitable for commit messages or bot comments. For example:
+//
+//	3 files changed; renamed a→b; mode change on script.sh; 2 binary files
+//
+// Summarize uses SummaryNormal verbosity.
+func Summarize(files []*File) string {
+	return SummarizeWith(files, SummaryOptions{Verbosity: SummaryNormal})
+}
+
+// SummarizeWith is like Summarize, but uses the given options.
+func SummarizeWith(files []*File, opts SummaryOptions) string {
+	sep := opts.Separator
+	if sep == "" {
+		sep = "; "
+	}
+
+	parts := []string{plural(len(files), "file", "files") + " changed"}
+	if opts.Verbosity < SummaryNormal {
+		return parts[0]
+	}
+
+	if opts.Verbosity >= SummaryVerbose {
+		var added, deleted int64
+		for _, f := range files {
+			for _, frag := range f.TextFragments {
+				added += frag.LinesAdded
+				deleted += frag.LinesDeleted
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s(+), %s(-)",
+			plural(int(added), "insertion", "insertions"),
+			plural(int(deleted), "deletion", "deletions"),
+		))
+	}
+
+	var created, deleted []string
+	var binary int
+	for _, f := range files {
+		switch {
+		case f.IsNew:
+			created = append(created, f.NewName)
+		case f.IsDelete:
+			deleted = append(deleted, f.OldName)
+		case f.IsRename:
+			parts = append(parts, fmt.Sprintf("renamed %s→%s", f.OldName, f.NewName))
+		case f.IsCopy:
+			parts = append(parts, fmt.Sprintf("copied %s→%s", f.OldName, f.NewName))
+		}
+		if !f.IsNew && !f.IsDelete && f.OldMode != 0 && f.NewMode != 0 && f.OldMode != f.NewMode {
+			parts = append(parts, "mode change on "+f.NewName)
+		}
+		if f.IsBinary {
+			binary++
+		}
+	}
+
+	if opts.Verbosity >= SummaryVerbose {
+		if len(created) > 0 {
+			parts = append(parts, "created "+strings.Join(created, ", "))
+		}
+		if len(deleted) > 0 {
+			parts = append(parts, "deleted "+strings.Join(deleted, ", "))
+		}
+	} else {
+		if len(created) > 0 {
+			parts = append(parts, plural(len(created), "file", "files")+" created")
+		}
+		if len(deleted) > 0 {
+			parts = append(parts, plural(len(deleted), "file", "files")+" deleted")
+		}
+	}
+	if binary > 0 {
+		parts = append(parts, plural(binary, "binary file", "binary files"))
+	}
+
+	return strings.Join(parts, sep)
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
@@ -0,0 +1,75 @@
+package gitdiff
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	files := []*File{
+		{
+			OldName:  "a",
+			NewName:  "b",
+			IsRename: true,
+		},
+		{
+			OldName: "script.sh",
+			NewName: "script.sh",
+			OldMode: os.FileMode(0100644),
+			NewMode: os.FileMode(0100755),
+			TextFragments: []*TextFragment{
+				{LinesAdded: 2, LinesDeleted: 1},
+			},
+		},
+		{
+			NewName:  "image.png",
+			IsNew:    true,
+			IsBinary: true,
+		},
+		{
+			OldName:  "data.bin",
+			IsDelete: true,
+			IsBinary: true,
+		},
+	}
+
+	tests := map[string]struct {
+		Files  []*File
+		Opts   SummaryOptions
+		Output string
+	}{
+		"short": {
+			Files:  files,
+			Opts:   SummaryOptions{Verbosity: SummaryShort},
+			Output: "4 files changed",
+		},
+		"normal": {
+			Files:  files,
+			Opts:   SummaryOptions{Verbosity: SummaryNormal},
+			Output: "4 files changed; renamed a→b; mode change on script.sh; 1 file created; 1 file deleted; 2 binary files",
+		},
+		"verbose": {
+			Files:  files,
+			Opts:   SummaryOptions{Verbosity: SummaryVerbose, Separator: ", "},
+			Output: "4 files changed, 2 insertions(+), 1 deletion(-), renamed a→b, mode change on script.sh, created image.png, deleted data.bin, 2 binary files",
+		},
+		"singleFile": {
+			Files:  files[1:2],
+			Opts:   SummaryOptions{Verbosity: SummaryNormal},
+			Output: "1 file changed; mode change on script.sh",
+		},
+		"empty": {
+			Opts:   SummaryOptions{Verbosity: SummaryNormal},
+			Output: "0 files changed",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			output := SummarizeWith(test.Files, test.Opts)
+			if output != test.Output {
+				t.Errorf("incorrect summary\nexpected: %q\n  actual: %q", test.Output, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package gitdiff
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"sort"
+)
+
+// Symbol is a top-level declaration in a file.
+type Symbol struct {
+	// Name is the name of the declaration. Methods are named with their
+	// receiver type, like "Parser.Next".
+	Name string
+
+	// Kind is the kind of declaration, such as "func" or "type". Kinds are
+	// chosen by the SymbolExtractor.
+	Kind string
+
+	// StartLine and EndLine are the first and last lines of the declaration,
+	// including its documentation, starting from 1.
+	StartLine int64
+	EndLine   int64
+}
+
+// SymbolExtractor returns the top-level declarations in the content of the
+// file with the given name.
+type SymbolExtractor func(name string, content []byte) ([]Symbol, error)
+
+// ChangedSymbols returns the top-level declarations in newContent, the
+// content of the file after applying f, that contain lines added by f or
+// the positions of lines it deleted. Declarations are found with the
+// Symbols extractor of the registered language for the new name of the
+// file; ChangedSymbols returns nil if there is no such language. Deleted
+// files and declarations removed entirely are not reported, because only
+// the new content is examined.
+func ChangedSymbols(f *File, newContent []byte) ([]Symbol, error) {
+	if f.IsDelete {
+		return nil, nil
+	}
+	lang, ok := LanguageForPath(f.NewName)
+	if !ok || lang.Symbols == nil {
+		return nil, nil
+	}
+
+	symbols, err := lang.Symbols(f.NewName, newContent)
+	if err != nil {
+		return nil, err
+	}
+
+	added, deleted := changedNewLines(f)
+	var changed []Symbol
+	for _, s := range symbols {
+		// find the first added line at or after the start of the symbol
+		i := sort.Search(len(added), func(i int) bool { return added[i] >= s.StartLine })
+		// a deletion is inside the symbol if lines of the symbol surround it
+		j := sort.Search(len(deleted), func(j int) bool { return deleted[j] > s.StartLine })
+		if (i < len(added) && added[i] <= s.EndLine) || (j < len(deleted) && deleted[j] <= s.EndLine) {
+			changed = append(changed, s)
+		}
+	}
+	return changed, nil
+}
+
+// changedNewLines returns the sorted line numbers in the new content of f
+// of lines added by f and the sorted positions of lines deleted by f. The
+// position of a deletion is the number of the new line that follows it, so
+// the deletion is between that line and the one before it.
+func changedNewLines(f *File) (added []int64, deleted []int64) {
+	for _, frag := range f.TextFragments {
+		line := frag.NewPosition
+		inDelete := false
+		for _, l := range frag.Lines {
+			switch l.Op {
+			case OpAdd:
+				added = append(added, line)
+				line++
+				inDelete = false
+			case OpDelete:
+				if !inDelete {
+					deleted = append(deleted, line)
+				}
+				inDelete = true
+			default:
+				line++
+				inDelete = false
+			}
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i] < added[j] })
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i] < deleted[j] })
+	return added, deleted
+}
+
+// GoSymbols is the SymbolExtractor for Go source files. It reports functions,
+// methods, types, variables, and constants, with grouped declarations
+// reported separately.
+func GoSymbols(name string, content []byte) ([]Symbol, error) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, name, content, goparser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	lineRange := func(start, end token.Pos, doc *ast.CommentGroup) (int64, int64) {
+		if doc != nil {
+			start = doc.Pos()
+		}
+		return int64(fset.Position(start).Line), int64(fset.Position(end).Line)
+	}
+
+	var symbols []Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			s := Symbol{Name: d.Name.Name, Kind: "func"}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				s.Name = goReceiverName(d.Recv.List[0].Type) + "." + s.Name
+				s.Kind = "method"
+			}
+			s.StartLine, s.EndLine = lineRange(d.Pos(), d.End(), d.Doc)
+			symbols = append(symbols, s)
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				// ungrouped declarations include the keyword and the
+				// documentation of the declaration
+				start, end, doc := spec.Pos(), spec.End(), (*ast.CommentGroup)(nil)
+				if !d.Lparen.IsValid() {
+					start, end, doc = d.Pos(), d.End(), d.Doc
+				}
+
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					if sp.Doc != nil && d.Lparen.IsValid() {
+						doc = sp.Doc
+					}
+					startLine, endLine := lineRange(start, end, doc)
+					symbols = append(symbols, Symbol{Name: sp.Name.Name, Kind: "type", StartLine: startLine, EndLine: endLine})
+				case *ast.ValueSpec:
+					if sp.Doc != nil && d.Lparen.IsValid() {
+						doc = sp.Doc
+					}
+					startLine, endLine := lineRange(start, end, doc)
+					for _, n := range sp.Names {
+						symbols = append(symbols, Symbol{Name: n.Name, Kind: d.Tok.String(), StartLine: startLine, EndLine: endLine})
+					}
+				}
+			}
+		}
+	}
+	return symbols, nil
+}
+
+func goReceiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return goReceiverName(e.X)
+	case *ast.IndexExpr:
+		return goReceiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	if x, ok := goIndexListBase(expr); ok {
+		return goReceiverName(x)
+	}
+	return ""
+}
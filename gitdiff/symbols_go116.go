@@ -0,0 +1,12 @@
+//go:build !go1.18
+// +build !go1.18
+
+package gitdiff
+
+import "go/ast"
+
+// goIndexListBase always returns false because versions of Go before 1.18
+// do not parse type parameters.
+func goIndexListBase(expr ast.Expr) (ast.Expr, bool) {
+	return nil, false
+}
@@ -0,0 +1,15 @@
+//go:build go1.18
+// +build go1.18
+
+package gitdiff
+
+import "go/ast"
+
+// goIndexListBase returns the generic type of a receiver with two or more
+// type parameters, like "Pair[K, V]".
+func goIndexListBase(expr ast.Expr) (ast.Expr, bool) {
+	if e, ok := expr.(*ast.IndexListExpr); ok {
+		return e.X, true
+	}
+	return nil, false
+}
@@ -0,0 +1,40 @@
+//go:build go1.18
+// +build go1.18
+
+package gitdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGoSymbolsGeneric(t *testing.T) {
+	const src = `package example
+
+type Box[T any] struct{ v T }
+
+func (b *Box[T]) Get() T { return b.v }
+
+type Pair[K comparable, V any] struct {
+	k K
+	v V
+}
+
+func (p Pair[K, V]) Key() K { return p.k }
+`
+
+	symbols, err := GoSymbols("example.go", []byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error extracting symbols: %v", err)
+	}
+
+	expected := []Symbol{
+		{Name: "Box", Kind: "type", StartLine: 3, EndLine: 3},
+		{Name: "Box.Get", Kind: "method", StartLine: 5, EndLine: 5},
+		{Name: "Pair", Kind: "type", StartLine: 7, EndLine: 10},
+		{Name: "Pair.Key", Kind: "method", StartLine: 12, EndLine: 12},
+	}
+	if !reflect.DeepEqual(expected, symbols) {
+		t.Errorf("incorrect symbols\nexpected: %+v\n  actual: %+v", expected, symbols)
+	}
+}
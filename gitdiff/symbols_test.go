@@ -0,0 +1,162 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const symbolsTestSource = `package example
+
+import "fmt"
+
+// Limit is the maximum size.
+const Limit = 10
+
+var (
+	// count is the number of calls.
+	count int
+	name  = "example"
+)
+
+// Thing is a thing.
+type Thing struct {
+	Size int
+}
+
+// Grow makes the thing bigger.
+func (t *Thing) Grow() {
+	t.Size++
+	count++
+}
+
+func main() {
+	fmt.Println(name)
+}
+`
+
+func TestGoSymbols(t *testing.T) {
+	symbols, err := GoSymbols("example.go", []byte(symbolsTestSource))
+	if err != nil {
+		t.Fatalf("unexpected error extracting symbols: %v", err)
+	}
+
+	expected := []Symbol{
+		{Name: "Limit", Kind: "const", StartLine: 5, EndLine: 6},
+		{Name: "count", Kind: "var", StartLine: 9, EndLine: 10},
+		{Name: "name", Kind: "var", StartLine: 11, EndLine: 11},
+		{Name: "Thing", Kind: "type", StartLine: 14, EndLine: 17},
+		{Name: "Thing.Grow", Kind: "method", StartLine: 19, EndLine: 23},
+		{Name: "main", Kind: "func", StartLine: 25, EndLine: 27},
+	}
+	if !reflect.DeepEqual(expected, symbols) {
+		t.Errorf("incorrect symbols\nexpected: %+v\n  actual: %+v", expected, symbols)
+	}
+
+	if _, err := GoSymbols("invalid.go", []byte("package")); err == nil {
+		t.Error("expected error for invalid source, but got nil")
+	}
+}
+
+func TestChangedSymbols(t *testing.T) {
+	tests := map[string]struct {
+		Patch   string
+		Content string
+		Symbols []string
+	}{
+		"addedAndDeleted": {
+			Patch: `diff --git a/example.go b/example.go
+--- a/example.go
++++ b/example.go
+@@ -19,5 +19,5 @@ type Thing struct {
+ // Grow makes the thing bigger.
+ func (t *Thing) Grow() {
+-	t.Size += 1
++	t.Size++
+ 	count++
+ }
+@@ -24,5 +24,4 @@ func (t *Thing) Grow() {
+ 
+ func main() {
+ 	fmt.Println(name)
+-	fmt.Println(count)
+ }
+`,
+			Content: symbolsTestSource,
+			Symbols: []string{"Thing.Grow", "main"},
+		},
+		"documentation": {
+			Patch: `diff --git a/example.go b/example.go
+--- a/example.go
++++ b/example.go
+@@ -13,3 +13,3 @@ var (
+ 
+-// Thing is a type.
++// Thing is a thing.
+ type Thing struct {
+`,
+			Content: symbolsTestSource,
+			Symbols: []string{"Thing"},
+		},
+		"betweenDeclarations": {
+			Patch: `diff --git a/example.go b/example.go
+--- a/example.go
++++ b/example.go
+@@ -2,4 +2,3 @@
+ 
+ import "fmt"
+ 
+-
+ // Limit is the maximum size.
+`,
+			Content: symbolsTestSource,
+		},
+		"deletedDeclaration": {
+			Patch: `diff --git a/example.go b/example.go
+--- a/example.go
++++ b/example.go
+@@ -22,7 +22,4 @@ func (t *Thing) Grow() {
+ 	count++
+ }
+ 
+-func helper() {
+-}
+-
+ func main() {
+`,
+			Content: symbolsTestSource,
+		},
+		"unknownLanguage": {
+			Patch: `diff --git a/notes.unknownext b/notes.unknownext
+--- a/notes.unknownext
++++ b/notes.unknownext
+@@ -1 +1 @@
+-a
++b
+`,
+			Content: "b\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			symbols, err := ChangedSymbols(files[0], []byte(test.Content))
+			if err != nil {
+				t.Fatalf("unexpected error finding symbols: %v", err)
+			}
+
+			var names []string
+			for _, s := range symbols {
+				names = append(names, s.Name)
+			}
+			if !reflect.DeepEqual(test.Symbols, names) {
+				t.Errorf("incorrect symbols\nexpected: %q\n  actual: %q", test.Symbols, names)
+			}
+		})
+	}
+}
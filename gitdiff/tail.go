@@ -0,0 +1,167 @@
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// TailLineReaderAt is a LineReaderAt that can read lines counted from the end
+// of its input without indexing the lines before them. The Applier uses it
+// with the AnchorTail option to apply fragments at the end of large sources.
+//
+// ReadTailLines reads the last len(lines) lines of the input into lines. It
+// returns the number of lines read, which is less than len(lines) only if the
+// input has fewer lines, and the byte offset of the first line read.
+//
+// LineOffset returns the byte offset of the start of a zero-indexed line, or
+// the length of the input if line is the number of lines in the input.
+type TailLineReaderAt interface {
+	LineReaderAt
+	ReadTailLines(lines [][]byte) (n int, offset int64, err error)
+	LineOffset(line int64) (int64, error)
+}
+
+// readerSize returns the size of the data in r if r is a type with a known
+// size.
+func readerSize(r io.ReaderAt) (int64, bool) {
+	switch r := r.(type) {
+	case *bytes.Reader:
+		return r.Size(), true
+	case *strings.Reader:
+		return r.Size(), true
+	case *io.SectionReader:
+		return r.Size(), true
+	case *LineReader:
+		return r.Len(), true
+	case *IndexedLineReader:
+		return readerSize(r.r)
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		return info.Size(), true
+	}
+	return 0, false
+}
+
+// tailStart returns the offset of the first of the last n lines of data, and
+// the number of lines from there to the end, which is less than n if data
+// has fewer lines. The last line may not end in a newline.
+func tailStart(data []byte, n int) (offset int64, count int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+	for count < n {
+		i := bytes.LastIndexByte(data[:end], '\n')
+		count++
+		if i < 0 {
+			return 0, count
+		}
+		end = i
+	}
+	return int64(end + 1), count
+}
+
+// splitTailLines splits data into lines that keep their newlines, storing at most
+// len(lines) lines.
+func splitTailLines(lines [][]byte, data []byte) int {
+	n := 0
+	for n < len(lines) && len(data) > 0 {
+		i := bytes.IndexByte(data, '\n') + 1
+		if i == 0 {
+			i = len(data)
+		}
+		lines[n] = data[:i:i]
+		data = data[i:]
+		n++
+	}
+	return n
+}
+
+// ReadTailLines implements TailLineReaderAt.
+func (r *LineReader) ReadTailLines(lines [][]byte) (n int, offset int64, err error) {
+	offset, count := tailStart(r.data, len(lines))
+	n = splitTailLines(lines[:count], r.data[offset:])
+	return n, offset, nil
+}
+
+// LineOffset implements TailLineReaderAt.
+func (r *LineReader) LineOffset(line int64) (int64, error) {
+	if line < 0 {
+		return 0, errors.New("LineOffset: negative line")
+	}
+	if line == 0 {
+		return 0, nil
+	}
+	r.indexTo(line)
+	if line > int64(len(r.index)) {
+		return 0, io.EOF
+	}
+	return r.index[line-1], nil
+}
+
+// tailSupported returns true if ReadTailLines works for the source.
+func (r *lineReaderAt) tailSupported() bool {
+	_, ok := readerSize(r.r)
+	return ok
+}
+
+// ReadTailLines implements TailLineReaderAt if the size of the source is
+// known. It reads backward from the end of the source in blocks until it
+// finds the start of the lines.
+func (r *lineReaderAt) ReadTailLines(lines [][]byte) (n int, offset int64, err error) {
+	size, ok := readerSize(r.r)
+	if !ok {
+		return 0, 0, errors.New("ReadTailLines: unknown source size")
+	}
+
+	var data []byte
+	for start, blockSize := size, int64(indexBufferSize); ; blockSize *= 2 {
+		next := start - blockSize
+		if next < 0 {
+			next = 0
+		}
+		block := make([]byte, start-next)
+		if _, err := r.r.ReadAt(block, next); err != nil && err != io.EOF {
+			return 0, 0, err
+		}
+		data = append(block, data...)
+		start = next
+
+		// one more newline than lines is needed to find the start of the
+		// first line, unless the data reaches the start of the source
+		off, count := tailStart(data, len(lines))
+		if start == 0 || (count == len(lines) && off > 0) {
+			n = splitTailLines(lines[:count], data[off:])
+			return n, start + off, nil
+		}
+	}
+}
+
+// LineOffset implements TailLineReaderAt.
+func (r *lineReaderAt) LineOffset(line int64) (int64, error) {
+	if line < 0 {
+		return 0, errors.New("LineOffset: negative line")
+	}
+	if line == 0 {
+		return 0, nil
+	}
+	if line > int64(len(r.index)) && !r.eof {
+		if err := r.indexTo(line); err != nil {
+			return 0, err
+		}
+	}
+	if line > int64(len(r.index)) {
+		return 0, io.EOF
+	}
+	return r.index[line-1], nil
+}
@@ -0,0 +1,247 @@
+package gitdiff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadTailLines(t *testing.T) {
+	long := strings.Repeat("a fairly long line of text in the source\n", 200)
+
+	tests := map[string]struct {
+		Input  string
+		Count  int
+		Output []string
+		Offset int64
+	}{
+		"newline": {
+			Input:  "1\n2\n3\n",
+			Count:  2,
+			Output: []string{"2\n", "3\n"},
+			Offset: 2,
+		},
+		"noFinalNewline": {
+			Input:  "1\n2\n3",
+			Count:  2,
+			Output: []string{"2\n", "3"},
+			Offset: 2,
+		},
+		"allLines": {
+			Input:  "1\n2\n3\n",
+			Count:  3,
+			Output: []string{"1\n", "2\n", "3\n"},
+			Offset: 0,
+		},
+		"fewerLines": {
+			Input:  "1\n2\n",
+			Count:  4,
+			Output: []string{"1\n", "2\n"},
+			Offset: 0,
+		},
+		"emptyLines": {
+			Input:  "1\n\n\n",
+			Count:  2,
+			Output: []string{"\n", "\n"},
+			Offset: 2,
+		},
+		"empty": {
+			Input:  "",
+			Count:  2,
+			Output: []string{},
+			Offset: 0,
+		},
+		"manyBlocks": {
+			Input:  "first\n" + long + "last\n",
+			Count:  202,
+			Output: append(append([]string{"first\n"}, strings.SplitAfter(long, "\n")[:200]...), "last\n"),
+			Offset: 0,
+		},
+		"afterManyBlocks": {
+			Input:  "first\n" + long + "last\n",
+			Count:  201,
+			Output: append(strings.SplitAfter(long, "\n")[:200], "last\n"),
+			Offset: 6,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			readers := map[string]TailLineReaderAt{
+				"lineReaderAt": &lineReaderAt{r: strings.NewReader(test.Input)},
+				"LineReader":   NewLineReaderFromString(test.Input),
+			}
+			for rname, r := range readers {
+				lines := make([][]byte, test.Count)
+				n, offset, err := r.ReadTailLines(lines)
+				if err != nil {
+					t.Fatalf("%s: unexpected error reading lines: %v", rname, err)
+				}
+				if offset != test.Offset {
+					t.Errorf("%s: incorrect offset: expected %d, actual %d", rname, test.Offset, offset)
+				}
+				if n != len(test.Output) {
+					t.Fatalf("%s: incorrect number of lines: expected %d, actual %d", rname, len(test.Output), n)
+				}
+				for i, line := range lines[:n] {
+					if string(line) != test.Output[i] {
+						t.Errorf("%s: incorrect line %d: expected %q, actual %q", rname, i, test.Output[i], line)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReadTailLinesUnknownSize(t *testing.T) {
+	r := &lineReaderAt{r: io.NewSectionReader(strings.NewReader("a\n"), 0, 2)}
+	if _, _, err := r.ReadTailLines(make([][]byte, 1)); err != nil {
+		t.Fatalf("unexpected error with sized reader: %v", err)
+	}
+
+	r = &lineReaderAt{r: unsizedReaderAt{strings.NewReader("a\n")}}
+	if _, _, err := r.ReadTailLines(make([][]byte, 1)); err == nil {
+		t.Fatal("expected error with unsized reader, but got nil")
+	}
+}
+
+func TestLineOffset(t *testing.T) {
+	input := "1\n22\n333"
+	readers := map[string]TailLineReaderAt{
+		"lineReaderAt": &lineReaderAt{r: strings.NewReader(input)},
+		"LineReader":   NewLineReaderFromString(input),
+	}
+	for rname, r := range readers {
+		for line, expected := range []int64{0, 2, 5, 8} {
+			offset, err := r.LineOffset(int64(line))
+			if err != nil {
+				t.Fatalf("%s: unexpected error for line %d: %v", rname, line, err)
+			}
+			if offset != expected {
+				t.Errorf("%s: incorrect offset for line %d: expected %d, actual %d", rname, line, expected, offset)
+			}
+		}
+		if _, err := r.LineOffset(4); err != io.EOF {
+			t.Errorf("%s: expected EOF after the last line, but got %v", rname, err)
+		}
+	}
+}
+
+type unsizedReaderAt struct {
+	r io.ReaderAt
+}
+
+func (r unsizedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.r.ReadAt(p, off)
+}
+
+func TestApplyAnchorTail(t *testing.T) {
+	var src bytes.Buffer
+	for i := 1; i <= 50000; i++ {
+		fmt.Fprintf(&src, "line %d\n", i)
+	}
+
+	tail := `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -49998,3 +49998,3 @@
+ line 49998
+ line 49999
+-line 50000
++last line
+`
+	expected := strings.Replace(src.String(), "line 50000\n", "last line\n", 1)
+
+	tests := map[string]struct {
+		Patch   string
+		Src     func() io.ReaderAt
+		Output  string
+		Indexed bool
+		Err     interface{}
+	}{
+		"anchored": {
+			Patch:  tail,
+			Src:    func() io.ReaderAt { return bytes.NewReader(src.Bytes()) },
+			Output: expected,
+		},
+		"lineReader": {
+			Patch:  tail,
+			Src:    func() io.ReaderAt { return NewLineReaderFromBytes(src.Bytes()) },
+			Output: expected,
+		},
+		"afterFragment": {
+			Patch: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+-line 1
++first line
+ line 2
+ line 3
+@@ -49998,3 +49998,3 @@
+ line 49998
+ line 49999
+-line 50000
++last line
+`,
+			Src:    func() io.ReaderAt { return bytes.NewReader(src.Bytes()) },
+			Output: strings.Replace(expected, "line 1\n", "first line\n", 1),
+		},
+		"unsized": {
+			Patch:   tail,
+			Src:     func() io.ReaderAt { return unsizedReaderAt{bytes.NewReader(src.Bytes())} },
+			Output:  expected,
+			Indexed: true,
+		},
+		"endDoesNotMatch": {
+			Patch:   strings.Replace(tail, "-line 50000", "-line 12345", 1),
+			Src:     func() io.ReaderAt { return bytes.NewReader(src.Bytes()) },
+			Indexed: true,
+			Err:     &Conflict{},
+		},
+		"fragmentAfterTail": {
+			Patch: tail + `@@ -50000 +50000 @@
+-line 50000
++other
+`,
+			Src: func() io.ReaderAt { return bytes.NewReader(src.Bytes()) },
+			Err: "overlaps",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			var dst bytes.Buffer
+			a := NewApplierWithOptions(test.Src(), ApplyOptions{AnchorTail: true})
+			err = a.ApplyFile(&dst, files[0])
+			if test.Err != nil {
+				assertError(t, test.Err, err, "applying fragment")
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error applying fragment: %v", err)
+			}
+			if dst.String() != test.Output {
+				t.Errorf("incorrect output: expected %d bytes, actual %d bytes", len(test.Output), dst.Len())
+			}
+
+			var indexed int
+			switch r := a.lineSrc.(type) {
+			case *lineReaderAt:
+				indexed = len(r.index)
+			case *LineReader:
+				indexed = len(r.index)
+			}
+			if isIndexed := indexed > 1000; isIndexed != test.Indexed {
+				t.Errorf("incorrect indexing: expected %t, actual %d lines indexed", test.Indexed, indexed)
+			}
+		})
+	}
+}
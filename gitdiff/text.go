@@ -3,6 +3,7 @@ package gitdiff
 import (
 	"fmt"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -12,6 +13,7 @@ import (
 // of fragments that were added.
 func (p *parser) ParseTextFragments(f *File) (n int, err error) {
 	for {
+		start := p.offset
 		frag, err := p.ParseTextFragmentHeader()
 		if err != nil {
 			return n, err
@@ -30,6 +32,9 @@ func (p *parser) ParseTextFragments(f *File) (n int, err error) {
 		if err := p.ParseTextChunk(frag); err != nil {
 			return n, err
 		}
+		if p.onFragment != nil {
+			p.onFragment([]*TextFragment{frag}, start, p.offset)
+		}
 
 		f.TextFragments = append(f.TextFragments, frag)
 		n++
@@ -42,11 +47,18 @@ func (p *parser) ParseTextFragmentHeader() (*TextFragment, error) {
 		endMark   = " @@"
 	)
 
-	if !strings.HasPrefix(p.Line(0), startMark) {
+	line := p.Line(0)
+	if p.lenientFragmentHeaders {
+		if m := lenientFragmentHeaderRegexp.FindStringSubmatch(line); m != nil {
+			line = startMark + m[1] + " +" + m[2] + endMark + m[3]
+		}
+	}
+
+	if !strings.HasPrefix(line, startMark) {
 		return nil, nil
 	}
 
-	parts := strings.SplitAfterN(p.Line(0), endMark, 2)
+	parts := strings.SplitAfterN(line, endMark, 2)
 	if len(parts) < 2 {
 		return nil, p.Errorf(0, "invalid fragment header")
 	}
@@ -74,19 +86,37 @@ func (p *parser) ParseTextFragmentHeader() (*TextFragment, error) {
 	return f, nil
 }
 
+// lenientFragmentHeaderRegexp matches text fragment headers with any amount of
+// whitespace between the fields.
+var lenientFragmentHeaderRegexp = regexp.MustCompile(`^@@[ \t]+-([0-9,]+)[ \t]+\+([0-9,]+)[ \t]+@@(.*\n?)$`)
+
 func (p *parser) ParseTextChunk(frag *TextFragment) error {
 	if p.Line(0) == "" {
-		return p.Errorf(0, "no content following fragment header")
+		return p.truncatedFragment(frag, frag.OldLines, frag.NewLines)
 	}
 
-	oldLines, newLines := frag.OldLines, frag.NewLines
+	oldTotal, newTotal := frag.OldLines, frag.NewLines
+	if p.recount {
+		// count down from a number that is never reached and compute the
+		// real counts when the fragment ends
+		const unlimited = 1 << 62
+		oldTotal, newTotal = unlimited, unlimited
+	}
+
+	oldLines, newLines := oldTotal, newTotal
 	for oldLines > 0 || newLines > 0 {
 		line := p.Line(0)
-		op, data := line[0], line[1:]
+		if p.recount && p.endsRecountedFragment(line) {
+			break
+		}
+		op, data := line[0], p.content(line[1:])
 
 		switch op {
 		case '\n':
-			data = "\n"
+			if p.rejectEmptyContext {
+				return p.Errorf(0, "empty context line")
+			}
+			data = p.content("\n")
 			fallthrough // newer GNU diff versions create empty context lines
 		case ' ':
 			oldLines--
@@ -96,17 +126,20 @@ func (p *parser) ParseTextChunk(frag *TextFragment) error {
 			} else {
 				frag.TrailingContext++
 			}
-			frag.Lines = append(frag.Lines, Line{OpContext, data})
+			p.appendLine(frag, OpContext, data)
 		case '-':
 			oldLines--
 			frag.LinesDeleted++
 			frag.TrailingContext = 0
-			frag.Lines = append(frag.Lines, Line{OpDelete, data})
+			p.appendLine(frag, OpDelete, data)
 		case '+':
+			if p.onAdd != nil {
+				p.onAdd(frag.NewPosition+newTotal-newLines, p.addedLine(data))
+			}
 			newLines--
 			frag.LinesAdded++
 			frag.TrailingContext = 0
-			frag.Lines = append(frag.Lines, Line{OpAdd, data})
+			p.appendLine(frag, OpAdd, data)
 		case '\\':
 			// this may appear in middle of fragment if it's for a deleted line
 			if isNoNewlineMarker(line) {
@@ -131,6 +164,14 @@ func (p *parser) ParseTextChunk(frag *TextFragment) error {
 		}
 	}
 
+	if p.recount {
+		frag.OldLines, frag.NewLines = oldTotal-oldLines, newTotal-newLines
+		oldLines, newLines = 0, 0
+	}
+
+	if p.eof && (oldLines > 0 || newLines > 0) {
+		return p.truncatedFragment(frag, oldLines, newLines)
+	}
 	if oldLines != 0 || newLines != 0 {
 		hdr := max(frag.OldLines-oldLines, frag.NewLines-newLines) + 1
 		return p.Errorf(-hdr, "fragment header miscounts lines: %+d old, %+d new", -oldLines, -newLines)
@@ -151,6 +192,34 @@ func (p *parser) ParseTextChunk(frag *TextFragment) error {
 	return nil
 }
 
+// endsRecountedFragment returns true if line is not part of a fragment when
+// recounting lines. Like git apply --recount, any line that does not start
+// with a content line operation ends the fragment, as does the start of a
+// traditional file header.
+func (p *parser) endsRecountedFragment(line string) bool {
+	if line == "" || !strings.ContainsRune(" -+\\\n", rune(line[0])) {
+		return true
+	}
+	return strings.HasPrefix(line, "--- ") && strings.HasPrefix(p.Line(1), "+++ ")
+}
+
+// appendLine adds a line to frag, unless the parser discards the lines of
+// fragments to parse with less memory.
+func (p *parser) appendLine(frag *TextFragment, op LineOp, data string) {
+	if !p.discardLines {
+		frag.Lines = append(frag.Lines, Line{op, data})
+	}
+}
+
+// addedLine returns the content of the added line data for onAdd, without the
+// newline if the next line is a "no newline" marker.
+func (p *parser) addedLine(data string) string {
+	if isNoNewlineMarker(p.Line(1)) {
+		return strings.TrimSuffix(data, "\n")
+	}
+	return data
+}
+
 func isNoNewlineMarker(s string) bool {
 	// test for "\ No newline at end of file" by prefix because the text
 	// changes by locale (git claims all versions are at least 12 chars)
@@ -360,6 +360,27 @@ func TestParseTextChunk(t *testing.T) {
 	}
 }
 
+func TestParseTextChunkDiscardLines(t *testing.T) {
+	p := newTestParser("-old\n+one\n+two\n\\ No newline at end of file\n", true)
+	p.discardLines = true
+
+	var added []string
+	p.onAdd = func(lineno int64, line string) {
+		added = append(added, line)
+	}
+
+	frag := TextFragment{OldPosition: 1, OldLines: 1, NewPosition: 1, NewLines: 2}
+	if err := p.ParseTextChunk(&frag); err != nil {
+		t.Fatalf("unexpected error parsing text chunk: %v", err)
+	}
+	if frag.Lines != nil || frag.LinesAdded != 2 || frag.LinesDeleted != 1 {
+		t.Errorf("incorrect fragment: %+v", frag)
+	}
+	if expected := []string{"one\n", "two"}; !reflect.DeepEqual(expected, added) {
+		t.Errorf("incorrect added lines\nexpected: %q\n  actual: %q", expected, added)
+	}
+}
+
 func TestParseTextFragments(t *testing.T) {
 	tests := map[string]struct {
 		Input string
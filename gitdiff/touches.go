@@ -0,0 +1,83 @@
+package gitdiff
+
+// FileRange is a range of lines in a file, such as a function from coverage
+// data or a block with an owner.
+type FileRange struct {
+	// Path is the slash-separated name of the file.
+	Path string
+
+	LineRange
+}
+
+// Touches returns true if the patch in files modifies any of the lines in r
+// of the file at path. Line numbers are in the version of the file before the
+// patch. A range is modified if the patch deletes one of its lines or adds
+// lines between two of its lines. Adding lines before the first or after the
+// last line of a range does not modify it. Every range of a deleted file or
+// a file with binary changes is modified.
+func Touches(files []*File, path string, r LineRange) bool {
+	for _, f := range files {
+		if f.OldName == path && !f.IsNew && fileTouches(f, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// TouchedRanges returns the ranges that are modified by the patch in files,
+// as defined by Touches, in the order they appear in ranges. It can be used
+// for test impact analysis, to find the tests that cover the changed code.
+func TouchedRanges(files []*File, ranges []FileRange) []FileRange {
+	byName := make(map[string][]*File)
+	for _, f := range files {
+		if !f.IsNew {
+			byName[f.OldName] = append(byName[f.OldName], f)
+		}
+	}
+
+	var touched []FileRange
+	for _, r := range ranges {
+		for _, f := range byName[r.Path] {
+			if fileTouches(f, r.LineRange) {
+				touched = append(touched, r)
+				break
+			}
+		}
+	}
+	return touched
+}
+
+func fileTouches(f *File, r LineRange) bool {
+	if r.Lines <= 0 {
+		return false
+	}
+	if f.IsDelete || f.IsBinary {
+		return true
+	}
+
+	first, last := r.Position, r.Position+r.Lines-1
+	for _, frag := range f.TextFragments {
+		if fragmentOldStart(frag)+frag.OldLines < first || fragmentOldStart(frag) > last {
+			continue
+		}
+
+		// next is the number of the next line in the old file
+		next := fragmentOldStart(frag) + 1
+		for _, line := range frag.Lines {
+			switch line.Op {
+			case OpAdd:
+				if first < next && next <= last {
+					return true
+				}
+			case OpDelete:
+				if first <= next && next <= last {
+					return true
+				}
+				next++
+			default:
+				next++
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package gitdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const touchesPatch = `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -2,3 +2,3 @@
+ line 2
+-line 3
++line three
+ line 4
+@@ -8,2 +8,3 @@
+ line 8
++inserted
+ line 9
+diff --git a/removed.txt b/removed.txt
+deleted file mode 100644
+--- a/removed.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-removed
+diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++new
+`
+
+func TestTouches(t *testing.T) {
+	files, _, err := ParseAll(strings.NewReader(touchesPatch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	tests := map[string]struct {
+		Path   string
+		Range  LineRange
+		Output bool
+	}{
+		"deletedLine":     {Path: "file.txt", Range: LineRange{Position: 3, Lines: 1}, Output: true},
+		"containsDeleted": {Path: "file.txt", Range: LineRange{Position: 1, Lines: 5}, Output: true},
+		"contextOnly":     {Path: "file.txt", Range: LineRange{Position: 4, Lines: 2}, Output: false},
+		"beforeFragment":  {Path: "file.txt", Range: LineRange{Position: 1, Lines: 1}, Output: false},
+		"insertInside":    {Path: "file.txt", Range: LineRange{Position: 8, Lines: 2}, Output: true},
+		"insertAfter":     {Path: "file.txt", Range: LineRange{Position: 7, Lines: 2}, Output: false},
+		"insertBefore":    {Path: "file.txt", Range: LineRange{Position: 9, Lines: 3}, Output: false},
+		"empty":           {Path: "file.txt", Range: LineRange{Position: 3, Lines: 0}, Output: false},
+		"deletedFile":     {Path: "removed.txt", Range: LineRange{Position: 10, Lines: 1}, Output: true},
+		"newFile":         {Path: "new.txt", Range: LineRange{Position: 1, Lines: 1}, Output: false},
+		"otherFile":       {Path: "other.txt", Range: LineRange{Position: 1, Lines: 100}, Output: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if touched := Touches(files, test.Path, test.Range); touched != test.Output {
+				t.Errorf("incorrect result: expected %t, actual %t", test.Output, touched)
+			}
+		})
+	}
+}
+
+func TestTouchedRanges(t *testing.T) {
+	files, _, err := ParseAll(strings.NewReader(touchesPatch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	ranges := []FileRange{
+		{Path: "file.txt", LineRange: LineRange{Position: 1, Lines: 2}},
+		{Path: "file.txt", LineRange: LineRange{Position: 3, Lines: 1}},
+		{Path: "removed.txt", LineRange: LineRange{Position: 1, Lines: 1}},
+		{Path: "file.txt", LineRange: LineRange{Position: 8, Lines: 2}},
+		{Path: "other.txt", LineRange: LineRange{Position: 1, Lines: 1}},
+	}
+	expected := []FileRange{ranges[1], ranges[2], ranges[3]}
+
+	if touched := TouchedRanges(files, ranges); !reflect.DeepEqual(expected, touched) {
+		t.Errorf("incorrect ranges\nexpected: %+v\n  actual: %+v", expected, touched)
+	}
+}
@@ -0,0 +1,189 @@
+package gitdiff
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+const zeroOID = "0000000000000000000000000000000000000000"
+
+// treeEntry is a regular file read from a tree.
+type treeEntry struct {
+	mode os.FileMode
+	data []byte
+}
+
+// DiffTrees compares the regular files in two file systems and returns the
+// changes that transform oldFS into newFS, sorted by name, as if the trees
+// were compared by git diff. Either file system may be nil to represent an
+// empty tree.
+//
+// Files are marked as binary using the same test as Git. Modes are limited to
+// the regular and executable file modes Git records and OIDs are the full
+// hashes of the file content as Git blobs. Directories only matter for the
+// files they contain; symbolic links and other special files are ignored.
+func DiffTrees(oldFS, newFS fs.FS, opts DiffOptions) ([]*File, error) {
+	oldTree, err := readTree(oldFS)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := readTree(newFS)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldTree)+len(newTree))
+	for name := range oldTree {
+		names = append(names, name)
+	}
+	for name := range newTree {
+		if _, ok := oldTree[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var files []*File
+	for _, name := range names {
+		if f := diffTreeEntries(name, oldTree[name], newTree[name], opts); f != nil {
+			files = append(files, f)
+		}
+	}
+
+	if opts.DetectRenames {
+		files = detectRenames(files, opts.renameThreshold(), opts)
+	}
+	if !opts.Binary {
+		for _, f := range files {
+			f.BinaryFragment = nil
+			f.ReverseBinaryFragment = nil
+		}
+	}
+	return files, nil
+}
+
+// readTree reads the content and mode of every regular file in fsys.
+func readTree(fsys fs.FS) (map[string]*treeEntry, error) {
+	tree := make(map[string]*treeEntry)
+	if fsys == nil {
+		return tree, nil
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		mode := os.FileMode(0100644)
+		if info.Mode()&0111 != 0 {
+			mode = os.FileMode(0100755)
+		}
+		tree[name] = &treeEntry{mode: mode, data: data}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gitdiff: reading tree: %v", err)
+	}
+	return tree, nil
+}
+
+// diffTreeEntries returns the changes between two versions of a file, either
+// of which may be nil. It returns nil if the versions are the same.
+func diffTreeEntries(name string, old, new *treeEntry, opts DiffOptions) *File {
+	attrs := opts.Attributes.Lookup(name)
+
+	var oldData, newData []byte
+	if old != nil {
+		oldData = old.data
+		if attrs.normalizesEOL(oldData) {
+			oldData = toLF(oldData)
+		}
+	}
+	if new != nil {
+		newData = new.data
+		if attrs.normalizesEOL(newData) {
+			newData = toLF(newData)
+		}
+	}
+
+	f := &File{OldOIDPrefix: zeroOID, NewOIDPrefix: zeroOID}
+	switch {
+	case old == nil:
+		f.IsNew = true
+		f.NewName, f.NewMode = name, new.mode
+	case new == nil:
+		f.IsDelete = true
+		f.OldName, f.OldMode = name, old.mode
+	default:
+		if old.mode == new.mode && bytes.Equal(oldData, newData) {
+			return nil
+		}
+		f.OldName, f.NewName, f.OldMode = name, name, old.mode
+		if new.mode != old.mode {
+			f.NewMode = new.mode
+		}
+	}
+	if old != nil {
+		f.OldOIDPrefix = blobOID(oldData)
+	}
+	if new != nil {
+		f.NewOIDPrefix = blobOID(newData)
+	}
+
+	driver, hasDriver := DiffDriver{}, false
+	var funcName *FuncNamePattern
+	if v, ok := attrs.Value("diff"); ok {
+		driver, hasDriver = opts.Drivers[v]
+		if hasDriver && driver.FuncName != nil {
+			funcName = funcNameFromRegexp(driver.FuncName)
+		} else if lang, ok := LookupLanguage(v); ok && !hasDriver {
+			funcName = lang.FuncName
+		}
+	}
+
+	switch {
+	case attrs.IsUnset("diff") || driver.Binary:
+		f.IsBinary = true
+	case attrs.IsSet("diff") || hasDriver:
+	default:
+		f.IsBinary = isBinary(oldData) || isBinary(newData)
+	}
+
+	if f.IsBinary {
+		if !bytes.Equal(oldData, newData) {
+			f.BinaryFragment, f.ReverseBinaryFragment = DiffBinary(oldData, newData)
+		}
+		return f
+	}
+
+	f.TextFragments = opts.textFragments(oldData, newData)
+	if funcName != nil {
+		setFuncNames(f.TextFragments, splitLines(oldData), funcName)
+	}
+	return f
+}
+
+// blobOID returns the hash of data as a Git blob.
+func blobOID(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	_, _ = h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
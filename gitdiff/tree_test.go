@@ -0,0 +1,151 @@
+package gitdiff
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDiffTrees(t *testing.T) {
+	oldFS := fstest.MapFS{
+		"README":          {Data: []byte("hello\n")},
+		"dir/modified.go": {Data: []byte("package dir\n\nvar x = 1\n")},
+		"dir/deleted.txt": {Data: []byte("gone\n")},
+		"dir/script.sh":   {Data: []byte("#!/bin/sh\n"), Mode: 0644},
+		"image.bin":       {Data: []byte{0, 1, 2, 3}},
+		"moved/a.txt":     {Data: []byte("line 1\nline 2\nline 3\nline 4\n")},
+	}
+	newFS := fstest.MapFS{
+		"README":          {Data: []byte("hello\n")},
+		"dir/modified.go": {Data: []byte("package dir\n\nvar x = 2\n")},
+		"dir/script.sh":   {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"dir/created.txt": {Data: []byte("new\n")},
+		"image.bin":       {Data: []byte{0, 1, 2, 4}},
+		"renamed/a.txt":   {Data: []byte("line 1\nline 2\nline 3\nline four\n")},
+	}
+
+	t.Run("default", func(t *testing.T) {
+		files, err := DiffTrees(oldFS, newFS, DiffOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error diffing trees: %v", err)
+		}
+
+		expected := []string{
+			"A dir/created.txt",
+			"D dir/deleted.txt",
+			"M dir/modified.go",
+			"M dir/script.sh",
+			"M image.bin",
+			"D moved/a.txt",
+			"A renamed/a.txt",
+		}
+		assertTreeFiles(t, files, expected)
+
+		mode := files[3]
+		if mode.OldMode != os.FileMode(0100644) || mode.NewMode != os.FileMode(0100755) || len(mode.TextFragments) > 0 {
+			t.Errorf("incorrect mode change: %+v", mode)
+		}
+
+		bin := files[4]
+		if !bin.IsBinary || bin.BinaryFragment != nil {
+			t.Errorf("incorrect binary file: %+v", bin)
+		}
+
+		created := files[0]
+		if created.NewOIDPrefix != "3e757656cf36eca53338e520d134963a44f793f8" || created.OldOIDPrefix != zeroOID {
+			t.Errorf("incorrect OIDs for created file: %s..%s", created.OldOIDPrefix, created.NewOIDPrefix)
+		}
+
+		assertTreeApplies(t, oldFS, newFS, files)
+	})
+
+	t.Run("renamesAndBinary", func(t *testing.T) {
+		files, err := DiffTrees(oldFS, newFS, DiffOptions{DetectRenames: true, Binary: true, Context: -1})
+		if err != nil {
+			t.Fatalf("unexpected error diffing trees: %v", err)
+		}
+
+		expected := []string{
+			"A dir/created.txt",
+			"D dir/deleted.txt",
+			"M dir/modified.go",
+			"M dir/script.sh",
+			"M image.bin",
+			"R moved/a.txt renamed/a.txt",
+		}
+		assertTreeFiles(t, files, expected)
+
+		rename := files[5]
+		if rename.Score != 67 {
+			t.Errorf("incorrect rename score: expected 67, actual %d", rename.Score)
+		}
+		if len(rename.TextFragments) != 1 || rename.TextFragments[0].LeadingContext != 0 {
+			t.Errorf("incorrect fragments for rename without context: %+v", rename.TextFragments)
+		}
+
+		bin := files[4]
+		if bin.BinaryFragment == nil || !bytes.Equal(bin.BinaryFragment.Data, []byte{0, 1, 2, 4}) {
+			t.Errorf("incorrect binary fragment: %+v", bin.BinaryFragment)
+		}
+
+		assertTreeApplies(t, oldFS, newFS, files)
+	})
+
+	t.Run("emptyTree", func(t *testing.T) {
+		files, err := DiffTrees(nil, newFS, DiffOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error diffing trees: %v", err)
+		}
+		if len(files) != len(newFS) {
+			t.Fatalf("incorrect number of files: expected %d, actual %d", len(newFS), len(files))
+		}
+		for _, f := range files {
+			if !f.IsNew {
+				t.Errorf("file %s is not new", f.NewName)
+			}
+		}
+	})
+}
+
+func assertTreeFiles(t *testing.T, files []*File, expected []string) {
+	t.Helper()
+
+	var actual []string
+	for _, f := range files {
+		e := NewRawEntry(f)
+		s := string(e.Status) + " " + e.OldName
+		if e.NewName != e.OldName {
+			s += " " + e.NewName
+		}
+		actual = append(actual, s)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("incorrect files\nexpected: %q\n  actual: %q", expected, actual)
+	}
+}
+
+func assertTreeApplies(t *testing.T, oldFS, newFS fstest.MapFS, files []*File) {
+	t.Helper()
+
+	for _, f := range files {
+		if f.IsDelete || (f.IsBinary && f.BinaryFragment == nil) {
+			continue
+		}
+
+		var src []byte
+		if !f.IsNew {
+			src = oldFS[f.OldName].Data
+		}
+
+		var dst bytes.Buffer
+		if err := Apply(&dst, bytes.NewReader(src), f); err != nil {
+			t.Errorf("%s: unexpected error applying changes: %v", f.NewName, err)
+			continue
+		}
+		if !bytes.Equal(dst.Bytes(), newFS[f.NewName].Data) {
+			t.Errorf("%s: incorrect content after apply\nexpected: %q\n  actual: %q", f.NewName, newFS[f.NewName].Data, dst.Bytes())
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package gitdiff
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DirTree is an ApplyTarget for the files in the directory Dir, so BuildTree
+// can apply patches directly to a working tree. Paths are checked with
+// ValidatePath, but DirTree does not check for symbolic links that resolve to
+// locations outside of Dir; use ApplyTree to apply untrusted patches to a
+// directory.
+type DirTree struct {
+	Dir string
+}
+
+func (t DirTree) path(name string) (string, error) {
+	if err := ValidatePath(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(t.Dir, filepath.FromSlash(name)), nil
+}
+
+// ReadBlob returns the content and mode of the file at path. Like Git, it
+// uses the target of a symbolic link as its content.
+func (t DirTree) ReadBlob(path string) ([]byte, os.FileMode, error) {
+	p, err := t.path(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := os.Lstat(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := readTreeFile(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, gitMode(info.Mode()), nil
+}
+
+// WriteBlob writes the file at path, creating its parent directories if they
+// do not exist. If the file is a symbolic link, the link is replaced.
+func (t DirTree) WriteBlob(path string, data []byte) error {
+	p, err := t.path(path)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p, data, 0644)
+}
+
+// SetMode sets the permissions of the file at path. If mode is 0120000, the
+// file is replaced by a symbolic link with the content of the file as its
+// target.
+func (t DirTree) SetMode(path string, mode os.FileMode) error {
+	p, err := t.path(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+
+	isLink := info.Mode()&os.ModeSymlink != 0
+	switch {
+	case mode == 0120000 && !isLink:
+		target, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+		return os.Symlink(filepath.FromSlash(string(target)), p)
+
+	case mode != 0120000 && isLink:
+		target, err := readTreeFile(p)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(p, target, mode.Perm())
+
+	case isLink:
+		return nil
+	}
+	return os.Chmod(p, mode.Perm())
+}
+
+// DeletePath removes the file at path.
+func (t DirTree) DeletePath(path string) error {
+	p, err := t.path(path)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// RenamePath moves the file at oldPath to newPath, creating the parent
+// directories of newPath if they do not exist.
+func (t DirTree) RenamePath(oldPath, newPath string) error {
+	oldP, err := t.path(oldPath)
+	if err != nil {
+		return err
+	}
+	newP, err := t.path(newPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(newP), 0755); err != nil {
+		return err
+	}
+	return os.Rename(oldP, newP)
+}
+
+// gitMode returns the Git mode for a file with the mode m.
+func gitMode(m os.FileMode) os.FileMode {
+	switch {
+	case m&os.ModeSymlink != 0:
+		return 0120000
+	case m.Perm()&0111 != 0:
+		return 0100755
+	}
+	return 0100644
+}
+
+// OverlayTree is an ApplyTarget that keeps changes in memory on top of a
+// read-only base, such as an fs.FS or a remote repository. Files are read
+// from the base the first time they are used and cached, so each file is
+// fetched at most once. Use Changed to find the files modified by BuildTree
+// and ReadBlob to get their new content.
+type OverlayTree struct {
+	read    func(path string) ([]byte, os.FileMode, error)
+	entries map[string]*overlayEntry
+}
+
+type overlayEntry struct {
+	data    []byte
+	mode    os.FileMode
+	deleted bool
+	changed bool
+}
+
+// NewOverlayTree returns an OverlayTree that reads files with read, which
+// returns the content and Git mode of a file in the base, or an error
+// matching fs.ErrNotExist if the file does not exist. For example, read could
+// fetch blobs from the API of a hosting service. If read is nil, the base is
+// empty.
+func NewOverlayTree(read func(path string) ([]byte, os.FileMode, error)) *OverlayTree {
+	return &OverlayTree{read: read, entries: make(map[string]*overlayEntry)}
+}
+
+// NewFSTree returns an OverlayTree with the files in fsys as its base.
+func NewFSTree(fsys fs.FS) *OverlayTree {
+	return NewOverlayTree(func(path string) ([]byte, os.FileMode, error) {
+		info, err := fs.Stat(fsys, path)
+		if err != nil {
+			return nil, 0, err
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, 0, err
+		}
+		return data, gitMode(info.Mode()), nil
+	})
+}
+
+func (t *OverlayTree) entry(path string) (*overlayEntry, error) {
+	if e, ok := t.entries[path]; ok {
+		if e.deleted {
+			return nil, &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
+		}
+		return e, nil
+	}
+	if t.read == nil {
+		return nil, &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
+	}
+
+	data, mode, err := t.read(path)
+	if err != nil {
+		return nil, err
+	}
+	e := &overlayEntry{data: data, mode: mode}
+	t.entries[path] = e
+	return e, nil
+}
+
+// ReadBlob returns the content and mode of the file at path, from the
+// changes in the overlay if the file was changed and from the base
+// otherwise.
+func (t *OverlayTree) ReadBlob(path string) ([]byte, os.FileMode, error) {
+	e, err := t.entry(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return e.data, e.mode, nil
+}
+
+// WriteBlob sets the content of the file at path in the overlay.
+func (t *OverlayTree) WriteBlob(path string, data []byte) error {
+	e, ok := t.entries[path]
+	if !ok || e.deleted {
+		e = &overlayEntry{}
+		t.entries[path] = e
+	}
+	e.data = append([]byte(nil), data...)
+	e.changed = true
+	return nil
+}
+
+// SetMode sets the mode of the file at path in the overlay.
+func (t *OverlayTree) SetMode(path string, mode os.FileMode) error {
+	e, err := t.entry(path)
+	if err != nil {
+		return err
+	}
+	e.mode = mode
+	e.changed = true
+	return nil
+}
+
+// DeletePath removes the file at path from the overlay.
+func (t *OverlayTree) DeletePath(path string) error {
+	t.entries[path] = &overlayEntry{deleted: true, changed: true}
+	return nil
+}
+
+// RenamePath moves the file at oldPath to newPath in the overlay.
+func (t *OverlayTree) RenamePath(oldPath, newPath string) error {
+	e, err := t.entry(oldPath)
+	if err != nil {
+		return err
+	}
+	t.entries[newPath] = &overlayEntry{data: e.data, mode: e.mode, changed: true}
+	t.entries[oldPath] = &overlayEntry{deleted: true, changed: true}
+	return nil
+}
+
+// Changed returns the paths of the files that were written, deleted, or had
+// their mode changed, in sorted order. ReadBlob returns an error matching
+// fs.ErrNotExist for deleted files.
+func (t *OverlayTree) Changed() []string {
+	var paths []string
+	for path, e := range t.entries {
+		if e.changed {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// IsDeleted returns true if the file at path was deleted from the overlay.
+func (t *OverlayTree) IsDeleted(path string) bool {
+	e, ok := t.entries[path]
+	return ok && e.deleted
+}
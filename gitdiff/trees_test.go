@@ -0,0 +1,178 @@
+package gitdiff
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const treesPatch = `diff --git a/file.txt b/file.txt
+index 1111111..2222222 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@
+ line 1
+-line 2
++line two
+diff --git a/old.txt b/dir/new.txt
+similarity index 100%
+rename from old.txt
+rename to dir/new.txt
+diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+diff --git a/removed.txt b/removed.txt
+deleted file mode 100644
+index 4444444..0000000
+--- a/removed.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-removed
+`
+
+func parseTreesPatch(t *testing.T) []*File {
+	files, _, err := ParseAll(strings.NewReader(treesPatch))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+	return files
+}
+
+func TestDirTree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitdiff-dirtree")
+	if err != nil {
+		t.Fatalf("unexpected error creating directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, data := range map[string]string{
+		"file.txt":    "line 1\nline 2\n",
+		"old.txt":     "old\n",
+		"script.sh":   "#!/bin/sh\n",
+		"removed.txt": "removed\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("unexpected error writing file: %v", err)
+		}
+	}
+
+	tree := DirTree{Dir: dir}
+	if err := BuildTree(parseTreesPatch(t), tree); err != nil {
+		t.Fatalf("unexpected error building tree: %v", err)
+	}
+
+	expected := map[string]string{
+		"file.txt":    "line 1\nline two\n",
+		"dir/new.txt": "old\n",
+		"script.sh":   "#!/bin/sh\n",
+	}
+	for name, data := range expected {
+		b, _, err := tree.ReadBlob(name)
+		if err != nil {
+			t.Fatalf("unexpected error reading %s: %v", name, err)
+		}
+		if string(b) != data {
+			t.Errorf("incorrect content of %s\nexpected: %q\n  actual: %q", name, data, b)
+		}
+	}
+	for _, name := range []string{"old.txt", "removed.txt"} {
+		if _, _, err := tree.ReadBlob(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected %s to be removed, but got error: %v", name, err)
+		}
+	}
+
+	if chmodSupported {
+		if _, mode, _ := tree.ReadBlob("script.sh"); mode != 0100755 {
+			t.Errorf("incorrect mode of script.sh: expected %o, actual %o", 0100755, mode)
+		}
+	}
+
+	if err := tree.WriteBlob("../escape.txt", []byte("data\n")); err == nil {
+		t.Error("expected error writing unsafe path, but got nil")
+	}
+}
+
+func TestFSTree(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file.txt":    &fstest.MapFile{Data: []byte("line 1\nline 2\n"), Mode: 0644},
+		"old.txt":     &fstest.MapFile{Data: []byte("old\n"), Mode: 0755},
+		"script.sh":   &fstest.MapFile{Data: []byte("#!/bin/sh\n"), Mode: 0644},
+		"removed.txt": &fstest.MapFile{Data: []byte("removed\n"), Mode: 0644},
+	}
+
+	tree := NewFSTree(fsys)
+	if err := BuildTree(parseTreesPatch(t), tree); err != nil {
+		t.Fatalf("unexpected error building tree: %v", err)
+	}
+
+	expectedChanged := []string{"dir/new.txt", "file.txt", "old.txt", "removed.txt", "script.sh"}
+	if changed := tree.Changed(); !reflect.DeepEqual(expectedChanged, changed) {
+		t.Errorf("incorrect changed paths\nexpected: %q\n  actual: %q", expectedChanged, changed)
+	}
+
+	if data, mode, err := tree.ReadBlob("dir/new.txt"); err != nil || string(data) != "old\n" || mode != 0100755 {
+		t.Errorf("incorrect renamed file: %q, %o, %v", data, mode, err)
+	}
+	if _, mode, err := tree.ReadBlob("script.sh"); err != nil || mode != 0100755 {
+		t.Errorf("incorrect mode of script.sh: %o, %v", mode, err)
+	}
+	if !tree.IsDeleted("removed.txt") {
+		t.Error("expected removed.txt to be deleted")
+	}
+	if _, _, err := tree.ReadBlob("removed.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected removed.txt to not exist, but got error: %v", err)
+	}
+
+	if string(fsys["file.txt"].Data) != "line 1\nline 2\n" {
+		t.Errorf("base file system was modified: %q", fsys["file.txt"].Data)
+	}
+}
+
+func TestOverlayTreeCache(t *testing.T) {
+	fetches := make(map[string]int)
+	tree := NewOverlayTree(func(path string) ([]byte, os.FileMode, error) {
+		fetches[path]++
+		if path != "file.txt" {
+			return nil, 0, fs.ErrNotExist
+		}
+		return []byte("line 1\nline 2\n"), 0100644, nil
+	})
+
+	patch := `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@
+ line 1
+-line 2
++line two
+`
+	for i := 0; i < 2; i++ {
+		files, _, err := ParseAll(strings.NewReader(patch))
+		if err != nil {
+			t.Fatalf("unexpected error parsing patch: %v", err)
+		}
+		err = BuildTree(files, tree)
+		if i == 0 && err != nil {
+			t.Fatalf("unexpected error building tree: %v", err)
+		}
+		if i == 1 && err == nil {
+			t.Fatal("expected error applying patch twice, but got nil")
+		}
+	}
+
+	if fetches["file.txt"] != 1 {
+		t.Errorf("incorrect number of fetches: expected 1, actual %d", fetches["file.txt"])
+	}
+	if data, _, _ := tree.ReadBlob("file.txt"); string(data) != "line 1\nline two\n" {
+		t.Errorf("incorrect content: %q", data)
+	}
+	if _, _, err := tree.ReadBlob("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected missing.txt to not exist, but got error: %v", err)
+	}
+}
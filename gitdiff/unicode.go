@@ -0,0 +1,125 @@
+package gitdiff
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules reported by UnicodeScanner.
+const (
+	RuleBidiControl = "bidi-control"
+	RuleInvisible   = "invisible-character"
+	RuleMixedScript = "mixed-script"
+)
+
+// UnicodeScanner is a Scanner that reports characters in added lines that can
+// make code look different to reviewers than it does to compilers, as in
+// "Trojan Source" attacks. It reports one finding for each:
+//
+//   - bidirectional control character, with rule "bidi-control"
+//   - invisible character, such as a zero width space, with rule
+//     "invisible-character"; a byte order mark at the start of the first line
+//     of a file is allowed
+//   - word that mixes Latin, Greek, and Cyrillic letters, a common sign of
+//     homoglyphs, with rule "mixed-script"; the finding is at the first
+//     letter that is not from the script of the first letter of the word
+//
+// The Column of each finding is the position of the character and the
+// Redacted line shows the character as an escape sequence, like <U+202E>.
+var UnicodeScanner Scanner = ScannerFunc(scanUnicode)
+
+func scanUnicode(file string, lineno int64, line string) []Finding {
+	line = strings.TrimSuffix(line, "\n")
+
+	var findings []Finding
+	report := func(rule string, i int) {
+		findings = append(findings, Finding{
+			File:        file,
+			Line:        lineno,
+			Column:      i + 1,
+			ColumnUTF16: int(textWidth(line[:i], PositionUTF16)) + 1,
+			Rule:        rule,
+			Redacted:    escapeSuspicious(line),
+		})
+	}
+
+	var wordScript *unicode.RangeTable
+	var wordReported bool
+	for i, r := range line {
+		switch {
+		case isBidiControl(r):
+			report(RuleBidiControl, i)
+		case isInvisible(r) && !(r == '\ufeff' && lineno == 1 && i == 0):
+			report(RuleInvisible, i)
+		}
+
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			wordScript, wordReported = nil, false
+			continue
+		}
+		if script := letterScript(r); script != nil {
+			if wordScript == nil {
+				wordScript = script
+			} else if script != wordScript && !wordReported {
+				report(RuleMixedScript, i)
+				wordReported = true
+			}
+		}
+	}
+	return findings
+}
+
+func isBidiControl(r rune) bool {
+	switch {
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+		return true
+	case r == '\u200e', r == '\u200f', r == '\u061c':
+		return true
+	}
+	return false
+}
+
+func isInvisible(r rune) bool {
+	switch {
+	case r >= '\u200b' && r <= '\u200d', r >= '\u2060' && r <= '\u2064':
+		return true
+	case r == '\u00ad', r == '\u034f', r == '\u180e', r == '\ufeff':
+		return true
+	case r == '\u115f', r == '\u1160', r == '\u3164', r == '\uffa0':
+		return true
+	}
+	return false
+}
+
+// letterScript returns the script of r if it is a Latin, Greek, or Cyrillic
+// letter.
+func letterScript(r rune) *unicode.RangeTable {
+	if r < utf8.RuneSelf {
+		if unicode.IsLetter(r) {
+			return unicode.Latin
+		}
+		return nil
+	}
+	for _, script := range []*unicode.RangeTable{unicode.Latin, unicode.Greek, unicode.Cyrillic} {
+		if unicode.Is(script, r) {
+			return script
+		}
+	}
+	return nil
+}
+
+// escapeSuspicious replaces the characters reported by UnicodeScanner and
+// other non-ASCII letters in line with escape sequences.
+func escapeSuspicious(line string) string {
+	var b strings.Builder
+	for _, r := range line {
+		if isBidiControl(r) || isInvisible(r) || (r >= utf8.RuneSelf && letterScript(r) != nil && letterScript(r) != unicode.Latin) {
+			fmt.Fprintf(&b, "<U+%04X>", r)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package gitdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnicodeScanner(t *testing.T) {
+	tests := map[string]struct {
+		Line     string
+		Lineno   int64
+		Findings []Finding
+	}{
+		"plain": {
+			Line: "if isAdmin { // check access\n",
+		},
+		"nonLatinText": {
+			Line: "// Привет, мир\n",
+		},
+		"bidiOverride": {
+			Line: "if access != \"user\u202e \u2066// admin\u2069 \u2066\" {\n",
+			Findings: []Finding{
+				{Column: 19, ColumnUTF16: 19, Rule: RuleBidiControl},
+				{Column: 23, ColumnUTF16: 21, Rule: RuleBidiControl},
+				{Column: 34, ColumnUTF16: 30, Rule: RuleBidiControl},
+				{Column: 38, ColumnUTF16: 32, Rule: RuleBidiControl},
+			},
+		},
+		"invisible": {
+			Line: "var admin\u200b = true\n",
+			Findings: []Finding{
+				{Column: 10, ColumnUTF16: 10, Rule: RuleInvisible},
+			},
+		},
+		"byteOrderMark": {
+			Line:   "\ufeffpackage main\n",
+			Lineno: 1,
+		},
+		"byteOrderMarkLater": {
+			Line:   "\ufeffpackage main\n",
+			Lineno: 2,
+			Findings: []Finding{
+				{Column: 1, ColumnUTF16: 1, Rule: RuleInvisible},
+			},
+		},
+		"homoglyph": {
+			Line: "if isAdmin\u0456 {\n",
+			Findings: []Finding{
+				{Column: 11, ColumnUTF16: 11, Rule: RuleMixedScript},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lineno := test.Lineno
+			if lineno == 0 {
+				lineno = 5
+			}
+
+			findings := UnicodeScanner.ScanLine("main.go", lineno, test.Line)
+			for i := range test.Findings {
+				test.Findings[i].File = "main.go"
+				test.Findings[i].Line = lineno
+				test.Findings[i].Redacted = escapeSuspicious(test.Line[:len(test.Line)-1])
+			}
+			if !reflect.DeepEqual(test.Findings, findings) {
+				t.Errorf("incorrect findings\nexpected: %+v\n  actual: %+v", test.Findings, findings)
+			}
+		})
+	}
+}
+
+func TestEscapeSuspicious(t *testing.T) {
+	line := "if isAdmin\u0456 {\u200b} // café"
+	expected := "if isAdmin<U+0456> {<U+200B>} // café"
+	if actual := escapeSuspicious(line); actual != expected {
+		t.Errorf("incorrect escaped line\nexpected: %q\n  actual: %q", expected, actual)
+	}
+}
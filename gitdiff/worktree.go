@@ -0,0 +1,484 @@
+package gitdiff
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// chmodSupported is true if the file system can store the executable bit of
+// file modes. On Windows, os.Chmod only controls the read-only attribute.
+var chmodSupported = runtime.GOOS != "windows"
+
+// caseInsensitive is true if file systems on this platform usually ignore the
+// case of file names.
+var caseInsensitive = runtime.GOOS == "windows" || runtime.GOOS == "darwin"
+
+// ApplyTreeOptions configures ApplyTree.
+type ApplyTreeOptions struct {
+	// ApplyOptions configures how the changes to each file are applied.
+	ApplyOptions ApplyOptions
+
+	// Check applies the patch in memory without modifying any files, to test
+	// that it applies.
+	Check bool
+
+	// Unsafe disables the validation of the names in the patch. Only set it
+	// if the patch is trusted.
+	Unsafe bool
+
+	// CaseInsensitive reports names that differ only in case as collisions,
+	// as on the default file systems of Windows and macOS, where collisions
+	// are always reported.
+	CaseInsensitive bool
+
+	// AllowSymlinkEscape allows reading and writing files through symbolic
+	// links that resolve to locations outside of the directory. By default,
+	// ApplyTree refuses to use these links, like git apply.
+	AllowSymlinkEscape bool
+
+	// ModeError makes ApplyTree fail instead of warning if a file mode cannot
+	// be changed, as with the executable bit on Windows.
+	ModeError bool
+
+	// Warnf, if set, is called with warnings about changes that could not be
+	// made exactly as described by the patch.
+	Warnf func(format string, args ...interface{})
+}
+
+// treeResult is the outcome of applying one file, written to disk only after
+// every file in the patch applies.
+type treeResult struct {
+	file    *File
+	content []byte
+}
+
+// ApplyTree applies the changes in files to the files in the directory dir.
+// Files are applied in the order returned by OrderFiles and later files see
+// the results of earlier ones. Every file is applied in memory before any file
+// is written, so a patch that does not apply leaves dir unchanged.
+//
+// Unless opts.Unsafe is set, ApplyTree first validates every name in the
+// patch with ValidatePath and rejects names that collide with other names in
+// the patch or in dir on case-insensitive file systems. Unless
+// opts.AllowSymlinkEscape is set, it also rejects names that resolve through
+// symbolic links in dir to files outside of dir.
+//
+// Files with mode 0120000 are written as symbolic links. Changes to submodules,
+// with mode 0160000, are rejected.
+func ApplyTree(dir string, files []*File, opts ApplyTreeOptions) error {
+	files, err := OrderFiles(files)
+	if err != nil {
+		return err
+	}
+	if err := checkMaxFiles(files, opts.ApplyOptions); err != nil {
+		return err
+	}
+
+	if !opts.Unsafe {
+		if err := checkTreePaths(dir, files, opts.CaseInsensitive || caseInsensitive); err != nil {
+			return err
+		}
+	}
+
+	if !opts.AllowSymlinkEscape {
+		if err := checkTreeSymlinks(dir, files); err != nil {
+			return err
+		}
+	}
+
+	// files later in the patch see the results of earlier files
+	pending := make(map[string][]byte)
+	deleted := make(map[string]bool)
+
+	var results []treeResult
+	total := totalLimit{max: opts.ApplyOptions.MaxTotalBytes}
+	for _, f := range files {
+		if f.OldMode == 0160000 || f.NewMode == 0160000 {
+			return fmt.Errorf("gitdiff: %s: cannot apply a change to a submodule to a working tree", fileName(f))
+		}
+
+		var src []byte
+		if !f.IsNew {
+			if deleted[f.OldName] {
+				return fmt.Errorf("gitdiff: %s: file was deleted by an earlier change", f.OldName)
+			}
+			if data, ok := pending[f.OldName]; ok {
+				src = data
+			} else if src, err = readTreeFile(filepath.Join(dir, filepath.FromSlash(f.OldName))); err != nil {
+				return err
+			}
+		}
+
+		var dst bytes.Buffer
+		if err := NewApplierWithOptions(bytes.NewReader(src), opts.ApplyOptions).ApplyFile(total.writer(&dst), f); err != nil {
+			return fmt.Errorf("gitdiff: %s: %w", fileName(f), err)
+		}
+		if opts.ModeError && !chmodSupported && isModeChange(f) {
+			return fmt.Errorf("gitdiff: %s: cannot change mode from %o to %o on this system", fileName(f), f.OldMode, f.NewMode)
+		}
+
+		if f.IsDelete || f.IsRename {
+			delete(pending, f.OldName)
+			deleted[f.OldName] = true
+		}
+		if !f.IsDelete {
+			pending[f.NewName] = dst.Bytes()
+			delete(deleted, f.NewName)
+		}
+		results = append(results, treeResult{f, dst.Bytes()})
+	}
+
+	if opts.Check {
+		return nil
+	}
+	for _, r := range results {
+		if err := writeTreeResult(dir, r, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTreeFile returns the content of the file at path. Like Git, it uses the
+// target of a symbolic link as its content, without following the link.
+func readTreeFile(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		return []byte(filepath.ToSlash(target)), err
+	}
+	return ioutil.ReadFile(path)
+}
+
+// isModeChange returns true if f changes the mode of an existing file.
+func isModeChange(f *File) bool {
+	return !f.IsNew && !f.IsDelete && f.OldMode != 0 && f.NewMode != 0 && f.OldMode != f.NewMode
+}
+
+// isModeOnly returns true if f changes the mode of a file without changing
+// its name or content.
+func isModeOnly(f *File) bool {
+	return isModeChange(f) && !f.IsRename && !f.IsCopy && len(f.TextFragments) == 0 && f.BinaryFragment == nil
+}
+
+func writeTreeResult(dir string, r treeResult, opts ApplyTreeOptions) error {
+	f := r.file
+	oldPath := filepath.Join(dir, filepath.FromSlash(f.OldName))
+	newPath := filepath.Join(dir, filepath.FromSlash(f.NewName))
+
+	if f.IsDelete {
+		return os.Remove(oldPath)
+	}
+
+	// a file without a new mode keeps the type of the old file
+	link := f.NewMode == 0120000
+	if f.NewMode == 0 {
+		if info, err := os.Lstat(oldPath); err == nil {
+			link = info.Mode()&os.ModeSymlink != 0
+		}
+	}
+	typeChange := link != (f.OldMode == 0120000) && f.OldMode != 0
+
+	if isModeChange(f) && !typeChange && !chmodSupported {
+		if opts.Warnf != nil {
+			opts.Warnf("%s: cannot change mode from %o to %o on this system", fileName(f), f.OldMode, f.NewMode)
+		}
+		if isModeOnly(f) {
+			return nil
+		}
+	}
+	if isModeOnly(f) && !typeChange {
+		return os.Chmod(newPath, f.NewMode.Perm())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+	if link {
+		if err := writeTreeSymlink(newPath, r.content); err != nil {
+			return err
+		}
+	} else if err := writeTreeRegular(newPath, oldPath, f, r.content); err != nil {
+		return err
+	}
+	if f.IsRename && oldPath != newPath {
+		return os.Remove(oldPath)
+	}
+	return nil
+}
+
+// writeTreeSymlink replaces the file at path with a symbolic link to target.
+func writeTreeSymlink(path string, target []byte) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(filepath.FromSlash(string(target)), path)
+}
+
+// writeTreeRegular writes content to the regular file at path, replacing any
+// symbolic link instead of writing to its target.
+func writeTreeRegular(path, oldPath string, f *File, content []byte) error {
+	mode := os.FileMode(0644)
+	if f.NewMode != 0 {
+		mode = f.NewMode.Perm()
+	} else if info, err := os.Stat(oldPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(path, content, mode); err != nil {
+		return err
+	}
+	if f.NewMode != 0 {
+		return os.Chmod(path, mode)
+	}
+	return nil
+}
+
+// UnsafePathError is returned when a patch contains a name that is not safe
+// to write to a working tree.
+type UnsafePathError struct {
+	Name   string
+	Reason string
+}
+
+func (e *UnsafePathError) Error() string {
+	return fmt.Sprintf("gitdiff: unsafe path %q: %s", e.Name, e.Reason)
+}
+
+// ValidatePath checks that name is safe to use as the path of a file in a
+// working tree on any platform, returning an *UnsafePathError if it is not.
+// Safe names are relative, slash-separated, and do not contain empty, ".", or
+// ".." elements, the ".git" directory, backslashes, or names reserved by
+// Windows, such as CON and NUL.
+func ValidatePath(name string) error {
+	unsafe := func(reason string) error {
+		return &UnsafePathError{Name: name, Reason: reason}
+	}
+
+	switch {
+	case name == "":
+		return unsafe("empty path")
+	case strings.HasPrefix(name, "/") || (len(name) >= 2 && name[1] == ':'):
+		return unsafe("absolute path")
+	case strings.ContainsRune(name, '\\'):
+		return unsafe("contains a backslash")
+	case strings.ContainsRune(name, 0):
+		return unsafe("contains a NUL byte")
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		switch {
+		case elem == "":
+			return unsafe("empty path element")
+		case elem == "." || elem == "..":
+			return unsafe("path traversal")
+		case strings.EqualFold(strings.TrimRight(elem, ". "), ".git"):
+			return unsafe("inside the .git directory")
+		case isWindowsReserved(elem):
+			return unsafe("reserved name on Windows")
+		}
+	}
+	return nil
+}
+
+// isWindowsReserved returns true if elem is a device name reserved by Windows.
+// Device names are reserved with any extension and trailing spaces.
+func isWindowsReserved(elem string) bool {
+	if i := strings.IndexByte(elem, '.'); i >= 0 {
+		elem = elem[:i]
+	}
+	elem = strings.ToUpper(strings.TrimRight(elem, " "))
+
+	switch elem {
+	case "CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$":
+		return true
+	}
+	if len(elem) == 4 && (strings.HasPrefix(elem, "COM") || strings.HasPrefix(elem, "LPT")) {
+		return elem[3] >= '1' && elem[3] <= '9'
+	}
+	return false
+}
+
+// checkTreePaths validates the names in files and, if foldCase is true,
+// checks that new names do not collide with each other or with existing files
+// in dir when case is ignored.
+func checkTreePaths(dir string, files []*File, foldCase bool) error {
+	removed := make(map[string]bool)
+	for _, f := range files {
+		for _, name := range []string{f.OldName, f.NewName} {
+			if name == "" {
+				continue
+			}
+			if err := ValidatePath(name); err != nil {
+				return err
+			}
+		}
+		if f.IsDelete || f.IsRename {
+			removed[f.OldName] = true
+		}
+	}
+	if !foldCase {
+		return nil
+	}
+
+	created := make(map[string]string)
+	for _, f := range files {
+		if f.IsDelete || !(f.IsNew || f.IsRename || f.IsCopy) {
+			continue
+		}
+
+		key := strings.ToLower(f.NewName)
+		if other, ok := created[key]; ok && other != f.NewName {
+			return &UnsafePathError{Name: f.NewName, Reason: fmt.Sprintf("collides with %q when case is ignored", other)}
+		}
+		created[key] = f.NewName
+
+		if existing := findFoldedName(dir, f.NewName); existing != "" && !removed[existing] {
+			return &UnsafePathError{Name: f.NewName, Reason: fmt.Sprintf("collides with %q when case is ignored", existing)}
+		}
+	}
+	return nil
+}
+
+// findFoldedName returns the name of an existing file in dir that matches name
+// when case is ignored but is not identical to it, or an empty string.
+func findFoldedName(dir, name string) string {
+	parent, base := "", name
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		parent, base = name[:i], name[i+1:]
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Join(dir, filepath.FromSlash(parent)))
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.Name() != base && strings.EqualFold(entry.Name(), base) {
+			if parent == "" {
+				return entry.Name()
+			}
+			return parent + "/" + entry.Name()
+		}
+	}
+	return ""
+}
+
+// checkTreeSymlinks checks that the files in the patch do not use symbolic
+// links in dir that resolve outside of dir. A deleted file may be a link,
+// because deleting it removes the link and not its target.
+func checkTreeSymlinks(dir string, files []*File) error {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return err
+	}
+
+	// like git apply, reject paths beyond symbolic links created by the
+	// patch, which do not exist yet when the tree is checked
+	links := make(map[string]bool)
+
+	for _, f := range files {
+		oldLink := (f.IsRename || f.IsCopy) && (links[f.OldName] || isTreeSymlink(root, f.OldName))
+		if f.OldName != "" && !f.IsNew {
+			if err := checkPatchSymlinks(links, f.OldName); err != nil {
+				return err
+			}
+			if err := checkSymlinkEscape(root, f.OldName, !f.IsDelete); err != nil {
+				return err
+			}
+			if f.IsDelete || f.IsRename {
+				delete(links, f.OldName)
+			}
+		}
+		if f.NewName != "" && !f.IsDelete {
+			if err := checkPatchSymlinks(links, f.NewName); err != nil {
+				return err
+			}
+			if err := checkSymlinkEscape(root, f.NewName, true); err != nil {
+				return err
+			}
+			switch {
+			case f.NewMode == 0120000 || (f.NewMode == 0 && oldLink):
+				links[f.NewName] = true
+			case f.NewMode != 0:
+				delete(links, f.NewName)
+			}
+		}
+	}
+	return nil
+}
+
+// isTreeSymlink returns true if the file name in root is a symbolic link.
+func isTreeSymlink(root, name string) bool {
+	info, err := os.Lstat(filepath.Join(root, filepath.FromSlash(name)))
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// checkPatchSymlinks returns an *UnsafePathError if a directory in name is a
+// symbolic link created by an earlier file in the patch.
+func checkPatchSymlinks(links map[string]bool, name string) error {
+	elems := strings.Split(name, "/")
+	for i := 1; i < len(elems); i++ {
+		if prefix := strings.Join(elems[:i], "/"); links[prefix] {
+			return &UnsafePathError{Name: name, Reason: fmt.Sprintf("beyond symbolic link %q created by the patch", prefix)}
+		}
+	}
+	return nil
+}
+
+// checkSymlinkEscape returns an *UnsafePathError if any existing directory in
+// name, or the file itself if follow is true, is a symbolic link that does not
+// resolve to a location inside of root.
+func checkSymlinkEscape(root, name string, follow bool) error {
+	elems := strings.Split(name, "/")
+	for i := range elems {
+		if i == len(elems)-1 && !follow {
+			break
+		}
+
+		prefix := strings.Join(elems[:i+1], "/")
+		path := filepath.Join(root, filepath.FromSlash(prefix))
+
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil || !isWithin(root, target) {
+			return &UnsafePathError{Name: name, Reason: fmt.Sprintf("symbolic link %q resolves outside of the tree", prefix)}
+		}
+	}
+	return nil
+}
+
+// isWithin returns true if path is root or a location inside of root. Both
+// paths must be absolute and clean.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
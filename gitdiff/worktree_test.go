@@ -0,0 +1,417 @@
+package gitdiff
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidatePath(t *testing.T) {
+	tests := map[string]string{
+		"file.txt":            "",
+		"dir/sub/file.txt":    "",
+		"console.txt":         "",
+		"COM0":                "",
+		".gitignore":          "",
+		"dir/.github/ci.yml":  "",
+		"":                    "empty path",
+		"/etc/passwd":         "absolute path",
+		"C:/Windows/file.txt": "absolute path",
+		"dir\\file.txt":       "contains a backslash",
+		"dir//file.txt":       "empty path element",
+		"../file.txt":         "path traversal",
+		"dir/../../file.txt":  "path traversal",
+		"./file.txt":          "path traversal",
+		".git/config":         "inside the .git directory",
+		"dir/.GIT./hooks":     "inside the .git directory",
+		"CON":                 "reserved name on Windows",
+		"dir/nul.txt":         "reserved name on Windows",
+		"Lpt1 .log":           "reserved name on Windows",
+	}
+
+	for name, reason := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidatePath(name)
+			if reason == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating path: %v", err)
+				}
+				return
+			}
+
+			var perr *UnsafePathError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected *UnsafePathError, but got %T: %v", err, err)
+			}
+			if perr.Name != name || perr.Reason != reason {
+				t.Errorf("incorrect error: expected reason %q, actual %+v", reason, perr)
+			}
+		})
+	}
+}
+
+func TestApplyTree(t *testing.T) {
+	setup := func(t *testing.T, files map[string]string) string {
+		dir, err := ioutil.TempDir("", "gitdiff-tree")
+		if err != nil {
+			t.Fatalf("unexpected error creating directory: %v", err)
+		}
+		for name, content := range files {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("unexpected error creating directory: %v", err)
+			}
+			if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("unexpected error writing file: %v", err)
+			}
+		}
+		return dir
+	}
+
+	newFile := func(name, content string) string {
+		return fmt.Sprintf("diff --git a/%[1]s b/%[1]s\nnew file mode 100644\n--- /dev/null\n+++ b/%[1]s\n@@ -0,0 +1 @@\n+%[2]s\n", name, content)
+	}
+
+	tests := map[string]struct {
+		Files  map[string]string
+		Patch  string
+		Opts   ApplyTreeOptions
+		Output map[string]string
+		Err    string
+	}{
+		"changes": {
+			Files: map[string]string{"file.txt": "line 1\nline 2\n", "old.txt": "old\n"},
+			Patch: "diff --git a/file.txt b/file.txt\n--- a/file.txt\n+++ b/file.txt\n@@ -1,2 +1,2 @@\n line 1\n-line 2\n+line two\n" +
+				"diff --git a/old.txt b/new.txt\nsimilarity index 100%\nrename from old.txt\nrename to new.txt\n" +
+				newFile("dir/added.txt", "added"),
+			Output: map[string]string{"file.txt": "line 1\nline two\n", "new.txt": "old\n", "dir/added.txt": "added\n"},
+		},
+		"check": {
+			Files:  map[string]string{"file.txt": "content\n"},
+			Patch:  newFile("added.txt", "added"),
+			Opts:   ApplyTreeOptions{Check: true},
+			Output: map[string]string{"file.txt": "content\n"},
+		},
+		"traversal": {
+			Patch: newFile("../escape.txt", "escaped"),
+			Err:   `unsafe path "../escape.txt": path traversal`,
+		},
+		"gitDirectory": {
+			Patch: newFile(".git/hooks/post-checkout", "exploit"),
+			Err:   `unsafe path ".git/hooks/post-checkout": inside the .git directory`,
+		},
+		"unsafe": {
+			Patch:  newFile("dir/../added.txt", "added"),
+			Opts:   ApplyTreeOptions{Unsafe: true},
+			Output: map[string]string{"added.txt": "added\n"},
+		},
+		"caseCollisionInPatch": {
+			Patch: newFile("README", "one") + newFile("readme", "two"),
+			Opts:  ApplyTreeOptions{CaseInsensitive: true},
+			Err:   `unsafe path "readme": collides with "README" when case is ignored`,
+		},
+		"caseCollisionExisting": {
+			Files: map[string]string{"docs/Guide.md": "guide\n"},
+			Patch: newFile("docs/guide.md", "other"),
+			Opts:  ApplyTreeOptions{CaseInsensitive: true},
+			Err:   `unsafe path "docs/guide.md": collides with "docs/Guide.md" when case is ignored`,
+		},
+		"maxFiles": {
+			Patch: newFile("a.txt", "a") + newFile("b.txt", "b"),
+			Opts:  ApplyTreeOptions{ApplyOptions: ApplyOptions{MaxFiles: 1}},
+			Err:   "apply limit exceeded: patch changes 2 files, more than 1",
+		},
+		"maxFileBytes": {
+			Patch: newFile("a.txt", "a") + newFile("b.txt", "too large"),
+			Opts:  ApplyTreeOptions{ApplyOptions: ApplyOptions{MaxFileBytes: 4}},
+			Err:   "b.txt: gitdiff: apply limit exceeded: result is larger than 4 bytes",
+		},
+		"maxTotalBytes": {
+			Patch: newFile("a.txt", "aaaa") + newFile("b.txt", "bbbb"),
+			Opts:  ApplyTreeOptions{ApplyOptions: ApplyOptions{MaxTotalBytes: 8}},
+			Err:   "apply limit exceeded: results are larger than 8 bytes",
+		},
+		"withinLimits": {
+			Patch:  newFile("a.txt", "aaa") + newFile("b.txt", "bbb"),
+			Opts:   ApplyTreeOptions{ApplyOptions: ApplyOptions{MaxFiles: 2, MaxFileBytes: 4, MaxTotalBytes: 8}},
+			Output: map[string]string{"a.txt": "aaa\n", "b.txt": "bbb\n"},
+		},
+		"caseRename": {
+			Files:  map[string]string{"readme": "content\n"},
+			Patch:  "diff --git a/readme b/README\nsimilarity index 100%\nrename from readme\nrename to README\n",
+			Opts:   ApplyTreeOptions{CaseInsensitive: true},
+			Output: map[string]string{"README": "content\n"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := setup(t, test.Files)
+			defer os.RemoveAll(dir)
+
+			files, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			err = ApplyTree(dir, files, test.Opts)
+			if test.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.Err) {
+					t.Fatalf("expected error containing %q, but got %v", test.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error applying patch: %v", err)
+			}
+
+			for name, content := range test.Output {
+				data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatalf("unexpected error reading file: %v", err)
+				}
+				if string(data) != content {
+					t.Errorf("incorrect content for %s: expected %q, actual %q", name, content, string(data))
+				}
+			}
+		})
+	}
+}
+
+func TestApplyTreeModeUnsupported(t *testing.T) {
+	defer func(supported bool) { chmodSupported = supported }(chmodSupported)
+	chmodSupported = false
+
+	dir, err := ioutil.TempDir("", "gitdiff-tree")
+	if err != nil {
+		t.Fatalf("unexpected error creating directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "script.sh")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading file info: %v", err)
+	}
+
+	files, _, err := ParseAll(strings.NewReader("diff --git a/script.sh b/script.sh\nold mode 100644\nnew mode 100755\n"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing patch: %v", err)
+	}
+
+	var warnings []string
+	opts := ApplyTreeOptions{
+		Warnf: func(format string, args ...interface{}) {
+			warnings = append(warnings, fmt.Sprintf(format, args...))
+		},
+	}
+	if err := ApplyTree(dir, files, opts); err != nil {
+		t.Fatalf("unexpected error applying patch: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0] != "script.sh: cannot change mode from 100644 to 100755 on this system" {
+		t.Errorf("incorrect warnings: %q", warnings)
+	}
+	if after, err := os.Stat(path); err != nil || after.Mode() != info.Mode() {
+		t.Errorf("file mode was modified: %v", after.Mode())
+	}
+
+	opts.ModeError = true
+	if err := ApplyTree(dir, files, opts); err == nil {
+		t.Fatal("expected error applying patch, but got nil")
+	}
+}
+
+func TestApplyTreeSymlinks(t *testing.T) {
+	newFile := func(name string) string {
+		return fmt.Sprintf("diff --git a/%[1]s b/%[1]s\nnew file mode 100644\n--- /dev/null\n+++ b/%[1]s\n@@ -0,0 +1 @@\n+content\n", name)
+	}
+
+	tests := map[string]struct {
+		Patch string
+		Opts  ApplyTreeOptions
+		Err   string
+	}{
+		"escapingDirectory": {
+			Patch: newFile("outside/file.txt"),
+			Err:   `unsafe path "outside/file.txt": symbolic link "outside" resolves outside of the tree`,
+		},
+		"escapingFile": {
+			Patch: "diff --git a/secret.txt b/secret.txt\n--- a/secret.txt\n+++ b/secret.txt\n@@ -1 +1 @@\n-secret\n+changed\n",
+			Err:   `unsafe path "secret.txt": symbolic link "secret.txt" resolves outside of the tree`,
+		},
+		"deleteLink": {
+			Patch: "diff --git a/secret.txt b/secret.txt\ndeleted file mode 120000\n--- a/secret.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-$OUTSIDE/secret.txt\n\\ No newline at end of file\n",
+		},
+		"insideLink": {
+			Patch: newFile("alias/file.txt"),
+		},
+		"allowEscape": {
+			Patch: newFile("outside/file.txt"),
+			Opts:  ApplyTreeOptions{AllowSymlinkEscape: true},
+		},
+		"createdLink": {
+			Patch: "diff --git a/evil b/evil\nnew file mode 120000\n--- /dev/null\n+++ b/evil\n@@ -0,0 +1 @@\n+$OUTSIDE\n\\ No newline at end of file\n" +
+				newFile("evil/secret.txt"),
+			Err: `unsafe path "evil/secret.txt": beyond symbolic link "evil" created by the patch`,
+		},
+		"renamedLink": {
+			Patch: "diff --git a/alias b/moved\nsimilarity index 100%\nrename from alias\nrename to moved\n" +
+				newFile("moved/file.txt"),
+			Err: `unsafe path "moved/file.txt": beyond symbolic link "moved" created by the patch`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			root, err := ioutil.TempDir("", "gitdiff-tree")
+			if err != nil {
+				t.Fatalf("unexpected error creating directory: %v", err)
+			}
+			defer os.RemoveAll(root)
+
+			dir := filepath.Join(root, "tree")
+			outside := filepath.Join(root, "outside")
+			for _, d := range []string{filepath.Join(dir, "docs"), outside} {
+				if err := os.MkdirAll(d, 0755); err != nil {
+					t.Fatalf("unexpected error creating directory: %v", err)
+				}
+			}
+			if err := ioutil.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret\n"), 0644); err != nil {
+				t.Fatalf("unexpected error writing file: %v", err)
+			}
+
+			links := map[string]string{
+				"outside":    outside,
+				"secret.txt": filepath.Join(outside, "secret.txt"),
+				"alias":      "docs",
+			}
+			for name, target := range links {
+				if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+					t.Skipf("cannot create symbolic links: %v", err)
+				}
+			}
+
+			patch := strings.Replace(test.Patch, "$OUTSIDE", filepath.ToSlash(outside), -1)
+			files, _, err := ParseAll(strings.NewReader(patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+
+			err = ApplyTree(dir, files, test.Opts)
+			if test.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.Err) {
+					t.Fatalf("expected error containing %q, but got %v", test.Err, err)
+				}
+				if data, _ := ioutil.ReadFile(filepath.Join(outside, "secret.txt")); string(data) != "secret\n" {
+					t.Errorf("file outside of the tree was modified: %q", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error applying patch: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(outside, "secret.txt")); err != nil {
+				t.Errorf("file outside of the tree was removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyTreeFileTypes(t *testing.T) {
+	tests := map[string]struct {
+		Patch  string
+		Link   string
+		Output string
+		Err    string
+	}{
+		"newSymlink": {
+			Patch: "diff --git a/link b/link\nnew file mode 120000\n--- /dev/null\n+++ b/link\n@@ -0,0 +1 @@\n+target.txt\n\\ No newline at end of file\n",
+			Link:  "target.txt",
+		},
+		"fileToSymlink": {
+			Patch: "diff --git a/file.txt b/file.txt\nold mode 100644\nnew mode 120000\n--- a/file.txt\n+++ b/file.txt\n@@ -1 +1 @@\n-content\n+target.txt\n\\ No newline at end of file\n",
+			Link:  "target.txt",
+		},
+		"modifySymlink": {
+			Patch: "diff --git a/alias b/alias\n--- a/alias\n+++ b/alias\n@@ -1 +1 @@\n-target.txt\n\\ No newline at end of file\n+file.txt\n\\ No newline at end of file\n",
+			Link:  "file.txt",
+		},
+		"symlinkToFile": {
+			Patch:  "diff --git a/alias b/alias\nold mode 120000\nnew mode 100644\n--- a/alias\n+++ b/alias\n@@ -1 +1 @@\n-target.txt\n\\ No newline at end of file\n+regular\n",
+			Output: "regular\n",
+		},
+		"newGitlink": {
+			Patch: "diff --git a/sub b/sub\nnew file mode 160000\nindex 0000000..3b18e51\n--- /dev/null\n+++ b/sub\n@@ -0,0 +1 @@\n+Subproject commit 3b18e512dba79e4c8300dd08aeb37f8e728b8dad\n",
+			Err:   "cannot apply a change to a submodule",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gitdiff-tree")
+			if err != nil {
+				t.Fatalf("unexpected error creating directory: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			for name, content := range map[string]string{"file.txt": "content\n", "target.txt": "target\n"} {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatalf("unexpected error writing file: %v", err)
+				}
+			}
+			if err := os.Symlink("target.txt", filepath.Join(dir, "alias")); err != nil {
+				t.Skipf("cannot create symbolic links: %v", err)
+			}
+
+			files, _, err := ParseAll(strings.NewReader(test.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error parsing patch: %v", err)
+			}
+			path := filepath.Join(dir, filepath.FromSlash(files[0].NewName))
+
+			err = ApplyTree(dir, files, ApplyTreeOptions{})
+			if test.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.Err) {
+					t.Fatalf("expected error containing %q, but got %v", test.Err, err)
+				}
+				if _, err := os.Lstat(path); !os.IsNotExist(err) {
+					t.Errorf("file was written for rejected change: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error applying patch: %v", err)
+			}
+
+			info, err := os.Lstat(path)
+			if err != nil {
+				t.Fatalf("unexpected error reading file info: %v", err)
+			}
+			if test.Link != "" {
+				if info.Mode()&os.ModeSymlink == 0 {
+					t.Fatalf("expected symbolic link, but got mode %v", info.Mode())
+				}
+				if target, _ := os.Readlink(path); target != test.Link {
+					t.Errorf("incorrect link target: expected %q, actual %q", test.Link, target)
+				}
+			} else {
+				if !info.Mode().IsRegular() {
+					t.Fatalf("expected regular file, but got mode %v", info.Mode())
+				}
+				if data, _ := ioutil.ReadFile(path); string(data) != test.Output {
+					t.Errorf("incorrect content: expected %q, actual %q", test.Output, data)
+				}
+			}
+			if data, _ := ioutil.ReadFile(filepath.Join(dir, "target.txt")); string(data) != "target\n" {
+				t.Errorf("link target was modified: %q", data)
+			}
+		})
+	}
+}
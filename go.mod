@@ -1,3 +1,3 @@
 module github.com/gitleaks/go-gitdiff
 
-go 1.13
+go 1.16